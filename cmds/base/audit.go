@@ -0,0 +1,169 @@
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gobox/pkg/procfs"
+)
+
+// AuditEnv names the environment variable that enables the audit log: a file
+// path that receives one JSON line per state-changing action, or "syslog".
+const AuditEnv = "GOBOX_AUDIT_LOG"
+
+// auditMaxAncestors bounds the parent chain recorded for each action.
+const auditMaxAncestors = 32
+
+// AuditProcess is one ancestor of the gobox process in an audit record.
+type AuditProcess struct {
+	PID  int    `json:"pid"`
+	Comm string `json:"comm"`
+}
+
+// AuditRecord is one line of the audit log.
+type AuditRecord struct {
+	Time    string            `json:"time"`
+	UID     int               `json:"uid"`
+	User    string            `json:"user,omitempty"`
+	TTY     string            `json:"tty,omitempty"`
+	PID     int               `json:"pid"`
+	PPIDs   []AuditProcess    `json:"ppids"`
+	Argv    []string          `json:"argv"`
+	Cwd     string            `json:"cwd,omitempty"`
+	Command string            `json:"command"`
+	Action  string            `json:"action"`
+	Target  string            `json:"target"`
+	Detail  map[string]string `json:"detail,omitempty"`
+	Outcome string            `json:"outcome"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// auditMu serialises writes so concurrent jobs (ioperf --numjobs) never
+// interleave lines.
+var auditMu sync.Mutex
+
+// auditProcFS is the procfs the parent chain is read from. It is the real
+// /proc, not the --proc-root a command may be inspecting.
+var auditProcFS = procfs.NewFS("")
+
+// Audit records that the command running in inv performed action on target,
+// with err as its outcome, when GOBOX_AUDIT_LOG is set. detail holds extra
+// key/value pairs such as the signal sent. Audit never fails the command: a
+// log it cannot write is reported on stderr and the action goes ahead.
+func Audit(inv *Invocation, action, target string, err error, detail ...string) {
+	dest := inv.Getenv(AuditEnv)
+	if dest == "" {
+		return
+	}
+	rec := newAuditRecord(inv, action, target, err, detail)
+	line, jerr := json.Marshal(rec)
+	if jerr != nil {
+		fmt.Fprintf(inv.Stderr, "gobox: audit: %v\n", jerr)
+		return
+	}
+	if werr := writeAuditLine(inv, dest, line); werr != nil {
+		fmt.Fprintf(inv.Stderr, "gobox: audit: %v\n", werr)
+	}
+}
+
+func newAuditRecord(inv *Invocation, action, target string, err error, detail []string) AuditRecord {
+	rec := AuditRecord{
+		Time:    time.Now().Format(time.RFC3339Nano),
+		UID:     os.Getuid(),
+		TTY:     auditTTY(),
+		PID:     os.Getpid(),
+		PPIDs:   auditAncestors(os.Getppid()),
+		Argv:    inv.Argv,
+		Action:  action,
+		Target:  target,
+		Outcome: "ok",
+	}
+	// Relative targets resolve against the working directory.
+	rec.Cwd = inv.Dir
+	if rec.Cwd == "" {
+		rec.Cwd, _ = os.Getwd()
+	}
+	if u, uerr := user.LookupId(strconv.Itoa(rec.UID)); uerr == nil {
+		rec.User = u.Username
+	}
+	if len(rec.Argv) == 0 {
+		// In-process callers that bypass Command.Run have no Argv.
+		rec.Argv = os.Args
+	}
+	rec.Command = filepath.Base(rec.Argv[0])
+	if len(detail) > 0 {
+		rec.Detail = make(map[string]string, len(detail)/2)
+		for i := 0; i+1 < len(detail); i += 2 {
+			rec.Detail[detail[i]] = detail[i+1]
+		}
+	}
+	if err != nil {
+		rec.Outcome = "error"
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+func writeAuditLine(inv *Invocation, dest string, line []byte) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if dest == "syslog" {
+		w, err := syslog.New(syslog.LOG_AUTHPRIV|syslog.LOG_NOTICE, "gobox")
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		return w.Notice(string(line))
+	}
+	f, err := os.OpenFile(inv.Path(dest), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	// One write per line keeps records whole when several gobox processes
+	// append to the same file.
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// auditAncestors walks the parent chain from pid up to PID 1.
+func auditAncestors(pid int) []AuditProcess {
+	chain := []AuditProcess{}
+	for pid > 0 && len(chain) < auditMaxAncestors {
+		st, err := auditProcFS.Proc(pid).Status()
+		if err != nil {
+			break
+		}
+		chain = append(chain, AuditProcess{PID: pid, Comm: st.Name})
+		if pid == 1 {
+			break
+		}
+		pid = st.PPID
+	}
+	return chain
+}
+
+// auditTTY returns the terminal on the first standard descriptor that has
+// one, or "" when gobox runs detached.
+func auditTTY() string {
+	for fd := 0; fd <= 2; fd++ {
+		target, err := os.Readlink(auditProcFS.Self().Path("fd", strconv.Itoa(fd)))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(target, "/dev/pts/") || strings.HasPrefix(target, "/dev/tty") || target == "/dev/console" {
+			return target
+		}
+	}
+	return ""
+}
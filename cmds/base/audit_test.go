@@ -0,0 +1,105 @@
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAuditRecords(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recs []AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec AuditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("audit line %q: %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestAuditWritesJSONLines(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	inv := &Invocation{Env: []string{AuditEnv + "=" + logPath}, Argv: []string{"truncate", "-s", "0", "app.log"}}
+
+	Audit(inv, "truncate", "/var/log/app.log", nil, "size", "0")
+	Audit(inv, "truncate", "/var/log/other.log", errors.New("permission denied"))
+
+	recs := readAuditRecords(t, logPath)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+	ok := recs[0]
+	if ok.Command != "truncate" || ok.Action != "truncate" || ok.Target != "/var/log/app.log" || ok.Outcome != "ok" || ok.Error != "" {
+		t.Fatalf("unexpected record: %+v", ok)
+	}
+	if strings.Join(ok.Argv, " ") != "truncate -s 0 app.log" || ok.Detail["size"] != "0" {
+		t.Fatalf("expected argv and detail to be recorded, got %+v", ok)
+	}
+	if ok.UID != os.Getuid() || ok.PID != os.Getpid() || ok.Time == "" {
+		t.Fatalf("expected uid, pid and time, got %+v", ok)
+	}
+	if len(ok.PPIDs) == 0 || ok.PPIDs[0].PID != os.Getppid() {
+		t.Fatalf("expected the parent chain to start at the parent, got %+v", ok.PPIDs)
+	}
+	if recs[1].Outcome != "error" || recs[1].Error != "permission denied" {
+		t.Fatalf("expected a failed outcome, got %+v", recs[1])
+	}
+	if info, err := os.Stat(logPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected the log to be created 0600, got %v %v", info, err)
+	}
+}
+
+func TestAuditDisabledWithoutEnv(t *testing.T) {
+	dir := t.TempDir()
+	inv := &Invocation{Env: []string{}, Dir: dir}
+	Audit(inv, "write", "x", nil)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected nothing written without %s, got %v", AuditEnv, entries)
+	}
+}
+
+func TestAuditFallsBackToProcessArgs(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	Audit(&Invocation{Env: []string{AuditEnv + "=" + logPath}}, "write", "out.bin", nil)
+	recs := readAuditRecords(t, logPath)
+	if len(recs[0].Argv) != len(os.Args) || recs[0].Command != filepath.Base(os.Args[0]) {
+		t.Fatalf("expected os.Args without an Argv, got %+v", recs[0])
+	}
+}
+
+func TestAuditUnwritableLogWarnsOnly(t *testing.T) {
+	var stderr bytes.Buffer
+	inv := &Invocation{Env: []string{AuditEnv + "=" + filepath.Join(t.TempDir(), "missing", "audit.log")}, Stderr: &stderr}
+	Audit(inv, "write", "out.bin", nil)
+	if !strings.Contains(stderr.String(), "gobox: audit:") {
+		t.Fatalf("expected a warning on stderr, got %q", stderr.String())
+	}
+}
+
+func TestCommandRunRecordsArgv(t *testing.T) {
+	var got []string
+	cmd := NewCommand("auditargv", "", func(inv *Invocation, args []string) error {
+		got = inv.Argv
+		return nil
+	})
+	if err := cmd.Run(&Invocation{}, []string{"-x", "file"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "auditargv -x file" {
+		t.Fatalf("expected Argv to hold the command line, got %q", got)
+	}
+}
@@ -0,0 +1,127 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check is one assertion from a gobox check file: its name, its type and the
+// type-specific settings, all kept as strings as the file spelled them.
+type Check struct {
+	Name   string
+	Type   string
+	Params map[string]string
+}
+
+// Param returns the setting key, or def when the file leaves it out.
+func (c Check) Param(key, def string) string {
+	if v, ok := c.Params[key]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// RequireParam returns the setting key or an error naming it.
+func (c Check) RequireParam(key string) (string, error) {
+	v := c.Param(key, "")
+	if v == "" {
+		return "", fmt.Errorf("%s check needs %q", c.Type, key)
+	}
+	return v, nil
+}
+
+// IntParam parses the setting key as an integer.
+func (c Check) IntParam(key string, def int) (int, error) {
+	v := c.Param(key, "")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return n, nil
+}
+
+// FloatParam parses the setting key as a number; a trailing % is allowed so
+// thresholds read the way df prints them.
+func (c Check) FloatParam(key string, def float64) (float64, error) {
+	v := c.Param(key, "")
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return f, nil
+}
+
+// DurationParam parses the setting key as a Go duration ("500ms", "2s") or
+// a plain number of seconds.
+func (c Check) DurationParam(key string, def time.Duration) (time.Duration, error) {
+	v := c.Param(key, "")
+	if v == "" {
+		return def, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d, nil
+	}
+	if sec, err := strconv.ParseFloat(v, 64); err == nil && sec > 0 {
+		return time.Duration(sec * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("invalid %s %q", key, v)
+}
+
+// CheckType runs one kind of gobox check with the same internals as the
+// command it mirrors. Command packages register theirs from init, next to
+// their commands.
+type CheckType struct {
+	// Name is the check's "type" in the check file.
+	Name string
+	Help string
+	// Run returns a short description of what it observed; an error fails
+	// the check and is reported instead.
+	Run func(inv *Invocation, c Check) (string, error)
+}
+
+var checkTypes = struct {
+	sync.RWMutex
+	byName map[string]CheckType
+}{
+	byName: make(map[string]CheckType),
+}
+
+// RegisterCheckType adds t to the check types gobox check accepts.
+func RegisterCheckType(t CheckType) {
+	checkTypes.Lock()
+	defer checkTypes.Unlock()
+	if _, exists := checkTypes.byName[t.Name]; exists {
+		panic(fmt.Sprintf("check type already registered: %s", t.Name))
+	}
+	checkTypes.byName[t.Name] = t
+}
+
+// LookupCheckType returns the check type registered under name.
+func LookupCheckType(name string) (CheckType, bool) {
+	checkTypes.RLock()
+	defer checkTypes.RUnlock()
+	t, ok := checkTypes.byName[name]
+	return t, ok
+}
+
+// CheckTypes returns the registered check types sorted by name.
+func CheckTypes() []CheckType {
+	checkTypes.RLock()
+	defer checkTypes.RUnlock()
+	out := make([]CheckType, 0, len(checkTypes.byName))
+	for _, t := range checkTypes.byName {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
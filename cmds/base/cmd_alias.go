@@ -6,83 +6,189 @@ import (
 	"fmt"
 	"gobox/cmds/utils"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
-const goboxAliasType = "bash"
+// defaultAliasShell is used when neither --shell nor detection names a
+// supported shell; it keeps the historical bash output.
+const defaultAliasShell = "bash"
+
+// aliasShells lists the shell types --shell accepts. sh covers POSIX shells
+// such as dash and busybox ash; zsh shares their alias syntax, while fish
+// gets wrapper functions.
+var aliasShells = []string{"sh", "bash", "zsh", "fish"}
+
+// aliasParentComm returns the parent process name, which is the shell that
+// will evaluate the script in `eval "$(gobox alias)"`; tests replace it.
+var aliasParentComm = func() string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(os.Getppid()), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
 
 func init() {
-	Register(NewCommand("alias", "Print bash alias or unalias shell code", aliasCmd))
+	Register(NewCommand("alias", "Print shell alias or unalias code", aliasCmd,
+		WithFlagValues("--shell", func() []string { return aliasShells })))
 }
 
-func aliasCmd(args []string, stdout io.Writer) error {
+func aliasCmd(inv *Invocation, args []string) error {
 	fs := flag.NewFlagSet("alias", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
 	unalias := fs.Bool("u", false, "print unalias commands")
+	shell := fs.String("shell", "", "target shell: sh, bash, zsh or fish (default: detected)")
 	help := fs.Bool("h", false, "show help")
 
 	if err := utils.ParseFlagSet(fs, args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			writeAliasUsage(stdout)
+			writeAliasUsage(inv.Stdout)
 			return nil
 		}
 		return err
 	}
 	if *help {
-		writeAliasUsage(stdout)
+		writeAliasUsage(inv.Stdout)
 		return nil
 	}
 	if fs.NArg() != 0 {
 		return fmt.Errorf("unexpected arguments: %v", fs.Args())
 	}
 
-	if *unalias {
-		writeUnaliasScript(stdout)
-		return nil
+	shellType := detectAliasShell(inv)
+	if *shell != "" {
+		shellType = aliasShellType(*shell)
+		if shellType == "" {
+			return fmt.Errorf("unsupported shell %q (want sh, bash, zsh or fish)", *shell)
+		}
 	}
 
-	writeAliasScript(stdout)
+	switch {
+	case shellType == "fish" && *unalias:
+		writeFishUnaliasScript(inv.Stdout)
+	case shellType == "fish":
+		writeFishAliasScript(inv.Stdout, aliasCommands(ReadOnly(inv)))
+	case *unalias:
+		writeUnaliasScript(inv.Stdout, shellType)
+	default:
+		writeAliasScript(inv.Stdout, shellType, aliasCommands(ReadOnly(inv)))
+	}
 	return nil
 }
 
+// aliasShellType maps a shell name or path (/bin/dash, -bash) onto one of
+// aliasShells, or "" when it is not a supported shell.
+func aliasShellType(name string) string {
+	switch strings.TrimPrefix(filepath.Base(name), "-") {
+	case "bash":
+		return "bash"
+	case "zsh":
+		return "zsh"
+	case "fish":
+		return "fish"
+	case "sh", "dash", "ash", "ksh", "mksh", "busybox":
+		return "sh"
+	}
+	return ""
+}
+
+// detectAliasShell prefers the parent process, which is the shell that runs
+// the generated code, then $SHELL, then bash.
+func detectAliasShell(inv *Invocation) string {
+	if shellType := aliasShellType(aliasParentComm()); shellType != "" {
+		return shellType
+	}
+	if shellType := aliasShellType(inv.Getenv("SHELL")); shellType != "" {
+		return shellType
+	}
+	return defaultAliasShell
+}
+
 func writeAliasUsage(w io.Writer) {
-	fmt.Fprintln(w, "Usage: gobox alias [-u]")
-	fmt.Fprintln(w, "Print shell code for enabling or disabling gobox bash aliases.")
+	fmt.Fprintln(w, "Usage: gobox alias [-u] [--shell sh|bash|zsh|fish]")
+	fmt.Fprintln(w, "Print shell code for enabling or disabling gobox aliases.")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Options:")
 	fmt.Fprintln(w, "  -u            print unalias commands instead of alias commands")
+	fmt.Fprintln(w, "  --shell SHELL target shell; detected from the parent process, then $SHELL,")
+	fmt.Fprintln(w, "                falling back to bash")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Examples:")
 	fmt.Fprintln(w, "  eval \"$(gobox alias)\"")
 	fmt.Fprintln(w, "  eval \"$(gobox alias -u)\"")
+	fmt.Fprintln(w, "  gobox alias --shell fish | source")
 }
 
-func writeAliasScript(w io.Writer) {
-	fmt.Fprintln(w, "if [ -n \"${gobox_alias_type:-}\" ] && [ \"${gobox_alias_type}\" != \""+goboxAliasType+"\" ]; then")
-	_, _ = io.WriteString(w, "  printf '%s\\n' \"gobox alias: expected gobox_alias_type to be empty or "+goboxAliasType+", got ${gobox_alias_type}\" >&2\n")
-	fmt.Fprintln(w, "  false")
-	fmt.Fprintln(w, "else")
-	fmt.Fprintln(w, "  export gobox_alias_type="+goboxAliasType)
+// aliasCommands lists the registered commands that get an alias. In
+// read-only mode commands that would only be refused are left out, so the
+// native tool stays reachable under its own name.
+func aliasCommands(readOnly bool) []Command {
+	var commands []Command
 	for _, cmd := range Commands() {
-		if cmd.Name() == "alias" {
+		if helperCommands[cmd.Name()] || (readOnly && IsMutating(cmd)) {
 			continue
 		}
-		fmt.Fprintf(w, "  alias %s='gobox %s'\n", cmd.Name(), cmd.Name())
+		commands = append(commands, cmd)
 	}
-	fmt.Fprintln(w, "fi")
+	return commands
 }
 
-func writeUnaliasScript(w io.Writer) {
-	fmt.Fprintln(w, "if [ -n \"${gobox_alias_type:-}\" ] && [ \"${gobox_alias_type}\" != \""+goboxAliasType+"\" ]; then")
-	_, _ = io.WriteString(w, "  printf '%s\\n' \"gobox alias: expected gobox_alias_type to be empty or "+goboxAliasType+", got ${gobox_alias_type}\" >&2\n")
+// writeAliasGuard opens the sh-family script: gobox_alias_type must be empty
+// or already name shellType, so aliases set up for another shell are never
+// mixed with or removed by this one.
+func writeAliasGuard(w io.Writer, shellType string) {
+	fmt.Fprintln(w, "if [ -n \"${gobox_alias_type:-}\" ] && [ \"${gobox_alias_type}\" != \""+shellType+"\" ]; then")
+	_, _ = io.WriteString(w, "  printf '%s\\n' \"gobox alias: expected gobox_alias_type to be empty or "+shellType+", got ${gobox_alias_type}\" >&2\n")
 	fmt.Fprintln(w, "  false")
 	fmt.Fprintln(w, "else")
-	for _, cmd := range Commands() {
-		if cmd.Name() == "alias" {
-			continue
-		}
+}
+
+func writeAliasScript(w io.Writer, shellType string, commands []Command) {
+	writeAliasGuard(w, shellType)
+	fmt.Fprintln(w, "  export gobox_alias_type="+shellType)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  alias %s='gobox %s'\n", cmd.Name(), cmd.Name())
+	}
+	fmt.Fprintln(w, "fi")
+}
+
+func writeUnaliasScript(w io.Writer, shellType string) {
+	writeAliasGuard(w, shellType)
+	for _, cmd := range aliasCommands(false) {
 		fmt.Fprintf(w, "  unalias %s 2>/dev/null || true\n", cmd.Name())
 	}
 	fmt.Fprintln(w, "  unset gobox_alias_type")
 	fmt.Fprintln(w, "fi")
 }
+
+// writeFishAliasGuard is writeAliasGuard in fish syntax.
+func writeFishAliasGuard(w io.Writer) {
+	fmt.Fprintln(w, "if test -n \"$gobox_alias_type\"; and test \"$gobox_alias_type\" != fish")
+	_, _ = io.WriteString(w, "    printf '%s\\n' \"gobox alias: expected gobox_alias_type to be empty or fish, got $gobox_alias_type\" >&2\n")
+	fmt.Fprintln(w, "    false")
+	fmt.Fprintln(w, "else")
+}
+
+// writeFishAliasScript defines wrapper functions, which is what fish's own
+// alias builtin creates.
+func writeFishAliasScript(w io.Writer, commands []Command) {
+	writeFishAliasGuard(w)
+	fmt.Fprintln(w, "    set -gx gobox_alias_type fish")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "    function %s --wraps 'gobox %s'; gobox %s $argv; end\n", cmd.Name(), cmd.Name(), cmd.Name())
+	}
+	fmt.Fprintln(w, "end")
+}
+
+func writeFishUnaliasScript(w io.Writer) {
+	writeFishAliasGuard(w)
+	for _, cmd := range aliasCommands(false) {
+		fmt.Fprintf(w, "    functions -e %s\n", cmd.Name())
+	}
+	fmt.Fprintln(w, "    set -e gobox_alias_type")
+	fmt.Fprintln(w, "end")
+}
@@ -2,7 +2,7 @@ package base
 
 import (
 	"bytes"
-	"io"
+	"os/exec"
 	"strings"
 	"sync"
 	"testing"
@@ -12,21 +12,30 @@ var registerAliasTestCommands sync.Once
 
 func ensureAliasTestCommands() {
 	registerAliasTestCommands.Do(func() {
-		Register(NewCommand("zz_test_alias_cmd", "test alias command", func(args []string, stdout io.Writer) error {
+		Register(NewCommand("zz_test_alias_cmd", "test alias command", func(inv *Invocation, args []string) error {
 			return nil
 		}))
-		Register(NewCommand("zz_test_alias_extra", "test alias extra command", func(args []string, stdout io.Writer) error {
+		Register(NewCommand("zz_test_alias_extra", "test alias extra command", func(inv *Invocation, args []string) error {
 			return nil
 		}))
 	})
 }
 
+// stubAliasParent replaces parent-shell detection for the duration of a test.
+func stubAliasParent(t *testing.T, comm string) {
+	t.Helper()
+	orig := aliasParentComm
+	aliasParentComm = func() string { return comm }
+	t.Cleanup(func() { aliasParentComm = orig })
+}
+
 func TestAliasCmd(t *testing.T) {
 	ensureAliasTestCommands()
+	stubAliasParent(t, "")
 
 	var out bytes.Buffer
 
-	if err := aliasCmd(nil, &out); err != nil {
+	if err := aliasCmd(&Invocation{Stdout: &out, Env: []string{}}, nil); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -44,10 +53,11 @@ func TestAliasCmd(t *testing.T) {
 
 func TestAliasCmdUnalias(t *testing.T) {
 	ensureAliasTestCommands()
+	stubAliasParent(t, "")
 
 	var out bytes.Buffer
 
-	if err := aliasCmd([]string{"-u"}, &out); err != nil {
+	if err := aliasCmd(&Invocation{Stdout: &out, Env: []string{}}, []string{"-u"}); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -63,7 +73,7 @@ func TestAliasCmdUnalias(t *testing.T) {
 func TestAliasCmdUsage(t *testing.T) {
 	var out bytes.Buffer
 
-	if err := aliasCmd([]string{"-h"}, &out); err != nil {
+	if err := aliasCmd(&Invocation{Stdout: &out}, []string{"-h"}); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -71,3 +81,114 @@ func TestAliasCmdUsage(t *testing.T) {
 		t.Fatalf("expected alias usage, got %q", out.String())
 	}
 }
+
+func TestAliasCmdDetectsShell(t *testing.T) {
+	cases := []struct {
+		parent, shellEnv, want string
+	}{
+		{"dash", "/bin/zsh", "sh"},
+		{"-bash", "", "bash"},
+		{"fish", "", "fish"},
+		{"go", "/usr/bin/zsh", "zsh"},
+		{"", "/bin/ash", "sh"},
+		{"python3", "/usr/bin/tcsh", "bash"},
+	}
+	for _, tc := range cases {
+		stubAliasParent(t, tc.parent)
+		if got := detectAliasShell(&Invocation{Env: []string{"SHELL=" + tc.shellEnv}}); got != tc.want {
+			t.Fatalf("parent %q SHELL %q: expected %s, got %s", tc.parent, tc.shellEnv, tc.want, got)
+		}
+	}
+}
+
+func TestAliasCmdShellFlagOverridesDetection(t *testing.T) {
+	ensureAliasTestCommands()
+	stubAliasParent(t, "fish")
+
+	var out bytes.Buffer
+	if err := aliasCmd(&Invocation{Stdout: &out}, []string{"--shell", "/usr/bin/zsh"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "export gobox_alias_type=zsh\n") {
+		t.Fatalf("expected zsh alias type, got %q", out.String())
+	}
+	if err := aliasCmd(&Invocation{Stdout: &out}, []string{"--shell", "csh"}); err == nil || !strings.Contains(err.Error(), "unsupported shell") {
+		t.Fatalf("expected unsupported shell error, got %v", err)
+	}
+}
+
+func TestAliasCmdBashOutputIsUnchanged(t *testing.T) {
+	ensureAliasTestCommands()
+
+	var out bytes.Buffer
+	if err := aliasCmd(&Invocation{Stdout: &out}, []string{"--shell", "bash"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "if [ -n \"${gobox_alias_type:-}\" ] && [ \"${gobox_alias_type}\" != \"bash\" ]; then\n" +
+		"  printf '%s\\n' \"gobox alias: expected gobox_alias_type to be empty or bash, got ${gobox_alias_type}\" >&2\n" +
+		"  false\n" +
+		"else\n" +
+		"  export gobox_alias_type=bash\n"
+	if !strings.HasPrefix(out.String(), want) || !strings.HasSuffix(out.String(), "  alias zz_test_alias_extra='gobox zz_test_alias_extra'\nfi\n") {
+		t.Fatalf("bash alias script changed:\n%s", out.String())
+	}
+}
+
+func TestAliasCmdFish(t *testing.T) {
+	ensureAliasTestCommands()
+
+	var out bytes.Buffer
+	if err := aliasCmd(&Invocation{Stdout: &out}, []string{"--shell", "fish"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, want := range []string{
+		"if test -n \"$gobox_alias_type\"; and test \"$gobox_alias_type\" != fish\n",
+		"    set -gx gobox_alias_type fish\n",
+		"    function zz_test_alias_cmd --wraps 'gobox zz_test_alias_cmd'; gobox zz_test_alias_cmd $argv; end\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("fish alias script missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := aliasCmd(&Invocation{Stdout: &out}, []string{"--shell", "fish", "-u"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "    functions -e zz_test_alias_cmd\n") || !strings.Contains(out.String(), "    set -e gobox_alias_type\n") {
+		t.Fatalf("unexpected fish unalias script:\n%s", out.String())
+	}
+}
+
+// TestAliasCmdScriptsRunInPOSIXShells evaluates the sh scripts in whatever
+// POSIX shells are installed, including the guard against another type.
+func TestAliasCmdScriptsRunInPOSIXShells(t *testing.T) {
+	ensureAliasTestCommands()
+	var aliasOut, unaliasOut bytes.Buffer
+	if err := aliasCmd(&Invocation{Stdout: &aliasOut}, []string{"--shell", "sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := aliasCmd(&Invocation{Stdout: &unaliasOut}, []string{"--shell", "sh", "-u"}); err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	for _, shell := range []string{"sh", "dash", "ash", "bash", "zsh"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			continue
+		}
+		ran = true
+		script := aliasOut.String() + "alias zz_test_alias_cmd\n" + unaliasOut.String() +
+			"alias zz_test_alias_cmd 2>/dev/null && echo still-aliased\n" +
+			"gobox_alias_type=fish\n" + aliasOut.String() + "echo guard=$?\n"
+		out, _ := exec.Command(path, "-c", script).CombinedOutput()
+		got := string(out)
+		if !strings.Contains(got, "gobox zz_test_alias_cmd") || strings.Contains(got, "still-aliased") ||
+			!strings.Contains(got, "expected gobox_alias_type to be empty or sh, got fish") || !strings.Contains(got, "guard=1") {
+			t.Fatalf("%s: unexpected result:\n%s", shell, got)
+		}
+	}
+	if !ran {
+		t.Skip("no POSIX shell available")
+	}
+}
@@ -0,0 +1,322 @@
+package base
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gobox/cmds/utils"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(NewCommand("completion", "Print shell completion scripts for bash, zsh or fish", completionCmd))
+}
+
+func completionCmd(inv *Invocation, args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	help := fs.Bool("h", false, "show help")
+
+	if err := utils.ParseFlagSet(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			writeCompletionUsage(inv.Stdout)
+			return nil
+		}
+		return err
+	}
+	if *help {
+		writeCompletionUsage(inv.Stdout)
+		return nil
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing shell (want bash, zsh or fish)")
+	}
+
+	shell, rest := fs.Arg(0), fs.Args()[1:]
+	if shell == "values" {
+		return writeCompletionValues(inv.Stdout, rest)
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", rest)
+	}
+	switch shell {
+	case "bash":
+		writeBashCompletion(inv.Stdout)
+	case "zsh":
+		writeZshCompletion(inv.Stdout)
+	case "fish":
+		writeFishCompletion(inv.Stdout)
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", shell)
+	}
+	return nil
+}
+
+func writeCompletionUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox completion bash|zsh|fish")
+	fmt.Fprintln(w, "Print a shell completion script covering every gobox command and its options.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Option values such as PIDs, signal names, interface names and sort keys are")
+	fmt.Fprintln(w, "completed at run time through 'gobox completion values COMMAND FLAG'.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  source <(gobox completion bash)")
+	fmt.Fprintln(w, "  gobox completion zsh > \"${fpath[1]}/_gobox\"")
+	fmt.Fprintln(w, "  gobox completion fish > ~/.config/fish/completions/gobox.fish")
+}
+
+// writeCompletionValues prints the candidate values of one command flag, one
+// per line. It backs the dynamic part of every generated script, so unknown
+// commands or flags print nothing rather than an error.
+func writeCompletionValues(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: gobox completion values COMMAND FLAG")
+	}
+	cmd, ok := Lookup(args[0])
+	if !ok {
+		return nil
+	}
+	for _, f := range CommandFlags(cmd) {
+		if f.Name != args[1] || f.Values == nil {
+			continue
+		}
+		for _, value := range f.Values() {
+			fmt.Fprintln(w, value)
+		}
+	}
+	return nil
+}
+
+// completionSpec is the per-command data every script embeds.
+type completionSpec struct {
+	cmd     Command
+	flags   []Flag
+	values  []string // flags taking a value
+	dynamic []string // flags whose values come from `gobox completion values`
+}
+
+func completionSpecs() []completionSpec {
+	var specs []completionSpec
+	for _, cmd := range Commands() {
+		spec := completionSpec{cmd: cmd, flags: CommandFlags(cmd)}
+		for _, f := range spec.flags {
+			if f.TakesValue {
+				spec.values = append(spec.values, f.Name)
+			}
+			if f.Values != nil {
+				spec.dynamic = append(spec.dynamic, f.Name)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+func flagNames(flags []Flag) []string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// shellQuote wraps s in single quotes for sh-family shells and fish.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// spaced renders a word list as " a b c " for the scripts' membership tests.
+func spaced(words []string) string {
+	return " " + strings.Join(words, " ") + " "
+}
+
+func writeBashCompletion(w io.Writer) {
+	specs := completionSpecs()
+	names := []string{"--no-config", "--output", "--proc-root", "--sys-root"}
+	for _, spec := range specs {
+		names = append(names, spec.cmd.Name())
+	}
+
+	fmt.Fprintln(w, "# bash completion for gobox; load with: source <(gobox completion bash)")
+	fmt.Fprintln(w, "_gobox() {")
+	fmt.Fprintln(w, "  local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}")
+	fmt.Fprintln(w, "  local i=1 cmd= flags= values= dynamic=")
+	fmt.Fprintln(w, "  while [ \"$i\" -lt \"$COMP_CWORD\" ]; do")
+	fmt.Fprintln(w, "    case ${COMP_WORDS[i]} in")
+	fmt.Fprintln(w, "      --output|--proc-root|--sys-root) i=$((i + 2)) ;;")
+	fmt.Fprintln(w, "      --output=*|--proc-root=*|--sys-root=*|--no-config) i=$((i + 1)) ;;")
+	fmt.Fprintln(w, "      *) cmd=${COMP_WORDS[i]}; break ;;")
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "  done")
+	fmt.Fprintln(w, "  if [ -z \"$cmd\" ]; then")
+	fmt.Fprintln(w, "    if [ \"$prev\" = --output ]; then")
+	fmt.Fprintf(w, "      COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(OutputFormats(), " ")))
+	fmt.Fprintln(w, "    elif [ \"$prev\" = --proc-root ] || [ \"$prev\" = --sys-root ]; then")
+	fmt.Fprintln(w, "      COMPREPLY=($(compgen -d -- \"$cur\"))")
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintf(w, "      COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(names, " ")))
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  case $cmd in")
+	for _, spec := range specs {
+		fmt.Fprintf(w, "    %s) flags=%s; values=%s; dynamic=%s ;;\n", spec.cmd.Name(),
+			shellQuote(strings.Join(flagNames(spec.flags), " ")), shellQuote(spaced(spec.values)), shellQuote(spaced(spec.dynamic)))
+	}
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "  if [[ $values == *\" $prev \"* ]]; then")
+	fmt.Fprintln(w, "    if [[ $dynamic == *\" $prev \"* ]]; then")
+	fmt.Fprintln(w, "      COMPREPLY=($(compgen -W \"$(\"${COMP_WORDS[0]}\" completion values \"$cmd\" \"$prev\" 2>/dev/null)\" -- \"$cur\"))")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  if [[ $cur == [-+]* ]]; then")
+	fmt.Fprintln(w, "    COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o default -F _gobox gobox")
+}
+
+// zshDescribe formats a name:description entry for _describe.
+func zshDescribe(name, description string) string {
+	return shellQuote(strings.ReplaceAll(name, ":", `\:`) + ":" + description)
+}
+
+func writeZshCompletion(w io.Writer) {
+	specs := completionSpecs()
+
+	fmt.Fprintln(w, "#compdef gobox")
+	fmt.Fprintln(w, "# zsh completion for gobox; install as _gobox in $fpath or load with: source <(gobox completion zsh)")
+	fmt.Fprintln(w, "_gobox() {")
+	fmt.Fprintln(w, "  local cmd prev=${words[CURRENT-1]} cur=${words[CURRENT]} values dynamic")
+	fmt.Fprintln(w, "  local -a commands flags candidates")
+	fmt.Fprintln(w, "  local -i i=2")
+	fmt.Fprintln(w, "  while (( i < CURRENT )); do")
+	fmt.Fprintln(w, "    case ${words[i]} in")
+	fmt.Fprintln(w, "      --output|--proc-root|--sys-root) (( i += 2 )) ;;")
+	fmt.Fprintln(w, "      --output=*|--proc-root=*|--sys-root=*|--no-config) (( i += 1 )) ;;")
+	fmt.Fprintln(w, "      *) cmd=${words[i]}; break ;;")
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "  done")
+	fmt.Fprintln(w, "  if [[ -z $cmd ]]; then")
+	fmt.Fprintln(w, "    if [[ $prev == --output ]]; then")
+	fmt.Fprintf(w, "      compadd -- %s\n", strings.Join(OutputFormats(), " "))
+	fmt.Fprintln(w, "      return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    if [[ $prev == --proc-root || $prev == --sys-root ]]; then")
+	fmt.Fprintln(w, "      _directories")
+	fmt.Fprintln(w, "      return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    commands=(")
+	for _, spec := range specs {
+		fmt.Fprintf(w, "      %s\n", zshDescribe(spec.cmd.Name(), spec.cmd.Help()))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    [[ $cur == -* ]] && compadd -- --no-config --output --proc-root --sys-root")
+	fmt.Fprintln(w, "    _describe -t commands 'gobox command' commands")
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  case $cmd in")
+	for _, spec := range specs {
+		fmt.Fprintf(w, "    %s)\n", spec.cmd.Name())
+		fmt.Fprintln(w, "      flags=(")
+		for _, f := range spec.flags {
+			fmt.Fprintf(w, "        %s\n", zshDescribe(f.Name, f.Usage))
+		}
+		fmt.Fprintln(w, "      )")
+		fmt.Fprintf(w, "      values=%s dynamic=%s\n", shellQuote(spaced(spec.values)), shellQuote(spaced(spec.dynamic)))
+		fmt.Fprintln(w, "      ;;")
+	}
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "  if [[ $values == *\" $prev \"* ]]; then")
+	fmt.Fprintln(w, "    if [[ $dynamic == *\" $prev \"* ]]; then")
+	fmt.Fprintln(w, "      candidates=(${(f)\"$(${words[1]} completion values $cmd $prev 2>/dev/null)\"})")
+	fmt.Fprintln(w, "      compadd -a candidates")
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintln(w, "      _files")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  if [[ $cur == [-+]* ]]; then")
+	fmt.Fprintln(w, "    _describe -t options option flags")
+	fmt.Fprintln(w, "  else")
+	fmt.Fprintln(w, "    _files")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "if [ \"$funcstack[1]\" = _gobox ]; then")
+	fmt.Fprintln(w, "  _gobox \"$@\"")
+	fmt.Fprintln(w, "else")
+	fmt.Fprintln(w, "  compdef _gobox gobox")
+	fmt.Fprintln(w, "fi")
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for gobox; install as ~/.config/fish/completions/gobox.fish")
+	fmt.Fprintln(w, "function __gobox_command")
+	fmt.Fprintln(w, "    set -l tokens (commandline -opc)")
+	fmt.Fprintln(w, "    set -e tokens[1]")
+	fmt.Fprintln(w, "    while set -q tokens[1]")
+	fmt.Fprintln(w, "        switch $tokens[1]")
+	fmt.Fprintln(w, "            case --output --proc-root --sys-root")
+	fmt.Fprintln(w, "                set -e tokens[1]")
+	fmt.Fprintln(w, "                set -q tokens[1]; and set -e tokens[1]")
+	fmt.Fprintln(w, "            case '--output=*' '--proc-root=*' '--sys-root=*' --no-config")
+	fmt.Fprintln(w, "                set -e tokens[1]")
+	fmt.Fprintln(w, "            case '*'")
+	fmt.Fprintln(w, "                echo $tokens[1]")
+	fmt.Fprintln(w, "                return 0")
+	fmt.Fprintln(w, "        end")
+	fmt.Fprintln(w, "    end")
+	fmt.Fprintln(w, "    return 1")
+	fmt.Fprintln(w, "end")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "function __gobox_using_command")
+	fmt.Fprintln(w, "    set -l cmd (__gobox_command); and test \"$cmd\" = $argv[1]")
+	fmt.Fprintln(w, "end")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "complete -c gobox -n 'not __gobox_command' -l no-config -d 'ignore configured defaults'")
+	fmt.Fprintf(w, "complete -c gobox -n 'not __gobox_command' -l output -x -a %s -d 'output format'\n", fishQuote(strings.Join(OutputFormats(), " ")))
+	fmt.Fprintln(w, "complete -c gobox -n 'not __gobox_command' -l proc-root -x -a '(__fish_complete_directories)' -d 'procfs root'")
+	fmt.Fprintln(w, "complete -c gobox -n 'not __gobox_command' -l sys-root -x -a '(__fish_complete_directories)' -d 'sysfs root'")
+	specs := completionSpecs()
+	for _, spec := range specs {
+		fmt.Fprintf(w, "complete -c gobox -n 'not __gobox_command' -f -a %s -d %s\n", spec.cmd.Name(), fishQuote(spec.cmd.Help()))
+	}
+	for _, spec := range specs {
+		fmt.Fprintln(w)
+		name := spec.cmd.Name()
+		for _, f := range spec.flags {
+			fmt.Fprintf(w, "complete -c gobox -n '__gobox_using_command %s' %s", name, fishFlagSpec(f.Name))
+			switch {
+			case f.Values != nil:
+				fmt.Fprintf(w, " -x -a %s", fishQuote(fmt.Sprintf("(gobox completion values %s %s)", name, f.Name)))
+			case f.TakesValue:
+				fmt.Fprint(w, " -r")
+			}
+			if f.Usage != "" {
+				fmt.Fprintf(w, " -d %s", fishQuote(f.Usage))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// fishFlagSpec maps a flag spelling onto complete's -s, -l and -o options;
+// anything else (dig's +short) is offered as a plain argument.
+func fishFlagSpec(name string) string {
+	switch {
+	case strings.HasPrefix(name, "--"):
+		return "-l " + name[2:]
+	case strings.HasPrefix(name, "-") && len(name) == 2:
+		return "-s " + name[1:]
+	case strings.HasPrefix(name, "-"):
+		return "-o " + name[1:]
+	}
+	return "-a " + fishQuote(name)
+}
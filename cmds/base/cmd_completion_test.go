@@ -0,0 +1,178 @@
+package base
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gobox/cmds/utils"
+)
+
+var registerCompletionTestCommands sync.Once
+
+func ensureCompletionTestCommands() {
+	registerCompletionTestCommands.Do(func() {
+		Register(NewCommand("zz_comp_flagset", "test flagset command", func(inv *Invocation, args []string) error {
+			fs := flag.NewFlagSet("zz_comp_flagset", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fs.Bool("v", false, "verbose")
+			fs.String("sort", "", "sort key")
+			fs.Int("max-depth", 0, "depth")
+			return utils.ParseFlagSet(fs, args)
+		}, WithTabularOutput(), WithFlagValues("--sort", func() []string { return []string{"name", "size"} })))
+		Register(NewCommand("zz_comp_manual", "test manual command", func(inv *Invocation, args []string) error {
+			panic("hand-written parsers must not be run for completion")
+		}, WithFlags(Flag{Name: "-n", TakesValue: true, Usage: "count"}, Flag{Name: "+short", Usage: "short"})))
+	})
+}
+
+func runCompletion(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	ensureCompletionTestCommands()
+	var out bytes.Buffer
+	err := completionCmd(&Invocation{Stdout: &out}, args)
+	return out.String(), err
+}
+
+func TestCommandFlagsDiscoversFlagSet(t *testing.T) {
+	ensureCompletionTestCommands()
+	cmd, _ := Lookup("zz_comp_flagset")
+	var got []string
+	for _, f := range CommandFlags(cmd) {
+		name := f.Name
+		if f.TakesValue {
+			name += "="
+		}
+		if f.Values != nil {
+			name += "*"
+		}
+		got = append(got, name)
+	}
+	if want := []string{"--max-depth=", "--output=*", "--sort=*", "-v"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected flags %q, want %q", got, want)
+	}
+}
+
+func TestCommandFlagsUsesDeclaredFlags(t *testing.T) {
+	ensureCompletionTestCommands()
+	cmd, _ := Lookup("zz_comp_manual")
+	flags := CommandFlags(cmd)
+	if len(flags) != 2 || flags[0].Name != "+short" || flags[1].Name != "-n" || !flags[1].TakesValue {
+		t.Fatalf("unexpected declared flags %+v", flags)
+	}
+}
+
+func TestClassifierFlags(t *testing.T) {
+	short := func(c byte) (bool, bool) { return c == 'o' || c == '4', c == 'o' }
+	flags := ClassifierFlags(short, map[string]bool{"output": true})
+	var got []string
+	for _, f := range flags {
+		got = append(got, f.Name)
+	}
+	if !reflect.DeepEqual(got, []string{"-4", "-o", "--output"}) || !flags[1].TakesValue || flags[0].TakesValue {
+		t.Fatalf("unexpected classifier flags %+v", flags)
+	}
+}
+
+func TestCompletionValues(t *testing.T) {
+	out, err := runCompletion(t, "values", "zz_comp_flagset", "--sort")
+	if err != nil || out != "name\nsize\n" {
+		t.Fatalf("unexpected values %q (%v)", out, err)
+	}
+	out, err = runCompletion(t, "values", "zz_comp_flagset", "--output")
+	if err != nil || !strings.Contains(out, "ndjson\n") {
+		t.Fatalf("expected output formats, got %q (%v)", out, err)
+	}
+	for _, args := range [][]string{{"values", "zz_comp_flagset", "-v"}, {"values", "no-such-command", "-x"}} {
+		if out, err := runCompletion(t, args...); err != nil || out != "" {
+			t.Fatalf("%q: expected no values, got %q (%v)", args, out, err)
+		}
+	}
+}
+
+func TestCompletionRejectsUnknownShell(t *testing.T) {
+	if _, err := runCompletion(t, "tcsh"); err == nil || !strings.Contains(err.Error(), "unsupported shell") {
+		t.Fatalf("expected unsupported shell error, got %v", err)
+	}
+	if _, err := runCompletion(t); err == nil {
+		t.Fatal("expected missing shell error")
+	}
+}
+
+func TestCompletionScriptsListCommandsAndFlags(t *testing.T) {
+	bash, err := runCompletion(t, "bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"zz_comp_flagset) flags='--max-depth --output --sort -v'; values=' --max-depth --output --sort '; dynamic=' --output --sort ' ;;",
+		"zz_comp_manual) flags='+short -n'; values=' -n '; dynamic='  ' ;;",
+		"complete -o default -F _gobox gobox",
+	} {
+		if !strings.Contains(bash, want) {
+			t.Fatalf("bash script missing %q", want)
+		}
+	}
+
+	zsh, err := runCompletion(t, "zsh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(zsh, "#compdef gobox\n") || !strings.Contains(zsh, "'zz_comp_flagset:test flagset command'") || !strings.Contains(zsh, "'--sort:sort key'") {
+		t.Fatalf("unexpected zsh script:\n%s", zsh)
+	}
+
+	fish, err := runCompletion(t, "fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"complete -c gobox -n 'not __gobox_command' -f -a zz_comp_flagset -d 'test flagset command'",
+		"complete -c gobox -n '__gobox_using_command zz_comp_flagset' -l sort -x -a '(gobox completion values zz_comp_flagset --sort)' -d 'sort key'",
+		"complete -c gobox -n '__gobox_using_command zz_comp_flagset' -l max-depth -r -d 'depth'",
+		"complete -c gobox -n '__gobox_using_command zz_comp_manual' -a '+short' -d 'short'",
+	} {
+		if !strings.Contains(fish, want) {
+			t.Fatalf("fish script missing %q", want)
+		}
+	}
+}
+
+func TestCompletionBashScriptCompletes(t *testing.T) {
+	bashPath, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	script, err := runCompletion(t, "bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe := script + `
+t() { COMP_WORDS=("$@"); COMP_CWORD=$((${#COMP_WORDS[@]}-1)); COMPREPLY=(); _gobox; echo "${COMPREPLY[*]}"; }
+t gobox zz_comp_f
+t gobox --output json zz_comp_m
+t gobox --proc-root /proc --sys-root=/sys zz_comp_m
+t gobox zz_comp_flagset --m
+t gobox zz_comp_manual +
+`
+	out, err := exec.Command(bashPath, "-c", probe).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, out)
+	}
+	if want := "zz_comp_flagset\nzz_comp_manual\nzz_comp_manual\n--max-depth\n+short\n"; string(out) != want {
+		t.Fatalf("unexpected completions %q, want %q", out, want)
+	}
+}
+
+func TestCompletionIsHelperCommand(t *testing.T) {
+	var out bytes.Buffer
+	writeAliasScript(&out, "bash", aliasCommands(false))
+	if strings.Contains(out.String(), "alias completion=") {
+		t.Fatal("expected completion to be left out of the alias script")
+	}
+}
@@ -0,0 +1,141 @@
+package base
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gobox/cmds/utils"
+)
+
+func init() {
+	Register(NewCommand("config", "Show configured command defaults and aliases", configCmd,
+		WithTabularOutput()))
+}
+
+func configCmd(inv *Invocation, args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	help := fs.Bool("h", false, "show help")
+
+	if err := utils.ParseFlagSet(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			writeConfigUsage(inv.Stdout)
+			return nil
+		}
+		return err
+	}
+	if *help {
+		writeConfigUsage(inv.Stdout)
+		return nil
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "show" {
+		return fmt.Errorf("usage: gobox config show")
+	}
+
+	table := configTable(inv.Config)
+	if utils.IsStructuredOutput(inv.Output) {
+		return table.Render(inv.Stdout, inv.Output)
+	}
+	switch {
+	case inv.Config == nil:
+		fmt.Fprintln(inv.Stdout, "# config disabled by --no-config")
+		return nil
+	case inv.Config.Path == "":
+		fmt.Fprintln(inv.Stdout, "# no config file")
+	default:
+		fmt.Fprintln(inv.Stdout, "# config file:", inv.Config.Path)
+	}
+	printConfigTable(inv.Stdout, table)
+	return nil
+}
+
+func writeConfigUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox config show")
+	fmt.Fprintln(w, "Print the default options and aliases each command runs with, and their source.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Config file: $GOBOX_CONFIG, ~/.config/gobox/config or /etc/gobox.conf")
+	fmt.Fprintln(w, "  [defaults]")
+	fmt.Fprintln(w, "  ps = -ww")
+	fmt.Fprintln(w, "  [alias]")
+	fmt.Fprintln(w, "  psmem = ps --sort rss -r -n 20")
+	fmt.Fprintln(w, "GOBOX_<CMD>_OPTS replaces the file defaults of one command;")
+	fmt.Fprintln(w, "gobox --no-config ignores both.")
+}
+
+// configTable lists defaults first, then aliases, each sorted by name.
+func configTable(cfg *Config) *utils.Table {
+	table := utils.NewTable("name", "kind", "args", "source")
+	if cfg == nil {
+		return table
+	}
+	for _, name := range cfg.DefaultNames() {
+		entry := cfg.Defaults[name]
+		table.Append(name, "defaults", configWords(entry.Args), entry.Source)
+	}
+	for _, name := range aliasNames(cfg) {
+		alias := cfg.alias(name)
+		table.Append(alias.Name, "alias", configWords(alias.Args), alias.Source)
+	}
+	return table
+}
+
+func aliasNames(cfg *Config) []string {
+	names := make([]string, 0, len(cfg.Aliases))
+	for _, alias := range cfg.Aliases {
+		names = append(names, alias.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configWords joins words the way the config file spells them, quoting only
+// the words that need it.
+func configWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = word
+		if word == "" || strings.ContainsAny(word, " \t'\"\\#") {
+			quoted[i] = shellQuote(word)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+func printConfigTable(w io.Writer, table *utils.Table) {
+	widths := make([]int, len(table.Columns)-1)
+	for i := range widths {
+		widths[i] = len(table.Columns[i])
+		for _, row := range table.Rows {
+			if n := len(row[i].(string)); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	printRow := func(values []string) {
+		var line strings.Builder
+		for i, value := range values {
+			if i < len(widths) {
+				fmt.Fprintf(&line, "%-*s  ", widths[i], value)
+			} else {
+				line.WriteString(value)
+			}
+		}
+		fmt.Fprintln(w, line.String())
+	}
+	header := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		header[i] = strings.ToUpper(column)
+	}
+	printRow(header)
+	for _, row := range table.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			values[i] = v.(string)
+		}
+		printRow(values)
+	}
+}
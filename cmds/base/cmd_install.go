@@ -0,0 +1,165 @@
+package base
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gobox/cmds/utils"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// helperCommands lists gobox-only helper commands that make no sense as
+// standalone applet names and would shadow unrelated system tools, so neither
+// install nor alias expose them under their bare name. sh in particular is a
+// small command language, not a POSIX shell, and must never replace /bin/sh,
+// and init must never replace /sbin/init.
+var helperCommands = map[string]bool{
+	"alias":      true,
+	"completion": true,
+	"config":     true,
+	"init":       true,
+	"install":    true,
+	"remote":     true,
+	"serve":      true,
+	"sh":         true,
+}
+
+// IsHelper reports whether cmd is one of the helper commands install and
+// alias leave out.
+func IsHelper(cmd Command) bool {
+	return helperCommands[cmd.Name()]
+}
+
+// executablePath resolves the running gobox binary; tests replace it.
+var executablePath = func() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+func init() {
+	Register(NewCommand("install", "Create per-command symlinks or hardlinks to gobox", installCmd, WithMutating()))
+}
+
+func installCmd(inv *Invocation, args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	symlinks := fs.Bool("symlinks", false, "create symbolic links (default)")
+	fs.BoolVar(symlinks, "s", false, "create symbolic links (default)")
+	hardlinks := fs.Bool("hardlinks", false, "create hard links")
+	force := fs.Bool("f", false, "replace existing files")
+	verbose := fs.Bool("v", false, "print each created link")
+	help := fs.Bool("h", false, "show help")
+
+	if err := utils.ParseFlagSet(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			writeInstallUsage(inv.Stdout)
+			return nil
+		}
+		return err
+	}
+	if *help {
+		writeInstallUsage(inv.Stdout)
+		return nil
+	}
+	if *symlinks && *hardlinks {
+		return fmt.Errorf("--symlinks and --hardlinks are mutually exclusive")
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected exactly one target directory")
+	}
+	dir := inv.Path(fs.Arg(0))
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: not a directory", dir)
+	}
+
+	exe, err := executablePath()
+	if err != nil {
+		return fmt.Errorf("cannot locate gobox executable: %w", err)
+	}
+
+	link, kind := os.Symlink, "symlink"
+	if *hardlinks {
+		link, kind = os.Link, "hardlink"
+	}
+	var failed int
+	for _, cmd := range Commands() {
+		name := cmd.Name()
+		// A link named after a config alias would start gobox before the
+		// config that defines it is read, so aliases stay subcommands, and
+		// plugins already have an executable of their own.
+		if helperCommands[name] || IsConfigAlias(cmd) || IsPlugin(cmd) {
+			continue
+		}
+		target := filepath.Join(dir, name)
+		if same, err := sameFile(target, exe); err == nil && same {
+			continue
+		}
+		if _, err := os.Lstat(target); err == nil {
+			if !*force {
+				fmt.Fprintf(inv.Stderr, "install: skipping %s: file exists\n", target)
+				continue
+			}
+			err := os.Remove(target)
+			Audit(inv, "delete", target, err)
+			if err != nil {
+				fmt.Fprintf(inv.Stderr, "install: %v\n", err)
+				failed++
+				continue
+			}
+		}
+		err := link(exe, target)
+		Audit(inv, "link", target, err, "kind", kind, "to", exe)
+		if err != nil {
+			fmt.Fprintf(inv.Stderr, "install: %v\n", err)
+			failed++
+			continue
+		}
+		if *verbose {
+			fmt.Fprintf(inv.Stdout, "%s -> %s\n", target, exe)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to create %d link(s)", failed)
+	}
+	return nil
+}
+
+// sameFile reports whether path already resolves to the gobox binary, so a
+// repeated install is a no-op instead of a "file exists" complaint.
+func sameFile(path, exe string) (bool, error) {
+	a, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	b, err := os.Stat(exe)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(a, b), nil
+}
+
+func writeInstallUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox install [--symlinks|--hardlinks] [-f] [-v] DIR")
+	fmt.Fprintln(w, "Create one link per registered command in DIR so that tools can be")
+	fmt.Fprintln(w, "invoked by name (ps, grep, curl, ...) without the gobox prefix.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -s, --symlinks  create symbolic links to the gobox binary (default)")
+	fmt.Fprintln(w, "  --hardlinks     create hard links instead of symbolic links")
+	fmt.Fprintln(w, "  -f              replace existing files with the same name")
+	fmt.Fprintln(w, "  -v              print each created link")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox install /usr/local/bin")
+	fmt.Fprintln(w, "  gobox install --hardlinks -f /bin")
+}
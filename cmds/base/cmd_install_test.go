@@ -0,0 +1,152 @@
+package base
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func withInstallExecutable(t *testing.T, path string) {
+	t.Helper()
+	old := executablePath
+	executablePath = func() (string, error) { return path, nil }
+	t.Cleanup(func() { executablePath = old })
+}
+
+func fakeGoboxBinary(t *testing.T, dir string) string {
+	t.Helper()
+	exe := filepath.Join(dir, "gobox")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func TestInstallCmdCreatesSymlinks(t *testing.T) {
+	ensureAliasTestCommands()
+	root := t.TempDir()
+	exe := fakeGoboxBinary(t, root)
+	withInstallExecutable(t, exe)
+	dir := filepath.Join(root, "bin")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := installCmd(&Invocation{Stdout: &out, Stderr: &out}, []string{dir}); err != nil {
+		t.Fatalf("expected no error, got %v (%s)", err, out.String())
+	}
+
+	target, err := os.Readlink(filepath.Join(dir, "zz_test_alias_cmd"))
+	if err != nil {
+		t.Fatalf("expected symlink for registered command: %v", err)
+	}
+	if target != exe {
+		t.Fatalf("expected link to %s, got %s", exe, target)
+	}
+	for _, skipped := range []string{"alias", "install"} {
+		if _, err := os.Lstat(filepath.Join(dir, skipped)); !os.IsNotExist(err) {
+			t.Fatalf("did not expect a link for %s, got err=%v", skipped, err)
+		}
+	}
+}
+
+func TestInstallCmdWritesAuditLog(t *testing.T) {
+	ensureAliasTestCommands()
+	root := t.TempDir()
+	exe := fakeGoboxBinary(t, root)
+	withInstallExecutable(t, exe)
+	dir := filepath.Join(root, "bin")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(root, "audit.log")
+	inv := &Invocation{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}, Env: []string{AuditEnv + "=" + logPath}}
+	if err := installCmd(inv, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "zz_test_alias_cmd")
+	for _, rec := range readAuditRecords(t, logPath) {
+		if rec.Action == "link" && rec.Target == target {
+			if rec.Detail["kind"] != "symlink" || rec.Detail["to"] != exe || rec.Outcome != "ok" {
+				t.Fatalf("unexpected audit record: %+v", rec)
+			}
+			return
+		}
+	}
+	t.Fatalf("no link record for %s", target)
+}
+
+func TestInstallCmdHardlinks(t *testing.T) {
+	ensureAliasTestCommands()
+	root := t.TempDir()
+	exe := fakeGoboxBinary(t, root)
+	withInstallExecutable(t, exe)
+	dir := filepath.Join(root, "bin")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := installCmd(&Invocation{Stdout: &out, Stderr: &out}, []string{"--hardlinks", dir}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	linkInfo, err := os.Lstat(filepath.Join(dir, "zz_test_alias_cmd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkInfo.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("expected a hard link, got symlink")
+	}
+	exeInfo, err := os.Stat(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(linkInfo, exeInfo) {
+		t.Fatalf("expected hard link to share the gobox inode")
+	}
+}
+
+func TestInstallCmdExistingFileNeedsForce(t *testing.T) {
+	ensureAliasTestCommands()
+	root := t.TempDir()
+	exe := fakeGoboxBinary(t, root)
+	withInstallExecutable(t, exe)
+	dir := filepath.Join(root, "bin")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(dir, "zz_test_alias_cmd")
+	if err := os.WriteFile(existing, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := installCmd(&Invocation{Stdout: &out, Stderr: &out}, []string{dir}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "file exists") {
+		t.Fatalf("expected skip notice, got %q", out.String())
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "keep" {
+		t.Fatalf("expected existing file to be preserved without -f")
+	}
+
+	out.Reset()
+	if err := installCmd(&Invocation{Stdout: &out, Stderr: &out}, []string{"-f", dir}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if target, err := os.Readlink(existing); err != nil || target != exe {
+		t.Fatalf("expected -f to replace existing file with link, got %q, %v", target, err)
+	}
+}
+
+func TestInstallCmdRejectsConflictingModes(t *testing.T) {
+	var out bytes.Buffer
+	if err := installCmd(&Invocation{Stdout: &out, Stderr: &out}, []string{"--symlinks", "--hardlinks", t.TempDir()}); err == nil {
+		t.Fatalf("expected conflicting link modes to fail")
+	}
+}
@@ -2,23 +2,64 @@ package base
 
 import (
 	"fmt"
-	"io"
 	"sort"
+	"strings"
 	"sync"
+
+	"gobox/cmds/utils"
 )
 
-type HandlerFunc func(args []string, stdout io.Writer) error
+// HandlerFunc implements a command. All I/O, environment lookups and
+// cancellation must go through inv rather than the os package globals.
+type HandlerFunc func(inv *Invocation, args []string) error
 
 type Command interface {
 	Name() string
 	Help() string
-	Run(args []string, stdout io.Writer) error
+	Run(inv *Invocation, args []string) error
 }
 
 type command struct {
-	name    string
-	help    string
-	handler HandlerFunc
+	name          string
+	help          string
+	handler       HandlerFunc
+	tabular       bool
+	flags         []Flag
+	declaredFlags bool
+	flagValues    map[string]func() []string
+	ownSignals    bool
+	mutating      bool
+	// aliasOf is the command a config alias runs.
+	aliasOf Command
+}
+
+// CommandOption configures optional command capabilities in NewCommand.
+type CommandOption func(*command)
+
+// WithTabularOutput marks a command whose results are utils.Table rows. Run
+// then accepts --output FORMAT (or --output=FORMAT) anywhere before "--" and
+// passes the format to the handler as Invocation.Output.
+func WithTabularOutput() CommandOption {
+	return func(c *command) { c.tabular = true }
+}
+
+// SupportsOutput reports whether cmd accepts --output.
+func SupportsOutput(cmd Command) bool {
+	c, ok := cmd.(command)
+	return ok && c.tabular
+}
+
+// WithOwnSignals marks a command that handles SIGINT and SIGTERM itself, such
+// as init forwarding them to its child. main then leaves those signals to the
+// command instead of cancelling its context and exiting.
+func WithOwnSignals() CommandOption {
+	return func(c *command) { c.ownSignals = true }
+}
+
+// OwnsSignals reports whether cmd handles SIGINT and SIGTERM itself.
+func OwnsSignals(cmd Command) bool {
+	c, ok := cmd.(command)
+	return ok && c.ownSignals
 }
 
 func (c command) Name() string {
@@ -29,22 +70,78 @@ func (c command) Help() string {
 	return c.help
 }
 
-func (c command) Run(args []string, stdout io.Writer) error {
-	return c.handler(args, stdout)
+func (c command) Run(inv *Invocation, args []string) error {
+	if inv == nil {
+		inv = Stdio()
+	}
+	inv = inv.withDefaults()
+	inv.ReadOnly = ReadOnly(inv)
+	if c.mutating {
+		if err := CheckWritable(inv, c.name); err != nil {
+			return err
+		}
+	}
+	inv.Argv = append([]string{c.name}, args...)
+	args = inv.Config.Args(c.name, args)
+	if c.tabular {
+		var format string
+		var err error
+		args, format, err = extractOutputFlag(args)
+		if err != nil {
+			return err
+		}
+		if format != "" {
+			inv.Output = format
+		}
+	}
+	if inv.Output != "" {
+		if !c.tabular {
+			return fmt.Errorf("--output is not supported by %s", c.name)
+		}
+		format, err := utils.ParseOutputFormat(inv.Output)
+		if err != nil {
+			return err
+		}
+		inv.Output = format
+	}
+	return c.handler(inv, args)
 }
 
-func NewCommand(name, help string, handler HandlerFunc) Command {
-	return command{
+// extractOutputFlag removes --output FORMAT / --output=FORMAT from args,
+// leaving everything after "--" untouched. The last occurrence wins.
+func extractOutputFlag(args []string) ([]string, string, error) {
+	var format string
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(out, args[i:]...), format, nil
+		case arg == "--output":
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("--output requires an argument")
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--output="):
+			format = strings.TrimPrefix(arg, "--output=")
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out, format, nil
+}
+
+func NewCommand(name, help string, handler HandlerFunc, opts ...CommandOption) Command {
+	c := command{
 		name:    name,
 		help:    help,
 		handler: handler,
 	}
-}
-
-func Adapt(handler func(args []string) error) HandlerFunc {
-	return func(args []string, _ io.Writer) error {
-		return handler(args)
+	for _, opt := range opts {
+		opt(&c)
 	}
+	return c
 }
 
 var registry = struct {
@@ -68,23 +165,39 @@ func Register(cmd Command) {
 	registry.order = append(registry.order, name)
 }
 
+// Lookup returns the registered command name, or else the gobox-<name>
+// plugin on the path set by SetPluginPath.
 func Lookup(name string) (Command, bool) {
 	registry.RLock()
-	defer registry.RUnlock()
-
 	cmd, ok := registry.byName[name]
-	return cmd, ok
+	registry.RUnlock()
+	if ok {
+		return cmd, true
+	}
+	return lookupPlugin(name)
 }
 
+// Commands returns the registered commands and the plugins they do not
+// shadow, sorted by name.
 func Commands() []Command {
+	commands := registeredCommands()
+	registered := make(map[string]Command, len(commands))
+	for _, cmd := range commands {
+		registered[cmd.Name()] = cmd
+	}
+	commands = append(commands, listPlugins(registered)...)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name() < commands[j].Name() })
+	return commands
+}
+
+// registeredCommands returns the registry alone, without scanning for
+// plugins.
+func registeredCommands() []Command {
 	registry.RLock()
 	defer registry.RUnlock()
 
-	names := append([]string(nil), registry.order...)
-	sort.Strings(names)
-
-	commands := make([]Command, 0, len(names))
-	for _, name := range names {
+	commands := make([]Command, 0, len(registry.order))
+	for _, name := range registry.order {
 		commands = append(commands, registry.byName[name])
 	}
 	return commands
@@ -0,0 +1,67 @@
+package base
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandRunExtractsOutputForTabularCommands(t *testing.T) {
+	var gotArgs []string
+	var gotOutput string
+	cmd := NewCommand("zz_tabular", "test", func(inv *Invocation, args []string) error {
+		gotArgs, gotOutput = args, inv.Output
+		return nil
+	}, WithTabularOutput())
+
+	if err := cmd.Run(&Invocation{}, []string{"-a", "--output", "JSON", "x", "--", "--output=csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if gotOutput != "json" || !reflect.DeepEqual(gotArgs, []string{"-a", "x", "--", "--output=csv"}) {
+		t.Fatalf("unexpected output %q args %q", gotOutput, gotArgs)
+	}
+
+	if err := cmd.Run(&Invocation{Output: "tsv"}, []string{"--output=ndjson"}); err != nil || gotOutput != "ndjson" {
+		t.Fatalf("expected the command's own flag to win, got %q (%v)", gotOutput, err)
+	}
+	if err := cmd.Run(&Invocation{}, []string{"--output", "xml"}); err == nil {
+		t.Fatal("expected invalid format error")
+	}
+	if err := cmd.Run(&Invocation{}, []string{"--output"}); err == nil {
+		t.Fatal("expected missing argument error")
+	}
+	if !SupportsOutput(cmd) {
+		t.Fatal("expected SupportsOutput for a tabular command")
+	}
+}
+
+func TestCommandRunRejectsOutputForPlainCommands(t *testing.T) {
+	var gotArgs []string
+	cmd := NewCommand("zz_plain", "test", func(inv *Invocation, args []string) error {
+		gotArgs = args
+		return nil
+	})
+	if err := cmd.Run(&Invocation{}, []string{"--output", "json"}); err != nil || len(gotArgs) != 2 {
+		t.Fatalf("expected --output to pass through as arguments, got %q (%v)", gotArgs, err)
+	}
+	err := cmd.Run(&Invocation{Output: "json"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not supported by zz_plain") {
+		t.Fatalf("expected unsupported error, got %v", err)
+	}
+	if SupportsOutput(cmd) {
+		t.Fatal("expected SupportsOutput to be false")
+	}
+}
+
+func TestOwnsSignalsCarriesOverToAliases(t *testing.T) {
+	owner := NewCommand("zz_owner", "test", func(*Invocation, []string) error { return nil }, WithOwnSignals())
+	if !OwnsSignals(owner) {
+		t.Fatal("expected OwnsSignals for a WithOwnSignals command")
+	}
+	if !OwnsSignals(newAliasCommand(ConfigAlias{Name: "zz_owner_alias", Args: []string{"zz_owner", "-x"}}, owner)) {
+		t.Fatal("expected an alias to inherit OwnsSignals")
+	}
+	if OwnsSignals(NewCommand("zz_plain_signals", "test", func(*Invocation, []string) error { return nil })) {
+		t.Fatal("expected OwnsSignals to be false by default")
+	}
+}
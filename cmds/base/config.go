@@ -0,0 +1,385 @@
+package base
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// systemConfigPath is the last config file candidate, after $GOBOX_CONFIG and
+// the user's ~/.config/gobox/config.
+const systemConfigPath = "/etc/gobox.conf"
+
+// Config holds per-command default options and command aliases, loaded from
+// a config file and GOBOX_<CMD>_OPTS variables. Command.Run prepends the
+// defaults to argv, so they reach utils.ParseFlagSet and the hand-written
+// parsers exactly as if typed first, and later options on the command line
+// override them.
+type Config struct {
+	// Path is the config file that was read, empty when none exists.
+	Path     string
+	Defaults map[string]ConfigEntry
+	Aliases  []ConfigAlias
+}
+
+// ConfigEntry is one command's default options and where they came from
+// ("file:line" or the variable name).
+type ConfigEntry struct {
+	Args   []string
+	Source string
+}
+
+// ConfigAlias is a command-level alias: running Name runs Args[0] with
+// Args[1:] prepended to its arguments.
+type ConfigAlias struct {
+	Name   string
+	Args   []string
+	Source string
+}
+
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// LoadConfig reads the first config file found among $GOBOX_CONFIG,
+// $XDG_CONFIG_HOME/gobox/config (~/.config/gobox/config) and /etc/gobox.conf,
+// then applies GOBOX_<CMD>_OPTS, which replaces the file's defaults for that
+// command. An explicit $GOBOX_CONFIG must exist.
+//
+// The file holds "name = words" lines in two sections; lines before any
+// section header are defaults:
+//
+//	[defaults]
+//	ps = -ww
+//	curl = -sS --connect-timeout 3
+//
+//	[alias]
+//	psmem = ps --sort rss -r -n 20
+//
+// Words are split like a shell would, with '...', "..." and backslash
+// quoting; '#' starts a comment at the beginning of a line.
+func LoadConfig(inv *Invocation) (*Config, error) {
+	cfg := &Config{Defaults: map[string]ConfigEntry{}}
+	path, err := findConfigFile(inv)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Map GOBOX_<CMD>_OPTS back to names through the registry and the
+	// aliases; the plugin path is only scanned for a variable left over.
+	names := map[string]string{}
+	for _, cmd := range registeredCommands() {
+		if !IsConfigAlias(cmd) {
+			names[configEnvKey(cmd.Name())] = cmd.Name()
+		}
+	}
+	for _, alias := range cfg.Aliases {
+		names[configEnvKey(alias.Name)] = alias.Name
+	}
+	pluginsListed := false
+	for _, kv := range inv.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, "GOBOX_") || !strings.HasSuffix(key, "_OPTS") {
+			continue
+		}
+		name, ok := names[key]
+		if !ok && !pluginsListed {
+			pluginsListed = true
+			for _, cmd := range Commands() {
+				if IsPlugin(cmd) {
+					names[configEnvKey(cmd.Name())] = cmd.Name()
+				}
+			}
+			name, ok = names[key]
+		}
+		if !ok {
+			continue
+		}
+		words, err := splitConfigWords(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		cfg.Defaults[name] = ConfigEntry{Args: words, Source: key}
+	}
+	return cfg, nil
+}
+
+func findConfigFile(inv *Invocation) (string, error) {
+	if path := inv.Getenv("GOBOX_CONFIG"); path != "" {
+		if _, err := os.Stat(inv.Path(path)); err != nil {
+			return "", fmt.Errorf("GOBOX_CONFIG: %v", err)
+		}
+		return inv.Path(path), nil
+	}
+	var candidates []string
+	if dir := inv.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "gobox", "config"))
+	} else if home := inv.Getenv("HOME"); home != "" {
+		candidates = append(candidates, filepath.Join(home, ".config", "gobox", "config"))
+	}
+	candidates = append(candidates, systemConfigPath)
+	for _, path := range candidates {
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// configEnvKey names the variable holding name's defaults: GOBOX_PS_OPTS,
+// with anything but letters and digits mapped to '_'.
+func configEnvKey(name string) string {
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, name)
+	return "GOBOX_" + key + "_OPTS"
+}
+
+func (cfg *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cfg.Path = path
+
+	section := "defaults"
+	var defaults []string
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		source := fmt.Sprintf("%s:%d", path, lineNo)
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section != "defaults" && section != "alias" {
+				return fmt.Errorf("%s: unknown section [%s]", source, section)
+			}
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("%s: expected name = value", source)
+		}
+		words, err := splitConfigWords(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", source, err)
+		}
+		if section == "alias" {
+			if err := cfg.addAlias(name, words, source); err != nil {
+				return err
+			}
+			continue
+		}
+		cfg.Defaults[name] = ConfigEntry{Args: words, Source: source}
+		defaults = append(defaults, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range defaults {
+		if cmd, ok := Lookup(name); ok && !IsConfigAlias(cmd) {
+			continue
+		}
+		if cfg.alias(name) != nil {
+			continue
+		}
+		return fmt.Errorf("%s: unknown command %q", cfg.Defaults[name].Source, name)
+	}
+	return nil
+}
+
+func (cfg *Config) addAlias(name string, words []string, source string) error {
+	if !aliasNamePattern.MatchString(name) {
+		return fmt.Errorf("%s: invalid alias name %q", source, name)
+	}
+	if cmd, ok := Lookup(name); ok && !IsConfigAlias(cmd) {
+		return fmt.Errorf("%s: alias %s would shadow the %s command", source, name, name)
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("%s: alias %s names no command", source, name)
+	}
+	if target, ok := Lookup(words[0]); !ok || IsConfigAlias(target) {
+		return fmt.Errorf("%s: alias %s: unknown command %q", source, name, words[0])
+	}
+	if existing := cfg.alias(name); existing != nil {
+		existing.Args, existing.Source = words, source
+		return nil
+	}
+	cfg.Aliases = append(cfg.Aliases, ConfigAlias{Name: name, Args: words, Source: source})
+	return nil
+}
+
+func (cfg *Config) alias(name string) *ConfigAlias {
+	for i := range cfg.Aliases {
+		if cfg.Aliases[i].Name == name {
+			return &cfg.Aliases[i]
+		}
+	}
+	return nil
+}
+
+// Args returns args with the configured defaults for name prepended. A nil
+// Config (--no-config) leaves args unchanged.
+func (cfg *Config) Args(name string, args []string) []string {
+	if cfg == nil {
+		return args
+	}
+	entry, ok := cfg.Defaults[name]
+	if !ok || len(entry.Args) == 0 {
+		return args
+	}
+	return append(append([]string(nil), entry.Args...), args...)
+}
+
+// DefaultNames lists the commands and aliases with configured defaults.
+func (cfg *Config) DefaultNames() []string {
+	names := make([]string, 0, len(cfg.Defaults))
+	for name := range cfg.Defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configAliases guards the names RegisterConfigAliases added, so a later call
+// can replace them.
+var configAliases = struct {
+	sync.Mutex
+	names []string
+}{}
+
+// RegisterConfigAliases replaces the registered config aliases with cfg's;
+// a nil cfg removes them. Aliases are ordinary registry entries, so help,
+// completion, alias and sh see them like any other command.
+func RegisterConfigAliases(cfg *Config) {
+	configAliases.Lock()
+	defer configAliases.Unlock()
+
+	registry.Lock()
+	for _, name := range configAliases.names {
+		delete(registry.byName, name)
+		for i, registered := range registry.order {
+			if registered == name {
+				registry.order = append(registry.order[:i], registry.order[i+1:]...)
+				break
+			}
+		}
+	}
+	registry.Unlock()
+	configAliases.names = nil
+
+	if cfg == nil {
+		return
+	}
+	for _, alias := range cfg.Aliases {
+		target, ok := Lookup(alias.Args[0])
+		if !ok {
+			continue
+		}
+		Register(newAliasCommand(alias, target))
+		configAliases.names = append(configAliases.names, alias.Name)
+	}
+}
+
+func newAliasCommand(alias ConfigAlias, target Command) Command {
+	words := append([]string(nil), alias.Args[1:]...)
+	handler := func(inv *Invocation, args []string) error {
+		return target.Run(inv, append(append([]string(nil), words...), args...))
+	}
+	opts := []CommandOption{func(c *command) { c.aliasOf = target }}
+	if SupportsOutput(target) {
+		opts = append(opts, WithTabularOutput())
+	}
+	if OwnsSignals(target) {
+		opts = append(opts, WithOwnSignals())
+	}
+	if IsMutating(target) {
+		opts = append(opts, WithMutating())
+	}
+	return NewCommand(alias.Name, "Alias for "+strings.Join(alias.Args, " "), handler, opts...)
+}
+
+// IsConfigAlias reports whether cmd was registered from a config [alias]
+// line.
+func IsConfigAlias(cmd Command) bool {
+	c, ok := cmd.(command)
+	return ok && c.aliasOf != nil
+}
+
+// splitConfigWords splits s into words like a POSIX shell without
+// expansions: blanks separate words, '...' is literal, and backslash escapes
+// the next character outside quotes and $ ` " \ inside "...".
+func splitConfigWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			inWord = true
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
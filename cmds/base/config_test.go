@@ -0,0 +1,173 @@
+package base
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var registerConfigTestCommands sync.Once
+
+// ensureConfigTestCommands registers zz_cfg_echo, which records the
+// arguments it was run with.
+func ensureConfigTestCommands() *[]string {
+	registerConfigTestCommands.Do(func() {
+		Register(NewCommand("zz_cfg_echo", "test config command", func(inv *Invocation, args []string) error {
+			configEchoArgs = append([]string(nil), args...)
+			return nil
+		}))
+	})
+	return &configEchoArgs
+}
+
+var configEchoArgs []string
+
+func writeTestConfig(t *testing.T, content string) *Invocation {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &Invocation{Env: []string{"GOBOX_CONFIG=" + path}}
+}
+
+func TestLoadConfigReadsDefaultsAliasesAndEnv(t *testing.T) {
+	ensureConfigTestCommands()
+	inv := writeTestConfig(t, `# team defaults
+zz_cfg_echo = -a "two words"
+
+[alias]
+zz_cfg_short = zz_cfg_echo -n 5
+`)
+	cfg, err := LoadConfig(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Defaults["zz_cfg_echo"]; !reflect.DeepEqual(got.Args, []string{"-a", "two words"}) || !strings.HasSuffix(got.Source, "config:2") {
+		t.Fatalf("unexpected defaults %+v", got)
+	}
+	if len(cfg.Aliases) != 1 || cfg.Aliases[0].Name != "zz_cfg_short" || !reflect.DeepEqual(cfg.Aliases[0].Args, []string{"zz_cfg_echo", "-n", "5"}) {
+		t.Fatalf("unexpected aliases %+v", cfg.Aliases)
+	}
+
+	inv.Env = append(inv.Env, "GOBOX_ZZ_CFG_ECHO_OPTS=-b", "GOBOX_ZZ_CFG_SHORT_OPTS=")
+	cfg, err = LoadConfig(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Defaults["zz_cfg_echo"]; !reflect.DeepEqual(got.Args, []string{"-b"}) || got.Source != "GOBOX_ZZ_CFG_ECHO_OPTS" {
+		t.Fatalf("expected the variable to replace the file defaults, got %+v", got)
+	}
+	if got := cfg.Args("zz_cfg_short", []string{"x"}); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Fatalf("expected an empty variable to add nothing, got %q", got)
+	}
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	cfg, err := LoadConfig(&Invocation{Env: []string{"HOME=" + t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(systemConfigPath); err != nil && (cfg.Path != "" || len(cfg.Defaults) != 0) {
+		t.Fatalf("expected an empty config, got %+v", cfg)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	ensureConfigTestCommands()
+	cases := map[string]string{
+		"[aliases]\n":                          "unknown section [aliases]",
+		"zz_cfg_missing = -x\n":                `unknown command "zz_cfg_missing"`,
+		"zz_cfg_echo -x\n":                     "expected name = value",
+		"zz_cfg_echo = 'open\n":                "unterminated quote",
+		"[alias]\nzz_cfg_echo = zz_cfg_echo\n": "would shadow the zz_cfg_echo command",
+		"[alias]\nzz_cfg_x = zz_cfg_missing\n": `unknown command "zz_cfg_missing"`,
+		"[alias]\nzz_cfg_x =\n":                "names no command",
+	}
+	for content, want := range cases {
+		_, err := LoadConfig(writeTestConfig(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "config:") {
+			t.Errorf("%q: expected %q with a file:line prefix, got %v", content, want, err)
+		}
+	}
+	if _, err := LoadConfig(&Invocation{Env: []string{"GOBOX_CONFIG=" + filepath.Join(t.TempDir(), "missing")}}); err == nil {
+		t.Error("expected an explicit GOBOX_CONFIG to be required")
+	}
+}
+
+func TestSplitConfigWords(t *testing.T) {
+	got, err := splitConfigWords(` -o 'pid comm'  --label="a \"b\"" c\ d ''`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-o", "pid comm", `--label=a "b"`, "c d", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCommandRunPrependsConfigDefaultsAndAliases(t *testing.T) {
+	got := ensureConfigTestCommands()
+	cfg, err := LoadConfig(writeTestConfig(t, "zz_cfg_echo = -a\n[alias]\nzz_cfg_short = zz_cfg_echo -n 5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterConfigAliases(cfg)
+	defer RegisterConfigAliases(nil)
+
+	echo, _ := Lookup("zz_cfg_echo")
+	if err := echo.Run(&Invocation{Config: cfg}, []string{"x"}); err != nil || !reflect.DeepEqual(*got, []string{"-a", "x"}) {
+		t.Fatalf("expected defaults before the arguments, got %q (%v)", *got, err)
+	}
+	if err := echo.Run(&Invocation{}, []string{"x"}); err != nil || !reflect.DeepEqual(*got, []string{"x"}) {
+		t.Fatalf("expected no defaults without a config, got %q (%v)", *got, err)
+	}
+
+	alias, ok := Lookup("zz_cfg_short")
+	if !ok || alias.Help() != "Alias for zz_cfg_echo -n 5" {
+		t.Fatalf("expected the alias to be registered, got %v", alias)
+	}
+	if err := alias.Run(&Invocation{Config: cfg}, []string{"y"}); err != nil || !reflect.DeepEqual(*got, []string{"-a", "-n", "5", "y"}) {
+		t.Fatalf("unexpected alias expansion %q (%v)", *got, err)
+	}
+
+	RegisterConfigAliases(nil)
+	if _, ok := Lookup("zz_cfg_short"); ok {
+		t.Fatal("expected the alias to be removed")
+	}
+}
+
+func TestConfigShow(t *testing.T) {
+	ensureConfigTestCommands()
+	cfg, err := LoadConfig(writeTestConfig(t, "zz_cfg_echo = -o 'pid comm'\n[alias]\nzz_cfg_short = zz_cfg_echo -n 5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := configCmd(&Invocation{Stdout: &out, Config: cfg}, []string{"show"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "# config file: ") || !strings.HasPrefix(lines[1], "NAME ") {
+		t.Fatalf("unexpected config show output:\n%s", out.String())
+	}
+	if !strings.Contains(lines[2], "zz_cfg_echo   defaults  -o 'pid comm'") || !strings.Contains(lines[3], "zz_cfg_short  alias     zz_cfg_echo -n 5") {
+		t.Fatalf("unexpected config rows:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := configCmd(&Invocation{Stdout: &out, Config: cfg, Output: "csv"}, []string{"show"}); err != nil || !strings.HasPrefix(out.String(), "name,kind,args,source\n") {
+		t.Fatalf("unexpected csv output %q (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	if err := configCmd(&Invocation{Stdout: &out}, []string{"show"}); err != nil || !strings.Contains(out.String(), "disabled by --no-config") {
+		t.Fatalf("unexpected output without config %q (%v)", out.String(), err)
+	}
+	if err := configCmd(&Invocation{}, nil); err == nil {
+		t.Fatal("expected a missing subcommand error")
+	}
+}
@@ -0,0 +1,32 @@
+package base
+
+type cliErrorSilencer interface {
+	SuppressCLIError() bool
+}
+
+type cliExitCoder interface {
+	ExitCode() int
+}
+
+// ExitStatus maps the error returned by a handler onto an exit status: 0 for
+// success, the error's own ExitCode() when it carries one, 2 otherwise.
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if coder, ok := err.(cliExitCoder); ok {
+		return coder.ExitCode()
+	}
+	return 2
+}
+
+// ReportError reports whether err should be printed as "<cmd>: <err>".
+// Errors that only carry an exit status (grep no-match, diff differences,
+// silent curl) opt out through SuppressCLIError.
+func ReportError(err error) bool {
+	if err == nil {
+		return false
+	}
+	silencer, ok := err.(cliErrorSilencer)
+	return !ok || !silencer.SuppressCLIError()
+}
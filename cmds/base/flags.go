@@ -0,0 +1,125 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"sort"
+	"strings"
+
+	"gobox/cmds/utils"
+)
+
+// Flag describes one command-line option for shell completion.
+type Flag struct {
+	// Name is the option as typed, dashes included: "-n", "--max-depth",
+	// a single-dash long name such as find's "-name", or dig's "+short".
+	Name       string
+	TakesValue bool
+	Usage      string
+	// Values lists candidate values when the completion is requested (sort
+	// keys, PIDs, interface names). Nil leaves the value to the shell's
+	// default file completion.
+	Values func() []string
+}
+
+// WithFlags declares every option of a command whose parser is hand-written.
+// Commands parsing with utils.ParseFlagSet need not call it: their FlagSet is
+// discovered by running the handler with utils.DescribeFlagsArg, which a
+// hand-written parser would treat as an ordinary argument.
+func WithFlags(flags ...Flag) CommandOption {
+	return func(c *command) {
+		c.flags = append(c.flags, flags...)
+		c.declaredFlags = true
+	}
+}
+
+// WithFlagValues attaches a value completer to the option spelled name, which
+// may be declared with WithFlags or discovered from the command's FlagSet.
+func WithFlagValues(name string, values func() []string) CommandOption {
+	return func(c *command) {
+		if c.flagValues == nil {
+			c.flagValues = map[string]func() []string{}
+		}
+		c.flagValues[name] = values
+	}
+}
+
+// CommandFlags returns the options cmd accepts, sorted by name. Tabular
+// commands also report --output, and config aliases their command's options.
+func CommandFlags(cmd Command) []Flag {
+	c, ok := cmd.(command)
+	if !ok {
+		return nil
+	}
+	if c.aliasOf != nil {
+		return CommandFlags(c.aliasOf)
+	}
+	flags := c.flags
+	if !c.declaredFlags {
+		flags = discoverFlags(c.handler)
+	}
+	out := make([]Flag, 0, len(flags)+1)
+	for _, f := range flags {
+		if values, ok := c.flagValues[f.Name]; ok {
+			f.Values = values
+		}
+		out = append(out, f)
+	}
+	if c.tabular {
+		out = append(out, Flag{Name: "--output", TakesValue: true, Usage: "output format", Values: OutputFormats})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// OutputFormats lists the --output values.
+func OutputFormats() []string {
+	return []string{utils.OutputText, utils.OutputJSON, utils.OutputNDJSON, utils.OutputCSV, utils.OutputTSV}
+}
+
+// discoverFlags asks a handler for the FlagSet it parses. The handler runs
+// with discarded I/O and an already cancelled context, and utils.ParseFlagSet
+// returns before any work is done.
+func discoverFlags(handler HandlerFunc) []Flag {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inv := &Invocation{Context: ctx, Stdin: strings.NewReader(""), Stdout: io.Discard, Stderr: io.Discard}
+	var desc *utils.FlagSetDescription
+	if err := handler(inv.withDefaults(), []string{utils.DescribeFlagsArg}); !errors.As(err, &desc) {
+		return nil
+	}
+	var flags []Flag
+	desc.FlagSet.VisitAll(func(f *flag.Flag) {
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		flags = append(flags, Flag{Name: name, TakesValue: !utils.IsBoolFlag(f), Usage: f.Usage})
+	})
+	return flags
+}
+
+// ClassifierFlags builds Flag entries for a hand-written parser: every
+// letter or digit its short classifier defines, plus the long flags in long
+// (name without dashes mapped to whether it takes a value) as --name.
+func ClassifierFlags(short utils.ShortFlagClassifier, long map[string]bool) []Flag {
+	var flags []Flag
+	for c := byte('0'); c <= 'z'; c++ {
+		if !isFlagLetter(c) {
+			continue
+		}
+		if defined, takesValue := short(c); defined {
+			flags = append(flags, Flag{Name: "-" + string(c), TakesValue: takesValue})
+		}
+	}
+	for name, takesValue := range long {
+		flags = append(flags, Flag{Name: "--" + name, TakesValue: takesValue})
+	}
+	return flags
+}
+
+func isFlagLetter(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
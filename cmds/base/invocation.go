@@ -0,0 +1,169 @@
+package base
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gobox/cmds/utils"
+)
+
+// Invocation carries the per-run state a command executes with: standard
+// streams, environment, working directory and a cancellation context.
+// Commands read and write only through it, which lets the same handler run
+// from main, in-process from another command, or concurrently in tests.
+type Invocation struct {
+	Context context.Context
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	// Env holds "KEY=value" pairs. Like exec.Cmd.Env, nil means the
+	// process environment.
+	Env []string
+	// Dir is the directory relative paths resolve against. Empty means the
+	// process working directory.
+	Dir string
+	// Output is the --output format (see utils.ParseOutputFormat) for
+	// commands registered WithTabularOutput. Empty means their text table.
+	Output string
+	// Config supplies per-command default options; nil (gobox --no-config,
+	// in-process callers) runs commands with exactly the arguments given.
+	Config *Config
+	// Argv is the command line as invoked, command name first, for the
+	// audit log. Command.Run fills it in.
+	Argv []string
+	// ReadOnly latches read-only mode. Command.Run sets it when ReadOnly
+	// reports true, and invocations derived from this one copy it, so a
+	// child whose Env clears GOBOX_READONLY stays read-only.
+	ReadOnly bool
+}
+
+// Stdio returns an Invocation bound to the process's standard streams and
+// environment, evaluated at call time.
+func Stdio() *Invocation {
+	return &Invocation{
+		Context: context.Background(),
+		Stdin:   os.Stdin,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	}
+}
+
+// withDefaults fills unset fields so handlers never see nil streams or a nil
+// context.
+func (inv *Invocation) withDefaults() *Invocation {
+	out := *inv
+	if out.Context == nil {
+		out.Context = context.Background()
+	}
+	if out.Stdin == nil {
+		out.Stdin = strings.NewReader("")
+	}
+	if out.Stdout == nil {
+		out.Stdout = io.Discard
+	}
+	if out.Stderr == nil {
+		out.Stderr = io.Discard
+	}
+	return &out
+}
+
+// Ctx returns the invocation's context, never nil.
+func (inv *Invocation) Ctx() context.Context {
+	if inv.Context == nil {
+		return context.Background()
+	}
+	return inv.Context
+}
+
+// WithContext returns a shallow copy of inv using ctx.
+func (inv *Invocation) WithContext(ctx context.Context) *Invocation {
+	out := *inv
+	out.Context = ctx
+	return &out
+}
+
+// LookupEnv looks key up in the invocation environment.
+func (inv *Invocation) LookupEnv(key string) (string, bool) {
+	if inv.Env == nil {
+		return os.LookupEnv(key)
+	}
+	prefix := key + "="
+	for i := len(inv.Env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(inv.Env[i], prefix) {
+			return inv.Env[i][len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+// Getenv returns the value of key in the invocation environment.
+func (inv *Invocation) Getenv(key string) string {
+	v, _ := inv.LookupEnv(key)
+	return v
+}
+
+// Environ returns the invocation environment as "KEY=value" pairs.
+func (inv *Invocation) Environ() []string {
+	if inv.Env == nil {
+		return os.Environ()
+	}
+	return append([]string(nil), inv.Env...)
+}
+
+// Path resolves name against Dir. Absolute names, "-" (stdin/stdout) and
+// invocations without a Dir are returned unchanged, so output that echoes
+// operands keeps showing what the user typed.
+func (inv *Invocation) Path(name string) string {
+	if inv.Dir == "" || name == "" || name == "-" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(inv.Dir, name)
+}
+
+// DisplayPath maps a path produced by walking Path(root) back onto root as
+// the user typed it, so tree walkers print the same names regardless of Dir.
+func (inv *Invocation) DisplayPath(root, walked string) string {
+	resolved := inv.Path(root)
+	if resolved == root {
+		return walked
+	}
+	if walked == resolved {
+		return root
+	}
+	rel, err := filepath.Rel(resolved, walked)
+	if err != nil {
+		return walked
+	}
+	return filepath.Join(root, rel)
+}
+
+// ProcRootEnv and SysRootEnv name the environment variables that point the
+// procfs and sysfs collectors at another tree; gobox --proc-root and
+// --sys-root set them.
+const (
+	ProcRootEnv = "GOBOX_PROCFS"
+	SysRootEnv  = "GOBOX_SYSFS"
+)
+
+// Roots returns the procfs and sysfs trees the invocation's collectors
+// read, from GOBOX_PROCFS and GOBOX_SYSFS in its environment. Relative
+// directories resolve against Dir; unset means /proc and /sys.
+func (inv *Invocation) Roots() utils.Roots {
+	return utils.NewRoots(inv.Path(inv.Getenv(ProcRootEnv)), inv.Path(inv.Getenv(SysRootEnv)))
+}
+
+// Exec prepares an external command wired to the invocation's streams,
+// environment and working directory, cancelled together with ctx.
+func (inv *Invocation) Exec(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = inv.Stdin
+	cmd.Stdout = inv.Stdout
+	cmd.Stderr = inv.Stderr
+	cmd.Env = inv.Env
+	cmd.Dir = inv.Dir
+	return cmd
+}
@@ -0,0 +1,86 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestInvocationLookupEnvUsesOwnEnvironment(t *testing.T) {
+	t.Setenv("GOBOX_INV_TEST", "process")
+	inv := &Invocation{Env: []string{"GOBOX_INV_TEST=first", "OTHER=1", "GOBOX_INV_TEST=last"}}
+
+	if got := inv.Getenv("GOBOX_INV_TEST"); got != "last" {
+		t.Fatalf("expected later entry to win, got %q", got)
+	}
+	if _, ok := inv.LookupEnv("GOBOX_INV_MISSING"); ok {
+		t.Fatalf("expected missing key to be reported as unset")
+	}
+	if got := (&Invocation{}).Getenv("GOBOX_INV_TEST"); got != "process" {
+		t.Fatalf("expected nil Env to fall back to the process environment, got %q", got)
+	}
+}
+
+func TestInvocationPathResolvesAgainstDir(t *testing.T) {
+	inv := &Invocation{Dir: "/srv/work"}
+	cases := map[string]string{
+		"a.txt":    filepath.Join("/srv/work", "a.txt"),
+		"/etc/x":   "/etc/x",
+		"-":        "-",
+		"":         "",
+		"../b.txt": "/srv/b.txt",
+	}
+	for in, want := range cases {
+		if got := inv.Path(in); got != want {
+			t.Fatalf("Path(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := (&Invocation{}).Path("a.txt"); got != "a.txt" {
+		t.Fatalf("expected no rewrite without Dir, got %q", got)
+	}
+}
+
+func TestInvocationDisplayPathKeepsTypedRoot(t *testing.T) {
+	inv := &Invocation{Dir: "/srv/work"}
+	walked := filepath.Join("/srv/work", "logs", "app.log")
+	if got := inv.DisplayPath("logs", walked); got != filepath.Join("logs", "app.log") {
+		t.Fatalf("unexpected display path %q", got)
+	}
+	if got := inv.DisplayPath("logs", "/srv/work/logs"); got != "logs" {
+		t.Fatalf("expected root itself to display as typed, got %q", got)
+	}
+}
+
+func TestCommandRunUsesInvocationStreams(t *testing.T) {
+	cmd := NewCommand("zz_test_invocation", "test", func(inv *Invocation, args []string) error {
+		data, err := io.ReadAll(inv.Stdin)
+		if err != nil {
+			return err
+		}
+		inv.Stdout.Write(bytes.ToUpper(data))
+		inv.Stderr.Write([]byte(inv.Getenv("NAME")))
+		return inv.Ctx().Err()
+	})
+
+	var out, errOut bytes.Buffer
+	inv := &Invocation{
+		Stdin:  bytes.NewBufferString("hello"),
+		Stdout: &out,
+		Stderr: &errOut,
+		Env:    []string{"NAME=gobox"},
+	}
+	if err := cmd.Run(inv, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out.String() != "HELLO" || errOut.String() != "gobox" {
+		t.Fatalf("unexpected streams stdout=%q stderr=%q", out.String(), errOut.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cmd.Run(&Invocation{Context: ctx}, nil); err != context.Canceled {
+		t.Fatalf("expected cancelled context to reach the handler, got %v", err)
+	}
+}
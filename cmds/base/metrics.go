@@ -0,0 +1,59 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gobox/cmds/utils"
+)
+
+// MetricsCollector produces one group of metrics for gobox exporter from the
+// same procfs/sysfs parsers the corresponding commands use. Command packages
+// register theirs from init, next to their commands.
+type MetricsCollector struct {
+	// Name selects the collector with exporter --collectors.
+	Name string
+	Help string
+	// Collect writes the collector's metric families; an error discards
+	// them for this scrape.
+	Collect func(w *utils.MetricsWriter, opts MetricsOptions) error
+}
+
+// MetricsOptions carries exporter settings collectors may honour.
+type MetricsOptions struct {
+	// TopProcesses bounds per-process series to the N busiest processes by
+	// CPU and the N largest by resident memory.
+	TopProcesses int
+	// Roots are the procfs and sysfs trees collectors read.
+	Roots utils.Roots
+}
+
+var metricsCollectors = struct {
+	sync.RWMutex
+	byName map[string]MetricsCollector
+}{
+	byName: make(map[string]MetricsCollector),
+}
+
+// RegisterMetricsCollector adds c to the collectors gobox exporter offers.
+func RegisterMetricsCollector(c MetricsCollector) {
+	metricsCollectors.Lock()
+	defer metricsCollectors.Unlock()
+	if _, exists := metricsCollectors.byName[c.Name]; exists {
+		panic(fmt.Sprintf("metrics collector already registered: %s", c.Name))
+	}
+	metricsCollectors.byName[c.Name] = c
+}
+
+// MetricsCollectors returns the registered collectors sorted by name.
+func MetricsCollectors() []MetricsCollector {
+	metricsCollectors.RLock()
+	defer metricsCollectors.RUnlock()
+	out := make([]MetricsCollector, 0, len(metricsCollectors.byName))
+	for _, c := range metricsCollectors.byName {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,199 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// PluginPrefix is the file name prefix of external gobox commands: an
+// executable gobox-foo on the plugin path runs as `gobox foo`.
+const PluginPrefix = "gobox-"
+
+// PluginDescribeArg asks a plugin for its one-line help; it prints the line
+// on stdout and exits 0.
+const PluginDescribeArg = "--gobox-describe"
+
+// pluginDescribeTimeout bounds each --gobox-describe probe, so one hung
+// plugin cannot stall usage or completion output.
+const pluginDescribeTimeout = time.Second
+
+// plugins holds the directories searched for gobox-<name> executables and,
+// once listed, the plugins found there.
+var plugins = struct {
+	sync.Mutex
+	dirs   []string
+	listed []Command
+	byName map[string]*pluginCommand
+}{}
+
+// SetPluginPath makes Lookup and Commands find external commands in
+// $GOBOX_PLUGIN_DIR (a list like PATH), then $PATH, of inv's environment,
+// the way git and kubectl find theirs. Registered commands always win over a
+// plugin of the same name.
+func SetPluginPath(inv *Invocation) {
+	var dirs []string
+	for _, key := range []string{"GOBOX_PLUGIN_DIR", "PATH"} {
+		for _, dir := range filepath.SplitList(inv.Getenv(key)) {
+			if dir == "" {
+				dir = "."
+			}
+			dirs = append(dirs, inv.Path(dir))
+		}
+	}
+	plugins.Lock()
+	defer plugins.Unlock()
+	plugins.dirs = dirs
+	plugins.listed = nil
+	plugins.byName = map[string]*pluginCommand{}
+}
+
+// lookupPlugin finds gobox-<name> on the plugin path.
+func lookupPlugin(name string) (Command, bool) {
+	if name == "" || strings.ContainsRune(name, filepath.Separator) {
+		return nil, false
+	}
+	plugins.Lock()
+	defer plugins.Unlock()
+	if cmd, ok := plugins.byName[name]; ok {
+		return cmd, true
+	}
+	for _, dir := range plugins.dirs {
+		path := filepath.Join(dir, PluginPrefix+name)
+		if isExecutableFile(path) {
+			cmd := &pluginCommand{name: name, path: path}
+			plugins.byName[name] = cmd
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+// listPlugins returns every plugin on the plugin path whose name is not
+// taken by a registered command, sorted by name.
+func listPlugins(registered map[string]Command) []Command {
+	plugins.Lock()
+	defer plugins.Unlock()
+	if plugins.listed == nil {
+		seen := map[string]bool{}
+		plugins.listed = []Command{}
+		for _, dir := range plugins.dirs {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				name := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), PluginPrefix), ".exe")
+				if !strings.HasPrefix(entry.Name(), PluginPrefix) || name == "" || seen[name] {
+					continue
+				}
+				path := filepath.Join(dir, entry.Name())
+				if !isExecutableFile(path) {
+					continue
+				}
+				seen[name] = true
+				cmd, ok := plugins.byName[name]
+				if !ok {
+					cmd = &pluginCommand{name: name, path: path}
+					plugins.byName[name] = cmd
+				}
+				plugins.listed = append(plugins.listed, cmd)
+			}
+		}
+		sort.Slice(plugins.listed, func(i, j int) bool { return plugins.listed[i].Name() < plugins.listed[j].Name() })
+	}
+	var out []Command
+	for _, cmd := range plugins.listed {
+		if _, taken := registered[cmd.Name()]; !taken {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
+
+// IsPlugin reports whether cmd runs an external gobox-<name> executable.
+func IsPlugin(cmd Command) bool {
+	_, ok := cmd.(*pluginCommand)
+	return ok
+}
+
+type pluginCommand struct {
+	name string
+	path string
+
+	describe sync.Once
+	help     string
+}
+
+func (p *pluginCommand) Name() string {
+	return p.name
+}
+
+// Help runs the plugin once with PluginDescribeArg and keeps the first line
+// it prints; plugins that do not answer are described by their path.
+func (p *pluginCommand) Help() string {
+	p.describe.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pluginDescribeTimeout)
+		defer cancel()
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, p.path, PluginDescribeArg)
+		cmd.Stdout = &out
+		if err := cmd.Run(); err == nil {
+			line, _, _ := strings.Cut(out.String(), "\n")
+			p.help = strings.TrimSpace(line)
+		}
+		if p.help == "" {
+			p.help = "External command " + p.path
+		}
+	})
+	return p.help
+}
+
+// Run executes the plugin with the invocation's streams, environment and
+// directory. Its exit status becomes gobox's, and cancellation interrupts it
+// rather than killing it, so it can restore the terminal like a built-in.
+func (p *pluginCommand) Run(inv *Invocation, args []string) error {
+	if inv == nil {
+		inv = Stdio()
+	}
+	inv = inv.withDefaults()
+	if inv.Output != "" {
+		return fmt.Errorf("--output is not supported by %s", p.name)
+	}
+	if err := CheckExternal(inv, p.name); err != nil {
+		return err
+	}
+	cmd := inv.Exec(inv.Ctx(), p.path, inv.Config.Args(p.name, args)...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return pluginExitError(128 + int(status.Signal()))
+		}
+		return pluginExitError(exitErr.ExitCode())
+	}
+	return err
+}
+
+// pluginExitError carries a plugin's exit status; the plugin has already
+// reported the failure itself.
+type pluginExitError int
+
+func (e pluginExitError) Error() string          { return fmt.Sprintf("exit status %d", int(e)) }
+func (e pluginExitError) ExitCode() int          { return int(e) }
+func (e pluginExitError) SuppressCLIError() bool { return true }
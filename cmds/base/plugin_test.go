@@ -0,0 +1,103 @@
+package base
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPlugin creates an sh script plugin in dir.
+func writeTestPlugin(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	if err := os.WriteFile(filepath.Join(dir, PluginPrefix+name), []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func usePluginDirs(t *testing.T, dirs ...string) {
+	t.Helper()
+	SetPluginPath(&Invocation{Env: []string{"GOBOX_PLUGIN_DIR=" + strings.Join(dirs, string(os.PathListSeparator)), "PATH="}})
+	t.Cleanup(func() { SetPluginPath(&Invocation{Env: []string{}}) })
+}
+
+func TestPluginLookupAndRun(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPlugin(t, dir, "zz-hello", `[ "$1" = --gobox-describe ] && { echo "Say hello"; exit 0; }
+echo "hello $*"; echo oops >&2; exit 3
+`)
+	usePluginDirs(t, dir)
+
+	cmd, ok := Lookup("zz-hello")
+	if !ok || !IsPlugin(cmd) {
+		t.Fatalf("expected the plugin to be found, got %v", cmd)
+	}
+	if cmd.Help() != "Say hello" {
+		t.Fatalf("unexpected help %q", cmd.Help())
+	}
+	var out, errOut bytes.Buffer
+	cfg := &Config{Defaults: map[string]ConfigEntry{"zz-hello": {Args: []string{"-v"}}}}
+	err := cmd.Run(&Invocation{Stdout: &out, Stderr: &errOut, Config: cfg}, []string{"a", "b"})
+	if ExitStatus(err) != 3 || ReportError(err) {
+		t.Fatalf("expected a silent exit status 3, got %v", err)
+	}
+	if out.String() != "hello -v a b\n" || errOut.String() != "oops\n" {
+		t.Fatalf("unexpected plugin output %q / %q", out.String(), errOut.String())
+	}
+	if err := cmd.Run(&Invocation{Output: "json"}, nil); err == nil {
+		t.Fatal("expected --output to be rejected")
+	}
+	out.Reset()
+	if err := cmd.Run(&Invocation{Stdout: &out, Env: []string{ReadOnlyEnv + "=1"}}, nil); ExitStatus(err) != ReadOnlyExitCode || out.Len() != 0 {
+		t.Fatalf("expected the plugin to be refused in read-only mode, got %v / %q", err, out.String())
+	}
+
+	cfg, err = LoadConfig(&Invocation{Env: []string{"GOBOX_CONFIG=/dev/null", "GOBOX_ZZ_HELLO_OPTS=-q"}})
+	if err != nil || strings.Join(cfg.Args("zz-hello", nil), " ") != "-q" {
+		t.Fatalf("expected GOBOX_ZZ_HELLO_OPTS to apply to the plugin, got %+v (%v)", cfg, err)
+	}
+}
+
+func TestPluginListing(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeTestPlugin(t, first, "zz-tool", "echo first\n")
+	writeTestPlugin(t, second, "zz-tool", "echo second\n")
+	writeTestPlugin(t, second, "zz-quiet", "exit 1\n")
+	writeTestPlugin(t, second, "alias", "echo shadowed\n")
+	if err := os.WriteFile(filepath.Join(second, PluginPrefix+"zz-data"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	usePluginDirs(t, first, second)
+
+	var names []string
+	for _, cmd := range Commands() {
+		if IsPlugin(cmd) {
+			names = append(names, cmd.Name())
+		}
+	}
+	if strings.Join(names, " ") != "zz-quiet zz-tool" {
+		t.Fatalf("unexpected plugins %q", names)
+	}
+	if cmd, _ := Lookup("alias"); IsPlugin(cmd) {
+		t.Fatal("expected the built-in alias command to win")
+	}
+	tool, _ := Lookup("zz-tool")
+	if tool.Help() != "first" {
+		t.Fatalf("expected the first plugin directory to win, got %q", tool.Help())
+	}
+	quiet, _ := Lookup("zz-quiet")
+	if want := "External command " + filepath.Join(second, "gobox-zz-quiet"); quiet.Help() != want {
+		t.Fatalf("expected %q for a plugin without a description, got %q", want, quiet.Help())
+	}
+
+	var out bytes.Buffer
+	writeAliasScript(&out, "bash", aliasCommands(false))
+	if !strings.Contains(out.String(), "alias zz-tool='gobox zz-tool'") {
+		t.Fatalf("expected plugins in the alias script:\n%s", out.String())
+	}
+}
@@ -0,0 +1,72 @@
+package base
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReadOnlyEnv names the environment variable that turns on read-only mode,
+// in which gobox refuses every operation that changes system state.
+const ReadOnlyEnv = "GOBOX_READONLY"
+
+// ReadOnlyExitCode is the exit status of an operation refused in read-only
+// mode (EX_NOPERM from sysexits.h), distinct from usage and runtime errors.
+const ReadOnlyExitCode = 77
+
+// ReadOnlyError reports an operation refused in read-only mode.
+type ReadOnlyError struct {
+	// Op names what was refused as the user spelled it, e.g. "sed -i".
+	Op string
+}
+
+func (e ReadOnlyError) Error() string {
+	if ReadOnlyBuild {
+		return fmt.Sprintf("%s is disabled in this read-only build", e.Op)
+	}
+	return fmt.Sprintf("%s is disabled in read-only mode (%s)", e.Op, ReadOnlyEnv)
+}
+
+func (e ReadOnlyError) ExitCode() int { return ReadOnlyExitCode }
+
+// ReadOnly reports whether inv runs in read-only mode: gobox was built with
+// the readonly tag, inv inherited the mode from its parent, or GOBOX_READONLY
+// is set to a true value.
+func ReadOnly(inv *Invocation) bool {
+	if ReadOnlyBuild || inv.ReadOnly {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(inv.Getenv(ReadOnlyEnv))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// CheckWritable returns a ReadOnlyError for op in read-only mode. Commands
+// call it once their arguments are parsed and before the first syscall that
+// would change anything.
+func CheckWritable(inv *Invocation, op string) error {
+	if ReadOnly(inv) {
+		return ReadOnlyError{Op: op}
+	}
+	return nil
+}
+
+// CheckExternal returns a ReadOnlyError for starting the external program
+// name in read-only mode: gobox cannot tell what another program changes.
+func CheckExternal(inv *Invocation, name string) error {
+	return CheckWritable(inv, "external command "+name)
+}
+
+// WithMutating marks a command whose every run changes state, such as
+// truncate. Run refuses it outright in read-only mode, and alias and the
+// command list leave it out.
+func WithMutating() CommandOption {
+	return func(c *command) { c.mutating = true }
+}
+
+// IsMutating reports whether cmd was registered WithMutating.
+func IsMutating(cmd Command) bool {
+	c, ok := cmd.(command)
+	return ok && c.mutating
+}
@@ -0,0 +1,7 @@
+//go:build !readonly
+
+package base
+
+// ReadOnlyBuild is true in binaries built with -tags readonly, where
+// read-only mode cannot be turned off.
+const ReadOnlyBuild = false
@@ -0,0 +1,84 @@
+package base
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var registerMutatingAliasTestCommand sync.Once
+
+func TestReadOnlyFromEnv(t *testing.T) {
+	if ReadOnlyBuild {
+		t.Skip("read-only build")
+	}
+	cases := map[string]bool{"1": true, "true": true, "YES": true, "on": true, "0": false, "false": false, "": false}
+	for value, want := range cases {
+		inv := &Invocation{Env: []string{ReadOnlyEnv + "=" + value}}
+		if got := ReadOnly(inv); got != want {
+			t.Fatalf("%s=%q: expected %v, got %v", ReadOnlyEnv, value, want, got)
+		}
+	}
+}
+
+func TestCheckWritableError(t *testing.T) {
+	inv := &Invocation{Env: []string{ReadOnlyEnv + "=1"}}
+	err := CheckWritable(inv, "sed -i")
+	var roErr ReadOnlyError
+	if !errors.As(err, &roErr) || roErr.Op != "sed -i" {
+		t.Fatalf("expected a ReadOnlyError for sed -i, got %v", err)
+	}
+	if ExitStatus(err) != ReadOnlyExitCode || !ReportError(err) {
+		t.Fatalf("expected exit %d with a printed error, got %d", ReadOnlyExitCode, ExitStatus(err))
+	}
+	if !strings.Contains(err.Error(), "sed -i is disabled") {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+	if !ReadOnlyBuild {
+		if err := CheckWritable(&Invocation{Env: []string{}}, "sed -i"); err != nil {
+			t.Fatalf("expected no error outside read-only mode, got %v", err)
+		}
+	}
+}
+
+func TestRunRefusesMutatingCommandInReadOnlyMode(t *testing.T) {
+	ran := false
+	cmd := NewCommand("zz_test_mutating", "", func(inv *Invocation, args []string) error {
+		ran = true
+		return nil
+	}, WithMutating())
+	err := cmd.Run(&Invocation{Env: []string{ReadOnlyEnv + "=1"}}, nil)
+	if ran || ExitStatus(err) != ReadOnlyExitCode {
+		t.Fatalf("expected the handler not to run, got ran=%v err=%v", ran, err)
+	}
+	if !ReadOnlyBuild {
+		if err := cmd.Run(&Invocation{Env: []string{}}, nil); err != nil || !ran {
+			t.Fatalf("expected the handler to run outside read-only mode, got ran=%v err=%v", ran, err)
+		}
+	}
+}
+
+func TestAliasSkipsMutatingCommandsInReadOnlyMode(t *testing.T) {
+	ensureAliasTestCommands()
+	stubAliasParent(t, "")
+	registerMutatingAliasTestCommand.Do(func() {
+		Register(NewCommand("zz_test_alias_mutating", "", func(inv *Invocation, args []string) error { return nil }, WithMutating()))
+	})
+
+	var out bytes.Buffer
+	if err := aliasCmd(&Invocation{Stdout: &out, Env: []string{ReadOnlyEnv + "=1"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "zz_test_alias_mutating") || !strings.Contains(out.String(), "alias zz_test_alias_cmd=") {
+		t.Fatalf("expected only the mutating command to be skipped, got %q", out.String())
+	}
+	out.Reset()
+	if err := aliasCmd(&Invocation{Stdout: &out, Env: []string{ReadOnlyEnv + "=1"}}, []string{"-u"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "unalias zz_test_alias_mutating") {
+		t.Fatalf("expected unalias to still cover every command, got %q", out.String())
+	}
+}
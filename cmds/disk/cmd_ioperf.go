@@ -3,6 +3,7 @@ package disk
 import (
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"math"
@@ -25,8 +26,12 @@ const O_DSYNC = 0x1000
 
 // IoperfCmd implements an I/O performance benchmark tool, simplified fio-like.
 func IoperfCmd(args []string) error {
+	return ioperfCmd(base.Stdio(), args)
+}
+
+func ioperfCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("ioperf", flag.ContinueOnError)
-	fsFlags.SetOutput(os.Stderr)
+	fsFlags.SetOutput(inv.Stderr)
 	rwMode := fsFlags.String("rw", "read", "I/O mode: read, write, randread, randwrite, readwrite")
 	rwMixRead := fsFlags.Int("rwmixread", 50, "read ratio (0-100) for readwrite mode")
 	filename := fsFlags.String("filename", "/tmp/ioperf_test", "test file path (jobs create filename.0, filename.1, ...)")
@@ -47,41 +52,41 @@ func IoperfCmd(args []string) error {
 	writeHistLog := fsFlags.String("write_hist_log", "", "fio-compatible histogram log prefix")
 
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox ioperf [OPTION]...")
-		fmt.Fprintln(os.Stderr, "I/O performance benchmark tool, simplified fio-like")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Workload:")
-		fmt.Fprintln(os.Stderr, "  --rw MODE                 I/O mode: read, write, randread, randwrite, readwrite")
-		fmt.Fprintln(os.Stderr, "  --rwmixread N             read ratio for readwrite mode")
-		fmt.Fprintln(os.Stderr, "  --filename PATH           test file path")
-		fmt.Fprintln(os.Stderr, "  --bs SIZE                 block size")
-		fmt.Fprintln(os.Stderr, "  --size SIZE               total I/O data size")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Parallelism and rate:")
-		fmt.Fprintln(os.Stderr, "  --numjobs N               parallel job count")
-		fmt.Fprintln(os.Stderr, "  --iodepth N               queue depth")
-		fmt.Fprintln(os.Stderr, "  --rate RATE               rate limit")
-		fmt.Fprintln(os.Stderr, "  --time_based              run based on time")
-		fmt.Fprintln(os.Stderr, "  --runtime SEC             runtime in seconds for time_based mode")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "I/O behavior:")
-		fmt.Fprintln(os.Stderr, "  --direct 0|1              use O_DIRECT to bypass cache")
-		fmt.Fprintln(os.Stderr, "  --fsync 0|1               execute fsync after each write")
-		fmt.Fprintln(os.Stderr, "  --sync MODE               synchronous write mode: none|sync|dsync|0|1")
-		fmt.Fprintln(os.Stderr, "  --group_reporting         aggregate multi-job reports")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Latency and histograms:")
-		fmt.Fprintln(os.Stderr, "  --percentile N            single percentile alias")
-		fmt.Fprintln(os.Stderr, "  --percentile_list LIST    fio-compatible latency percentile list")
-		fmt.Fprintln(os.Stderr, "  --latency                 output latency distribution histogram")
-		fmt.Fprintln(os.Stderr, "  --write_hist_log PREFIX   fio-compatible histogram log prefix")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "  -h, --help                show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox ioperf --rw=write --filename=/tmp/testfile --size=1G --bs=4k")
-		fmt.Fprintln(os.Stderr, "  gobox ioperf --rw=randread --filename=/tmp/testfile --size=1G --numjobs=4 --direct=1")
-		fmt.Fprintln(os.Stderr, "  gobox ioperf --rw=readwrite --rwmixread=70 --filename=/tmp/testfile --size=1G --numjobs=4 --iodepth=4")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox ioperf [OPTION]...")
+		fmt.Fprintln(inv.Stderr, "I/O performance benchmark tool, simplified fio-like")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Workload:")
+		fmt.Fprintln(inv.Stderr, "  --rw MODE                 I/O mode: read, write, randread, randwrite, readwrite")
+		fmt.Fprintln(inv.Stderr, "  --rwmixread N             read ratio for readwrite mode")
+		fmt.Fprintln(inv.Stderr, "  --filename PATH           test file path")
+		fmt.Fprintln(inv.Stderr, "  --bs SIZE                 block size")
+		fmt.Fprintln(inv.Stderr, "  --size SIZE               total I/O data size")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Parallelism and rate:")
+		fmt.Fprintln(inv.Stderr, "  --numjobs N               parallel job count")
+		fmt.Fprintln(inv.Stderr, "  --iodepth N               queue depth")
+		fmt.Fprintln(inv.Stderr, "  --rate RATE               rate limit")
+		fmt.Fprintln(inv.Stderr, "  --time_based              run based on time")
+		fmt.Fprintln(inv.Stderr, "  --runtime SEC             runtime in seconds for time_based mode")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "I/O behavior:")
+		fmt.Fprintln(inv.Stderr, "  --direct 0|1              use O_DIRECT to bypass cache")
+		fmt.Fprintln(inv.Stderr, "  --fsync 0|1               execute fsync after each write")
+		fmt.Fprintln(inv.Stderr, "  --sync MODE               synchronous write mode: none|sync|dsync|0|1")
+		fmt.Fprintln(inv.Stderr, "  --group_reporting         aggregate multi-job reports")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Latency and histograms:")
+		fmt.Fprintln(inv.Stderr, "  --percentile N            single percentile alias")
+		fmt.Fprintln(inv.Stderr, "  --percentile_list LIST    fio-compatible latency percentile list")
+		fmt.Fprintln(inv.Stderr, "  --latency                 output latency distribution histogram")
+		fmt.Fprintln(inv.Stderr, "  --write_hist_log PREFIX   fio-compatible histogram log prefix")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "  -h, --help                show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox ioperf --rw=write --filename=/tmp/testfile --size=1G --bs=4k")
+		fmt.Fprintln(inv.Stderr, "  gobox ioperf --rw=randread --filename=/tmp/testfile --size=1G --numjobs=4 --direct=1")
+		fmt.Fprintln(inv.Stderr, "  gobox ioperf --rw=readwrite --rwmixread=70 --filename=/tmp/testfile --size=1G --numjobs=4 --iodepth=4")
 	}
 
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
@@ -136,6 +141,22 @@ func IoperfCmd(args []string) error {
 		return fmt.Errorf("ioperf: rwmixread is only valid in readwrite mode")
 	}
 
+	// Read-only mode refuses the write modes and the histogram log, and
+	// read modes then use an existing file as it is instead of creating or
+	// growing it.
+	readOnly := base.ReadOnly(inv)
+	switch *rwMode {
+	case "write", "randwrite", "readwrite":
+		if err := base.CheckWritable(inv, "ioperf --rw="+*rwMode); err != nil {
+			return err
+		}
+	}
+	if *writeHistLog != "" {
+		if err := base.CheckWritable(inv, "ioperf --write_hist_log"); err != nil {
+			return err
+		}
+	}
+
 	var syncFileFlag int
 	switch strings.ToLower(strings.TrimSpace(*syncMode)) {
 	case "", "none", "0":
@@ -166,7 +187,7 @@ func IoperfCmd(args []string) error {
 	}
 
 	// Ensure filename is not a device file
-	if isDevicePath(*filename) {
+	if isDevicePath(inv.Path(*filename)) {
 		return fmt.Errorf("ioperf: refusing to write to device file %q", *filename)
 	}
 
@@ -214,15 +235,30 @@ func IoperfCmd(args []string) error {
 
 			// Open file for this job
 			fileFlags := os.O_RDWR | os.O_CREATE
+			if readOnly {
+				fileFlags = os.O_RDONLY
+			}
 			if *direct == 1 {
 				fileFlags |= O_DIRECT
 			}
 			if syncFileFlag != 0 {
 				fileFlags |= syncFileFlag
 			}
-			file, err := os.OpenFile(jobFilename, fileFlags, 0644)
+			// Write modes overwrite the target, so each job's file goes
+			// into the audit log along with how much it wrote.
+			var jobErr error
+			var jobWritten int64
+			switch *rwMode {
+			case "write", "randwrite", "readwrite":
+				defer func() {
+					base.Audit(inv, "write", inv.Path(jobFilename), jobErr, "mode", *rwMode, "bytes", strconv.FormatInt(jobWritten, 10))
+				}()
+			}
+
+			file, err := os.OpenFile(inv.Path(jobFilename), fileFlags, 0644)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "ioperf: job %d: failed to open %s: %v\n", jid, jobFilename, err)
+				jobErr = err
+				fmt.Fprintf(inv.Stderr, "ioperf: job %d: failed to open %s: %v\n", jid, jobFilename, err)
 				resultChan <- jobResult{jobID: jid}
 				return
 			}
@@ -237,14 +273,18 @@ func IoperfCmd(args []string) error {
 			switch *rwMode {
 			case "write", "randwrite", "readwrite":
 				if err := file.Truncate(sizeBytes); err != nil {
-					fmt.Fprintf(os.Stderr, "ioperf: job %d: truncate %s: %v\n", jid, jobFilename, err)
+					jobErr = err
+					fmt.Fprintf(inv.Stderr, "ioperf: job %d: truncate %s: %v\n", jid, jobFilename, err)
 					resultChan <- jobResult{jobID: jid}
 					return
 				}
 			case "read", "randread":
+				if readOnly {
+					break
+				}
 				if info, statErr := file.Stat(); statErr == nil && info.Size() < sizeBytes {
 					if err := file.Truncate(sizeBytes); err != nil {
-						fmt.Fprintf(os.Stderr, "ioperf: job %d: truncate %s: %v\n", jid, jobFilename, err)
+						fmt.Fprintf(inv.Stderr, "ioperf: job %d: truncate %s: %v\n", jid, jobFilename, err)
 						resultChan <- jobResult{jobID: jid}
 						return
 					}
@@ -386,6 +426,7 @@ func IoperfCmd(args []string) error {
 			}
 
 			workerWG.Wait()
+			jobWritten = aggResult.writeBytes
 			resultChan <- aggResult
 		}(jobID)
 	}
@@ -465,7 +506,7 @@ func IoperfCmd(args []string) error {
 	}
 
 	// Print header
-	fmt.Printf("ioperf: bs=%s, jobs=%d, iodepth=%d\n", *blockSize, *numJobs, *ioDepth)
+	fmt.Fprintf(inv.Stdout, "ioperf: bs=%s, jobs=%d, iodepth=%d\n", *blockSize, *numJobs, *ioDepth)
 
 	printResult := func(prefix string, readOps, writeOps, readBytes, writeBytes int64, readLatencies, writeLatencies []int64) {
 		localReadBW := float64(readBytes) / (1024 * 1024) / duration
@@ -475,11 +516,11 @@ func IoperfCmd(args []string) error {
 
 		if readOps > 0 || *rwMode == "read" || *rwMode == "randread" || *rwMode == "readwrite" {
 			avgLat, pLat := calcLatencyStats(readLatencies, latencyPercentiles)
-			fmt.Printf("%sREAD:  IOPS=%.0f, BW=%.2fMB/s, lat=%s\n", prefix, localReadIOPS, localReadBW, formatLat(avgLat, pLat, latencyPercentiles))
+			fmt.Fprintf(inv.Stdout, "%sREAD:  IOPS=%.0f, BW=%.2fMB/s, lat=%s\n", prefix, localReadIOPS, localReadBW, formatLat(avgLat, pLat, latencyPercentiles))
 		}
 		if writeOps > 0 || *rwMode == "write" || *rwMode == "randwrite" || *rwMode == "readwrite" {
 			avgLat, pLat := calcLatencyStats(writeLatencies, latencyPercentiles)
-			fmt.Printf("%sWRITE: IOPS=%.0f, BW=%.2fMB/s, lat=%s\n", prefix, localWriteIOPS, localWriteBW, formatLat(avgLat, pLat, latencyPercentiles))
+			fmt.Fprintf(inv.Stdout, "%sWRITE: IOPS=%.0f, BW=%.2fMB/s, lat=%s\n", prefix, localWriteIOPS, localWriteBW, formatLat(avgLat, pLat, latencyPercentiles))
 		}
 	}
 
@@ -487,29 +528,29 @@ func IoperfCmd(args []string) error {
 		printResult("", totalReadOps, totalWriteOps, totalReadBytes, totalWriteBytes, allReadLatencies, allWriteLatencies)
 	} else {
 		for _, result := range results {
-			fmt.Printf("job %d:\n", result.jobID)
+			fmt.Fprintf(inv.Stdout, "job %d:\n", result.jobID)
 			printResult("  ", result.readOps, result.writeOps, result.readBytes, result.writeBytes, result.readLatencies, result.writeLatencies)
 		}
 	}
 
 	// Print latency histogram if requested
 	if histogramEnabled {
-		fmt.Println("\nLatency histogram (us):")
+		fmt.Fprintln(inv.Stdout, "\nLatency histogram (us):")
 		if len(allReadLatencies) > 0 {
-			printLatencyHistogram("READ", allReadLatencies)
+			printLatencyHistogram(inv.Stdout, "READ", allReadLatencies)
 		}
 		if len(allWriteLatencies) > 0 {
-			printLatencyHistogram("WRITE", allWriteLatencies)
+			printLatencyHistogram(inv.Stdout, "WRITE", allWriteLatencies)
 		}
 	}
 	if *writeHistLog != "" {
 		if len(allReadLatencies) > 0 {
-			if err := writeLatencyHistogramLog(*writeHistLog, "read", allReadLatencies); err != nil {
+			if err := writeLatencyHistogramLog(inv.Path(*writeHistLog), "read", allReadLatencies); err != nil {
 				return err
 			}
 		}
 		if len(allWriteLatencies) > 0 {
-			if err := writeLatencyHistogramLog(*writeHistLog, "write", allWriteLatencies); err != nil {
+			if err := writeLatencyHistogramLog(inv.Path(*writeHistLog), "write", allWriteLatencies); err != nil {
 				return err
 			}
 		}
@@ -622,7 +663,7 @@ func isDevicePath(path string) bool {
 }
 
 // printLatencyHistogram prints a latency distribution histogram
-func printLatencyHistogram(label string, latencies []int64) {
+func printLatencyHistogram(w io.Writer, label string, latencies []int64) {
 	if len(latencies) == 0 {
 		return
 	}
@@ -644,25 +685,25 @@ func printLatencyHistogram(label string, latencies []int64) {
 	}
 
 	total := len(latencies)
-	fmt.Printf("%s latency distribution (%d samples):\n", label, total)
+	fmt.Fprintf(w, "%s latency distribution (%d samples):\n", label, total)
 
 	// Print header
-	fmt.Printf("%-15s %10s %10s\n", "Bucket (us)", "Count", "Percent")
+	fmt.Fprintf(w, "%-15s %10s %10s\n", "Bucket (us)", "Count", "Percent")
 
 	// Print each bucket
 	for i, b := range buckets {
 		count := bucketCounts[i]
 		percent := float64(count) * 100 / float64(total)
 		if i == 0 {
-			fmt.Printf("%-15s %10d %9.2f%%\n", fmt.Sprintf("< %d", b), count, percent)
+			fmt.Fprintf(w, "%-15s %10d %9.2f%%\n", fmt.Sprintf("< %d", b), count, percent)
 		} else {
-			fmt.Printf("%-15s %10d %9.2f%%\n", fmt.Sprintf("%d-%d", buckets[i-1], b), count, percent)
+			fmt.Fprintf(w, "%-15s %10d %9.2f%%\n", fmt.Sprintf("%d-%d", buckets[i-1], b), count, percent)
 		}
 	}
 	// Last bucket (above max)
 	count := bucketCounts[len(buckets)]
 	percent := float64(count) * 100 / float64(total)
-	fmt.Printf("%-15s %10d %9.2f%%\n", fmt.Sprintf("> %d", buckets[len(buckets)-1]), count, percent)
+	fmt.Fprintf(w, "%-15s %10d %9.2f%%\n", fmt.Sprintf("> %d", buckets[len(buckets)-1]), count, percent)
 }
 
 func parsePercentileList(list string, single int) ([]float64, error) {
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"gobox/cmds/base"
 )
 
 // runIoperfCmd runs IoperfCmd and captures stdout and stderr
@@ -453,6 +455,26 @@ func TestIoperfCmdGroupReportingAggregatesJobs(t *testing.T) {
 
 // ============== ERROR CASES ==============
 
+func TestIoperfReadOnlyMode(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ioperf is Linux-only")
+	}
+	dir := t.TempDir()
+	inv := &base.Invocation{Stdout: io.Discard, Stderr: io.Discard, Env: []string{base.ReadOnlyEnv + "=1"}, Dir: dir}
+	for _, mode := range []string{"write", "randwrite", "readwrite"} {
+		err := ioperfCmd(inv, []string{"--rw=" + mode, "--filename=test.bin", "--size=8k", "--bs=4k"})
+		if base.ExitStatus(err) != base.ReadOnlyExitCode {
+			t.Fatalf("--rw=%s: expected a read-only refusal, got %v", mode, err)
+		}
+	}
+	if err := ioperfCmd(inv, []string{"--rw=read", "--filename=test.bin", "--size=8k", "--bs=4k"}); err != nil {
+		t.Fatalf("expected read mode to run, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.bin")); !os.IsNotExist(err) {
+		t.Fatalf("expected read mode not to create the file in read-only mode, got %v", err)
+	}
+}
+
 func TestIoperfCmdDeviceFileRejectionNull(t *testing.T) {
 	// /dev/null should be rejected as a device file
 	args := []string{
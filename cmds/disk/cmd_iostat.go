@@ -6,10 +6,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"gobox/pkg/procfs"
 	"io"
 	"os"
-	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -17,8 +18,6 @@ import (
 	"time"
 )
 
-const diskSectorSize = 512
-
 type ioCounters struct {
 	Name         string
 	ReadBytes    uint64
@@ -57,12 +56,12 @@ var (
 )
 
 func IostatCmd(args []string) error {
-	return iostatCmd(args, os.Stdout)
+	return iostatCmd(base.Stdio(), args)
 }
 
-func iostatCmd(args []string, stdout io.Writer) error {
+func iostatCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("iostat", flag.ContinueOnError)
-	fsFlags.SetOutput(os.Stderr)
+	fsFlags.SetOutput(inv.Stderr)
 
 	interval := fsFlags.Int("i", 1, "sample interval in seconds")
 	count := fsFlags.Int("n", 1, "number of samples to take")
@@ -70,38 +69,38 @@ func iostatCmd(args []string, stdout io.Writer) error {
 	showNonZero := fsFlags.Bool("z", false, "show only devices with non-zero I/O rates")
 	useCgroup := fsFlags.Bool("cgroup", false, "use cgroup io.stat/blkio based output")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox iostat [OPTION]... [interval [count]]")
-		fmt.Fprintln(os.Stderr, "Report block device I/O activity sampled over time.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "By default gobox reads /proc/diskstats and prints per-device rates.")
-		fmt.Fprintln(os.Stderr, "With --cgroup it reads cgroup io.stat / blkio counters instead.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -i SEC      sample interval in seconds")
-		fmt.Fprintln(os.Stderr, "  -n COUNT    number of samples to take")
-		fmt.Fprintln(os.Stderr, "  -H          humanize IOPS and throughput")
-		fmt.Fprintln(os.Stderr, "  -z          show only devices with non-zero I/O rates")
-		fmt.Fprintln(os.Stderr, "  --cgroup    use cgroup io.stat/blkio based output")
-		fmt.Fprintln(os.Stderr, "  -h, --help  show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Positionals:")
-		fmt.Fprintln(os.Stderr, "  interval   sample interval in seconds (same as -i)")
-		fmt.Fprintln(os.Stderr, "  count      number of reports to print (same as -n)")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Columns:")
-		fmt.Fprintln(os.Stderr, "  Device     device name from diskstats or cgroup entry")
-		fmt.Fprintln(os.Stderr, "  ReadIOPS   read operations per second")
-		fmt.Fprintln(os.Stderr, "  WriteIOPS  write operations per second")
-		fmt.Fprintln(os.Stderr, "  TotalIOPS  combined read + write IOPS")
-		fmt.Fprintln(os.Stderr, "  ReadB/s    read throughput in bytes per second")
-		fmt.Fprintln(os.Stderr, "  WriteB/s   write throughput in bytes per second")
-		fmt.Fprintln(os.Stderr, "  TotalB/s   combined throughput in bytes per second")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox iostat")
-		fmt.Fprintln(os.Stderr, "  gobox iostat 1 5")
-		fmt.Fprintln(os.Stderr, "  gobox iostat -i 2 -n 3 -H -z")
-		fmt.Fprintln(os.Stderr, "  gobox iostat --cgroup 1 1")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox iostat [OPTION]... [interval [count]]")
+		fmt.Fprintln(inv.Stderr, "Report block device I/O activity sampled over time.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "By default gobox reads /proc/diskstats and prints per-device rates.")
+		fmt.Fprintln(inv.Stderr, "With --cgroup it reads cgroup io.stat / blkio counters instead.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -i SEC      sample interval in seconds")
+		fmt.Fprintln(inv.Stderr, "  -n COUNT    number of samples to take")
+		fmt.Fprintln(inv.Stderr, "  -H          humanize IOPS and throughput")
+		fmt.Fprintln(inv.Stderr, "  -z          show only devices with non-zero I/O rates")
+		fmt.Fprintln(inv.Stderr, "  --cgroup    use cgroup io.stat/blkio based output")
+		fmt.Fprintln(inv.Stderr, "  -h, --help  show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Positionals:")
+		fmt.Fprintln(inv.Stderr, "  interval   sample interval in seconds (same as -i)")
+		fmt.Fprintln(inv.Stderr, "  count      number of reports to print (same as -n)")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Columns:")
+		fmt.Fprintln(inv.Stderr, "  Device     device name from diskstats or cgroup entry")
+		fmt.Fprintln(inv.Stderr, "  ReadIOPS   read operations per second")
+		fmt.Fprintln(inv.Stderr, "  WriteIOPS  write operations per second")
+		fmt.Fprintln(inv.Stderr, "  TotalIOPS  combined read + write IOPS")
+		fmt.Fprintln(inv.Stderr, "  ReadB/s    read throughput in bytes per second")
+		fmt.Fprintln(inv.Stderr, "  WriteB/s   write throughput in bytes per second")
+		fmt.Fprintln(inv.Stderr, "  TotalB/s   combined throughput in bytes per second")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox iostat")
+		fmt.Fprintln(inv.Stderr, "  gobox iostat 1 5")
+		fmt.Fprintln(inv.Stderr, "  gobox iostat -i 2 -n 3 -H -z")
+		fmt.Fprintln(inv.Stderr, "  gobox iostat --cgroup 1 1")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -124,7 +123,7 @@ func iostatCmd(args []string, stdout io.Writer) error {
 		return errors.New("iostat: count must be >= 1")
 	}
 
-	reader, err := buildIostatReader(*useCgroup)
+	reader, err := buildIostatReader(inv.Stderr, inv.Roots(), *useCgroup)
 	if err != nil {
 		return err
 	}
@@ -136,12 +135,17 @@ func iostatCmd(args []string, stdout io.Writer) error {
 		return err
 	}
 
+	var tw *utils.TableWriter
+	if utils.IsStructuredOutput(inv.Output) {
+		tw = utils.NewTableWriter(inv.Stdout, inv.Output)
+	}
+
 	for iter := 0; iter < *count; iter++ {
 		var start, end map[string]ioCounters
 		var dur float64
 		if iter == 0 {
 			end = current
-			dur, err = uptimeIostat()
+			dur, err = uptimeIostat(inv.Roots())
 			if err != nil {
 				return err
 			}
@@ -157,9 +161,15 @@ func iostatCmd(args []string, stdout io.Writer) error {
 		}
 
 		rows := buildIostatRows(start, end, dur, *human, *showNonZero, *useCgroup)
-		writeIostatTable(stdout, rows)
+		if tw != nil {
+			if err := tw.Write(iostatTable(rows, iter+1, *useCgroup)); err != nil {
+				return err
+			}
+			continue
+		}
+		writeIostatTable(inv.Stdout, rows)
 		if iter != *count-1 {
-			fmt.Fprintln(stdout)
+			fmt.Fprintln(inv.Stdout)
 		}
 	}
 
@@ -187,17 +197,17 @@ func applyIostatPositionals(args []string, interval, count *int) error {
 	return nil
 }
 
-func buildIostatReader(useCgroup bool) (func() (map[string]ioCounters, error), error) {
+func buildIostatReader(stderr io.Writer, roots utils.Roots, useCgroup bool) (func() (map[string]ioCounters, error), error) {
 	if useCgroup {
-		return buildCgroupReader()
+		return buildCgroupReader(stderr, roots)
 	}
 	return func() (map[string]ioCounters, error) {
-		return readDiskstats("/proc/diskstats")
+		return readDiskstats(roots.ProcPath("diskstats"))
 	}, nil
 }
 
-func readUptimeIostat() (float64, error) {
-	data, err := readFileIostat("/proc/uptime")
+func readUptimeIostat(roots utils.Roots) (float64, error) {
+	data, err := readFileIostat(roots.ProcPath("uptime"))
 	if err != nil {
 		return 0, err
 	}
@@ -220,59 +230,39 @@ func readDiskstats(path string) (map[string]ioCounters, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	out := make(map[string]ioCounters)
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 14 {
-			continue
-		}
-		readIOs, err1 := strconv.ParseUint(fields[3], 10, 64)
-		readSectors, err2 := strconv.ParseUint(fields[5], 10, 64)
-		writeIOs, err3 := strconv.ParseUint(fields[7], 10, 64)
-		writeSectors, err4 := strconv.ParseUint(fields[9], 10, 64)
-		ioMillis, err5 := strconv.ParseUint(fields[12], 10, 64)
-		weightedIOms, err6 := strconv.ParseUint(fields[13], 10, 64)
-		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil {
-			continue
-		}
-
-		name := fields[2]
-		out[name] = ioCounters{
-			Name:         name,
-			ReadBytes:    readSectors * diskSectorSize,
-			WriteBytes:   writeSectors * diskSectorSize,
-			ReadIOs:      readIOs,
-			WriteIOs:     writeIOs,
-			IoMillis:     ioMillis,
-			WeightedIOms: weightedIOms,
+	disks, err := procfs.ParseDiskstats(bytes.NewReader(data))
+	out := make(map[string]ioCounters, len(disks))
+	for _, d := range disks {
+		out[d.Name] = ioCounters{
+			Name:         d.Name,
+			ReadBytes:    d.ReadSectors * procfs.SectorSize,
+			WriteBytes:   d.WriteSectors * procfs.SectorSize,
+			ReadIOs:      d.ReadIOs,
+			WriteIOs:     d.WriteIOs,
+			IoMillis:     d.IOTicks,
+			WeightedIOms: d.WeightedIOTicks,
 		}
 	}
-	return out, scanner.Err()
+	return out, err
 }
 
 // selfCgroupPaths reads /proc/self/cgroup and returns the current process's
 // cgroup v2 unified path (if any) and its cgroup v1 blkio subsystem path (if
 // any). Either may be empty if that hierarchy isn't in use.
-func selfCgroupPaths() (v2Path string, v1BlkioPath string) {
-	data, err := readFileIostat("/proc/self/cgroup")
+func selfCgroupPaths(roots utils.Roots) (v2Path string, v1BlkioPath string) {
+	data, err := readFileIostat(roots.ProcPath(roots.ProcSelf(), "cgroup"))
 	if err != nil {
 		return "", ""
 	}
-	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
-		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
-		if len(parts) != 3 {
-			continue
-		}
-		id, controllers, path := parts[0], parts[1], parts[2]
-		if id == "0" && controllers == "" {
-			v2Path = path
+	cgroups, _ := procfs.ParseCgroups(bytes.NewReader(data))
+	for _, e := range cgroups {
+		if e.Unified() {
+			v2Path = e.Path
 			continue
 		}
-		for _, c := range strings.Split(controllers, ",") {
+		for _, c := range e.Controllers {
 			if c == "blkio" {
-				v1BlkioPath = path
+				v1BlkioPath = e.Path
 			}
 		}
 	}
@@ -285,11 +275,11 @@ func selfCgroupPaths() (v2Path string, v1BlkioPath string) {
 // output). If the io controller isn't delegated to this process's own
 // cgroup, it falls back to the root cgroup as a best-effort approximation
 // and warns that the data is system-wide rather than cgroup-scoped.
-func buildCgroupReader() (func() (map[string]ioCounters, error), error) {
-	v2Path, v1Path := selfCgroupPaths()
+func buildCgroupReader(stderr io.Writer, roots utils.Roots) (func() (map[string]ioCounters, error), error) {
+	v2Path, v1Path := selfCgroupPaths(roots)
 
 	if v2Path != "" {
-		path := filepath.Join("/sys/fs/cgroup", v2Path, "io.stat")
+		path := roots.SysPath("fs", "cgroup", v2Path, "io.stat")
 		if _, err := statIostat(path); err == nil {
 			return func() (map[string]ioCounters, error) {
 				return readCgroupV2(path)
@@ -298,15 +288,15 @@ func buildCgroupReader() (func() (map[string]ioCounters, error), error) {
 	}
 
 	if v1Path != "" {
-		bytesPath := filepath.Join("/sys/fs/cgroup/blkio", v1Path, "blkio.throttle.io_service_bytes")
-		servicedPath := filepath.Join("/sys/fs/cgroup/blkio", v1Path, "blkio.throttle.io_serviced")
+		bytesPath := roots.SysPath("fs", "cgroup", "blkio", v1Path, "blkio.throttle.io_service_bytes")
+		servicedPath := roots.SysPath("fs", "cgroup", "blkio", v1Path, "blkio.throttle.io_serviced")
 		if _, err := statIostat(bytesPath); err == nil {
 			return func() (map[string]ioCounters, error) {
 				return readCgroupV1(bytesPath, servicedPath)
 			}, nil
 		}
-		bytesPath = filepath.Join("/sys/fs/cgroup/blkio", v1Path, "blkio.io_service_bytes")
-		servicedPath = filepath.Join("/sys/fs/cgroup/blkio", v1Path, "blkio.io_serviced")
+		bytesPath = roots.SysPath("fs", "cgroup", "blkio", v1Path, "blkio.io_service_bytes")
+		servicedPath = roots.SysPath("fs", "cgroup", "blkio", v1Path, "blkio.io_serviced")
 		if _, err := statIostat(bytesPath); err == nil {
 			return func() (map[string]ioCounters, error) {
 				return readCgroupV1(bytesPath, servicedPath)
@@ -318,22 +308,22 @@ func buildCgroupReader() (func() (map[string]ioCounters, error), error) {
 	// controller isn't delegated to this process's own cgroup (e.g. session
 	// scopes that don't enable the io controller). This is not truly
 	// cgroup-scoped, so warn rather than silently presenting it as such.
-	if _, err := statIostat("/sys/fs/cgroup/io.stat"); err == nil {
-		fmt.Fprintln(os.Stderr, "iostat: warning: io controller not delegated to current cgroup; falling back to root cgroup (system-wide) io.stat")
+	if _, err := statIostat(roots.SysPath("fs", "cgroup", "io.stat")); err == nil {
+		fmt.Fprintln(stderr, "iostat: warning: io controller not delegated to current cgroup; falling back to root cgroup (system-wide) io.stat")
 		return func() (map[string]ioCounters, error) {
-			return readCgroupV2("/sys/fs/cgroup/io.stat")
+			return readCgroupV2(roots.SysPath("fs", "cgroup", "io.stat"))
 		}, nil
 	}
-	if _, err := statIostat("/sys/fs/cgroup/blkio/blkio.throttle.io_service_bytes"); err == nil {
-		fmt.Fprintln(os.Stderr, "iostat: warning: blkio not delegated to current cgroup; falling back to root cgroup (system-wide) blkio stats")
+	if _, err := statIostat(roots.SysPath("fs", "cgroup", "blkio", "blkio.throttle.io_service_bytes")); err == nil {
+		fmt.Fprintln(stderr, "iostat: warning: blkio not delegated to current cgroup; falling back to root cgroup (system-wide) blkio stats")
 		return func() (map[string]ioCounters, error) {
-			return readCgroupV1("/sys/fs/cgroup/blkio/blkio.throttle.io_service_bytes", "/sys/fs/cgroup/blkio/blkio.throttle.io_serviced")
+			return readCgroupV1(roots.SysPath("fs", "cgroup", "blkio", "blkio.throttle.io_service_bytes"), roots.SysPath("fs", "cgroup", "blkio", "blkio.throttle.io_serviced"))
 		}, nil
 	}
-	if _, err := statIostat("/sys/fs/cgroup/blkio/blkio.io_service_bytes"); err == nil {
-		fmt.Fprintln(os.Stderr, "iostat: warning: blkio not delegated to current cgroup; falling back to root cgroup (system-wide) blkio stats")
+	if _, err := statIostat(roots.SysPath("fs", "cgroup", "blkio", "blkio.io_service_bytes")); err == nil {
+		fmt.Fprintln(stderr, "iostat: warning: blkio not delegated to current cgroup; falling back to root cgroup (system-wide) blkio stats")
 		return func() (map[string]ioCounters, error) {
-			return readCgroupV1("/sys/fs/cgroup/blkio/blkio.io_service_bytes", "/sys/fs/cgroup/blkio/blkio.io_serviced")
+			return readCgroupV1(roots.SysPath("fs", "cgroup", "blkio", "blkio.io_service_bytes"), roots.SysPath("fs", "cgroup", "blkio", "blkio.io_serviced"))
 		}, nil
 	}
 	return nil, errors.New("iostat: no supported cgroup blkio/io.stat files found")
@@ -533,6 +523,21 @@ func buildIostatRows(start, end map[string]ioCounters, dur float64, human, showN
 	return rows
 }
 
+// iostatTable is the structured form of one report. Rates stay unformatted
+// regardless of -h; the latency and utilization columns are null in cgroup
+// mode, which has no per-device busy time.
+func iostatTable(rows []iostatRow, sample int, cgroupMode bool) *utils.Table {
+	t := utils.NewTable("sample", "device", "read_iops", "write_iops", "total_iops", "read_bytes_per_sec", "write_bytes_per_sec", "total_bytes_per_sec", "await_ms", "avg_queue_size", "util_percent")
+	for _, row := range rows {
+		var await, queue, util any
+		if !cgroupMode {
+			await, queue, util = row.Await, row.AvgQueueSize, row.UtilPercent
+		}
+		t.Append(sample, row.FormattedDev, row.ReadIOPS, row.WriteIOPS, row.TotalIOPS, row.ReadBps, row.WriteBps, row.TotalBps, await, queue, util)
+	}
+	return t
+}
+
 func writeIostatTable(w io.Writer, rows []iostatRow) {
 	nameW := len("Device")
 	readW := len("ReadIOPS")
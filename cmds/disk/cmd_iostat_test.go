@@ -3,6 +3,8 @@ package disk
 import (
 	"bytes"
 	"errors"
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
 	"io"
 	"os"
 	"strings"
@@ -30,6 +32,10 @@ func captureIostatHelp(t *testing.T, args []string) (string, error) {
 	return buf.String(), runErr
 }
 
+func testIostatInvocation(out *bytes.Buffer) *base.Invocation {
+	return &base.Invocation{Stdout: out, Stderr: io.Discard}
+}
+
 func TestIostatCmdUsesDiskstatsByDefault(t *testing.T) {
 	oldReadFile := readFileIostat
 	oldUptime := uptimeIostat
@@ -44,10 +50,10 @@ func TestIostatCmdUsesDiskstatsByDefault(t *testing.T) {
 		}
 		return []byte("8 0 sda 100 0 200 0 300 0 400 0 0 500 600\n"), nil
 	}
-	uptimeIostat = func() (float64, error) { return 10, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 10, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"-i", "1", "-n", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"-i", "1", "-n", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -86,10 +92,10 @@ func TestIostatCmdHumanizeUnits(t *testing.T) {
 		}
 		return []byte("8 0 sda 20000 0 204800 0 10000 0 102400 0 0 0 0\n"), nil
 	}
-	uptimeIostat = func() (float64, error) { return 10, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 10, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"-H", "-i", "1", "-n", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"-H", "-i", "1", "-n", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -119,10 +125,10 @@ func TestIostatCmdSupportsPositionals(t *testing.T) {
 		}
 		return []byte("8 0 sda 1 0 2 0 3 0 4 0 0 5 6\n"), nil
 	}
-	uptimeIostat = func() (float64, error) { return 1, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 1, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"1", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"1", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 	if !strings.Contains(out.String(), "sda") {
@@ -152,10 +158,10 @@ func TestIostatCmdUsesCgroupWhenRequested(t *testing.T) {
 		}
 		return nil, os.ErrNotExist
 	}
-	uptimeIostat = func() (float64, error) { return 2, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 2, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"--cgroup", "-i", "1", "-n", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"--cgroup", "-i", "1", "-n", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -203,10 +209,10 @@ func TestIostatCmdUsesOwnCgroupIoStatWhenAvailable(t *testing.T) {
 		}
 		return nil, os.ErrNotExist
 	}
-	uptimeIostat = func() (float64, error) { return 2, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 2, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"--cgroup", "-i", "1", "-n", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"--cgroup", "-i", "1", "-n", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -260,10 +266,10 @@ func TestIostatCmdFallsBackToCgroupV1WhenV2Unavailable(t *testing.T) {
 		// fallback path is actually the one taken.
 		return nil, os.ErrNotExist
 	}
-	uptimeIostat = func() (float64, error) { return 2, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 2, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"--cgroup", "-i", "1", "-n", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"--cgroup", "-i", "1", "-n", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -292,10 +298,10 @@ func TestIostatCmdZeroFilterHidesInactiveRows(t *testing.T) {
 		}
 		return []byte("8 0 sda 0 0 0 0 0 0 0 0 0 0 0\n8 1 sdb 8 0 10 0 11 0 14 0 0 15 18\n"), nil
 	}
-	uptimeIostat = func() (float64, error) { return 1, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 1, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"-z", "1", "1"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"-z", "1", "1"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -334,10 +340,10 @@ func TestIostatCmdUsesIntervalDeltasAfterFirstReport(t *testing.T) {
 		return []byte(out), nil
 	}
 	sleepIostat = func(time.Duration) {}
-	uptimeIostat = func() (float64, error) { return 10, nil }
+	uptimeIostat = func(utils.Roots) (float64, error) { return 10, nil }
 
 	var out bytes.Buffer
-	if err := iostatCmd([]string{"-i", "1", "-n", "2"}, &out); err != nil {
+	if err := iostatCmd(testIostatInvocation(&out), []string{"-i", "1", "-n", "2"}); err != nil {
 		t.Fatalf("iostatCmd failed: %v", err)
 	}
 
@@ -355,7 +361,7 @@ func TestIostatCmdUsesIntervalDeltasAfterFirstReport(t *testing.T) {
 
 func TestIostatCmdRejectsInvalidPositionals(t *testing.T) {
 	var out bytes.Buffer
-	err := iostatCmd([]string{"abc", "1"}, &out)
+	err := iostatCmd(testIostatInvocation(&out), []string{"abc", "1"})
 	if err == nil || !strings.Contains(err.Error(), `invalid interval "abc"`) {
 		t.Fatalf("expected invalid interval error, got %v", err)
 	}
@@ -366,7 +372,7 @@ func TestIostatCmdRejectsInvalidPositionals(t *testing.T) {
 // invalid *interval*, never an invalid count.
 func TestIostatCmdRejectsZeroCount(t *testing.T) {
 	var out bytes.Buffer
-	err := iostatCmd([]string{"-n", "0"}, &out)
+	err := iostatCmd(testIostatInvocation(&out), []string{"-n", "0"})
 	if err == nil {
 		t.Fatalf("expected -n 0 to be rejected, got success with output %q", out.String())
 	}
@@ -377,7 +383,7 @@ func TestIostatCmdRejectsZeroCount(t *testing.T) {
 
 func TestIostatCmdRejectsNegativeCount(t *testing.T) {
 	var out bytes.Buffer
-	err := iostatCmd([]string{"1", "-2"}, &out)
+	err := iostatCmd(testIostatInvocation(&out), []string{"1", "-2"})
 	if err == nil {
 		t.Fatalf("expected a negative positional count to be rejected, got success with output %q", out.String())
 	}
@@ -416,3 +422,42 @@ func (f fakeFileInfo) Mode() os.FileMode  { return 0 }
 func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
 func (f fakeFileInfo) IsDir() bool        { return false }
 func (f fakeFileInfo) Sys() any           { return nil }
+
+func TestIostatCmdStructuredOutputStreamsSamples(t *testing.T) {
+	oldReadFile := readFileIostat
+	oldSleep := sleepIostat
+	oldUptime := uptimeIostat
+	t.Cleanup(func() {
+		readFileIostat = oldReadFile
+		sleepIostat = oldSleep
+		uptimeIostat = oldUptime
+	})
+
+	snapshots := []string{
+		"8 0 sda 100 0 200 0 300 0 400 0 0 500 600\n",
+		"8 0 sda 110 0 240 0 320 0 440 0 0 560 720\n",
+	}
+	readFileIostat = func(path string) ([]byte, error) {
+		if path != "/proc/diskstats" || len(snapshots) == 0 {
+			return nil, os.ErrNotExist
+		}
+		out := snapshots[0]
+		snapshots = snapshots[1:]
+		return []byte(out), nil
+	}
+	sleepIostat = func(time.Duration) {}
+	uptimeIostat = func(utils.Roots) (float64, error) { return 10, nil }
+
+	var out bytes.Buffer
+	inv := testIostatInvocation(&out)
+	inv.Output = "csv"
+	if err := iostatCmd(inv, []string{"-i", "1", "-n", "2", "-H"}); err != nil {
+		t.Fatalf("iostatCmd failed: %v", err)
+	}
+	want := "sample,device,read_iops,write_iops,total_iops,read_bytes_per_sec,write_bytes_per_sec,total_bytes_per_sec,await_ms,avg_queue_size,util_percent\n" +
+		"1,sda,10,30,40,10240,20480,30720,1.5,0.06,5\n" +
+		"2,sda,10,20,30,20480,20480,40960,4,0.12,6\n"
+	if out.String() != want {
+		t.Fatalf("unexpected csv output:\n%s", out.String())
+	}
+}
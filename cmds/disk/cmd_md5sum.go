@@ -6,6 +6,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -36,6 +37,10 @@ func (e md5sumExitError) ExitCode() int {
 }
 
 func Md5sumCmd(args []string) error {
+	return md5sumCmd(base.Stdio(), args)
+}
+
+func md5sumCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("md5sum", flag.ContinueOnError)
 	var checkMode bool
 	var tag bool
@@ -52,18 +57,18 @@ func Md5sumCmd(args []string) error {
 	fsFlags.BoolVar(&warn, "w", false, "warn about malformed lines")
 	fsFlags.BoolVar(&warn, "warn", false, "warn about malformed lines")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox md5sum [OPTION]... [FILE]...")
-		fmt.Fprintln(os.Stderr, "Compute or check MD5 message digests.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Modes:")
-		fmt.Fprintln(os.Stderr, "  -c, --check      check MD5 sums from files")
-		fmt.Fprintln(os.Stderr, "      --tag        use BSD style output (MD5 (file) = xxx)")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Output:")
-		fmt.Fprintln(os.Stderr, "  -q, --quiet      quiet mode")
-		fmt.Fprintln(os.Stderr, "  -s, --status     status mode")
-		fmt.Fprintln(os.Stderr, "  -w, --warn       warn about malformed lines")
-		fmt.Fprintln(os.Stderr, "  -h, --help       show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox md5sum [OPTION]... [FILE]...")
+		fmt.Fprintln(inv.Stderr, "Compute or check MD5 message digests.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Modes:")
+		fmt.Fprintln(inv.Stderr, "  -c, --check      check MD5 sums from files")
+		fmt.Fprintln(inv.Stderr, "      --tag        use BSD style output (MD5 (file) = xxx)")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Output:")
+		fmt.Fprintln(inv.Stderr, "  -q, --quiet      quiet mode")
+		fmt.Fprintln(inv.Stderr, "  -s, --status     status mode")
+		fmt.Fprintln(inv.Stderr, "  -w, --warn       warn about malformed lines")
+		fmt.Fprintln(inv.Stderr, "  -h, --help       show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -76,21 +81,27 @@ func Md5sumCmd(args []string) error {
 
 	// If no files and no stdin, show usage
 	if len(files) == 0 {
-		stat, _ := os.Stdin.Stat()
-		hasStdinData := (stat.Mode() & os.ModeCharDevice) == 0
+		// Only a terminal counts as "no input"; pipes, files and in-process
+		// readers all carry data.
+		hasStdinData := true
+		if f, ok := inv.Stdin.(*os.File); ok {
+			if stat, err := f.Stat(); err == nil {
+				hasStdinData = (stat.Mode() & os.ModeCharDevice) == 0
+			}
+		}
 		if checkMode {
 			// -c with no file operands reads the checksum list from stdin,
 			// matching GNU md5sum -- it must not fall through to compute
 			// mode and hash the stdin bytes themselves.
 			if hasStdinData {
-				return md5sumCheckStdin(warn, status, quiet)
+				return md5sumCheckStdin(inv, warn, status, quiet)
 			}
 			fsFlags.Usage()
 			return errors.New("no files specified")
 		}
 		if hasStdinData {
 			// Data is available on stdin
-			if err := md5sumStdin(tag, quiet); err != nil {
+			if err := md5sumStdin(inv.Stdout, inv.Stdin, tag, quiet); err != nil {
 				return err
 			}
 			return nil
@@ -100,7 +111,7 @@ func Md5sumCmd(args []string) error {
 	}
 
 	if checkMode {
-		return md5sumCheck(files, warn, status, quiet)
+		return md5sumCheck(inv, files, warn, status, quiet)
 	}
 
 	if quiet {
@@ -108,7 +119,7 @@ func Md5sumCmd(args []string) error {
 	}
 
 	// Default: compute mode
-	return md5sumFiles(files, tag, quiet)
+	return md5sumFiles(inv, files, tag, quiet)
 }
 
 type md5sumQuietError struct{}
@@ -119,30 +130,30 @@ func (md5sumQuietError) Error() string {
 func (md5sumQuietError) ExitCode() int          { return 1 }
 func (md5sumQuietError) SuppressCLIError() bool { return false }
 
-func md5sumStdin(tag, quiet bool) error {
+func md5sumStdin(w io.Writer, stdin io.Reader, tag, quiet bool) error {
 	h := md5.New()
-	if _, err := io.Copy(h, os.Stdin); err != nil {
+	if _, err := io.Copy(h, stdin); err != nil {
 		return err
 	}
 	hashStr := fmt.Sprintf("%x", h.Sum(nil))
 	if tag {
-		fmt.Printf("MD5 (stdin) = %s\n", hashStr)
+		fmt.Fprintf(w, "MD5 (stdin) = %s\n", hashStr)
 	} else if quiet {
-		fmt.Println(hashStr)
+		fmt.Fprintln(w, hashStr)
 	} else {
-		fmt.Printf("%s  -\n", hashStr)
+		fmt.Fprintf(w, "%s  -\n", hashStr)
 	}
 	return nil
 }
 
-func md5sumFiles(files []string, tag, quiet bool) error {
+func md5sumFiles(inv *base.Invocation, files []string, tag, quiet bool) error {
 	var hadErr bool
 	for _, file := range files {
-		f, err := os.Open(file)
+		f, err := os.Open(inv.Path(file))
 		if err != nil {
 			hadErr = true
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "md5sum: %s: %v\n", file, err)
+				fmt.Fprintf(inv.Stderr, "md5sum: %s: %v\n", file, err)
 			}
 			continue
 		}
@@ -151,17 +162,17 @@ func md5sumFiles(files []string, tag, quiet bool) error {
 		if err != nil {
 			hadErr = true
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "md5sum: %s: %v\n", file, err)
+				fmt.Fprintf(inv.Stderr, "md5sum: %s: %v\n", file, err)
 			}
 			continue
 		}
 		hashStr := fmt.Sprintf("%x", hash)
 		if tag {
-			fmt.Printf("MD5 (%s) = %s\n", file, hashStr)
+			fmt.Fprintf(inv.Stdout, "MD5 (%s) = %s\n", file, hashStr)
 		} else if quiet {
-			fmt.Println(hashStr)
+			fmt.Fprintln(inv.Stdout, hashStr)
 		} else {
-			fmt.Printf("%s  %s\n", hashStr, file)
+			fmt.Fprintf(inv.Stdout, "%s  %s\n", hashStr, file)
 		}
 	}
 	if hadErr {
@@ -178,27 +189,27 @@ func computeMD5(r io.Reader) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
-func md5sumCheck(files []string, warn, status, quiet bool) error {
+func md5sumCheck(inv *base.Invocation, files []string, warn, status, quiet bool) error {
 	var hasError bool
 
 	for _, file := range files {
 		// "-" as a checksum-file operand means read the checksum list from
 		// stdin, matching GNU md5sum -c -.
 		if file == "-" {
-			if md5sumCheckReader(os.Stdin, "-", warn, status, quiet) {
+			if md5sumCheckReader(inv, inv.Stdin, "-", warn, status, quiet) {
 				hasError = true
 			}
 			continue
 		}
-		f, err := os.Open(file)
+		f, err := os.Open(inv.Path(file))
 		if err != nil {
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "md5sum: %s: %v\n", file, err)
+				fmt.Fprintf(inv.Stderr, "md5sum: %s: %v\n", file, err)
 			}
 			hasError = true
 			continue
 		}
-		if md5sumCheckReader(f, file, warn, status, quiet) {
+		if md5sumCheckReader(inv, f, file, warn, status, quiet) {
 			hasError = true
 		}
 		f.Close()
@@ -213,8 +224,8 @@ func md5sumCheck(files []string, warn, status, quiet bool) error {
 // md5sumCheckStdin implements `-c` with no file operands: GNU md5sum reads
 // the checksum list from stdin in that case (rather than falling through to
 // compute mode and hashing the stdin bytes themselves).
-func md5sumCheckStdin(warn, status, quiet bool) error {
-	if md5sumCheckReader(os.Stdin, "-", warn, status, quiet) {
+func md5sumCheckStdin(inv *base.Invocation, warn, status, quiet bool) error {
+	if md5sumCheckReader(inv, inv.Stdin, "-", warn, status, quiet) {
 		return md5sumExitError{code: 1, err: errors.New("checksum verification failed")}
 	}
 	return nil
@@ -227,7 +238,7 @@ func md5sumCheckStdin(warn, status, quiet bool) error {
 // do not make it return true -- matching GNU md5sum, they only ever produce
 // a warning (optionally per-line via -w, always as a trailing summary),
 // never a checksum-verification failure.
-func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool) bool {
+func md5sumCheckReader(inv *base.Invocation, r io.Reader, sourceName string, warn, status, quiet bool) bool {
 	var hasError bool
 	var malformed, processed int
 	scanner := bufio.NewScanner(r)
@@ -248,7 +259,7 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 			parts := strings.SplitN(line, " = ", 2)
 			if len(parts) != 2 {
 				if warn {
-					fmt.Fprintf(os.Stderr, "md5sum: %s:%d: improperly formatted BSD style checksum line\n", sourceName, lineNum)
+					fmt.Fprintf(inv.Stderr, "md5sum: %s:%d: improperly formatted BSD style checksum line\n", sourceName, lineNum)
 				}
 				malformed++
 				continue
@@ -257,7 +268,7 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 			middle := strings.TrimPrefix(parts[0], "MD5 (")
 			if !strings.HasSuffix(middle, ")") {
 				if warn {
-					fmt.Fprintf(os.Stderr, "md5sum: %s:%d: improperly formatted BSD style checksum line\n", sourceName, lineNum)
+					fmt.Fprintf(inv.Stderr, "md5sum: %s:%d: improperly formatted BSD style checksum line\n", sourceName, lineNum)
 				}
 				malformed++
 				continue
@@ -270,7 +281,7 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 			parts := strings.Fields(line)
 			if len(parts) < 2 {
 				if warn {
-					fmt.Fprintf(os.Stderr, "md5sum: %s:%d: improperly formatted checksum line\n", sourceName, lineNum)
+					fmt.Fprintf(inv.Stderr, "md5sum: %s:%d: improperly formatted checksum line\n", sourceName, lineNum)
 				}
 				malformed++
 				continue
@@ -278,7 +289,7 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 			expectedHash = parts[0]
 			if !md5HexPattern.MatchString(expectedHash) {
 				if warn {
-					fmt.Fprintf(os.Stderr, "md5sum: %s:%d: improperly formatted checksum line\n", sourceName, lineNum)
+					fmt.Fprintf(inv.Stderr, "md5sum: %s:%d: improperly formatted checksum line\n", sourceName, lineNum)
 				}
 				malformed++
 				continue
@@ -290,13 +301,13 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 		processed++
 
 		// Compute actual hash
-		fileToCheck, err := os.Open(filename)
+		fileToCheck, err := os.Open(inv.Path(filename))
 		if err != nil {
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "md5sum: %s: %v\n", filename, err)
+				fmt.Fprintf(inv.Stderr, "md5sum: %s: %v\n", filename, err)
 			}
 			if !quiet && !status {
-				fmt.Printf("%s: FAILED open or read\n", filename)
+				fmt.Fprintf(inv.Stdout, "%s: FAILED open or read\n", filename)
 			}
 			hasError = true
 			continue
@@ -306,7 +317,7 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 		fileToCheck.Close()
 		if err != nil {
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "md5sum: %s: %v\n", filename, err)
+				fmt.Fprintf(inv.Stderr, "md5sum: %s: %v\n", filename, err)
 			}
 			hasError = true
 			continue
@@ -317,10 +328,10 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 		if !status {
 			if actualHash == expectedHash {
 				if !quiet {
-					fmt.Printf("%s: OK\n", filename)
+					fmt.Fprintf(inv.Stdout, "%s: OK\n", filename)
 				}
 			} else {
-				fmt.Printf("%s: FAILED\n", filename)
+				fmt.Fprintf(inv.Stdout, "%s: FAILED\n", filename)
 				hasError = true
 			}
 		} else {
@@ -332,7 +343,7 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 
 	if err := scanner.Err(); err != nil {
 		if !quiet {
-			fmt.Fprintf(os.Stderr, "md5sum: %s: error reading: %v\n", sourceName, err)
+			fmt.Fprintf(inv.Stderr, "md5sum: %s: error reading: %v\n", sourceName, err)
 		}
 		hasError = true
 	}
@@ -344,13 +355,13 @@ func md5sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool)
 			// merely a warning.
 			hasError = true
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "md5sum: %s: no properly formatted MD5 checksum lines found\n", sourceName)
+				fmt.Fprintf(inv.Stderr, "md5sum: %s: no properly formatted MD5 checksum lines found\n", sourceName)
 			}
 		} else if !status {
 			if malformed == 1 {
-				fmt.Fprintln(os.Stderr, "md5sum: WARNING: 1 line is improperly formatted")
+				fmt.Fprintln(inv.Stderr, "md5sum: WARNING: 1 line is improperly formatted")
 			} else {
-				fmt.Fprintf(os.Stderr, "md5sum: WARNING: %d lines are improperly formatted\n", malformed)
+				fmt.Fprintf(inv.Stderr, "md5sum: WARNING: %d lines are improperly formatted\n", malformed)
 			}
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -29,6 +30,10 @@ func (e sha256sumExitError) Error() string {
 func (e sha256sumExitError) ExitCode() int { return e.code }
 
 func Sha256sumCmd(args []string) error {
+	return sha256sumCmd(base.Stdio(), args)
+}
+
+func sha256sumCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("sha256sum", flag.ContinueOnError)
 	checkMode := fsFlags.Bool("c", false, "check SHA256 sums")
 	fsFlags.BoolVar(checkMode, "check", false, "check SHA256 sums")
@@ -40,18 +45,18 @@ func Sha256sumCmd(args []string) error {
 	warn := fsFlags.Bool("w", false, "warn malformed lines")
 	fsFlags.BoolVar(warn, "warn", false, "warn malformed lines")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox sha256sum [OPTION]... [FILE]...")
-		fmt.Fprintln(os.Stderr, "Compute or check SHA256 message digests.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Modes:")
-		fmt.Fprintln(os.Stderr, "  -c, --check       check SHA256 sums from files")
-		fmt.Fprintln(os.Stderr, "  --tag             use BSD style output")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Output:")
-		fmt.Fprintln(os.Stderr, "  -q, --quiet       quiet mode")
-		fmt.Fprintln(os.Stderr, "  -s, --status      status mode")
-		fmt.Fprintln(os.Stderr, "  -w, --warn        warn about malformed lines")
-		fmt.Fprintln(os.Stderr, "  -h, --help        show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox sha256sum [OPTION]... [FILE]...")
+		fmt.Fprintln(inv.Stderr, "Compute or check SHA256 message digests.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Modes:")
+		fmt.Fprintln(inv.Stderr, "  -c, --check       check SHA256 sums from files")
+		fmt.Fprintln(inv.Stderr, "  --tag             use BSD style output")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Output:")
+		fmt.Fprintln(inv.Stderr, "  -q, --quiet       quiet mode")
+		fmt.Fprintln(inv.Stderr, "  -s, --status      status mode")
+		fmt.Fprintln(inv.Stderr, "  -w, --warn        warn about malformed lines")
+		fmt.Fprintln(inv.Stderr, "  -h, --help        show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -64,23 +69,23 @@ func Sha256sumCmd(args []string) error {
 		if len(files) == 0 {
 			return fmt.Errorf("sha256sum: check mode requires a file")
 		}
-		return sha256sumCheck(files, *warn, *status, *quiet)
+		return sha256sumCheck(inv, files, *warn, *status, *quiet)
 	}
 	if len(files) == 0 {
-		sum, err := computeSHA256(os.Stdin)
+		sum, err := computeSHA256(inv.Stdin)
 		if err != nil {
 			return err
 		}
-		printSHA256("-", sum, *tag, *quiet)
+		printSHA256(inv.Stdout, "-", sum, *tag, *quiet)
 		return nil
 	}
 	var hadErr bool
 	for _, file := range files {
-		f, err := os.Open(file)
+		f, err := os.Open(inv.Path(file))
 		if err != nil {
 			hadErr = true
 			if !*quiet {
-				fmt.Fprintf(os.Stderr, "sha256sum: %s: %v\n", file, err)
+				fmt.Fprintf(inv.Stderr, "sha256sum: %s: %v\n", file, err)
 			}
 			continue
 		}
@@ -89,11 +94,11 @@ func Sha256sumCmd(args []string) error {
 		if err != nil {
 			hadErr = true
 			if !*quiet {
-				fmt.Fprintf(os.Stderr, "sha256sum: %s: %v\n", file, err)
+				fmt.Fprintf(inv.Stderr, "sha256sum: %s: %v\n", file, err)
 			}
 			continue
 		}
-		printSHA256(file, sum, *tag, *quiet)
+		printSHA256(inv.Stdout, file, sum, *tag, *quiet)
 	}
 	if hadErr {
 		return sha256sumExitError{code: 1}
@@ -109,27 +114,27 @@ func computeSHA256(r io.Reader) (string, error) {
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
-func printSHA256(file, sum string, tag, quiet bool) {
+func printSHA256(w io.Writer, file, sum string, tag, quiet bool) {
 	if tag {
 		name := file
 		if name == "-" {
 			name = "stdin"
 		}
-		fmt.Printf("SHA256 (%s) = %s\n", name, sum)
+		fmt.Fprintf(w, "SHA256 (%s) = %s\n", name, sum)
 	} else if quiet {
-		fmt.Println(sum)
+		fmt.Fprintln(w, sum)
 	} else {
-		fmt.Printf("%s  %s\n", sum, file)
+		fmt.Fprintf(w, "%s  %s\n", sum, file)
 	}
 }
 
-func sha256sumCheck(files []string, warn, status, quiet bool) error {
+func sha256sumCheck(inv *base.Invocation, files []string, warn, status, quiet bool) error {
 	var failed bool
 	for _, file := range files {
 		// "-" as a checksum-file operand means read the checksum list from
 		// stdin, matching GNU sha256sum -c -.
 		if file == "-" {
-			ok, err := sha256sumCheckReader(os.Stdin, "-", warn, status, quiet)
+			ok, err := sha256sumCheckReader(inv, inv.Stdin, "-", warn, status, quiet)
 			if err != nil {
 				return err
 			}
@@ -138,15 +143,15 @@ func sha256sumCheck(files []string, warn, status, quiet bool) error {
 			}
 			continue
 		}
-		f, err := os.Open(file)
+		f, err := os.Open(inv.Path(file))
 		if err != nil {
 			failed = true
 			if !status {
-				fmt.Fprintf(os.Stderr, "sha256sum: %s: %v\n", file, err)
+				fmt.Fprintf(inv.Stderr, "sha256sum: %s: %v\n", file, err)
 			}
 			continue
 		}
-		ok, err := sha256sumCheckReader(f, file, warn, status, quiet)
+		ok, err := sha256sumCheckReader(inv, f, file, warn, status, quiet)
 		_ = f.Close()
 		if err != nil {
 			return err
@@ -168,7 +173,7 @@ func sha256sumCheck(files []string, warn, status, quiet bool) error {
 // (optionally per-line via -w, always as a trailing summary), never a
 // checksum-verification failure, unless the checksum list contained no
 // parseable line at all.
-func sha256sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bool) (bool, error) {
+func sha256sumCheckReader(inv *base.Invocation, r io.Reader, sourceName string, warn, status, quiet bool) (bool, error) {
 	ok := true
 	var malformed, processed int
 	scanner := bufio.NewScanner(r)
@@ -183,25 +188,25 @@ func sha256sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bo
 		if !parsed {
 			malformed++
 			if warn && !status {
-				fmt.Fprintf(os.Stderr, "sha256sum: %s:%d: improperly formatted checksum line\n", sourceName, lineNo)
+				fmt.Fprintf(inv.Stderr, "sha256sum: %s:%d: improperly formatted checksum line\n", sourceName, lineNo)
 			}
 			continue
 		}
 		processed++
-		actual, err := sha256File(name)
+		actual, err := sha256File(inv.Path(name))
 		if err != nil {
 			ok = false
 			if !status {
-				fmt.Fprintf(os.Stderr, "sha256sum: %s: %v\n", name, err)
-				fmt.Printf("%s: FAILED\n", name)
+				fmt.Fprintf(inv.Stderr, "sha256sum: %s: %v\n", name, err)
+				fmt.Fprintf(inv.Stdout, "%s: FAILED\n", name)
 			}
 		} else if !strings.EqualFold(actual, expected) {
 			ok = false
 			if !status {
-				fmt.Printf("%s: FAILED\n", name)
+				fmt.Fprintf(inv.Stdout, "%s: FAILED\n", name)
 			}
 		} else if !status && !quiet {
-			fmt.Printf("%s: OK\n", name)
+			fmt.Fprintf(inv.Stdout, "%s: OK\n", name)
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -211,13 +216,13 @@ func sha256sumCheckReader(r io.Reader, sourceName string, warn, status, quiet bo
 		if processed == 0 {
 			ok = false
 			if !quiet {
-				fmt.Fprintf(os.Stderr, "sha256sum: %s: no properly formatted SHA256 checksum lines found\n", sourceName)
+				fmt.Fprintf(inv.Stderr, "sha256sum: %s: no properly formatted SHA256 checksum lines found\n", sourceName)
 			}
 		} else if !status {
 			if malformed == 1 {
-				fmt.Fprintln(os.Stderr, "sha256sum: WARNING: 1 line is improperly formatted")
+				fmt.Fprintln(inv.Stderr, "sha256sum: WARNING: 1 line is improperly formatted")
 			} else {
-				fmt.Fprintf(os.Stderr, "sha256sum: WARNING: %d lines are improperly formatted\n", malformed)
+				fmt.Fprintf(inv.Stderr, "sha256sum: WARNING: %d lines are improperly formatted\n", malformed)
 			}
 		}
 	}
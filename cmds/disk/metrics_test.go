@@ -0,0 +1,44 @@
+package disk
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+func TestCollectDiskMetricsWithOwnCgroup(t *testing.T) {
+	oldReadFile := readFileIostat
+	t.Cleanup(func() { readFileIostat = oldReadFile })
+	readFileIostat = func(path string) ([]byte, error) {
+		switch path {
+		case "/proc/diskstats":
+			return []byte("   8       0 sda 4 0 16 0 8 0 32 0 0 1500 2500 0 0 0 0\n"), nil
+		case "/proc/self/cgroup":
+			return []byte("0::/app.slice\n"), nil
+		case "/sys/fs/cgroup/app.slice/io.stat":
+			return []byte("8:0 rbytes=1024 wbytes=2048 rios=1 wios=2\n"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	var buf bytes.Buffer
+	if err := collectDiskMetrics(utils.NewMetricsWriter(&buf), base.MetricsOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`gobox_disk_reads_completed_total{device="sda"} 4`,
+		`gobox_disk_read_bytes_total{device="sda"} 8192`,
+		`gobox_disk_io_time_seconds_total{device="sda"} 1.5`,
+		`gobox_cgroup_io_written_bytes_total{device="`,
+		`cgroup="/app.slice"} 2048`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in %q", want, out)
+		}
+	}
+}
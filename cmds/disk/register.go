@@ -3,8 +3,9 @@ package disk
 import "gobox/cmds/base"
 
 func init() {
-	base.Register(base.NewCommand("iostat", "Show block device I/O stats", base.Adapt(IostatCmd)))
-	base.Register(base.NewCommand("ioperf", "I/O performance benchmark tool (simplified fio-like)", base.Adapt(IoperfCmd)))
-	base.Register(base.NewCommand("md5sum", "Compute/check MD5 checksums", base.Adapt(Md5sumCmd)))
-	base.Register(base.NewCommand("sha256sum", "Compute/check SHA-256 checksums", base.Adapt(Sha256sumCmd)))
+	base.Register(base.NewCommand("iostat", "Show block device I/O stats", iostatCmd, base.WithTabularOutput()))
+	base.Register(base.NewCommand("ioperf", "I/O performance benchmark tool (simplified fio-like)", ioperfCmd))
+	base.Register(base.NewCommand("md5sum", "Compute/check MD5 checksums", md5sumCmd))
+	base.Register(base.NewCommand("sha256sum", "Compute/check SHA-256 checksums", sha256sumCmd))
+	base.RegisterMetricsCollector(base.MetricsCollector{Name: "disk", Help: "Block device and cgroup I/O counters", Collect: collectDiskMetrics})
 }
@@ -0,0 +1,57 @@
+package fs
+
+import (
+	"fmt"
+
+	"gobox/cmds/base"
+)
+
+// runDiskCheck reads the filesystem holding "path" the way df PATH does and
+// fails when its use% (or iuse% with "max_inodes") is above the threshold.
+func runDiskCheck(inv *base.Invocation, c base.Check) (string, error) {
+	p, err := c.RequireParam("path")
+	if err != nil {
+		return "", err
+	}
+	maxUse, err := c.FloatParam("max_use", 90)
+	if err != nil {
+		return "", err
+	}
+	maxInodes, err := c.FloatParam("max_inodes", 0)
+	if err != nil {
+		return "", err
+	}
+	if _, err := statDfPath(inv.Path(p)); err != nil {
+		return "", err
+	}
+	mounts, err := readMounts(inv.Roots())
+	if err != nil {
+		return "", err
+	}
+	row, err := readDfRow(inv.Roots(), bestMountForPath(mounts, inv.Path(p)))
+	if err != nil {
+		return "", err
+	}
+	st := row.stat
+	use := usePercent((st.Blocks-st.Bfree)*uint64(st.Bsize), st.Blocks*uint64(st.Bsize))
+	detail := fmt.Sprintf("%s %d%% used", row.mount.Target, use)
+	if float64(use) > maxUse {
+		return "", fmt.Errorf("%s, above %g%%", detail, maxUse)
+	}
+	if maxInodes > 0 && st.Files >= st.Ffree {
+		iuse := usePercent(st.Files-st.Ffree, st.Files)
+		detail += fmt.Sprintf(", %d%% inodes", iuse)
+		if float64(iuse) > maxInodes {
+			return "", fmt.Errorf("%s, above %g%%", detail, maxInodes)
+		}
+	}
+	return detail, nil
+}
+
+// usePercent rounds up like df's Use% column.
+func usePercent(used, total uint64) uint64 {
+	if total == 0 {
+		return 0
+	}
+	return (used*100 + total - 1) / total
+}
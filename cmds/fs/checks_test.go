@@ -0,0 +1,30 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+)
+
+func TestDiskCheckThresholds(t *testing.T) {
+	dir := setupDfFixture(t)
+	inv := &base.Invocation{}
+	check := func(params map[string]string) (string, error) {
+		params["path"] = dir
+		return runDiskCheck(inv, base.Check{Type: "disk", Params: params})
+	}
+	// The fixture is 15 of 20 blocks and 4 of 10 inodes used.
+	if detail, err := check(map[string]string{}); err != nil || !strings.Contains(detail, "75% used") {
+		t.Fatalf("default threshold: %q, %v", detail, err)
+	}
+	if _, err := check(map[string]string{"max_use": "70%"}); err == nil || !strings.Contains(err.Error(), "above 70%") {
+		t.Fatalf("expected max_use failure, got %v", err)
+	}
+	if _, err := check(map[string]string{"max_inodes": "30"}); err == nil || !strings.Contains(err.Error(), "40% inodes") {
+		t.Fatalf("expected max_inodes failure, got %v", err)
+	}
+	if _, err := runDiskCheck(inv, base.Check{Type: "disk"}); err == nil {
+		t.Fatal("expected missing path error")
+	}
+}
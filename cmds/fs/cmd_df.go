@@ -1,24 +1,23 @@
 package fs
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"gobox/pkg/procfs"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 )
 
-type mountInfo struct {
-	Source string
-	Target string
-	FSType string
-}
+type mountInfo = procfs.MountInfo
 
 var (
 	dfGOOS       = runtime.GOOS
@@ -49,14 +48,25 @@ type dfOptions struct {
 	excludeType []string
 	total       bool
 	posix       bool
+	color       *utils.Colorizer
 }
 
+// Use% thresholds at which df colors a filesystem as filling up.
+const (
+	dfWarnPercent     = 80
+	dfCriticalPercent = 90
+)
+
 type dfRow struct {
 	mount mountInfo
 	stat  syscall.Statfs_t
 }
 
 func DfCmd(args []string) error {
+	return dfCmd(base.Stdio(), args)
+}
+
+func dfCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("df", flag.ContinueOnError)
 	var opts dfOptions
 	var includeTypes dfTypeFilter
@@ -71,25 +81,27 @@ func DfCmd(args []string) error {
 	fsFlags.Var(&excludeTypes, "x", "exclude filesystems of type TYPE")
 	fsFlags.BoolVar(&opts.total, "total", false, "produce a grand total")
 	fsFlags.BoolVar(&opts.posix, "P", false, "use POSIX output format")
+	color := utils.AddColorFlag(fsFlags)
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox df [OPTION]... [PATH...]")
-		fmt.Fprintln(os.Stderr, "Report filesystem disk space usage.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Display:")
-		fmt.Fprintln(os.Stderr, "  -h               human readable units")
-		fmt.Fprintln(os.Stderr, "  -H               human readable SI units")
-		fmt.Fprintln(os.Stderr, "  -T               show filesystem type")
-		fmt.Fprintln(os.Stderr, "  -i               show inode usage")
-		fmt.Fprintln(os.Stderr, "  -P               use POSIX output format")
-		fmt.Fprintln(os.Stderr, "  --total          produce a grand total")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Filters:")
-		fmt.Fprintln(os.Stderr, "  -a               include all filesystems")
-		fmt.Fprintln(os.Stderr, "  -l               limit listing to local filesystems")
-		fmt.Fprintln(os.Stderr, "  -t TYPE          limit listing to filesystems of type TYPE")
-		fmt.Fprintln(os.Stderr, "  -x TYPE          exclude filesystems of type TYPE")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "      --help       show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox df [OPTION]... [PATH...]")
+		fmt.Fprintln(inv.Stderr, "Report filesystem disk space usage.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Display:")
+		fmt.Fprintln(inv.Stderr, "  -h               human readable units")
+		fmt.Fprintln(inv.Stderr, "  -H               human readable SI units")
+		fmt.Fprintln(inv.Stderr, "  -T               show filesystem type")
+		fmt.Fprintln(inv.Stderr, "  -i               show inode usage")
+		fmt.Fprintln(inv.Stderr, "  -P               use POSIX output format")
+		fmt.Fprintln(inv.Stderr, "  --total          produce a grand total")
+		fmt.Fprintln(inv.Stderr, "  --color[=WHEN]   color Use% at 80% and 90%: auto (default), always or never")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Filters:")
+		fmt.Fprintln(inv.Stderr, "  -a               include all filesystems")
+		fmt.Fprintln(inv.Stderr, "  -l               limit listing to local filesystems")
+		fmt.Fprintln(inv.Stderr, "  -t TYPE          limit listing to filesystems of type TYPE")
+		fmt.Fprintln(inv.Stderr, "  -x TYPE          exclude filesystems of type TYPE")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "      --help       show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -102,7 +114,8 @@ func DfCmd(args []string) error {
 	}
 	opts.includeType = includeTypes
 	opts.excludeType = excludeTypes
-	mounts, err := readMounts()
+	opts.color = utils.NewColorizer(inv.Stdout, color.Mode, inv.Getenv)
+	mounts, err := readMounts(inv.Roots())
 	if err != nil {
 		return err
 	}
@@ -119,11 +132,11 @@ func DfCmd(args []string) error {
 	var rowErr error
 	for _, p := range paths {
 		if len(fsFlags.Args()) > 0 {
-			if _, err := statDfPath(p); err != nil {
+			if _, err := statDfPath(inv.Path(p)); err != nil {
 				return fmt.Errorf("%s: %w", p, err)
 			}
 		}
-		m := bestMountForPath(mounts, p)
+		m := bestMountForPath(mounts, inv.Path(p))
 		if !dfMountAllowed(m, opts) {
 			continue
 		}
@@ -131,7 +144,7 @@ func DfCmd(args []string) error {
 			continue
 		}
 		seen[m.Target] = true
-		row, err := readDfRow(m)
+		row, err := readDfRow(inv.Roots(), m)
 		if err != nil {
 			if len(fsFlags.Args()) == 0 {
 				rowErr = err
@@ -147,12 +160,21 @@ func DfCmd(args []string) error {
 	if opts.total {
 		rows = append(rows, totalDfRow(rows, opts))
 	}
+	if utils.IsStructuredOutput(inv.Output) {
+		if err := dfTable(rows, opts).Render(inv.Stdout, inv.Output); err != nil {
+			return err
+		}
+		if len(rows) == 0 && rowErr != nil {
+			return rowErr
+		}
+		return nil
+	}
 	sourceWidth, typeWidth := dfColumnWidths(rows, opts)
 	col1Header, col2Header, col3Header, pctHeader := dfColumnHeaders(opts)
 	w1, w2, w3, w4 := dfNumericWidths(rows, opts, col1Header, col2Header, col3Header, pctHeader)
-	printDfHeader(sourceWidth, typeWidth, w1, w2, w3, w4, opts)
+	printDfHeader(inv.Stdout, sourceWidth, typeWidth, w1, w2, w3, w4, opts)
 	for _, row := range rows {
-		printDfRow(row, sourceWidth, typeWidth, w1, w2, w3, w4, opts)
+		printDfRow(inv.Stdout, row, sourceWidth, typeWidth, w1, w2, w3, w4, opts)
 	}
 	if len(rows) == 0 && rowErr != nil {
 		return rowErr
@@ -160,37 +182,13 @@ func DfCmd(args []string) error {
 	return nil
 }
 
-func readMountInfo() ([]mountInfo, error) {
-	f, err := os.Open("/proc/self/mountinfo")
+func readMountInfo(roots utils.Roots) ([]mountInfo, error) {
+	mounts, err := roots.SelfProc().MountInfo()
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	var mounts []mountInfo
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, " - ")
-		if len(parts) != 2 {
-			continue
-		}
-		left := strings.Fields(parts[0])
-		right := strings.Fields(parts[1])
-		if len(left) < 5 || len(right) < 3 {
-			continue
-		}
-		mounts = append(mounts, mountInfo{Target: decodeMountField(left[4]), FSType: right[0], Source: right[1]})
-	}
 	sort.Slice(mounts, func(i, j int) bool { return len(mounts[i].Target) > len(mounts[j].Target) })
-	return mounts, scanner.Err()
-}
-
-func decodeMountField(s string) string {
-	s = strings.ReplaceAll(s, `\040`, " ")
-	s = strings.ReplaceAll(s, `\011`, "\t")
-	s = strings.ReplaceAll(s, `\012`, "\n")
-	s = strings.ReplaceAll(s, `\134`, `\`)
-	return s
+	return mounts, err
 }
 
 func bestMountForPath(mounts []mountInfo, p string) mountInfo {
@@ -208,9 +206,12 @@ func bestMountForPath(mounts []mountInfo, p string) mountInfo {
 	return mountInfo{Target: p, Source: p}
 }
 
-func readDfRow(m mountInfo) (dfRow, error) {
+// readDfRow statfs's the mount point; under --proc-root the mount table is
+// the inspected system's, so its mount points are reached through
+// ROOT/1/root.
+func readDfRow(roots utils.Roots, m mountInfo) (dfRow, error) {
 	var st syscall.Statfs_t
-	if err := statfsDfPath(m.Target, &st); err != nil {
+	if err := statfsDfPath(roots.HostPath(m.Target), &st); err != nil {
 		return dfRow{}, err
 	}
 	return dfRow{mount: m, stat: st}, nil
@@ -304,23 +305,69 @@ func dfColumnHeaders(opts dfOptions) (col1, col2, col3, pctHeader string) {
 	return blockHeader, "Used", availHeader, pctHeader
 }
 
-func printDfHeader(sourceWidth, typeWidth, w1, w2, w3, w4 int, opts dfOptions) {
+func printDfHeader(w io.Writer, sourceWidth, typeWidth, w1, w2, w3, w4 int, opts dfOptions) {
 	col1, col2, col3, pctHeader := dfColumnHeaders(opts)
 	if opts.showType {
-		fmt.Printf("%-*s %-*s %*s %*s %*s %*s %s\n", sourceWidth, "Filesystem", typeWidth, "Type", w1, col1, w2, col2, w3, col3, w4, pctHeader, "Mounted on")
+		fmt.Fprintf(w, "%-*s %-*s %*s %*s %*s %*s %s\n", sourceWidth, "Filesystem", typeWidth, "Type", w1, col1, w2, col2, w3, col3, w4, pctHeader, "Mounted on")
 		return
 	}
-	fmt.Printf("%-*s %*s %*s %*s %*s %s\n", sourceWidth, "Filesystem", w1, col1, w2, col2, w3, col3, w4, pctHeader, "Mounted on")
+	fmt.Fprintf(w, "%-*s %*s %*s %*s %*s %s\n", sourceWidth, "Filesystem", w1, col1, w2, col2, w3, col3, w4, pctHeader, "Mounted on")
 }
 
-func printDfRow(row dfRow, sourceWidth, typeWidth, w1, w2, w3, w4 int, opts dfOptions) {
+func printDfRow(w io.Writer, row dfRow, sourceWidth, typeWidth, w1, w2, w3, w4 int, opts dfOptions) {
 	m := row.mount
 	c1, c2, c3, pct := dfRowValues(row, opts)
+	pct = opts.color.PaintField(dfPercentRole(pct), fmt.Sprintf("%*s", w4, pct))
 	if opts.showType {
-		fmt.Printf("%-*s %-*s %*s %*s %*s %*s %s\n", sourceWidth, m.Source, typeWidth, m.FSType, w1, c1, w2, c2, w3, c3, w4, pct, m.Target)
+		fmt.Fprintf(w, "%-*s %-*s %*s %*s %*s %s %s\n", sourceWidth, m.Source, typeWidth, m.FSType, w1, c1, w2, c2, w3, c3, pct, m.Target)
 		return
 	}
-	fmt.Printf("%-*s %*s %*s %*s %*s %s\n", sourceWidth, m.Source, w1, c1, w2, c2, w3, c3, w4, pct, m.Target)
+	fmt.Fprintf(w, "%-*s %*s %*s %*s %s %s\n", sourceWidth, m.Source, w1, c1, w2, c2, w3, c3, pct, m.Target)
+}
+
+// dfPercentRole picks the color for a Use% cell such as "85%".
+func dfPercentRole(pct string) string {
+	n, err := strconv.Atoi(strings.TrimSuffix(pct, "%"))
+	switch {
+	case err != nil:
+		return ""
+	case n >= dfCriticalPercent:
+		return utils.ColorCritical
+	case n >= dfWarnPercent:
+		return utils.ColorWarn
+	}
+	return ""
+}
+
+// dfTable reports sizes in bytes (inode counts with -i) and usage as a raw
+// percentage, independent of -h/-H/-P which only affect the text table.
+func dfTable(rows []dfRow, opts dfOptions) *utils.Table {
+	if opts.inodes {
+		t := utils.NewTable("filesystem", "type", "inodes", "iused", "ifree", "iuse_percent", "mounted_on")
+		for _, row := range rows {
+			st := row.stat
+			used := int64(st.Files) - int64(st.Ffree)
+			var pct any
+			if st.Files > 0 && used >= 0 {
+				pct = float64(used) * 100 / float64(st.Files)
+			}
+			t.Append(row.mount.Source, row.mount.FSType, st.Files, used, st.Ffree, pct, row.mount.Target)
+		}
+		return t
+	}
+	t := utils.NewTable("filesystem", "type", "size_bytes", "used_bytes", "avail_bytes", "use_percent", "mounted_on")
+	for _, row := range rows {
+		st := row.stat
+		blockSize := uint64(st.Bsize)
+		total := st.Blocks * blockSize
+		used := (st.Blocks - st.Bfree) * blockSize
+		var pct any
+		if total > 0 {
+			pct = float64(used) * 100 / float64(total)
+		}
+		t.Append(row.mount.Source, row.mount.FSType, total, used, st.Bavail*blockSize, pct, row.mount.Target)
+	}
+	return t
 }
 
 func formatDfSize(total, used, free uint64, opts dfOptions) (string, string, string) {
@@ -424,5 +471,5 @@ func percent(used, total uint64) string {
 	if total == 0 {
 		return "-"
 	}
-	return fmt.Sprintf("%d%%", (used*100+total-1)/total)
+	return fmt.Sprintf("%d%%", usePercent(used, total))
 }
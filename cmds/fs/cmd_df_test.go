@@ -1,11 +1,15 @@
 package fs
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
 )
 
 func setupDfFixture(t *testing.T) string {
@@ -13,7 +17,7 @@ func setupDfFixture(t *testing.T) string {
 	dir := t.TempDir()
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "dev-test", Target: dir, FSType: "tmpfs"}}, nil
 	}
 	statDfPath = os.Stat
@@ -105,7 +109,7 @@ func TestDfType(t *testing.T) {
 func TestDfLongFilesystemAndTypeStayAligned(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{
 			Source: "/dev/mapper/very-long-container-volume-name",
 			Target: "/mnt/data",
@@ -223,7 +227,7 @@ func TestDfUnsupportedOSReturnsError(t *testing.T) {
 func TestDfMountinfoReadError(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) { return nil, os.ErrPermission }
+	readMounts = func(utils.Roots) ([]mountInfo, error) { return nil, os.ErrPermission }
 	t.Cleanup(func() {
 		dfGOOS, readMounts, statDfPath, statfsDfPath = oldGOOS, oldReadMounts, oldStatPath, oldStatfs
 	})
@@ -235,7 +239,7 @@ func TestDfMountinfoReadError(t *testing.T) {
 func TestDfDefaultDeduplicatesMountTargets(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{
 			{Source: "dev-a", Target: "/mnt/a", FSType: "tmpfs"},
 			{Source: "dev-b", Target: "/mnt/a", FSType: "tmpfs"},
@@ -265,7 +269,7 @@ func TestDfDefaultDeduplicatesMountTargets(t *testing.T) {
 func TestDfAllIncludesDuplicateMountTargets(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{
 			{Source: "dev-a", Target: "/mnt/a", FSType: "tmpfs"},
 			{Source: "dev-b", Target: "/mnt/a", FSType: "tmpfs"},
@@ -293,7 +297,7 @@ func TestDfAllIncludesDuplicateMountTargets(t *testing.T) {
 func TestDfTypeFiltersAndLocal(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{
 			{Source: "local-dev", Target: "/local", FSType: "ext4"},
 			{Source: "server:/share", Target: "/remote", FSType: "nfs"},
@@ -330,7 +334,7 @@ func TestDfTypeFiltersAndLocal(t *testing.T) {
 func TestDfTotalAndPosix(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{
 			{Source: "dev-a", Target: "/a", FSType: "ext4"},
 			{Source: "dev-b", Target: "/b", FSType: "ext4"},
@@ -383,7 +387,7 @@ func TestDfTotalAndPosix(t *testing.T) {
 func TestDfZeroTotalsRenderDashPercent(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "zero", Target: "/zero", FSType: "tmpfs"}}, nil
 	}
 	statfsDfPath = func(_ string, st *syscall.Statfs_t) error {
@@ -410,7 +414,7 @@ func TestDfZeroTotalsRenderDashPercent(t *testing.T) {
 func TestDfStatfsErrorReturnsError(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "bad", Target: "/bad", FSType: "tmpfs"}}, nil
 	}
 	statfsDfPath = func(_ string, _ *syscall.Statfs_t) error { return os.ErrPermission }
@@ -425,7 +429,7 @@ func TestDfStatfsErrorReturnsError(t *testing.T) {
 func TestDfExplicitPathStatErrorReturnsError(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "root", Target: "/", FSType: "tmpfs"}}, nil
 	}
 	statDfPath = func(string) (os.FileInfo, error) { return nil, os.ErrNotExist }
@@ -443,7 +447,7 @@ func TestDfExplicitPathStatErrorReturnsError(t *testing.T) {
 func TestDfHumanAdaptivePrecision(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "dev-small", Target: "/small", FSType: "tmpfs"}}, nil
 	}
 	// Total of exactly 20 MiB (>=10, no decimal) and a free amount giving
@@ -480,7 +484,7 @@ func TestDfHumanAdaptivePrecision(t *testing.T) {
 func TestDfSILowercaseKilo(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "dev-k", Target: "/k", FSType: "tmpfs"}}, nil
 	}
 	statfsDfPath = func(_ string, st *syscall.Statfs_t) error {
@@ -545,7 +549,7 @@ func TestDfPosixCapacityHeader(t *testing.T) {
 func TestDfInodesHidesZeroBlockFilesystemsByDefault(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{
 			{Source: "real", Target: "/real", FSType: "tmpfs"},
 			{Source: "pseudo", Target: "/sys/fs/pseudo", FSType: "pseudofs"},
@@ -595,7 +599,7 @@ func TestDfInodesHidesZeroBlockFilesystemsByDefault(t *testing.T) {
 func TestDfInodesNegativeUsedDoesNotUnderflow(t *testing.T) {
 	oldGOOS, oldReadMounts, oldStatPath, oldStatfs := dfGOOS, readMounts, statDfPath, statfsDfPath
 	dfGOOS = "linux"
-	readMounts = func() ([]mountInfo, error) {
+	readMounts = func(utils.Roots) ([]mountInfo, error) {
 		return []mountInfo{{Source: "weird", Target: "/weird", FSType: "vboxsf"}}, nil
 	}
 	statfsDfPath = func(_ string, st *syscall.Statfs_t) error {
@@ -621,3 +625,60 @@ func TestDfInodesNegativeUsedDoesNotUnderflow(t *testing.T) {
 		t.Fatalf("expected no unsigned-underflow wraparound value, got %q", out)
 	}
 }
+
+func TestDfStructuredOutputKeepsBytes(t *testing.T) {
+	dir := setupDfFixture(t)
+	var out bytes.Buffer
+	cmd := base.NewCommand("df", "", dfCmd, base.WithTabularOutput())
+	if err := cmd.Run(&base.Invocation{Stdout: &out}, []string{"-h", "--output", "json", dir}); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {"filesystem":"dev-test","type":"tmpfs","size_bytes":20480,"used_bytes":15360,"avail_bytes":5120,"use_percent":75,"mounted_on":"` + dir + `"}
+]
+`
+	if out.String() != want {
+		t.Fatalf("unexpected df json:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := cmd.Run(&base.Invocation{Stdout: &out}, []string{"-i", "--output=csv", dir}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "filesystem,type,inodes,iused,ifree,iuse_percent,mounted_on\ndev-test,tmpfs,10,4,6,40," + dir + "\n"; out.String() != want {
+		t.Fatalf("unexpected df -i csv:\n%s", out.String())
+	}
+}
+
+func TestDfPercentRoleThresholds(t *testing.T) {
+	for pct, want := range map[string]string{"79%": "", "80%": utils.ColorWarn, "89%": utils.ColorWarn, "90%": utils.ColorCritical, "100%": utils.ColorCritical, "-": ""} {
+		if got := dfPercentRole(pct); got != want {
+			t.Fatalf("dfPercentRole(%q) = %q, want %q", pct, got, want)
+		}
+	}
+}
+
+func TestDfColorAlwaysPaintsFullFilesystem(t *testing.T) {
+	dir := setupDfFixture(t)
+	statfsDfPath = func(_ string, st *syscall.Statfs_t) error {
+		st.Bsize = 1024
+		st.Blocks = 20
+		st.Bfree = 1
+		st.Bavail = 1
+		return nil
+	}
+	out, err := captureFsCmd(t, func() error { return DfCmd([]string{"--color=always", dir}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, " \x1b[31m\x1b[K95%\x1b[m\x1b[K "+dir) {
+		t.Fatalf("expected Use%% painted red, got %q", out)
+	}
+	plain, err := captureFsCmd(t, func() error { return DfCmd([]string{dir}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(plain, "\x1b[") {
+		t.Fatalf("auto mode colored a pipe: %q", plain)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"strings"
 	"syscall"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"io"
 )
 
 type duExcludePatterns []string
@@ -38,12 +40,18 @@ type duOptions struct {
 type duRow struct {
 	path string
 	size int64
+	info fs.FileInfo
 }
 
 func DuCmd(args []string) error {
+	return duCmd(base.Stdio(), args)
+}
+
+func duCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("du", flag.ContinueOnError)
 	var opts duOptions
 	var excludes duExcludePatterns
+	var interactive bool
 	fsFlags.BoolVar(&opts.human, "h", false, "human readable sizes")
 	fsFlags.BoolVar(&opts.summary, "s", false, "summarize")
 	fsFlags.BoolVar(&opts.all, "a", false, "write counts for all files")
@@ -53,25 +61,29 @@ func DuCmd(args []string) error {
 	fsFlags.Var(&excludes, "exclude", "exclude files matching PATTERN")
 	fsFlags.BoolVar(&opts.oneFS, "x", false, "skip directories on different filesystems")
 	fsFlags.BoolVar(&opts.apparentSize, "apparent-size", false, "print apparent sizes instead of disk usage")
+	fsFlags.BoolVar(&interactive, "interactive", false, "browse disk usage in a full-screen explorer")
 
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox du [OPTION]... [PATH...]")
-		fmt.Fprintln(os.Stderr, "Summarize disk usage of the set of FILEs, recursively for directories.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -h                    human readable sizes")
-		fmt.Fprintln(os.Stderr, "  -s                    summarize each argument")
-		fmt.Fprintln(os.Stderr, "  -a                    write counts for all files")
-		fmt.Fprintln(os.Stderr, "  -c                    produce a grand total")
-		fmt.Fprintln(os.Stderr, "  -d, --max-depth N     print directories at most N levels deep")
-		fmt.Fprintln(os.Stderr, "  --exclude PATTERN     exclude files matching PATTERN")
-		fmt.Fprintln(os.Stderr, "  -x                    skip directories on different filesystems")
-		fmt.Fprintln(os.Stderr, "  --apparent-size       print apparent sizes instead of disk usage")
-		fmt.Fprintln(os.Stderr, "  --help                show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox du -sh .")
-		fmt.Fprintln(os.Stderr, "  gobox du --max-depth 2 --exclude '*.tmp' /var")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox du [OPTION]... [PATH...]")
+		fmt.Fprintln(inv.Stderr, "Summarize disk usage of the set of FILEs, recursively for directories.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -h                    human readable sizes")
+		fmt.Fprintln(inv.Stderr, "  -s                    summarize each argument")
+		fmt.Fprintln(inv.Stderr, "  -a                    write counts for all files")
+		fmt.Fprintln(inv.Stderr, "  -c                    produce a grand total")
+		fmt.Fprintln(inv.Stderr, "  -d, --max-depth N     print directories at most N levels deep")
+		fmt.Fprintln(inv.Stderr, "  --exclude PATTERN     exclude files matching PATTERN")
+		fmt.Fprintln(inv.Stderr, "  -x                    skip directories on different filesystems")
+		fmt.Fprintln(inv.Stderr, "  --apparent-size       print apparent sizes instead of disk usage")
+		fmt.Fprintln(inv.Stderr, "  --interactive         browse one PATH in a full-screen explorer (ncdu-style);")
+		fmt.Fprintln(inv.Stderr, "                        press ? inside for keys")
+		fmt.Fprintln(inv.Stderr, "  --help                show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox du -sh .")
+		fmt.Fprintln(inv.Stderr, "  gobox du --max-depth 2 --exclude '*.tmp' /var")
+		fmt.Fprintln(inv.Stderr, "  gobox du --interactive -x /")
 	}
 
 	if err := utils.ParseFlagSet(fsFlags, expandDuBundledFlags(args)); err != nil {
@@ -85,24 +97,47 @@ func DuCmd(args []string) error {
 		paths = []string{"."}
 	}
 	opts.excludes = excludes
+	if interactive {
+		if len(paths) > 1 {
+			return fmt.Errorf("--interactive takes a single PATH")
+		}
+		return runDuExplorer(inv, paths[0], opts)
+	}
+
+	// Structured output is collected and rendered once at the end; text rows
+	// keep streaming as each root is walked.
+	var table *utils.Table
+	emit := func(size int64, path string) {
+		if table != nil {
+			table.Append(size, path)
+			return
+		}
+		printDuRow(inv.Stdout, size, path, opts.human)
+	}
+	if utils.IsStructuredOutput(inv.Output) {
+		table = utils.NewTable("size_bytes", "path")
+	}
 
 	var grandTotal int64
 	for _, root := range paths {
-		rows, total, err := collectDiskUsage(root, opts)
+		rows, total, err := collectDiskUsage(inv.Path(root), opts)
 		if err != nil {
 			return err
 		}
 		grandTotal += total
 		if opts.summary {
-			printDuRow(total, root, opts.human)
+			emit(total, root)
 			continue
 		}
 		for _, row := range rows {
-			printDuRow(row.size, row.path, opts.human)
+			emit(row.size, inv.DisplayPath(root, row.path))
 		}
 	}
 	if opts.total {
-		printDuRow(grandTotal, "total", opts.human)
+		emit(grandTotal, "total")
+	}
+	if table != nil {
+		return table.Render(inv.Stdout, inv.Output)
 	}
 	return nil
 }
@@ -200,13 +235,13 @@ func walkDu(path string, info fs.FileInfo, depth int, root string, rootDev uint6
 			}
 		}
 		if opts.maxDepth < 0 || depth <= opts.maxDepth {
-			*rows = append(*rows, duRow{path: path, size: total})
+			*rows = append(*rows, duRow{path: path, size: total, info: info})
 		}
 		return total, nil
 	}
 
 	if (opts.all || depth == 0) && (opts.maxDepth < 0 || depth <= opts.maxDepth) {
-		*rows = append(*rows, duRow{path: path, size: total})
+		*rows = append(*rows, duRow{path: path, size: total, info: info})
 	}
 	return total, nil
 }
@@ -254,11 +289,11 @@ func duFileSize(info fs.FileInfo, apparent bool) int64 {
 	return info.Size()
 }
 
-func printDuRow(size int64, path string, human bool) {
+func printDuRow(w io.Writer, size int64, path string, human bool) {
 	if human {
-		fmt.Printf("%s\t%s\n", utils.HumanSize(size), path)
+		fmt.Fprintf(w, "%s\t%s\n", utils.HumanSize(size), path)
 		return
 	}
 	blocks := (size + 1023) / 1024
-	fmt.Printf("%d\t%s\n", blocks, path)
+	fmt.Fprintf(w, "%d\t%s\n", blocks, path)
 }
@@ -1,6 +1,8 @@
 package fs
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -8,6 +10,7 @@ import (
 	"syscall"
 	"testing"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
@@ -164,6 +167,26 @@ func TestDuApparentAllSummaryTotalAndExclude(t *testing.T) {
 	}
 }
 
+func TestDuStructuredOutputReportsBytes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), make([]byte, 3000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	cmd := base.NewCommand("du", "", duCmd, base.WithTabularOutput())
+	if err := cmd.Run(&base.Invocation{Stdout: &out}, []string{"--output", "tsv", "-h", "--apparent-size", "-a", dir}); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("size_bytes\tpath\n3000\t%s\n%d\t%s\n", filepath.Join(dir, "a.txt"), info.Size()+3000, dir)
+	if out.String() != want {
+		t.Fatalf("unexpected du tsv %q, want %q", out.String(), want)
+	}
+}
+
 // TestDuExcludeMalformedPatternReturnsError is a regression test: an invalid
 // glob pattern must surface as an error (matching GNU du), not be silently
 // ignored (which would leave every file un-excluded with no diagnostic).
@@ -351,3 +374,190 @@ func TestDuOneFilesystemSkipsChildOnDifferentDevice(t *testing.T) {
 		t.Fatalf("expected only the root dir row, got %#v", rows)
 	}
 }
+
+func duExplorerTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, size := range map[string]int{"big/a": 6000, "big/sub/b": 2000, "small/c": 1000, "d.txt": 500, "skip.tmp": 9000, "big/sub/keep.tmp": 100} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, bytes.Repeat([]byte("x"), size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func duExplorerNames(e *duExplorer) string {
+	var names []string
+	for _, n := range e.entries() {
+		names = append(names, n.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestDuExplorerTreeAndNavigation(t *testing.T) {
+	dir := duExplorerTree(t)
+	e, err := newDuExplorer(&base.Invocation{}, dir, duOptions{apparentSize: true, excludes: []string{"skip.tmp", "small/*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// small/ keeps only its own 4 KiB directory size once small/c is
+	// excluded, which still outweighs d.txt.
+	if got := duExplorerNames(e); got != "big small d.txt" {
+		t.Fatalf("entries = %q", got)
+	}
+	dirSize := func(p string) int64 {
+		info, err := os.Lstat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	want := 6000 + 2000 + 100 + 500 + dirSize(dir) + dirSize(filepath.Join(dir, "big")) + dirSize(filepath.Join(dir, "big", "sub")) + dirSize(filepath.Join(dir, "small"))
+	if e.top.apparent != want || e.top.items != 7 {
+		t.Fatalf("top = %d bytes, %d items; want %d, 7", e.top.apparent, e.top.items, want)
+	}
+
+	e.handleKey(utils.KeyEnter)
+	if e.cwd.name != "big" || duExplorerNames(e) != "sub a" {
+		t.Fatalf("after enter: cwd %q, entries %q", e.cwd.name, duExplorerNames(e))
+	}
+	e.handleKey("j")
+	e.handleKey("l") // a is a file
+	e.handleKey("k")
+	e.handleKey("l")
+	if e.cwd.name != "sub" || e.cwd.depth != 2 {
+		t.Fatalf("after k l: cwd %q depth %d", e.cwd.name, e.cwd.depth)
+	}
+	e.handleKey(utils.KeyLeft)
+	if e.cwd.name != "big" || e.selected().name != "sub" {
+		t.Fatalf("after left: cwd %q, selected %q", e.cwd.name, e.selected().name)
+	}
+	e.handleKey(utils.KeyLeft)
+	e.handleKey(utils.KeyLeft)
+	if e.cwd != e.top {
+		t.Fatalf("left above the scan root moved to %q", e.cwd.path)
+	}
+
+	e.handleKey("a")
+	if e.apparent || e.top.size(false) != e.top.allocated {
+		t.Fatal("a did not switch to allocated sizes")
+	}
+	if e.handleKey("q") {
+		t.Fatal("q did not quit")
+	}
+}
+
+func TestDuExplorerResolvesRootAgainstInvocationDir(t *testing.T) {
+	dir := duExplorerTree(t)
+	inv := &base.Invocation{Dir: filepath.Dir(dir)}
+	e, err := newDuExplorer(inv, "./"+filepath.Base(dir)+"/", duOptions{apparentSize: true, excludes: []string{"skip.tmp", "small/*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.top.path != dir || duExplorerNames(e) != "big small d.txt" {
+		t.Fatalf("top %q, entries %q", e.top.path, duExplorerNames(e))
+	}
+}
+
+func TestDuExplorerRescanAndDelete(t *testing.T) {
+	dir := duExplorerTree(t)
+	inv := &base.Invocation{}
+	e, err := newDuExplorer(inv, dir, duOptions{apparentSize: true, excludes: []string{"*.tmp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := e.top.apparent
+	e.handleKey(utils.KeyEnter) // big
+	if err := os.WriteFile(filepath.Join(dir, "big", "new"), make([]byte, 700), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "big", "new.tmp"), make([]byte, 700), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e.handleKey("r")
+	if got := duExplorerNames(e); got != "sub a new" {
+		t.Fatalf("after rescan: entries %q", got)
+	}
+	if e.top.apparent != before+700 || e.top.items != 8 {
+		t.Fatalf("after rescan: top %d bytes, %d items; want %d, 8", e.top.apparent, e.top.items, before+700)
+	}
+
+	e.handleKey(utils.KeyEnd)
+	e.handleKey("d")
+	e.handleKey("n")
+	if _, err := os.Stat(filepath.Join(dir, "big", "new")); err != nil || e.message != "Not deleted." {
+		t.Fatalf("declined delete: %v, message %q", err, e.message)
+	}
+
+	e.handleKey(utils.KeyHome)
+	e.handleKey("d")
+	if e.pending == nil || e.pending.name != "sub" {
+		t.Fatalf("pending delete = %v", e.pending)
+	}
+	e.handleKey("y")
+	if _, err := os.Stat(filepath.Join(dir, "big", "sub")); !os.IsNotExist(err) {
+		t.Fatalf("sub still exists: %v", err)
+	}
+	if got := duExplorerNames(e); got != "a new" || e.top.items != 6 {
+		t.Fatalf("after delete: entries %q, %d items", got, e.top.items)
+	}
+
+	ro := &base.Invocation{Env: []string{base.ReadOnlyEnv + "=1"}}
+	e, err = newDuExplorer(ro, dir, duOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.handleKey("d")
+	if e.pending != nil || !strings.Contains(e.message, "read-only") {
+		t.Fatalf("read-only delete: pending %v, message %q", e.pending, e.message)
+	}
+}
+
+func TestDuExplorerRender(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"half": 5000, "rest/x": 5000} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, err := newDuExplorer(&base.Invocation{}, dir, duOptions{apparentSize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	e.render(&out, 80, 10)
+	screen := out.String()
+	rest, err := os.Lstat(filepath.Join(dir, "rest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := fmt.Sprintf("%5.1f%%", float64(5000+rest.Size())*100/float64(e.top.apparent))
+	for _, want := range []string{"apparent size", "--- " + dir, share + " [", "]       1  rest/", "]          half", "Items: 3"} {
+		if !strings.Contains(screen, want) {
+			t.Fatalf("screen missing %q:\n%s", want, screen)
+		}
+	}
+	if n := strings.Count(screen, "\n"); n != 9 {
+		t.Fatalf("screen has %d newlines, want 9 for 10 lines", n)
+	}
+}
+
+func TestDuInteractiveRequiresTerminal(t *testing.T) {
+	var out, errOut bytes.Buffer
+	inv := &base.Invocation{Stdin: strings.NewReader(""), Stdout: &out, Stderr: &errOut}
+	err := duCmd(inv, []string{"--interactive", t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "terminal") {
+		t.Fatalf("--interactive without a terminal: %v", err)
+	}
+	if err := duCmd(inv, []string{"--interactive", "a", "b"}); err == nil {
+		t.Fatal("--interactive accepted two paths")
+	}
+}
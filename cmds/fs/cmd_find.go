@@ -3,7 +3,8 @@ package fs
 import (
 	"flag"
 	"fmt"
-	"gobox/cmds/utils"
+	"gobox/cmds/base"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -16,319 +17,334 @@ import (
 
 // FindCmd implements a basic subset of busybox find
 func FindCmd(args []string) error {
-	args = normalizeFindArgs(args)
-	// GNU find binds -not/! to the predicate that immediately follows it, not
-	// to the whole expression. Pull those per-predicate negations out before
-	// flag parsing; any remaining bare -not keeps the legacy whole-result flag.
-	negated, args := extractNegations(args)
-	fsFlags := flag.NewFlagSet("find", flag.ContinueOnError)
-	name := fsFlags.String("name", "", "match basename with pattern (shell glob)")
-	pathPattern := fsFlags.String("path", "", "match full path with pattern (shell glob)")
-	negate := fsFlags.Bool("not", false, "negate the following predicate (legacy: whole result if unbound)")
-	typ := fsFlags.String("type", "", "file type: f (file) or d (dir)")
-	maxdepth := fsFlags.Int("maxdepth", -1, "maximum depth")
-	mindepth := fsFlags.Int("mindepth", 0, "minimum depth")
-	printFlag := fsFlags.Bool("print", true, "print matched paths")
-	empty := fsFlags.Bool("empty", false, "match empty files or directories")
-	size := fsFlags.String("size", "", "file size: +N (larger than N), -N (smaller than N), N (equal to N) (c/K/M/G suffixes; default unit is bytes)")
-	atime := fsFlags.String("atime", "", "file access time: +N, -N, N (N[smhd] = seconds/minutes/hours/days; no suffix = days)")
-	mtime := fsFlags.String("mtime", "", "file modify time: +N, -N, N (N[smhd] = seconds/minutes/hours/days; no suffix = days)")
-
-	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox find [OPTION]... [PATH...]")
-		fmt.Fprintln(os.Stderr, "Search for files in a directory hierarchy.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Filters:")
-		fmt.Fprintln(os.Stderr, "  -name PATTERN      match basename with shell glob")
-		fmt.Fprintln(os.Stderr, "  -path PATTERN      match full path with shell glob")
-		fmt.Fprintln(os.Stderr, "  -type TYPE         file type: f (file) or d (directory)")
-		fmt.Fprintln(os.Stderr, "  -empty             match empty files or directories")
-		fmt.Fprintln(os.Stderr, "  -size SPEC         size filter: +N, -N, N with optional c/K/M/G suffix (default bytes)")
-		fmt.Fprintln(os.Stderr, "  -atime SPEC        access time filter: +N, -N, N with optional s/m/h suffix")
-		fmt.Fprintln(os.Stderr, "  -mtime SPEC        modify time filter: +N, -N, N with optional s/m/h suffix")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Traversal:")
-		fmt.Fprintln(os.Stderr, "  -maxdepth N        descend at most N levels")
-		fmt.Fprintln(os.Stderr, "  -mindepth N        skip matches shallower than N levels")
-		fmt.Fprintln(os.Stderr, "  -not, !            negate the immediately following predicate")
-		fmt.Fprintln(os.Stderr, "  -print             print matched paths (default true)")
-		fmt.Fprintln(os.Stderr, "  -h, --help         show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox find . -type f -name '*.log'")
-		fmt.Fprintln(os.Stderr, "  gobox find /tmp -maxdepth 2 -empty")
-	}
-
-	flagArgs, pathArgs := splitFindArgs(args)
-	if err := utils.ParseFlagSet(fsFlags, flagArgs); err != nil {
+	return findCmd(base.Stdio(), args)
+}
+
+func findCmd(inv *base.Invocation, args []string) error {
+	paths, opts, expr, err := parseFindArgs(inv, args)
+	if err != nil {
 		if err == flag.ErrHelp {
+			printFindUsage(inv.Stderr)
 			return nil
 		}
 		return err
 	}
-
-	if *typ != "" && *typ != "f" && *typ != "d" {
-		return fmt.Errorf("invalid type %q: must be 'f' or 'd'", *typ)
-	}
-
-	// fsFlags.Args() should be empty since splitFindArgs already separated
-	// out the paths, but guard against any stray flag-shaped leftovers.
-	if fsFlags.NArg() > 0 {
-		for _, arg := range fsFlags.Args() {
-			if strings.HasPrefix(arg, "-") {
-				return fmt.Errorf("unexpected option %q found after flags", arg)
-			}
-		}
-	}
-
-	paths := append(fsFlags.Args(), pathArgs...)
 	if len(paths) == 0 {
 		paths = []string{"."}
 	}
 
 	// Debug output
-	if os.Getenv("DEBUG_FIND") != "" {
-		fmt.Fprintf(os.Stderr, "DEBUG: paths=%v, name='%s', path='%s', typ='%s', not=%v, maxdepth=%d, mindepth=%d, empty=%v, size='%s', atime='%s', mtime='%s'\n",
-			paths, *name, *pathPattern, *typ, *negate, *maxdepth, *mindepth, *empty, *size, *atime, *mtime)
+	if inv.Getenv("DEBUG_FIND") != "" {
+		fmt.Fprintf(inv.Stderr, "DEBUG: paths=%v, maxdepth=%d, mindepth=%d, expr=%s\n",
+			paths, opts.maxdepth, opts.mindepth, expr)
 	}
 
-	// Compile path pattern regex once before walking.
-	var pathPatternRe *regexp.Regexp
-	if *pathPattern != "" {
-		cleanPat := filepath.ToSlash(filepath.Clean(*pathPattern))
-		if re, err := regexp.Compile(globToRegex(cleanPat)); err == nil {
-			pathPatternRe = re
+	for _, op := range opts.writes {
+		if err := base.CheckWritable(inv, op); err != nil {
+			return err
 		}
 	}
-
-	for _, root := range paths {
-		// Preserve the root exactly as given for display (GNU find prints the
-		// starting point verbatim, e.g. "find ." yields "./x"), but walk the
-		// cleaned path since WalkDir requires a real filesystem path.
-		origRoot := root
-		cleanRoot := filepath.Clean(root)
-		baseDepth := pathDepth(cleanRoot)
-		err := filepath.WalkDir(cleanRoot, func(p string, d fs.DirEntry, err error) error {
-			if err != nil {
-				if p == cleanRoot {
-					return err
-				}
-				return nil
-			}
-			// display is the path as GNU find would print it (root prefix preserved).
-			display := origRoot
-			if rel, relErr := filepath.Rel(cleanRoot, p); relErr == nil && rel != "." {
-				display = joinDisplayPath(origRoot, rel)
-			}
-			depth := pathDepth(p) - baseDepth
-			if *maxdepth >= 0 && depth > *maxdepth {
-				if d.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-			if depth < *mindepth {
-				return nil
+	for i, out := range opts.outputs {
+		if err := out.open(inv); err != nil {
+			for _, opened := range opts.outputs[:i] {
+				opened.close()
 			}
-
-			// Each predicate is evaluated to a raw boolean, negated on its own
-			// if it was preceded by -not/!, then AND-combined into matched.
-			matched := true
-			apply := func(pred string, ok bool) {
-				if negated[pred] {
-					ok = !ok
-				}
-				matched = matched && ok
-			}
-
-			// type filter
-			if *typ != "" {
-				ok := true
-				if *typ == "f" && !d.Type().IsRegular() {
-					ok = false
-				}
-				if *typ == "d" && !d.IsDir() {
-					ok = false
-				}
-				apply("type", ok)
-			}
-
-			// name filter (glob pattern matching, not regex)
-			if *name != "" {
-				// Use filepath.Match for glob pattern matching
-				// Patterns: * matches any sequence, ? matches any single char, [abc] matches char class
-				nameMatched, err := filepath.Match(*name, d.Name())
-				apply("name", err == nil && nameMatched)
-			}
-
-			if pathPatternRe != nil {
-				// GNU find matches -path against the printed path, which keeps
-				// the root prefix (so "find . -path '*/x/*'" can match "./x/...").
-				candidate := filepath.ToSlash(display)
-				apply("path", pathPatternRe.MatchString(candidate))
-			}
-
-			// empty filter
-			if *empty {
-				ok := true
-				if d.IsDir() {
-					// Check if directory is empty
-					entries, err := os.ReadDir(p)
-					if err != nil || len(entries) > 0 {
-						ok = false
-					}
-				} else {
-					// Check if file is empty
-					info, err := d.Info()
-					if err != nil || info.Size() > 0 {
-						ok = false
-					}
-				}
-				apply("empty", ok)
-			}
-
-			// size filter
-			if *size != "" {
-				ok := true
-				if d.IsDir() {
-					// Match GNU find semantics: size filtering applies to files here, so directories do not match.
-					ok = false
-				} else {
-					info, err := d.Info()
-					if err != nil || !matchSize(info.Size(), *size) {
-						ok = false
-					}
-				}
-				apply("size", ok)
-			}
-
-			// atime filter (access time)
-			if *atime != "" {
-				info, err := d.Info()
-				apply("atime", err == nil && matchTime(info, *atime, "atime"))
-			}
-
-			// mtime filter (modify time)
-			if *mtime != "" {
-				info, err := d.Info()
-				apply("mtime", err == nil && matchTime(info, *mtime, "mtime"))
-			}
-
-			if *negate {
-				matched = !matched
-			}
-
-			if matched && *printFlag {
-				fmt.Println(display)
-			}
-			return nil
-		})
-		if err != nil {
 			return err
 		}
 	}
+
+	run := &findRun{inv: inv}
+	var walkErr error
+	for _, root := range paths {
+		walkFindRoot(run, root, opts, expr)
+	}
+	for _, e := range opts.batches {
+		e.flush(run)
+	}
+	for _, out := range opts.outputs {
+		if err := out.close(); err != nil && walkErr == nil {
+			walkErr = err
+		}
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+	if run.failed {
+		return findExitError(1)
+	}
 	return nil
 }
 
-// findBoolFlags lists find's flags that take no argument value, so that
-// splitFindArgs knows not to consume the next token as a flag value.
-var findBoolFlags = map[string]bool{
-	"not":   true,
-	"print": true,
-	"empty": true,
+// findWalker walks one starting point. It descends in lexical order like
+// filepath.WalkDir but decides itself whether to enter each directory, so
+// that -prune, -xdev, -maxdepth and symlink following share one place.
+type findWalker struct {
+	run  *findRun
+	opts findOptions
+	expr findExpr
+	root string // starting point as given
+	dev  uint64 // device of the starting point, for -xdev
+	// ancestors are the directories being walked when symlinks are followed,
+	// to detect loops.
+	ancestors []findAncestor
 }
 
-// splitFindArgs separates flag tokens (and their values) from bare path
-// arguments regardless of where they appear on the command line. This lets
-// paths be given before, after, or interleaved with flags (e.g.
-// `find . -type f -name '*.log'`), matching GNU find and gobox's own
-// documented usage example, instead of requiring all flags to precede paths.
-func splitFindArgs(args []string) (flagArgs []string, paths []string) {
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		if arg == "--" {
-			paths = append(paths, args[i+1:]...)
-			break
-		}
-		if len(arg) > 1 && arg[0] == '-' {
-			flagArgs = append(flagArgs, arg)
-			name := strings.TrimLeft(arg, "-")
-			if eq := strings.IndexByte(name, '='); eq >= 0 {
-				// value embedded via -name=value, nothing more to consume
-				continue
-			}
-			if !findBoolFlags[name] && i+1 < len(args) {
-				i++
-				flagArgs = append(flagArgs, args[i])
-			}
-			continue
+type findAncestor struct {
+	dev, ino uint64
+	display  string
+}
+
+// walkFindRoot evaluates expr for root and everything below it. In
+// depth-first mode a directory is evaluated after its contents. Like GNU
+// find, a starting point that cannot be read is reported and the run goes
+// on with the next one, exiting 1.
+func walkFindRoot(run *findRun, root string, opts findOptions, expr findExpr) {
+	// Preserve the root exactly as given for display (GNU find prints the
+	// starting point verbatim, e.g. "find ." yields "./x"), but walk the
+	// cleaned path.
+	cleanRoot := filepath.Clean(run.inv.Path(root))
+	info, err := os.Lstat(cleanRoot)
+	if err != nil {
+		run.warn("'%s': %v", root, findErrorText(err))
+		return
+	}
+	w := &findWalker{run: run, opts: opts, expr: expr, root: root}
+	d := w.follow(cleanRoot, fs.FileInfoToDirEntry(info), 0)
+	if target, err := d.Info(); err == nil {
+		if st, ok := target.Sys().(*syscall.Stat_t); ok {
+			w.dev = st.Dev
 		}
-		paths = append(paths, arg)
 	}
-	return flagArgs, paths
+	w.visit(cleanRoot, root, d, 0)
 }
 
-// findPredicateFlags lists the match predicates that a preceding -not/! can
-// negate individually (GNU find semantics).
-var findPredicateFlags = map[string]bool{
-	"name":  true,
-	"path":  true,
-	"type":  true,
-	"empty": true,
-	"size":  true,
-	"atime": true,
-	"mtime": true,
+// findErrorText is the system error of err, capitalised as GNU find prints
+// it ("No such file or directory").
+func findErrorText(err error) string {
+	msg := unwrapPathError(err).Error()
+	if msg == "" {
+		return msg
+	}
+	return strings.ToUpper(msg[:1]) + msg[1:]
 }
 
-// extractNegations consumes -not tokens that immediately precede a match
-// predicate and records them as per-predicate negations, returning the
-// remaining args with those -not tokens removed. Consecutive -not tokens
-// toggle (double negation cancels). A -not that does not bind to a predicate
-// is left in place so the FlagSet still applies the legacy whole-result flag.
-func extractNegations(args []string) (map[string]bool, []string) {
-	negated := make(map[string]bool)
-	out := make([]string, 0, len(args))
-	pendingNot := false
-	for _, arg := range args {
-		if arg == "-not" {
-			pendingNot = !pendingNot
-			continue
-		}
-		if pendingNot && len(arg) > 1 && arg[0] == '-' {
-			pred := strings.TrimLeft(arg, "-")
-			if eq := strings.IndexByte(pred, '='); eq >= 0 {
-				pred = pred[:eq]
-			}
-			if findPredicateFlags[pred] {
-				negated[pred] = !negated[pred]
-				pendingNot = false
-				out = append(out, arg)
-				continue
-			}
+// follow replaces a symlink entry with its target under -L, or under -H for
+// a starting point. Broken links stay links.
+func (w *findWalker) follow(p string, d fs.DirEntry, depth int) fs.DirEntry {
+	if d.Type()&fs.ModeSymlink == 0 || w.opts.follow == findFollowNever ||
+		(w.opts.follow == findFollowRoots && depth > 0) {
+		return d
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return d
+	}
+	return fs.FileInfoToDirEntry(info)
+}
+
+func (w *findWalker) visit(p, display string, d fs.DirEntry, depth int) {
+	c := &findContext{run: w.run, path: p, display: display, root: w.root, entry: d, depth: depth}
+	if d.IsDir() && w.inLoop(c) {
+		return
+	}
+	matchable := depth >= w.opts.mindepth
+	if matchable && !w.opts.depthFirst {
+		w.expr.eval(c)
+	}
+	ancestors := len(w.ancestors)
+	if d.IsDir() && (w.opts.maxdepth < 0 || depth < w.opts.maxdepth) && !c.prune && w.enter(c) {
+		// ReadDir returns what it read before failing; those entries are
+		// still walked, as GNU find does.
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			w.run.warn("'%s': %v", display, findErrorText(err))
 		}
-		if pendingNot {
-			// Unbound -not: preserve legacy whole-result negation.
-			out = append(out, "-not")
-			pendingNot = false
+		for _, e := range entries {
+			child := filepath.Join(p, e.Name())
+			w.visit(child, joinDisplayPath(display, e.Name()), w.follow(child, e, depth+1), depth+1)
 		}
-		out = append(out, arg)
 	}
-	if pendingNot {
-		out = append(out, "-not")
+	w.ancestors = w.ancestors[:ancestors]
+	if matchable && w.opts.depthFirst {
+		w.expr.eval(c)
 	}
-	return negated, out
 }
 
-func normalizeFindArgs(args []string) []string {
-	normalized := make([]string, 0, len(args))
-	for _, arg := range args {
-		if arg == "!" {
-			normalized = append(normalized, "-not")
-			continue
+// inLoop reports whether, with symlinks followed, the directory c is one
+// the walk is already inside. Like GNU find, the loop is reported and the
+// directory neither evaluated nor entered.
+func (w *findWalker) inLoop(c *findContext) bool {
+	if w.opts.follow == findFollowNever {
+		return false
+	}
+	st := c.statT()
+	if st == nil {
+		return false
+	}
+	for _, a := range w.ancestors {
+		if a.dev == st.Dev && a.ino == st.Ino {
+			w.run.warn("File system loop detected; %s is part of the same file system loop as %s.", c.display, a.display)
+			return true
 		}
-		normalized = append(normalized, arg)
 	}
-	return normalized
+	return false
+}
+
+// enter reports whether the walk may descend into the directory c: under
+// -xdev only on the starting point's filesystem. When symlinks are followed
+// it records c as an ancestor for inLoop.
+func (w *findWalker) enter(c *findContext) bool {
+	if !w.opts.xdev && w.opts.follow == findFollowNever {
+		return true
+	}
+	st := c.statT()
+	if st == nil {
+		return true
+	}
+	if w.opts.xdev && st.Dev != w.dev {
+		return false
+	}
+	if w.opts.follow != findFollowNever {
+		w.ancestors = append(w.ancestors, findAncestor{dev: st.Dev, ino: st.Ino, display: c.display})
+	}
+	return true
+}
+
+// findFlags lists find's primaries and options for shell completion; the
+// expression parser is hand-written.
+var findFlags = []base.Flag{
+	{Name: "-P", Usage: "never follow symlinks (default)"},
+	{Name: "-H", Usage: "follow symlinks given as starting points"},
+	{Name: "-L", Usage: "follow all symlinks"},
+	{Name: "-name", TakesValue: true, Usage: "match basename with pattern (shell glob)"},
+	{Name: "-iname", TakesValue: true, Usage: "like -name, ignoring case"},
+	{Name: "-path", TakesValue: true, Usage: "match full path with pattern (shell glob)"},
+	{Name: "-ipath", TakesValue: true, Usage: "like -path, ignoring case"},
+	{Name: "-regex", TakesValue: true, Usage: "match full path with regular expression"},
+	{Name: "-iregex", TakesValue: true, Usage: "like -regex, ignoring case"},
+	{Name: "-regextype", TakesValue: true, Usage: "syntax of later -regex: emacs (default), posix-basic, posix-extended, ..."},
+	{Name: "-type", TakesValue: true, Usage: "file type: f d l s p c b, or a comma list"},
+	{Name: "-empty", Usage: "match empty files or directories"},
+	{Name: "-size", TakesValue: true, Usage: "file size: +N, -N, N (c/K/M/G suffixes; default unit is bytes)"},
+	{Name: "-atime", TakesValue: true, Usage: "file access time: +N, -N, N (N[smhd]; no suffix = days)"},
+	{Name: "-mtime", TakesValue: true, Usage: "file modify time: +N, -N, N (N[smhd]; no suffix = days)"},
+	{Name: "-ctime", TakesValue: true, Usage: "file status change time: +N, -N, N (N[smhd]; no suffix = days)"},
+	{Name: "-amin", TakesValue: true, Usage: "file access time in minutes: +N, -N, N"},
+	{Name: "-mmin", TakesValue: true, Usage: "file modify time in minutes: +N, -N, N"},
+	{Name: "-cmin", TakesValue: true, Usage: "file status change time in minutes: +N, -N, N"},
+	{Name: "-newer", TakesValue: true, Usage: "modified more recently than FILE"},
+	{Name: "-newermt", TakesValue: true, Usage: "modified after DATE"},
+	{Name: "-perm", TakesValue: true, Usage: "permission bits: MODE exactly, -MODE all, /MODE any"},
+	{Name: "-user", TakesValue: true, Usage: "owned by user NAME or ID"},
+	{Name: "-group", TakesValue: true, Usage: "owned by group NAME or ID"},
+	{Name: "-uid", TakesValue: true, Usage: "owner ID: +N, -N, N"},
+	{Name: "-gid", TakesValue: true, Usage: "group ID: +N, -N, N"},
+	{Name: "-nouser", Usage: "owner has no user entry"},
+	{Name: "-nogroup", Usage: "group has no group entry"},
+	{Name: "-links", TakesValue: true, Usage: "hard link count: +N, -N, N"},
+	{Name: "-inum", TakesValue: true, Usage: "inode number: +N, -N, N"},
+	{Name: "-samefile", TakesValue: true, Usage: "same inode as FILE"},
+	{Name: "-readable", Usage: "readable by the current user"},
+	{Name: "-writable", Usage: "writable by the current user"},
+	{Name: "-executable", Usage: "executable (or searchable) by the current user"},
+	{Name: "-print", Usage: "print matched paths"},
+	{Name: "-print0", Usage: "print matched paths followed by NUL"},
+	{Name: "-printf", TakesValue: true, Usage: "print FORMAT with %-directives and escapes"},
+	{Name: "-fprint", TakesValue: true, Usage: "write matched paths to FILE"},
+	{Name: "-delete", Usage: "delete matched files and empty directories"},
+	{Name: "-exec", TakesValue: true, Usage: "run COMMAND ... ; per file or COMMAND ... {} + in batches"},
+	{Name: "-execdir", TakesValue: true, Usage: "like -exec, run in the file's directory"},
+	{Name: "-ok", TakesValue: true, Usage: "like -exec ... ;, asking first"},
+	{Name: "-okdir", TakesValue: true, Usage: "like -execdir ... ;, asking first"},
+	{Name: "-prune", Usage: "do not descend into the current directory"},
+	{Name: "-depth", Usage: "visit directory contents before the directory"},
+	{Name: "-xdev", Usage: "stay on each starting point's filesystem"},
+	{Name: "-mount", Usage: "same as -xdev"},
+	{Name: "-follow", Usage: "same as -L"},
+	{Name: "-maxdepth", TakesValue: true, Usage: "maximum depth"},
+	{Name: "-mindepth", TakesValue: true, Usage: "minimum depth"},
+	{Name: "-not", Usage: "negate the following expression"},
+	{Name: "-a", Usage: "AND (implied between adjacent expressions)"},
+	{Name: "-and", Usage: "AND (implied between adjacent expressions)"},
+	{Name: "-o", Usage: "OR"},
+	{Name: "-or", Usage: "OR"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
+func printFindUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox find [OPTION]... [PATH...]")
+	fmt.Fprintln(w, "Search for files in a directory hierarchy.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Filters:")
+	fmt.Fprintln(w, "  -name PATTERN      match basename with shell glob (-iname ignores case)")
+	fmt.Fprintln(w, "  -path PATTERN      match full path with shell glob (-ipath ignores case)")
+	fmt.Fprintln(w, "  -regex RE          whole path matches RE (-iregex ignores case)")
+	fmt.Fprintln(w, "  -regextype TYPE    syntax of later -regex: emacs (default), posix-basic,")
+	fmt.Fprintln(w, "                     posix-extended, egrep, grep, sed, awk, ...")
+	fmt.Fprintln(w, "  -type TYPES        f file, d directory, l symlink, s socket, p FIFO,")
+	fmt.Fprintln(w, "                     c/b char/block device; comma list for any of them")
+	fmt.Fprintln(w, "  -empty             match empty files or directories")
+	fmt.Fprintln(w, "  -size SPEC         size filter: +N, -N, N with optional c/K/M/G suffix (default bytes)")
+	fmt.Fprintln(w, "  -atime SPEC        access time filter: +N, -N, N with optional s/m/h suffix")
+	fmt.Fprintln(w, "  -mtime SPEC        modify time filter: +N, -N, N with optional s/m/h suffix")
+	fmt.Fprintln(w, "  -ctime SPEC        status change time filter, as -mtime")
+	fmt.Fprintln(w, "  -amin/-mmin/-cmin N  access/modify/change time in minutes: +N, -N, N")
+	fmt.Fprintln(w, "  -newer FILE        modified more recently than FILE")
+	fmt.Fprintln(w, "  -newermt DATE      modified after DATE (YYYY-MM-DD[ HH:MM[:SS]], RFC 3339, @EPOCH)")
+	fmt.Fprintln(w, "  -perm MODE         permission bits exactly MODE; -MODE all of them, /MODE any")
+	fmt.Fprintln(w, "                     (octal or symbolic, e.g. 644, -u+x, /g=w,o=w)")
+	fmt.Fprintln(w, "  -user NAME         owned by user NAME or ID (-group for groups)")
+	fmt.Fprintln(w, "  -uid N, -gid N     owner/group ID: +N, -N, N")
+	fmt.Fprintln(w, "  -nouser, -nogroup  owner/group ID has no name")
+	fmt.Fprintln(w, "  -links N           hard link count: +N, -N, N")
+	fmt.Fprintln(w, "  -inum N            inode number: +N, -N, N")
+	fmt.Fprintln(w, "  -samefile FILE     same inode as FILE (hard links)")
+	fmt.Fprintln(w, "  -readable, -writable, -executable  accessible by the current user")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Operators (highest precedence first):")
+	fmt.Fprintln(w, "  ( EXPR )           group")
+	fmt.Fprintln(w, "  ! EXPR, -not EXPR  true if EXPR is false")
+	fmt.Fprintln(w, "  EXPR -a EXPR       true if both are; -a may be omitted (also -and)")
+	fmt.Fprintln(w, "  EXPR -o EXPR       true if either is (also -or)")
+	fmt.Fprintln(w, "  EXPR , EXPR        evaluate both, value of the second")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Actions:")
+	fmt.Fprintln(w, "  -print             print the path; implied when no action is given")
+	fmt.Fprintln(w, "  -print0            print the path followed by NUL")
+	fmt.Fprintln(w, "  -printf FORMAT     print FORMAT, no newline added; directives take")
+	fmt.Fprintf(w, "%s\n", "                     width and precision (%-10s, %.5p) and include:")
+	fmt.Fprintf(w, "%s\n", "                     %p path  %P path below start  %f name  %h dir  %H start")
+	fmt.Fprintf(w, "%s\n", "                     %s size  %k KiB used  %b blocks  %m/%M mode  %y/%Y type")
+	fmt.Fprintf(w, "%s\n", "                     %u/%g owner  %U/%G ids  %i inode  %n links  %l target")
+	fmt.Fprintf(w, "%s\n", "                     %d depth  %D device  %a/%c/%t times  %Ak/%Ck/%Tk time")
+	fmt.Fprintln(w, "                     field k (@ Y m d H M S T + ...)  \\n \\t \\0 \\NNN \\c")
+	fmt.Fprintln(w, "  -fprint FILE       write the path to FILE (truncated first)")
+	fmt.Fprintln(w, "  -delete            delete files and empty directories; implies -depth")
+	fmt.Fprintln(w, "  -exec CMD ;        run CMD with {} replaced by the path; true if it exits 0")
+	fmt.Fprintln(w, "  -exec CMD {} +     run CMD on many paths at once; find exits 1 if CMD fails")
+	fmt.Fprintln(w, "  -execdir CMD ;|+   like -exec, run in the file's directory on ./NAME")
+	fmt.Fprintln(w, "  -ok CMD ;          like -exec, ask on stderr first (also -okdir)")
+	fmt.Fprintln(w, "                     gobox commands run in-process, others are executed")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Traversal:")
+	fmt.Fprintln(w, "  -P                 never follow symlinks (default)")
+	fmt.Fprintln(w, "  -H                 follow symlinks given as starting points")
+	fmt.Fprintln(w, "  -L, -follow        follow all symlinks; loops are reported and skipped")
+	fmt.Fprintln(w, "  -prune             true; do not descend into the current directory")
+	fmt.Fprintln(w, "  -xdev, -mount      stay on each starting point's filesystem")
+	fmt.Fprintln(w, "  -maxdepth N        descend at most N levels")
+	fmt.Fprintln(w, "  -mindepth N        skip matches shallower than N levels")
+	fmt.Fprintln(w, "  -depth             visit directory contents before the directory")
+	fmt.Fprintln(w, "  -h, --help         show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox find . -type f -name '*.log'")
+	fmt.Fprintln(w, "  gobox find /tmp -maxdepth 2 -empty")
+	fmt.Fprintln(w, "  gobox find . \\( -name '*.log' -o -name '*.gz' \\) -size +10M")
+	fmt.Fprintln(w, "  gobox find /var/log -name '*.gz' -mtime +7 -delete")
+	fmt.Fprintln(w, "  gobox find . -type f -exec sha256sum {} +")
+	fmt.Fprintln(w, "  gobox find . -name .git -prune -o -type f -perm -u+x -print")
+	fmt.Fprintf(w, "%s\n", "  gobox find . -type f -printf '%T@ %p\\n' | sort -n")
 }
 
 // joinDisplayPath joins a root prefix with a relative path without cleaning,
@@ -555,6 +571,12 @@ func matchTime(info fs.FileInfo, timeSpec string, timeType string) bool {
 		}
 	} else if timeType == "mtime" {
 		fileTime = info.ModTime()
+	} else if timeType == "ctime" {
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return false
+		}
+		fileTime = time.Unix(st.Ctim.Sec, st.Ctim.Nsec)
 	} else {
 		return false
 	}
@@ -2,9 +2,15 @@ package fs
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -433,6 +439,22 @@ func TestFindSizeEndToEnd(t *testing.T) {
 	}
 }
 
+func TestFindSizeMatchesDirectories(t *testing.T) {
+	dir := t.TempDir()
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := strconv.FormatInt(info.Size(), 10) + "c"
+	output, err := runFindCmd(t, []string{"-maxdepth", "0", "-size", spec, dir})
+	if err != nil {
+		t.Fatalf("FindCmd failed: %v", err)
+	}
+	if output != dir+"\n" {
+		t.Fatalf("expected -size %s to match the directory, got %q", spec, output)
+	}
+}
+
 func TestFindMtimeEndToEnd(t *testing.T) {
 	dir := t.TempDir()
 	oldFile := filepath.Join(dir, "old.txt")
@@ -526,3 +548,617 @@ func TestFindDotRootPrefix(t *testing.T) {
 		t.Fatalf("find . -path '*/sub/*' = %q, want it to contain ./sub/inner.txt", out)
 	}
 }
+
+// TestFindPathMatchesPatternAsGiven is a regression test: -path cleaned its
+// pattern, so "./x" became "x" and never matched the printed "./x".
+func TestFindPathMatchesPatternAsGiven(t *testing.T) {
+	dir := findTree(t, "a/f", "x/g", "y")
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{".", "-path", "./x", "-prune", "-o", "-print"}, ".\n./a\n./a/f\n./y\n"},
+		{[]string{".", "-path", "./a/*"}, "./a/f\n"},
+		{[]string{".", "-path", "a/*"}, ""},
+	} {
+		var out bytes.Buffer
+		if err := findCmd(&base.Invocation{Stdout: &out, Stderr: io.Discard, Dir: dir}, tc.args); err != nil {
+			t.Fatalf("find %v: %v", tc.args, err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("find %v = %q, want %q", tc.args, out.String(), tc.want)
+		}
+	}
+}
+
+// findTree creates files (and their parent directories) under a fresh temp
+// dir and returns it. Names ending in "/" are created as directories.
+func findTree(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				t.Fatalf("mkdir %s: %v", name, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", name, err)
+		}
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// findRelLines runs find and returns its output lines relative to dir.
+func findRelLines(t *testing.T, dir string, args ...string) []string {
+	t.Helper()
+	out, err := runFindCmd(t, append([]string{dir}, args...))
+	if err != nil {
+		t.Fatalf("find %v failed: %v", args, err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		rel, _ := filepath.Rel(dir, line)
+		lines = append(lines, filepath.ToSlash(rel))
+	}
+	return lines
+}
+
+func TestFindExpressionOperators(t *testing.T) {
+	dir := findTree(t, "a.log", "b.gz", "c.txt", "sub/d.log", "logs.d/")
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"or of repeated -name", []string{"-name", "*.log", "-o", "-name", "*.gz"}, "a.log b.gz sub/d.log"},
+		{"parentheses group the or", []string{"(", "-name", "*.log", "-o", "-name", "*.gz", ")", "-type", "f"}, "a.log b.gz sub/d.log"},
+		{"implicit and binds tighter than or", []string{"-name", "*.gz", "-o", "-name", "*.d", "-type", "f"}, "b.gz"},
+		{"explicit -and and -or", []string{"-type", "f", "-and", "-name", "*.txt", "-or", "-name", "*.gz"}, "b.gz c.txt"},
+		{"not applies to a group", []string{"-type", "f", "!", "(", "-name", "*.log", "-o", "-name", "*.gz", ")"}, "c.txt"},
+		{"repeated -name all must match", []string{"-name", "*.log", "-name", "d.*"}, "sub/d.log"},
+		{"comma yields the right operand", []string{"-name", "*.txt", ",", "-name", "*.gz"}, "b.gz"},
+		{"comma runs actions on both sides", []string{"-name", "*.txt", "-print", ",", "-name", "*.gz", "-print"}, "c.txt b.gz"},
+		{"or short-circuits the print", []string{"-type", "d", "-o", "-name", "*.log", "-o", "-print"}, "b.gz c.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findRelLines(t, dir, tt.args...)
+			sort.Strings(got)
+			want := strings.Fields(tt.want)
+			sort.Strings(want)
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Fatalf("find %v = %v, want %v", tt.args, got, want)
+			}
+		})
+	}
+}
+
+func TestFindExpressionErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"(", "-name", "x"}, "missing ')'"},
+		{[]string{"-name", "x", ")"}, "unexpected ')'"},
+		{[]string{"(", ")"}, "empty parentheses"},
+		{[]string{"-not"}, "expected an expression after -not"},
+		{[]string{"-name", "x", "-o"}, "expected an expression after -o"},
+		{[]string{"-o", "-name", "x"}, "unexpected -o"},
+		{[]string{"-name"}, "missing argument to -name"},
+		{[]string{"-bogus"}, "unknown predicate"},
+		{[]string{"-type", "x"}, "invalid type"},
+		{[]string{"-size", "big"}, "invalid size"},
+		{[]string{"-maxdepth", "-1"}, "invalid argument"},
+	}
+	for _, tt := range tests {
+		_, err := runFindCmd(t, append([]string{dir}, tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("find %v error = %v, want it to contain %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestParseFindArgsPrecedence(t *testing.T) {
+	paths, opts, expr, err := parseFindArgs(&base.Invocation{}, []string{"a", "-name", "x", "-o", "!", "-type", "d", "-size", "+1", ",", "-maxdepth", "2", "b"})
+	if err != nil {
+		t.Fatalf("parseFindArgs: %v", err)
+	}
+	if strings.Join(paths, " ") != "a b" || opts.maxdepth != 2 {
+		t.Fatalf("paths=%v maxdepth=%d, want [a b] 2", paths, opts.maxdepth)
+	}
+	want := "(((-name x -o (! -type d -a -size +1)) , -maxdepth 2) -a -print)"
+	if expr.String() != want {
+		t.Fatalf("expr = %s, want %s", expr, want)
+	}
+}
+
+// runFindInv runs find with buffered streams and the given stdin and env.
+func runFindInv(t *testing.T, stdin string, env []string, args ...string) (string, string, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	inv := &base.Invocation{Stdin: strings.NewReader(stdin), Stdout: &out, Stderr: &errOut, Env: env}
+	err := findCmd(inv, args)
+	return out.String(), errOut.String(), err
+}
+
+func TestFindExecRunsGoboxCommandsInProcess(t *testing.T) {
+	dir := findTree(t, "a.log", "b.log", "c.txt")
+	if _, stderr, err := runFindInv(t, "", nil, dir, "-name", "a.log", "-exec", "truncate", "-s", "0", "{}", ";"); err != nil {
+		t.Fatalf("-exec ; failed: %v (%s)", err, stderr)
+	}
+	if _, stderr, err := runFindInv(t, "", nil, dir, "-name", "*.txt", "-exec", "gobox", "truncate", "-s", "1", "{}", "+"); err != nil {
+		t.Fatalf("-exec + failed: %v (%s)", err, stderr)
+	}
+	for name, want := range map[string]int64{"a.log": 0, "b.log": 4, "c.txt": 1} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || info.Size() != want {
+			t.Fatalf("%s size = %v (%v), want %d", name, info.Size(), err, want)
+		}
+	}
+}
+
+func TestFindExecExternalWritesAuditLog(t *testing.T) {
+	dir := findTree(t, "a.log")
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	env := []string{base.AuditEnv + "=" + logPath}
+	if _, stderr, err := runFindInv(t, "", env, dir, "-name", "a.log", "-exec", "/bin/sh", "-c", "exit 0", "{}", ";"); err != nil {
+		t.Fatalf("-exec failed: %v (%s)", err, stderr)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec base.AuditRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("audit line %q: %v", data, err)
+	}
+	want := "-c exit 0 " + filepath.Join(dir, "a.log")
+	if rec.Action != "exec" || rec.Target != "/bin/sh" || rec.Detail["args"] != want || rec.Outcome != "ok" {
+		t.Fatalf("unexpected audit record: %+v", rec)
+	}
+}
+
+func TestFindExecStatusIsPredicate(t *testing.T) {
+	dir := findTree(t, "a.log", "sub/")
+	// stat fails on the file's non-existent child, so only directories pass.
+	out, _, err := runFindInv(t, "", nil, dir, "-mindepth", "1", "-exec", "stat", "-c", "%n", "{}/.", ";", "-print")
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	want := filepath.Join(dir, "sub") + "/.\n" + filepath.Join(dir, "sub") + "\n"
+	if out != want {
+		t.Fatalf("output = %q, want %q", out, want)
+	}
+}
+
+func TestFindMissingStartingPointContinues(t *testing.T) {
+	dir := findTree(t, "a/x", "b")
+	missing := filepath.Join(dir, "nonexist")
+	out, stderr, err := runFindInv(t, "", nil, filepath.Join(dir, "b"), missing, filepath.Join(dir, "a"))
+	want := filepath.Join(dir, "b") + "\n" + filepath.Join(dir, "a") + "\n" + filepath.Join(dir, "a", "x") + "\n"
+	if out != want {
+		t.Fatalf("stdout = %q, want %q", out, want)
+	}
+	if stderr != "find: '"+missing+"': No such file or directory\n" {
+		t.Fatalf("stderr = %q", stderr)
+	}
+	if base.ExitStatus(err) != 1 {
+		t.Fatalf("exit status = %d (%v), want 1", base.ExitStatus(err), err)
+	}
+}
+
+func TestFindUnreadableDirectoryContinues(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	dir := findTree(t, "locked/x", "open/y")
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0o311); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+	out, stderr, err := runFindInv(t, "", nil, dir, "-name", "y")
+	if out != filepath.Join(dir, "open", "y")+"\n" || stderr != "find: '"+locked+"': Permission denied\n" || base.ExitStatus(err) != 1 {
+		t.Fatalf("stdout %q, stderr %q, err %v", out, stderr, err)
+	}
+}
+
+func TestFindExecBatchFailureSetsExitStatus(t *testing.T) {
+	dir := findTree(t, "a.log", "b.log")
+	out, _, err := runFindInv(t, "", nil, dir, "-type", "f", "-exec", "stat", "-c", "%n", "{}", "+", "-print")
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	// The batch runs once, after the walk, and -exec ... + is always true.
+	if want := a + "\n" + b + "\n" + a + "\n" + b + "\n"; out != want {
+		t.Fatalf("batch output = %q, want %q", out, want)
+	}
+
+	_, stderr, err := runFindInv(t, "", nil, dir, "-type", "f", "-exec", "stat", "missing", "{}", "+")
+	var exitErr findExitError
+	if !errors.As(err, &exitErr) || base.ExitStatus(err) != 1 {
+		t.Fatalf("err = %v, want find exit status 1", err)
+	}
+	if !strings.Contains(stderr, "missing") {
+		t.Fatalf("stderr = %q, want the failing command's message", stderr)
+	}
+}
+
+func TestFindExecParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-exec", "echo", "{}"}, "missing argument to -exec"},
+		{[]string{"-exec", ";"}, "no command given to -exec"},
+		{[]string{"-exec", "echo", "{}", "x", "+"}, "missing argument to -exec"},
+		{[]string{"-exec", "echo", "{}/a", "{}", "+"}, "only one instance of {}"},
+	} {
+		if _, _, err := runFindInv(t, "", nil, append([]string{t.TempDir()}, tt.args...)...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("find %v error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestFindExecdirRunsInParentDirectory(t *testing.T) {
+	dir := findTree(t, "top.log", "sub/inner.log")
+	out, _, err := runFindInv(t, "", nil, dir, "-name", "*.log", "-execdir", "stat", "-c", "%n", "{}", "+")
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if out != "./inner.log\n./top.log\n" {
+		t.Fatalf("output = %q, want ./NAME per directory", out)
+	}
+}
+
+func TestFindOkAsksBeforeRunning(t *testing.T) {
+	dir := findTree(t, "a.log", "b.log")
+	out, stderr, err := runFindInv(t, "y\nn\n", nil, dir, "-name", "*.log", "-ok", "truncate", "-s", "0", "{}", ";", "-print")
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	a := filepath.Join(dir, "a.log")
+	if !strings.Contains(stderr, "< truncate -s 0 "+a+" > ? ") {
+		t.Fatalf("prompt = %q", stderr)
+	}
+	if out != a+"\n" {
+		t.Fatalf("output = %q, want only the confirmed file", out)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "b.log")); info.Size() == 0 {
+		t.Fatal("declined file was truncated")
+	}
+}
+
+func TestFindDeleteIsDepthFirst(t *testing.T) {
+	dir := findTree(t, "keep.txt", "old/a.gz", "old/deeper/b.gz", "mixed/c.gz", "mixed/d.txt")
+	out, stderr, err := runFindInv(t, "", nil, dir, "-mindepth", "1", "(", "-name", "*.gz", "-o", "-type", "d", ")", "-delete", "-print")
+	var exitErr findExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("err = %v, want find exit error for the non-empty directory", err)
+	}
+	if !strings.Contains(stderr, "cannot delete "+filepath.Join(dir, "mixed")) {
+		t.Fatalf("stderr = %q, want a message for mixed", stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 || lines[0] != filepath.Join(dir, "mixed", "c.gz") {
+		t.Fatalf("output = %q, want contents before their directory", out)
+	}
+	for _, gone := range []string{"old", "mixed/c.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, gone)); !os.IsNotExist(err) {
+			t.Fatalf("%s still exists", gone)
+		}
+	}
+	for _, kept := range []string{"keep.txt", "mixed/d.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, kept)); err != nil {
+			t.Fatalf("%s was removed: %v", kept, err)
+		}
+	}
+}
+
+func TestFindDeleteRefusedInReadOnlyMode(t *testing.T) {
+	dir := findTree(t, "a.log")
+	_, _, err := runFindInv(t, "", []string{base.ReadOnlyEnv + "=1"}, dir, "-delete")
+	var roErr base.ReadOnlyError
+	if !errors.As(err, &roErr) {
+		t.Fatalf("err = %v, want ReadOnlyError", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.log")); err != nil {
+		t.Fatalf("file removed in read-only mode: %v", err)
+	}
+}
+
+func TestFindExecExternalRefusedInReadOnlyMode(t *testing.T) {
+	dir := findTree(t, "a.log")
+	ro := []string{base.ReadOnlyEnv + "=1"}
+	_, _, err := runFindInv(t, "", ro, dir, "-exec", "rm", "{}", ";")
+	var roErr base.ReadOnlyError
+	if !errors.As(err, &roErr) || roErr.Op != "find -exec rm" {
+		t.Fatalf("err = %v, want ReadOnlyError for find -exec rm", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.log")); err != nil {
+		t.Fatalf("file removed in read-only mode: %v", err)
+	}
+	if out, stderr, err := runFindInv(t, "", ro, dir, "-name", "a.log", "-exec", "gobox", "find", "{}", ";"); err != nil || out != filepath.Join(dir, "a.log")+"\n" {
+		t.Fatalf("expected gobox commands to run in read-only mode, got %q (%v, %s)", out, err, stderr)
+	}
+}
+
+func TestFindPrint0AndFprint(t *testing.T) {
+	dir := findTree(t, "a b.log", "c.txt")
+	list := filepath.Join(t.TempDir(), "list")
+	out, _, err := runFindInv(t, "", nil, dir, "-name", "*.log", "-print0", ",", "-name", "*.txt", "-fprint", list)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if out != filepath.Join(dir, "a b.log")+"\x00" {
+		t.Fatalf("-print0 output = %q", out)
+	}
+	data, err := os.ReadFile(list)
+	if err != nil || string(data) != filepath.Join(dir, "c.txt")+"\n" {
+		t.Fatalf("-fprint file = %q (%v)", data, err)
+	}
+}
+
+func TestFindPrintfDirectives(t *testing.T) {
+	dir := findTree(t, "sub/f.txt")
+	file := filepath.Join(dir, "sub", "f.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2023, 10, 13, 10, 24, 17, 118321277, time.Local)
+	if err := os.Chtimes(file, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f.txt", filepath.Join(dir, "sub", "ln")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, err := runFindInv(t, "", nil, dir, "-name", "f.txt", "-printf", `%P|%f|%s|%m|%M|%y|%d|%T@|%TY-%Tm-%Td %TT|%-4s|%.3f\n`)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	want := fmt.Sprintf("sub/f.txt|f.txt|5|640|-rw-r-----|f|2|%d.1183212770|2023-10-13 10:24:17.1183212770|5   |f.t\n", mtime.Unix())
+	if out != want {
+		t.Fatalf("-printf = %q, want %q", out, want)
+	}
+
+	out, _, err = runFindInv(t, "", nil, filepath.Join(dir, "sub"), "-name", "ln", "-printf", `%y %Y %l %h\0`)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if want := "l f f.txt " + filepath.Join(dir, "sub") + "\x00"; out != want {
+		t.Fatalf("-printf symlink = %q, want %q", out, want)
+	}
+}
+
+func TestFindPrintfEscapes(t *testing.T) {
+	dir := t.TempDir()
+	for format, want := range map[string]string{
+		`a\tb\n`:    "a\tb\n",
+		`\101\045p`: "A%p",
+		`x\cyz`:     "x",
+		`\q%%`:      `\q%`,
+	} {
+		out, _, err := runFindInv(t, "", nil, dir, "-maxdepth", "0", "-printf", format)
+		if err != nil || out != want {
+			t.Errorf("-printf %q = %q, %v; want %q", format, out, err, want)
+		}
+	}
+	if _, _, err := runFindInv(t, "", nil, dir, "-printf", "%p%"); err == nil || !strings.Contains(err.Error(), "% at end of format") {
+		t.Fatalf("trailing %% error = %v", err)
+	}
+	for _, format := range []string{"%Q", "%Z", "%x", "%TQ", "%A"} {
+		if _, _, err := runFindInv(t, "", nil, dir, "-printf", format); err == nil || !strings.Contains(err.Error(), "unknown directive") {
+			t.Errorf("-printf %q error = %v; want unknown directive", format, err)
+		}
+	}
+}
+
+func TestFindPrintfFlags(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := runFindInv(t, "", nil, dir, "-maxdepth", "0", "-printf", "%+5d|%#m|%05m|%-6m|%05d|%-3y|\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "   +0|0755|00755|755   |00000|d  |\n"; out != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+func TestFindNamePredicates(t *testing.T) {
+	dir := findTree(t, "Src/Main.GO", "src/util.go", "README")
+	if err := os.Symlink("README", filepath.Join(dir, "ln")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-iname", "*.go"}, "Src/Main.GO src/util.go"},
+		{[]string{"-ipath", "*/src/*"}, "Src/Main.GO src/util.go"},
+		{[]string{"-type", "l"}, "ln"},
+		{[]string{"-type", "l,d", "-mindepth", "1"}, "Src ln src"},
+		{[]string{"-regex", `.*/\(Main\|util\)\.go`}, "src/util.go"},
+		{[]string{"-iregex", `.*/[a-z]+\.go`}, "Src/Main.GO src/util.go"},
+		{[]string{"-regextype", "posix-extended", "-regex", `.*/(README|ln)`}, "README ln"},
+		{[]string{"-regextype", "posix-basic", "-regex", `.*/s\{0,1\}rc`}, "src"},
+	} {
+		if got := strings.Join(findRelLines(t, dir, tc.args...), " "); got != tc.want {
+			t.Errorf("find %v = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestTranslateFindRegex(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		emacs bool
+		want  string
+	}{
+		{`a\(b\|c\)+`, true, `a(b|c)+`},
+		{`(x){2}`, true, `\(x\)\{2\}`},
+		{`a\{2\}b+`, false, `a{2}b\+`},
+		{`[\]x]\<`, false, `[\\]x]\b`},
+	} {
+		if got := translateFindRegex(tc.in, tc.emacs); got != tc.want {
+			t.Errorf("translateFindRegex(%q, %v) = %q, want %q", tc.in, tc.emacs, got, tc.want)
+		}
+	}
+}
+
+func TestParseFindMode(t *testing.T) {
+	for in, want := range map[string]uint32{
+		"644":       0o644,
+		"4755":      0o4755,
+		"u=rw,g=r":  0o640,
+		"u+x":       0o100,
+		"a+r,go-r":  0o444 &^ 0o044,
+		"ug+s":      0o6000,
+		"o+t,u=rwx": 0o1700,
+	} {
+		if got, err := parseFindMode(in); err != nil || got != want {
+			t.Errorf("parseFindMode(%q) = %o, %v; want %o", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "9", "17777", "u", "u*x", "z+r"} {
+		if _, err := parseFindMode(in); err == nil {
+			t.Errorf("parseFindMode(%q) succeeded", in)
+		}
+	}
+}
+
+func TestFindPermAndOwnership(t *testing.T) {
+	dir := findTree(t, "a", "b", "c")
+	for name, mode := range map[string]os.FileMode{"a": 0o644, "b": 0o755, "c": 0o600 | os.ModeSetuid} {
+		if err := os.Chmod(filepath.Join(dir, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uid := fmt.Sprint(os.Getuid())
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-type", "f", "-perm", "644"}, "a"},
+		{[]string{"-type", "f", "-perm", "-u+x"}, "b"},
+		{[]string{"-type", "f", "-perm", "/o=r"}, "a b"},
+		{[]string{"-perm", "-4000"}, "c"},
+		{[]string{"-type", "f", "-uid", uid}, "a b c"},
+		{[]string{"-type", "f", "-user", uid, "-links", "1"}, "a b c"},
+		{[]string{"-type", "f", "-uid", "+" + uid}, ""},
+	} {
+		if got := strings.Join(findRelLines(t, dir, tc.args...), " "); got != tc.want {
+			t.Errorf("find %v = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+	if _, err := runFindCmd(t, []string{dir, "-user", "no-such-user-gobox"}); err == nil || !strings.Contains(err.Error(), "is not the name of a known user") {
+		t.Fatalf("-user unknown error = %v", err)
+	}
+}
+
+func TestFindNewerAndSameFile(t *testing.T) {
+	dir := findTree(t, "old", "ref", "new")
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	for i, name := range []string{"old", "ref", "new"} {
+		mtime := base.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "ref"), filepath.Join(dir, "hard")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-type", "f", "-newer", filepath.Join(dir, "ref")}, "new"},
+		{[]string{"-type", "f", "-newermt", "2024-01-02 03:30"}, "hard new ref"},
+		{[]string{"-type", "f", "-newermt", fmt.Sprintf("@%d", base.Add(90*time.Minute).Unix())}, "new"},
+		{[]string{"-samefile", filepath.Join(dir, "ref")}, "hard ref"},
+		{[]string{"-type", "f", "-links", "+1"}, "hard ref"},
+		{[]string{"-type", "f", "-mmin", "-5"}, ""},
+	} {
+		if got := strings.Join(findRelLines(t, dir, tc.args...), " "); got != tc.want {
+			t.Errorf("find %v = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+	if _, err := runFindCmd(t, []string{dir, "-newermt", "yesterday-ish"}); err == nil {
+		t.Fatal("-newermt accepted an unparsable date")
+	}
+}
+
+func TestFindPrune(t *testing.T) {
+	dir := findTree(t, ".git/config", "src/.git/HEAD", "src/main.go")
+	got := strings.Join(findRelLines(t, dir, "-name", ".git", "-prune", "-o", "-type", "f", "-print"), " ")
+	if got != "src/main.go" {
+		t.Fatalf("-prune = %q", got)
+	}
+}
+
+func TestFindFollowSymlinks(t *testing.T) {
+	dir := findTree(t, "real/f", "top/")
+	if err := os.Symlink("../real", filepath.Join(dir, "top", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(dir, "real", "up")); err != nil {
+		t.Fatal(err)
+	}
+	top := filepath.Join(dir, "top")
+
+	if got := strings.Join(findRelLines(t, top, "-type", "f"), " "); got != "" {
+		t.Fatalf("-P followed a link: %q", got)
+	}
+	if got := strings.Join(findRelLines(t, filepath.Join(top, "link"), "-type", "f"), " "); got != "" {
+		t.Fatalf("-P followed a starting point link: %q", got)
+	}
+	out, _ := runFindCmd(t, []string{"-H", filepath.Join(top, "link"), "-type", "f"})
+	if want := filepath.Join(top, "link", "f") + "\n"; out != want {
+		t.Fatalf("-H = %q, want %q", out, want)
+	}
+
+	// real/up points back at dir, so -L meets top/link/up/real and
+	// top/link/up/top again: loops, reported and skipped.
+	out, errOut, err := runFindInv(t, "", nil, "-L", top, "-name", "f")
+	if want := filepath.Join(top, "link", "f") + "\n"; out != want {
+		t.Fatalf("-L = %q, want %q", out, want)
+	}
+	if !strings.Contains(errOut, "File system loop detected") || base.ExitStatus(err) != 1 {
+		t.Fatalf("-L loop: stderr %q, err %v", errOut, err)
+	}
+}
+
+func TestFindPredicateParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"-type", "x"},
+		{"-type", "f,"},
+		{"-perm", "u*x"},
+		{"-regex", "["},
+		{"-regextype", "perl"},
+		{"-links", "many"},
+		{"-mmin", "5m"},
+		{"-newer", filepath.Join(dir, "missing")},
+	} {
+		if _, err := runFindCmd(t, append([]string{dir}, args...)); err == nil {
+			t.Errorf("find %v succeeded", args)
+		}
+	}
+}
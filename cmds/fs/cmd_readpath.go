@@ -3,6 +3,7 @@ package fs
 import (
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"os"
 	"path/filepath"
@@ -15,6 +16,10 @@ func (readpathExitError) ExitCode() int          { return 1 }
 func (readpathExitError) SuppressCLIError() bool { return true }
 
 func ReadpathCmd(args []string) error {
+	return readpathCmd(base.Stdio(), args)
+}
+
+func readpathCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("readpath", flag.ContinueOnError)
 	canonicalize := fsFlags.Bool("f", false, "canonicalize by following symlinks")
 	fsFlags.BoolVar(canonicalize, "canonicalize", false, "canonicalize by following symlinks")
@@ -31,20 +36,20 @@ func ReadpathCmd(args []string) error {
 	zero := fsFlags.Bool("z", false, "end each output line with NUL")
 	fsFlags.BoolVar(zero, "zero", false, "end each output line with NUL")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox readpath [OPTION]... FILE...")
-		fmt.Fprintln(os.Stderr, "Resolve or inspect pathnames.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Modes:")
-		fmt.Fprintln(os.Stderr, "  -f, --canonicalize             canonicalize by following symlinks")
-		fmt.Fprintln(os.Stderr, "  -e, --canonicalize-existing    require all path components to exist")
-		fmt.Fprintln(os.Stderr, "  -m, --canonicalize-missing     allow missing path components")
-		fmt.Fprintln(os.Stderr, "  -l, --readlink                 print symlink target instead of canonical path")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Output:")
-		fmt.Fprintln(os.Stderr, "  -n, --no-newline               do not print trailing newline")
-		fmt.Fprintln(os.Stderr, "  -z, --zero                     terminate each output with NUL")
-		fmt.Fprintln(os.Stderr, "  -q, --quiet                    suppress most error messages")
-		fmt.Fprintln(os.Stderr, "  -h, --help                     show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox readpath [OPTION]... FILE...")
+		fmt.Fprintln(inv.Stderr, "Resolve or inspect pathnames.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Modes:")
+		fmt.Fprintln(inv.Stderr, "  -f, --canonicalize             canonicalize by following symlinks")
+		fmt.Fprintln(inv.Stderr, "  -e, --canonicalize-existing    require all path components to exist")
+		fmt.Fprintln(inv.Stderr, "  -m, --canonicalize-missing     allow missing path components")
+		fmt.Fprintln(inv.Stderr, "  -l, --readlink                 print symlink target instead of canonical path")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Output:")
+		fmt.Fprintln(inv.Stderr, "  -n, --no-newline               do not print trailing newline")
+		fmt.Fprintln(inv.Stderr, "  -z, --zero                     terminate each output with NUL")
+		fmt.Fprintln(inv.Stderr, "  -q, --quiet                    suppress most error messages")
+		fmt.Fprintln(inv.Stderr, "  -h, --help                     show this help")
 	}
 	if err := utils.ParseFlagSetPermute(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -62,7 +67,7 @@ func ReadpathCmd(args []string) error {
 	}
 	var hadErr bool
 	for _, p := range paths {
-		out, err := resolveReadpath(p, *readlinkMode, *canonicalize, *mustExist, *allowMissing)
+		out, err := resolveReadpath(inv.Path(p), *readlinkMode, *canonicalize, *mustExist, *allowMissing)
 		if err != nil {
 			hadErr = true
 			// GNU readlink is silent by default on failure (it only reports
@@ -71,13 +76,13 @@ func ReadpathCmd(args []string) error {
 			// selects readlink semantics, suppress the message there even
 			// without -q to match native readlink's default silence.
 			if !*quiet && !*readlinkMode {
-				fmt.Fprintf(os.Stderr, "readpath: %s: %v\n", p, err)
+				fmt.Fprintf(inv.Stderr, "readpath: %s: %v\n", p, err)
 			}
 			continue
 		}
-		fmt.Fprint(os.Stdout, out)
+		fmt.Fprint(inv.Stdout, out)
 		if !*noNewline || *zero || len(paths) > 1 {
-			fmt.Fprint(os.Stdout, sep)
+			fmt.Fprint(inv.Stdout, sep)
 		}
 	}
 	if hadErr {
@@ -3,7 +3,9 @@ package fs
 import (
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"io"
 	"os"
 	"os/user"
 	"runtime"
@@ -11,6 +13,7 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 var statFSTypeNames = map[int64]string{
@@ -28,6 +31,10 @@ var statFSTypeNames = map[int64]string{
 }
 
 func StatCmd(args []string) error {
+	return statCmd(base.Stdio(), args)
+}
+
+func statCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("stat", flag.ContinueOnError)
 	deref := fsFlags.Bool("L", false, "follow links")
 	fsFlags.BoolVar(deref, "dereference", false, "follow links")
@@ -38,34 +45,34 @@ func StatCmd(args []string) error {
 	terse := fsFlags.Bool("t", false, "terse output")
 	fsFlags.BoolVar(terse, "terse", false, "terse output")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox stat [OPTION]... FILE...")
-		fmt.Fprintln(os.Stderr, "Display file or filesystem status.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -L, --dereference    follow links")
-		fmt.Fprintln(os.Stderr, "  -f, --file-system    display filesystem status")
-		fmt.Fprintln(os.Stderr, "  -c, --format FORMAT  use custom format string (see directives below)")
-		fmt.Fprintln(os.Stderr, "  -t, --terse          terse output")
-		fmt.Fprintln(os.Stderr, "  -h, --help           show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Format directives:")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %n  filename              %N  quoted name (+ link target)")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %s  size in bytes")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %f  raw mode (hex)        %F  file type")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %u  user ID               %g  group ID")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %U  user name             %G  group name")
-		fmt.Fprintln(os.Stderr, "  %a  access rights (octal) %A  access rights (human-readable)")
-		fmt.Fprintln(os.Stderr, "  %i  inode number          %h  number of hard links")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %d  device number (dec)   %D  device number (hex)")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %o  I/O block size        %b  number of blocks")
-		fmt.Fprintf(os.Stderr, "%s\n", "  %X  last access (epoch)   %x  last access (readable)")
-		fmt.Fprintln(os.Stderr, "  %Y  last modify (epoch)   %y  last modify (readable)")
-		fmt.Fprintln(os.Stderr, "  %Z  last change (epoch)   %z  last change (readable)")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox stat file.txt")
-		fmt.Fprintln(os.Stderr, "  gobox stat -f /tmp")
-		fmt.Fprintf(os.Stderr, "%s\n", "  gobox stat -c '%n %s %y' file.txt")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox stat [OPTION]... FILE...")
+		fmt.Fprintln(inv.Stderr, "Display file or filesystem status.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -L, --dereference    follow links")
+		fmt.Fprintln(inv.Stderr, "  -f, --file-system    display filesystem status")
+		fmt.Fprintln(inv.Stderr, "  -c, --format FORMAT  use custom format string (see directives below)")
+		fmt.Fprintln(inv.Stderr, "  -t, --terse          terse output")
+		fmt.Fprintln(inv.Stderr, "  -h, --help           show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Format directives:")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %n  filename              %N  quoted name (+ link target)")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %s  size in bytes")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %f  raw mode (hex)        %F  file type")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %u  user ID               %g  group ID")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %U  user name             %G  group name")
+		fmt.Fprintln(inv.Stderr, "  %a  access rights (octal) %A  access rights (human-readable)")
+		fmt.Fprintln(inv.Stderr, "  %i  inode number          %h  number of hard links")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %d  device number (dec)   %D  device number (hex)")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %o  I/O block size        %b  number of blocks")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  %X  last access (epoch)   %x  last access (readable)")
+		fmt.Fprintln(inv.Stderr, "  %Y  last modify (epoch)   %y  last modify (readable)")
+		fmt.Fprintln(inv.Stderr, "  %Z  last change (epoch)   %z  last change (readable)")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox stat file.txt")
+		fmt.Fprintln(inv.Stderr, "  gobox stat -f /tmp")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  gobox stat -c '%n %s %y' file.txt")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -79,7 +86,7 @@ func StatCmd(args []string) error {
 	}
 	for _, file := range files {
 		if *fileSystem {
-			if err := printStatFS(file, *format, *terse); err != nil {
+			if err := printStatFS(inv, file, *format, *terse); err != nil {
 				return err
 			}
 			continue
@@ -87,19 +94,19 @@ func StatCmd(args []string) error {
 		var info os.FileInfo
 		var err error
 		if *deref {
-			info, err = os.Stat(file)
+			info, err = os.Stat(inv.Path(file))
 		} else {
-			info, err = os.Lstat(file)
+			info, err = os.Lstat(inv.Path(file))
 		}
 		if err != nil {
 			return err
 		}
 		if *format != "" {
-			fmt.Println(formatStat(*format, file, info))
+			fmt.Fprintln(inv.Stdout, formatStat(*format, file, info))
 		} else if *terse {
-			printStatTerse(file, info)
+			printStatTerse(inv.Stdout, file, info)
 		} else {
-			printStatDefault(file, info)
+			printStatDefault(inv.Stdout, file, info)
 		}
 	}
 	return nil
@@ -108,7 +115,7 @@ func StatCmd(args []string) error {
 // printStatDefault prints file metadata in GNU coreutils' default multi-line
 // stat format: File/Size/Device/Inode/Access/Modify/Change, matching the
 // well-known layout users expect from real `stat FILE`.
-func printStatDefault(file string, info os.FileInfo) {
+func printStatDefault(w io.Writer, file string, info os.FileInfo) {
 	st, _ := info.Sys().(*syscall.Stat_t)
 	var dev, ino, nlink uint64
 	var uid, gid uint32
@@ -123,13 +130,13 @@ func printStatDefault(file string, info os.FileInfo) {
 	perm := permString(info.Mode())
 	fullMode := statFullOctal(info.Mode())
 
-	fmt.Printf("  File: %s\n", file)
-	fmt.Printf("  Size: %-10d\tBlocks: %-10d IO Block: %-6d %s\n", info.Size(), blocks, blksize, fileType(info))
-	fmt.Printf("Device: %xh/%dd\tInode: %-11d Links: %d\n", dev, dev, ino, nlink)
-	fmt.Printf("Access: (%04o/%s)  Uid: (%5d/%8s)   Gid: (%5d/%8s)\n", fullMode, perm, uid, lookupUserName(uid), gid, lookupGroupName(gid))
-	fmt.Printf("Access: %s\n", statTimeString(atim))
-	fmt.Printf("Modify: %s\n", statTimeString(mtim))
-	fmt.Printf("Change: %s\n", statTimeString(ctim))
+	fmt.Fprintf(w, "  File: %s\n", file)
+	fmt.Fprintf(w, "  Size: %-10d\tBlocks: %-10d IO Block: %-6d %s\n", info.Size(), blocks, blksize, fileType(info))
+	fmt.Fprintf(w, "Device: %xh/%dd\tInode: %-11d Links: %d\n", dev, dev, ino, nlink)
+	fmt.Fprintf(w, "Access: (%04o/%s)  Uid: (%5d/%8s)   Gid: (%5d/%8s)\n", fullMode, perm, uid, lookupUserName(uid), gid, lookupGroupName(gid))
+	fmt.Fprintf(w, "Access: %s\n", statTimeString(atim))
+	fmt.Fprintf(w, "Modify: %s\n", statTimeString(mtim))
+	fmt.Fprintf(w, "Change: %s\n", statTimeString(ctim))
 }
 
 // printStatTerse prints file metadata the way GNU coreutils' `stat -t` does:
@@ -139,7 +146,7 @@ func printStatDefault(file string, info os.FileInfo) {
 // labels, no formatted dates). Birthtime is not tracked (see
 // printStatDefault) so it is always reported as 0, matching an unsupported
 // birth time on native stat.
-func printStatTerse(file string, info os.FileInfo) {
+func printStatTerse(w io.Writer, file string, info os.FileInfo) {
 	st, _ := info.Sys().(*syscall.Stat_t)
 	var dev, ino, nlink, rdev uint64
 	var uid, gid, rawMode uint32
@@ -154,7 +161,7 @@ func printStatTerse(file string, info os.FileInfo) {
 		rdev = uint64(st.Rdev)
 	}
 	major, minor := gnuDevMajor(rdev), gnuDevMinor(rdev)
-	fmt.Printf("%s %d %d %x %d %d %x %d %d %x %x %d %d %d %d %d\n",
+	fmt.Fprintf(w, "%s %d %d %x %d %d %x %d %d %x %x %d %d %d %d %d\n",
 		file, info.Size(), blocks, rawMode, uid, gid, dev, ino, nlink,
 		major, minor, atim.Sec, mtim.Sec, ctim.Sec, 0, blksize)
 }
@@ -257,9 +264,19 @@ func lookupGroupName(gid uint32) string {
 	return strconv.FormatUint(uint64(gid), 10)
 }
 
+// statFieldKinds are the numeric stat -c directives, typed as GNU stat
+// prints them: sizes and epoch seconds are signed, %a is octal and %f/%D
+// hexadecimal.
+var statFieldKinds = map[rune]fieldKind{
+	's': fieldSigned, 'X': fieldSigned, 'Y': fieldSigned, 'Z': fieldSigned,
+	'u': fieldUnsigned, 'g': fieldUnsigned, 'i': fieldUnsigned, 'h': fieldUnsigned,
+	'd': fieldUnsigned, 'o': fieldUnsigned, 'b': fieldUnsigned,
+	'a': fieldOctal, 'f': fieldHex, 'D': fieldHex,
+}
+
 // formatStat renders a stat -c/--format FORMAT string against file. It
-// supports the common GNU stat file-mode directives and %% for a literal
-// percent sign; unrecognized directives are left as-is (directive char
+// supports the common GNU stat file-mode directives with optional width and
+// precision; unrecognized directives are left as-is (directive char
 // included) rather than silently dropped.
 func formatStat(format, name string, info os.FileInfo) string {
 	st, _ := info.Sys().(*syscall.Stat_t)
@@ -275,77 +292,190 @@ func formatStat(format, name string, info os.FileInfo) string {
 		rawMode = st.Mode
 	}
 
-	var out strings.Builder
-	runes := []rune(format)
-	for i := 0; i < len(runes); i++ {
-		if runes[i] != '%' || i == len(runes)-1 {
-			out.WriteRune(runes[i])
-			continue
-		}
-		i++
-		switch runes[i] {
-		case '%':
-			out.WriteByte('%')
+	out, _ := formatDirectives(format, statFieldKinds, func(verb rune, _ func() (rune, bool)) (string, bool) {
+		switch verb {
 		case 'n':
-			out.WriteString(name)
+			return name, true
 		case 'N':
 			// Quoted file name; for a symlink (only when not dereferenced,
 			// i.e. info is the link itself) append its target like GNU stat.
 			if info.Mode()&os.ModeSymlink != 0 {
 				if target, lerr := os.Readlink(name); lerr == nil {
-					fmt.Fprintf(&out, "'%s' -> '%s'", name, target)
-					break
+					return fmt.Sprintf("'%s' -> '%s'", name, target), true
 				}
 			}
-			fmt.Fprintf(&out, "'%s'", name)
+			return fmt.Sprintf("'%s'", name), true
 		case 's':
-			fmt.Fprintf(&out, "%d", info.Size())
+			return strconv.FormatInt(info.Size(), 10), true
 		case 'f':
-			fmt.Fprintf(&out, "%x", rawMode)
+			return strconv.FormatUint(uint64(rawMode), 16), true
 		case 'F':
-			out.WriteString(fileType(info))
+			return fileType(info), true
 		case 'u':
-			fmt.Fprintf(&out, "%d", uid)
+			return strconv.FormatUint(uint64(uid), 10), true
 		case 'g':
-			fmt.Fprintf(&out, "%d", gid)
+			return strconv.FormatUint(uint64(gid), 10), true
 		case 'U':
-			out.WriteString(lookupUserName(uid))
+			return lookupUserName(uid), true
 		case 'G':
-			out.WriteString(lookupGroupName(gid))
+			return lookupGroupName(gid), true
 		case 'a':
-			fmt.Fprintf(&out, "%o", info.Mode().Perm())
+			return strconv.FormatUint(uint64(info.Mode().Perm()), 8), true
 		case 'A':
-			out.WriteString(permString(info.Mode()))
+			return permString(info.Mode()), true
 		case 'X':
-			fmt.Fprintf(&out, "%d", atim.Sec)
+			return strconv.FormatInt(atim.Sec, 10), true
 		case 'Y':
-			fmt.Fprintf(&out, "%d", mtim.Sec)
+			return strconv.FormatInt(mtim.Sec, 10), true
 		case 'Z':
-			fmt.Fprintf(&out, "%d", ctim.Sec)
+			return strconv.FormatInt(ctim.Sec, 10), true
 		case 'x':
-			out.WriteString(statTimeString(atim))
+			return statTimeString(atim), true
 		case 'y':
-			out.WriteString(info.ModTime().Format("2006-01-02 15:04:05.000000000 -0700"))
+			return info.ModTime().Format("2006-01-02 15:04:05.000000000 -0700"), true
 		case 'z':
-			out.WriteString(statTimeString(ctim))
+			return statTimeString(ctim), true
 		case 'i':
-			fmt.Fprintf(&out, "%d", ino)
+			return strconv.FormatUint(ino, 10), true
 		case 'h':
-			fmt.Fprintf(&out, "%d", nlink)
+			return strconv.FormatUint(nlink, 10), true
 		case 'd':
-			fmt.Fprintf(&out, "%d", dev)
+			return strconv.FormatUint(dev, 10), true
 		case 'D':
-			fmt.Fprintf(&out, "%x", dev)
+			return strconv.FormatUint(dev, 16), true
 		case 'o':
-			fmt.Fprintf(&out, "%d", blksize)
+			return strconv.FormatInt(blksize, 10), true
 		case 'b':
-			fmt.Fprintf(&out, "%d", blocks)
-		default:
-			out.WriteByte('%')
+			return strconv.FormatInt(blocks, 10), true
+		}
+		return "", false
+	})
+	return out
+}
+
+// fieldKind says how formatDirectives applies printf flags to a directive:
+// the text of a fieldString is only padded and truncated, numeric kinds
+// honour the '0' flag and a precision as a minimum digit count, fieldSigned
+// takes '+' and ' ', and '#' prefixes fieldOctal with "0" and fieldHex
+// with "0x", as in C printf.
+type fieldKind int
+
+const (
+	fieldString fieldKind = iota
+	fieldSigned
+	fieldUnsigned
+	fieldOctal
+	fieldHex
+)
+
+// formatDirectives expands the %-directives of a stat -c or find -printf
+// format. A directive may carry GNU printf-style flags, a field width and a
+// precision ("%-10s", "%05a", "%.3p"); kinds maps the numeric verbs to
+// their fieldKind, and every other verb is a string whose precision
+// truncates. "%%" is a literal percent sign. expand returns the text of one
+// directive, reading the second character of two-character directives such
+// as find's %T@ through next; directives it does not recognize are copied
+// through unchanged. A '%' that ends the format is also kept, and reported
+// as an error for callers that reject it.
+func formatDirectives(format string, kinds map[rune]fieldKind, expand func(verb rune, next func() (rune, bool)) (string, bool)) (string, error) {
+	var out strings.Builder
+	runes := []rune(format)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
 			out.WriteRune(runes[i])
+			continue
+		}
+		start := i
+		i++
+		for i < len(runes) && strings.ContainsRune("-+ #0", runes[i]) {
+			i++
 		}
+		flags := string(runes[start+1 : i])
+		width, precision := 0, -1
+		for ; i < len(runes) && runes[i] >= '0' && runes[i] <= '9'; i++ {
+			width = width*10 + int(runes[i]-'0')
+		}
+		if i < len(runes) && runes[i] == '.' {
+			precision = 0
+			for i++; i < len(runes) && runes[i] >= '0' && runes[i] <= '9'; i++ {
+				precision = precision*10 + int(runes[i]-'0')
+			}
+		}
+		if i >= len(runes) {
+			out.WriteString(string(runes[start:]))
+			return out.String(), fmt.Errorf("%% at end of format string")
+		}
+		if runes[i] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		verb, verbAt := runes[i], i
+		next := func() (rune, bool) {
+			if i+1 >= len(runes) {
+				return 0, false
+			}
+			i++
+			return runes[i], true
+		}
+		value, ok := expand(verb, next)
+		if !ok {
+			i = verbAt
+			out.WriteString(string(runes[start : i+1]))
+			continue
+		}
+		out.WriteString(formatField(value, kinds[verb], flags, width, precision))
+	}
+	return out.String(), nil
+}
+
+// formatField applies the flags, width and precision of one directive to
+// its expanded value.
+func formatField(value string, kind fieldKind, flags string, width, precision int) string {
+	leftAlign := strings.ContainsRune(flags, '-')
+	if kind == fieldString {
+		if precision >= 0 && utf8.RuneCountInString(value) > precision {
+			value = string([]rune(value)[:precision])
+		}
+		return padField(value, "", width, leftAlign, false)
+	}
+
+	sign, digits := "", value
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	} else if kind == fieldSigned && strings.ContainsRune(flags, '+') {
+		sign = "+"
+	} else if kind == fieldSigned && strings.ContainsRune(flags, ' ') {
+		sign = " "
+	}
+	if precision >= 0 && len(digits) < precision {
+		digits = strings.Repeat("0", precision-len(digits)) + digits
+	}
+	if strings.ContainsRune(flags, '#') {
+		switch {
+		case kind == fieldOctal && !strings.HasPrefix(digits, "0"):
+			digits = "0" + digits
+		case kind == fieldHex && strings.Trim(digits, "0") != "":
+			sign += "0x"
+		}
+	}
+	zeroPad := strings.ContainsRune(flags, '0') && !leftAlign && precision < 0
+	return padField(digits, sign, width, leftAlign, zeroPad)
+}
+
+// padField pads prefix+value to width: with spaces on the right when
+// leftAlign, with zeros between prefix and value when zeroPad, and with
+// spaces on the left otherwise.
+func padField(value, prefix string, width int, leftAlign, zeroPad bool) string {
+	pad := width - utf8.RuneCountInString(prefix) - utf8.RuneCountInString(value)
+	switch {
+	case pad <= 0:
+		return prefix + value
+	case leftAlign:
+		return prefix + value + strings.Repeat(" ", pad)
+	case zeroPad:
+		return prefix + strings.Repeat("0", pad) + value
 	}
-	return out.String()
+	return strings.Repeat(" ", pad) + prefix + value
 }
 
 func fileType(info os.FileInfo) string {
@@ -362,12 +492,14 @@ func fileType(info os.FileInfo) string {
 	}
 }
 
-func printStatFS(path, format string, terse bool) error {
+func printStatFS(inv *base.Invocation, path, format string, terse bool) error {
+	w := inv.Stdout
 	if runtime.GOOS != "linux" {
 		return fmt.Errorf("stat -f supported only on Linux")
 	}
+	// Under --proc-root PATH names a file of the inspected system.
 	var st syscall.Statfs_t
-	if err := syscall.Statfs(path, &st); err != nil {
+	if err := syscall.Statfs(inv.Roots().HostPath(inv.Path(path)), &st); err != nil {
 		return err
 	}
 	if format != "" {
@@ -375,17 +507,17 @@ func printStatFS(path, format string, terse bool) error {
 		out = strings.ReplaceAll(out, "%s", fmt.Sprintf("%d", st.Bsize))
 		out = strings.ReplaceAll(out, "%b", fmt.Sprintf("%d", st.Blocks))
 		out = strings.ReplaceAll(out, "%f", fmt.Sprintf("%d", st.Bfree))
-		fmt.Println(out)
+		fmt.Fprintln(w, out)
 		return nil
 	}
 	if terse {
-		fmt.Printf("%s %d %d %d\n", path, st.Bsize, st.Blocks, st.Bfree)
+		fmt.Fprintf(w, "%s %d %d %d\n", path, st.Bsize, st.Blocks, st.Bfree)
 	} else {
-		fmt.Printf("  File: %q\n", path)
-		fmt.Printf("    ID: %-8s Namelen: %-7d Type: %s\n", formatFsid(st.Fsid), st.Namelen, statFSTypeName(st.Type))
-		fmt.Printf("Block size: %-10d Fundamental block size: %d\n", st.Bsize, st.Bsize)
-		fmt.Printf("Blocks: Total: %-10d Free: %-10d Available: %d\n", st.Blocks, st.Bfree, st.Bavail)
-		fmt.Printf("Inodes: Total: %-10d Free: %d\n", st.Files, st.Ffree)
+		fmt.Fprintf(w, "  File: %q\n", path)
+		fmt.Fprintf(w, "    ID: %-8s Namelen: %-7d Type: %s\n", formatFsid(st.Fsid), st.Namelen, statFSTypeName(st.Type))
+		fmt.Fprintf(w, "Block size: %-10d Fundamental block size: %d\n", st.Bsize, st.Bsize)
+		fmt.Fprintf(w, "Blocks: Total: %-10d Free: %-10d Available: %d\n", st.Blocks, st.Bfree, st.Bavail)
+		fmt.Fprintf(w, "Inodes: Total: %-10d Free: %d\n", st.Files, st.Ffree)
 	}
 	return nil
 }
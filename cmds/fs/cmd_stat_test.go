@@ -614,3 +614,75 @@ func TestStatCmdOptionsDanglingSymlinkDereferenceErrors(t *testing.T) {
 	}
 
 }
+
+func TestFormatDirectivesWidthPrecisionAndUnknown(t *testing.T) {
+	kinds := map[rune]fieldKind{'s': fieldSigned, 'a': fieldOctal, 'x': fieldHex}
+	expand := func(verb rune, next func() (rune, bool)) (string, bool) {
+		switch verb {
+		case 'n':
+			return "name", true
+		case 's':
+			return "42", true
+		case 'a':
+			return "755", true
+		case 'x':
+			return "1f", true
+		case 'T':
+			if k, ok := next(); ok && k == '@' {
+				return "12.5", true
+			}
+		}
+		return "", false
+	}
+	tests := []struct{ format, want string }{
+		{"%n|%6n|%-6n|%.2n|%6.2n", "name|  name|name  |na|    na"},
+		{"%T@ %%", "12.5 %"},
+		{"%Q %5q %TX", "%Q %5q %TX"},
+		{"%05s|%+5s|% s|%-5s|%.4s|%05n", "00042|  +42| 42|42   |0042| name"},
+		{"%05a|%#a|%#6a|%-5a|%#x|%#06x", "00755|0755|  0755|755  |0x1f|0x001f"},
+	}
+	for _, tt := range tests {
+		got, err := formatDirectives(tt.format, kinds, expand)
+		if err != nil || got != tt.want {
+			t.Errorf("formatDirectives(%q) = %q, %v; want %q", tt.format, got, err, tt.want)
+		}
+	}
+	if got, err := formatDirectives("50%", kinds, expand); err == nil || got != "50%" {
+		t.Fatalf("trailing %%: got %q, %v; want the literal and an error", got, err)
+	}
+}
+
+func TestStatCmdFormatWidth(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := captureFsCmd(t, func() error {
+		return StatCmd([]string{"-c", "[%6s|%-4s]", file})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "[     5|5   ]\n" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestStatCmdFormatFlags(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := captureFsCmd(t, func() error {
+		return StatCmd([]string{"-c", "%05a|%#a|%+4s|%03h", file})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "00755|0755|  +5|001\n" {
+		t.Fatalf("got %q", out)
+	}
+}
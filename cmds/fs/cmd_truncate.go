@@ -3,6 +3,7 @@ package fs
 import (
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"os"
 	"strconv"
@@ -10,24 +11,28 @@ import (
 )
 
 func TruncateCmd(args []string) error {
+	return truncateCmd(base.Stdio(), args)
+}
+
+func truncateCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("truncate", flag.ContinueOnError)
 	sizeArg := fsFlags.String("s", "", "set or adjust file size")
 	noCreate := fsFlags.Bool("c", false, "do not create files")
 	fsFlags.BoolVar(noCreate, "no-create", false, "do not create files")
 	ref := fsFlags.String("r", "", "use reference file size")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox truncate -s SIZE FILE... | gobox truncate -r RFILE FILE...")
-		fmt.Fprintln(os.Stderr, "Shrink or extend files to a specified size.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -s SIZE             set or adjust file size")
-		fmt.Fprintln(os.Stderr, "  -r RFILE            use reference file size")
-		fmt.Fprintln(os.Stderr, "  -c, --no-create     do not create files")
-		fmt.Fprintln(os.Stderr, "  -h, --help          show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox truncate -s 0 app.log")
-		fmt.Fprintln(os.Stderr, "  gobox truncate -r ref.bin copy.bin")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox truncate -s SIZE FILE... | gobox truncate -r RFILE FILE...")
+		fmt.Fprintln(inv.Stderr, "Shrink or extend files to a specified size.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -s SIZE             set or adjust file size")
+		fmt.Fprintln(inv.Stderr, "  -r RFILE            use reference file size")
+		fmt.Fprintln(inv.Stderr, "  -c, --no-create     do not create files")
+		fmt.Fprintln(inv.Stderr, "  -h, --help          show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox truncate -s 0 app.log")
+		fmt.Fprintln(inv.Stderr, "  gobox truncate -r ref.bin copy.bin")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -41,7 +46,7 @@ func TruncateCmd(args []string) error {
 	}
 	var refSize int64
 	if *ref != "" {
-		info, err := os.Stat(*ref)
+		info, err := os.Stat(inv.Path(*ref))
 		if err != nil {
 			return err
 		}
@@ -50,6 +55,7 @@ func TruncateCmd(args []string) error {
 		return fmt.Errorf("missing -s SIZE or -r RFILE")
 	}
 	for _, file := range files {
+		file = inv.Path(file)
 		targetSize := refSize
 		if *ref == "" {
 			current := int64(0)
@@ -79,14 +85,18 @@ func TruncateCmd(args []string) error {
 				return err
 			}
 		}
+		var err error
 		if !*noCreate {
-			f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0o666)
-			if err != nil {
-				return err
+			var f *os.File
+			if f, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0o666); err == nil {
+				_ = f.Close()
 			}
-			_ = f.Close()
 		}
-		if err := os.Truncate(file, targetSize); err != nil {
+		if err == nil {
+			err = os.Truncate(file, targetSize)
+		}
+		base.Audit(inv, "truncate", file, err, "size", strconv.FormatInt(targetSize, 10))
+		if err != nil {
 			return err
 		}
 	}
@@ -1,6 +1,8 @@
 package fs
 
 import (
+	"encoding/json"
+	"gobox/cmds/base"
 	"os"
 	"path/filepath"
 	"strings"
@@ -227,3 +229,24 @@ func TestTruncateCmdOptionsMissingFileOperandErrors(t *testing.T) {
 	}
 
 }
+
+func TestTruncateCmdWritesAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.log")
+	logPath := filepath.Join(dir, "audit.log")
+	inv := &base.Invocation{Env: []string{base.AuditEnv + "=" + logPath}}
+	if err := truncateCmd(inv, []string{"-s", "3", file}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec base.AuditRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("audit line %q: %v", data, err)
+	}
+	if rec.Action != "truncate" || rec.Target != file || rec.Detail["size"] != "3" || rec.Outcome != "ok" {
+		t.Fatalf("unexpected audit record: %+v", rec)
+	}
+}
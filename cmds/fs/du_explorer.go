@@ -0,0 +1,439 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// duNode is one entry of the tree du --interactive browses. Sizes include
+// everything below the entry, so the explorer can switch between apparent
+// and allocated sizes without rescanning.
+type duNode struct {
+	name      string
+	path      string
+	dir       bool
+	apparent  int64
+	allocated int64
+	items     int // entries below a directory
+	depth     int // below the scan root, as walkDu counts it for -x
+	parent    *duNode
+	children  []*duNode
+}
+
+func (n *duNode) size(apparent bool) int64 {
+	if apparent {
+		return n.apparent
+	}
+	return n.allocated
+}
+
+// buildDuTree assembles walkDu's rows into a tree and returns its root. The
+// rows must come from a walk with every file listed (opts.all) and no depth
+// limit; walkDu emits each directory after its contents, so the last row is
+// the root and a child's totals are complete before they are added to its
+// parent.
+func buildDuTree(rows []duRow, depth int) *duNode {
+	if len(rows) == 0 {
+		return nil
+	}
+	nodes := make(map[string]*duNode, len(rows))
+	for _, row := range rows {
+		nodes[row.path] = &duNode{
+			name:      filepath.Base(row.path),
+			path:      row.path,
+			dir:       row.info.IsDir(),
+			apparent:  duFileSize(row.info, true),
+			allocated: duFileSize(row.info, false),
+		}
+	}
+	root := nodes[rows[len(rows)-1].path]
+	for _, row := range rows[:len(rows)-1] {
+		n, parent := nodes[row.path], nodes[filepath.Dir(row.path)]
+		if parent == nil {
+			continue
+		}
+		n.parent = parent
+		parent.children = append(parent.children, n)
+		parent.apparent += n.apparent
+		parent.allocated += n.allocated
+		parent.items += n.items + 1
+	}
+	setDuDepth(root, depth)
+	return root
+}
+
+func setDuDepth(n *duNode, depth int) {
+	n.depth = depth
+	for _, c := range n.children {
+		setDuDepth(c, depth+1)
+	}
+}
+
+// duExplorer is the state of du --interactive: the scanned tree, the
+// directory being shown and the selection in it. handleKey and render hold
+// all of its behaviour, so it runs the same without a terminal.
+type duExplorer struct {
+	inv     *base.Invocation
+	opts    duOptions
+	rootDev uint64 // device of the scan root, for -x on rescans
+	top     *duNode
+	cwd     *duNode
+	cursor  int
+	offset  int // first entry on screen
+	rows    int // entries that fit on screen at the last render
+	// apparent shows apparent sizes rather than allocated ones.
+	apparent bool
+	help     bool
+	// pending is the entry awaiting a y to be deleted.
+	pending *duNode
+	message string
+}
+
+// newDuExplorer scans root, resolved against the invocation's directory,
+// once and opens the explorer on it. Every file is listed and --max-depth
+// is ignored; -x, --exclude and --apparent-size apply as in the plain
+// listing. root is cleaned so its rows' parents (filepath.Dir) match it.
+func newDuExplorer(inv *base.Invocation, root string, opts duOptions) (*duExplorer, error) {
+	opts.all, opts.maxDepth = true, -1
+	root = filepath.Clean(inv.Path(root))
+	rows, _, err := collectDiskUsage(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	top := buildDuTree(rows, 0)
+	if top == nil {
+		return nil, fmt.Errorf("%s: excluded by --exclude", root)
+	}
+	e := &duExplorer{inv: inv, opts: opts, top: top, cwd: top, apparent: opts.apparentSize, rows: 1}
+	if st, ok := rows[len(rows)-1].info.Sys().(*syscall.Stat_t); ok {
+		e.rootDev = uint64(st.Dev)
+	}
+	return e, nil
+}
+
+// entries lists the current directory, largest first.
+func (e *duExplorer) entries() []*duNode {
+	list := append([]*duNode(nil), e.cwd.children...)
+	sort.SliceStable(list, func(i, j int) bool {
+		si, sj := list[i].size(e.apparent), list[j].size(e.apparent)
+		if si != sj {
+			return si > sj
+		}
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+func (e *duExplorer) selected() *duNode {
+	list := e.entries()
+	if e.cursor < 0 || e.cursor >= len(list) {
+		return nil
+	}
+	return list[e.cursor]
+}
+
+// selectNode moves the cursor onto n when it is listed, after a change
+// that may have reordered the entries.
+func (e *duExplorer) selectNode(n *duNode) {
+	for i, c := range e.entries() {
+		if c == n {
+			e.cursor = i
+			return
+		}
+	}
+}
+
+// handleKey applies one keypress and reports whether to keep running.
+func (e *duExplorer) handleKey(key utils.Key) bool {
+	e.message = ""
+	if n := e.pending; n != nil {
+		e.pending = nil
+		if key == "y" || key == "Y" {
+			e.delete(n)
+		} else {
+			e.message = "Not deleted."
+		}
+		return true
+	}
+	if e.help {
+		e.help = false
+		return key != "q"
+	}
+	sel := e.selected()
+	switch key {
+	case "q":
+		return false
+	case utils.KeyUp, "k":
+		e.cursor--
+	case utils.KeyDown, "j":
+		e.cursor++
+	case utils.KeyPageUp:
+		e.cursor -= e.rows
+	case utils.KeyPageDown:
+		e.cursor += e.rows
+	case utils.KeyHome, "g":
+		e.cursor = 0
+	case utils.KeyEnd, "G":
+		e.cursor = len(e.cwd.children) - 1
+	case utils.KeyRight, utils.KeyEnter, "l":
+		if sel != nil && sel.dir {
+			e.cwd, e.cursor, e.offset = sel, 0, 0
+		}
+	case utils.KeyLeft, utils.KeyBackspace, "h":
+		if e.cwd != e.top {
+			from := e.cwd
+			e.cwd, e.offset = e.cwd.parent, 0
+			e.selectNode(from)
+		}
+	case "a":
+		e.apparent = !e.apparent
+		e.selectNode(sel)
+	case "r":
+		e.rescan(e.cwd)
+		e.selectNode(sel)
+	case "d":
+		if sel == nil {
+			break
+		}
+		if err := base.CheckWritable(e.inv, "du --interactive delete"); err != nil {
+			e.message = err.Error()
+			break
+		}
+		e.pending = sel
+	case "?":
+		e.help = true
+	}
+	if e.cursor >= len(e.cwd.children) {
+		e.cursor = len(e.cwd.children) - 1
+	}
+	if e.cursor < 0 {
+		e.cursor = 0
+	}
+	return true
+}
+
+// rescan walks n again and swaps the fresh subtree in, updating the totals
+// above it. If n is gone, or now excluded, it is dropped from the tree and
+// the view moves to the nearest directory still present.
+func (e *duExplorer) rescan(n *duNode) {
+	var rows []duRow
+	info, err := os.Lstat(n.path)
+	if err == nil {
+		_, err = walkDu(n.path, info, n.depth, e.top.path, e.rootDev, e.opts, &rows)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		e.message = err.Error()
+		return
+	}
+	fresh := buildDuTree(rows, n.depth)
+	if fresh == nil {
+		if n != e.top {
+			e.detach(n)
+		}
+		e.message = fmt.Sprintf("%s: no longer exists", n.path)
+		return
+	}
+	adjustDuTotals(n.parent, fresh.apparent-n.apparent, fresh.allocated-n.allocated, fresh.items-n.items)
+	n.dir, n.apparent, n.allocated, n.items, n.children = fresh.dir, fresh.apparent, fresh.allocated, fresh.items, fresh.children
+	for _, c := range n.children {
+		c.parent = n
+	}
+	e.message = fmt.Sprintf("Rescanned %s.", n.path)
+}
+
+// delete removes n from disk and from the tree. A partial failure leaves
+// whatever could not be removed, so n is rescanned to show it.
+func (e *duExplorer) delete(n *duNode) {
+	err := os.RemoveAll(n.path)
+	base.Audit(e.inv, "delete", n.path, err)
+	if err != nil {
+		e.rescan(n)
+		e.message = fmt.Sprintf("cannot delete %s: %v", n.path, unwrapPathError(err))
+		return
+	}
+	e.detach(n)
+	e.message = fmt.Sprintf("Deleted %s.", n.path)
+}
+
+// detach drops n from its parent, and the view out of n if it was inside.
+func (e *duExplorer) detach(n *duNode) {
+	parent := n.parent
+	for i, c := range parent.children {
+		if c == n {
+			parent.children = append(parent.children[:i], parent.children[i+1:]...)
+			break
+		}
+	}
+	adjustDuTotals(parent, -n.apparent, -n.allocated, -(n.items + 1))
+	for d := e.cwd; d != nil; d = d.parent {
+		if d == n {
+			e.cwd, e.cursor, e.offset = parent, 0, 0
+			break
+		}
+	}
+}
+
+func adjustDuTotals(n *duNode, apparent, allocated int64, items int) {
+	for ; n != nil; n = n.parent {
+		n.apparent += apparent
+		n.allocated += allocated
+		n.items += items
+	}
+}
+
+// duBarWidth is the number of cells in each entry's size bar.
+const duBarWidth = 10
+
+// render draws the whole screen, sized width x height, from the top left.
+func (e *duExplorer) render(w io.Writer, width, height int) {
+	if width < 20 {
+		width = 20
+	}
+	e.rows = height - 3
+	if e.rows < 1 {
+		e.rows = 1
+	}
+	var b strings.Builder
+	b.WriteString("\033[H\033[J")
+	mode := "disk usage"
+	if e.apparent {
+		mode = "apparent size"
+	}
+	b.WriteString("\033[7m" + duFitLine(fmt.Sprintf(" gobox du --interactive  %s  ? for keys", mode), width) + "\033[0m\n")
+	b.WriteString(duFitLine("--- "+e.cwd.path+" ", width) + "\n")
+
+	var lines []string
+	if e.help {
+		lines = duExplorerHelp
+	} else {
+		list := e.entries()
+		if e.cursor < e.offset {
+			e.offset = e.cursor
+		}
+		if e.cursor >= e.offset+e.rows {
+			e.offset = e.cursor - e.rows + 1
+		}
+		if len(list) == 0 {
+			lines = []string{"  (empty)"}
+		}
+		total := e.cwd.size(e.apparent)
+		for i := e.offset; i < len(list) && i < e.offset+e.rows; i++ {
+			line := duFitLine(e.formatEntry(list[i], total), width)
+			if i == e.cursor {
+				line = "\033[7m" + line + "\033[0m"
+			}
+			lines = append(lines, line)
+		}
+	}
+	for i := 0; i < e.rows; i++ {
+		if i < len(lines) {
+			b.WriteString(lines[i])
+		}
+		b.WriteString("\n")
+	}
+
+	footer := fmt.Sprintf(" Total disk usage: %s  Apparent size: %s  Items: %d",
+		utils.HumanSize(e.cwd.allocated), utils.HumanSize(e.cwd.apparent), e.cwd.items)
+	if e.pending != nil {
+		footer = fmt.Sprintf(" Delete %s? This cannot be undone. [y/N]", e.pending.path)
+	} else if e.message != "" {
+		footer = " " + e.message
+	}
+	b.WriteString("\033[7m" + duFitLine(footer, width) + "\033[0m")
+	io.WriteString(w, b.String())
+}
+
+// formatEntry is one listing line: size, share of the directory shown,
+// bar, number of entries below and name.
+func (e *duExplorer) formatEntry(n *duNode, total int64) string {
+	size := n.size(e.apparent)
+	share := 0.0
+	if total > 0 {
+		share = float64(size) / float64(total)
+	}
+	bar := strings.Repeat("#", int(share*duBarWidth+0.5))
+	items, name := "", n.name
+	if n.dir {
+		items, name = fmt.Sprint(n.items), name+"/"
+	}
+	return fmt.Sprintf("%9s %5.1f%% [%-*s] %7s  %s", utils.HumanSize(size), share*100, duBarWidth, bar, items, name)
+}
+
+// duFitLine cuts s to width columns, counting runes.
+func duFitLine(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
+
+var duExplorerHelp = []string{
+	"  up/down, k/j         move the selection (PgUp/PgDn, Home/End, g/G)",
+	"  right, enter, l      open the selected directory",
+	"  left, backspace, h   back to the parent directory",
+	"  a                    toggle apparent size / disk usage",
+	"  r                    rescan the directory shown",
+	"  d                    delete the selected entry (asks first)",
+	"  q                    quit",
+	"",
+	"  Press any key to return.",
+}
+
+// runDuExplorer scans root and runs the explorer on the terminal until q
+// or an interrupt, redrawing when the terminal is resized.
+func runDuExplorer(inv *base.Invocation, root string, opts duOptions) error {
+	f, ok := inv.Stdin.(*os.File)
+	if !ok || !utils.IsTerminal(f) {
+		return fmt.Errorf("--interactive requires a terminal on stdin")
+	}
+	fmt.Fprintf(inv.Stdout, "Scanning %s...\n", root)
+	e, err := newDuExplorer(inv, root, opts)
+	if err != nil {
+		return err
+	}
+	fd := int(f.Fd())
+	state, err := utils.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer utils.RestoreTerminal(fd, state)
+	fmt.Fprint(inv.Stdout, "\033[?25l")
+	defer fmt.Fprint(inv.Stdout, "\033[H\033[J\033[?25h")
+
+	// mu keeps a resize redraw from interleaving with a keypress.
+	var mu sync.Mutex
+	draw := func() {
+		width, height, ok := utils.StdoutSize(inv.Stdout, inv.Getenv)
+		if !ok {
+			width, height = 80, 24
+		}
+		e.render(inv.Stdout, width, height)
+	}
+	draw()
+	stopResize := utils.OnResize(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		draw()
+	})
+	defer stopResize()
+	utils.ReadKeys(fd, inv.Ctx().Done(), func(key utils.Key) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if !e.handleKey(key) {
+			return false
+		}
+		draw()
+		return true
+	})
+	return nil
+}
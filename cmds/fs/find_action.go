@@ -0,0 +1,278 @@
+package fs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gobox/cmds/base"
+)
+
+// findExitError carries find's exit status after a walk in which an action
+// failed; the failures themselves were already reported on stderr.
+type findExitError int
+
+func (e findExitError) Error() string { return fmt.Sprintf("exit code %d", int(e)) }
+func (e findExitError) ExitCode() int { return int(e) }
+
+// SuppressCLIError keeps main from printing "find: exit code 1" after the
+// per-file messages, matching GNU find.
+func (e findExitError) SuppressCLIError() bool { return true }
+
+// findRun is the state shared by every file of one find invocation.
+type findRun struct {
+	inv *base.Invocation
+	// failed records that an action failed; find exits 1 after the walk.
+	failed bool
+	// answers reads -ok replies from stdin.
+	answers *bufio.Reader
+}
+
+// warn reports a failed action and marks the run as failed.
+func (r *findRun) warn(format string, args ...any) {
+	fmt.Fprintf(r.inv.Stderr, "find: "+format+"\n", args...)
+	r.failed = true
+}
+
+// findExecBatchBytes bounds the argument bytes one -exec ... + invocation
+// receives, well under the kernel's ARG_MAX.
+const findExecBatchBytes = 128 * 1024
+
+// findExec is -exec, -execdir, -ok or -okdir. The ";" form runs argv once per
+// file with every "{}" replaced by the path and is true when the command
+// exits 0. The "+" form collects paths and runs argv with them appended in
+// batches; it is always true, and a failing batch makes find exit 1.
+type findExec struct {
+	name   string
+	argv   []string // command template; for "+" without the trailing "{}"
+	inDir  bool     // -execdir/-okdir: run in the file's directory on ./NAME
+	prompt bool     // -ok/-okdir: ask on stderr first
+	batch  bool
+
+	pending    []string
+	pendingDir string
+	pendingLen int
+}
+
+// parseFindExec consumes the command of an -exec-style primary up to its
+// terminator: ";" always, or "+" right after a "{}".
+func parseFindExec(p *findParser, name string) (*findExec, error) {
+	e := &findExec{name: name, inDir: strings.HasSuffix(name, "dir"), prompt: strings.HasPrefix(name, "-ok")}
+	for ; p.pos < len(p.tokens); p.pos++ {
+		tok := p.tokens[p.pos]
+		if tok == ";" || (tok == "+" && !e.prompt && len(e.argv) > 0 && e.argv[len(e.argv)-1] == "{}") {
+			p.pos++
+			if len(e.argv) == 0 {
+				return nil, fmt.Errorf("no command given to %s", name)
+			}
+			if tok == "+" {
+				e.batch = true
+				e.argv = e.argv[:len(e.argv)-1]
+				for _, arg := range e.argv {
+					if strings.Contains(arg, "{}") {
+						return nil, fmt.Errorf("only one instance of {} is supported with %s ... +", name)
+					}
+				}
+				p.opts.batches = append(p.opts.batches, e)
+			}
+			if !findRunsInProcess(e.argv) {
+				p.opts.writes = append(p.opts.writes, "find "+name+" "+e.argv[0])
+			}
+			p.hasAction = true
+			return e, nil
+		}
+		e.argv = append(e.argv, tok)
+	}
+	return nil, fmt.Errorf("missing argument to %s", name)
+}
+
+// findRunsInProcess reports whether argv names a gobox command, which
+// command runs in-process under the same read-only mode as find.
+func findRunsInProcess(argv []string) bool {
+	name := argv[0]
+	if name == "gobox" && len(argv) > 1 {
+		name = argv[1]
+	}
+	_, ok := base.Lookup(name)
+	return ok
+}
+
+func (e *findExec) String() string {
+	end := ";"
+	if e.batch {
+		end = "{} +"
+	}
+	return e.name + " " + strings.Join(append(append([]string{}, e.argv...), end), " ")
+}
+
+func (e *findExec) eval(c *findContext) bool {
+	target, dir := c.display, ""
+	if e.inDir {
+		target, dir = "./"+filepath.Base(c.path), filepath.Dir(c.path)
+	}
+	if e.batch {
+		if e.inDir && len(e.pending) > 0 && dir != e.pendingDir {
+			e.flush(c.run)
+		}
+		e.pending = append(e.pending, target)
+		e.pendingDir = dir
+		e.pendingLen += len(target) + 1
+		if e.pendingLen >= findExecBatchBytes {
+			e.flush(c.run)
+		}
+		return true
+	}
+	argv := make([]string, len(e.argv))
+	for i, arg := range e.argv {
+		argv[i] = strings.ReplaceAll(arg, "{}", target)
+	}
+	if e.prompt && !c.run.confirm(argv) {
+		return false
+	}
+	return c.run.command(argv, dir, e.prompt) == 0
+}
+
+// flush runs the collected batch, if any.
+func (e *findExec) flush(r *findRun) {
+	if len(e.pending) == 0 {
+		return
+	}
+	argv := append(append([]string{}, e.argv...), e.pending...)
+	if status := r.command(argv, e.pendingDir, false); status != 0 {
+		r.failed = true
+	}
+	e.pending, e.pendingLen = nil, 0
+}
+
+// confirm asks whether to run argv the way GNU -ok does and reads the reply
+// from stdin; anything but an answer starting with y declines.
+func (r *findRun) confirm(argv []string) bool {
+	fmt.Fprintf(r.inv.Stderr, "< %s > ? ", strings.Join(argv, " "))
+	if r.answers == nil {
+		r.answers = bufio.NewReader(r.inv.Stdin)
+	}
+	answer, err := r.answers.ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.TrimSpace(answer)
+	return answer != "" && (answer[0] == 'y' || answer[0] == 'Y')
+}
+
+// command runs argv in dir (the invocation's directory when empty) and
+// returns its exit status. gobox commands, with or without a leading
+// "gobox", run in-process and audit their own changes; anything else is
+// executed and audited here, since find cannot tell what it changes.
+// Commands run by -ok get an empty stdin, since find's stdin carries the
+// answers.
+func (r *findRun) command(argv []string, dir string, prompted bool) int {
+	inv := r.inv
+	if dir == "" {
+		dir = inv.Dir
+	}
+	var stdin io.Reader = inv.Stdin
+	if prompted {
+		stdin = strings.NewReader("")
+	}
+	name, args := argv[0], argv[1:]
+	if name == "gobox" && len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if cmd, ok := base.Lookup(name); ok {
+		err := cmd.Run(&base.Invocation{
+			Context:  inv.Ctx(),
+			Stdin:    stdin,
+			Stdout:   inv.Stdout,
+			Stderr:   inv.Stderr,
+			Env:      inv.Env,
+			Dir:      dir,
+			Config:   inv.Config,
+			ReadOnly: base.ReadOnly(inv),
+		}, args)
+		if base.ReportError(err) {
+			fmt.Fprintf(inv.Stderr, "%s: %v\n", name, err)
+		}
+		return base.ExitStatus(err)
+	}
+	cmd := inv.Exec(inv.Ctx(), argv[0], argv[1:]...)
+	cmd.Stdin = stdin
+	cmd.Dir = dir
+	err := cmd.Run()
+	base.Audit(inv, "exec", argv[0], err, "args", strings.Join(argv[1:], " "), "dir", dir)
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		err = execErr.Err
+	}
+	fmt.Fprintf(inv.Stderr, "find: %s: %v\n", argv[0], err)
+	return 127
+}
+
+// findDelete removes the file or empty directory. -delete turns on
+// depth-first traversal, so a directory's contents are gone by the time it
+// is visited. The starting point "." is never removed.
+var findDelete = findTest{"-delete", "", func(c *findContext) bool {
+	if c.display == "." {
+		return true
+	}
+	err := os.Remove(c.path)
+	base.Audit(c.run.inv, "delete", c.path, err)
+	if err != nil {
+		c.run.warn("cannot delete %s: %v", c.display, unwrapPathError(err))
+		return false
+	}
+	return true
+}}
+
+func unwrapPathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+var findPrint0 = findTest{"-print0", "", func(c *findContext) bool {
+	fmt.Fprint(c.run.inv.Stdout, c.display+"\x00")
+	return true
+}}
+
+// findOutput is the file written by -fprint. It is opened, truncating it,
+// before the walk even when nothing matches, as GNU find does.
+type findOutput struct {
+	name string
+	file *os.File
+	w    *bufio.Writer
+}
+
+func (o *findOutput) open(inv *base.Invocation) error {
+	f, err := os.Create(inv.Path(o.name))
+	base.Audit(inv, "write", inv.Path(o.name), err)
+	if err != nil {
+		return err
+	}
+	o.file, o.w = f, bufio.NewWriter(f)
+	return nil
+}
+
+func (o *findOutput) close() error {
+	if o.file == nil {
+		return nil
+	}
+	err := o.w.Flush()
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
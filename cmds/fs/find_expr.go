@@ -0,0 +1,573 @@
+package fs
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"gobox/cmds/base"
+)
+
+// findExpr is one node of a parsed find expression. eval reports whether the
+// current file satisfies the node; operators evaluate their operands left to
+// right and stop as soon as the result is known, as GNU find does.
+type findExpr interface {
+	eval(c *findContext) bool
+	String() string
+}
+
+// findContext is the file being evaluated during the walk.
+type findContext struct {
+	run     *findRun
+	path    string // filesystem path
+	display string // path as printed, with the root prefix as given
+	root    string // starting point as given
+	entry   fs.DirEntry
+	depth   int
+	// prune is set by -prune to keep the walk out of this directory.
+	prune bool
+
+	info    fs.FileInfo
+	infoErr error
+	statted bool
+}
+
+// stat returns the file's FileInfo, calling Info at most once per file.
+func (c *findContext) stat() (fs.FileInfo, error) {
+	if !c.statted {
+		c.info, c.infoErr = c.entry.Info()
+		c.statted = true
+	}
+	return c.info, c.infoErr
+}
+
+// statT returns the file's raw stat data, or nil when it cannot be read.
+func (c *findContext) statT() *syscall.Stat_t {
+	info, err := c.stat()
+	if err != nil {
+		return nil
+	}
+	st, _ := info.Sys().(*syscall.Stat_t)
+	return st
+}
+
+type findAnd struct{ left, right findExpr }
+
+func (e findAnd) eval(c *findContext) bool { return e.left.eval(c) && e.right.eval(c) }
+func (e findAnd) String() string           { return "(" + e.left.String() + " -a " + e.right.String() + ")" }
+
+type findOr struct{ left, right findExpr }
+
+func (e findOr) eval(c *findContext) bool { return e.left.eval(c) || e.right.eval(c) }
+func (e findOr) String() string           { return "(" + e.left.String() + " -o " + e.right.String() + ")" }
+
+type findNot struct{ expr findExpr }
+
+func (e findNot) eval(c *findContext) bool { return !e.expr.eval(c) }
+func (e findNot) String() string           { return "! " + e.expr.String() }
+
+// findComma evaluates both operands and yields the right one's result.
+type findComma struct{ left, right findExpr }
+
+func (e findComma) eval(c *findContext) bool {
+	e.left.eval(c)
+	return e.right.eval(c)
+}
+func (e findComma) String() string { return "(" + e.left.String() + " , " + e.right.String() + ")" }
+
+// findTest is a leaf of the expression: a test, an action or a global
+// option, spelled as on the command line.
+type findTest struct {
+	name  string
+	arg   string
+	match func(c *findContext) bool
+}
+
+func (e findTest) eval(c *findContext) bool { return e.match(c) }
+
+func (e findTest) String() string {
+	if e.arg == "" {
+		return e.name
+	}
+	return e.name + " " + e.arg
+}
+
+// findOptions holds the global options, which may appear anywhere in the
+// expression but affect the whole walk.
+type findOptions struct {
+	maxdepth int
+	mindepth int
+	// depthFirst visits a directory after its contents (-depth, -delete).
+	depthFirst bool
+	// writes names the state-changing primaries, refused in read-only mode.
+	writes []string
+	// batches are the -exec ... + actions to flush once the walk ends.
+	batches []*findExec
+	// outputs are the -fprint files, opened before the walk.
+	outputs []*findOutput
+	// follow says which symlinks are followed: -P (never), -H or -L.
+	follow findFollow
+	// xdev keeps the walk on each starting point's filesystem.
+	xdev bool
+}
+
+type findFollow int
+
+const (
+	findFollowNever findFollow = iota // -P, the default
+	findFollowRoots                   // -H: starting points only
+	findFollowAll                     // -L and -follow
+)
+
+// findPrimary builds a leaf from its argument; primaries without one get "".
+type findPrimary struct {
+	takesArg bool
+	build    func(p *findParser, arg string) (findExpr, error)
+}
+
+// findPrimaries lists the tests, actions and options find accepts. Actions
+// set p.hasAction so the implicit -print is not added.
+var findPrimaries = map[string]findPrimary{
+	"-name": {true, func(p *findParser, pattern string) (findExpr, error) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q for -name", pattern)
+		}
+		return findTest{"-name", pattern, func(c *findContext) bool {
+			ok, err := filepath.Match(pattern, c.entry.Name())
+			return err == nil && ok
+		}}, nil
+	}},
+	"-path": {true, newFindPathTest("-path", false)},
+	"-iname": {true, func(p *findParser, pattern string) (findExpr, error) {
+		lower := strings.ToLower(pattern)
+		if _, err := filepath.Match(lower, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q for -iname", pattern)
+		}
+		return findTest{"-iname", pattern, func(c *findContext) bool {
+			ok, err := filepath.Match(lower, strings.ToLower(c.entry.Name()))
+			return err == nil && ok
+		}}, nil
+	}},
+	"-ipath":      {true, newFindPathTest("-ipath", true)},
+	"-wholename":  {true, newFindPathTest("-wholename", false)},
+	"-iwholename": {true, newFindPathTest("-iwholename", true)},
+	"-regex": {true, func(p *findParser, pattern string) (findExpr, error) {
+		return newFindRegexTest("-regex", pattern, p.regexType, false)
+	}},
+	"-iregex": {true, func(p *findParser, pattern string) (findExpr, error) {
+		return newFindRegexTest("-iregex", pattern, p.regexType, true)
+	}},
+	"-regextype": {true, func(p *findParser, typ string) (findExpr, error) {
+		if _, ok := findRegexTypes[typ]; !ok {
+			return nil, fmt.Errorf("unknown regular expression type %q", typ)
+		}
+		p.regexType = typ
+		return findTest{"-regextype", typ, func(*findContext) bool { return true }}, nil
+	}},
+	"-type": {true, func(p *findParser, types string) (findExpr, error) {
+		return newFindTypeTest(types)
+	}},
+	"-empty": {false, func(p *findParser, _ string) (findExpr, error) {
+		return findTest{"-empty", "", func(c *findContext) bool {
+			if c.entry.IsDir() {
+				entries, err := os.ReadDir(c.path)
+				return err == nil && len(entries) == 0
+			}
+			info, err := c.stat()
+			return err == nil && info.Size() == 0
+		}}, nil
+	}},
+	"-size": {true, func(p *findParser, spec string) (findExpr, error) {
+		if _, _, err := parseSize(spec); err != nil {
+			return nil, err
+		}
+		return findTest{"-size", spec, func(c *findContext) bool {
+			// Like GNU find, directories are compared by their own size.
+			info, err := c.stat()
+			return err == nil && matchSize(info.Size(), spec)
+		}}, nil
+	}},
+	"-atime": {true, func(p *findParser, spec string) (findExpr, error) {
+		return newFindTimeTest("-atime", "atime", spec)
+	}},
+	"-mtime": {true, func(p *findParser, spec string) (findExpr, error) {
+		return newFindTimeTest("-mtime", "mtime", spec)
+	}},
+	"-ctime": {true, func(p *findParser, spec string) (findExpr, error) {
+		return newFindTimeTest("-ctime", "ctime", spec)
+	}},
+	"-amin": {true, newFindMinTest("-amin", "atime")},
+	"-mmin": {true, newFindMinTest("-mmin", "mtime")},
+	"-cmin": {true, newFindMinTest("-cmin", "ctime")},
+	"-newer": {true, func(p *findParser, name string) (findExpr, error) {
+		info, err := p.reference(name)
+		if err != nil {
+			return nil, err
+		}
+		return newFindNewerTest("-newer", name, info.ModTime())
+	}},
+	"-newermt": {true, func(p *findParser, date string) (findExpr, error) {
+		t, err := parseFindDate(date)
+		if err != nil {
+			return nil, err
+		}
+		return newFindNewerTest("-newermt", date, t)
+	}},
+	"-perm": {true, func(p *findParser, mode string) (findExpr, error) {
+		return newFindPermTest(mode)
+	}},
+	"-user": {true, func(p *findParser, name string) (findExpr, error) {
+		return newFindOwnerTest("-user", name)
+	}},
+	"-group": {true, func(p *findParser, name string) (findExpr, error) {
+		return newFindOwnerTest("-group", name)
+	}},
+	"-uid":     {true, newFindStatNumberTest("-uid", func(st *syscall.Stat_t) uint64 { return uint64(st.Uid) })},
+	"-gid":     {true, newFindStatNumberTest("-gid", func(st *syscall.Stat_t) uint64 { return uint64(st.Gid) })},
+	"-links":   {true, newFindStatNumberTest("-links", func(st *syscall.Stat_t) uint64 { return uint64(st.Nlink) })},
+	"-inum":    {true, newFindStatNumberTest("-inum", func(st *syscall.Stat_t) uint64 { return st.Ino })},
+	"-nouser":  {false, newFindNoOwnerTest("-nouser")},
+	"-nogroup": {false, newFindNoOwnerTest("-nogroup")},
+	"-samefile": {true, func(p *findParser, name string) (findExpr, error) {
+		info, err := p.reference(name)
+		if err != nil {
+			return nil, err
+		}
+		want, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil, fmt.Errorf("-samefile: cannot read inode of %s", name)
+		}
+		return findTest{"-samefile", name, func(c *findContext) bool {
+			st := c.statT()
+			return st != nil && st.Dev == want.Dev && st.Ino == want.Ino
+		}}, nil
+	}},
+	"-readable":   {false, newFindAccessTest("-readable", findAccessRead)},
+	"-writable":   {false, newFindAccessTest("-writable", findAccessWrite)},
+	"-executable": {false, newFindAccessTest("-executable", findAccessExecute)},
+	"-prune": {false, func(p *findParser, _ string) (findExpr, error) {
+		return findTest{"-prune", "", func(c *findContext) bool {
+			c.prune = true
+			return true
+		}}, nil
+	}},
+	"-xdev": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.opts.xdev = true
+		return findTest{"-xdev", "", func(*findContext) bool { return true }}, nil
+	}},
+	"-mount": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.opts.xdev = true
+		return findTest{"-mount", "", func(*findContext) bool { return true }}, nil
+	}},
+	"-follow": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.opts.follow = findFollowAll
+		return findTest{"-follow", "", func(*findContext) bool { return true }}, nil
+	}},
+	"-print": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.hasAction = true
+		return findPrint, nil
+	}},
+	"-printf": {true, func(p *findParser, format string) (findExpr, error) {
+		p.hasAction = true
+		return newFindPrintf(format)
+	}},
+	"-print0": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.hasAction = true
+		return findPrint0, nil
+	}},
+	"-fprint": {true, func(p *findParser, name string) (findExpr, error) {
+		p.hasAction = true
+		p.opts.writes = append(p.opts.writes, "find -fprint")
+		out := &findOutput{name: name}
+		p.opts.outputs = append(p.opts.outputs, out)
+		return findTest{"-fprint", name, func(c *findContext) bool {
+			fmt.Fprintln(out.w, c.display)
+			return true
+		}}, nil
+	}},
+	"-delete": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.hasAction = true
+		p.opts.depthFirst = true
+		p.opts.writes = append(p.opts.writes, "find -delete")
+		return findDelete, nil
+	}},
+	"-exec":    {false, findExecPrimary("-exec")},
+	"-execdir": {false, findExecPrimary("-execdir")},
+	"-ok":      {false, findExecPrimary("-ok")},
+	"-okdir":   {false, findExecPrimary("-okdir")},
+	"-depth": {false, func(p *findParser, _ string) (findExpr, error) {
+		p.opts.depthFirst = true
+		return findTest{"-depth", "", func(*findContext) bool { return true }}, nil
+	}},
+	"-maxdepth": {true, func(p *findParser, arg string) (findExpr, error) {
+		return p.depthOption("-maxdepth", arg, &p.opts.maxdepth)
+	}},
+	"-mindepth": {true, func(p *findParser, arg string) (findExpr, error) {
+		return p.depthOption("-mindepth", arg, &p.opts.mindepth)
+	}},
+}
+
+var findPrint = findTest{"-print", "", func(c *findContext) bool {
+	fmt.Fprintln(c.run.inv.Stdout, c.display)
+	return true
+}}
+
+func findExecPrimary(name string) func(p *findParser, _ string) (findExpr, error) {
+	return func(p *findParser, _ string) (findExpr, error) {
+		return parseFindExec(p, name)
+	}
+}
+
+func newFindTimeTest(name, timeType, spec string) (findExpr, error) {
+	if _, _, err := parseTime(spec); err != nil {
+		return nil, err
+	}
+	return findTest{name, spec, func(c *findContext) bool {
+		info, err := c.stat()
+		return err == nil && matchTime(info, spec, timeType)
+	}}, nil
+}
+
+// findParser turns find's command line into paths, global options and an
+// expression tree. Grammar, loosest binding first:
+//
+//	list    = or { "," or }
+//	or      = and { ("-o" | "-or") and }
+//	and     = unary { ["-a" | "-and"] unary }
+//	unary   = ("!" | "-not") unary | "(" list ")" | primary
+//
+// Unlike GNU find, paths may also follow or interleave with the expression;
+// any word that is not an operator or a primary's argument is a path.
+type findParser struct {
+	inv       *base.Invocation
+	tokens    []string
+	pos       int
+	paths     []string
+	opts      findOptions
+	hasAction bool
+	// regexType is the -regextype in effect for following -regex tests.
+	regexType string
+}
+
+// parseFindArgs parses args into paths, options and an expression. Without
+// an action, matching files are printed, as if the expression were written
+// "( EXPR ) -print"; an empty expression prints everything. Leading -P, -H
+// and -L choose how symlinks are followed; the last one wins. inv resolves
+// the reference files of -newer and -samefile.
+func parseFindArgs(inv *base.Invocation, args []string) (paths []string, opts findOptions, expr findExpr, err error) {
+	p := &findParser{inv: inv, tokens: args, opts: findOptions{maxdepth: -1}, regexType: "emacs"}
+	for ; p.pos < len(args); p.pos++ {
+		switch args[p.pos] {
+		case "-P":
+			p.opts.follow = findFollowNever
+			continue
+		case "-H":
+			p.opts.follow = findFollowRoots
+			continue
+		case "-L":
+			p.opts.follow = findFollowAll
+			continue
+		}
+		break
+	}
+	if p.peek() != "" {
+		if expr, err = p.parseList(); err != nil {
+			return nil, opts, nil, err
+		}
+		if tok := p.peek(); tok != "" {
+			if tok == ")" {
+				return nil, opts, nil, fmt.Errorf("unexpected ')' without matching '('")
+			}
+			return nil, opts, nil, fmt.Errorf("unexpected %q in expression", tok)
+		}
+	}
+	if !p.hasAction {
+		if expr == nil {
+			expr = findPrint
+		} else {
+			expr = findAnd{expr, findPrint}
+		}
+	}
+	return p.paths, p.opts, expr, nil
+}
+
+// peek returns the next expression token, first moving any paths in front of
+// it into p.paths. It returns "" at the end of the arguments.
+func (p *findParser) peek() string {
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		if tok == "--" {
+			p.paths = append(p.paths, p.tokens[p.pos+1:]...)
+			p.pos = len(p.tokens)
+			break
+		}
+		if isFindExprToken(tok) {
+			return tok
+		}
+		p.paths = append(p.paths, tok)
+		p.pos++
+	}
+	return ""
+}
+
+func isFindExprToken(tok string) bool {
+	switch tok {
+	case "(", ")", "!", ",":
+		return true
+	}
+	return len(tok) > 1 && tok[0] == '-'
+}
+
+func (p *findParser) parseList() (findExpr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "," {
+		p.pos++
+		if err := p.expectOperand(","); err != nil {
+			return nil, err
+		}
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = findComma{left, right}
+	}
+	return left, nil
+}
+
+func (p *findParser) parseOr() (findExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok == "-o" || tok == "-or"; tok = p.peek() {
+		p.pos++
+		if err := p.expectOperand(tok); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = findOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *findParser) parseAnd() (findExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch tok := p.peek(); tok {
+		case "", ")", ",", "-o", "-or":
+			return left, nil
+		case "-a", "-and":
+			p.pos++
+			if err := p.expectOperand(tok); err != nil {
+				return nil, err
+			}
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			left = findAnd{left, right}
+		default:
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			left = findAnd{left, right}
+		}
+	}
+}
+
+func (p *findParser) parseUnary() (findExpr, error) {
+	tok := p.peek()
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("missing expression")
+	case "!", "-not":
+		p.pos++
+		if err := p.expectOperand(tok); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return findNot{expr}, nil
+	case "(":
+		p.pos++
+		if p.peek() == ")" {
+			return nil, fmt.Errorf("empty parentheses are not allowed")
+		}
+		expr, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ')' to close '('")
+		}
+		p.pos++
+		return expr, nil
+	case ")":
+		return nil, fmt.Errorf("unexpected ')'")
+	case ",", "-o", "-or", "-a", "-and":
+		return nil, fmt.Errorf("unexpected %s", tok)
+	case "-h", "--help":
+		return nil, flag.ErrHelp
+	}
+	primary, ok := findPrimaries[tok]
+	if !ok {
+		return nil, fmt.Errorf("unknown predicate %q", tok)
+	}
+	p.pos++
+	arg := ""
+	if primary.takesArg {
+		if p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("missing argument to %s", tok)
+		}
+		arg = p.tokens[p.pos]
+		p.pos++
+	}
+	return primary.build(p, arg)
+}
+
+// reference stats the file named by -newer or -samefile, following it
+// unless symlinks are never followed.
+func (p *findParser) reference(name string) (fs.FileInfo, error) {
+	path := p.inv.Path(name)
+	if p.opts.follow == findFollowNever {
+		return os.Lstat(path)
+	}
+	return os.Stat(path)
+}
+
+// expectOperand fails when the operator op is not followed by an expression.
+func (p *findParser) expectOperand(op string) error {
+	switch p.peek() {
+	case "", ")", ",", "-o", "-or", "-a", "-and":
+		return fmt.Errorf("expected an expression after %s", op)
+	}
+	return nil
+}
+
+// depthOption records a -maxdepth/-mindepth value. Options are always true
+// wherever they appear in the expression.
+func (p *findParser) depthOption(name, arg string, dst *int) (findExpr, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid argument %q to %s", arg, name)
+	}
+	*dst = n
+	return findTest{name, arg, func(*findContext) bool { return true }}, nil
+}
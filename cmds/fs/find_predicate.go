@@ -0,0 +1,387 @@
+package fs
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// newFindTypeTest builds -type. types is one letter or a comma-separated
+// list of them: f d l s p c b, as %y prints them.
+func newFindTypeTest(types string) (findExpr, error) {
+	want := map[string]bool{}
+	for _, t := range strings.Split(types, ",") {
+		if len(t) != 1 || !strings.Contains("fdlspcb", t) {
+			return nil, fmt.Errorf("invalid type %q: must be one of f, d, l, s, p, c, b", types)
+		}
+		want[t] = true
+	}
+	return findTest{"-type", types, func(c *findContext) bool {
+		return want[findTypeChar(c.entry.Type())]
+	}}, nil
+}
+
+// newFindPathTest builds -path and its spellings. GNU find matches the
+// pattern as given against the printed path, which keeps the root prefix
+// (so "find . -path ./x" and "-path '*/x/*'" match "./x" and "./x/...").
+func newFindPathTest(name string, fold bool) func(p *findParser, pattern string) (findExpr, error) {
+	return func(p *findParser, pattern string) (findExpr, error) {
+		expr := globToRegex(filepath.ToSlash(pattern))
+		if fold {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q for %s", pattern, name)
+		}
+		return findTest{name, pattern, func(c *findContext) bool {
+			return re.MatchString(filepath.ToSlash(c.display))
+		}}, nil
+	}
+}
+
+// findRegexTypes are the -regextype names; true marks the POSIX extended
+// family, which Go's syntax already covers. The others are basic syntaxes
+// that translateFindRegex rewrites first.
+var findRegexTypes = map[string]bool{
+	"emacs":               false,
+	"findutils-default":   false,
+	"ed":                  false,
+	"grep":                false,
+	"sed":                 false,
+	"posix-basic":         false,
+	"posix-minimal-basic": false,
+	"awk":                 true,
+	"gnu-awk":             true,
+	"posix-awk":           true,
+	"egrep":               true,
+	"posix-egrep":         true,
+	"posix-extended":      true,
+}
+
+// newFindRegexTest builds -regex and -iregex. As in GNU find, the pattern
+// must match the whole printed path, not just part of it.
+func newFindRegexTest(name, pattern, typ string, fold bool) (findExpr, error) {
+	expr := pattern
+	if !findRegexTypes[typ] {
+		expr = translateFindRegex(pattern, typ == "emacs" || typ == "findutils-default")
+	}
+	expr = "^(?:" + expr + ")$"
+	if fold {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q for %s", pattern, name)
+	}
+	return findTest{name, pattern, func(c *findContext) bool {
+		return re.MatchString(c.display)
+	}}, nil
+}
+
+// translateFindRegex rewrites a basic regular expression into Go syntax:
+// \( \) \| group and alternate while bare ( ) | are literal. In POSIX basic
+// syntax \{ \} \+ \? are operators and bare { } + ? literal; in the emacs
+// syntax find uses by default + and ? are operators and braces literal.
+// Bracket expressions are copied with backslashes made literal.
+func translateFindRegex(pattern string, emacs bool) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == '[':
+			j := i + 1
+			if j < len(pattern) && pattern[j] == '^' {
+				j++
+			}
+			if j < len(pattern) && pattern[j] == ']' {
+				j++
+			}
+			for j < len(pattern) && pattern[j] != ']' {
+				if pattern[j] == '[' && j+1 < len(pattern) && strings.IndexByte(":.=", pattern[j+1]) >= 0 {
+					if k := strings.Index(pattern[j+2:], string(pattern[j+1])+"]"); k >= 0 {
+						j += k + 4
+						continue
+					}
+				}
+				j++
+			}
+			if j >= len(pattern) {
+				// Unterminated: leave it for the compiler to reject.
+				b.WriteString(pattern[i:])
+				return b.String()
+			}
+			b.WriteString(strings.ReplaceAll(pattern[i:j+1], `\`, `\\`))
+			i = j
+		case ch == '\\' && i+1 < len(pattern):
+			i++
+			switch next := pattern[i]; {
+			case strings.IndexByte("()|", next) >= 0:
+				b.WriteByte(next)
+			case strings.IndexByte("{}+?", next) >= 0 && !emacs:
+				b.WriteByte(next)
+			case next == '<' || next == '>':
+				b.WriteString(`\b`)
+			case next == '`':
+				b.WriteString(`\A`)
+			case next == '\'':
+				b.WriteString(`\z`)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(next)
+			}
+		case strings.IndexByte("()|{}", ch) >= 0, strings.IndexByte("+?", ch) >= 0 && !emacs:
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// newFindMinTest builds -amin, -mmin and -cmin: -Xtime counted in minutes.
+func newFindMinTest(name, timeType string) func(p *findParser, spec string) (findExpr, error) {
+	return func(p *findParser, spec string) (findExpr, error) {
+		if _, err := strconv.ParseUint(strings.TrimLeft(spec, "+-"), 10, 63); err != nil || strings.HasSuffix(spec, "m") {
+			return nil, fmt.Errorf("invalid argument %q to %s", spec, name)
+		}
+		return findTest{name, spec, func(c *findContext) bool {
+			info, err := c.stat()
+			return err == nil && matchTime(info, spec+"m", timeType)
+		}}, nil
+	}
+}
+
+// newFindNewerTest builds -newer and -newermt: modified strictly after t.
+func newFindNewerTest(name, arg string, t time.Time) (findExpr, error) {
+	return findTest{name, arg, func(c *findContext) bool {
+		info, err := c.stat()
+		return err == nil && info.ModTime().After(t)
+	}}, nil
+}
+
+// findDateLayouts are the -newermt forms accepted besides @EPOCH; dates
+// without a zone are local time.
+var findDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseFindDate(date string) (time.Time, error) {
+	if secs, ok := strings.CutPrefix(date, "@"); ok {
+		if n, err := strconv.ParseInt(secs, 10, 64); err == nil {
+			return time.Unix(n, 0), nil
+		}
+	}
+	for _, layout := range findDateLayouts {
+		if t, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot interpret %q as a date or time", date)
+}
+
+// newFindPermTest builds -perm MODE (exactly these bits), -perm -MODE (all
+// of them) and -perm /MODE (any of them; /0 matches every file). MODE is
+// octal or chmod-style symbolic, applied to 0 without the umask.
+func newFindPermTest(arg string) (findExpr, error) {
+	kind, mode := byte(0), arg
+	if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "/") {
+		kind, mode = arg[0], arg[1:]
+	}
+	want, err := parseFindMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	return findTest{"-perm", arg, func(c *findContext) bool {
+		info, err := c.stat()
+		if err != nil {
+			return false
+		}
+		got := statFullOctal(info.Mode())
+		switch kind {
+		case '-':
+			return got&want == want
+		case '/':
+			return want == 0 || got&want != 0
+		}
+		return got == want
+	}}, nil
+}
+
+func parseFindMode(mode string) (uint32, error) {
+	if mode != "" && strings.Trim(mode, "01234567") == "" {
+		n, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || n > 07777 {
+			return 0, fmt.Errorf("invalid mode %q", mode)
+		}
+		return uint32(n), nil
+	}
+	var bits uint32
+	for _, clause := range strings.Split(mode, ",") {
+		i := 0
+		var who uint32
+		for ; i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0; i++ {
+			switch clause[i] {
+			case 'u':
+				who |= 04700
+			case 'g':
+				who |= 02070
+			case 'o':
+				who |= 01007
+			case 'a':
+				who |= 07777
+			}
+		}
+		if who == 0 {
+			who = 07777
+		}
+		if i == len(clause) {
+			return 0, fmt.Errorf("invalid mode %q", mode)
+		}
+		for i < len(clause) {
+			op := clause[i]
+			if op != '=' && op != '+' && op != '-' {
+				return 0, fmt.Errorf("invalid mode %q", mode)
+			}
+			var perm uint32
+			for i++; i < len(clause) && strings.IndexByte("rwxXst", clause[i]) >= 0; i++ {
+				switch clause[i] {
+				case 'r':
+					perm |= 0444
+				case 'w':
+					perm |= 0222
+				case 'x', 'X':
+					perm |= 0111
+				case 's':
+					perm |= 06000
+				case 't':
+					perm |= 01000
+				}
+			}
+			perm &= who
+			switch op {
+			case '=':
+				bits = bits&^who | perm
+			case '+':
+				bits |= perm
+			case '-':
+				bits &^= perm
+			}
+		}
+	}
+	return bits, nil
+}
+
+// newFindOwnerTest builds -user and -group, which take a name or a numeric
+// ID.
+func newFindOwnerTest(name, owner string) (findExpr, error) {
+	var id string
+	if name == "-user" {
+		if u, err := user.Lookup(owner); err == nil {
+			id = u.Uid
+		}
+	} else if g, err := user.LookupGroup(owner); err == nil {
+		id = g.Gid
+	}
+	if id == "" {
+		id = owner
+	}
+	want, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		kind := strings.TrimPrefix(name, "-")
+		return nil, fmt.Errorf("'%s' is not the name of a known %s", owner, kind)
+	}
+	return findTest{name, owner, func(c *findContext) bool {
+		st := c.statT()
+		if st == nil {
+			return false
+		}
+		if name == "-user" {
+			return uint64(st.Uid) == want
+		}
+		return uint64(st.Gid) == want
+	}}, nil
+}
+
+// newFindNoOwnerTest builds -nouser and -nogroup: the owner ID has no
+// entry in the user or group database. Lookups are cached per ID.
+func newFindNoOwnerTest(name string) func(p *findParser, _ string) (findExpr, error) {
+	return func(p *findParser, _ string) (findExpr, error) {
+		known := map[uint32]bool{}
+		return findTest{name, "", func(c *findContext) bool {
+			st := c.statT()
+			if st == nil {
+				return false
+			}
+			id := st.Uid
+			if name == "-nogroup" {
+				id = st.Gid
+			}
+			ok, cached := known[id]
+			if !cached {
+				var err error
+				if name == "-nouser" {
+					_, err = user.LookupId(strconv.FormatUint(uint64(id), 10))
+				} else {
+					_, err = user.LookupGroupId(strconv.FormatUint(uint64(id), 10))
+				}
+				ok = err == nil
+				known[id] = ok
+			}
+			return !ok
+		}}, nil
+	}
+}
+
+// newFindStatNumberTest builds a test comparing a stat field with N, +N
+// (greater than N) or -N (less than N): -uid, -gid, -links and -inum.
+func newFindStatNumberTest(name string, field func(st *syscall.Stat_t) uint64) func(p *findParser, arg string) (findExpr, error) {
+	return func(p *findParser, arg string) (findExpr, error) {
+		n, err := strconv.ParseUint(strings.TrimLeft(arg, "+-"), 10, 64)
+		if err != nil || len(arg)-len(strings.TrimLeft(arg, "+-")) > 1 {
+			return nil, fmt.Errorf("invalid argument %q to %s", arg, name)
+		}
+		return findTest{name, arg, func(c *findContext) bool {
+			st := c.statT()
+			if st == nil {
+				return false
+			}
+			switch v := field(st); arg[0] {
+			case '+':
+				return v > n
+			case '-':
+				return v < n
+			default:
+				return v == n
+			}
+		}}, nil
+	}
+}
+
+// access(2) modes; package syscall does not export them on every platform.
+const (
+	findAccessExecute = 1
+	findAccessWrite   = 2
+	findAccessRead    = 4
+)
+
+// newFindAccessTest builds -readable, -writable and -executable, which ask
+// the kernel whether the current user may access the file, so ACLs and
+// read-only mounts count.
+func newFindAccessTest(name string, mode uint32) func(p *findParser, _ string) (findExpr, error) {
+	return func(p *findParser, _ string) (findExpr, error) {
+		return findTest{name, "", func(c *findContext) bool {
+			return syscall.Access(c.path, mode) == nil
+		}}, nil
+	}
+}
@@ -0,0 +1,310 @@
+package fs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// newFindPrintf builds -printf FORMAT. Escapes are decoded once here; the
+// %-directives go through formatDirectives, which stat -c shares, for each
+// file. No newline is added. Unknown directives are rejected up front.
+func newFindPrintf(format string) (findExpr, error) {
+	format = decodeFindEscapes(format)
+	var unknown string
+	if _, err := formatDirectives(format, nil, func(verb rune, next func() (rune, bool)) (string, bool) {
+		if !isFindPrintfDirective(verb, next) && unknown == "" {
+			unknown = string(verb)
+			if strings.ContainsRune("ATC", verb) {
+				if k, ok := next(); ok {
+					unknown += string(k)
+				}
+			}
+		}
+		return "", true
+	}); err != nil {
+		return nil, fmt.Errorf("-printf: %v", err)
+	}
+	if unknown != "" {
+		return nil, fmt.Errorf("-printf: unknown directive %%%s", unknown)
+	}
+	return findTest{"-printf", strconv.Quote(format), func(c *findContext) bool {
+		out, _ := formatDirectives(format, findFieldKinds, func(verb rune, next func() (rune, bool)) (string, bool) {
+			return findPrintfDirective(c, verb, next)
+		})
+		fmt.Fprint(c.run.inv.Stdout, out)
+		return true
+	}}, nil
+}
+
+// findFieldKinds are the numeric -printf directives. GNU find prints the
+// other numbers (%s, %i, %U, ...) as strings, so they only take '-' and a
+// width.
+var findFieldKinds = map[rune]fieldKind{'d': fieldSigned, 'm': fieldOctal}
+
+// isFindPrintfDirective reports whether findPrintfDirective knows verb,
+// reading the time letter of %Ak, %Ck and %Tk through next.
+func isFindPrintfDirective(verb rune, next func() (rune, bool)) bool {
+	if strings.ContainsRune("ATC", verb) {
+		k, ok := next()
+		if !ok {
+			return false
+		}
+		_, ok = findTimeField(time.Time{}, k)
+		return ok
+	}
+	return strings.ContainsRune("pPHfhdyYl", verb) || isFindStatDirective(verb)
+}
+
+// decodeFindEscapes interprets the backslash escapes of a -printf format:
+// \a \b \f \n \r \t \v \\, \NNN in octal (\0 is NUL) and \c, which ends the
+// output there. Unknown escapes are kept as written. An escaped '%' stays
+// literal rather than starting a directive.
+func decodeFindEscapes(format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '\\' || i == len(format)-1 {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch ch := format[i]; ch {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\\':
+			b.WriteByte('\\')
+		case 'c':
+			return b.String()
+		default:
+			if ch < '0' || ch > '7' {
+				b.WriteByte('\\')
+				b.WriteByte(ch)
+				continue
+			}
+			n, j := 0, i
+			for ; j < len(format) && j < i+3 && format[j] >= '0' && format[j] <= '7'; j++ {
+				n = n*8 + int(format[j]-'0')
+			}
+			i = j - 1
+			if byte(n) == '%' {
+				b.WriteString("%%")
+			} else {
+				b.WriteByte(byte(n))
+			}
+		}
+	}
+	return b.String()
+}
+
+// findPrintfDirective expands one -printf directive for the current file.
+func findPrintfDirective(c *findContext, verb rune, next func() (rune, bool)) (string, bool) {
+	switch verb {
+	case 'p':
+		return c.display, true
+	case 'P':
+		return strings.TrimPrefix(strings.TrimPrefix(c.display, c.root), string(filepath.Separator)), true
+	case 'H':
+		return c.root, true
+	case 'f':
+		return filepath.Base(c.display), true
+	case 'h':
+		dir := strings.TrimRight(c.display, string(filepath.Separator))
+		if i := strings.LastIndexByte(dir, filepath.Separator); i >= 0 {
+			return dir[:i], true
+		}
+		if dir == "" {
+			return "", true
+		}
+		return ".", true
+	case 'd':
+		return strconv.Itoa(c.depth), true
+	case 'y':
+		return findTypeChar(c.entry.Type()), true
+	case 'Y':
+		if c.entry.Type()&fs.ModeSymlink == 0 {
+			return findTypeChar(c.entry.Type()), true
+		}
+		info, err := os.Stat(c.path)
+		if err != nil {
+			return "N", true
+		}
+		return findTypeChar(info.Mode()), true
+	case 'l':
+		if c.entry.Type()&fs.ModeSymlink == 0 {
+			return "", true
+		}
+		target, _ := os.Readlink(c.path)
+		return target, true
+	}
+
+	info, err := c.stat()
+	if err != nil {
+		return "", isFindStatDirective(verb)
+	}
+	st, _ := info.Sys().(*syscall.Stat_t)
+	if st == nil {
+		st = &syscall.Stat_t{}
+	}
+	switch verb {
+	case 's':
+		return strconv.FormatInt(info.Size(), 10), true
+	case 'k':
+		return strconv.FormatInt((st.Blocks*512+1023)/1024, 10), true
+	case 'b':
+		return strconv.FormatInt(st.Blocks, 10), true
+	case 'm':
+		return strconv.FormatUint(uint64(statFullOctal(info.Mode())), 8), true
+	case 'M':
+		return permString(info.Mode()), true
+	case 'u':
+		return lookupUserName(st.Uid), true
+	case 'g':
+		return lookupGroupName(st.Gid), true
+	case 'U':
+		return strconv.FormatUint(uint64(st.Uid), 10), true
+	case 'G':
+		return strconv.FormatUint(uint64(st.Gid), 10), true
+	case 'i':
+		return strconv.FormatUint(st.Ino, 10), true
+	case 'n':
+		return strconv.FormatUint(uint64(st.Nlink), 10), true
+	case 'D':
+		return strconv.FormatUint(st.Dev, 10), true
+	case 'a':
+		return findCtime(timespecTime(st.Atim)), true
+	case 't':
+		return findCtime(info.ModTime()), true
+	case 'c':
+		return findCtime(timespecTime(st.Ctim)), true
+	case 'A', 'T', 'C':
+		k, ok := next()
+		if !ok {
+			return "", false
+		}
+		t := info.ModTime()
+		if verb == 'A' {
+			t = timespecTime(st.Atim)
+		} else if verb == 'C' {
+			t = timespecTime(st.Ctim)
+		}
+		return findTimeField(t, k)
+	}
+	return "", false
+}
+
+// isFindStatDirective reports whether verb needs the file's metadata; such
+// directives expand to nothing when it cannot be read.
+func isFindStatDirective(verb rune) bool {
+	return strings.ContainsRune("skbmMugUGinDatcATC", verb)
+}
+
+func timespecTime(ts syscall.Timespec) time.Time {
+	return time.Unix(ts.Sec, ts.Nsec)
+}
+
+// findTypeChar is the -type letter for mode, as %y prints it.
+func findTypeChar(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return "l"
+	case mode.IsDir():
+		return "d"
+	case mode&fs.ModeNamedPipe != 0:
+		return "p"
+	case mode&fs.ModeSocket != 0:
+		return "s"
+	case mode&fs.ModeCharDevice != 0:
+		return "c"
+	case mode&fs.ModeDevice != 0:
+		return "b"
+	}
+	return "f"
+}
+
+// findFraction is GNU find's 10-digit fractional-second suffix.
+func findFraction(t time.Time) string {
+	return fmt.Sprintf(".%09d0", t.Nanosecond())
+}
+
+// findCtime formats %a, %c and %t: ctime(3) layout with fractional seconds.
+func findCtime(t time.Time) string {
+	return t.Format("Mon Jan _2 15:04:05") + findFraction(t) + t.Format(" 2006")
+}
+
+// findTimeField expands the strftime-like letter k of %Ak, %Ck and %Tk.
+func findTimeField(t time.Time, k rune) (string, bool) {
+	switch k {
+	case '@':
+		return strconv.FormatInt(t.Unix(), 10) + findFraction(t), true
+	case 's':
+		return strconv.FormatInt(t.Unix(), 10), true
+	case 'S':
+		return t.Format("05") + findFraction(t), true
+	case 'T', 'X':
+		return t.Format("15:04:05") + findFraction(t), true
+	case '+':
+		return t.Format("2006-01-02+15:04:05") + findFraction(t), true
+	case 'c':
+		return t.Format("Mon Jan _2 15:04:05 2006"), true
+	case 'D', 'x':
+		return t.Format("01/02/06"), true
+	case 'Y':
+		return t.Format("2006"), true
+	case 'y':
+		return t.Format("06"), true
+	case 'm':
+		return t.Format("01"), true
+	case 'd':
+		return t.Format("02"), true
+	case 'H':
+		return t.Format("15"), true
+	case 'I':
+		return t.Format("03"), true
+	case 'k':
+		return fmt.Sprintf("%2d", t.Hour()), true
+	case 'l':
+		return fmt.Sprintf("%2d", (t.Hour()+11)%12+1), true
+	case 'M':
+		return t.Format("04"), true
+	case 'p':
+		return t.Format("PM"), true
+	case 'Z':
+		return t.Format("MST"), true
+	case 'z':
+		return t.Format("-0700"), true
+	case 'a':
+		return t.Format("Mon"), true
+	case 'A':
+		return t.Format("Monday"), true
+	case 'b', 'h':
+		return t.Format("Jan"), true
+	case 'B':
+		return t.Format("January"), true
+	case 'j':
+		return fmt.Sprintf("%03d", t.YearDay()), true
+	case 'w':
+		return strconv.Itoa(int(t.Weekday())), true
+	case 'U':
+		return fmt.Sprintf("%02d", (t.YearDay()+6-int(t.Weekday()))/7), true
+	case 'W':
+		return fmt.Sprintf("%02d", (t.YearDay()+6-(int(t.Weekday())+6)%7)/7), true
+	}
+	return "", false
+}
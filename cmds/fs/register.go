@@ -3,10 +3,11 @@ package fs
 import "gobox/cmds/base"
 
 func init() {
-	base.Register(base.NewCommand("find", "Search for files in a directory tree", base.Adapt(FindCmd)))
-	base.Register(base.NewCommand("du", "Show file/directory disk usage", base.Adapt(DuCmd)))
-	base.Register(base.NewCommand("df", "Show filesystem usage", base.Adapt(DfCmd)))
-	base.Register(base.NewCommand("readpath", "Resolve paths and symlinks", base.Adapt(ReadpathCmd)))
-	base.Register(base.NewCommand("stat", "Show file or filesystem status", base.Adapt(StatCmd)))
-	base.Register(base.NewCommand("truncate", "Shrink or extend file size", base.Adapt(TruncateCmd)))
+	base.Register(base.NewCommand("find", "Search for files in a directory tree", findCmd, base.WithFlags(findFlags...)))
+	base.Register(base.NewCommand("du", "Show file/directory disk usage", duCmd, base.WithTabularOutput()))
+	base.Register(base.NewCommand("df", "Show filesystem usage", dfCmd, base.WithTabularOutput()))
+	base.Register(base.NewCommand("readpath", "Resolve paths and symlinks", readpathCmd))
+	base.Register(base.NewCommand("stat", "Show file or filesystem status", statCmd))
+	base.Register(base.NewCommand("truncate", "Shrink or extend file size", truncateCmd, base.WithMutating()))
+	base.RegisterCheckType(base.CheckType{Name: "disk", Help: "filesystem holding path is at most max_use (90%) full; max_inodes", Run: runDiskCheck})
 }
@@ -0,0 +1,179 @@
+package net
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gobox/cmds/base"
+)
+
+// checkHostPort reads "address" (HOST:PORT), or "host" plus "port".
+func checkHostPort(c base.Check, defPort string) (string, error) {
+	if addr := c.Param("address", ""); addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			if defPort == "" {
+				return "", fmt.Errorf("invalid address %q: %v", addr, err)
+			}
+			addr = net.JoinHostPort(addr, defPort)
+		}
+		return addr, nil
+	}
+	host, err := c.RequireParam("host")
+	if err != nil {
+		return "", fmt.Errorf("%s check needs \"address\" or \"host\"", c.Type)
+	}
+	port := c.Param("port", defPort)
+	if port == "" {
+		return "", fmt.Errorf("%s check needs \"port\"", c.Type)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// runTCPCheck dials the address the way np -z probes a port.
+func runTCPCheck(_ *base.Invocation, c base.Check) (string, error) {
+	addr, err := checkHostPort(c, "")
+	if err != nil {
+		return "", err
+	}
+	timeout, err := c.DurationParam("timeout", 3*time.Second)
+	if err != nil {
+		return "", err
+	}
+	started := time.Now()
+	if err := npScanPort(addr, timeout); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s connected in %s", addr, time.Since(started).Round(time.Millisecond)), nil
+}
+
+// runDNSCheck resolves host through the same lookups as nslookup. Without a
+// server it asks the system resolver rather than dig's 8.8.8.8 default, so
+// the check sees what the host's applications see.
+func runDNSCheck(_ *base.Invocation, c base.Check) (string, error) {
+	name, err := c.RequireParam("host")
+	if err != nil {
+		return "", err
+	}
+	queryType := strings.ToUpper(c.Param("record", "A"))
+	resolver := net.DefaultResolver
+	if server := c.Param("server", ""); server != "" {
+		resolver = newResolver(server, false)
+	}
+	var out bytes.Buffer
+	if err := doDNSQueryWithResolver(&out, name, queryType, resolver); err != nil {
+		return "", err
+	}
+	var answers []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if v, ok := strings.CutPrefix(line, "Address: "); ok {
+			answers = append(answers, strings.TrimSpace(v))
+		}
+	}
+	if want := c.Param("expect", ""); want != "" && !strings.Contains(out.String(), want) {
+		return "", fmt.Errorf("%s %s does not include %s", name, queryType, want)
+	}
+	if len(answers) > 0 {
+		return fmt.Sprintf("%s %s %s", name, queryType, strings.Join(answers, ",")), nil
+	}
+	return fmt.Sprintf("%s %s resolved", name, queryType), nil
+}
+
+// runHTTPCheck sends one request through curl's request path and compares
+// the status; "timeout" bounds the whole exchange like curl -m.
+func runHTTPCheck(inv *base.Invocation, c base.Check) (string, error) {
+	targetURL, err := c.RequireParam("url")
+	if err != nil {
+		return "", err
+	}
+	status, err := c.IntParam("status", http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	timeout, err := c.DurationParam("timeout", 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	method := strings.ToUpper(c.Param("method", http.MethodGet))
+	if method != http.MethodGet && method != http.MethodHead {
+		if err := base.CheckWritable(inv, "http check method "+method); err != nil {
+			return "", err
+		}
+	}
+	req, err := buildCurlRequest(targetURL, method, nil, "", "", nil, method == http.MethodHead)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{
+		Transport: newCurlTransport(timeout, c.Param("insecure", "") == "true"),
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	started := time.Now()
+	resp, err := client.Do(req.WithContext(inv.Ctx()))
+	if method != http.MethodGet && method != http.MethodHead {
+		// Like curl, audit requests that may change state on the server.
+		base.Audit(inv, "request", targetURL, err, "method", method)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	elapsed := time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != status {
+		return "", fmt.Errorf("status %d, want %d (%s)", resp.StatusCode, status, elapsed)
+	}
+	if want := c.Param("contains", ""); want != "" && !bytes.Contains(body, []byte(want)) {
+		return "", fmt.Errorf("body does not contain %q", want)
+	}
+	return fmt.Sprintf("%d in %s", resp.StatusCode, elapsed), nil
+}
+
+// runCertCheck completes a TLS handshake and fails when the leaf certificate
+// expires within "days" days.
+func runCertCheck(_ *base.Invocation, c base.Check) (string, error) {
+	addr, err := checkHostPort(c, "443")
+	if err != nil {
+		return "", err
+	}
+	days, err := c.IntParam("days", 14)
+	if err != nil {
+		return "", err
+	}
+	timeout, err := c.DurationParam("timeout", 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, &tls.Config{
+		ServerName:         c.Param("server_name", host),
+		InsecureSkipVerify: c.Param("insecure", "") == "true",
+	})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", fmt.Errorf("%s presented no certificate", addr)
+	}
+	notAfter := certs[0].NotAfter
+	left := time.Until(notAfter)
+	detail := "expires " + notAfter.UTC().Format("2006-01-02") + " (" + strconv.Itoa(int(left.Hours()/24)) + " days)"
+	if left < time.Duration(days)*24*time.Hour {
+		return "", fmt.Errorf("%s, within %d days", detail, days)
+	}
+	return detail, nil
+}
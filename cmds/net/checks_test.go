@@ -0,0 +1,92 @@
+package net
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+)
+
+func TestTCPAndHTTPChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok ready"))
+	}))
+	defer srv.Close()
+	inv := &base.Invocation{Context: context.Background()}
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	if _, err := runTCPCheck(inv, base.Check{Type: "tcp", Params: map[string]string{"address": addr}}); err != nil {
+		t.Fatalf("tcp check against a listening port: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+	if _, err := runTCPCheck(inv, base.Check{Type: "tcp", Params: map[string]string{"address": closed, "timeout": "500ms"}}); err == nil {
+		t.Fatal("expected tcp failure against a closed port")
+	}
+
+	if detail, err := runHTTPCheck(inv, base.Check{Type: "http", Params: map[string]string{"url": srv.URL, "contains": "ready"}}); err != nil || !strings.HasPrefix(detail, "200 in ") {
+		t.Fatalf("http check: %q, %v", detail, err)
+	}
+	if _, err := runHTTPCheck(inv, base.Check{Type: "http", Params: map[string]string{"url": srv.URL + "/missing"}}); err == nil || !strings.Contains(err.Error(), "status 404, want 200") {
+		t.Fatalf("expected status failure, got %v", err)
+	}
+	if _, err := runHTTPCheck(inv, base.Check{Type: "http", Params: map[string]string{"url": srv.URL + "/missing", "status": "404"}}); err != nil {
+		t.Fatalf("status 404 should pass: %v", err)
+	}
+
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	audited := &base.Invocation{Context: context.Background(), Env: []string{base.AuditEnv + "=" + logPath}}
+	if _, err := runHTTPCheck(audited, base.Check{Type: "http", Params: map[string]string{"url": srv.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("GET check should not be audited, stat err = %v", err)
+	}
+	if _, err := runHTTPCheck(audited, base.Check{Type: "http", Params: map[string]string{"url": srv.URL, "method": "post"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec base.AuditRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("audit line %q: %v", data, err)
+	}
+	if rec.Action != "request" || rec.Target != srv.URL || rec.Detail["method"] != "POST" || rec.Outcome != "ok" {
+		t.Fatalf("unexpected audit record: %+v", rec)
+	}
+}
+
+func TestCertCheckExpiryWindow(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "https://")
+	inv := &base.Invocation{}
+
+	if _, err := runCertCheck(inv, base.Check{Type: "cert", Params: map[string]string{"address": addr}}); err == nil {
+		t.Fatal("expected verification failure for the self-signed test certificate")
+	}
+	if detail, err := runCertCheck(inv, base.Check{Type: "cert", Params: map[string]string{"address": addr, "insecure": "true", "days": "1"}}); err != nil || !strings.HasPrefix(detail, "expires ") {
+		t.Fatalf("cert check: %q, %v", detail, err)
+	}
+	// The test certificate is valid for decades, but not for a century.
+	if _, err := runCertCheck(inv, base.Check{Type: "cert", Params: map[string]string{"address": addr, "insecure": "true", "days": "36500"}}); err == nil || !strings.Contains(err.Error(), "within 36500 days") {
+		t.Fatalf("expected expiry failure, got %v", err)
+	}
+}
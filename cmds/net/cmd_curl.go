@@ -18,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
@@ -33,20 +34,29 @@ func curlShortClassifier(c byte) (defined, takesValue bool) {
 	return false, false
 }
 
+// curlLongFlags maps curl's long flags to whether they take a value.
+var curlLongFlags = map[string]bool{
+	"silent": false, "show-error": false, "remote-name": false, "location": false, "head": false,
+	"insecure": false, "fail": false, "include": false, "bench": false, "help": false,
+	"output": true, "write-out": true, "max-time": true, "request": true, "header": true, "data": true,
+	"upload-file": true, "form": true, "connect-timeout": true, "resolve": true, "warmup": true,
+	"timeout": true, "concurrent": true, "requests": true,
+}
+
 // curlLongClassifier reports curl's long flags. It lets cluster expansion skip
 // the space-separated value of a long option (e.g. --header -X) instead of
 // mistaking it for a flag cluster.
 func curlLongClassifier(name string) (defined, takesValue bool) {
-	switch name {
-	case "silent", "show-error", "remote-name", "location", "head",
-		"insecure", "fail", "include", "bench", "help":
-		return true, false
-	case "output", "write-out", "max-time", "request", "header", "data",
-		"upload-file", "form", "connect-timeout", "resolve", "warmup",
-		"timeout", "concurrent", "requests":
-		return true, true
-	}
-	return false, false
+	takesValue, defined = curlLongFlags[name]
+	return defined, takesValue
+}
+
+// curlFlags declares curl's options for shell completion from its classifiers.
+var curlFlags = base.ClassifierFlags(curlShortClassifier, curlLongFlags)
+
+// curlMethods are the -X values offered by shell completion.
+func curlMethods() []string {
+	return []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 }
 
 // splitCurlLongFlagEquals rewrites "--flag=value" into ["--flag", "value"]
@@ -117,6 +127,10 @@ func (e curlCommandError) ExitCode() int {
 
 // curlCmd implements curl functionality
 func CurlCmd(args []string) error {
+	return curlCmd(base.Stdio(), args)
+}
+
+func curlCmd(inv *base.Invocation, args []string) error {
 	var (
 		// Basic options
 		silent          bool
@@ -235,7 +249,7 @@ func CurlCmd(args []string) error {
 			if err != nil {
 				return err
 			}
-			field, err := parseCurlFormField(v)
+			field, err := parseCurlFormField(inv, v)
 			if err != nil {
 				return err
 			}
@@ -309,7 +323,7 @@ func CurlCmd(args []string) error {
 			}
 			requestTimeout = time.Duration(sec * float64(time.Second))
 		case arg == "-h" || arg == "--help":
-			printCurlUsage(os.Stdout)
+			printCurlUsage(inv.Stdout)
 			return nil
 		case strings.HasPrefix(arg, "-"):
 			return fmt.Errorf("unknown option: %s", arg)
@@ -329,6 +343,18 @@ doneFlags:
 	if (uploadFile != "" && postData != "") || (uploadFile != "" && len(formFields) > 0) || (postData != "" && len(formFields) > 0) {
 		return fmt.Errorf("only one of -d, -T, or -F may be used at a time")
 	}
+	// Read-only mode allows fetching and looking, never sending data or
+	// writing a file.
+	if m := strings.ToUpper(curlMethod(request, postData, uploadFile, formFields, head)); m != http.MethodGet && m != http.MethodHead {
+		if err := base.CheckWritable(inv, "curl -X "+m); err != nil {
+			return err
+		}
+	}
+	if outputFile != "" || remoteName {
+		if err := base.CheckWritable(inv, "curl -o/-O"); err != nil {
+			return err
+		}
+	}
 
 	// Set defaults for benchmark mode
 	if benchMode {
@@ -359,14 +385,7 @@ doneFlags:
 	}
 
 	// Create HTTP client
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout: connectTimeout,
-		}).DialContext,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: insecure,
-		},
-	}
+	transport := newCurlTransport(connectTimeout, insecure)
 	if len(resolveHosts) > 0 {
 		resolveMap := make(map[string]string, len(resolveHosts))
 		for _, host := range resolveHosts {
@@ -394,13 +413,13 @@ doneFlags:
 
 	// Benchmark mode
 	if benchMode {
-		return runBench(client, targetURL, request, headers, postData, head,
+		return runBench(inv, client, targetURL, request, headers, postData, head,
 			concurrent, totalRequests, warmupRequests, requestTimeout, failOnError, silent)
 	}
 
 	// Normal mode
-	return runSingle(client, targetURL, request, headers, postData, uploadFile, formFields, head,
-		outputFile, writeOut, showHeaders, failOnError, silent, showError)
+	return runSingle(inv, client, targetURL, request, headers, postData, inv.Path(uploadFile), formFields, head,
+		inv.Path(outputFile), writeOut, showHeaders, failOnError, silent, showError)
 }
 
 // wrapCurlError wraps an error that CurlCmd has not already printed itself
@@ -418,7 +437,7 @@ func wrapCurlError(err error, silent, showError bool) error {
 }
 
 // wrapCurlErrorAlreadyPrinted wraps an error for a diagnostic runSingle has
-// already printed directly to os.Stderr (or deliberately suppressed under
+// already printed directly to stderr (or deliberately suppressed under
 // -s/--silent); the top-level CLI dispatcher must never print it again.
 func wrapCurlErrorAlreadyPrinted(err error, exitCode int) error {
 	if err == nil {
@@ -431,7 +450,7 @@ func wrapCurlErrorAlreadyPrinted(err error, exitCode int) error {
 	}
 }
 
-func parseCurlFormField(spec string) (curlFormField, error) {
+func parseCurlFormField(inv *base.Invocation, spec string) (curlFormField, error) {
 	name, value, ok := strings.Cut(spec, "=")
 	if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
 		return curlFormField{}, fmt.Errorf("invalid form field %q", spec)
@@ -439,7 +458,7 @@ func parseCurlFormField(spec string) (curlFormField, error) {
 	value = strings.TrimSpace(value)
 	if strings.HasPrefix(value, "@") {
 		filePath := strings.TrimPrefix(value, "@")
-		data, err := os.ReadFile(filePath)
+		data, err := os.ReadFile(inv.Path(filePath))
 		if err != nil {
 			return curlFormField{}, fmt.Errorf("cannot read form file %s: %w", filePath, err)
 		}
@@ -455,13 +474,24 @@ func parseCurlFormField(spec string) (curlFormField, error) {
 	}, nil
 }
 
-func buildCurlPayload(method, postData, uploadFile string, formFields []curlFormField, head bool) (*curlRequestPayload, error) {
-	payload := &curlRequestPayload{method: "GET"}
-	if method != "" {
-		payload.method = method
-	} else if head {
-		payload.method = "HEAD"
+// curlMethod is the method a request is sent with: -X wins, then PUT for -T
+// and POST for -F or -d, then HEAD for -I.
+func curlMethod(method, postData, uploadFile string, formFields []curlFormField, head bool) string {
+	switch {
+	case method != "":
+		return method
+	case uploadFile != "":
+		return "PUT"
+	case len(formFields) > 0 || postData != "":
+		return "POST"
+	case head:
+		return "HEAD"
 	}
+	return "GET"
+}
+
+func buildCurlPayload(method, postData, uploadFile string, formFields []curlFormField, head bool) (*curlRequestPayload, error) {
+	payload := &curlRequestPayload{method: curlMethod(method, postData, uploadFile, formFields, head)}
 	switch {
 	case uploadFile != "":
 		data, err := os.ReadFile(uploadFile)
@@ -470,9 +500,6 @@ func buildCurlPayload(method, postData, uploadFile string, formFields []curlForm
 		}
 		payload.body = data
 		payload.contentType = "application/octet-stream"
-		if method == "" {
-			payload.method = "PUT"
-		}
 	case len(formFields) > 0:
 		var buf bytes.Buffer
 		writer := multipart.NewWriter(&buf)
@@ -490,19 +517,26 @@ func buildCurlPayload(method, postData, uploadFile string, formFields []curlForm
 		}
 		payload.body = buf.Bytes()
 		payload.contentType = writer.FormDataContentType()
-		if method == "" {
-			payload.method = "POST"
-		}
 	case postData != "":
 		payload.body = []byte(postData)
 		payload.contentType = "application/x-www-form-urlencoded"
-		if method == "" {
-			payload.method = "POST"
-		}
 	}
 	return payload, nil
 }
 
+// newCurlTransport is the transport curl and the http check send requests
+// through.
+func newCurlTransport(connectTimeout time.Duration, insecure bool) *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: connectTimeout,
+		}).DialContext,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecure,
+		},
+	}
+}
+
 func applyCurlHeaders(req *http.Request, headers []string, contentType string) {
 	for _, h := range headers {
 		parts := strings.SplitN(h, ":", 2)
@@ -580,13 +614,26 @@ func printCurlUsage(w io.Writer) {
 	fmt.Fprintln(w, "  gobox curl --bench -c 10 -n 100 https://example.com")
 }
 
-func runSingle(client *http.Client, targetURL, method string, headers []string, postData, uploadFile string, formFields []curlFormField,
-	head bool, outputFile, writeOut string, showHeaders, failOnError, silent, showError bool) error {
+func runSingle(inv *base.Invocation, client *http.Client, targetURL, method string, headers []string, postData, uploadFile string, formFields []curlFormField,
+	head bool, outputFile, writeOut string, showHeaders, failOnError, silent, showError bool) (err error) {
+
+	if uploadFile != "" {
+		defer func() { base.Audit(inv, "upload", targetURL, err, "file", uploadFile) }()
+	}
+	if outputFile != "" {
+		defer func() { base.Audit(inv, "write", outputFile, err, "url", targetURL) }()
+	}
+	// -T is audited as the upload above; any other request that is not a
+	// plain fetch may change state on the server.
+	if m := curlMethod(method, postData, uploadFile, formFields, head); uploadFile == "" && m != "GET" && m != "HEAD" {
+		defer func() { base.Audit(inv, "request", targetURL, err, "method", m) }()
+	}
 
 	req, err := buildCurlRequest(targetURL, method, headers, postData, uploadFile, formFields, head)
 	if err != nil {
 		return wrapCurlError(err, silent, showError)
 	}
+	req = req.WithContext(inv.Ctx())
 
 	start := time.Now()
 
@@ -594,7 +641,7 @@ func runSingle(client *http.Client, targetURL, method string, headers []string,
 	resp, err := client.Do(req)
 	if err != nil {
 		if !silent || showError {
-			fmt.Fprintf(os.Stderr, "curl: %v\n", err)
+			fmt.Fprintf(inv.Stderr, "curl: %v\n", err)
 		}
 		return wrapCurlErrorAlreadyPrinted(fmt.Errorf("request failed: %w", err), 2)
 	}
@@ -605,7 +652,7 @@ func runSingle(client *http.Client, targetURL, method string, headers []string,
 	// Handle fail on error
 	if failOnError && resp.StatusCode >= 400 {
 		if !silent || showError {
-			fmt.Fprintf(os.Stderr, "curl: HTTP error %d\n", resp.StatusCode)
+			fmt.Fprintf(inv.Stderr, "curl: HTTP error %d\n", resp.StatusCode)
 		}
 		return wrapCurlErrorAlreadyPrinted(fmt.Errorf("HTTP error %d", resp.StatusCode), 22)
 	}
@@ -621,7 +668,7 @@ func runSingle(client *http.Client, targetURL, method string, headers []string,
 		output = f
 		defer f.Close()
 	} else {
-		output = os.Stdout
+		output = inv.Stdout
 	}
 
 	// Write headers if requested (or if this is a HEAD request)
@@ -645,7 +692,7 @@ func runSingle(client *http.Client, targetURL, method string, headers []string,
 
 	// Write format output
 	if writeOut != "" {
-		fmt.Fprintf(os.Stdout, "%s", formatWriteOut(writeOut, resp, elapsed))
+		fmt.Fprintf(inv.Stdout, "%s", formatWriteOut(writeOut, resp, elapsed))
 	}
 
 	return nil
@@ -677,16 +724,20 @@ type benchResult struct {
 	statusCode int
 }
 
-func runBench(client *http.Client, targetURL, method string, headers []string, postData string,
+func runBench(inv *base.Invocation, client *http.Client, targetURL, method string, headers []string, postData string,
 	head bool, concurrent, totalRequests, warmupRequests int, requestTimeout time.Duration,
 	failOnError, silent bool) error {
 
+	if m := curlMethod(method, postData, "", nil, head); m != "GET" && m != "HEAD" {
+		base.Audit(inv, "request", targetURL, nil, "method", m, "requests", strconv.Itoa(warmupRequests+totalRequests))
+	}
+
 	// Warmup
 	if !silent && warmupRequests > 0 {
-		fmt.Fprintf(os.Stderr, "Warming up %d requests...\n", warmupRequests)
+		fmt.Fprintf(inv.Stderr, "Warming up %d requests...\n", warmupRequests)
 	}
 	for i := 0; i < warmupRequests; i++ {
-		_, _ = doRequest(client, targetURL, method, headers, postData, head, requestTimeout, failOnError)
+		_, _ = doRequest(inv.Ctx(), client, targetURL, method, headers, postData, head, requestTimeout, failOnError)
 	}
 
 	// Prepare work channel
@@ -702,7 +753,7 @@ func runBench(client *http.Client, targetURL, method string, headers []string, p
 			defer wg.Done()
 			for range workCh {
 				start := time.Now()
-				statusCode, err := doRequest(client, targetURL, method, headers, postData, head, requestTimeout, failOnError)
+				statusCode, err := doRequest(inv.Ctx(), client, targetURL, method, headers, postData, head, requestTimeout, failOnError)
 				latency := time.Since(start)
 				results <- benchResult{latency: latency, err: err, statusCode: statusCode}
 			}
@@ -737,8 +788,8 @@ func runBench(client *http.Client, targetURL, method string, headers []string, p
 
 	// Calculate statistics
 	if len(latencies) == 0 {
-		fmt.Fprintf(os.Stdout, "Requests: %d, Concurrency: %d, Failed: %d\n", totalRequests, concurrent, failed)
-		fmt.Fprintf(os.Stdout, "Latency: no successful requests\n")
+		fmt.Fprintf(inv.Stdout, "Requests: %d, Concurrency: %d, Failed: %d\n", totalRequests, concurrent, failed)
+		fmt.Fprintf(inv.Stdout, "Latency: no successful requests\n")
 		return nil
 	}
 
@@ -765,27 +816,28 @@ func runBench(client *http.Client, targetURL, method string, headers []string, p
 	}
 	throughput := float64(len(latencies)) / totalTime
 
-	fmt.Fprintf(os.Stdout, "Requests: %d, Concurrency: %d, Failed: %d\n", totalRequests, concurrent, failed)
-	fmt.Fprintf(os.Stdout, "Latency: min=%.0fms, max=%.0fms, mean=%.0fms, p50=%.0fms, p90=%.0fms, p99=%.0fms\n",
+	fmt.Fprintf(inv.Stdout, "Requests: %d, Concurrency: %d, Failed: %d\n", totalRequests, concurrent, failed)
+	fmt.Fprintf(inv.Stdout, "Latency: min=%.0fms, max=%.0fms, mean=%.0fms, p50=%.0fms, p90=%.0fms, p99=%.0fms\n",
 		min.Seconds()*1000, max.Seconds()*1000, mean.Seconds()*1000,
 		p50.Seconds()*1000, p90.Seconds()*1000, p99.Seconds()*1000)
-	fmt.Fprintf(os.Stdout, "Throughput: %.0f req/s, Total time: %.1fs\n",
+	fmt.Fprintf(inv.Stdout, "Throughput: %.0f req/s, Total time: %.1fs\n",
 		throughput, totalTime)
 
 	return nil
 }
 
-func doRequest(client *http.Client, targetURL, method string, headers []string, postData string,
+func doRequest(ctx context.Context, client *http.Client, targetURL, method string, headers []string, postData string,
 	head bool, timeout time.Duration, failOnError bool) (int, error) {
 	req, err := buildCurlRequest(targetURL, method, headers, postData, "", nil, head)
 	if err != nil {
 		return 0, err
 	}
 	if timeout > 0 {
-		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
-		req = req.WithContext(ctx)
 	}
+	req = req.WithContext(ctx)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -2,6 +2,7 @@ package net
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,6 +18,7 @@ import (
 	"testing"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
@@ -128,6 +130,42 @@ func TestCurlGetWithQueryParams(t *testing.T) {
 	}
 }
 
+func TestCurlReadOnlyModeAllowsOnlyFetching(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, r.Method)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdout: &out, Stderr: io.Discard, Env: []string{base.ReadOnlyEnv + "=1"}, Dir: dir}
+	for _, args := range [][]string{
+		{"-d", "x=1", server.URL},
+		{"-X", "DELETE", server.URL},
+		{"-T", "missing.bin", server.URL},
+		{"-o", "page.html", server.URL},
+		{"-O", server.URL + "/page.html"},
+	} {
+		if err := curlCmd(inv, args); base.ExitStatus(err) != base.ReadOnlyExitCode {
+			t.Fatalf("%q: expected a read-only refusal, got %v", args, err)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Fatalf("expected refused requests never to be sent, got %d", n)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no files to be written, got %v", entries)
+	}
+	if err := curlCmd(inv, []string{"-I", server.URL}); err != nil {
+		t.Fatalf("expected HEAD to be allowed, got %v", err)
+	}
+	if err := curlCmd(inv, []string{server.URL}); err != nil || !strings.HasSuffix(out.String(), "GET") {
+		t.Fatalf("expected GET to be allowed, got %v %q", err, out.String())
+	}
+}
+
 // ============== SILENT MODE TESTS (-s) ==============
 
 func TestCurlSilentMode(t *testing.T) {
@@ -498,6 +536,33 @@ func TestCurlPostMethod(t *testing.T) {
 	}
 }
 
+func TestCurlNonGetRequestWritesAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	inv := &base.Invocation{Stdout: io.Discard, Stderr: io.Discard, Env: []string{base.AuditEnv + "=" + logPath}}
+	if err := curlCmd(inv, []string{"-s", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("GET should not be audited, stat err = %v", err)
+	}
+	if err := curlCmd(inv, []string{"-s", "-d", "a=1", server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec base.AuditRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("audit line %q: %v", data, err)
+	}
+	if rec.Action != "request" || rec.Target != server.URL || rec.Detail["method"] != "POST" || rec.Outcome != "ok" {
+		t.Fatalf("unexpected audit record: %+v", rec)
+	}
+}
+
 func TestCurlPutMethod(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "PUT" {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"os"
 	"runtime"
@@ -16,6 +17,10 @@ import (
 var ifstatGOOS = runtime.GOOS
 
 func IfstatCmd(args []string) error {
+	return ifstatCmd(base.Stdio(), args)
+}
+
+func ifstatCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("ifstat", flag.ContinueOnError)
 	interval := fsFlags.Int("p", 1, "sample interval in seconds")
 	count := fsFlags.Int("n", 0, "number of samples to take (0 = continuous)")
@@ -25,21 +30,21 @@ func IfstatCmd(args []string) error {
 	showErrors := fsFlags.Bool("e", false, "show error packet counts (rx_errors, tx_errors)")
 	showDrops := fsFlags.Bool("d", false, "show drop packet counts (rx_dropped, tx_dropped)")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox ifstat [-p SEC] [-n COUNT] [-a] [-A] [-e] [-d] [-i IFACES]")
-		fmt.Fprintln(os.Stderr, "Print network interface statistics (packets/s, bytes/s).")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Sampling:")
-		fmt.Fprintln(os.Stderr, "  -p SEC          sample interval in seconds")
-		fmt.Fprintln(os.Stderr, "  -n COUNT        number of samples to take (0 = continuous)")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Display:")
-		fmt.Fprintln(os.Stderr, "  -i IFACES       comma-separated interface list")
-		fmt.Fprintln(os.Stderr, "  -a              show absolute cumulative values")
-		fmt.Fprintln(os.Stderr, "  -A              show all interfaces including virtual ones")
-		fmt.Fprintln(os.Stderr, "  -e              show error packet counts")
-		fmt.Fprintln(os.Stderr, "  -d              show drop packet counts")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "  -h, --help      show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox ifstat [-p SEC] [-n COUNT] [-a] [-A] [-e] [-d] [-i IFACES]")
+		fmt.Fprintln(inv.Stderr, "Print network interface statistics (packets/s, bytes/s).")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Sampling:")
+		fmt.Fprintln(inv.Stderr, "  -p SEC          sample interval in seconds")
+		fmt.Fprintln(inv.Stderr, "  -n COUNT        number of samples to take (0 = continuous)")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Display:")
+		fmt.Fprintln(inv.Stderr, "  -i IFACES       comma-separated interface list")
+		fmt.Fprintln(inv.Stderr, "  -a              show absolute cumulative values")
+		fmt.Fprintln(inv.Stderr, "  -A              show all interfaces including virtual ones")
+		fmt.Fprintln(inv.Stderr, "  -e              show error packet counts")
+		fmt.Fprintln(inv.Stderr, "  -d              show drop packet counts")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "  -h, --help      show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -52,6 +57,8 @@ func IfstatCmd(args []string) error {
 		return errors.New("ifstat: supported only on Linux")
 	}
 
+	roots := inv.Roots()
+
 	// Parse comma-separated interfaces
 	var wantedIfaces map[string]bool
 	if *iface != "" {
@@ -63,7 +70,7 @@ func IfstatCmd(args []string) error {
 
 	// Read network interface type from /sys/class/net/<iface>/type
 	isPhysical := func(iface string) bool {
-		data, err := os.ReadFile(fmt.Sprintf("/sys/class/net/%s/type", iface))
+		data, err := os.ReadFile(roots.SysPath("class", "net", iface, "type"))
 		if err != nil {
 			return false
 		}
@@ -77,7 +84,7 @@ func IfstatCmd(args []string) error {
 
 	// Get list of all network interfaces
 	listIfaces := func() ([]string, error) {
-		entries, err := os.ReadDir("/sys/class/net")
+		entries, err := os.ReadDir(roots.SysPath("class", "net"))
 		if err != nil {
 			return nil, err
 		}
@@ -122,7 +129,7 @@ func IfstatCmd(args []string) error {
 			}
 			return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
 		}
-		base := fmt.Sprintf("/sys/class/net/%s/statistics", iface)
+		base := roots.SysPath("class", "net", iface, "statistics")
 
 		if v, err := readU64(base + "/rx_packets"); err == nil {
 			s.RxPackets = v
@@ -171,7 +178,7 @@ func IfstatCmd(args []string) error {
 				}
 			}
 			if !found {
-				fmt.Fprintf(os.Stderr, "ifstat: warning: interface %s not found or not a physical NIC\n", wanted)
+				fmt.Fprintf(inv.Stderr, "ifstat: warning: interface %s not found or not a physical NIC\n", wanted)
 			}
 		}
 	}
@@ -183,11 +190,19 @@ func IfstatCmd(args []string) error {
 		rxCol, txCol, rxKBCol, txKBCol = "rxpkts", "txpkts", "rxKB", "txKB"
 	}
 
+	var tw *utils.TableWriter
+	if utils.IsStructuredOutput(inv.Output) {
+		tw = utils.NewTableWriter(inv.Stdout, inv.Output)
+	}
+
 	if len(ifaces) == 0 {
 		if wantedIfaces != nil {
+			if tw != nil {
+				return tw.Write(newIfstatTable(*absolute))
+			}
 			// User specified interfaces but none were found - warn was already printed above
 			// Print header (to stdout) so the command produces some output
-			fmt.Printf("%-12s  %9s  %9s  %9s  %9s\n",
+			fmt.Fprintf(inv.Stdout, "%-12s  %9s  %9s  %9s  %9s\n",
 				"Interface", rxCol, txCol, rxKBCol, txKBCol)
 			return nil
 		}
@@ -208,16 +223,16 @@ func IfstatCmd(args []string) error {
 	// Header
 	printHeader := func() {
 		if showErrorsCol && showDropsCol {
-			fmt.Printf("%-*s  %9s  %9s  %9s  %9s  %7s  %7s  %7s  %7s\n",
+			fmt.Fprintf(inv.Stdout, "%-*s  %9s  %9s  %9s  %9s  %7s  %7s  %7s  %7s\n",
 				interfaceWidth, "Interface", rxCol, txCol, rxKBCol, txKBCol, "rxerrs", "txerrs", "rxdrop", "txdrop")
 		} else if showErrorsCol {
-			fmt.Printf("%-*s  %9s  %9s  %9s  %9s  %7s  %7s\n",
+			fmt.Fprintf(inv.Stdout, "%-*s  %9s  %9s  %9s  %9s  %7s  %7s\n",
 				interfaceWidth, "Interface", rxCol, txCol, rxKBCol, txKBCol, "rxerrs", "txerrs")
 		} else if showDropsCol {
-			fmt.Printf("%-*s  %9s  %9s  %9s  %9s  %7s  %7s\n",
+			fmt.Fprintf(inv.Stdout, "%-*s  %9s  %9s  %9s  %9s  %7s  %7s\n",
 				interfaceWidth, "Interface", rxCol, txCol, rxKBCol, txKBCol, "rxdrop", "txdrop")
 		} else {
-			fmt.Printf("%-*s  %9s  %9s  %9s  %9s\n",
+			fmt.Fprintf(inv.Stdout, "%-*s  %9s  %9s  %9s  %9s\n",
 				interfaceWidth, "Interface", rxCol, txCol, rxKBCol, txKBCol)
 		}
 	}
@@ -236,7 +251,11 @@ func IfstatCmd(args []string) error {
 		}
 		if iter > 1 {
 			// Sleep between samples; emit the first sample immediately so finite runs don't stall.
-			time.Sleep(time.Duration(*interval) * time.Second)
+			select {
+			case <-inv.Ctx().Done():
+				return nil
+			case <-time.After(time.Duration(*interval) * time.Second):
+			}
 		}
 
 		// Re-list interfaces in case they changed
@@ -259,9 +278,13 @@ func IfstatCmd(args []string) error {
 		}
 
 		// Print header once at start
-		if iter == 1 {
+		if iter == 1 && tw == nil {
 			printHeader()
 		}
+		var table *utils.Table
+		if tw != nil {
+			table = newIfstatTable(*absolute)
+		}
 
 		// Calculate and print rates
 		var dur float64 = 1.0
@@ -300,24 +323,33 @@ func IfstatCmd(args []string) error {
 				}
 			}
 
-			if showErrorsCol && showDropsCol {
-				fmt.Printf("%-*s  %9.2f  %9.2f  %9.2f  %9.2f  %7d  %7d  %7d  %7d\n",
+			if table != nil {
+				table.Append(iter, ifaceName, rxPps, txPps, rxKBps*1024.0, txKBps*1024.0,
+					curr.RxErrors, curr.TxErrors, curr.RxDropped, curr.TxDropped)
+			} else if showErrorsCol && showDropsCol {
+				fmt.Fprintf(inv.Stdout, "%-*s  %9.2f  %9.2f  %9.2f  %9.2f  %7d  %7d  %7d  %7d\n",
 					interfaceWidth, ifaceName, rxPps, txPps, rxKBps, txKBps,
 					curr.RxErrors, curr.TxErrors, curr.RxDropped, curr.TxDropped)
 			} else if showErrorsCol {
-				fmt.Printf("%-*s  %9.2f  %9.2f  %9.2f  %9.2f  %7d  %7d\n",
+				fmt.Fprintf(inv.Stdout, "%-*s  %9.2f  %9.2f  %9.2f  %9.2f  %7d  %7d\n",
 					interfaceWidth, ifaceName, rxPps, txPps, rxKBps, txKBps,
 					curr.RxErrors, curr.TxErrors)
 			} else if showDropsCol {
-				fmt.Printf("%-*s  %9.2f  %9.2f  %9.2f  %9.2f  %7d  %7d\n",
+				fmt.Fprintf(inv.Stdout, "%-*s  %9.2f  %9.2f  %9.2f  %9.2f  %7d  %7d\n",
 					interfaceWidth, ifaceName, rxPps, txPps, rxKBps, txKBps,
 					curr.RxDropped, curr.TxDropped)
 			} else {
-				fmt.Printf("%-*s  %9.2f  %9.2f  %9.2f  %9.2f\n",
+				fmt.Fprintf(inv.Stdout, "%-*s  %9.2f  %9.2f  %9.2f  %9.2f\n",
 					interfaceWidth, ifaceName, rxPps, txPps, rxKBps, txKBps)
 			}
 		}
 
+		if table != nil {
+			if err := tw.Write(table); err != nil {
+				return err
+			}
+		}
+
 		// Store current as previous for next iteration
 		prevStats = currStats
 
@@ -339,3 +371,26 @@ func IfstatCmd(args []string) error {
 
 	return nil
 }
+
+// newIfstatTable returns the structured table for one sample. Byte columns
+// are bytes rather than the text view's KB; the error and drop counters are
+// always present so the field set does not depend on -e/-d.
+// interfaceNames lists network interfaces for shell completion of -i / -I.
+func interfaceNames() []string {
+	entries, err := os.ReadDir(utils.Roots{}.SysPath("class", "net"))
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func newIfstatTable(absolute bool) *utils.Table {
+	if absolute {
+		return utils.NewTable("sample", "interface", "rx_packets", "tx_packets", "rx_bytes", "tx_bytes", "rx_errors", "tx_errors", "rx_dropped", "tx_dropped")
+	}
+	return utils.NewTable("sample", "interface", "rx_packets_per_sec", "tx_packets_per_sec", "rx_bytes_per_sec", "tx_bytes_per_sec", "rx_errors", "tx_errors", "rx_dropped", "tx_dropped")
+}
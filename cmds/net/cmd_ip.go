@@ -3,6 +3,9 @@ package net
 import (
 	"bufio"
 	"fmt"
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"gobox/pkg/netfs"
 	"io"
 	"net"
 	"os"
@@ -13,13 +16,18 @@ import (
 )
 
 var (
-	ipInterfaces     = net.Interfaces
-	ipInterfaceAddrs = func(iface net.Interface) ([]net.Addr, error) { return iface.Addrs() }
+	ipInterfaces     = ipSystemInterfaces
+	ipInterfaceAddrs = ipSystemInterfaceAddrs
 	ipOpenFile       = os.Open
-	ipStatsRoot      = "/sys/class/net"
+	ipStatsRoot      = func(roots utils.Roots) string { return roots.SysPath("class", "net") }
 )
 
 func IpCmd(args []string) error {
+	return ipCmd(base.Stdio(), args)
+}
+
+func ipCmd(inv *base.Invocation, args []string) error {
+	roots := inv.Roots()
 	oneLine := false
 	stats := false
 	filtered := make([]string, 0, len(args))
@@ -43,55 +51,82 @@ func IpCmd(args []string) error {
 	if stats && object != "link" && object != "l" {
 		return fmt.Errorf("-s is supported only with link")
 	}
+	if utils.IsStructuredOutput(inv.Output) {
+		var t *utils.Table
+		var err error
+		switch object {
+		case "addr", "a":
+			t, err = ipAddrTable(roots)
+		case "link", "l":
+			t, err = ipLinkTable(roots)
+		case "route", "r":
+			t, err = ipRouteTable(roots)
+		case "neigh", "n":
+			t, err = ipNeighTable(roots)
+		default:
+			return fmt.Errorf("unsupported ip object %s", object)
+		}
+		if err != nil {
+			return err
+		}
+		return t.Render(inv.Stdout, inv.Output)
+	}
 	switch object {
 	case "addr", "a":
-		return ipAddr(oneLine)
+		return ipAddr(inv.Stdout, roots, oneLine)
 	case "link", "l":
-		return ipLink(stats)
+		return ipLink(inv.Stdout, roots, stats)
 	case "route", "r":
-		return ipRoute()
+		return ipRoute(inv.Stdout, roots)
 	case "neigh", "n":
-		return ipNeigh()
+		return ipNeigh(inv.Stdout, roots)
 	case "-h", "--help", "help":
-		printIpUsage()
+		printIpUsage(inv.Stdout)
 		return nil
 	default:
 		return fmt.Errorf("unsupported ip object %s", object)
 	}
 }
 
-func printIpUsage() {
-	fmt.Fprintln(os.Stdout, "Usage: gobox ip [-o] addr | [-s] link | route | neigh")
-	fmt.Fprintln(os.Stdout, "Show network interfaces, routes, and neighbours (read-only subset).")
-	fmt.Fprintln(os.Stdout)
-	fmt.Fprintln(os.Stdout, "Objects:")
-	fmt.Fprintln(os.Stdout, "  addr, a       show interface addresses")
-	fmt.Fprintln(os.Stdout, "  link, l       show interface link state")
-	fmt.Fprintln(os.Stdout, "  route, r      show the routing table")
-	fmt.Fprintln(os.Stdout, "  neigh, n      show the ARP/neighbour table")
-	fmt.Fprintln(os.Stdout)
-	fmt.Fprintln(os.Stdout, "Options:")
-	fmt.Fprintln(os.Stdout, "  -o            single-line output (addr only)")
-	fmt.Fprintln(os.Stdout, "  -s            show extra statistics (link only)")
-	fmt.Fprintln(os.Stdout, "  -h, --help    show this help")
-	fmt.Fprintln(os.Stdout)
-	fmt.Fprintln(os.Stdout, "Examples:")
-	fmt.Fprintln(os.Stdout, "  gobox ip addr")
-	fmt.Fprintln(os.Stdout, "  gobox ip -o addr")
-	fmt.Fprintln(os.Stdout, "  gobox ip -s link")
-	fmt.Fprintln(os.Stdout, "  gobox ip route")
-}
-
-func ipAddr(oneLine bool) error {
-	ifaces, err := ipInterfaces()
+// ipFlags declares ip's options for shell completion.
+var ipFlags = []base.Flag{
+	{Name: "-o", Usage: "single-line output (addr only)"},
+	{Name: "-s", Usage: "show extra statistics (link only)"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
+func printIpUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox ip [-o] addr | [-s] link | route | neigh")
+	fmt.Fprintln(w, "Show network interfaces, routes, and neighbours (read-only subset).")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Objects:")
+	fmt.Fprintln(w, "  addr, a       show interface addresses")
+	fmt.Fprintln(w, "  link, l       show interface link state")
+	fmt.Fprintln(w, "  route, r      show the routing table")
+	fmt.Fprintln(w, "  neigh, n      show the ARP/neighbour table")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -o            single-line output (addr only)")
+	fmt.Fprintln(w, "  -s            show extra statistics (link only)")
+	fmt.Fprintln(w, "  -h, --help    show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox ip addr")
+	fmt.Fprintln(w, "  gobox ip -o addr")
+	fmt.Fprintln(w, "  gobox ip -s link")
+	fmt.Fprintln(w, "  gobox ip route")
+}
+
+func ipAddr(w io.Writer, roots utils.Roots, oneLine bool) error {
+	ifaces, err := ipInterfaces(roots)
 	if err != nil {
 		return err
 	}
 	for _, iface := range ifaces {
-		addrs, _ := ipInterfaceAddrs(iface)
+		addrs, _ := ipInterfaceAddrs(roots, iface)
 		if !oneLine {
-			fmt.Printf("%d: %s: <%s> mtu %d state %s\n", iface.Index, iface.Name, ipFlagsString(iface), iface.MTU, ipOperState(iface))
-			printIpLinkLine(iface)
+			fmt.Fprintf(w, "%d: %s: <%s> mtu %d state %s\n", iface.Index, iface.Name, ipFlagsString(iface), iface.MTU, ipOperState(roots, iface))
+			printIpLinkLine(w, iface)
 		}
 		for _, addr := range addrs {
 			fam := "inet"
@@ -117,30 +152,30 @@ func ipAddr(oneLine bool) error {
 				// -o packs the whole record onto a single physical line,
 				// using a literal "\" continuation marker (not an actual
 				// newline) before the valid_lft/preferred_lft fields.
-				fmt.Printf("%d: %s    %s %s scope %s%s\\       valid_lft forever preferred_lft forever\n", iface.Index, iface.Name, fam, addrText, scope, ifaceSuffix)
+				fmt.Fprintf(w, "%d: %s    %s %s scope %s%s\\       valid_lft forever preferred_lft forever\n", iface.Index, iface.Name, fam, addrText, scope, ifaceSuffix)
 				continue
 			}
-			fmt.Printf("    %s %s scope %s%s\n", fam, addrText, scope, ifaceSuffix)
-			fmt.Println("       valid_lft forever preferred_lft forever")
+			fmt.Fprintf(w, "    %s %s scope %s%s\n", fam, addrText, scope, ifaceSuffix)
+			fmt.Fprintln(w, "       valid_lft forever preferred_lft forever")
 		}
 	}
 	return nil
 }
 
-func ipLink(stats bool) error {
-	ifaces, err := ipInterfaces()
+func ipLink(w io.Writer, roots utils.Roots, stats bool) error {
+	ifaces, err := ipInterfaces(roots)
 	if err != nil {
 		return err
 	}
 	for _, iface := range ifaces {
-		fmt.Printf("%d: %s: <%s> mtu %d state %s\n", iface.Index, iface.Name, ipFlagsString(iface), iface.MTU, ipOperState(iface))
-		printIpLinkLine(iface)
+		fmt.Fprintf(w, "%d: %s: <%s> mtu %d state %s\n", iface.Index, iface.Name, ipFlagsString(iface), iface.MTU, ipOperState(roots, iface))
+		printIpLinkLine(w, iface)
 		if stats {
-			s := readIfaceStats(iface.Name)
-			fmt.Printf("    RX: %10s %8s %6s %7s %7s %7s\n", "bytes", "packets", "errors", "dropped", "missed", "mcast")
-			fmt.Printf("    %10d %8d %6d %7d %7d %7d\n", s["rx_bytes"], s["rx_packets"], s["rx_errors"], s["rx_dropped"], s["rx_missed_errors"], s["multicast"])
-			fmt.Printf("    TX: %10s %8s %6s %7s %7s %7s\n", "bytes", "packets", "errors", "dropped", "carrier", "collsns")
-			fmt.Printf("    %10d %8d %6d %7d %7d %7d\n", s["tx_bytes"], s["tx_packets"], s["tx_errors"], s["tx_dropped"], s["tx_carrier_errors"], s["collisions"])
+			s := readIfaceStats(roots, iface.Name)
+			fmt.Fprintf(w, "    RX: %10s %8s %6s %7s %7s %7s\n", "bytes", "packets", "errors", "dropped", "missed", "mcast")
+			fmt.Fprintf(w, "    %10d %8d %6d %7d %7d %7d\n", s["rx_bytes"], s["rx_packets"], s["rx_errors"], s["rx_dropped"], s["rx_missed_errors"], s["multicast"])
+			fmt.Fprintf(w, "    TX: %10s %8s %6s %7s %7s %7s\n", "bytes", "packets", "errors", "dropped", "carrier", "collsns")
+			fmt.Fprintf(w, "    %10d %8d %6d %7d %7d %7d\n", s["tx_bytes"], s["tx_packets"], s["tx_errors"], s["tx_dropped"], s["tx_carrier_errors"], s["collisions"])
 		}
 	}
 	return nil
@@ -158,13 +193,13 @@ func zeroHardwareAddrOr(addr net.HardwareAddr, fallback string) string {
 // printIpLinkLine prints the "link/ether MAC brd BROADCAST" (or
 // "link/loopback ...") line ip addr/link both show beneath the interface
 // summary line.
-func printIpLinkLine(iface net.Interface) {
+func printIpLinkLine(w io.Writer, iface net.Interface) {
 	if iface.Flags&net.FlagLoopback != 0 {
 		mac := zeroHardwareAddrOr(iface.HardwareAddr, "00:00:00:00:00:00")
-		fmt.Printf("    link/loopback %s brd 00:00:00:00:00:00\n", mac)
+		fmt.Fprintf(w, "    link/loopback %s brd 00:00:00:00:00:00\n", mac)
 		return
 	}
-	fmt.Printf("    link/ether %s brd ff:ff:ff:ff:ff:ff\n", iface.HardwareAddr.String())
+	fmt.Fprintf(w, "    link/ether %s brd ff:ff:ff:ff:ff:ff\n", iface.HardwareAddr.String())
 }
 
 // ipOperState reads the interface's kernel-reported operational state from
@@ -174,8 +209,8 @@ func printIpLinkLine(iface net.Interface) {
 // e.g. a bridge interface can be administratively up but operationally down
 // when it has no carrier. Falls back to a FlagUp-based guess if the sysfs
 // file can't be read (e.g. non-Linux, or an injected test interface).
-func ipOperState(iface net.Interface) string {
-	data, err := os.ReadFile(filepath.Join(ipStatsRoot, iface.Name, "operstate"))
+func ipOperState(roots utils.Roots, iface net.Interface) string {
+	data, err := os.ReadFile(filepath.Join(ipStatsRoot(roots), iface.Name, "operstate"))
 	if err != nil {
 		if iface.Flags&net.FlagUp != 0 {
 			return "UP"
@@ -241,14 +276,14 @@ func ipBroadcastAddr(addr net.Addr) string {
 	return bcast.String()
 }
 
-func readIfaceStats(iface string) map[string]uint64 {
+func readIfaceStats(roots utils.Roots, iface string) map[string]uint64 {
 	keys := []string{
 		"rx_bytes", "rx_packets", "rx_errors", "rx_dropped", "rx_missed_errors", "multicast",
 		"tx_bytes", "tx_packets", "tx_errors", "tx_dropped", "tx_carrier_errors", "collisions",
 	}
 	out := map[string]uint64{}
 	for _, key := range keys {
-		data, err := os.ReadFile(filepath.Join(ipStatsRoot, iface, "statistics", key))
+		data, err := os.ReadFile(filepath.Join(ipStatsRoot(roots), iface, "statistics", key))
 		if err == nil {
 			out[key], _ = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
 		}
@@ -256,65 +291,232 @@ func readIfaceStats(iface string) map[string]uint64 {
 	return out
 }
 
-func ipRoute() error {
-	f, err := ipOpenFile("/proc/net/route")
+// ipSystemInterfaces asks the kernel for gobox's own interfaces, or lists
+// them from SYSROOT/class/net when --sys-root points at another system's
+// sysfs.
+func ipSystemInterfaces(roots utils.Roots) ([]net.Interface, error) {
+	if roots.Sys() == utils.DefaultSysRoot {
+		return net.Interfaces()
+	}
+	entries, err := os.ReadDir(ipStatsRoot(roots))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var ifaces []net.Interface
+	for _, entry := range entries {
+		dir := filepath.Join(ipStatsRoot(roots), entry.Name())
+		read := func(name string) string {
+			data, _ := os.ReadFile(filepath.Join(dir, name))
+			return strings.TrimSpace(string(data))
+		}
+		iface := net.Interface{Name: entry.Name()}
+		iface.Index, _ = strconv.Atoi(read("ifindex"))
+		iface.MTU, _ = strconv.Atoi(read("mtu"))
+		iface.HardwareAddr, _ = net.ParseMAC(read("address"))
+		iface.Flags = sysfsIfaceFlags(read("flags"))
+		if read("carrier") == "1" {
+			iface.Flags |= net.FlagRunning
+		}
+		ifaces = append(ifaces, iface)
 	}
-	defer f.Close()
-	return ipRouteFromReader(f)
+	sort.Slice(ifaces, func(i, j int) bool { return ifaces[i].Index < ifaces[j].Index })
+	return ifaces, nil
+}
+
+// sysfsIfaceFlags converts the kernel IFF_* bits in class/net/IFACE/flags
+// to net.Flags. IFF_RUNNING is not among them; the carrier file stands in.
+func sysfsIfaceFlags(hexFlags string) net.Flags {
+	v, _ := strconv.ParseUint(strings.TrimPrefix(hexFlags, "0x"), 16, 32)
+	var flags net.Flags
+	for bit, flag := range map[uint64]net.Flags{
+		0x1: net.FlagUp, 0x2: net.FlagBroadcast, 0x8: net.FlagLoopback,
+		0x10: net.FlagPointToPoint, 0x1000: net.FlagMulticast,
+	} {
+		if v&bit != 0 {
+			flags |= flag
+		}
+	}
+	return flags
+}
+
+// ipSystemInterfaceAddrs returns iface's addresses. Under an alternate
+// --proc-root they come from the inspected system's network tables instead:
+// IPv6 from if_inet6, IPv4 from the local addresses in fib_trie, with the
+// prefix length of the connected route in route (loopback addresses are
+// /8).
+func ipSystemInterfaceAddrs(roots utils.Roots, iface net.Interface) ([]net.Addr, error) {
+	if roots.Proc() == utils.DefaultProcRoot {
+		return iface.Addrs()
+	}
+	var addrs []net.Addr
+	if f, err := os.Open(roots.ProcNetPath("fib_trie")); err == nil {
+		var networks []*net.IPNet
+		if rf, err := os.Open(roots.ProcNetPath("route")); err == nil {
+			networks = connectedNetworks(rf, iface.Name)
+			rf.Close()
+		}
+		addrs = append(addrs, parseFibTrieAddrs(f, iface.Name, networks)...)
+		f.Close()
+	}
+	if f, err := os.Open(roots.ProcNetPath("if_inet6")); err == nil {
+		addrs = append(addrs, parseIfInet6Addrs(f, iface.Name)...)
+		f.Close()
+	}
+	return addrs, nil
 }
 
-func ipRouteFromReader(r io.Reader) error {
+// connectedNetworks returns the gateway-less routes out of name in a
+// /proc/net/route table.
+func connectedNetworks(r io.Reader, name string) []*net.IPNet {
+	var networks []*net.IPNet
 	scanner := bufio.NewScanner(r)
-	first := true
 	for scanner.Scan() {
-		if first {
-			first = false
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] != name || fields[2] != "00000000" {
 			continue
 		}
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 8 {
+		_, network, err := net.ParseCIDR(parseRouteHex(fields[1]) + "/" + routeMaskPrefixLen(fields[7]))
+		if err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// parseFibTrieAddrs picks the "/32 host LOCAL" leaves of fib_trie's tables
+// that fall in one of name's connected networks.
+func parseFibTrieAddrs(r io.Reader, name string, networks []*net.IPNet) []net.Addr {
+	var addrs []net.Addr
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	leaf := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "|-- ") {
+			leaf = strings.TrimPrefix(line, "|-- ")
 			continue
 		}
-		iface := fields[0]
-		dest := parseRouteHex(fields[1])
-		gw := parseRouteHex(fields[2])
-		mask := routeMaskPrefixLen(fields[7])
-		metric := ""
-		if len(fields) > 6 {
-			if m, err := strconv.ParseUint(fields[6], 10, 64); err == nil && m != 0 {
-				metric = fmt.Sprintf(" metric %d", m)
+		if line != "/32 host LOCAL" || seen[leaf] {
+			continue
+		}
+		seen[leaf] = true
+		ip := net.ParseIP(leaf).To4()
+		if ip == nil {
+			continue
+		}
+		if ip[0] == 127 {
+			if name == "lo" {
+				addrs = append(addrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(8, 32)})
 			}
+			continue
+		}
+		for _, network := range networks {
+			if network.Contains(ip) {
+				addrs = append(addrs, &net.IPNet{IP: ip, Mask: network.Mask})
+				break
+			}
+		}
+	}
+	return addrs
+}
+
+// parseIfInet6Addrs reads name's addresses from if_inet6 lines:
+// "ADDRESS IFINDEX PREFIXLEN SCOPE FLAGS IFNAME".
+func parseIfInet6Addrs(r io.Reader, name string) []net.Addr {
+	var addrs []net.Addr
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[5] != name || len(fields[0]) != 32 {
+			continue
+		}
+		ip := make(net.IP, net.IPv6len)
+		for i := range ip {
+			v, _ := strconv.ParseUint(fields[0][i*2:i*2+2], 16, 8)
+			ip[i] = byte(v)
 		}
-		if fields[1] == "00000000" {
+		prefixLen, _ := strconv.ParseUint(fields[2], 16, 8)
+		addrs = append(addrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(int(prefixLen), 128)})
+	}
+	return addrs
+}
+
+func ipRoute(w io.Writer, roots utils.Roots) error {
+	f, err := ipOpenFile(roots.ProcNetPath("route"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ipRouteFromReader(w, roots, f)
+}
+
+// ipRouteEntry is one /proc/net/route line as ip route reports it. Routes
+// with a gateway are shown as "proto static", directly-connected subnets as
+// "proto kernel scope link".
+type ipRouteEntry struct {
+	dst      string // "default" or DEST/PREFIXLEN
+	gateway  string
+	dev      string
+	src      string
+	metric   uint64
+	linkdown bool
+}
+
+func parseIpRoutes(roots utils.Roots, r io.Reader) ([]ipRouteEntry, error) {
+	parsed, err := netfs.ParseIPv4Routes(r)
+	routes := make([]ipRouteEntry, 0, len(parsed))
+	for _, pr := range parsed {
+		route := ipRouteEntry{dev: pr.Iface, metric: uint64(pr.Metric)}
+		if pr.Destination.Equal(net.IPv4zero) {
 			// A route with a real gateway is (heuristically, since
 			// /proc/net/route carries no explicit "proto" field) always a
 			// manually/DHCP-configured route, matching native ip route's
 			// "proto static" for the default gateway.
-			fmt.Printf("default via %s dev %s proto static%s\n", gw, iface, metric)
+			route.dst = "default"
+			route.gateway = pr.Gateway.String()
+			routes = append(routes, route)
 			continue
 		}
 		// A route with no gateway is a directly-connected subnet route,
 		// which native ip route reports as "proto kernel scope link" with
 		// the interface's own address as "src".
-		line := fmt.Sprintf("%s/%s dev %s proto kernel scope link", dest, mask, iface)
-		if src := ipRouteSrcFor(iface); src != "" {
-			line += " src " + src
+		ones, _ := pr.Mask.Size()
+		route.dst = pr.Destination.String() + "/" + strconv.Itoa(ones)
+		route.src = ipRouteSrcFor(roots, route.dev)
+		route.linkdown = !ipInterfaceIsRunning(roots, route.dev)
+		routes = append(routes, route)
+	}
+	return routes, err
+}
+
+func ipRouteFromReader(w io.Writer, roots utils.Roots, r io.Reader) error {
+	routes, err := parseIpRoutes(roots, r)
+	for _, route := range routes {
+		metric := ""
+		if route.metric != 0 {
+			metric = fmt.Sprintf(" metric %d", route.metric)
+		}
+		if route.gateway != "" {
+			fmt.Fprintf(w, "default via %s dev %s proto static%s\n", route.gateway, route.dev, metric)
+			continue
+		}
+		line := fmt.Sprintf("%s dev %s proto kernel scope link", route.dst, route.dev)
+		if route.src != "" {
+			line += " src " + route.src
 		}
 		line += metric
-		if !ipInterfaceIsRunning(iface) {
+		if route.linkdown {
 			line += " linkdown"
 		}
-		fmt.Println(line)
+		fmt.Fprintln(w, line)
 	}
-	return scanner.Err()
+	return err
 }
 
 // ipRouteSrcFor returns the interface's own IPv4 address, used as the "src"
 // field on a directly-connected route line.
-func ipRouteSrcFor(name string) string {
-	ifaces, err := ipInterfaces()
+func ipRouteSrcFor(roots utils.Roots, name string) string {
+	ifaces, err := ipInterfaces(roots)
 	if err != nil {
 		return ""
 	}
@@ -322,7 +524,7 @@ func ipRouteSrcFor(name string) string {
 		if iface.Name != name {
 			continue
 		}
-		addrs, _ := ipInterfaceAddrs(iface)
+		addrs, _ := ipInterfaceAddrs(roots, iface)
 		for _, addr := range addrs {
 			ipnet, ok := addr.(*net.IPNet)
 			if ok && ipnet.IP.To4() != nil {
@@ -335,8 +537,8 @@ func ipRouteSrcFor(name string) string {
 
 // ipInterfaceIsRunning reports whether the named interface has carrier
 // (kernel IFF_RUNNING), used for the "linkdown" route flag.
-func ipInterfaceIsRunning(name string) bool {
-	ifaces, err := ipInterfaces()
+func ipInterfaceIsRunning(roots utils.Roots, name string) bool {
+	ifaces, err := ipInterfaces(roots)
 	if err != nil {
 		return true
 	}
@@ -380,34 +582,43 @@ func routeMaskPrefixLen(s string) string {
 	return strconv.Itoa(ones)
 }
 
-func ipNeigh() error {
-	f, err := ipOpenFile("/proc/net/arp")
+func ipNeigh(w io.Writer, roots utils.Roots) error {
+	f, err := ipOpenFile(roots.ProcNetPath("arp"))
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	return ipNeighFromReader(f)
+	return ipNeighFromReader(w, f)
 }
 
-func ipNeighFromReader(r io.Reader) error {
-	scanner := bufio.NewScanner(r)
-	first := true
-	var lines []string
-	for scanner.Scan() {
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(scanner.Text())
-		if len(fields) >= 6 {
-			lines = append(lines, fmt.Sprintf("%s dev %s lladdr %s %s", fields[0], fields[5], fields[3], arpFlagsToState(fields[2])))
-		}
-	}
-	sort.Strings(lines)
-	for _, line := range lines {
-		fmt.Println(line)
+type ipNeighEntry struct {
+	addr   string
+	dev    string
+	lladdr string
+	state  string
+}
+
+// parseIpNeighbours reads /proc/net/arp, sorted the way ip neigh prints it.
+func parseIpNeighbours(r io.Reader) ([]ipNeighEntry, error) {
+	arp, err := netfs.ParseARP(r)
+	entries := make([]ipNeighEntry, 0, len(arp))
+	for _, e := range arp {
+		entries = append(entries, ipNeighEntry{addr: e.IP.String(), dev: e.Device, lladdr: e.HWAddr.String(), state: arpFlagsToState(e.Flags)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].line() < entries[j].line() })
+	return entries, err
+}
+
+func (e ipNeighEntry) line() string {
+	return fmt.Sprintf("%s dev %s lladdr %s %s", e.addr, e.dev, e.lladdr, e.state)
+}
+
+func ipNeighFromReader(w io.Writer, r io.Reader) error {
+	entries, err := parseIpNeighbours(r)
+	for _, e := range entries {
+		fmt.Fprintln(w, e.line())
 	}
-	return scanner.Err()
+	return err
 }
 
 // arpFlagsToState maps /proc/net/arp's legacy HW-flags column to the
@@ -417,19 +628,11 @@ func ipNeighFromReader(r io.Reader) error {
 // non-REACHABLE state in practice) can't be distinguished here; this at
 // least stops permanent/static and incomplete entries from being
 // mislabeled as REACHABLE.
-func arpFlagsToState(hexFlags string) string {
-	v, err := strconv.ParseUint(strings.TrimPrefix(hexFlags, "0x"), 16, 32)
-	if err != nil {
-		return "REACHABLE"
-	}
-	const (
-		atfComplete = 0x2
-		atfPerm     = 0x4
-	)
+func arpFlagsToState(flags uint32) string {
 	switch {
-	case v&atfPerm != 0:
+	case flags&netfs.ARPPermanent != 0:
 		return "PERMANENT"
-	case v&atfComplete != 0:
+	case flags&netfs.ARPComplete != 0:
 		return "REACHABLE"
 	default:
 		return "INCOMPLETE"
@@ -450,3 +653,96 @@ func ipAddrScope(ifName string, addr net.Addr) string {
 	}
 	return "global"
 }
+
+// ipAddrTable lists one row per address, the structured form of ip addr.
+func ipAddrTable(roots utils.Roots) (*utils.Table, error) {
+	ifaces, err := ipInterfaces(roots)
+	if err != nil {
+		return nil, err
+	}
+	t := utils.NewTable("ifindex", "ifname", "family", "address", "prefix_len", "broadcast", "scope")
+	for _, iface := range ifaces {
+		addrs, _ := ipInterfaceAddrs(roots, iface)
+		for _, addr := range addrs {
+			family := "inet"
+			if strings.Contains(addr.String(), ":") {
+				family = "inet6"
+			}
+			address, prefixLen := addr.String(), any(nil)
+			if ipnet, ok := addr.(*net.IPNet); ok {
+				ones, _ := ipnet.Mask.Size()
+				address, prefixLen = ipnet.IP.String(), ones
+			}
+			scope := ipAddrScope(iface.Name, addr)
+			var broadcast any
+			if brd := ipBroadcastAddr(addr); brd != "" && scope != "host" {
+				broadcast = brd
+			}
+			t.Append(iface.Index, iface.Name, family, address, prefixLen, broadcast, scope)
+		}
+	}
+	return t, nil
+}
+
+// ipLinkTable is the structured form of ip -s link; the counters are always
+// included.
+func ipLinkTable(roots utils.Roots) (*utils.Table, error) {
+	ifaces, err := ipInterfaces(roots)
+	if err != nil {
+		return nil, err
+	}
+	t := utils.NewTable("ifindex", "ifname", "flags", "mtu", "operstate", "link_type", "address",
+		"rx_bytes", "rx_packets", "rx_errors", "rx_dropped", "tx_bytes", "tx_packets", "tx_errors", "tx_dropped")
+	for _, iface := range ifaces {
+		linkType, address := "ether", iface.HardwareAddr.String()
+		if iface.Flags&net.FlagLoopback != 0 {
+			linkType, address = "loopback", zeroHardwareAddrOr(iface.HardwareAddr, "00:00:00:00:00:00")
+		}
+		s := readIfaceStats(roots, iface.Name)
+		t.Append(iface.Index, iface.Name, ipFlagsString(iface), iface.MTU, ipOperState(roots, iface), linkType, address,
+			s["rx_bytes"], s["rx_packets"], s["rx_errors"], s["rx_dropped"], s["tx_bytes"], s["tx_packets"], s["tx_errors"], s["tx_dropped"])
+	}
+	return t, nil
+}
+
+func ipRouteTable(roots utils.Roots) (*utils.Table, error) {
+	f, err := ipOpenFile(roots.ProcNetPath("route"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	routes, err := parseIpRoutes(roots, f)
+	if err != nil {
+		return nil, err
+	}
+	t := utils.NewTable("dst", "gateway", "dev", "protocol", "scope", "prefsrc", "metric", "linkdown")
+	for _, route := range routes {
+		var gateway, src any
+		protocol, scope := "kernel", "link"
+		if route.gateway != "" {
+			gateway, protocol, scope = route.gateway, "static", "global"
+		}
+		if route.src != "" {
+			src = route.src
+		}
+		t.Append(route.dst, gateway, route.dev, protocol, scope, src, route.metric, route.linkdown)
+	}
+	return t, nil
+}
+
+func ipNeighTable(roots utils.Roots) (*utils.Table, error) {
+	f, err := ipOpenFile(roots.ProcNetPath("arp"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := parseIpNeighbours(f)
+	if err != nil {
+		return nil, err
+	}
+	t := utils.NewTable("dst", "dev", "lladdr", "state")
+	for _, e := range entries {
+		t.Append(e.addr, e.dev, e.lladdr, e.state)
+	}
+	return t, nil
+}
@@ -1,12 +1,17 @@
 package net
 
 import (
+	"bytes"
 	"errors"
 	stdnet "net"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"gobox/internal/testutil"
 )
 
 func TestIpAddrAndLink(t *testing.T) {
@@ -108,6 +113,26 @@ func TestIpRoute(t *testing.T) {
 	}
 }
 
+func TestIpStructuredOutput(t *testing.T) {
+	setupInjectedIP(t)
+	cmd := base.NewCommand("ip", "", ipCmd, base.WithTabularOutput())
+	cases := map[string]string{
+		"addr":  `{"ifindex":7,"ifname":"ut0","family":"inet","address":"192.0.2.10","prefix_len":24,"broadcast":"192.0.2.255","scope":"global"}`,
+		"link":  `"ifindex":7,"ifname":"ut0","flags":"NO-CARRIER,UP","mtu":1500`,
+		"route": `{"dst":"default","gateway":"127.0.0.1","dev":"eth0","protocol":"static","scope":"global","prefsrc":null,"metric":0,"linkdown":false}`,
+		"neigh": `{"dst":"10.0.0.1","dev":"eth0","lladdr":"aa:bb:cc:dd:ee:01","state":"REACHABLE"}`,
+	}
+	for object, want := range cases {
+		var out bytes.Buffer
+		if err := cmd.Run(&base.Invocation{Stdout: &out}, []string{"--output", "ndjson", object}); err != nil {
+			t.Fatalf("ip %s: %v", object, err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("ip %s: expected %s in %q", object, want, out.String())
+		}
+	}
+}
+
 func TestIpRouteAlias(t *testing.T) {
 	setupInjectedIP(t)
 	out, err := captureNetOutput(t, func() error { return IpCmd([]string{"r"}) })
@@ -189,7 +214,7 @@ func TestIpInternalBoundaries(t *testing.T) {
 	if got := parseRouteHex("bad"); got != "0.0.0.0" {
 		t.Fatalf("unexpected invalid route hex parse %q", got)
 	}
-	stats := readIfaceStats("definitely-missing-gobox-ut-iface")
+	stats := readIfaceStats(utils.Roots{}, "definitely-missing-gobox-ut-iface")
 	for key, val := range stats {
 		if val != 0 {
 			t.Fatalf("expected missing iface stat %s to default zero, got %d", key, val)
@@ -199,7 +224,7 @@ func TestIpInternalBoundaries(t *testing.T) {
 
 func TestIpInterfacesFailureReturnsError(t *testing.T) {
 	oldInterfaces, oldAddrs, oldOpenFile, oldStatsRoot := ipInterfaces, ipInterfaceAddrs, ipOpenFile, ipStatsRoot
-	ipInterfaces = func() ([]stdnet.Interface, error) { return nil, errors.New("interfaces unavailable") }
+	ipInterfaces = func(utils.Roots) ([]stdnet.Interface, error) { return nil, errors.New("interfaces unavailable") }
 	t.Cleanup(func() {
 		ipInterfaces, ipInterfaceAddrs, ipOpenFile, ipStatsRoot = oldInterfaces, oldAddrs, oldOpenFile, oldStatsRoot
 	})
@@ -213,11 +238,12 @@ func TestIpInterfacesFailureReturnsError(t *testing.T) {
 
 func TestIpStatsRootMissingDefaultsToZero(t *testing.T) {
 	oldInterfaces, oldAddrs, oldOpenFile, oldStatsRoot := ipInterfaces, ipInterfaceAddrs, ipOpenFile, ipStatsRoot
-	ipStatsRoot = filepath.Join(t.TempDir(), "missing-sys")
+	missing := filepath.Join(t.TempDir(), "missing-sys")
+	ipStatsRoot = func(utils.Roots) string { return missing }
 	t.Cleanup(func() {
 		ipInterfaces, ipInterfaceAddrs, ipOpenFile, ipStatsRoot = oldInterfaces, oldAddrs, oldOpenFile, oldStatsRoot
 	})
-	stats := readIfaceStats("lo")
+	stats := readIfaceStats(utils.Roots{}, "lo")
 	for key, val := range stats {
 		if val != 0 {
 			t.Fatalf("expected missing stat %s to default to zero, got %d", key, val)
@@ -270,13 +296,13 @@ func setupInjectedIP(t *testing.T) {
 	}
 	v4 := &stdnet.IPNet{IP: stdnet.ParseIP("192.0.2.10"), Mask: stdnet.CIDRMask(24, 32)}
 	v6 := &stdnet.IPNet{IP: stdnet.ParseIP("2001:db8::1"), Mask: stdnet.CIDRMask(64, 128)}
-	ipInterfaces = func() ([]stdnet.Interface, error) {
+	ipInterfaces = func(utils.Roots) ([]stdnet.Interface, error) {
 		return []stdnet.Interface{{Index: 7, Name: "ut0", MTU: 1500, Flags: stdnet.FlagUp}}, nil
 	}
-	ipInterfaceAddrs = func(stdnet.Interface) ([]stdnet.Addr, error) {
+	ipInterfaceAddrs = func(utils.Roots, stdnet.Interface) ([]stdnet.Addr, error) {
 		return []stdnet.Addr{v4, v6}, nil
 	}
-	ipStatsRoot = dir
+	ipStatsRoot = func(utils.Roots) string { return dir }
 	ipOpenFile = func(path string) (*os.File, error) {
 		if strings.HasSuffix(path, "/route") {
 			return os.Open(routeFile)
@@ -293,7 +319,7 @@ func setupInjectedIP(t *testing.T) {
 
 func TestIpRouteReaderParsesDefaultRoute(t *testing.T) {
 	out, err := captureNetOutput(t, func() error {
-		return ipRouteFromReader(strings.NewReader("Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT\neth0 00000000 0100007F 0003 0 0 0 00000000 0 0 0\n"))
+		return ipRouteFromReader(os.Stdout, utils.Roots{}, strings.NewReader("Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT\neth0 00000000 0100007F 0003 0 0 0 00000000 0 0 0\n"))
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -305,7 +331,7 @@ func TestIpRouteReaderParsesDefaultRoute(t *testing.T) {
 
 func TestIpNeighReaderSortsRows(t *testing.T) {
 	input := "IP address HW type Flags HW address Mask Device\n10.0.0.2 0x1 0x2 aa:bb:cc:dd:ee:02 * eth0\n10.0.0.1 0x1 0x2 aa:bb:cc:dd:ee:01 * eth0\n"
-	out, err := captureNetOutput(t, func() error { return ipNeighFromReader(strings.NewReader(input)) })
+	out, err := captureNetOutput(t, func() error { return ipNeighFromReader(os.Stdout, strings.NewReader(input)) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -322,7 +348,7 @@ func TestIpNeighReaderMapsArpFlagsToState(t *testing.T) {
 		"10.0.0.1 0x1 0x2 aa:bb:cc:dd:ee:01 * eth0\n" + // complete -> REACHABLE
 		"10.0.0.2 0x1 0x4 aa:bb:cc:dd:ee:02 * eth0\n" + // permanent -> PERMANENT
 		"10.0.0.3 0x1 0x0 aa:bb:cc:dd:ee:03 * eth0\n" // no flags -> INCOMPLETE
-	out, err := captureNetOutput(t, func() error { return ipNeighFromReader(strings.NewReader(input)) })
+	out, err := captureNetOutput(t, func() error { return ipNeighFromReader(os.Stdout, strings.NewReader(input)) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -345,10 +371,10 @@ func TestIpNeighReaderMapsArpFlagsToState(t *testing.T) {
 func TestIpRouteReaderIncludesProtoScopeSrcMetric(t *testing.T) {
 	oldInterfaces, oldAddrs := ipInterfaces, ipInterfaceAddrs
 	t.Cleanup(func() { ipInterfaces, ipInterfaceAddrs = oldInterfaces, oldAddrs })
-	ipInterfaces = func() ([]stdnet.Interface, error) {
+	ipInterfaces = func(utils.Roots) ([]stdnet.Interface, error) {
 		return []stdnet.Interface{{Name: "eth0", Flags: stdnet.FlagUp | stdnet.FlagRunning}}, nil
 	}
-	ipInterfaceAddrs = func(stdnet.Interface) ([]stdnet.Addr, error) {
+	ipInterfaceAddrs = func(utils.Roots, stdnet.Interface) ([]stdnet.Addr, error) {
 		return []stdnet.Addr{&stdnet.IPNet{IP: stdnet.ParseIP("192.168.1.5"), Mask: stdnet.CIDRMask(24, 32)}}, nil
 	}
 	// eth0 00000000 0100007F 0003 0 0 100 00000000 -> default via 127.0.0.1 metric 100
@@ -356,7 +382,7 @@ func TestIpRouteReaderIncludesProtoScopeSrcMetric(t *testing.T) {
 	input := "Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT\n" +
 		"eth0 00000000 0100007F 0003 0 0 100 00000000 0 0 0\n" +
 		"eth0 0001A8C0 00000000 0001 0 0 0 00FFFFFF 0 0 0\n"
-	out, err := captureNetOutput(t, func() error { return ipRouteFromReader(strings.NewReader(input)) })
+	out, err := captureNetOutput(t, func() error { return ipRouteFromReader(os.Stdout, utils.Roots{}, strings.NewReader(input)) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -378,15 +404,15 @@ func TestIpRouteReaderIncludesProtoScopeSrcMetric(t *testing.T) {
 func TestIpRouteReaderMarksLinkdownWhenNotRunning(t *testing.T) {
 	oldInterfaces, oldAddrs := ipInterfaces, ipInterfaceAddrs
 	t.Cleanup(func() { ipInterfaces, ipInterfaceAddrs = oldInterfaces, oldAddrs })
-	ipInterfaces = func() ([]stdnet.Interface, error) {
+	ipInterfaces = func(utils.Roots) ([]stdnet.Interface, error) {
 		return []stdnet.Interface{{Name: "docker0", Flags: stdnet.FlagUp}}, nil // no FlagRunning
 	}
-	ipInterfaceAddrs = func(stdnet.Interface) ([]stdnet.Addr, error) {
+	ipInterfaceAddrs = func(utils.Roots, stdnet.Interface) ([]stdnet.Addr, error) {
 		return []stdnet.Addr{&stdnet.IPNet{IP: stdnet.ParseIP("172.17.0.1"), Mask: stdnet.CIDRMask(16, 32)}}, nil
 	}
 	input := "Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT\n" +
 		"docker0 000011AC 00000000 0001 0 0 0 0000FFFF 0 0 0\n"
-	out, err := captureNetOutput(t, func() error { return ipRouteFromReader(strings.NewReader(input)) })
+	out, err := captureNetOutput(t, func() error { return ipRouteFromReader(os.Stdout, utils.Roots{}, strings.NewReader(input)) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -401,7 +427,7 @@ func TestIpRouteReaderMarksLinkdownWhenNotRunning(t *testing.T) {
 func TestIpRouteMaskUsesCIDRPrefixLength(t *testing.T) {
 	input := "Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT\n" +
 		"docker0 000011AC 00000000 0001 0 0 0 0000FFFF 0 0 0\n"
-	out, err := captureNetOutput(t, func() error { return ipRouteFromReader(strings.NewReader(input)) })
+	out, err := captureNetOutput(t, func() error { return ipRouteFromReader(os.Stdout, utils.Roots{}, strings.NewReader(input)) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -492,8 +518,8 @@ func TestIpOperStateUsesSysfs(t *testing.T) {
 	if err := os.WriteFile(filepath.Join(ifDir, "operstate"), []byte("down\n"), 0o644); err != nil {
 		t.Fatal(err)
 	}
-	ipStatsRoot = dir
-	ipInterfaces = func() ([]stdnet.Interface, error) {
+	ipStatsRoot = func(utils.Roots) string { return dir }
+	ipInterfaces = func(utils.Roots) ([]stdnet.Interface, error) {
 		return []stdnet.Interface{{Name: "br0", Flags: stdnet.FlagUp}}, nil // administratively up
 	}
 	out, err := captureNetOutput(t, func() error { return IpCmd([]string{"link"}) })
@@ -504,3 +530,54 @@ func TestIpOperStateUsesSysfs(t *testing.T) {
 		t.Fatalf("expected operstate-derived \"state DOWN\" despite FlagUp, got %q", out)
 	}
 }
+
+func TestIpReadsSysAndProcRoots(t *testing.T) {
+	sysRoot := testutil.Tree(t, map[string]string{
+		"class/net/lo/ifindex":   "1\n",
+		"class/net/lo/mtu":       "65536\n",
+		"class/net/lo/address":   "00:00:00:00:00:00\n",
+		"class/net/lo/flags":     "0x9\n",
+		"class/net/lo/operstate": "unknown\n",
+		"class/net/lo/carrier":   "1\n",
+		"class/net/eth0/ifindex": "2\n",
+		"class/net/eth0/mtu":     "1500\n",
+		"class/net/eth0/address": "02:42:ac:11:00:02\n",
+		"class/net/eth0/flags":   "0x1003\n",
+		"class/net/eth0/carrier": "1\n",
+	})
+	procRoot := testutil.Tree(t, map[string]string{
+		"1/net/route": "Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT\n" +
+			"eth0 00000000 010011AC 0003 0 0 0 00000000 0 0 0\neth0 000011AC 00000000 0001 0 0 0 0000FFFF 0 0 0\n",
+		"1/net/fib_trie": "Local:\n  +-- 0.0.0.0/0 3 0 5\n     |-- 127.0.0.1\n        /32 host LOCAL\n" +
+			"     |-- 172.17.0.2\n        /32 host LOCAL\n     |-- 172.17.255.255\n        /32 link BROADCAST\n",
+		"1/net/if_inet6": "fe800000000000000042acfffe110002 02 40 20 80     eth0\n",
+	})
+	env := []string{base.SysRootEnv + "=" + sysRoot, base.ProcRootEnv + "=" + procRoot}
+	runIp := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := ipCmd(&base.Invocation{Stdout: &out, Env: env}, args)
+		return out.String(), err
+	}
+
+	out, err := runIp("-o", "addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"1: lo    inet 127.0.0.1/8 scope host lo",
+		"2: eth0    inet 172.17.0.2/16 brd 172.17.255.255 scope global eth0",
+		"2: eth0    inet6 fe80::42:acff:fe11:2/64 scope link",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+	out, err = runIp("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "1: lo: <LOOPBACK,UP,LOWER_UP> mtu 65536 state UNKNOWN\n") ||
+		!strings.Contains(out, "2: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 state UP\n    link/ether 02:42:ac:11:00:02") {
+		t.Fatalf("unexpected links from the sysfs fixture:\n%s", out)
+	}
+}
@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
@@ -32,11 +32,15 @@ func ncShortClassifier(c byte) (defined, takesValue bool) {
 
 // ncCmd implements netcat functionality
 func NcCmd(args []string) error {
-	return NcCmdWithContext(context.Background(), args)
+	return ncCmd(base.Stdio(), args)
 }
 
 // NcCmdWithContext implements netcat functionality and allows tests/callers to cancel listen mode.
 func NcCmdWithContext(ctx context.Context, args []string) error {
+	return ncCmd(base.Stdio().WithContext(ctx), args)
+}
+
+func ncCmd(inv *base.Invocation, args []string) error {
 	var (
 		listenMode     bool
 		zeroIO         bool
@@ -210,7 +214,7 @@ doneFlags:
 	remaining := args[i:]
 
 	if showHelp {
-		printNCHelp(os.Stdout)
+		printNCHelp(inv.Stdout)
 		return nil
 	}
 
@@ -220,7 +224,7 @@ doneFlags:
 			return fmt.Errorf("listen mode requires port")
 		}
 		port := remaining[0]
-		return ncServer(ctx, port, udpMode, zeroIO, verbose, benchMode, blockSize, numericOnly, forceIPv4, forceIPv6)
+		return ncServer(inv, port, udpMode, zeroIO, verbose, benchMode, blockSize, numericOnly, forceIPv4, forceIPv6)
 	}
 
 	// Client mode
@@ -234,11 +238,30 @@ doneFlags:
 		if requestsSet && testDuration != 0 {
 			return fmt.Errorf("nc: -n/--requests and -t/--time are mutually exclusive")
 		}
-		return ncBenchmarkClient(host, port, udpMode, verbose, numericOnly, forceIPv4, forceIPv6,
+		return ncBenchmarkClient(inv.Stdout, inv.Stderr, host, port, udpMode, verbose, numericOnly, forceIPv4, forceIPv6,
 			concurrent, totalRequests, testDuration, reportInterval, int(blockSize), waitSec)
 	}
 
-	return ncClient(host, port, udpMode, zeroIO, verbose, numericOnly, forceIPv4, forceIPv6, waitSec)
+	return ncClient(inv, host, port, udpMode, zeroIO, verbose, numericOnly, forceIPv4, forceIPv6, waitSec)
+}
+
+// ncFlags declares nc's options for shell completion. Its long options only
+// take an attached value (--wait=SEC), so they are listed as plain words.
+var ncFlags = []base.Flag{
+	{Name: "-l", Usage: "listen mode"}, {Name: "--listen", Usage: "listen mode"},
+	{Name: "-z", Usage: "zero I/O mode"}, {Name: "--zero", Usage: "zero I/O mode"},
+	{Name: "-u", Usage: "UDP mode"}, {Name: "--udp", Usage: "UDP mode"},
+	{Name: "-w", TakesValue: true, Usage: "connection timeout in seconds"}, {Name: "--wait", Usage: "connection timeout in seconds (--wait=N)"},
+	{Name: "-v", Usage: "verbose output"}, {Name: "--verbose", Usage: "verbose output"},
+	{Name: "-n", Usage: "skip DNS resolution, or request count with --bench"}, {Name: "--numeric-only", Usage: "skip DNS resolution"},
+	{Name: "-4", Usage: "force IPv4"}, {Name: "-6", Usage: "force IPv6"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+	{Name: "--bench", Usage: "benchmark mode"},
+	{Name: "-c", TakesValue: true, Usage: "concurrent connections"}, {Name: "--concurrent", Usage: "concurrent connections (--concurrent=N)"},
+	{Name: "--requests", Usage: "total requests (--requests=N)"},
+	{Name: "-s", TakesValue: true, Usage: "data block size"}, {Name: "--size", Usage: "data block size (--size=N)"},
+	{Name: "-t", TakesValue: true, Usage: "test duration"}, {Name: "--time", Usage: "test duration (--time=N)"},
+	{Name: "-i", TakesValue: true, Usage: "report interval"}, {Name: "--interval", Usage: "report interval (--interval=N)"},
 }
 
 func printNCHelp(w io.Writer) {
@@ -305,7 +328,7 @@ func parseNCSize(s string) int64 {
 var benchMagic = []byte("GOBENCH\x00")
 
 // ncClient implements basic netcat client
-func ncClient(host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, forceIPv6 bool, waitSec int) error {
+func ncClient(inv *base.Invocation, host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, forceIPv6 bool, waitSec int) error {
 	protocol := "tcp"
 	if udp {
 		protocol = "udp"
@@ -313,9 +336,9 @@ func ncClient(host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, f
 
 	if verbose {
 		if numericOnly {
-			fmt.Printf("(not resolving host) %s %s\n", protocol, net.JoinHostPort(host, port))
+			fmt.Fprintf(inv.Stdout, "(not resolving host) %s %s\n", protocol, net.JoinHostPort(host, port))
 		} else {
-			fmt.Printf("%s %s\n", protocol, net.JoinHostPort(host, port))
+			fmt.Fprintf(inv.Stdout, "%s %s\n", protocol, net.JoinHostPort(host, port))
 		}
 	}
 
@@ -347,7 +370,7 @@ func ncClient(host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, f
 
 	if zeroIO {
 		if verbose {
-			fmt.Println("Connection successful")
+			fmt.Fprintln(inv.Stdout, "Connection successful")
 		}
 		return nil
 	}
@@ -355,7 +378,7 @@ func ncClient(host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, f
 	// Copy stdin to connection
 	done := make(chan error, 1)
 	go func() {
-		_, err := io.Copy(conn, os.Stdin)
+		_, err := io.Copy(conn, inv.Stdin)
 		done <- err
 	}()
 
@@ -367,7 +390,7 @@ func ncClient(host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, f
 	}
 
 	// Copy connection to stdout
-	_, err = io.Copy(os.Stdout, conn)
+	_, err = io.Copy(inv.Stdout, conn)
 	if err != nil {
 		if ne, ok := err.(net.Error); ok && ne.Timeout() {
 			return nil
@@ -387,7 +410,8 @@ func ncClient(host, port string, udp, zeroIO, verbose, numericOnly, forceIPv4, f
 }
 
 // ncServer implements netcat server/listen mode
-func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode bool, blockSize int64, numericOnly, forceIPv4, forceIPv6 bool) error {
+func ncServer(inv *base.Invocation, port string, udp, zeroIO, verbose, benchMode bool, blockSize int64, numericOnly, forceIPv4, forceIPv6 bool) error {
+	ctx := inv.Ctx()
 	network := "tcp"
 	if udp {
 		network = "udp"
@@ -406,12 +430,8 @@ func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode
 
 	addr := net.JoinHostPort("", port)
 
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
 	if udp {
-		return ncUDPServer(ctx, network, addr, port, zeroIO, verbose)
+		return ncUDPServer(inv, network, addr, port, zeroIO, verbose)
 	}
 
 	listener, err := net.Listen(network, addr)
@@ -421,7 +441,7 @@ func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode
 	defer listener.Close()
 
 	if verbose {
-		fmt.Printf("Listening on port %s\n", port)
+		fmt.Fprintf(inv.Stdout, "Listening on port %s\n", port)
 	}
 
 	done := make(chan struct{})
@@ -435,7 +455,7 @@ func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode
 	}()
 
 	if benchMode {
-		return ncBenchServer(listener, udp, blockSize, verbose)
+		return ncBenchServer(inv.Stdout, listener, udp, blockSize, verbose)
 	}
 
 	conn, err := listener.Accept()
@@ -448,7 +468,7 @@ func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode
 	defer conn.Close()
 
 	if verbose {
-		fmt.Printf("Connection from %s\n", conn.RemoteAddr().String())
+		fmt.Fprintf(inv.Stdout, "Connection from %s\n", conn.RemoteAddr().String())
 	}
 
 	if zeroIO {
@@ -457,14 +477,14 @@ func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode
 
 	stdinDone := make(chan error, 1)
 	go func() {
-		_, err := io.Copy(conn, os.Stdin)
+		_, err := io.Copy(conn, inv.Stdin)
 		if tcpConn, ok := conn.(*net.TCPConn); ok {
 			_ = tcpConn.CloseWrite()
 		}
 		stdinDone <- err
 	}()
 
-	_, err = io.Copy(os.Stdout, conn)
+	_, err = io.Copy(inv.Stdout, conn)
 	if err != nil {
 		return err
 	}
@@ -483,7 +503,8 @@ func ncServer(ctx context.Context, port string, udp, zeroIO, verbose, benchMode
 // concept in net.Listen, so it must use net.ListenPacket (or net.ListenUDP)
 // instead of net.Listen("udp", ...), which is not supported and returns an
 // "unexpected address type" error.
-func ncUDPServer(ctx context.Context, network, addr, port string, zeroIO, verbose bool) error {
+func ncUDPServer(inv *base.Invocation, network, addr, port string, zeroIO, verbose bool) error {
+	ctx := inv.Ctx()
 	pc, err := net.ListenPacket(network, addr)
 	if err != nil {
 		return fmt.Errorf("listen failed: %w", err)
@@ -491,7 +512,7 @@ func ncUDPServer(ctx context.Context, network, addr, port string, zeroIO, verbos
 	defer pc.Close()
 
 	if verbose {
-		fmt.Printf("Listening on port %s (udp)\n", port)
+		fmt.Fprintf(inv.Stdout, "Listening on port %s (udp)\n", port)
 	}
 
 	done := make(chan struct{})
@@ -514,7 +535,7 @@ func ncUDPServer(ctx context.Context, network, addr, port string, zeroIO, verbos
 	}
 
 	if verbose {
-		fmt.Printf("Connection from %s\n", raddr.String())
+		fmt.Fprintf(inv.Stdout, "Connection from %s\n", raddr.String())
 	}
 
 	if zeroIO {
@@ -522,14 +543,14 @@ func ncUDPServer(ctx context.Context, network, addr, port string, zeroIO, verbos
 	}
 
 	if n > 0 {
-		os.Stdout.Write(buf[:n])
+		inv.Stdout.Write(buf[:n])
 	}
 
 	stdinDone := make(chan error, 1)
 	go func() {
 		sbuf := make([]byte, 65535)
 		for {
-			m, rerr := os.Stdin.Read(sbuf)
+			m, rerr := inv.Stdin.Read(sbuf)
 			if m > 0 {
 				if _, werr := pc.WriteTo(sbuf[:m], raddr); werr != nil {
 					stdinDone <- werr
@@ -551,7 +572,7 @@ func ncUDPServer(ctx context.Context, network, addr, port string, zeroIO, verbos
 		_ = pc.SetReadDeadline(time.Now().Add(time.Second))
 		n, _, rerr := pc.ReadFrom(buf)
 		if n > 0 {
-			os.Stdout.Write(buf[:n])
+			inv.Stdout.Write(buf[:n])
 		}
 		if rerr != nil {
 			if ne, ok := rerr.(net.Error); ok && ne.Timeout() {
@@ -579,7 +600,7 @@ func ncUDPServer(ctx context.Context, network, addr, port string, zeroIO, verbos
 }
 
 // ncBenchServer implements benchmark server mode
-func ncBenchServer(listener net.Listener, udp bool, blockSize int64, verbose bool) error {
+func ncBenchServer(w io.Writer, listener net.Listener, udp bool, blockSize int64, verbose bool) error {
 	if listener.Addr().Network() != "udp" {
 		// TCP listener
 		for {
@@ -588,13 +609,13 @@ func ncBenchServer(listener net.Listener, udp bool, blockSize int64, verbose boo
 				return err
 			}
 
-			go handleBenchConnection(conn, blockSize, verbose)
+			go handleBenchConnection(w, conn, blockSize, verbose)
 		}
 	}
 	return nil
 }
 
-func handleBenchConnection(conn net.Conn, blockSize int64, verbose bool) {
+func handleBenchConnection(w io.Writer, conn net.Conn, blockSize int64, verbose bool) {
 	defer conn.Close()
 
 	// Read magic header
@@ -626,7 +647,7 @@ func handleBenchConnection(conn net.Conn, blockSize int64, verbose bool) {
 		if err != nil {
 			if verbose {
 				duration := time.Since(startTime)
-				fmt.Printf("Benchmark complete: %d bytes in %v (%.2f MB/s)\n",
+				fmt.Fprintf(w, "Benchmark complete: %d bytes in %v (%.2f MB/s)\n",
 					totalBytes, duration.Round(time.Second),
 					float64(totalBytes)/1024/1024/duration.Seconds())
 			}
@@ -638,7 +659,7 @@ func handleBenchConnection(conn net.Conn, blockSize int64, verbose bool) {
 		if now.Sub(lastReport).Seconds() >= float64(reportInterval) {
 			duration := now.Sub(startTime)
 			if verbose {
-				fmt.Printf("Transfer: %d bytes in %v (%.2f MB/s)\n",
+				fmt.Fprintf(w, "Transfer: %d bytes in %v (%.2f MB/s)\n",
 					totalBytes, duration.Round(time.Second),
 					float64(totalBytes)/1024/1024/duration.Seconds())
 			}
@@ -648,7 +669,7 @@ func handleBenchConnection(conn net.Conn, blockSize int64, verbose bool) {
 }
 
 // ncBenchmarkClient implements benchmark client mode
-func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4, forceIPv6 bool,
+func ncBenchmarkClient(w, stderr io.Writer, host, port string, udp, verbose, numericOnly, forceIPv4, forceIPv6 bool,
 	concurrent, totalRequests, testDuration, reportInterval, blockSize int, waitSec int) error {
 
 	network := "tcp"
@@ -673,7 +694,7 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 	}
 
 	if verbose {
-		fmt.Printf("Connecting to %s:%s\n", host, port)
+		fmt.Fprintf(w, "Connecting to %s:%s\n", host, port)
 	}
 
 	// Use time duration if specified
@@ -713,7 +734,7 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 			if err != nil {
 				atomic.AddInt32(&connectionErrors, 1)
 				if verbose {
-					fmt.Printf("[%2d] connection failed: %v\n", connId+1, err)
+					fmt.Fprintf(w, "[%2d] connection failed: %v\n", connId+1, err)
 				}
 				return
 			}
@@ -732,7 +753,7 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 			conn.Write(benchMagic)
 
 			if verbose {
-				fmt.Printf("[%2d] local=%s port=%d connected\n",
+				fmt.Fprintf(w, "[%2d] local=%s port=%d connected\n",
 					connId+1, conn.LocalAddr().String(), 0)
 			}
 
@@ -778,10 +799,10 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 	}
 
 	// Print header
-	fmt.Println()
-	fmt.Printf("Connecting to %s\n", addr)
-	fmt.Println("[ ID] Interval       Transfer     Bandwidth")
-	fmt.Println()
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Connecting to %s\n", addr)
+	fmt.Fprintln(w, "[ ID] Interval       Transfer     Bandwidth")
+	fmt.Fprintln(w)
 
 	// Report timer — use ticker directly in the select to avoid race with time.After.
 	ticker := time.NewTicker(intervalDuration)
@@ -816,7 +837,7 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 			transferStr := formatBytes(totalBytes)
 			bandwidthStr := formatBandwidth(bytesPerSec)
 
-			fmt.Printf("%s  %-12s  %-12s\n", intervalStr, transferStr, bandwidthStr)
+			fmt.Fprintf(w, "%s  %-12s  %-12s\n", intervalStr, transferStr, bandwidthStr)
 
 			oldTotalBytes = totalBytes
 			oldTotalReqs = totalRequestsCompleted
@@ -832,7 +853,7 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 				intervalStr := fmt.Sprintf("[%2d] %.1f-%.1fs", reportNum, 0.0, tailDuration.Seconds())
 				transferStr := formatBytes(totalBytes)
 				bandwidthStr := formatBandwidth(bytesPerSec)
-				fmt.Printf("%s  %-12s  %-12s\n", intervalStr, transferStr, bandwidthStr)
+				fmt.Fprintf(w, "%s  %-12s  %-12s\n", intervalStr, transferStr, bandwidthStr)
 			}
 			finished = true
 		}
@@ -841,7 +862,7 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 	duration := time.Since(startTime)
 
 	// Print final stats
-	fmt.Println()
+	fmt.Fprintln(w)
 	if len(latencyList) > 0 {
 		minLat := latencyList[0]
 		maxLat := latencyList[0]
@@ -857,24 +878,24 @@ func ncBenchmarkClient(host, port string, udp, verbose, numericOnly, forceIPv4,
 		}
 		meanLat := sumLat / float64(len(latencyList))
 
-		fmt.Printf("Latency: min=%.1fms, max=%.1fms, mean=%.1fms\n",
+		fmt.Fprintf(w, "Latency: min=%.1fms, max=%.1fms, mean=%.1fms\n",
 			minLat, maxLat, meanLat)
 	}
 
-	fmt.Printf("Total: %.1fs, %s, %.2fMbps\n",
+	fmt.Fprintf(w, "Total: %.1fs, %s, %.2fMbps\n",
 		duration.Seconds(),
 		formatBytes(totalBytes),
 		float64(totalBytes)*8/1024/1024/duration.Seconds())
 
 	if connectionErrors > 0 {
-		fmt.Printf("Connection errors: %d\n", connectionErrors)
+		fmt.Fprintf(w, "Connection errors: %d\n", connectionErrors)
 	}
 
 	// A benchmark that moved no data almost always means the peer does not speak
 	// gobox's private echo protocol (e.g. a plain `nc -l` sink or an HTTP server).
 	// Point the user at the matching server instead of leaving a silent zero.
 	if totalBytes == 0 {
-		fmt.Fprintln(os.Stderr, "nc: no data transferred - nc --bench requires a matching server started with 'gobox nc -l --bench PORT'")
+		fmt.Fprintln(stderr, "nc: no data transferred - nc --bench requires a matching server started with 'gobox nc -l --bench PORT'")
 	}
 
 	return nil
@@ -823,7 +823,7 @@ func TestNCBenchmarkServer(t *testing.T) {
 
 	done := make(chan error, 1)
 	go func() {
-		done <- ncBenchServer(ln, false, 64*1024, false)
+		done <- ncBenchServer(io.Discard, ln, false, 64*1024, false)
 	}()
 
 	port := ln.Addr().(*net.TCPAddr).Port
@@ -847,7 +847,7 @@ func TestNCBenchmarkClient(t *testing.T) {
 	defer ln.Close()
 
 	go func() {
-		_ = ncBenchServer(ln, false, 64*1024, false)
+		_ = ncBenchServer(io.Discard, ln, false, 64*1024, false)
 	}()
 
 	port := ln.Addr().(*net.TCPAddr).Port
@@ -855,7 +855,7 @@ func TestNCBenchmarkClient(t *testing.T) {
 		t.Fatalf("Benchmark server did not start listening on port %d", port)
 	}
 
-	err = ncBenchmarkClient("127.0.0.1", strconv.Itoa(port), false, false, false, false, false, 1, 1, 0, 1, 1, 1)
+	err = ncBenchmarkClient(io.Discard, io.Discard, "127.0.0.1", strconv.Itoa(port), false, false, false, false, false, 1, 1, 0, 1, 1, 1)
 	if err != nil {
 		t.Fatalf("Benchmark client failed: %v", err)
 	}
@@ -873,7 +873,7 @@ func TestNCBenchRequestsAndTimeAreMutuallyExclusive(t *testing.T) {
 		t.Fatalf("Failed to start listener: %v", err)
 	}
 	defer ln.Close()
-	go func() { _ = ncBenchServer(ln, false, 64*1024, false) }()
+	go func() { _ = ncBenchServer(io.Discard, ln, false, 64*1024, false) }()
 	port := ln.Addr().(*net.TCPAddr).Port
 	if !waitForPort(port, time.Second) {
 		t.Fatalf("server did not start listening on port %d", port)
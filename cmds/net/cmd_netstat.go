@@ -2,26 +2,31 @@ package net
 
 import (
 	"bufio"
-	"encoding/hex"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"gobox/pkg/netfs"
+	"io"
 	"net"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 )
 
 var parseProcNetDevNetstat = parseProcNetDev
 
 func NetstatCmd(args []string) error {
+	return netstatCmd(base.Stdio(), args)
+}
+
+func netstatCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("netstat", flag.ContinueOnError)
 	stateFilter := fsFlags.String("state", "", "filter by connection state (comma-separated, e.g., LISTEN,ESTABLISHED)")
 	portFilter := fsFlags.Int("port", 0, "filter by local or remote port")
@@ -56,38 +61,40 @@ func NetstatCmd(args []string) error {
 	timersLong := fsFlags.Bool("timers", false, "show TCP timer information")
 	wide := fsFlags.Bool("W", false, "wide output (accepted; gobox does not truncate addresses)")
 	wideLong := fsFlags.Bool("wide", false, "wide output (accepted; gobox does not truncate addresses)")
+	color := utils.AddColorFlag(fsFlags)
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox netstat [OPTION]...")
-		fmt.Fprintln(os.Stderr, "Print network connection statistics (Linux /proc/net/tcp*, /proc/net/udp*, /proc/net/unix).")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Filters:")
-		fmt.Fprintln(os.Stderr, "  -t, --tcp           show TCP sockets only")
-		fmt.Fprintln(os.Stderr, "  -u, --udp           show UDP sockets only")
-		fmt.Fprintln(os.Stderr, "  -x, --unix          show Unix domain sockets only")
-		fmt.Fprintln(os.Stderr, "  -l, --listening     show listening sockets only")
-		fmt.Fprintln(os.Stderr, "  -4                  show IPv4 sockets only")
-		fmt.Fprintln(os.Stderr, "  -6                  show IPv6 sockets only")
-		fmt.Fprintln(os.Stderr, "      --state STATES  filter by connection state list")
-		fmt.Fprintln(os.Stderr, "      --port PORT     filter by local or remote port")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Output:")
-		fmt.Fprintln(os.Stderr, "  -p, --programs      show PID/Program column")
-		fmt.Fprintln(os.Stderr, "  -e, --extend        show extended socket information")
-		fmt.Fprintln(os.Stderr, "  -o, --timers        show TCP timer information")
-		fmt.Fprintln(os.Stderr, "  -n, --numeric       keep numeric address/port output (default gobox view is already numeric)")
-		fmt.Fprintln(os.Stderr, "  -W, --wide          keep wide output; gobox does not truncate addresses by default")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Views:")
-		fmt.Fprintln(os.Stderr, "  -r, --route         show routing table")
-		fmt.Fprintln(os.Stderr, "  -i, --interfaces    show network interfaces")
-		fmt.Fprintln(os.Stderr, "  -s, --statistics    show protocol statistics")
-		fmt.Fprintln(os.Stderr, "  -c, --continuous    refresh output continuously")
-		fmt.Fprintln(os.Stderr, "  -a, --all           accepted for compatibility; currently matches the default socket selection")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Sorting:")
-		fmt.Fprintln(os.Stderr, "      --sort FIELD    sort by recvq|sendq|local|remote|pid")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "  -h, --help          show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox netstat [OPTION]...")
+		fmt.Fprintln(inv.Stderr, "Print network connection statistics (Linux /proc/net/tcp*, /proc/net/udp*, /proc/net/unix).")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Filters:")
+		fmt.Fprintln(inv.Stderr, "  -t, --tcp           show TCP sockets only")
+		fmt.Fprintln(inv.Stderr, "  -u, --udp           show UDP sockets only")
+		fmt.Fprintln(inv.Stderr, "  -x, --unix          show Unix domain sockets only")
+		fmt.Fprintln(inv.Stderr, "  -l, --listening     show listening sockets only")
+		fmt.Fprintln(inv.Stderr, "  -4                  show IPv4 sockets only")
+		fmt.Fprintln(inv.Stderr, "  -6                  show IPv6 sockets only")
+		fmt.Fprintln(inv.Stderr, "      --state STATES  filter by connection state list")
+		fmt.Fprintln(inv.Stderr, "      --port PORT     filter by local or remote port")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Output:")
+		fmt.Fprintln(inv.Stderr, "  -p, --programs      show PID/Program column")
+		fmt.Fprintln(inv.Stderr, "  -e, --extend        show extended socket information")
+		fmt.Fprintln(inv.Stderr, "  -o, --timers        show TCP timer information")
+		fmt.Fprintln(inv.Stderr, "  -n, --numeric       keep numeric address/port output (default gobox view is already numeric)")
+		fmt.Fprintln(inv.Stderr, "  -W, --wide          keep wide output; gobox does not truncate addresses by default")
+		fmt.Fprintln(inv.Stderr, "      --color[=WHEN]  color the State column: auto (default), always or never")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Views:")
+		fmt.Fprintln(inv.Stderr, "  -r, --route         show routing table")
+		fmt.Fprintln(inv.Stderr, "  -i, --interfaces    show network interfaces")
+		fmt.Fprintln(inv.Stderr, "  -s, --statistics    show protocol statistics")
+		fmt.Fprintln(inv.Stderr, "  -c, --continuous    refresh output continuously")
+		fmt.Fprintln(inv.Stderr, "  -a, --all           accepted for compatibility; currently matches the default socket selection")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Sorting:")
+		fmt.Fprintln(inv.Stderr, "      --sort FIELD    sort by recvq|sendq|local|remote|pid")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "  -h, --help          show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, normalizeNetstatArgs(args)); err != nil {
 		if err == flag.ErrHelp {
@@ -145,71 +152,86 @@ func NetstatCmd(args []string) error {
 		return errors.New("netstat: supported only on Linux in this implementation")
 	}
 
+	var tw *utils.TableWriter
+	if utils.IsStructuredOutput(inv.Output) {
+		if *routeTable || *interfaces || *statistics {
+			return fmt.Errorf("netstat: --output %s supports only the socket listing", inv.Output)
+		}
+		tw = utils.NewTableWriter(inv.Stdout, inv.Output)
+	}
+	colorizer := utils.NewColorizer(inv.Stdout, color.Mode, inv.Getenv)
+
 	render := func() error {
 		if *routeTable || *interfaces || *statistics {
 			first := true
 			if *routeTable {
-				if err := printNetstatRoutes(); err != nil {
+				if err := printNetstatRoutes(inv.Stdout, inv.Roots()); err != nil {
 					return err
 				}
 				first = false
 			}
 			if *interfaces {
 				if !first {
-					fmt.Println()
+					fmt.Fprintln(inv.Stdout)
 				}
-				if err := printNetstatInterfaces(*extended); err != nil {
+				if err := printNetstatInterfaces(inv.Stdout, inv.Roots(), *extended); err != nil {
 					return err
 				}
 				first = false
 			}
 			if *statistics {
 				if !first {
-					fmt.Println()
+					fmt.Fprintln(inv.Stdout)
 				}
-				if err := printNetstatStats(*tcpOnly, *udpOnly, *unixOnly, *ipv4Only, *ipv6Only); err != nil {
+				if err := printNetstatStats(inv.Stdout, inv.Roots(), *tcpOnly, *udpOnly, *unixOnly, *ipv4Only, *ipv6Only); err != nil {
 					return err
 				}
 			}
 			return nil
 		}
-		return printNetstatSockets(*allSockets, *tcpOnly, *udpOnly, *unixOnly, *listeningOnly, *numericOnly, *ipv4Only, *ipv6Only, *extended, *timers, *programs, *wide, *stateFilter, *portFilter, *sortBy)
+		return printNetstatSockets(inv.Stdout, inv.Roots(), tw, colorizer, *allSockets, *tcpOnly, *udpOnly, *unixOnly, *listeningOnly, *numericOnly, *ipv4Only, *ipv6Only, *extended, *timers, *programs, *wide, *stateFilter, *portFilter, *sortBy)
 	}
 
 	if *continuous {
-		return runNetstatContinuous(render)
+		sep := inv.Stdout
+		if tw != nil {
+			sep = io.Discard
+		}
+		return runNetstatContinuous(inv.Ctx(), sep, render)
 	}
 	return render()
 }
 
-func printNetstatSockets(allSockets, tcpOnly, udpOnly, unixOnly, listeningOnly, numericOnly, ipv4Only, ipv6Only, extended, timers, programs, wide bool, stateFilter string, portFilter int, sortBy string) error {
+// printNetstatSockets prints the socket listing, or writes it to tw as one
+// structured table when tw is non-nil.
+func printNetstatSockets(w io.Writer, roots utils.Roots, tw *utils.TableWriter, c *utils.Colorizer, allSockets, tcpOnly, udpOnly, unixOnly, listeningOnly, numericOnly, ipv4Only, ipv6Only, extended, timers, programs, wide bool, stateFilter string, portFilter int, sortBy string) error {
 	_ = allSockets
 	_ = numericOnly
 	_ = wide
 	// Parse tcp/udp tables
 	conns := make([]tcpConn, 0)
 	if !unixOnly && !ipv6Only {
-		if cs, err := parseProcNetTCP("/proc/net/tcp", "TCP"); err == nil {
+		if cs, err := parseProcNetTCP(roots.ProcNetPath("tcp"), "TCP"); err == nil {
 			conns = append(conns, cs...)
 		}
 	}
 	if !unixOnly && !ipv4Only {
-		if cs, err := parseProcNetTCP("/proc/net/tcp6", "TCP6"); err == nil {
+		if cs, err := parseProcNetTCP(roots.ProcNetPath("tcp6"), "TCP6"); err == nil {
 			conns = append(conns, cs...)
 		}
 	}
 	if !unixOnly && !ipv6Only {
-		if cs, err := parseProcNetUDP("/proc/net/udp", "UDP"); err == nil {
+		if cs, err := parseProcNetUDP(roots.ProcNetPath("udp"), "UDP"); err == nil {
 			conns = append(conns, cs...)
 		}
 	}
 	if !unixOnly && !ipv4Only {
-		if cs, err := parseProcNetUDP("/proc/net/udp6", "UDP6"); err == nil {
+		if cs, err := parseProcNetUDP(roots.ProcNetPath("udp6"), "UDP6"); err == nil {
 			conns = append(conns, cs...)
 		}
 	}
 	if unixOnly || (!tcpOnly && !udpOnly && !ipv4Only && !ipv6Only) {
-		if cs, err := parseProcNetUnix("/proc/net/unix"); err == nil {
+		if cs, err := parseProcNetUnix(roots.ProcNetPath("unix")); err == nil {
 			conns = append(conns, cs...)
 		}
 	}
@@ -236,7 +258,7 @@ func printNetstatSockets(allSockets, tcpOnly, udpOnly, unixOnly, listeningOnly,
 	var inodeToPid map[string]int
 	var pidName map[string]string
 	if netstatNeedsInodePidMap(programs, sortBy) {
-		inodeToPid, pidName = buildInodePidMap()
+		inodeToPid, pidName = buildInodePidMap(roots)
 	}
 
 	// Apply filtering by state and port
@@ -331,25 +353,33 @@ func printNetstatSockets(allSockets, tcpOnly, udpOnly, unixOnly, listeningOnly,
 			inetRows = append(inetRows, row)
 		}
 	}
+	if tw != nil {
+		return tw.Write(netstatTable(append(inetRows, unixRows...)))
+	}
 	if len(inetRows) > 0 {
-		printNetstatTable(inetRows, extended, timers, programs)
+		printNetstatTable(w, c, inetRows, extended, timers, programs)
 	}
 	if len(unixRows) > 0 {
 		if len(inetRows) > 0 {
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
-		printNetstatTable(unixRows, extended, timers, programs)
+		printNetstatTable(w, c, unixRows, extended, timers, programs)
 	}
 	return nil
 }
 
+// netstatSortKeys are the --sort values offered by shell completion.
+func netstatSortKeys() []string {
+	return []string{"recvq", "sendq", "local", "remote", "pid"}
+}
+
 func normalizeNetstatArgs(args []string) []string {
 	knownWordFlags := map[string]bool{
 		"state": true, "port": true, "sort": true,
 		"all": true, "tcp": true, "udp": true, "unix": true, "listening": true,
 		"numeric": true, "programs": true, "route": true, "interfaces": true,
 		"statistics": true, "continuous": true, "extend": true, "timers": true,
-		"wide": true,
+		"wide": true, "color": true,
 	}
 	boolShort := "atulnpriscexoW46"
 	out := make([]string, 0, len(args))
@@ -377,20 +407,18 @@ func normalizeNetstatArgs(args []string) []string {
 	return out
 }
 
-func runNetstatContinuous(render func() error) error {
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(sigCh)
-
+// runNetstatContinuous re-renders every second until ctx is cancelled, which
+// main does on SIGINT/SIGTERM.
+func runNetstatContinuous(ctx context.Context, w io.Writer, render func() error) error {
 	for {
 		if err := render(); err != nil {
 			return err
 		}
 		select {
-		case <-sigCh:
+		case <-ctx.Done():
 			return nil
 		case <-time.After(time.Second):
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
 	}
 }
@@ -403,7 +431,48 @@ type netstatSocketRow struct {
 	pidProgram string
 }
 
-func printNetstatTable(rows []netstatSocketRow, extended, timers, programs bool) {
+// netstatTable is the structured socket listing. Every column is always
+// present; pid and program are null unless -p (or --sort pid) resolved them.
+func netstatTable(rows []netstatSocketRow) *utils.Table {
+	t := utils.NewTable("proto", "recv_q", "send_q", "local_address", "local_port", "remote_address", "remote_port", "state", "uid", "inode", "pid", "program", "timer")
+	for _, row := range rows {
+		c := row.conn
+		var pid, program any
+		if p, name, ok := strings.Cut(row.pidProgram, "/"); ok && p != "-" {
+			pid = netstatNumber(p)
+			program = name
+		}
+		var localPort, remotePort any
+		if row.proto != "UNIX" {
+			localPort, remotePort = c.LocalPort, c.RemotePort
+		}
+		var timer any
+		if c.Timer != "" {
+			timer = c.Timer
+		}
+		t.Append(row.proto, c.RxQueue, c.TxQueue, netstatText(c.LocalIP), localPort, netstatText(c.RemoteIP), remotePort, c.State, netstatNumber(c.UID), netstatNumber(c.Inode), pid, program, timer)
+	}
+	return t
+}
+
+// netstatText returns s, or nil for the "-" placeholder the text table uses.
+func netstatText(s string) any {
+	if s == "" || s == "-" {
+		return nil
+	}
+	return s
+}
+
+// netstatNumber returns s as an integer, or nil when it is empty or "-".
+func netstatNumber(s string) any {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+func printNetstatTable(w io.Writer, c *utils.Colorizer, rows []netstatSocketRow, extended, timers, programs bool) {
 	recvWidth := len("Recv-Q")
 	sendWidth := len("Send-Q")
 	protoWidth := len("Proto")
@@ -448,33 +517,52 @@ func printNetstatTable(rows []netstatSocketRow, extended, timers, programs bool)
 		}
 	}
 
-	fmt.Printf("%*s %*s %-*s %-*s %-*s %-*s", recvWidth, "Recv-Q", sendWidth, "Send-Q", protoWidth, "Proto", localWidth, "LocalAddress", remoteWidth, "RemoteAddress", stateWidth, "State")
+	fmt.Fprintf(w, "%*s %*s %-*s %-*s %-*s %-*s", recvWidth, "Recv-Q", sendWidth, "Send-Q", protoWidth, "Proto", localWidth, "LocalAddress", remoteWidth, "RemoteAddress", stateWidth, "State")
 	if programs {
-		fmt.Printf(" %-*s", pidProgramWidth, "PID/Program")
+		fmt.Fprintf(w, " %-*s", pidProgramWidth, "PID/Program")
 	}
 	if extended {
-		fmt.Printf(" %-*s %-*s", userWidth, "User", inodeWidth, "Inode")
+		fmt.Fprintf(w, " %-*s %-*s", userWidth, "User", inodeWidth, "Inode")
 	}
 	if timers {
-		fmt.Printf(" %-*s", timerWidth, "Timer")
+		fmt.Fprintf(w, " %-*s", timerWidth, "Timer")
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	for _, row := range rows {
-		fmt.Printf("%*d %*d %-*s %-*s %-*s %-*s", recvWidth, row.conn.RxQueue, sendWidth, row.conn.TxQueue, protoWidth, row.proto, localWidth, row.local, remoteWidth, row.remote, stateWidth, row.conn.State)
+		state := c.PaintField(netstatStateRole(row.conn.State), fmt.Sprintf("%-*s", stateWidth, row.conn.State))
+		fmt.Fprintf(w, "%*d %*d %-*s %-*s %-*s %s", recvWidth, row.conn.RxQueue, sendWidth, row.conn.TxQueue, protoWidth, row.proto, localWidth, row.local, remoteWidth, row.remote, state)
 		if programs {
-			fmt.Printf(" %-*s", pidProgramWidth, row.pidProgram)
+			fmt.Fprintf(w, " %-*s", pidProgramWidth, row.pidProgram)
 		}
 		if extended {
-			fmt.Printf(" %-*s %-*s", userWidth, row.conn.UID, inodeWidth, row.conn.Inode)
+			fmt.Fprintf(w, " %-*s %-*s", userWidth, row.conn.UID, inodeWidth, row.conn.Inode)
 		}
 		if timers {
-			fmt.Printf(" %-*s", timerWidth, row.conn.Timer)
+			fmt.Fprintf(w, " %-*s", timerWidth, row.conn.Timer)
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
 
+// netstatStateRole picks the color for a socket state: established and
+// connected sockets are healthy, listeners stand apart, CLOSE_WAIT (the
+// application never closed its end) is critical and the other transitional
+// TCP states are warnings.
+func netstatStateRole(state string) string {
+	switch state {
+	case "ESTABLISHED", "CONNECTED":
+		return utils.ColorOK
+	case "LISTEN", "LISTENING":
+		return utils.ColorListen
+	case "CLOSE_WAIT":
+		return utils.ColorCritical
+	case "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT", "CLOSING", "LAST_ACK":
+		return utils.ColorWarn
+	}
+	return ""
+}
+
 func formatNetstatAddress(addr string, port int) string {
 	if port == 0 {
 		if addr == "" {
@@ -538,23 +626,23 @@ type netstatStatSection struct {
 	Fields []string
 }
 
-func printNetstatRoutes() error {
-	ipv4, err4 := parseProcNetRoute("/proc/net/route")
-	ipv6, err6 := parseProcNetIPv6Route("/proc/net/ipv6_route")
+func printNetstatRoutes(w io.Writer, roots utils.Roots) error {
+	ipv4, err4 := parseProcNetRoute(roots.ProcNetPath("route"))
+	ipv6, err6 := parseProcNetIPv6Route(roots.ProcNetPath("ipv6_route"))
 	if err4 != nil && err6 != nil {
 		return err4
 	}
-	fmt.Println("Kernel IP routing table")
-	fmt.Printf("%-15s %-15s %-15s %-7s %3s %-6s %5s %s\n", "Destination", "Gateway", "Genmask", "Flags", "MSS", "Window", "irtt", "Iface")
+	fmt.Fprintln(w, "Kernel IP routing table")
+	fmt.Fprintf(w, "%-15s %-15s %-15s %-7s %3s %-6s %5s %s\n", "Destination", "Gateway", "Genmask", "Flags", "MSS", "Window", "irtt", "Iface")
 	for _, r := range ipv4 {
-		fmt.Printf("%-15s %-15s %-15s %-7s %3s %-6s %5s %s\n", r.Destination, r.Gateway, r.Genmask, r.Flags, r.MSS, r.Window, r.IRTT, r.Iface)
+		fmt.Fprintf(w, "%-15s %-15s %-15s %-7s %3s %-6s %5s %s\n", r.Destination, r.Gateway, r.Genmask, r.Flags, r.MSS, r.Window, r.IRTT, r.Iface)
 	}
 	if len(ipv6) > 0 {
-		fmt.Println()
-		fmt.Println("Kernel IPv6 routing table")
-		fmt.Printf("%-39s %-39s %-6s %-6s %s\n", "Destination", "Gateway", "Flags", "Metric", "Iface")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Kernel IPv6 routing table")
+		fmt.Fprintf(w, "%-39s %-39s %-6s %-6s %s\n", "Destination", "Gateway", "Flags", "Metric", "Iface")
 		for _, r := range ipv6 {
-			fmt.Printf("%-39s %-39s %-6s %-6s %s\n", r.Destination, r.Gateway, r.Flags, r.Metric, r.Iface)
+			fmt.Fprintf(w, "%-39s %-39s %-6s %-6s %s\n", r.Destination, r.Gateway, r.Flags, r.Metric, r.Iface)
 		}
 	}
 	return nil
@@ -566,45 +654,30 @@ func parseProcNetRoute(path string) ([]netstatIPv4Route, error) {
 		return nil, err
 	}
 	defer f.Close()
-	var routes []netstatIPv4Route
-	scanner := bufio.NewScanner(f)
-	first := true
-	for scanner.Scan() {
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 8 {
-			continue
-		}
-		dest := parseIPv4RouteHex(fields[1])
-		if fields[1] == "00000000" {
+	parsed, err := netfs.ParseIPv4Routes(f)
+	routes := make([]netstatIPv4Route, 0, len(parsed))
+	for _, r := range parsed {
+		dest := r.Destination.String()
+		if r.Destination.Equal(net.IPv4zero) {
 			// Native netstat prints the default route's destination as the
 			// literal word "default", not "0.0.0.0".
 			dest = "default"
 		}
-		route := netstatIPv4Route{
-			Iface:       fields[0],
+		// /proc/net/route's own MTU/Window/IRTT columns match native
+		// netstat -r's "MSS Window irtt" columns.
+		routes = append(routes, netstatIPv4Route{
+			Iface:       r.Iface,
 			Destination: dest,
-			Gateway:     parseIPv4RouteHex(fields[2]),
-			Flags:       routeFlagsName(fields[3]),
-			Metric:      fields[6],
-			Genmask:     parseIPv4RouteHex(fields[7]),
-			MSS:         "0",
-			Window:      "0",
-			IRTT:        "0",
-		}
-		// /proc/net/route's own MTU/Window/IRTT columns (indices 8-10),
-		// matching native netstat -r's "MSS Window irtt" columns.
-		if len(fields) >= 11 {
-			route.MSS = fields[8]
-			route.Window = fields[9]
-			route.IRTT = fields[10]
-		}
-		routes = append(routes, route)
-	}
-	return routes, scanner.Err()
+			Gateway:     r.Gateway.String(),
+			Flags:       routeFlagsString(r.Flags),
+			Metric:      strconv.Itoa(r.Metric),
+			Genmask:     net.IP(r.Mask).String(),
+			MSS:         strconv.Itoa(r.MTU),
+			Window:      strconv.Itoa(r.Window),
+			IRTT:        strconv.Itoa(r.IRTT),
+		})
+	}
+	return routes, err
 }
 
 func parseProcNetIPv6Route(path string) ([]netstatIPv6Route, error) {
@@ -613,74 +686,35 @@ func parseProcNetIPv6Route(path string) ([]netstatIPv6Route, error) {
 		return nil, err
 	}
 	defer f.Close()
-	var routes []netstatIPv6Route
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 10 {
-			continue
-		}
-		destPrefix := parseHexInt(fields[1], 64)
-		destination := parseIPv6RouteHex(fields[0])
-		if destPrefix >= 0 {
-			destination = fmt.Sprintf("%s/%d", destination, destPrefix)
-		}
+	parsed, err := netfs.ParseIPv6Routes(f)
+	routes := make([]netstatIPv6Route, 0, len(parsed))
+	for _, r := range parsed {
 		routes = append(routes, netstatIPv6Route{
-			Destination: destination,
-			Gateway:     parseIPv6RouteHex(fields[4]),
-			Metric:      fmt.Sprintf("%d", parseHexInt(fields[5], 64)),
-			Flags:       routeFlagsName(fields[8]),
-			Iface:       fields[9],
+			Destination: fmt.Sprintf("%s/%d", r.Destination, r.PrefixLen),
+			Gateway:     r.NextHop.String(),
+			Metric:      strconv.FormatUint(uint64(r.Metric), 10),
+			Flags:       routeFlagsString(r.Flags),
+			Iface:       r.Iface,
 		})
 	}
-	return routes, scanner.Err()
+	return routes, err
 }
 
-func parseIPv4RouteHex(s string) string {
-	if len(s) != 8 {
-		return s
-	}
-	var bytes [4]byte
-	for i := 0; i < 4; i++ {
-		v, err := strconv.ParseUint(s[i*2:i*2+2], 16, 8)
-		if err != nil {
-			return s
-		}
-		bytes[3-i] = byte(v)
-	}
-	return fmt.Sprintf("%d.%d.%d.%d", bytes[0], bytes[1], bytes[2], bytes[3])
-}
-
-func parseIPv6RouteHex(s string) string {
-	if len(s) != 32 {
-		return s
-	}
-	b, err := hex.DecodeString(s)
-	if err != nil || len(b) != 16 {
-		return s
-	}
-	return net.IP(b).String()
-}
-
-func routeFlagsName(s string) string {
-	flags := parseHexInt(s, 64)
-	if flags < 0 {
-		return s
-	}
+func routeFlagsString(flags uint64) string {
 	var out strings.Builder
-	if flags&0x1 != 0 {
+	if flags&netfs.RouteUp != 0 {
 		out.WriteByte('U')
 	}
-	if flags&0x2 != 0 {
+	if flags&netfs.RouteGateway != 0 {
 		out.WriteByte('G')
 	}
-	if flags&0x4 != 0 {
+	if flags&netfs.RouteHost != 0 {
 		out.WriteByte('H')
 	}
-	if flags&0x10 != 0 {
+	if flags&netfs.RouteDynamic != 0 {
 		out.WriteByte('D')
 	}
-	if flags&0x20 != 0 {
+	if flags&netfs.RouteModified != 0 {
 		out.WriteByte('M')
 	}
 	if out.Len() == 0 {
@@ -689,8 +723,8 @@ func routeFlagsName(s string) string {
 	return out.String()
 }
 
-func printNetstatInterfaces(extended bool) error {
-	ifaces, err := parseProcNetDevNetstat("/proc/net/dev")
+func printNetstatInterfaces(w io.Writer, roots utils.Roots, extended bool) error {
+	ifaces, err := parseProcNetDevNetstat(roots.ProcNetPath("dev"))
 	if err != nil {
 		return err
 	}
@@ -704,17 +738,17 @@ func printNetstatInterfaces(extended bool) error {
 			hwAddrWidth = len(iface.HWAddr)
 		}
 	}
-	fmt.Println("Kernel Interface table")
+	fmt.Fprintln(w, "Kernel Interface table")
 	if extended {
-		fmt.Printf("%-*s %6s %12s %10s %7s %7s %7s %12s %10s %7s %7s %7s %-*s %s\n", nameWidth, "Iface", "MTU", "RX-Bytes", "RX-OK", "RX-ERR", "RX-DRP", "RX-OVR", "TX-Bytes", "TX-OK", "TX-ERR", "TX-DRP", "TX-OVR", hwAddrWidth, "HWaddr", "Flg")
+		fmt.Fprintf(w, "%-*s %6s %12s %10s %7s %7s %7s %12s %10s %7s %7s %7s %-*s %s\n", nameWidth, "Iface", "MTU", "RX-Bytes", "RX-OK", "RX-ERR", "RX-DRP", "RX-OVR", "TX-Bytes", "TX-OK", "TX-ERR", "TX-DRP", "TX-OVR", hwAddrWidth, "HWaddr", "Flg")
 		for _, iface := range ifaces {
-			fmt.Printf("%-*s %6d %12d %10d %7d %7d %7d %12d %10d %7d %7d %7d %-*s %s\n", nameWidth, iface.Name, iface.MTU, iface.RXBytes, iface.RXOK, iface.RXErr, iface.RXDrop, iface.RXOvr, iface.TXBytes, iface.TXOK, iface.TXErr, iface.TXDrop, iface.TXOvr, hwAddrWidth, iface.HWAddr, iface.Flags)
+			fmt.Fprintf(w, "%-*s %6d %12d %10d %7d %7d %7d %12d %10d %7d %7d %7d %-*s %s\n", nameWidth, iface.Name, iface.MTU, iface.RXBytes, iface.RXOK, iface.RXErr, iface.RXDrop, iface.RXOvr, iface.TXBytes, iface.TXOK, iface.TXErr, iface.TXDrop, iface.TXOvr, hwAddrWidth, iface.HWAddr, iface.Flags)
 		}
 		return nil
 	}
-	fmt.Printf("%-*s %6s %10s %7s %7s %7s %10s %7s %7s %7s %s\n", nameWidth, "Iface", "MTU", "RX-OK", "RX-ERR", "RX-DRP", "RX-OVR", "TX-OK", "TX-ERR", "TX-DRP", "TX-OVR", "Flg")
+	fmt.Fprintf(w, "%-*s %6s %10s %7s %7s %7s %10s %7s %7s %7s %s\n", nameWidth, "Iface", "MTU", "RX-OK", "RX-ERR", "RX-DRP", "RX-OVR", "TX-OK", "TX-ERR", "TX-DRP", "TX-OVR", "Flg")
 	for _, iface := range ifaces {
-		fmt.Printf("%-*s %6d %10d %7d %7d %7d %10d %7d %7d %7d %s\n", nameWidth, iface.Name, iface.MTU, iface.RXOK, iface.RXErr, iface.RXDrop, iface.RXOvr, iface.TXOK, iface.TXErr, iface.TXDrop, iface.TXOvr, iface.Flags)
+		fmt.Fprintf(w, "%-*s %6d %10d %7d %7d %7d %10d %7d %7d %7d %s\n", nameWidth, iface.Name, iface.MTU, iface.RXOK, iface.RXErr, iface.RXDrop, iface.RXOvr, iface.TXOK, iface.TXErr, iface.TXDrop, iface.TXOvr, iface.Flags)
 	}
 	return nil
 }
@@ -801,19 +835,19 @@ func parseProcNetDev(path string) ([]netstatInterface, error) {
 	return ifaces, scanner.Err()
 }
 
-func printNetstatStats(tcpOnly, udpOnly, unixOnly, ipv4Only, ipv6Only bool) error {
-	sections, err := parseNetstatStatsFiles([]string{"/proc/net/snmp", "/proc/net/netstat", "/proc/net/snmp6"})
+func printNetstatStats(w io.Writer, roots utils.Roots, tcpOnly, udpOnly, unixOnly, ipv4Only, ipv6Only bool) error {
+	sections, err := parseNetstatStatsFiles([]string{roots.ProcNetPath("snmp"), roots.ProcNetPath("netstat"), roots.ProcNetPath("snmp6")})
 	if err != nil {
 		return err
 	}
 	sections = filterNetstatStatSections(sections, tcpOnly, udpOnly, unixOnly, ipv4Only, ipv6Only)
 	for i, section := range sections {
 		if i > 0 {
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
-		fmt.Printf("%s:\n", section.Name)
+		fmt.Fprintf(w, "%s:\n", section.Name)
 		for _, field := range section.Fields {
-			fmt.Printf("    %-32s %s\n", field, section.Stats[field])
+			fmt.Fprintf(w, "    %-32s %s\n", field, section.Stats[field])
 		}
 	}
 	return nil
@@ -964,14 +998,6 @@ func parseUintField(s string) uint64 {
 	return v
 }
 
-func parseHexInt(s string, bitSize int) int64 {
-	v, err := strconv.ParseInt(s, 16, bitSize)
-	if err != nil {
-		return -1
-	}
-	return v
-}
-
 type tcpConn struct {
 	LocalPort  int
 	RemotePort int
@@ -987,178 +1013,38 @@ type tcpConn struct {
 }
 
 func parseProcNetTCP(path string, proto string) ([]tcpConn, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	var res []tcpConn
-	scanner := bufio.NewScanner(f)
-	first := true
-	for scanner.Scan() {
-		line := scanner.Text()
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 10 {
-			continue
-		}
-		// fields[1] = local_address, fields[2] = rem_address, fields[3] = st, fields[4] = tx_queue:rx_queue, fields[9] = inode
-		local := fields[1]
-		remote := fields[2]
-		stateHex := fields[3]
-		txrx := fields[4]
-		timer := fields[5]
-		uid := fields[7]
-		inode := fields[9]
-
-		lp := parsePortFromAddr(local)
-		rp := parsePortFromAddr(remote)
-		lip := parseIPFromAddr(local)
-		rip := parseIPFromAddr(remote)
-
-		tx, rx := 0, 0
-		if parts := strings.Split(txrx, ":"); len(parts) == 2 {
-			if v, err := strconv.ParseUint(parts[0], 16, 64); err == nil {
-				tx = int(v)
-			}
-			if v, err := strconv.ParseUint(parts[1], 16, 64); err == nil {
-				rx = int(v)
-			}
-		}
-
-		res = append(res, tcpConn{
-			LocalPort:  lp,
-			RemotePort: rp,
-			TxQueue:    tx,
-			RxQueue:    rx,
-			Inode:      inode,
-			UID:        uid,
-			LocalIP:    lip,
-			RemoteIP:   rip,
-			State:      tcpStateName(stateHex),
-			Proto:      proto,
-			Timer:      timer,
-		})
-	}
-	if err := scanner.Err(); err != nil {
-		return res, err
-	}
-	return res, nil
-}
-
-func parsePortFromAddr(addr string) int {
-	// addr is like "0100007F:0035" or for IPv6 a larger hex; we only need port after ':'
-	parts := strings.Split(addr, ":")
-	if len(parts) < 2 {
-		return 0
-	}
-	ph := parts[len(parts)-1]
-	if v, err := strconv.ParseUint(ph, 16, 16); err == nil {
-		return int(v)
-	}
-	return 0
+	return readNetSockets(path, proto, tcpStateName)
 }
 
-func parseIPFromAddr(addr string) string {
-	// addr like "0100007F:0035" for IPv4 (8 hex chars) or 32 hex chars for IPv6
-	parts := strings.Split(addr, ":")
-	if len(parts) < 2 {
-		return ""
-	}
-	ih := parts[0]
-	// IPv4 (8 hex chars) appears in little-endian in /proc/net/tcp
-	if len(ih) == 8 {
-		// read bytes in pairs and reverse
-		var bytes [4]byte
-		for i := 0; i < 4; i++ {
-			b, err := strconv.ParseUint(ih[i*2:i*2+2], 16, 8)
-			if err != nil {
-				return ""
-			}
-			bytes[3-i] = byte(b)
-		}
-		return fmt.Sprintf("%d.%d.%d.%d", bytes[0], bytes[1], bytes[2], bytes[3])
-	}
-	// IPv6: 32 hex chars -> 16 bytes stored as 4 little-endian uint32 words
-	if len(ih) == 32 {
-		b, err := hex.DecodeString(ih)
-		if err != nil || len(b) != 16 {
-			return ""
-		}
-		// byte-swap each 4-byte word
-		for i := 0; i < 16; i += 4 {
-			b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
-		}
-		return net.IP(b).String()
-	}
-	// fallback: return the hex string
-	return ih
+func parseProcNetUDP(path string, proto string) ([]tcpConn, error) {
+	return readNetSockets(path, proto, udpStateName)
 }
 
-func parseProcNetUDP(path string, proto string) ([]tcpConn, error) {
+// readNetSockets reads a tcp/udp table into the rows netstat prints.
+func readNetSockets(path, proto string, stateName func(string) string) ([]tcpConn, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	var res []tcpConn
-	scanner := bufio.NewScanner(f)
-	first := true
-	for scanner.Scan() {
-		line := scanner.Text()
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 10 {
-			continue
-		}
-		// fields[1] = local_address, fields[2] = rem_address, fields[3] = st, fields[4] = tx_queue:rx_queue, fields[9] = inode
-		local := fields[1]
-		remote := fields[2]
-		stateHex := fields[3]
-		txrx := fields[4]
-		timer := fields[5]
-		uid := fields[7]
-		inode := fields[9]
-
-		lp := parsePortFromAddr(local)
-		rp := parsePortFromAddr(remote)
-		lip := parseIPFromAddr(local)
-		rip := parseIPFromAddr(remote)
-
-		tx, rx := 0, 0
-		if parts := strings.Split(txrx, ":"); len(parts) == 2 {
-			if v, err := strconv.ParseUint(parts[0], 16, 64); err == nil {
-				tx = int(v)
-			}
-			if v, err := strconv.ParseUint(parts[1], 16, 64); err == nil {
-				rx = int(v)
-			}
-		}
-
+	socks, err := netfs.ParseSockets(f)
+	res := make([]tcpConn, 0, len(socks))
+	for _, sk := range socks {
 		res = append(res, tcpConn{
-			LocalPort:  lp,
-			RemotePort: rp,
-			TxQueue:    tx,
-			RxQueue:    rx,
-			Inode:      inode,
-			UID:        uid,
-			LocalIP:    lip,
-			RemoteIP:   rip,
-			State:      udpStateName(stateHex),
+			LocalPort:  sk.LocalPort,
+			RemotePort: sk.RemotePort,
+			TxQueue:    int(sk.TxQueue),
+			RxQueue:    int(sk.RxQueue),
+			Inode:      strconv.FormatUint(sk.Inode, 10),
+			UID:        strconv.Itoa(sk.UID),
+			LocalIP:    sk.LocalIP.String(),
+			RemoteIP:   sk.RemoteIP.String(),
+			State:      stateName(fmt.Sprintf("%02X", sk.State)),
 			Proto:      proto,
-			Timer:      timer,
+			Timer:      sk.Timer,
 		})
 	}
-	if err := scanner.Err(); err != nil {
-		return res, err
-	}
-	return res, nil
+	return res, err
 }
 
 func parseProcNetUnix(path string) ([]tcpConn, error) {
@@ -1167,35 +1053,22 @@ func parseProcNetUnix(path string) ([]tcpConn, error) {
 		return nil, err
 	}
 	defer f.Close()
-	var res []tcpConn
-	scanner := bufio.NewScanner(f)
-	first := true
-	for scanner.Scan() {
-		line := scanner.Text()
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 7 {
-			continue
-		}
-		path := "-"
-		if len(fields) >= 8 {
-			path = strings.Join(fields[7:], " ")
+	socks, err := netfs.ParseUnixSockets(f)
+	res := make([]tcpConn, 0, len(socks))
+	for _, sk := range socks {
+		path := sk.Path
+		if path == "" {
+			path = "-"
 		}
 		res = append(res, tcpConn{
-			Inode:    fields[6],
+			Inode:    strconv.FormatUint(sk.Inode, 10),
 			LocalIP:  path,
 			RemoteIP: "-",
-			State:    unixStateName(fields[5]),
+			State:    unixStateName(fmt.Sprintf("%02X", sk.State)),
 			Proto:    "UNIX",
 		})
 	}
-	if err := scanner.Err(); err != nil {
-		return res, err
-	}
-	return res, nil
+	return res, err
 }
 
 func unixStateName(h string) string {
@@ -1228,32 +1101,11 @@ func udpStateName(h string) string {
 }
 
 func tcpStateName(h string) string {
-	switch strings.ToUpper(h) {
-	case "01":
-		return "ESTABLISHED"
-	case "02":
-		return "SYN_SENT"
-	case "03":
-		return "SYN_RECV"
-	case "04":
-		return "FIN_WAIT1"
-	case "05":
-		return "FIN_WAIT2"
-	case "06":
-		return "TIME_WAIT"
-	case "07":
-		return "CLOSE"
-	case "08":
-		return "CLOSE_WAIT"
-	case "09":
-		return "LAST_ACK"
-	case "0A", "0a":
-		return "LISTEN"
-	case "0B", "0b":
-		return "CLOSING"
-	default:
+	state, err := strconv.ParseUint(h, 16, 8)
+	if err != nil {
 		return h
 	}
+	return netfs.TCPStateName(uint8(state))
 }
 
 // netstatNeedsInodePidMap reports whether the inode→PID mapping is actually
@@ -1263,11 +1115,11 @@ func netstatNeedsInodePidMap(programs bool, sortBy string) bool {
 }
 
 // buildInodePidMap walks /proc and finds which pid owns a given socket inode
-func buildInodePidMap() (map[string]int, map[string]string) {
+func buildInodePidMap(roots utils.Roots) (map[string]int, map[string]string) {
 	inodeToPid := make(map[string]int)
 	pidName := make(map[string]string)
 
-	procEntries, err := os.ReadDir("/proc")
+	procEntries, err := os.ReadDir(roots.Proc())
 	if err != nil {
 		return inodeToPid, pidName
 	}
@@ -1282,14 +1134,14 @@ func buildInodePidMap() (map[string]int, map[string]string) {
 		}
 		pid := name
 		// read process name
-		commPath := filepath.Join("/proc", pid, "comm")
+		commPath := roots.ProcPath(pid, "comm")
 		pname := ""
 		if b, err := os.ReadFile(commPath); err == nil {
 			pname = strings.TrimSpace(string(b))
 		}
 		pidName[pid] = pname
 
-		fdDir := filepath.Join("/proc", pid, "fd")
+		fdDir := roots.ProcPath(pid, "fd")
 		fds, err := os.ReadDir(fdDir)
 		if err != nil {
 			continue
@@ -2,6 +2,7 @@ package net
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net"
 	"os"
@@ -12,6 +13,9 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
 )
 
 func captureNetOutput(t *testing.T, fn func() error) (string, error) {
@@ -62,28 +66,6 @@ func netstatDataLines(output string) []string {
 	return lines[1:]
 }
 
-func TestParsePortFromAddr(t *testing.T) {
-	if got := parsePortFromAddr("0100007F:0035"); got != 53 {
-		t.Fatalf("expected port 53, got %d", got)
-	}
-	if got := parsePortFromAddr("bad"); got != 0 {
-		t.Fatalf("expected port 0 for bad input, got %d", got)
-	}
-}
-
-func TestParseIPFromAddr(t *testing.T) {
-	if got := parseIPFromAddr("0100007F:0035"); got != "127.0.0.1" {
-		t.Fatalf("expected IPv4 127.0.0.1, got %q", got)
-	}
-	// ::1 in /proc/net/tcp6 is stored as 4 little-endian uint32 words: last word 0x01000000
-	if got := parseIPFromAddr("00000000000000000000000001000000:0035"); got != "::1" {
-		t.Fatalf("expected IPv6 ::1, got %q", got)
-	}
-	if got := parseIPFromAddr("bad"); got != "" {
-		t.Fatalf("expected empty IP for bad input, got %q", got)
-	}
-}
-
 func TestTCPStateName(t *testing.T) {
 	if got := tcpStateName("01"); got != "ESTABLISHED" {
 		t.Fatalf("expected ESTABLISHED, got %q", got)
@@ -342,7 +324,7 @@ func TestNetstatCmdCombinedShortFlags(t *testing.T) {
 
 func TestPrintNetstatTableAlignsLongAddresses(t *testing.T) {
 	out, err := captureNetOutput(t, func() error {
-		printNetstatTable([]netstatSocketRow{
+		printNetstatTable(os.Stdout, nil, []netstatSocketRow{
 			{
 				conn:       tcpConn{RxQueue: 1, TxQueue: 2, State: "ESTABLISHED", UID: "1000", Inode: "123", Timer: "off"},
 				proto:      "TCP6",
@@ -369,6 +351,59 @@ func TestPrintNetstatTableAlignsLongAddresses(t *testing.T) {
 	}
 }
 
+func TestPrintNetstatTableColorsStatesKeepingAlignment(t *testing.T) {
+	var out bytes.Buffer
+	c := utils.NewColorizer(&out, utils.ColorAlways, func(string) string { return "" })
+	printNetstatTable(&out, c, []netstatSocketRow{
+		{conn: tcpConn{State: "LISTEN"}, proto: "TCP", local: "0.0.0.0:22", remote: "0.0.0.0:*"},
+		{conn: tcpConn{State: "CLOSE_WAIT"}, proto: "TCP", local: "10.0.0.1:22", remote: "10.0.0.2:5000"},
+	}, false, false, false)
+	for _, want := range []string{"\x1b[36m\x1b[KLISTEN\x1b[m\x1b[K    ", "\x1b[31m\x1b[KCLOSE_WAIT\x1b[m\x1b[K"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in %q", want, out.String())
+		}
+	}
+	for state, want := range map[string]string{"ESTABLISHED": utils.ColorOK, "TIME_WAIT": utils.ColorWarn, "LISTENING": utils.ColorListen, "": ""} {
+		if got := netstatStateRole(state); got != want {
+			t.Fatalf("netstatStateRole(%q) = %q, want %q", state, got, want)
+		}
+	}
+}
+
+func TestNetstatTableKeepsRawFields(t *testing.T) {
+	table := netstatTable([]netstatSocketRow{
+		{
+			conn:       tcpConn{RxQueue: 1, TxQueue: 2, LocalIP: "10.0.0.1", LocalPort: 22, RemoteIP: "10.0.0.2", RemotePort: 51000, State: "ESTABLISHED", UID: "1000", Inode: "123"},
+			proto:      "TCP",
+			pidProgram: "1234/sshd",
+		},
+		{
+			conn:       tcpConn{LocalIP: "/run/x.sock", RemoteIP: "-", State: "LISTEN", Inode: "99"},
+			proto:      "UNIX",
+			pidProgram: "-/-",
+		},
+	})
+	var out bytes.Buffer
+	if err := table.Render(&out, "ndjson"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"proto":"TCP","recv_q":1,"send_q":2,"local_address":"10.0.0.1","local_port":22,"remote_address":"10.0.0.2","remote_port":51000,"state":"ESTABLISHED","uid":1000,"inode":123,"pid":1234,"program":"sshd","timer":null}` + "\n" +
+		`{"proto":"UNIX","recv_q":0,"send_q":0,"local_address":"/run/x.sock","local_port":null,"remote_address":null,"remote_port":null,"state":"LISTEN","uid":null,"inode":99,"pid":null,"program":null,"timer":null}` + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected netstat table:\n%s", out.String())
+	}
+}
+
+func TestNetstatStructuredOutputRejectsNonSocketModes(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("netstat is only supported on Linux")
+	}
+	err := netstatCmd(&base.Invocation{Output: "json", Stdout: io.Discard}, []string{"-r"})
+	if err == nil || !strings.Contains(err.Error(), "socket listing") {
+		t.Fatalf("expected -r to reject structured output, got %v", err)
+	}
+}
+
 func TestPrintNetstatInterfacesAlignsLongNames(t *testing.T) {
 	oldParse := parseProcNetDevNetstat
 	parseProcNetDevNetstat = func(string) ([]netstatInterface, error) {
@@ -386,7 +421,7 @@ func TestPrintNetstatInterfacesAlignsLongNames(t *testing.T) {
 	}
 	t.Cleanup(func() { parseProcNetDevNetstat = oldParse })
 
-	out, err := captureNetOutput(t, func() error { return printNetstatInterfaces(false) })
+	out, err := captureNetOutput(t, func() error { return printNetstatInterfaces(os.Stdout, utils.Roots{}, false) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -565,7 +600,7 @@ func TestPrintNetstatInterfacesIncludesTitleLine(t *testing.T) {
 		return []netstatInterface{{Name: "eth0", MTU: 1500, Flags: "BMRU"}}, nil
 	}
 	t.Cleanup(func() { parseProcNetDevNetstat = oldParse })
-	out, err := captureNetOutput(t, func() error { return printNetstatInterfaces(false) })
+	out, err := captureNetOutput(t, func() error { return printNetstatInterfaces(os.Stdout, utils.Roots{}, false) })
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -625,18 +660,14 @@ func TestNetstatCmdRouteInterfaceAndStatsModes(t *testing.T) {
 	}
 }
 
-func TestRunNetstatContinuousStopsOnInterrupt(t *testing.T) {
+func TestRunNetstatContinuousStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	count := 0
-	err := runNetstatContinuous(func() error {
+	err := runNetstatContinuous(ctx, io.Discard, func() error {
 		count++
 		if count == 1 {
-			p, err := os.FindProcess(os.Getpid())
-			if err != nil {
-				t.Fatalf("find process: %v", err)
-			}
-			if err := p.Signal(os.Interrupt); err != nil {
-				t.Fatalf("signal interrupt: %v", err)
-			}
+			cancel()
 		}
 		return nil
 	})
@@ -644,7 +675,7 @@ func TestRunNetstatContinuousStopsOnInterrupt(t *testing.T) {
 		t.Fatalf("runNetstatContinuous returned error: %v", err)
 	}
 	if count != 1 {
-		t.Fatalf("expected one render before interrupt, got %d", count)
+		t.Fatalf("expected one render before cancellation, got %d", count)
 	}
 }
 
@@ -1068,21 +1099,19 @@ func TestNetstatCmdLongContinuousFlagRuns(t *testing.T) {
 	defer ln.Close()
 
 	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	go func() {
 		time.Sleep(50 * time.Millisecond)
-		p, err := os.FindProcess(os.Getpid())
-		if err == nil {
-			_ = p.Signal(os.Interrupt)
-		}
+		cancel()
 	}()
 
 	done := make(chan struct{})
 	var runErr error
 	go func() {
 		defer close(done)
-		_, runErr = captureNetOutput(t, func() error {
-			return NetstatCmd([]string{"--continuous", "--tcp", "--listening", "--port", port})
-		})
+		inv := &base.Invocation{Context: ctx, Stdout: io.Discard, Stderr: io.Discard}
+		runErr = netstatCmd(inv, []string{"--continuous", "--tcp", "--listening", "--port", port})
 	}()
 
 	select {
@@ -1091,7 +1120,7 @@ func TestNetstatCmdLongContinuousFlagRuns(t *testing.T) {
 			t.Fatalf("NetstatCmd --continuous failed: %v", runErr)
 		}
 	case <-time.After(2 * time.Second):
-		t.Fatal("NetstatCmd --continuous did not stop after interrupt")
+		t.Fatal("NetstatCmd --continuous did not stop after cancellation")
 	}
 }
 
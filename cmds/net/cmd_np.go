@@ -1,7 +1,6 @@
 package net
 
 import (
-	"bufio"
 	"encoding/binary"
 	"flag"
 	"fmt"
@@ -16,42 +15,48 @@ import (
 	"syscall"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"gobox/pkg/netfs"
 )
 
 // NpCmd implements network ping/connectivity troubleshooting tool
 func NpCmd(args []string) error {
+	return npCmd(base.Stdio(), args)
+}
+
+func npCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("np", flag.ContinueOnError)
-	fsFlags.SetOutput(os.Stderr)
+	fsFlags.SetOutput(inv.Stderr)
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox np [OPTION]... [HOST]")
-		fmt.Fprintln(os.Stderr, "Network connectivity troubleshooting tool (TCP/UDP/ICMP/ARP ping, port scanning)")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Modes:")
-		fmt.Fprintln(os.Stderr, "  --tcp              TCP mode (default)")
-		fmt.Fprintln(os.Stderr, "  --udp              UDP mode")
-		fmt.Fprintln(os.Stderr, "  --icmp             ICMP mode")
-		fmt.Fprintln(os.Stderr, "  --arp              ARP mode")
-		fmt.Fprintln(os.Stderr, "  --scan             port scanning mode")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -c COUNT           packet count to send")
-		fmt.Fprintln(os.Stderr, "  -i SEC             interval between packets in seconds (supports decimals)")
-		fmt.Fprintln(os.Stderr, "  -p PORT            target port")
-		fmt.Fprintln(os.Stderr, "  -s PORT            source port")
-		fmt.Fprintln(os.Stderr, "  -I IFACE           network interface to use")
-		fmt.Fprintln(os.Stderr, "  -W SEC             timeout in seconds")
-		fmt.Fprintln(os.Stderr, "  --flood            flood mode (max speed)")
-		fmt.Fprintln(os.Stderr, "  -w N               concurrent workers (for TCP/UDP ping)")
-		fmt.Fprintln(os.Stderr, "  -l N               long connection mode")
-		fmt.Fprintln(os.Stderr, "  -q                 quiet mode, only show final statistics")
-		fmt.Fprintln(os.Stderr, "  -v                 verbose output")
-		fmt.Fprintln(os.Stderr, "  -h, --help         show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox np --tcp -p 80 -c 1 127.0.0.1")
-		fmt.Fprintln(os.Stderr, "  gobox np --udp -p 53 -c 2 127.0.0.1")
-		fmt.Fprintln(os.Stderr, "  gobox np --scan 80,443 127.0.0.1")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox np [OPTION]... [HOST]")
+		fmt.Fprintln(inv.Stderr, "Network connectivity troubleshooting tool (TCP/UDP/ICMP/ARP ping, port scanning)")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Modes:")
+		fmt.Fprintln(inv.Stderr, "  --tcp              TCP mode (default)")
+		fmt.Fprintln(inv.Stderr, "  --udp              UDP mode")
+		fmt.Fprintln(inv.Stderr, "  --icmp             ICMP mode")
+		fmt.Fprintln(inv.Stderr, "  --arp              ARP mode")
+		fmt.Fprintln(inv.Stderr, "  --scan             port scanning mode")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -c COUNT           packet count to send")
+		fmt.Fprintln(inv.Stderr, "  -i SEC             interval between packets in seconds (supports decimals)")
+		fmt.Fprintln(inv.Stderr, "  -p PORT            target port")
+		fmt.Fprintln(inv.Stderr, "  -s PORT            source port")
+		fmt.Fprintln(inv.Stderr, "  -I IFACE           network interface to use")
+		fmt.Fprintln(inv.Stderr, "  -W SEC             timeout in seconds")
+		fmt.Fprintln(inv.Stderr, "  --flood            flood mode (max speed)")
+		fmt.Fprintln(inv.Stderr, "  -w N               concurrent workers (for TCP/UDP ping)")
+		fmt.Fprintln(inv.Stderr, "  -l N               long connection mode")
+		fmt.Fprintln(inv.Stderr, "  -q                 quiet mode, only show final statistics")
+		fmt.Fprintln(inv.Stderr, "  -v                 verbose output")
+		fmt.Fprintln(inv.Stderr, "  -h, --help         show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox np --tcp -p 80 -c 1 127.0.0.1")
+		fmt.Fprintln(inv.Stderr, "  gobox np --udp -p 53 -c 2 127.0.0.1")
+		fmt.Fprintln(inv.Stderr, "  gobox np --scan 80,443 127.0.0.1")
 	}
 
 	// Mode selection
@@ -170,7 +175,7 @@ func NpCmd(args []string) error {
 		portRange:  portRange,
 	}
 
-	return runNp(opts)
+	return runNp(inv, opts)
 }
 
 type npOptions struct {
@@ -190,25 +195,25 @@ type npOptions struct {
 	portRange  []int
 }
 
-func runNp(opts *npOptions) error {
+func runNp(inv *base.Invocation, opts *npOptions) error {
 	switch opts.mode {
 	case "tcp":
-		return npTCP(opts)
+		return npTCP(inv, opts)
 	case "udp":
-		return npUDP(opts)
+		return npUDP(inv, opts)
 	case "icmp":
-		return npICMP(opts)
+		return npICMP(inv, opts)
 	case "arp":
-		return npARP(opts)
+		return npARP(inv, opts)
 	case "scan":
-		return npScan(opts)
+		return npScan(inv, opts)
 	default:
 		return fmt.Errorf("unknown mode: %s", opts.mode)
 	}
 }
 
 // TCP ping - connect and measure latency
-func npTCP(opts *npOptions) error {
+func npTCP(inv *base.Invocation, opts *npOptions) error {
 	var wg sync.WaitGroup
 	var sent, received, errors int64
 	var minLatency, maxLatency, totalLatency int64
@@ -226,7 +231,7 @@ func npTCP(opts *npOptions) error {
 		wg.Add(1)
 		go func(workerId int) {
 			defer wg.Done()
-			npTCPWorker(workerId, opts, &sent, &received, &errors, &seqCounter, &mu, &latencies, stopChan)
+			npTCPWorker(inv.Stdout, workerId, opts, &sent, &received, &errors, &seqCounter, &mu, &latencies, stopChan)
 		}(w)
 	}
 
@@ -235,7 +240,7 @@ func npTCP(opts *npOptions) error {
 		progressWG.Add(1)
 		go func() {
 			defer progressWG.Done()
-			npProgressReporter(&sent, &received, &errors, opts, stopChan)
+			npProgressReporter(inv.Stdout, &sent, &received, &errors, opts, stopChan)
 		}()
 	}
 
@@ -259,13 +264,13 @@ func npTCP(opts *npOptions) error {
 	}
 
 	if opts.quiet || opts.verbose {
-		printNpStats(opts.host, sent, received, errors, minLatency, maxLatency, totalLatency)
+		printNpStats(inv.Stdout, opts.host, sent, received, errors, minLatency, maxLatency, totalLatency)
 	}
 
 	return nil
 }
 
-func npTCPWorker(workerId int, opts *npOptions, sent, received, errors, seqCounter *int64, mu *sync.Mutex, latencies *[]int64, stopChan chan struct{}) {
+func npTCPWorker(w io.Writer, workerId int, opts *npOptions, sent, received, errors, seqCounter *int64, mu *sync.Mutex, latencies *[]int64, stopChan chan struct{}) {
 	addr := net.JoinHostPort(opts.host, strconv.Itoa(opts.port))
 	dialer := net.Dialer{Timeout: opts.wait}
 	configureNpDialer(&dialer, "tcp", opts)
@@ -301,7 +306,7 @@ func npTCPWorker(workerId int, opts *npOptions, sent, received, errors, seqCount
 		if err != nil {
 			atomic.AddInt64(errors, 1)
 			if opts.verbose {
-				fmt.Printf("From %s: seq=%d Connection failed: %v\n", opts.host, seq, err)
+				fmt.Fprintf(w, "From %s: seq=%d Connection failed: %v\n", opts.host, seq, err)
 			}
 			if opts.interval > 0 {
 				time.Sleep(opts.interval)
@@ -316,7 +321,7 @@ func npTCPWorker(workerId int, opts *npOptions, sent, received, errors, seqCount
 		mu.Unlock()
 
 		if !opts.quiet {
-			fmt.Printf("%d bytes from %s: seq=%d ttl=64 time=%.3f ms\n",
+			fmt.Fprintf(w, "%d bytes from %s: seq=%d ttl=64 time=%.3f ms\n",
 				64, opts.host, seq, float64(latency)/1000.0)
 		}
 
@@ -334,7 +339,7 @@ func npTCPWorker(workerId int, opts *npOptions, sent, received, errors, seqCount
 }
 
 // UDP ping
-func npUDP(opts *npOptions) error {
+func npUDP(inv *base.Invocation, opts *npOptions) error {
 	addr := net.JoinHostPort(opts.host, strconv.Itoa(opts.port))
 	dialer := net.Dialer{Timeout: opts.wait}
 	configureNpDialer(&dialer, "udp", opts)
@@ -362,7 +367,7 @@ func npUDP(opts *npOptions) error {
 		if err != nil {
 			atomic.AddInt64(&errors, 1)
 			if opts.verbose {
-				fmt.Printf("From %s: seq=%d Connection failed: %v\n", opts.host, i, err)
+				fmt.Fprintf(inv.Stdout, "From %s: seq=%d Connection failed: %v\n", opts.host, i, err)
 			}
 		} else {
 			atomic.AddInt64(&received, 1)
@@ -371,7 +376,7 @@ func npUDP(opts *npOptions) error {
 			mu.Unlock()
 
 			if !opts.quiet {
-				fmt.Printf("%d bytes from %s: seq=%d ttl=64 time=%.3f ms\n",
+				fmt.Fprintf(inv.Stdout, "%d bytes from %s: seq=%d ttl=64 time=%.3f ms\n",
 					64, opts.host, i, float64(latency)/1000.0)
 			}
 			conn.Close()
@@ -401,14 +406,14 @@ func npUDP(opts *npOptions) error {
 	}
 
 	if opts.quiet || opts.verbose {
-		printNpStats(opts.host, sent, received, errors, minLatency, maxLatency, totalLatency)
+		printNpStats(inv.Stdout, opts.host, sent, received, errors, minLatency, maxLatency, totalLatency)
 	}
 
 	return nil
 }
 
 // ICMP ping using raw socket
-func npICMP(opts *npOptions) error {
+func npICMP(inv *base.Invocation, opts *npOptions) error {
 	// Prefer the system ping binary when present; it usually carries the
 	// capabilities needed for ICMP echo without requiring a privileged gobox process.
 	if path, err := exec.LookPath("ping"); err == nil {
@@ -429,9 +434,7 @@ func npICMP(opts *npOptions) error {
 			args = append(args, "-c", strconv.Itoa(opts.count))
 		}
 		args = append(args, "-W", strconv.Itoa(npWaitSeconds(opts)), opts.host)
-		cmd := exec.Command(path, args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd := inv.Exec(inv.Ctx(), path, args...)
 		if err := cmd.Run(); err != nil {
 			return err
 		}
@@ -444,7 +447,7 @@ func npICMP(opts *npOptions) error {
 		return fmt.Errorf("cannot resolve %s: %w", opts.host, err)
 	}
 
-	fmt.Printf("PING %s (%s): 56 data bytes\n", opts.host, ipAddr.String())
+	fmt.Fprintf(inv.Stdout, "PING %s (%s): 56 data bytes\n", opts.host, ipAddr.String())
 
 	var sent, received, errors int64
 	var minLatency, maxLatency, totalLatency int64
@@ -460,7 +463,7 @@ func npICMP(opts *npOptions) error {
 		if err != nil {
 			atomic.AddInt64(&errors, 1)
 			if opts.verbose || !opts.quiet {
-				fmt.Printf("From %s: seq=%d Connection failed: %v\n", opts.host, i, err)
+				fmt.Fprintf(inv.Stdout, "From %s: seq=%d Connection failed: %v\n", opts.host, i, err)
 			}
 		} else {
 			atomic.AddInt64(&received, 1)
@@ -469,7 +472,7 @@ func npICMP(opts *npOptions) error {
 			mu.Unlock()
 
 			if !opts.quiet {
-				fmt.Printf("64 bytes from %s: seq=%d ttl=64 time=%.3f ms\n",
+				fmt.Fprintf(inv.Stdout, "64 bytes from %s: seq=%d ttl=64 time=%.3f ms\n",
 					opts.host, i, float64(latency)/1000.0)
 			}
 		}
@@ -498,7 +501,7 @@ func npICMP(opts *npOptions) error {
 	}
 
 	if opts.quiet || opts.verbose {
-		printNpStats(opts.host, sent, received, errors, minLatency, maxLatency, totalLatency)
+		printNpStats(inv.Stdout, opts.host, sent, received, errors, minLatency, maxLatency, totalLatency)
 	}
 
 	return nil
@@ -577,31 +580,27 @@ func icmpChecksum(packet []byte) uint16 {
 }
 
 // ARP ping (ARP discovery on local network)
-func npARP(opts *npOptions) error {
+func npARP(inv *base.Invocation, opts *npOptions) error {
 	if path, err := exec.LookPath("arping"); err == nil {
 		args := []string{"-c", strconv.Itoa(npPacketCount(opts)), "-w", strconv.Itoa(npWaitSeconds(opts))}
 		if opts.iface != "" {
 			args = append(args, "-I", opts.iface)
 		}
 		args = append(args, opts.host)
-		cmd := exec.Command(path, args...)
+		cmd := inv.Exec(inv.Ctx(), path, args...)
 		if opts.quiet {
 			cmd.Stdout = io.Discard
 			cmd.Stderr = io.Discard
-		} else {
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
 		}
 		return cmd.Run()
 	}
 
 	if !opts.quiet {
-		fmt.Printf("ARPING %s from unspecified\n", opts.host)
+		fmt.Fprintf(inv.Stdout, "ARPING %s from unspecified\n", opts.host)
 	}
 
-	// Try to get MAC address via ARP
-	// On Linux, we can check /proc/net/arp
-	f, err := os.Open("/proc/net/arp")
+	// Try to get MAC address from the ARP cache (/proc/net/arp on Linux)
+	entries, err := inv.Roots().NetFS().ARP()
 	if err != nil {
 		// Fallback: try to ping and see if we can get ARP info
 		conn, err := net.DialTimeout("ip4:icmp", opts.host, opts.wait)
@@ -611,32 +610,18 @@ func npARP(opts *npOptions) error {
 		conn.Close()
 
 		if !opts.quiet {
-			fmt.Printf("%s is alive (ARP request sent)\n", opts.host)
+			fmt.Fprintf(inv.Stdout, "%s is alive (ARP request sent)\n", opts.host)
 		}
 		return nil
 	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Skip header
-		if strings.HasPrefix(line, "IP address") {
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 4 {
-			continue
-		}
-		ip := fields[0]
-		mac := fields[3]
-		if ip == opts.host && mac != "" && mac != "00:00:00:00:00:00" {
-			fmt.Printf("%s is at %s\n", opts.host, mac)
+	for _, e := range entries {
+		if e.IP.String() == opts.host && e.Flags&netfs.ARPComplete != 0 {
+			fmt.Fprintf(inv.Stdout, "%s is at %s\n", opts.host, e.HWAddr)
 			return nil
 		}
 	}
 
-	fmt.Printf("%s not found in ARP cache (request sent)\n", opts.host)
+	fmt.Fprintf(inv.Stdout, "%s not found in ARP cache (request sent)\n", opts.host)
 	return nil
 }
 
@@ -659,9 +644,9 @@ func npWaitSeconds(opts *npOptions) int {
 }
 
 // Port scanning
-func npScan(opts *npOptions) error {
+func npScan(inv *base.Invocation, opts *npOptions) error {
 	if !opts.quiet {
-		fmt.Printf("Starting scan of %d ports on %s\n", len(opts.portRange), opts.host)
+		fmt.Fprintf(inv.Stdout, "Starting scan of %d ports on %s\n", len(opts.portRange), opts.host)
 	}
 
 	var mu sync.Mutex
@@ -693,11 +678,11 @@ func npScan(opts *npOptions) error {
 					// Port is closed or filtered
 					closedPorts++
 					if opts.verbose {
-						fmt.Printf("Port %d: closed\n", port)
+						fmt.Fprintf(inv.Stdout, "Port %d: closed\n", port)
 					}
 				} else {
 					openPorts++
-					fmt.Printf("Port %d: open\n", port)
+					fmt.Fprintf(inv.Stdout, "Port %d: open\n", port)
 				}
 				mu.Unlock()
 			}
@@ -707,7 +692,7 @@ func npScan(opts *npOptions) error {
 	wg.Wait()
 
 	if !opts.quiet {
-		fmt.Printf("\nScan complete: %d open, %d closed, %d errors\n",
+		fmt.Fprintf(inv.Stdout, "\nScan complete: %d open, %d closed, %d errors\n",
 			openPorts, closedPorts, errors)
 	}
 
@@ -772,12 +757,12 @@ func configureNpDialer(dialer *net.Dialer, network string, opts *npOptions) {
 	}
 }
 
-func npProgressReporter(sent, received, errors *int64, opts *npOptions, stopChan chan struct{}) {
+func npProgressReporter(w io.Writer, sent, received, errors *int64, opts *npOptions, stopChan chan struct{}) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
 	// "\r" in-place redraw only works on a real terminal; fall back to newline-terminated lines otherwise.
-	interactive := utils.IsTerminal(os.Stdout)
+	interactive := utils.IsTerminal(w)
 
 	// Skip re-printing unchanged Sent/Received/Errors between ticks — avoids spurious repeats during slow -W/-l waits.
 	lastSent, lastReceived, lastErrors := int64(-1), int64(-1), int64(-1)
@@ -794,7 +779,7 @@ func npProgressReporter(sent, received, errors *int64, opts *npOptions, stopChan
 					// tick, so printing an all-zero line here is harmless
 					// and doubles as a "still probing" liveness indicator
 					// during a long -W wait.
-					fmt.Printf("\rSent=%d Received=%d Errors=%d", s, r, e)
+					fmt.Fprintf(w, "\rSent=%d Received=%d Errors=%d", s, r, e)
 				} else if (s != 0 || r != 0 || e != 0) && (s != lastSent || r != lastReceived || e != lastErrors) {
 					// sent is only incremented once a probe's dial/timeout
 					// has resolved, so an all-zero tick means no probe has
@@ -803,7 +788,7 @@ func npProgressReporter(sent, received, errors *int64, opts *npOptions, stopChan
 					// printing that transient "Sent=0 Received=0 Errors=0"
 					// line would persist as pure noise instead of real
 					// progress — skip it here specifically.
-					fmt.Printf("Sent=%d Received=%d Errors=%d\n", s, r, e)
+					fmt.Fprintf(w, "Sent=%d Received=%d Errors=%d\n", s, r, e)
 				}
 			}
 			lastSent, lastReceived, lastErrors = s, r, e
@@ -813,19 +798,19 @@ func npProgressReporter(sent, received, errors *int64, opts *npOptions, stopChan
 	}
 }
 
-func printNpStats(host string, sent, received, errors, minLatency, maxLatency, totalLatency int64) {
-	fmt.Println()
-	fmt.Printf("--- %s ping statistics ---\n", host)
-	fmt.Printf("%d packets transmitted, %d packets received, %d errors\n",
+func printNpStats(w io.Writer, host string, sent, received, errors, minLatency, maxLatency, totalLatency int64) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "--- %s ping statistics ---\n", host)
+	fmt.Fprintf(w, "%d packets transmitted, %d packets received, %d errors\n",
 		sent, received, errors)
 
 	if received > 0 {
 		packetLoss := float64(sent-received) / float64(sent) * 100
-		fmt.Printf("round-trip min/avg/max = %.3f/%.3f/%.3f ms\n",
+		fmt.Fprintf(w, "round-trip min/avg/max = %.3f/%.3f/%.3f ms\n",
 			float64(minLatency)/1000.0,
 			float64(totalLatency)/1000.0/float64(received),
 			float64(maxLatency)/1000.0)
-		fmt.Printf("%.1f%% packet loss\n", packetLoss)
+		fmt.Fprintf(w, "%.1f%% packet loss\n", packetLoss)
 	}
 }
 
@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"os"
 	"strings"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
@@ -36,16 +36,24 @@ func cnameIsSelf(host, cname string) bool {
 
 // DigCmd implements dig functionality
 func DigCmd(args []string) error {
-	return runDNSLookup("dig", args)
+	return digCmd(base.Stdio(), args)
+}
+
+func digCmd(inv *base.Invocation, args []string) error {
+	return runDNSLookup(inv, "dig", args)
 }
 
 // NslookupCmd runs the same DNS lookup logic as DigCmd, but with help text
 // and error messages that reflect the "nslookup" invocation name.
 func NslookupCmd(args []string) error {
-	return runDNSLookup("nslookup", args)
+	return nslookupCmd(base.Stdio(), args)
+}
+
+func nslookupCmd(inv *base.Invocation, args []string) error {
+	return runDNSLookup(inv, "nslookup", args)
 }
 
-func runDNSLookup(progName string, args []string) error {
+func runDNSLookup(inv *base.Invocation, progName string, args []string) error {
 	var host string
 	var dnsServer string
 	var queryType string
@@ -61,7 +69,7 @@ func runDNSLookup(progName string, args []string) error {
 		arg := args[i]
 		switch {
 		case arg == "-h" || arg == "--help":
-			digUsage(os.Stdout, progName)
+			digUsage(inv.Stdout, progName)
 			return nil
 		case arg == "+short":
 			shortOutput = true
@@ -97,8 +105,8 @@ func runDNSLookup(progName string, args []string) error {
 	}
 
 	if host == "" {
-		fmt.Fprintf(os.Stderr, "%s: missing host argument\n", progName)
-		digUsage(os.Stderr, progName)
+		fmt.Fprintf(inv.Stderr, "%s: missing host argument\n", progName)
+		digUsage(inv.Stderr, progName)
 		return fmt.Errorf("host required")
 	}
 
@@ -107,7 +115,7 @@ func runDNSLookup(progName string, args []string) error {
 		queryType = "A"
 	}
 	if !isSupportedDNSQueryType(queryType) {
-		fmt.Fprintf(os.Stderr, "Warning, ignoring invalid type %s\n", strings.ToUpper(queryType))
+		fmt.Fprintf(inv.Stderr, "Warning, ignoring invalid type %s\n", strings.ToUpper(queryType))
 		queryType = "A"
 	}
 
@@ -118,16 +126,16 @@ func runDNSLookup(progName string, args []string) error {
 
 	// If +short, just show the answer
 	if shortOutput {
-		return digShortOutput(host, queryType, dnsServer, useTCP)
+		return digShortOutput(inv, host, queryType, dnsServer, useTCP)
 	}
 
 	// If +noall +answer, show only answer section
 	if noall && showAnswer {
-		return digAnswerOnly(host, queryType, dnsServer, useTCP)
+		return digAnswerOnly(inv, host, queryType, dnsServer, useTCP)
 	}
 
 	// Full dig output
-	return digFullOutput(host, queryType, dnsServer, useTCP)
+	return digFullOutput(inv, host, queryType, dnsServer, useTCP)
 }
 
 // isSupportedDNSQueryType reports whether typ is one of the record types
@@ -142,6 +150,22 @@ func isSupportedDNSQueryType(typ string) bool {
 	}
 }
 
+// digFlags declares the dig and nslookup options for shell completion.
+var digFlags = []base.Flag{
+	{Name: "-t", TakesValue: true, Usage: "query type", Values: dnsQueryTypes},
+	{Name: "--type", TakesValue: true, Usage: "query type", Values: dnsQueryTypes},
+	{Name: "+short", Usage: "show short output"},
+	{Name: "+noall", Usage: "clear display flags"},
+	{Name: "+answer", Usage: "show the answer section"},
+	{Name: "+tcp", Usage: "use TCP instead of UDP"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
+// dnsQueryTypes lists the record types isSupportedDNSQueryType accepts.
+func dnsQueryTypes() []string {
+	return []string{"A", "AAAA", "TXT", "CNAME", "NS", "MX", "SRV", "PTR"}
+}
+
 func digUsage(w io.Writer, progName string) {
 	fmt.Fprintf(w, "Usage: gobox %s [@DNS_SERVER] HOST [DNS_SERVER] [OPTIONS]\n", progName)
 	fmt.Fprintln(w, "DNS lookup utility")
@@ -170,12 +194,12 @@ func digUsage(w io.Writer, progName string) {
 // mirrors that upper bound with a single bounded context.
 const dnsQueryTimeout = 15 * time.Second
 
-func dnsQueryContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), dnsQueryTimeout)
+func dnsQueryContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, dnsQueryTimeout)
 }
 
-func doDNSQuery(host, queryType, dnsServer string) error {
-	return doDNSQueryWithResolver(host, queryType, newResolver(dnsServer, false))
+func doDNSQuery(w io.Writer, host, queryType, dnsServer string) error {
+	return doDNSQueryWithResolver(w, host, queryType, newResolver(dnsServer, false))
 }
 
 func newResolver(dnsServer string, useTCP bool) *net.Resolver {
@@ -196,40 +220,40 @@ func newResolver(dnsServer string, useTCP bool) *net.Resolver {
 	}
 }
 
-func doDNSQueryWithResolver(host, queryType string, resolver *net.Resolver) error {
+func doDNSQueryWithResolver(w io.Writer, host, queryType string, resolver *net.Resolver) error {
 	queryType = strings.ToUpper(queryType)
 
 	switch queryType {
 	case "A":
-		return lookupA(host, resolver)
+		return lookupA(w, host, resolver)
 	case "AAAA":
-		return lookupAAAA(host, resolver)
+		return lookupAAAA(w, host, resolver)
 	case "TXT":
-		return lookupTXT(host, resolver)
+		return lookupTXT(w, host, resolver)
 	case "CNAME":
-		return lookupCNAME(host, resolver)
+		return lookupCNAME(w, host, resolver)
 	case "NS":
-		return lookupNS(host, resolver)
+		return lookupNS(w, host, resolver)
 	case "MX":
-		return lookupMX(host, resolver)
+		return lookupMX(w, host, resolver)
 	case "SRV":
-		return lookupSRV(host, resolver)
+		return lookupSRV(w, host, resolver)
 	case "PTR":
-		return lookupPTR(host, resolver)
+		return lookupPTR(w, host, resolver)
 	default:
 		// Default to A lookup
-		return lookupA(host, resolver)
+		return lookupA(w, host, resolver)
 	}
 }
 
-func lookupA(host string, resolver *net.Resolver) error {
-	ctx, cancel := dnsQueryContext()
+func lookupA(w io.Writer, host string, resolver *net.Resolver) error {
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	ips, err := resolver.LookupHost(ctx, host)
 	if err != nil {
 		// Check if it's a DNS error or no such host
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
@@ -238,157 +262,157 @@ func lookupA(host string, resolver *net.Resolver) error {
 		// Only show IPv4 addresses for A records
 		if net.ParseIP(ip).To4() != nil {
 			hadV4 = true
-			fmt.Printf("Name:   %s\nAddress: %s\n\n", host, ip)
+			fmt.Fprintf(w, "Name:   %s\nAddress: %s\n\n", host, ip)
 		}
 	}
 	if !hadV4 {
-		fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+		fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		return errors.New("no A records found")
 	}
 	return nil
 }
 
-func lookupAAAA(host string, resolver *net.Resolver) error {
-	ctx, cancel := dnsQueryContext()
+func lookupAAAA(w io.Writer, host string, resolver *net.Resolver) error {
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	addrs, err := resolver.LookupHost(ctx, host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
-	fmt.Printf("Name:   %s\n", host)
+	fmt.Fprintf(w, "Name:   %s\n", host)
 	hadV6 := false
 	for _, addr := range addrs {
 		ip := net.ParseIP(addr)
 		if ip != nil && ip.To4() == nil {
 			hadV6 = true
-			fmt.Printf("Address: %s\n", addr)
+			fmt.Fprintf(w, "Address: %s\n", addr)
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 	if !hadV6 {
-		fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+		fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		return errors.New("no AAAA records found")
 	}
 	return nil
 }
 
-func lookupTXT(host string, resolver *net.Resolver) error {
-	ctx, cancel := dnsQueryContext()
+func lookupTXT(w io.Writer, host string, resolver *net.Resolver) error {
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	txts, err := resolver.LookupTXT(ctx, host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
-	fmt.Printf("Name:   %s\n", host)
+	fmt.Fprintf(w, "Name:   %s\n", host)
 	for _, txt := range txts {
-		fmt.Printf("TXT:    \"%s\"\n", txt)
+		fmt.Fprintf(w, "TXT:    \"%s\"\n", txt)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 	return nil
 }
 
-func lookupCNAME(host string, resolver *net.Resolver) error {
-	ctx, cancel := dnsQueryContext()
+func lookupCNAME(w io.Writer, host string, resolver *net.Resolver) error {
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	cname, err := resolver.LookupCNAME(ctx, host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
-	fmt.Printf("Name:   %s\n", host)
+	fmt.Fprintf(w, "Name:   %s\n", host)
 	if cnameIsSelf(host, cname) {
-		fmt.Println()
+		fmt.Fprintln(w)
 		return nil
 	}
-	fmt.Printf("Canonical name: %s\n\n", cname)
+	fmt.Fprintf(w, "Canonical name: %s\n\n", cname)
 	return nil
 }
 
-func lookupNS(host string, resolver *net.Resolver) error {
-	ctx, cancel := dnsQueryContext()
+func lookupNS(w io.Writer, host string, resolver *net.Resolver) error {
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	nameservers, err := resolver.LookupNS(ctx, host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
-	fmt.Printf("Name:   %s\n", host)
+	fmt.Fprintf(w, "Name:   %s\n", host)
 	for _, ns := range nameservers {
-		fmt.Printf("Nameserver: %s\n", ns.Host)
+		fmt.Fprintf(w, "Nameserver: %s\n", ns.Host)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 	return nil
 }
 
-func lookupMX(host string, resolver *net.Resolver) error {
-	ctx, cancel := dnsQueryContext()
+func lookupMX(w io.Writer, host string, resolver *net.Resolver) error {
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	mxs, err := resolver.LookupMX(ctx, host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
-	fmt.Printf("Name:   %s\n", host)
+	fmt.Fprintf(w, "Name:   %s\n", host)
 	for _, mx := range mxs {
-		fmt.Printf("Mail exchanger: %d %s\n", mx.Pref, mx.Host)
+		fmt.Fprintf(w, "Mail exchanger: %d %s\n", mx.Pref, mx.Host)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 	return nil
 }
 
-func lookupSRV(host string, resolver *net.Resolver) error {
+func lookupSRV(w io.Writer, host string, resolver *net.Resolver) error {
 	// SRV record format: _service._proto.name
 	// Try to parse and lookup SRV record
-	ctx, cancel := dnsQueryContext()
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	_, addrs, err := resolver.LookupSRV(ctx, "", "", host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
-	fmt.Printf("Name:   %s\n", host)
+	fmt.Fprintf(w, "Name:   %s\n", host)
 	for _, srv := range addrs {
-		fmt.Printf("SRV:    %d %d %d %s\n", srv.Priority, srv.Weight, srv.Port, srv.Target)
+		fmt.Fprintf(w, "SRV:    %d %d %d %s\n", srv.Priority, srv.Weight, srv.Port, srv.Target)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 	return nil
 }
 
-func lookupPTR(host string, resolver *net.Resolver) error {
+func lookupPTR(w io.Writer, host string, resolver *net.Resolver) error {
 	// Reverse lookup
-	ctx, cancel := dnsQueryContext()
+	ctx, cancel := dnsQueryContext(context.Background())
 	defer cancel()
 	names, err := resolver.LookupAddr(ctx, host)
 	if err != nil {
 		if _, ok := err.(*net.DNSError); ok {
-			fmt.Printf("** server can't find %s: NXDOMAIN\n", host)
+			fmt.Fprintf(w, "** server can't find %s: NXDOMAIN\n", host)
 		}
 		return fmt.Errorf("lookup failed: %w", err)
 	}
 	for _, name := range names {
-		fmt.Printf("%s\n", name)
+		fmt.Fprintf(w, "%s\n", name)
 	}
 	return nil
 }
 
-func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
+func digShortOutput(inv *base.Invocation, host, queryType, dnsServer string, useTCP bool) error {
 	queryType = strings.ToUpper(queryType)
 	resolver := newResolver(dnsServer, useTCP)
-	ctx, cancel := dnsQueryContext()
+	ctx, cancel := dnsQueryContext(inv.Ctx())
 	defer cancel()
 
 	switch queryType {
@@ -399,7 +423,7 @@ func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
 		}
 		for _, ip := range ips {
 			if net.ParseIP(ip).To4() != nil {
-				fmt.Println(ip)
+				fmt.Fprintln(inv.Stdout, ip)
 			}
 		}
 	case "AAAA":
@@ -410,7 +434,7 @@ func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
 		for _, addr := range addrs {
 			ip := net.ParseIP(addr)
 			if ip != nil && ip.To4() == nil {
-				fmt.Println(addr)
+				fmt.Fprintln(inv.Stdout, addr)
 			}
 		}
 	case "TXT":
@@ -419,21 +443,21 @@ func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
 			return nil
 		}
 		for _, txt := range txts {
-			fmt.Println(txt)
+			fmt.Fprintln(inv.Stdout, txt)
 		}
 	case "CNAME":
 		cname, err := resolver.LookupCNAME(ctx, host)
 		if err != nil || cnameIsSelf(host, cname) {
 			return nil
 		}
-		fmt.Println(cname)
+		fmt.Fprintln(inv.Stdout, cname)
 	case "NS":
 		nss, err := resolver.LookupNS(ctx, host)
 		if err != nil {
 			return nil
 		}
 		for _, ns := range nss {
-			fmt.Println(ns.Host)
+			fmt.Fprintln(inv.Stdout, ns.Host)
 		}
 	case "MX":
 		mxs, err := resolver.LookupMX(ctx, host)
@@ -441,7 +465,7 @@ func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
 			return nil
 		}
 		for _, mx := range mxs {
-			fmt.Printf("%d %s\n", mx.Pref, mx.Host)
+			fmt.Fprintf(inv.Stdout, "%d %s\n", mx.Pref, mx.Host)
 		}
 	case "SRV":
 		_, addrs, err := resolver.LookupSRV(ctx, "", "", host)
@@ -449,7 +473,7 @@ func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
 			return nil
 		}
 		for _, srv := range addrs {
-			fmt.Printf("%d %d %d %s\n", srv.Priority, srv.Weight, srv.Port, srv.Target)
+			fmt.Fprintf(inv.Stdout, "%d %d %d %s\n", srv.Priority, srv.Weight, srv.Port, srv.Target)
 		}
 	default:
 		ips, err := resolver.LookupHost(ctx, host)
@@ -457,119 +481,119 @@ func digShortOutput(host, queryType, dnsServer string, useTCP bool) error {
 			return nil
 		}
 		for _, ip := range ips {
-			fmt.Println(ip)
+			fmt.Fprintln(inv.Stdout, ip)
 		}
 	}
 
 	return nil
 }
 
-func digAnswerOnly(host, queryType, dnsServer string, useTCP bool) error {
+func digAnswerOnly(inv *base.Invocation, host, queryType, dnsServer string, useTCP bool) error {
 	queryType = strings.ToUpper(queryType)
 	resolver := newResolver(dnsServer, useTCP)
-	ctx, cancel := dnsQueryContext()
+	ctx, cancel := dnsQueryContext(inv.Ctx())
 	defer cancel()
 
-	fmt.Printf(";; ANSWER SECTION:\n")
+	fmt.Fprintf(inv.Stdout, ";; ANSWER SECTION:\n")
 	switch queryType {
 	case "A":
 		ips, err := resolver.LookupHost(ctx, host)
 		if err != nil {
-			fmt.Printf("%s. IN A\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN A\n", host)
 			return nil
 		}
 		for _, ip := range ips {
 			if net.ParseIP(ip).To4() != nil {
-				fmt.Printf("%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
 			}
 		}
 	case "AAAA":
 		addrs, err := resolver.LookupHost(ctx, host)
 		if err != nil {
-			fmt.Printf("%s. IN AAAA\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN AAAA\n", host)
 			return nil
 		}
 		for _, addr := range addrs {
 			ip := net.ParseIP(addr)
 			if ip != nil && ip.To4() == nil {
-				fmt.Printf("%s.\t\t%d\tIN\tAAAA\t%s\n", host, digDefaultTTL, addr)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tAAAA\t%s\n", host, digDefaultTTL, addr)
 			}
 		}
 	case "TXT":
 		txts, err := resolver.LookupTXT(ctx, host)
 		if err != nil {
-			fmt.Printf("%s. IN TXT\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN TXT\n", host)
 			return nil
 		}
 		for _, txt := range txts {
-			fmt.Printf("%s.\t\t%d\tIN\tTXT\t\"%s\"\n", host, digDefaultTTL, txt)
+			fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tTXT\t\"%s\"\n", host, digDefaultTTL, txt)
 		}
 	case "CNAME":
 		cname, err := resolver.LookupCNAME(ctx, host)
 		if err != nil || cnameIsSelf(host, cname) {
-			fmt.Printf("%s. IN CNAME\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN CNAME\n", host)
 			return nil
 		}
-		fmt.Printf("%s.\t\t%d\tIN\tCNAME\t%s\n", host, digDefaultTTL, cname)
+		fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tCNAME\t%s\n", host, digDefaultTTL, cname)
 	case "NS":
 		nss, err := resolver.LookupNS(ctx, host)
 		if err != nil {
-			fmt.Printf("%s. IN NS\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN NS\n", host)
 			return nil
 		}
 		for _, ns := range nss {
-			fmt.Printf("%s.\t\t%d\tIN\tNS\t%s\n", host, digDefaultTTL, ns.Host)
+			fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tNS\t%s\n", host, digDefaultTTL, ns.Host)
 		}
 	case "MX":
 		mxs, err := resolver.LookupMX(ctx, host)
 		if err != nil {
-			fmt.Printf("%s. IN MX\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN MX\n", host)
 			return nil
 		}
 		for _, mx := range mxs {
-			fmt.Printf("%s.\t\t%d\tIN\tMX\t%d %s\n", host, digDefaultTTL, mx.Pref, mx.Host)
+			fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tMX\t%d %s\n", host, digDefaultTTL, mx.Pref, mx.Host)
 		}
 	case "SRV":
 		_, addrs, err := resolver.LookupSRV(ctx, "", "", host)
 		if err != nil {
-			fmt.Printf("%s. IN SRV\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN SRV\n", host)
 			return nil
 		}
 		for _, srv := range addrs {
-			fmt.Printf("%s.\t\t%d\tIN\tSRV\t%d %d %d %s\n", host, digDefaultTTL, srv.Priority, srv.Weight, srv.Port, srv.Target)
+			fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tSRV\t%d %d %d %s\n", host, digDefaultTTL, srv.Priority, srv.Weight, srv.Port, srv.Target)
 		}
 	default:
 		ips, err := resolver.LookupHost(ctx, host)
 		if err != nil {
-			fmt.Printf("%s. IN A\n", host)
+			fmt.Fprintf(inv.Stdout, "%s. IN A\n", host)
 			return nil
 		}
 		for _, ip := range ips {
-			fmt.Printf("%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
+			fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
 		}
 	}
 
 	return nil
 }
 
-func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
+func digFullOutput(inv *base.Invocation, host, queryType, dnsServer string, useTCP bool) error {
 	queryType = strings.ToUpper(queryType)
 	resolver := newResolver(dnsServer, useTCP)
-	ctx, cancel := dnsQueryContext()
+	ctx, cancel := dnsQueryContext(inv.Ctx())
 	defer cancel()
 
 	// Header
-	fmt.Printf("; <<>> DiG 9.18.0 <<>> %s %s @%s\n", queryType, host, dnsServer)
+	fmt.Fprintf(inv.Stdout, "; <<>> DiG 9.18.0 <<>> %s %s @%s\n", queryType, host, dnsServer)
 	if useTCP {
-		fmt.Printf(";; TCP connection\n")
+		fmt.Fprintf(inv.Stdout, ";; TCP connection\n")
 	}
-	fmt.Printf(";; global options: +cmd\n")
+	fmt.Fprintf(inv.Stdout, ";; global options: +cmd\n")
 
 	// Query section
-	fmt.Printf("\n;; Query: %s. %s IN %s\n", host, "300", queryType)
+	fmt.Fprintf(inv.Stdout, "\n;; Query: %s. %s IN %s\n", host, "300", queryType)
 
 	// Answer section
-	fmt.Printf("\n;; ANSWER SECTION:\n")
+	fmt.Fprintf(inv.Stdout, "\n;; ANSWER SECTION:\n")
 	hasAnswer := false
 
 	queryStart := time.Now()
@@ -581,7 +605,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		if err == nil {
 			for _, ip := range ips {
 				if net.ParseIP(ip).To4() != nil {
-					fmt.Printf("%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
+					fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
 					hasAnswer = true
 				}
 			}
@@ -593,7 +617,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 			for _, addr := range addrs {
 				ip := net.ParseIP(addr)
 				if ip != nil && ip.To4() == nil {
-					fmt.Printf("%s.\t\t%d\tIN\tAAAA\t%s\n", host, digDefaultTTL, addr)
+					fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tAAAA\t%s\n", host, digDefaultTTL, addr)
 					hasAnswer = true
 				}
 			}
@@ -603,7 +627,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		queryErr = err
 		if err == nil {
 			for _, txt := range txts {
-				fmt.Printf("%s.\t\t%d\tIN\tTXT\t\"%s\"\n", host, digDefaultTTL, txt)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tTXT\t\"%s\"\n", host, digDefaultTTL, txt)
 				hasAnswer = true
 			}
 		}
@@ -611,7 +635,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		cname, err := resolver.LookupCNAME(ctx, host)
 		queryErr = err
 		if err == nil && !cnameIsSelf(host, cname) {
-			fmt.Printf("%s.\t\t%d\tIN\tCNAME\t%s\n", host, digDefaultTTL, cname)
+			fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tCNAME\t%s\n", host, digDefaultTTL, cname)
 			hasAnswer = true
 		}
 	case "NS":
@@ -619,7 +643,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		queryErr = err
 		if err == nil {
 			for _, ns := range nss {
-				fmt.Printf("%s.\t\t%d\tIN\tNS\t%s\n", host, digDefaultTTL, ns.Host)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tNS\t%s\n", host, digDefaultTTL, ns.Host)
 				hasAnswer = true
 			}
 		}
@@ -628,7 +652,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		queryErr = err
 		if err == nil {
 			for _, mx := range mxs {
-				fmt.Printf("%s.\t\t%d\tIN\tMX\t%d %s\n", host, digDefaultTTL, mx.Pref, mx.Host)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tMX\t%d %s\n", host, digDefaultTTL, mx.Pref, mx.Host)
 				hasAnswer = true
 			}
 		}
@@ -637,7 +661,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		queryErr = err
 		if err == nil {
 			for _, srv := range addrs {
-				fmt.Printf("%s.\t\t%d\tIN\tSRV\t%d %d %d %s\n", host, digDefaultTTL, srv.Priority, srv.Weight, srv.Port, srv.Target)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tSRV\t%d %d %d %s\n", host, digDefaultTTL, srv.Priority, srv.Weight, srv.Port, srv.Target)
 				hasAnswer = true
 			}
 		}
@@ -646,7 +670,7 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 		queryErr = err
 		if err == nil {
 			for _, ip := range ips {
-				fmt.Printf("%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
+				fmt.Fprintf(inv.Stdout, "%s.\t\t%d\tIN\tA\t%s\n", host, digDefaultTTL, ip)
 				hasAnswer = true
 			}
 		}
@@ -656,16 +680,16 @@ func digFullOutput(host, queryType, dnsServer string, useTCP bool) error {
 	if !hasAnswer {
 		if dnsErr, ok := queryErr.(*net.DNSError); ok && dnsErr.IsNotFound {
 			nxdomain = true
-			fmt.Printf(";; ->>HEADER<<- status: NXDOMAIN\n")
+			fmt.Fprintf(inv.Stdout, ";; ->>HEADER<<- status: NXDOMAIN\n")
 		} else {
-			fmt.Printf(";; No answer\n")
+			fmt.Fprintf(inv.Stdout, ";; No answer\n")
 		}
 	}
 
 	// Footer
-	fmt.Printf("\n;; Query time: %d msec\n", time.Since(queryStart).Milliseconds())
-	fmt.Printf(";; SERVER: %s#53(%s)\n", dnsServer, dnsServer)
-	fmt.Printf(";; WHEN: %s\n", time.Now().Format("Mon Jan 2 15:04:05 MST 2006"))
+	fmt.Fprintf(inv.Stdout, "\n;; Query time: %d msec\n", time.Since(queryStart).Milliseconds())
+	fmt.Fprintf(inv.Stdout, ";; SERVER: %s#53(%s)\n", dnsServer, dnsServer)
+	fmt.Fprintf(inv.Stdout, ";; WHEN: %s\n", time.Now().Format("Mon Jan 2 15:04:05 MST 2006"))
 
 	if nxdomain {
 		return digNXDOMAINError{host: host}
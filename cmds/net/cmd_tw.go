@@ -2,6 +2,7 @@ package net
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -12,11 +13,16 @@ import (
 	"strings"
 	"syscall"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
 // twCmd implements a tiny web server
 func TwCmd(args []string) error {
+	return twCmd(base.Stdio(), args)
+}
+
+func twCmd(inv *base.Invocation, args []string) error {
 	var (
 		port     int    = 8080
 		dir      string = "."
@@ -71,12 +77,12 @@ func TwCmd(args []string) error {
 doneFlags:
 
 	if showHelp {
-		printTwUsage(os.Stdout)
+		printTwUsage(inv.Stdout)
 		return nil
 	}
 
 	if !bench {
-		info, err := os.Stat(dir)
+		info, err := os.Stat(inv.Path(dir))
 		if err != nil {
 			return fmt.Errorf("invalid directory %s: %w", dir, err)
 		}
@@ -91,9 +97,11 @@ doneFlags:
 	mux := http.NewServeMux()
 	if bench {
 		mux.HandleFunc("/ping", handlePing)
+		// /upload only counts the request body and never writes it, so
+		// it needs no read-only check.
 		mux.HandleFunc("/upload", handleUpload)
 	} else {
-		mux.HandleFunc("/", MakeStaticHandler(dir))
+		mux.HandleFunc("/", MakeStaticHandler(inv.Path(dir)))
 	}
 
 	// Configure HTTP server
@@ -109,15 +117,44 @@ doneFlags:
 			return fmt.Errorf("failed to create listener: %w", err)
 		}
 		if bench {
-			fmt.Fprintf(os.Stderr, "tw: starting benchmark server on %s (SO_REUSEADDR)\n", addr)
+			fmt.Fprintf(inv.Stderr, "tw: starting benchmark server on %s (SO_REUSEADDR)\n", addr)
 		} else {
-			fmt.Fprintf(os.Stderr, "tw: starting server on %s, serving %s (SO_REUSEADDR)\n", addr, dir)
+			fmt.Fprintf(inv.Stderr, "tw: starting server on %s, serving %s (SO_REUSEADDR)\n", addr, dir)
 		}
-		return server.Serve(ln)
+		return serveTwUntilDone(inv, server, func() error { return server.Serve(ln) })
 	}
 
-	fmt.Fprintf(os.Stderr, "tw: starting server on %s, serving %s\n", addr, dir)
-	return server.ListenAndServe()
+	fmt.Fprintf(inv.Stderr, "tw: starting server on %s, serving %s\n", addr, dir)
+	return serveTwUntilDone(inv, server, server.ListenAndServe)
+}
+
+// serveTwUntilDone runs serve and closes server once the invocation is
+// cancelled, reporting that shutdown as a clean exit.
+func serveTwUntilDone(inv *base.Invocation, server *http.Server, serve func() error) error {
+	ctx := inv.Ctx()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = server.Close()
+		case <-done:
+		}
+	}()
+	err := serve()
+	if errors.Is(err, http.ErrServerClosed) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// twFlags declares tw's options for shell completion.
+var twFlags = []base.Flag{
+	{Name: "-p", TakesValue: true, Usage: "listen port"}, {Name: "--port", TakesValue: true, Usage: "listen port"},
+	{Name: "-d", TakesValue: true, Usage: "directory to serve"}, {Name: "--dir", TakesValue: true, Usage: "directory to serve"},
+	{Name: "-r", Usage: "enable SO_REUSEADDR"}, {Name: "--reuse", Usage: "enable SO_REUSEADDR"},
+	{Name: "--bench", Usage: "benchmark mode"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
 }
 
 func printTwUsage(w io.Writer) {
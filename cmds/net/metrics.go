@@ -0,0 +1,92 @@
+package net
+
+import (
+	"strconv"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// tcpMetricStates lists every state /proc/net/tcp reports, so each one has a
+// series even while it has no sockets.
+var tcpMetricStates = []string{"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT", "CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING"}
+
+// collectNetMetrics exposes the interface counters netstat -i reads from
+// /proc/net/dev and the protocol counters netstat -s reads from
+// /proc/net/snmp, netstat and snmp6.
+func collectNetMetrics(w *utils.MetricsWriter, opts base.MetricsOptions) error {
+	ifaces, err := parseProcNetDev(opts.Roots.ProcNetPath("dev"))
+	if err != nil {
+		return err
+	}
+	for _, m := range []struct {
+		name, help string
+		value      func(netstatInterface) uint64
+	}{
+		{"gobox_network_receive_bytes_total", "Bytes received.", func(i netstatInterface) uint64 { return i.RXBytes }},
+		{"gobox_network_receive_packets_total", "Packets received.", func(i netstatInterface) uint64 { return i.RXOK }},
+		{"gobox_network_receive_errs_total", "Receive errors.", func(i netstatInterface) uint64 { return i.RXErr }},
+		{"gobox_network_receive_drop_total", "Received packets dropped.", func(i netstatInterface) uint64 { return i.RXDrop }},
+		{"gobox_network_transmit_bytes_total", "Bytes transmitted.", func(i netstatInterface) uint64 { return i.TXBytes }},
+		{"gobox_network_transmit_packets_total", "Packets transmitted.", func(i netstatInterface) uint64 { return i.TXOK }},
+		{"gobox_network_transmit_errs_total", "Transmit errors.", func(i netstatInterface) uint64 { return i.TXErr }},
+		{"gobox_network_transmit_drop_total", "Transmitted packets dropped.", func(i netstatInterface) uint64 { return i.TXDrop }},
+	} {
+		w.Family(m.name, utils.MetricCounter, m.help)
+		for _, iface := range ifaces {
+			w.Sample(m.name, float64(m.value(iface)), "device", iface.Name)
+		}
+	}
+
+	sections, err := parseNetstatStatsFiles([]string{opts.Roots.ProcNetPath("snmp"), opts.Roots.ProcNetPath("netstat"), opts.Roots.ProcNetPath("snmp6")})
+	if err != nil {
+		return err
+	}
+	for _, section := range sections {
+		for _, field := range section.Fields {
+			v, err := strconv.ParseFloat(section.Stats[field], 64)
+			if err != nil {
+				continue
+			}
+			name := "gobox_netstat_" + utils.MetricName(section.Name) + "_" + utils.MetricName(field)
+			w.Family(name, utils.MetricUntyped, "Statistic "+section.Name+" "+field+" from netstat -s.")
+			w.Sample(name, v)
+		}
+	}
+	return w.Err()
+}
+
+// collectTCPMetrics counts IPv4 and IPv6 TCP sockets by state, as netstat
+// -ta lists them.
+func collectTCPMetrics(w *utils.MetricsWriter, opts base.MetricsOptions) error {
+	counts := map[string]map[string]int{}
+	var firstErr error
+	found := false
+	for _, proto := range []string{"tcp", "tcp6"} {
+		conns, err := parseProcNetTCP(opts.Roots.ProcNetPath(proto), proto)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		found = true
+		counts[proto] = map[string]int{}
+		for _, c := range conns {
+			counts[proto][c.State]++
+		}
+	}
+	if !found {
+		return firstErr
+	}
+	w.Family("gobox_tcp_connections", utils.MetricGauge, "TCP sockets by state.")
+	for _, proto := range []string{"tcp", "tcp6"} {
+		if counts[proto] == nil {
+			continue
+		}
+		for _, state := range tcpMetricStates {
+			w.Sample("gobox_tcp_connections", float64(counts[proto][state]), "proto", proto, "state", state)
+		}
+	}
+	return w.Err()
+}
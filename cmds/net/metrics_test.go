@@ -0,0 +1,72 @@
+package net
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"gobox/internal/testutil"
+)
+
+// useNetMetricsFixture writes files under ROOT/1/net and returns roots
+// reading them.
+func useNetMetricsFixture(t *testing.T, files map[string]string) utils.Roots {
+	t.Helper()
+	tree := map[string]string{}
+	for name, content := range files {
+		tree["1/net/"+name] = content
+	}
+	return utils.NewRoots(testutil.Tree(t, tree), "")
+}
+
+func TestCollectTCPMetricsCountsEveryState(t *testing.T) {
+	header := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	roots := useNetMetricsFixture(t, map[string]string{
+		"tcp": header +
+			"   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 100 1\n" +
+			"   1: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 101 1\n" +
+			"   2: 0100007F:1F90 0100007F:C351 01 00000000:00000000 00:00000000 00000000     0        0 102 1\n",
+	})
+	var buf bytes.Buffer
+	if err := collectTCPMetrics(utils.NewMetricsWriter(&buf), base.MetricsOptions{Roots: roots}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`gobox_tcp_connections{proto="tcp",state="LISTEN"} 1`,
+		`gobox_tcp_connections{proto="tcp",state="ESTABLISHED"} 2`,
+		`gobox_tcp_connections{proto="tcp",state="TIME_WAIT"} 0`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Fatalf("missing %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, `proto="tcp6"`) {
+		t.Fatalf("tcp6 is absent from the fixture but was reported: %q", out)
+	}
+}
+
+func TestCollectNetMetricsInterfacesAndCounters(t *testing.T) {
+	roots := useNetMetricsFixture(t, map[string]string{
+		"dev": "Inter-|   Receive                                                |  Transmit\n" +
+			" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n" +
+			"  eth0: 1000 10 1 2 0 0 0 0 2000 20 3 4 0 0 0 0\n",
+		"snmp": "Tcp: RtoAlgorithm ActiveOpens\nTcp: 1 42\n",
+	})
+	var buf bytes.Buffer
+	if err := collectNetMetrics(utils.NewMetricsWriter(&buf), base.MetricsOptions{Roots: roots}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`gobox_network_receive_bytes_total{device="eth0"} 1000`,
+		`gobox_network_transmit_drop_total{device="eth0"} 4`,
+		`gobox_netstat_Tcp_ActiveOpens 42`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Fatalf("missing %q in %q", want, out)
+		}
+	}
+}
@@ -3,13 +3,23 @@ package net
 import "gobox/cmds/base"
 
 func init() {
-	base.Register(base.NewCommand("netstat", "Show network connection status", base.Adapt(NetstatCmd)))
-	base.Register(base.NewCommand("ip", "Show network interfaces, routes, and neighbours", base.Adapt(IpCmd)))
-	base.Register(base.NewCommand("curl", "Transfer data from a URL", base.Adapt(CurlCmd)))
-	base.Register(base.NewCommand("dig", "DNS lookup utility", base.Adapt(DigCmd)))
-	base.Register(base.NewCommand("nslookup", "DNS lookup utility", base.Adapt(NslookupCmd)))
-	base.Register(base.NewCommand("nc", "Netcat - arbitrary TCP/UDP connections and listening", base.Adapt(NcCmd)))
-	base.Register(base.NewCommand("tw", "Tiny web server for static files or benchmark", base.Adapt(TwCmd)))
-	base.Register(base.NewCommand("ifstat", "Network interface statistics monitoring", base.Adapt(IfstatCmd)))
-	base.Register(base.NewCommand("np", "Network ping/connectivity tool (TCP/UDP/ICMP/ARP/scan)", base.Adapt(NpCmd)))
+	base.Register(base.NewCommand("netstat", "Show network connection status", netstatCmd, base.WithTabularOutput(),
+		base.WithFlagValues("--sort", netstatSortKeys)))
+	base.Register(base.NewCommand("ip", "Show network interfaces, routes, and neighbours", ipCmd, base.WithTabularOutput(), base.WithFlags(ipFlags...)))
+	base.Register(base.NewCommand("curl", "Transfer data from a URL", curlCmd, base.WithFlags(curlFlags...),
+		base.WithFlagValues("-X", curlMethods), base.WithFlagValues("--request", curlMethods)))
+	base.Register(base.NewCommand("dig", "DNS lookup utility", digCmd, base.WithFlags(digFlags...)))
+	base.Register(base.NewCommand("nslookup", "DNS lookup utility", nslookupCmd, base.WithFlags(digFlags...)))
+	base.Register(base.NewCommand("nc", "Netcat - arbitrary TCP/UDP connections and listening", ncCmd, base.WithFlags(ncFlags...)))
+	base.Register(base.NewCommand("tw", "Tiny web server for static files or benchmark", twCmd, base.WithFlags(twFlags...)))
+	base.Register(base.NewCommand("ifstat", "Network interface statistics monitoring", ifstatCmd, base.WithTabularOutput(),
+		base.WithFlagValues("-i", interfaceNames)))
+	base.Register(base.NewCommand("np", "Network ping/connectivity tool (TCP/UDP/ICMP/ARP/scan)", npCmd,
+		base.WithFlagValues("-I", interfaceNames)))
+	base.RegisterMetricsCollector(base.MetricsCollector{Name: "net", Help: "Interface and protocol counters (netstat -i, netstat -s)", Collect: collectNetMetrics})
+	base.RegisterMetricsCollector(base.MetricsCollector{Name: "tcp", Help: "TCP sockets by state", Collect: collectTCPMetrics})
+	base.RegisterCheckType(base.CheckType{Name: "tcp", Help: "address (HOST:PORT) accepts a TCP connection within timeout (3s)", Run: runTCPCheck})
+	base.RegisterCheckType(base.CheckType{Name: "dns", Help: "host resolves (record A, server, expect)", Run: runDNSCheck})
+	base.RegisterCheckType(base.CheckType{Name: "http", Help: "url answers with status (200) within timeout (5s); method, contains, insecure", Run: runHTTPCheck})
+	base.RegisterCheckType(base.CheckType{Name: "cert", Help: "TLS certificate at address (HOST[:443]) is valid for more than days (14)", Run: runCertCheck})
 }
@@ -0,0 +1,76 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gobox/cmds/base"
+)
+
+// runZombiesCheck fails when more than "max" (0) processes are zombies.
+func runZombiesCheck(inv *base.Invocation, c base.Check) (string, error) {
+	limit, err := c.IntParam("max", 0)
+	if err != nil {
+		return "", err
+	}
+	snap, err := captureLinuxProcSnapshot(inv.Roots())
+	if err != nil {
+		return "", err
+	}
+	var pids []int
+	for pid, pi := range snap.infos {
+		if strings.HasPrefix(pi.state, "Z") {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) <= limit {
+		return fmt.Sprintf("%d zombie processes", len(pids)), nil
+	}
+	sort.Ints(pids)
+	shown := make([]string, 0, len(pids))
+	for i, pid := range pids {
+		if i == 5 {
+			shown = append(shown, "...")
+			break
+		}
+		shown = append(shown, strconv.Itoa(pid))
+	}
+	return "", fmt.Errorf("%d zombie processes (pid %s), max %d", len(pids), strings.Join(shown, ","), limit)
+}
+
+// runProcessCheck counts processes whose command name is "command", as
+// ps -C does, and fails outside [min, max].
+func runProcessCheck(inv *base.Invocation, c base.Check) (string, error) {
+	name, err := c.RequireParam("command")
+	if err != nil {
+		return "", err
+	}
+	lo, err := c.IntParam("min", 1)
+	if err != nil {
+		return "", err
+	}
+	hi, err := c.IntParam("max", -1)
+	if err != nil {
+		return "", err
+	}
+	snap, err := captureLinuxProcSnapshot(inv.Roots())
+	if err != nil {
+		return "", err
+	}
+	n := 0
+	for _, pi := range snap.infos {
+		if pi.exe == name && !strings.HasPrefix(pi.state, "Z") {
+			n++
+		}
+	}
+	detail := fmt.Sprintf("%d %s processes", n, name)
+	if n < lo {
+		return "", fmt.Errorf("%s, want at least %d", detail, lo)
+	}
+	if hi >= 0 && n > hi {
+		return "", fmt.Errorf("%s, want at most %d", detail, hi)
+	}
+	return detail, nil
+}
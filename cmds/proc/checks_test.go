@@ -0,0 +1,41 @@
+package proc
+
+import (
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+)
+
+func TestZombiesAndProcessChecks(t *testing.T) {
+	zombie := strings.Replace(fixtureProcStat("12", "defunct", 1), ") S ", ") Z ", 1)
+	useProcFixture(t, map[string]string{
+		"stat":      "cpu  100 0 100 1000 0 0 0 0 0 0\nbtime 1700000000\n",
+		"10/stat":   fixtureProcStat("10", "nginx", 1),
+		"11/stat":   fixtureProcStat("11", "nginx", 10),
+		"12/stat":   zombie,
+		"10/comm":   "nginx\n",
+		"11/comm":   "nginx\n",
+		"12/comm":   "defunct\n",
+		"10/status": "Uid:\t0\t0\t0\t0\n",
+		"11/status": "Uid:\t0\t0\t0\t0\n",
+		"12/status": "Uid:\t0\t0\t0\t0\n",
+	})
+	inv := &base.Invocation{}
+
+	if _, err := runZombiesCheck(inv, base.Check{Type: "zombies"}); err == nil || !strings.Contains(err.Error(), "pid 12") {
+		t.Fatalf("expected zombie failure naming pid 12, got %v", err)
+	}
+	if _, err := runZombiesCheck(inv, base.Check{Type: "zombies", Params: map[string]string{"max": "1"}}); err != nil {
+		t.Fatalf("max 1 should pass: %v", err)
+	}
+	if detail, err := runProcessCheck(inv, base.Check{Type: "process", Params: map[string]string{"command": "nginx", "min": "2"}}); err != nil || detail != "2 nginx processes" {
+		t.Fatalf("nginx check: %q, %v", detail, err)
+	}
+	if _, err := runProcessCheck(inv, base.Check{Type: "process", Params: map[string]string{"command": "nginx", "max": "1"}}); err == nil {
+		t.Fatal("expected max failure")
+	}
+	if _, err := runProcessCheck(inv, base.Check{Type: "process", Params: map[string]string{"command": "postgres"}}); err == nil {
+		t.Fatal("expected missing process failure")
+	}
+}
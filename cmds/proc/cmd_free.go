@@ -1,15 +1,12 @@
 package proc
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"io"
-	"os"
-	"strconv"
-	"strings"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
@@ -19,6 +16,10 @@ var (
 )
 
 func FreeCmd(args []string) error {
+	return freeCmd(base.Stdio(), args)
+}
+
+func freeCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("free", flag.ContinueOnError)
 	human := fsFlags.Bool("h", false, "human readable")
 	bytesUnit := fsFlags.Bool("b", false, "show bytes")
@@ -28,21 +29,21 @@ func FreeCmd(args []string) error {
 	interval := fsFlags.Int("s", 0, "repeat every SEC seconds")
 	count := fsFlags.Int("c", 1, "repeat COUNT times")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox free [OPTION]...")
-		fmt.Fprintln(os.Stderr, "Display memory and swap usage.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Units:")
-		fmt.Fprintln(os.Stderr, "  -h          human readable")
-		fmt.Fprintln(os.Stderr, "  -b          show bytes")
-		fmt.Fprintln(os.Stderr, "  -k          show KiB (default)")
-		fmt.Fprintln(os.Stderr, "  -m          show MiB")
-		fmt.Fprintln(os.Stderr, "  -g          show GiB")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Sampling:")
-		fmt.Fprintln(os.Stderr, "  -s SEC      repeat every SEC seconds")
-		fmt.Fprintln(os.Stderr, "  -c COUNT    repeat COUNT times")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "      --help  show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox free [OPTION]...")
+		fmt.Fprintln(inv.Stderr, "Display memory and swap usage.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Units:")
+		fmt.Fprintln(inv.Stderr, "  -h          human readable")
+		fmt.Fprintln(inv.Stderr, "  -b          show bytes")
+		fmt.Fprintln(inv.Stderr, "  -k          show KiB (default)")
+		fmt.Fprintln(inv.Stderr, "  -m          show MiB")
+		fmt.Fprintln(inv.Stderr, "  -g          show GiB")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Sampling:")
+		fmt.Fprintln(inv.Stderr, "  -s SEC      repeat every SEC seconds")
+		fmt.Fprintln(inv.Stderr, "  -c COUNT    repeat COUNT times")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "      --help  show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -54,63 +55,74 @@ func FreeCmd(args []string) error {
 	if *count <= 0 {
 		*count = 1
 	}
+	var tw *utils.TableWriter
+	if utils.IsStructuredOutput(inv.Output) {
+		tw = utils.NewTableWriter(inv.Stdout, inv.Output)
+	}
 	for i := 0; i < *count; i++ {
 		if i > 0 {
 			freeSleep(time.Duration(*interval) * time.Second)
 		}
-		mem, err := readMemInfoData()
+		mem, err := readMemInfoData(inv.Roots())
 		if err != nil {
 			return err
 		}
-		printFree(mem, *human, *bytesUnit, *miB, *giB)
+		if tw != nil {
+			if err := tw.Write(freeTable(mem)); err != nil {
+				return err
+			}
+			continue
+		}
+		printFree(inv.Stdout, mem, *human, *bytesUnit, *miB, *giB)
 	}
 	return nil
 }
 
-func readMemInfo() (map[string]uint64, error) {
-	f, err := os.Open("/proc/meminfo")
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	return parseMemInfo(f)
+func readMemInfo(roots utils.Roots) (map[string]uint64, error) {
+	return roots.ProcFS().MemInfo()
 }
 
-func parseMemInfo(r io.Reader) (map[string]uint64, error) {
-	out := map[string]uint64{}
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 2 {
-			continue
-		}
-		v, err := strconv.ParseUint(fields[1], 10, 64)
-		if err == nil {
-			out[strings.TrimSuffix(fields[0], ":")] = v * 1024
-		}
-	}
-	return out, scanner.Err()
+// freeStats holds the derived byte counts both renderers report.
+type freeStats struct {
+	total, used, free, shared, buffCache, available uint64
+	swapTotal, swapUsed, swapFree                   uint64
 }
 
-func printFree(m map[string]uint64, human, bytesUnit, miB, giB bool) {
-	total := m["MemTotal"]
-	free := m["MemFree"]
-	shared := m["Shmem"]
-	buffCache := m["Buffers"] + m["Cached"] + m["SReclaimable"]
-	available := m["MemAvailable"]
-	var used uint64
-	if total > free+buffCache {
-		used = total - free - buffCache
+func computeFree(m map[string]uint64) freeStats {
+	st := freeStats{
+		total:     m["MemTotal"],
+		free:      m["MemFree"],
+		shared:    m["Shmem"],
+		buffCache: m["Buffers"] + m["Cached"] + m["SReclaimable"],
+		available: m["MemAvailable"],
+		swapTotal: m["SwapTotal"],
+		swapFree:  m["SwapFree"],
 	}
-	swapTotal := m["SwapTotal"]
-	swapFree := m["SwapFree"]
-	var swapUsed uint64
-	if swapTotal > swapFree {
-		swapUsed = swapTotal - swapFree
+	if st.total > st.free+st.buffCache {
+		st.used = st.total - st.free - st.buffCache
 	}
-	fmt.Printf("%13s %12s %12s %12s %12s %12s\n", "total", "used", "free", "shared", "buff/cache", "available")
-	fmt.Printf("Mem:  %12s %12s %12s %12s %12s %12s\n", formatMem(total, human, bytesUnit, miB, giB), formatMem(used, human, bytesUnit, miB, giB), formatMem(free, human, bytesUnit, miB, giB), formatMem(shared, human, bytesUnit, miB, giB), formatMem(buffCache, human, bytesUnit, miB, giB), formatMem(available, human, bytesUnit, miB, giB))
-	fmt.Printf("Swap: %12s %12s %12s\n", formatMem(swapTotal, human, bytesUnit, miB, giB), formatMem(swapUsed, human, bytesUnit, miB, giB), formatMem(swapFree, human, bytesUnit, miB, giB))
+	if st.swapTotal > st.swapFree {
+		st.swapUsed = st.swapTotal - st.swapFree
+	}
+	return st
+}
+
+// freeTable reports raw bytes; the swap row has no shared/buff/cache/available.
+func freeTable(m map[string]uint64) *utils.Table {
+	st := computeFree(m)
+	t := utils.NewTable("type", "total_bytes", "used_bytes", "free_bytes", "shared_bytes", "buff_cache_bytes", "available_bytes")
+	t.Append("mem", st.total, st.used, st.free, st.shared, st.buffCache, st.available)
+	t.Append("swap", st.swapTotal, st.swapUsed, st.swapFree, nil, nil, nil)
+	return t
+}
+
+func printFree(w io.Writer, m map[string]uint64, human, bytesUnit, miB, giB bool) {
+	st := computeFree(m)
+	total, used, free, shared, buffCache, available := st.total, st.used, st.free, st.shared, st.buffCache, st.available
+	swapTotal, swapUsed, swapFree := st.swapTotal, st.swapUsed, st.swapFree
+	fmt.Fprintf(w, "%13s %12s %12s %12s %12s %12s\n", "total", "used", "free", "shared", "buff/cache", "available")
+	fmt.Fprintf(w, "Mem:  %12s %12s %12s %12s %12s %12s\n", formatMem(total, human, bytesUnit, miB, giB), formatMem(used, human, bytesUnit, miB, giB), formatMem(free, human, bytesUnit, miB, giB), formatMem(shared, human, bytesUnit, miB, giB), formatMem(buffCache, human, bytesUnit, miB, giB), formatMem(available, human, bytesUnit, miB, giB))
+	fmt.Fprintf(w, "Swap: %12s %12s %12s\n", formatMem(swapTotal, human, bytesUnit, miB, giB), formatMem(swapUsed, human, bytesUnit, miB, giB), formatMem(swapFree, human, bytesUnit, miB, giB))
 }
 
 func formatMem(v uint64, human, bytesUnit, miB, giB bool) string {
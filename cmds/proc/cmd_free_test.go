@@ -1,10 +1,14 @@
 package proc
 
 import (
+	"bytes"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
 )
 
 func TestFreeProducesMemoryRows(t *testing.T) {
@@ -107,7 +111,7 @@ func TestFreeKiBUnit(t *testing.T) {
 // KiB-divided) using injected meminfo data.
 func TestFreeBytesUnitValues(t *testing.T) {
 	setupFreeInjected(t)
-	readMemInfoData = func() (map[string]uint64, error) {
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
 		return map[string]uint64{
 			"MemTotal":     2048,
 			"MemFree":      1024,
@@ -128,7 +132,7 @@ func TestFreeBytesUnitValues(t *testing.T) {
 // "buff/cache". Native free reads it from /proc/meminfo's Shmem field.
 func TestFreeIncludesSharedColumn(t *testing.T) {
 	setupFreeInjected(t)
-	readMemInfoData = func() (map[string]uint64, error) {
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
 		return map[string]uint64{
 			"MemTotal":     2048,
 			"MemFree":      1024,
@@ -171,7 +175,7 @@ func TestFreeIncludesSharedColumn(t *testing.T) {
 // consistently with the other columns across -m/-g.
 func TestFreeSharedColumnRespectsUnits(t *testing.T) {
 	setupFreeInjected(t)
-	readMemInfoData = func() (map[string]uint64, error) {
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
 		return map[string]uint64{
 			"MemTotal":     4 * 1024 * 1024,
 			"MemFree":      1 * 1024 * 1024,
@@ -233,7 +237,7 @@ func setupFreeInjected(t *testing.T) {
 
 func TestFreeReadErrorReturnsError(t *testing.T) {
 	setupFreeInjected(t)
-	readMemInfoData = func() (map[string]uint64, error) { return nil, os.ErrPermission }
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) { return nil, os.ErrPermission }
 	if _, err := captureProcCmd(t, func() error { return FreeCmd(nil) }); err == nil {
 		t.Fatal("expected meminfo read error")
 	}
@@ -241,7 +245,7 @@ func TestFreeReadErrorReturnsError(t *testing.T) {
 
 func TestFreeZeroSwapAndSmallMiBValuesAreStable(t *testing.T) {
 	setupFreeInjected(t)
-	readMemInfoData = func() (map[string]uint64, error) {
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
 		return map[string]uint64{
 			"MemTotal":     512 * 1024,
 			"MemFree":      128 * 1024,
@@ -264,7 +268,7 @@ func TestFreeZeroSwapAndSmallMiBValuesAreStable(t *testing.T) {
 
 func TestFreeHumanAndGiBUnitsAreStable(t *testing.T) {
 	setupFreeInjected(t)
-	readMemInfoData = func() (map[string]uint64, error) {
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
 		return map[string]uint64{
 			"MemTotal":     2 * 1024 * 1024 * 1024,
 			"MemFree":      1 * 1024 * 1024 * 1024,
@@ -291,7 +295,7 @@ func TestFreeCountUsesInjectedSleepBetweenSamples(t *testing.T) {
 	setupFreeInjected(t)
 	reads := 0
 	sleeps := 0
-	readMemInfoData = func() (map[string]uint64, error) {
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
 		reads++
 		return map[string]uint64{"MemTotal": 1024, "MemFree": 512, "MemAvailable": 512}, nil
 	}
@@ -305,15 +309,33 @@ func TestFreeCountUsesInjectedSleepBetweenSamples(t *testing.T) {
 	}
 }
 
-func TestParseMemInfo(t *testing.T) {
-	mem, err := parseMemInfo(strings.NewReader("MemTotal: 2 kB\nBad:\nCached: nope kB\nSwapFree: 1 kB\n"))
-	if err != nil {
+func TestFreeStructuredOutputKeepsBytes(t *testing.T) {
+	setupFreeInjected(t)
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
+		return map[string]uint64{"MemTotal": 4096, "MemFree": 1024, "Buffers": 512, "Shmem": 8, "MemAvailable": 2048, "SwapTotal": 100, "SwapFree": 40}, nil
+	}
+	freeSleep = func(time.Duration) {}
+	var out bytes.Buffer
+	cmd := base.NewCommand("free", "", freeCmd, base.WithTabularOutput())
+	if err := cmd.Run(&base.Invocation{Stdout: &out}, []string{"-h", "-c", "2", "--output=ndjson"}); err != nil {
 		t.Fatal(err)
 	}
-	if mem["MemTotal"] != 2048 || mem["SwapFree"] != 1024 {
-		t.Fatalf("unexpected parsed meminfo %#v", mem)
+	mem := `{"type":"mem","total_bytes":4096,"used_bytes":2560,"free_bytes":1024,"shared_bytes":8,"buff_cache_bytes":512,"available_bytes":2048}`
+	swap := `{"type":"swap","total_bytes":100,"used_bytes":60,"free_bytes":40,"shared_bytes":null,"buff_cache_bytes":null,"available_bytes":null}`
+	if want := strings.Repeat(mem+"\n"+swap+"\n", 2); out.String() != want {
+		t.Fatalf("unexpected ndjson output:\n%s", out.String())
+	}
+}
+
+func TestFreeReadsProcRoot(t *testing.T) {
+	useProcFixture(t, map[string]string{
+		"meminfo": "MemTotal:        2048000 kB\nMemFree:          512000 kB\nMemAvailable:    1024000 kB\nBuffers:           10000 kB\nCached:           200000 kB\nShmem:              1000 kB\nSReclaimable:      20000 kB\nSwapTotal:             0 kB\nSwapFree:              0 kB\n",
+	})
+	out, err := captureProcCmd(t, func() error { return FreeCmd(nil) })
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, ok := mem["Cached"]; ok {
-		t.Fatalf("invalid numeric field should be ignored: %#v", mem)
+	if !strings.Contains(out, "2048000") || !strings.Contains(out, "1024000") {
+		t.Fatalf("expected the fixture meminfo, got %q", out)
 	}
 }
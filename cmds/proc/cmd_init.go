@@ -0,0 +1,294 @@
+package proc
+
+import (
+	"flag"
+	"fmt"
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Restart policies for init --restart.
+const (
+	initRestartNever     = "never"
+	initRestartOnFailure = "on-failure"
+	initRestartAlways    = "always"
+)
+
+// initMaxBackoff caps the doubling restart delay; a child that ran at least
+// this long before exiting starts over from --backoff.
+const initMaxBackoff = time.Minute
+
+// initReapInterval is how often init reaps even without a SIGCHLD, since
+// signal delivery to a full channel is dropped.
+const initReapInterval = time.Second
+
+// prSetChildSubreaper is PR_SET_CHILD_SUBREAPER from <linux/prctl.h>.
+const prSetChildSubreaper = 36
+
+type initExitError int
+
+func (e initExitError) Error() string { return fmt.Sprintf("exit code %d", int(e)) }
+func (e initExitError) ExitCode() int { return int(e) }
+
+// SuppressCLIError keeps init silent about the child's own exit status, which
+// it only propagates, as timeout does.
+func (e initExitError) SuppressCLIError() bool { return true }
+
+type initOptions struct {
+	group      bool
+	grace      time.Duration
+	stopSignal syscall.Signal
+	restart    string
+	maxRestart int
+	backoff    time.Duration
+}
+
+func InitCmd(args []string) error {
+	return initCmd(base.Stdio(), args)
+}
+
+func initCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("init", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	group := fsFlags.Bool("g", false, "run the command in its own process group and signal the whole group")
+	graceArg := fsFlags.String("grace", "", "SIGKILL the command this long after a stop signal")
+	stopArg := fsFlags.String("stop-signal", "TERM", "signal to send the command when init receives SIGTERM")
+	restart := fsFlags.String("restart", initRestartNever, "restart policy: never, on-failure or always")
+	maxRestart := fsFlags.Int("max-restarts", 0, "give up after N restarts (0 = no limit)")
+	backoffArg := fsFlags.String("backoff", "1s", "delay before the first restart, doubling up to 1m")
+	fsFlags.Usage = func() { printInitUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	argv := fsFlags.Args()
+	if len(argv) > 0 && argv[0] == "--" {
+		argv = argv[1:]
+	}
+	if len(argv) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	if err := base.CheckExternal(inv, argv[0]); err != nil {
+		return err
+	}
+	opts := initOptions{group: *group, restart: *restart, maxRestart: *maxRestart}
+	switch opts.restart {
+	case initRestartNever, initRestartOnFailure, initRestartAlways:
+	default:
+		return fmt.Errorf("invalid --restart %q (want never, on-failure or always)", opts.restart)
+	}
+	if opts.maxRestart < 0 {
+		return fmt.Errorf("invalid --max-restarts %d", opts.maxRestart)
+	}
+	var err error
+	if *graceArg != "" {
+		if opts.grace, err = parseDurationArg(*graceArg); err != nil {
+			return err
+		}
+	}
+	if opts.backoff, err = parseDurationArg(*backoffArg); err != nil {
+		return err
+	}
+	sig, err := parseSignal(*stopArg)
+	if err != nil {
+		return err
+	}
+	opts.stopSignal = sig.(syscall.Signal)
+
+	if os.Getpid() != 1 {
+		// Outside PID 1, orphans of the command are reparented to init only
+		// if it is a child subreaper.
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+			fmt.Fprintf(inv.Stderr, "init: cannot become a child subreaper: %v\n", errno)
+		}
+	}
+	sigs := make(chan os.Signal, 64)
+	signal.Notify(sigs)
+	defer signal.Stop(sigs)
+	return runInit(inv, opts, argv, sigs)
+}
+
+func printInitUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox init [OPTION]... [--] COMMAND [ARG]...")
+	fmt.Fprintln(w, "Run COMMAND as a minimal PID-1 init: forward signals to it, reap orphaned")
+	fmt.Fprintln(w, "zombies, and exit with its status (128+N when killed by signal N).")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -g                     run COMMAND in its own process group and signal the group")
+	fmt.Fprintln(w, "  --grace DURATION       SIGKILL COMMAND if it is still running this long after")
+	fmt.Fprintln(w, "                         SIGTERM, SIGINT or SIGQUIT")
+	fmt.Fprintln(w, "  --stop-signal SIGNAL   send SIGNAL instead of SIGTERM (default TERM)")
+	fmt.Fprintln(w, "  --restart POLICY       never (default), on-failure or always")
+	fmt.Fprintln(w, "  --max-restarts N       give up after N restarts (default 0, no limit)")
+	fmt.Fprintln(w, "  --backoff DURATION     delay before the first restart, doubling up to 1m (default 1s)")
+	fmt.Fprintln(w, "  -h                     show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  ENTRYPOINT [\"/gobox\", \"init\", \"--\", \"/app/server\"]")
+	fmt.Fprintln(w, "  gobox init -g --grace 10s --stop-signal QUIT -- nginx -g 'daemon off;'")
+	fmt.Fprintln(w, "  gobox init --restart on-failure --max-restarts 5 -- ./worker")
+}
+
+// runInit starts argv and supervises it until it exits for good, reading
+// every signal init receives from sigs.
+func runInit(inv *base.Invocation, opts initOptions, argv []string, sigs <-chan os.Signal) error {
+	backoff := opts.backoff
+	restarts := 0
+	for {
+		started := time.Now()
+		status, stopping, err := superviseInitChild(inv, opts, argv, sigs)
+		if err != nil {
+			return err
+		}
+		code := initExitCode(status)
+		if stopping || !initShouldRestart(opts.restart, code) || (opts.maxRestart > 0 && restarts >= opts.maxRestart) {
+			if code != 0 {
+				return initExitError(code)
+			}
+			return nil
+		}
+		if time.Since(started) >= initMaxBackoff {
+			backoff = opts.backoff
+		}
+		fmt.Fprintf(inv.Stderr, "init: %s exited with status %d, restarting in %s\n", argv[0], code, backoff)
+		if initWaitBackoff(backoff, sigs) {
+			if code != 0 {
+				return initExitError(code)
+			}
+			return nil
+		}
+		restarts++
+		backoff *= 2
+		if backoff > initMaxBackoff {
+			backoff = initMaxBackoff
+		}
+	}
+}
+
+// superviseInitChild runs argv once. It forwards signals until the child
+// exits and reports whether a stop signal arrived meanwhile, so the child
+// is not restarted.
+func superviseInitChild(inv *base.Invocation, opts initOptions, argv []string, sigs <-chan os.Signal) (syscall.WaitStatus, bool, error) {
+	cmd := inv.Exec(inv.Ctx(), argv[0], argv[1:]...)
+	if opts.group {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if f, ok := inv.Stdin.(*os.File); ok && utils.IsTerminal(f) {
+			// Hand the terminal to the child's group so it can still
+			// read from it.
+			cmd.SysProcAttr.Foreground = true
+			cmd.SysProcAttr.Ctty = int(f.Fd())
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, false, err
+	}
+	pid := cmd.Process.Pid
+	target := pid
+	if opts.group {
+		target = -pid
+	}
+
+	ticker := time.NewTicker(initReapInterval)
+	defer ticker.Stop()
+	var grace <-chan time.Time
+	stopping := false
+	for {
+		if status, exited := reapInitChildren(pid); exited {
+			// Wait only flushes the command's output now; the process
+			// itself was reaped above.
+			_ = cmd.Wait()
+			return status, stopping, nil
+		}
+		select {
+		case sig := <-sigs:
+			s, ok := sig.(syscall.Signal)
+			if !ok || s == syscall.SIGCHLD || s == syscall.SIGURG {
+				// SIGURG is the Go runtime's own preemption signal.
+				continue
+			}
+			if isInitStopSignal(s) {
+				stopping = true
+				if s == syscall.SIGTERM {
+					s = opts.stopSignal
+				}
+				if opts.grace > 0 && grace == nil {
+					grace = time.After(opts.grace)
+				}
+			}
+			_ = syscall.Kill(target, s)
+		case <-grace:
+			fmt.Fprintf(inv.Stderr, "init: %s still running %s after stop signal, sending KILL\n", argv[0], opts.grace)
+			_ = syscall.Kill(target, syscall.SIGKILL)
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapInitChildren reaps every exited child, orphans included, and reports
+// the status of pid when it was among them.
+func reapInitChildren(pid int) (syscall.WaitStatus, bool) {
+	var childStatus syscall.WaitStatus
+	exited := false
+	for {
+		var ws syscall.WaitStatus
+		wpid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || wpid <= 0 {
+			return childStatus, exited
+		}
+		if wpid == pid {
+			childStatus, exited = ws, true
+		}
+	}
+}
+
+// initWaitBackoff sleeps before a restart and reports whether a stop signal
+// cut it short.
+func initWaitBackoff(d time.Duration, sigs <-chan os.Signal) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case sig := <-sigs:
+			if s, ok := sig.(syscall.Signal); ok && isInitStopSignal(s) {
+				return true
+			}
+			if sig == syscall.SIGCHLD {
+				reapInitChildren(0)
+			}
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+func isInitStopSignal(s syscall.Signal) bool {
+	return s == syscall.SIGTERM || s == syscall.SIGINT || s == syscall.SIGQUIT
+}
+
+func initShouldRestart(policy string, code int) bool {
+	switch policy {
+	case initRestartAlways:
+		return true
+	case initRestartOnFailure:
+		return code != 0
+	}
+	return false
+}
+
+// initExitCode maps a wait status onto a shell-style exit code.
+func initExitCode(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
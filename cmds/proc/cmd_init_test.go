@@ -0,0 +1,143 @@
+package proc
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gobox/cmds/base"
+)
+
+// lockedBuffer is shared by init's own messages and the child's output,
+// which os/exec copies from another goroutine.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startTestInit runs runInit in the background with a signal channel the
+// test can inject into; SIGCHLD still arrives from the kernel.
+func startTestInit(t *testing.T, opts initOptions, argv ...string) (chan os.Signal, <-chan error, *lockedBuffer) {
+	t.Helper()
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs, syscall.SIGCHLD)
+	t.Cleanup(func() { signal.Stop(sigs) })
+	if opts.stopSignal == 0 {
+		opts.stopSignal = syscall.SIGTERM
+	}
+	stderr := &lockedBuffer{}
+	inv := &base.Invocation{Context: context.Background(), Stdout: &lockedBuffer{}, Stderr: stderr}
+	done := make(chan error, 1)
+	go func() { done <- runInit(inv, opts, argv, sigs) }()
+	return sigs, done, stderr
+}
+
+func waitTestInit(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("init did not return")
+		return nil
+	}
+}
+
+// waitForFile polls until the child has written path, so a signal is only
+// injected once its trap is installed.
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s never appeared", path)
+}
+
+func TestInitPropagatesExitStatus(t *testing.T) {
+	_, done, _ := startTestInit(t, initOptions{}, "sh", "-c", "exit 3")
+	err := waitTestInit(t, done)
+	if base.ExitStatus(err) != 3 || base.ReportError(err) {
+		t.Fatalf("expected silent exit 3, got %v", err)
+	}
+	_, done, _ = startTestInit(t, initOptions{}, "sh", "-c", "kill -KILL $$")
+	if err := waitTestInit(t, done); base.ExitStatus(err) != 137 {
+		t.Fatalf("expected 128+SIGKILL, got %v", err)
+	}
+}
+
+func TestInitForwardsStopSignalAndDoesNotRestart(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	sigs, done, _ := startTestInit(t, initOptions{restart: initRestartAlways, backoff: time.Millisecond, stopSignal: syscall.SIGUSR1},
+		"sh", "-c", `trap "exit 7" USR1; touch "$0"; while :; do sleep 0.05; done`, ready)
+	waitForFile(t, ready)
+	sigs <- syscall.SIGTERM
+	if err := waitTestInit(t, done); base.ExitStatus(err) != 7 {
+		t.Fatalf("expected the child's exit 7 after SIGTERM was rewritten to USR1, got %v", err)
+	}
+}
+
+func TestInitGraceEscalatesToKill(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	sigs, done, stderr := startTestInit(t, initOptions{group: true, grace: 100 * time.Millisecond},
+		"sh", "-c", `trap "" TERM; touch "$0"; while :; do sleep 0.05; done`, ready)
+	waitForFile(t, ready)
+	sigs <- syscall.SIGTERM
+	if err := waitTestInit(t, done); base.ExitStatus(err) != 137 {
+		t.Fatalf("expected SIGKILL after the grace period, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "sending KILL") {
+		t.Fatalf("expected escalation notice, got %q", stderr.String())
+	}
+}
+
+func TestInitRestartsOnFailureWithLimit(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	_, done, stderr := startTestInit(t, initOptions{restart: initRestartOnFailure, maxRestart: 2, backoff: time.Millisecond},
+		"sh", "-c", `echo run >> "$0"; exit 1`, runs)
+	if err := waitTestInit(t, done); base.ExitStatus(err) != 1 {
+		t.Fatalf("expected final exit 1, got %v", err)
+	}
+	data, _ := os.ReadFile(runs)
+	if n := strings.Count(string(data), "run"); n != 3 {
+		t.Fatalf("expected 1 run plus 2 restarts, got %d", n)
+	}
+	if !strings.Contains(stderr.String(), "restarting in 2ms") {
+		t.Fatalf("expected doubling backoff, got %q", stderr.String())
+	}
+
+	_, done, _ = startTestInit(t, initOptions{restart: initRestartOnFailure, backoff: time.Millisecond}, "sh", "-c", "exit 0")
+	if err := waitTestInit(t, done); err != nil {
+		t.Fatalf("on-failure must not restart a clean exit: %v", err)
+	}
+}
+
+func TestInitCmdRejectsBadOptions(t *testing.T) {
+	for _, args := range [][]string{{}, {"--restart", "sometimes", "true"}, {"--stop-signal", "NOPE", "true"}, {"--max-restarts", "-1", "true"}} {
+		if err := initCmd(&base.Invocation{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}, args); err == nil {
+			t.Fatalf("expected error for %q", args)
+		}
+	}
+}
@@ -3,9 +3,10 @@ package proc
 import (
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"io"
 	"os"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -87,6 +88,10 @@ func buildSupportedSignals() []signalSpec {
 }
 
 func KillCmd(args []string) error {
+	return killCmd(base.Stdio(), args)
+}
+
+func killCmd(inv *base.Invocation, args []string) error {
 	signal := syscall.SIGTERM
 	if len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 && !strings.HasPrefix(args[0], "--") {
 		name := args[0][1:]
@@ -111,23 +116,23 @@ func KillCmd(args []string) error {
 	oldest := fsFlags.Bool("o", false, "oldest match")
 	dryRun := fsFlags.Bool("dry-run", false, "print matches only")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox kill [OPTION]... PID... | PATTERN")
-		fmt.Fprintln(os.Stderr, "Send signals to processes by PID or by match criteria.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Signals:")
-		fmt.Fprintln(os.Stderr, "  -l, --list        list signals")
-		fmt.Fprintln(os.Stderr, "  -s SIGNAL         signal name or number")
-		fmt.Fprintln(os.Stderr, "  -SIGNAL           short form signal selector")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Matching:")
-		fmt.Fprintln(os.Stderr, "  -f PATTERN        match full command line")
-		fmt.Fprintln(os.Stderr, "  -x PATTERN        match exact command name")
-		fmt.Fprintln(os.Stderr, "  -P PPID           match parent PID")
-		fmt.Fprintln(os.Stderr, "  -n                choose newest match")
-		fmt.Fprintln(os.Stderr, "  -o                choose oldest match")
-		fmt.Fprintln(os.Stderr, "  --dry-run         print matches only")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "  -h, --help        show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox kill [OPTION]... PID... | PATTERN")
+		fmt.Fprintln(inv.Stderr, "Send signals to processes by PID or by match criteria.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Signals:")
+		fmt.Fprintln(inv.Stderr, "  -l, --list        list signals")
+		fmt.Fprintln(inv.Stderr, "  -s SIGNAL         signal name or number")
+		fmt.Fprintln(inv.Stderr, "  -SIGNAL           short form signal selector")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Matching:")
+		fmt.Fprintln(inv.Stderr, "  -f PATTERN        match full command line")
+		fmt.Fprintln(inv.Stderr, "  -x PATTERN        match exact command name")
+		fmt.Fprintln(inv.Stderr, "  -P PPID           match parent PID")
+		fmt.Fprintln(inv.Stderr, "  -n                choose newest match")
+		fmt.Fprintln(inv.Stderr, "  -o                choose oldest match")
+		fmt.Fprintln(inv.Stderr, "  --dry-run         print matches only")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "  -h, --help        show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -142,17 +147,17 @@ func KillCmd(args []string) error {
 				if !ok {
 					return fmt.Errorf("unsupported signal %s", rest[0])
 				}
-				fmt.Println(name)
+				fmt.Fprintln(inv.Stdout, name)
 				return nil
 			}
 			sig, err := parseSignal(rest[0])
 			if err != nil {
 				return err
 			}
-			fmt.Println(int(sig.(syscall.Signal)))
+			fmt.Fprintln(inv.Stdout, int(sig.(syscall.Signal)))
 			return nil
 		}
-		printSignalGrid(supportedSignals)
+		printSignalGrid(inv.Stdout, supportedSignals)
 		return nil
 	}
 	if *sigName != "" {
@@ -162,6 +167,12 @@ func KillCmd(args []string) error {
 		}
 		signal = sig.(syscall.Signal)
 	}
+	// Signal 0 and --dry-run only look at processes.
+	if signal != 0 && !*dryRun {
+		if err := base.CheckWritable(inv, "kill"); err != nil {
+			return err
+		}
+	}
 	rest := fsFlags.Args()
 	if *full == "" && *exact == "" && *ppid < 0 {
 		if len(rest) == 0 {
@@ -173,10 +184,10 @@ func KillCmd(args []string) error {
 				return err
 			}
 			if *dryRun {
-				fmt.Println(pid)
+				fmt.Fprintln(inv.Stdout, pid)
 				continue
 			}
-			if err := syscall.Kill(pid, signal); err != nil {
+			if err := sendSignal(inv, pid, signal); err != nil {
 				return err
 			}
 		}
@@ -191,7 +202,13 @@ func KillCmd(args []string) error {
 	if pattern == "" && len(rest) > 0 {
 		pattern = rest[0]
 	}
-	matches, err := findProcesses(pattern, mode, *ppid)
+	roots := inv.Roots()
+	if signal != 0 && !*dryRun {
+		if err := checkKillPIDNamespace(roots); err != nil {
+			return err
+		}
+	}
+	matches, err := findProcesses(roots, pattern, mode, *ppid)
 	if err != nil {
 		return err
 	}
@@ -202,7 +219,39 @@ func KillCmd(args []string) error {
 	if *oldest && len(matches) > 1 {
 		matches = matches[:1]
 	}
-	return signalMatches(matches, signal, *dryRun)
+	return signalMatches(inv, matches, signal, *dryRun)
+}
+
+// checkKillPIDNamespace refuses to signal processes matched under an
+// alternate procfs root unless that root shows gobox's own PID namespace:
+// the PIDs of a host or another container's /proc name unrelated processes
+// here.
+func checkKillPIDNamespace(roots utils.Roots) error {
+	if roots.Proc() == utils.DefaultProcRoot {
+		return nil
+	}
+	own, err := utils.Roots{}.SelfProc().Namespace("pid")
+	if err != nil {
+		return fmt.Errorf("cannot read own PID namespace: %w", err)
+	}
+	if ino, err := roots.ProcFS().Proc(1).Namespace("pid"); err != nil || ino != own {
+		return fmt.Errorf("%s is not this PID namespace's procfs; use --dry-run to list matches", roots.Proc())
+	}
+	return nil
+}
+
+// sendSignal signals pid and records it in the audit log. Signal 0 only
+// probes for the process, so it is not recorded.
+func sendSignal(inv *base.Invocation, pid int, signal syscall.Signal) error {
+	err := killSignal(pid, signal)
+	if signal != 0 {
+		name, ok := signalName(signal)
+		if !ok {
+			name = strconv.Itoa(int(signal))
+		}
+		base.Audit(inv, "signal", strconv.Itoa(pid), err, "signal", name)
+	}
+	return err
 }
 
 // signalMatches sends signal to each matched process for pattern-based kill
@@ -211,13 +260,13 @@ func KillCmd(args []string) error {
 // lack permission to signal (EPERM, e.g. a different UID) is skipped so the
 // rest of the batch still gets signaled, instead of aborting the whole
 // match set on the first failure.
-func signalMatches(matches []procMatch, signal syscall.Signal, dryRun bool) error {
+func signalMatches(inv *base.Invocation, matches []procMatch, signal syscall.Signal, dryRun bool) error {
 	for _, p := range matches {
 		if dryRun {
-			fmt.Printf("%d %s\n", p.pid, p.cmd)
+			fmt.Fprintf(inv.Stdout, "%d %s\n", p.pid, p.cmd)
 			continue
 		}
-		if err := killSignal(p.pid, signal); err != nil {
+		if err := sendSignal(inv, p.pid, signal); err != nil {
 			if err == syscall.ESRCH || err == syscall.EPERM {
 				continue
 			}
@@ -235,8 +284,8 @@ type procMatch struct {
 	cmd   string
 }
 
-func findProcesses(pattern, mode string, ppid int) ([]procMatch, error) {
-	entries, err := os.ReadDir("/proc")
+func findProcesses(roots utils.Roots, pattern, mode string, ppid int) ([]procMatch, error) {
+	entries, err := os.ReadDir(roots.Proc())
 	if err != nil {
 		return nil, err
 	}
@@ -254,7 +303,7 @@ func findProcesses(pattern, mode string, ppid int) ([]procMatch, error) {
 		if err != nil || pid == os.Getpid() {
 			continue
 		}
-		pm, err := readProcMatch(pid)
+		pm, err := readProcMatch(roots, pid)
 		if err != nil {
 			continue
 		}
@@ -278,8 +327,8 @@ func findProcesses(pattern, mode string, ppid int) ([]procMatch, error) {
 	return out, nil
 }
 
-func readProcMatch(pid int) (procMatch, error) {
-	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+func readProcMatch(roots utils.Roots, pid int) (procMatch, error) {
+	stat, err := os.ReadFile(roots.ProcPath(strconv.Itoa(pid), "stat"))
 	if err != nil {
 		return procMatch{}, err
 	}
@@ -299,8 +348,8 @@ func readProcMatch(pid int) (procMatch, error) {
 			start, _ = strconv.ParseUint(afterComm[19], 10, 64)
 		}
 	}
-	commBytes, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
-	cmdBytes, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	commBytes, _ := os.ReadFile(roots.ProcPath(strconv.Itoa(pid), "comm"))
+	cmdBytes, _ := os.ReadFile(roots.ProcPath(strconv.Itoa(pid), "cmdline"))
 	cmd := strings.ReplaceAll(strings.TrimRight(string(cmdBytes), "\x00"), "\x00", " ")
 	if cmd == "" {
 		cmd = strings.TrimSpace(string(commBytes))
@@ -320,20 +369,29 @@ func signalName(sig syscall.Signal) (string, bool) {
 	return "", false
 }
 
+// signalNames lists supported signal names for shell completion of -s.
+func signalNames() []string {
+	names := make([]string, len(supportedSignals))
+	for i, spec := range supportedSignals {
+		names[i] = spec.name
+	}
+	return names
+}
+
 // printSignalGrid prints the signal table the way GNU kill -l does: a
 // 5-column, tab-separated grid of " N) SIGNAME" entries, number right
 // justified to 2 characters, with a possibly-short final row.
-func printSignalGrid(specs []signalSpec) {
+func printSignalGrid(w io.Writer, specs []signalSpec) {
 	const cols = 5
 	for i, spec := range specs {
-		fmt.Printf("%2d) SIG%s", int(spec.sig), spec.name)
+		fmt.Fprintf(w, "%2d) SIG%s", int(spec.sig), spec.name)
 		if (i+1)%cols == 0 {
-			fmt.Println()
+			fmt.Fprintln(w)
 		} else {
-			fmt.Print("\t")
+			fmt.Fprint(w, "\t")
 		}
 	}
 	if len(specs)%cols != 0 {
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
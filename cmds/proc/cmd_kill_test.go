@@ -1,9 +1,14 @@
 package proc
 
 import (
+	"encoding/json"
 	"errors"
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -331,7 +336,7 @@ func TestSignalMatchesSkipsEPERMAndContinuesBatch(t *testing.T) {
 	}
 
 	matches := []procMatch{{pid: 111, cmd: "owned-by-someone-else"}, {pid: 222, cmd: "our-process"}}
-	if err := signalMatches(matches, syscall.SIGTERM, false); err != nil {
+	if err := signalMatches(&base.Invocation{Stdout: io.Discard}, matches, syscall.SIGTERM, false); err != nil {
 		t.Fatalf("expected batch signal to succeed despite one EPERM match, got %v", err)
 	}
 	if len(signaled) != 1 || signaled[0] != 222 {
@@ -355,7 +360,7 @@ func TestSignalMatchesSkipsESRCHAndContinuesBatch(t *testing.T) {
 	}
 
 	matches := []procMatch{{pid: 111, cmd: "already-exited"}, {pid: 222, cmd: "our-process"}}
-	if err := signalMatches(matches, syscall.SIGTERM, false); err != nil {
+	if err := signalMatches(&base.Invocation{Stdout: io.Discard}, matches, syscall.SIGTERM, false); err != nil {
 		t.Fatalf("expected batch signal to succeed despite one ESRCH match, got %v", err)
 	}
 	if len(signaled) != 1 || signaled[0] != 222 {
@@ -374,11 +379,74 @@ func TestSignalMatchesPropagatesOtherErrors(t *testing.T) {
 	}
 
 	matches := []procMatch{{pid: 111, cmd: "x"}}
-	if err := signalMatches(matches, syscall.SIGTERM, false); err != syscall.EINVAL {
+	if err := signalMatches(&base.Invocation{Stdout: io.Discard}, matches, syscall.SIGTERM, false); err != syscall.EINVAL {
 		t.Fatalf("expected EINVAL to propagate, got %v", err)
 	}
 }
 
+func TestSignalMatchesWritesAuditLog(t *testing.T) {
+	origKill := killSignal
+	defer func() { killSignal = origKill }()
+	killSignal = func(pid int, sig syscall.Signal) error {
+		if pid == 111 {
+			return syscall.EPERM
+		}
+		return nil
+	}
+
+	logPath := t.TempDir() + "/audit.log"
+	inv := &base.Invocation{Stdout: io.Discard, Env: []string{base.AuditEnv + "=" + logPath}, Argv: []string{"kill", "-f", "worker"}}
+	matches := []procMatch{{pid: 111, cmd: "worker a"}, {pid: 222, cmd: "worker b"}}
+	if err := signalMatches(inv, matches, syscall.SIGHUP, false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one audit line per match, got %q", data)
+	}
+	var recs [2]base.AuditRecord
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &recs[i]); err != nil {
+			t.Fatalf("audit line %q: %v", line, err)
+		}
+	}
+	if recs[0].Target != "111" || recs[0].Outcome != "error" || recs[0].Detail["signal"] != "HUP" {
+		t.Fatalf("unexpected record for the EPERM match: %+v", recs[0])
+	}
+	if recs[1].Target != "222" || recs[1].Outcome != "ok" || recs[1].Command != "kill" || recs[1].Action != "signal" {
+		t.Fatalf("unexpected record for the signaled match: %+v", recs[1])
+	}
+}
+
+func TestKillReadOnlyModeAllowsOnlyProbes(t *testing.T) {
+	origKill := killSignal
+	defer func() { killSignal = origKill }()
+	var sent []syscall.Signal
+	killSignal = func(pid int, sig syscall.Signal) error {
+		sent = append(sent, sig)
+		return nil
+	}
+
+	inv := &base.Invocation{Stdout: io.Discard, Env: []string{base.ReadOnlyEnv + "=1"}}
+	pid := strconv.Itoa(os.Getpid())
+	if err := killCmd(inv, []string{"-9", pid}); base.ExitStatus(err) != base.ReadOnlyExitCode {
+		t.Fatalf("expected kill -9 to be refused, got %v", err)
+	}
+	if err := killCmd(inv, []string{"-0", pid}); err != nil {
+		t.Fatalf("expected kill -0 to be allowed, got %v", err)
+	}
+	if err := killCmd(inv, []string{"--dry-run", "-x", "no-such-gobox-process"}); err != nil {
+		t.Fatalf("expected --dry-run to be allowed, got %v", err)
+	}
+	if len(sent) != 1 || sent[0] != 0 {
+		t.Fatalf("expected only the signal 0 probe to be sent, got %v", sent)
+	}
+}
+
 func TestKillCmdOptionsNewestAndOldestSelectOneProcess(t *testing.T) {
 
 	token := "gobox-ut-new-old"
@@ -521,3 +589,50 @@ func TestKillCmdOptionsSignalOptionDryRun(t *testing.T) {
 	}
 
 }
+
+func TestKillMatchesReadProcRoot(t *testing.T) {
+	useProcFixture(t, map[string]string{
+		"4242/stat":    fixtureProcStat("4242", "fixture-sleep", 1),
+		"4242/comm":    "fixture-sleep\n",
+		"4242/cmdline": "fixture-sleep\x0030\x00",
+	})
+	out, err := captureProcCmd(t, func() error { return KillCmd([]string{"--dry-run", "-x", "fixture-sleep"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "4242 fixture-sleep 30\n" {
+		t.Fatalf("expected the fixture process, got %q", out)
+	}
+}
+
+func TestKillRefusesSignalsAcrossPIDNamespaces(t *testing.T) {
+	root := useProcFixture(t, map[string]string{
+		"4242/stat":    fixtureProcStat("4242", "fixture-sleep", 1),
+		"4242/comm":    "fixture-sleep\n",
+		"4242/cmdline": "fixture-sleep\x0030\x00",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "1", "ns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("pid:[1]", filepath.Join(root, "1", "ns", "pid")); err != nil {
+		t.Fatal(err)
+	}
+	err := killCmd(&base.Invocation{Stdout: io.Discard, Stderr: io.Discard}, []string{"-x", "fixture-sleep"})
+	if err == nil || !strings.Contains(err.Error(), "not this PID namespace") {
+		t.Fatalf("expected signals across PID namespaces to be refused, got %v", err)
+	}
+
+	own, err := os.Readlink("/proc/self/ns/pid")
+	if err != nil {
+		t.Skip("no PID namespace link:", err)
+	}
+	if err := os.Remove(filepath.Join(root, "1", "ns", "pid")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(own, filepath.Join(root, "1", "ns", "pid")); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkKillPIDNamespace(utils.NewRoots(root, "")); err != nil {
+		t.Fatalf("expected a procfs of the same PID namespace to be accepted, got %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 package proc
 
 import (
-	"bufio"
-	"encoding/hex"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"gobox/pkg/netfs"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -15,11 +16,15 @@ import (
 )
 
 var (
-	lsofProcRoot       = "/proc"
+	lsofProcRoot       = utils.Roots.Proc
 	collectSocketsLsof = collectProcNetSockets
 )
 
 func LsofCmd(args []string) error {
+	return lsofCmd(base.Stdio(), args)
+}
+
+func lsofCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("lsof", flag.ContinueOnError)
 	pidFilter := fsFlags.Int("p", 0, "pid")
 	cmdFilter := fsFlags.String("c", "", "command prefix")
@@ -28,22 +33,22 @@ func LsofCmd(args []string) error {
 	noPort := fsFlags.Bool("P", false, "no port lookup")
 	pidsOnly := fsFlags.Bool("t", false, "pids only")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox lsof [OPTION]... [FILE]...")
-		fmt.Fprintln(os.Stderr, "List open files for visible processes.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Filters:")
-		fmt.Fprintln(os.Stderr, "  -p PID        filter by PID")
-		fmt.Fprintln(os.Stderr, "  -c PREFIX     filter by command prefix")
-		fmt.Fprintln(os.Stderr, "  -i            show network files")
-		fmt.Fprintln(os.Stderr, "  -iTCP         show TCP network files only")
-		fmt.Fprintln(os.Stderr, "  -iUDP         show UDP network files only")
-		fmt.Fprintln(os.Stderr, "  -i :PORT      show network files bound to PORT")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Output:")
-		fmt.Fprintln(os.Stderr, "  -n            do not resolve host names")
-		fmt.Fprintln(os.Stderr, "  -P            do not resolve port names")
-		fmt.Fprintln(os.Stderr, "  -t            print only PIDs")
-		fmt.Fprintln(os.Stderr, "  -h, --help    show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox lsof [OPTION]... [FILE]...")
+		fmt.Fprintln(inv.Stderr, "List open files for visible processes.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Filters:")
+		fmt.Fprintln(inv.Stderr, "  -p PID        filter by PID")
+		fmt.Fprintln(inv.Stderr, "  -c PREFIX     filter by command prefix")
+		fmt.Fprintln(inv.Stderr, "  -i            show network files")
+		fmt.Fprintln(inv.Stderr, "  -iTCP         show TCP network files only")
+		fmt.Fprintln(inv.Stderr, "  -iUDP         show UDP network files only")
+		fmt.Fprintln(inv.Stderr, "  -i :PORT      show network files bound to PORT")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Output:")
+		fmt.Fprintln(inv.Stderr, "  -n            do not resolve host names")
+		fmt.Fprintln(inv.Stderr, "  -P            do not resolve port names")
+		fmt.Fprintln(inv.Stderr, "  -t            print only PIDs")
+		fmt.Fprintln(inv.Stderr, "  -h, --help    show this help")
 	}
 	filterArgs := normalizeLsofArgs(args)
 	protoFilter, portFilter := parseLsofIFilters(args)
@@ -56,24 +61,24 @@ func LsofCmd(args []string) error {
 	_ = noHost
 	_ = noPort
 	files := fsFlags.Args()
-	rows, err := collectLsofRows(*pidFilter, *cmdFilter, *netOnly || protoFilter != "" || portFilter != "", files)
+	for i, f := range files {
+		files[i] = inv.Path(f)
+	}
+	rows, err := collectLsofRows(inv.Roots(), *pidFilter, *cmdFilter, *netOnly || protoFilter != "" || portFilter != "", files)
 	if err != nil {
 		return err
 	}
 	printed := map[int]bool{}
+	if utils.IsStructuredOutput(inv.Output) && !*pidsOnly {
+		return lsofTable(filterLsofRows(rows, protoFilter, portFilter)).Render(inv.Stdout, inv.Output)
+	}
 	if !*pidsOnly {
-		printLsofTable(rows, protoFilter, portFilter)
+		printLsofTable(inv.Stdout, rows, protoFilter, portFilter)
 		return nil
 	}
-	for _, r := range rows {
-		if protoFilter != "" && strings.ToUpper(r.node) != protoFilter {
-			continue
-		}
-		if portFilter != "" && !strings.Contains(r.name, ":"+portFilter) {
-			continue
-		}
+	for _, r := range filterLsofRows(rows, protoFilter, portFilter) {
 		if !printed[r.pid] {
-			fmt.Println(r.pid)
+			fmt.Fprintln(inv.Stdout, r.pid)
 			printed[r.pid] = true
 		}
 	}
@@ -92,16 +97,9 @@ type lsofRow struct {
 	name    string
 }
 
-func printLsofTable(rows []lsofRow, protoFilter, portFilter string) {
+// filterLsofRows applies the -iTCP/-iUDP protocol and -i :PORT filters.
+func filterLsofRows(rows []lsofRow, protoFilter, portFilter string) []lsofRow {
 	filtered := make([]lsofRow, 0, len(rows))
-	commandWidth := len("COMMAND")
-	pidWidth := len("PID")
-	userWidth := len("USER")
-	fdWidth := len("FD")
-	typeWidth := len("TYPE")
-	deviceWidth := len("DEVICE")
-	sizeOffWidth := len("SIZE/OFF")
-	nodeWidth := len("NODE")
 	for _, r := range rows {
 		if protoFilter != "" && strings.ToUpper(r.node) != protoFilter {
 			continue
@@ -110,6 +108,35 @@ func printLsofTable(rows []lsofRow, protoFilter, portFilter string) {
 			continue
 		}
 		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// lsofTable keeps lsof's column set; size_off becomes a number (sockets'
+// "0t0" offset notation included) and is null when unknown.
+func lsofTable(rows []lsofRow) *utils.Table {
+	t := utils.NewTable("command", "pid", "user", "fd", "type", "device", "size_off", "node", "name")
+	for _, r := range rows {
+		var sizeOff any
+		if n, err := strconv.ParseInt(strings.TrimPrefix(r.sizeOff, "0t"), 10, 64); err == nil {
+			sizeOff = n
+		}
+		t.Append(r.command, r.pid, r.user, r.fd, r.typ, r.device, sizeOff, r.node, r.name)
+	}
+	return t
+}
+
+func printLsofTable(w io.Writer, rows []lsofRow, protoFilter, portFilter string) {
+	filtered := filterLsofRows(rows, protoFilter, portFilter)
+	commandWidth := len("COMMAND")
+	pidWidth := len("PID")
+	userWidth := len("USER")
+	fdWidth := len("FD")
+	typeWidth := len("TYPE")
+	deviceWidth := len("DEVICE")
+	sizeOffWidth := len("SIZE/OFF")
+	nodeWidth := len("NODE")
+	for _, r := range filtered {
 		if len(r.command) > commandWidth {
 			commandWidth = len(r.command)
 		}
@@ -135,11 +162,11 @@ func printLsofTable(rows []lsofRow, protoFilter, portFilter string) {
 			nodeWidth = len(r.node)
 		}
 	}
-	fmt.Printf("%-*s %*s %-*s %-*s %-*s %*s %*s %*s %s\n",
+	fmt.Fprintf(w, "%-*s %*s %-*s %-*s %-*s %*s %*s %*s %s\n",
 		commandWidth, "COMMAND", pidWidth, "PID", userWidth, "USER", fdWidth, "FD",
 		typeWidth, "TYPE", deviceWidth, "DEVICE", sizeOffWidth, "SIZE/OFF", nodeWidth, "NODE", "NAME")
 	for _, r := range filtered {
-		fmt.Printf("%-*s %*d %-*s %-*s %-*s %*s %*s %*s %s\n",
+		fmt.Fprintf(w, "%-*s %*d %-*s %-*s %-*s %*s %*s %*s %s\n",
 			commandWidth, r.command, pidWidth, r.pid, userWidth, r.user, fdWidth, r.fd,
 			typeWidth, r.typ, deviceWidth, r.device, sizeOffWidth, r.sizeOff, nodeWidth, r.node, r.name)
 	}
@@ -180,12 +207,12 @@ func parseLsofIFilters(args []string) (string, string) {
 	return proto, port
 }
 
-func collectLsofRows(pidFilter int, cmdFilter string, netOnly bool, files []string) ([]lsofRow, error) {
-	entries, err := os.ReadDir(lsofProcRoot)
+func collectLsofRows(roots utils.Roots, pidFilter int, cmdFilter string, netOnly bool, files []string) ([]lsofRow, error) {
+	entries, err := os.ReadDir(lsofProcRoot(roots))
 	if err != nil {
 		return nil, err
 	}
-	sockets := collectSocketsLsof()
+	sockets := collectSocketsLsof(roots)
 	fileTargets := map[string]bool{}
 	for _, f := range files {
 		if abs, err := filepath.Abs(f); err == nil {
@@ -201,18 +228,18 @@ func collectLsofRows(pidFilter int, cmdFilter string, netOnly bool, files []stri
 		if pidFilter != 0 && pid != pidFilter {
 			continue
 		}
-		commBytes, _ := os.ReadFile(filepath.Join(lsofProcRoot, e.Name(), "comm"))
+		commBytes, _ := os.ReadFile(filepath.Join(lsofProcRoot(roots), e.Name(), "comm"))
 		comm := strings.TrimSpace(string(commBytes))
 		if cmdFilter != "" && !strings.HasPrefix(comm, cmdFilter) {
 			continue
 		}
 		user := ""
-		if info, err := os.Stat(filepath.Join(lsofProcRoot, e.Name())); err == nil {
+		if info, err := os.Stat(filepath.Join(lsofProcRoot(roots), e.Name())); err == nil {
 			if st, ok := info.Sys().(*syscall.Stat_t); ok {
 				user = lookupUsername(int(st.Uid))
 			}
 		}
-		fdDir := filepath.Join(lsofProcRoot, e.Name(), "fd")
+		fdDir := filepath.Join(lsofProcRoot(roots), e.Name(), "fd")
 		fds, err := os.ReadDir(fdDir)
 		if err != nil {
 			continue
@@ -351,98 +378,35 @@ func socketInode(target string) string {
 	return strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
 }
 
-func collectProcNetSockets() map[string]string {
+// collectProcNetSockets maps socket inodes to the NAME lsof shows for them:
+// "TCP local->remote" and "UDP ..." from the tcp/udp tables, "UNIX path"
+// from /proc/net/unix.
+func collectProcNetSockets(roots utils.Roots) map[string]string {
 	out := map[string]string{}
-	readProcNet(out, "/proc/net/tcp", "TCP")
-	readProcNet(out, "/proc/net/tcp6", "TCP")
-	readProcNet(out, "/proc/net/udp", "UDP")
-	readProcNet(out, "/proc/net/udp6", "UDP")
-	readProcNetUnix(out, "/proc/net/unix")
-	return out
-}
-
-// readProcNetUnix adds unix domain socket inode->detail entries ("UNIX
-// path") from /proc/net/unix, so lsof can resolve NAME for unix socket fds
-// instead of falling back to a bare "socket:[inode]" placeholder. Columns
-// per `man 5 proc`: Num RefCount Protocol Flags Type St Inode Path.
-func readProcNetUnix(out map[string]string, path string) {
-	f, err := os.Open(path)
-	if err != nil {
-		return
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	first := true
-	for scanner.Scan() {
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 7 {
-			continue
+	nfs := roots.NetFS()
+	for _, table := range []struct {
+		proto string
+		read  func() ([]netfs.Socket, error)
+	}{
+		{"TCP", nfs.TCP}, {"TCP", nfs.TCP6}, {"UDP", nfs.UDP}, {"UDP", nfs.UDP6},
+	} {
+		socks, _ := table.read()
+		for _, sk := range socks {
+			out[strconv.FormatUint(sk.Inode, 10)] = fmt.Sprintf("%s %s->%s", table.proto, lsofEndpoint(sk.LocalIP, sk.LocalPort), lsofEndpoint(sk.RemoteIP, sk.RemotePort))
 		}
-		inode := fields[6]
-		name := ""
-		if len(fields) >= 8 {
-			name = strings.Join(fields[7:], " ")
-		}
-		out[inode] = strings.TrimSpace("UNIX " + name)
-	}
-}
-
-func readProcNet(out map[string]string, path, proto string) {
-	f, err := os.Open(path)
-	if err != nil {
-		return
 	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	first := true
-	for scanner.Scan() {
-		if first {
-			first = false
-			continue
-		}
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 10 {
-			continue
-		}
-		inode := fields[9]
-		out[inode] = fmt.Sprintf("%s %s->%s", proto, procNetEndpoint(fields[1]), procNetEndpoint(fields[2]))
+	unix, _ := nfs.Unix()
+	for _, sk := range unix {
+		out[strconv.FormatUint(sk.Inode, 10)] = strings.TrimSpace("UNIX " + sk.Path)
 	}
+	return out
 }
 
-func procNetEndpoint(value string) string {
-	parts := strings.SplitN(value, ":", 2)
-	if len(parts) != 2 {
-		return value
-	}
-	port, err := strconv.ParseInt(parts[1], 16, 32)
-	if err != nil {
-		return value
-	}
-	ih := parts[0]
-	if len(ih) == 8 {
-		var b [4]byte
-		for i := 0; i < 4; i++ {
-			v, err := strconv.ParseUint(ih[i*2:i*2+2], 16, 8)
-			if err != nil {
-				return value
-			}
-			b[3-i] = byte(v)
-		}
-		return fmt.Sprintf("%d.%d.%d.%d:%d", b[0], b[1], b[2], b[3], port)
-	}
-	if len(ih) == 32 {
-		raw, err := hex.DecodeString(ih)
-		if err != nil || len(raw) != 16 {
-			return value
-		}
-		for i := 0; i < 16; i += 4 {
-			raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
-		}
-		return fmt.Sprintf("[%s]:%d", net.IP(raw).String(), port)
+// lsofEndpoint prints an address from the tcp6/udp6 tables in brackets,
+// even when it is IPv4-mapped.
+func lsofEndpoint(ip net.IP, port int) string {
+	if len(ip) == net.IPv6len {
+		return fmt.Sprintf("[%s]:%d", ip, port)
 	}
-	return fmt.Sprintf("%s:%d", ih, port)
+	return fmt.Sprintf("%s:%d", ip, port)
 }
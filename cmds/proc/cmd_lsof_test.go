@@ -1,6 +1,8 @@
 package proc
 
 import (
+	"gobox/cmds/utils"
+	"gobox/internal/testutil"
 	"net"
 	"os"
 	"path/filepath"
@@ -229,7 +231,8 @@ func TestLsofCmdInjectedProcRootMissingProcRootReturnsError(t *testing.T) {
 	}
 
 	restore(t)
-	lsofProcRoot = filepath.Join(t.TempDir(), "missing-proc")
+	missing := filepath.Join(t.TempDir(), "missing-proc")
+	lsofProcRoot = func(utils.Roots) string { return missing }
 	if _, err := captureProcCmd(t, func() error { return LsofCmd(nil) }); err == nil {
 		t.Fatal("expected missing proc root error")
 	}
@@ -441,27 +444,31 @@ func TestLsofCmdNetworkFilterExcludesUnixSockets(t *testing.T) {
 	}
 }
 
-// TestReadProcNetUnix is a unit test for the /proc/net/unix line parser
-// backing unix domain socket resolution: it must extract the inode (column
-// 7) and the bound path (column 8, only present for filesystem-bound
-// sockets) into a "UNIX <path>" detail string, and tolerate paths with
-// spaces or a missing path column (anonymous/unbound sockets).
-func TestReadProcNetUnix(t *testing.T) {
-	dir := t.TempDir()
-	path := filepath.Join(dir, "unix")
-	content := "Num       RefCount Protocol Flags    Type St Inode Path\n" +
-		"0000000000000000: 00000002 00000000 00010000 0001 01 14173 /run/systemd/userdb/io.systemd.DynamicUser\n" +
-		"0000000000000000: 00000003 00000000 00000000 0001 03 22663\n"
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
-		t.Fatal(err)
-	}
-	out := map[string]string{}
-	readProcNetUnix(out, path)
-	if got := out["14173"]; got != "UNIX /run/systemd/userdb/io.systemd.DynamicUser" {
-		t.Fatalf("unexpected detail for bound socket: %q", got)
-	}
-	if got := out["22663"]; got != "UNIX" {
-		t.Fatalf("unexpected detail for anonymous socket: %q", got)
+// TestCollectProcNetSockets covers the socket NAMEs lsof resolves from the
+// network tables: the inode of a /proc/net/unix line maps to "UNIX <path>"
+// (paths may contain spaces; unbound sockets have none) and tcp/tcp6 lines
+// to "TCP local->remote", IPv6 addresses in brackets.
+func TestCollectProcNetSockets(t *testing.T) {
+	tcpHeader := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	root := testutil.Tree(t, map[string]string{
+		"1/net/unix": "Num       RefCount Protocol Flags    Type St Inode Path\n" +
+			"0000000000000000: 00000002 00000000 00010000 0001 01 14173 /run/systemd/userdb/io.systemd.DynamicUser\n" +
+			"0000000000000000: 00000003 00000000 00000000 0001 03 22663\n",
+		"1/net/tcp": tcpHeader +
+			"   0: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 101 1\n",
+		"1/net/tcp6": tcpHeader +
+			"   0: 00000000000000000000000001000000:0035 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 102 1\n",
+	})
+	out := collectProcNetSockets(utils.NewRoots(root, ""))
+	for inode, want := range map[string]string{
+		"14173": "UNIX /run/systemd/userdb/io.systemd.DynamicUser",
+		"22663": "UNIX",
+		"101":   "TCP 127.0.0.1:8080->127.0.0.1:50000",
+		"102":   "TCP [::1]:53->[::]:0",
+	} {
+		if got := out[inode]; got != want {
+			t.Errorf("socket %s: got %q, want %q", inode, got, want)
+		}
 	}
 }
 
@@ -475,8 +482,8 @@ func setupFakeLsofProc(t *testing.T, procs []fakeLsofProcess, sockets map[string
 	t.Helper()
 	oldRoot, oldSockets := lsofProcRoot, collectSocketsLsof
 	root := t.TempDir()
-	lsofProcRoot = root
-	collectSocketsLsof = func() map[string]string { return sockets }
+	lsofProcRoot = func(utils.Roots) string { return root }
+	collectSocketsLsof = func(utils.Roots) map[string]string { return sockets }
 	t.Cleanup(func() {
 		lsofProcRoot, collectSocketsLsof = oldRoot, oldSockets
 	})
@@ -502,3 +509,18 @@ func setupFakeLsofProc(t *testing.T, procs []fakeLsofProcess, sockets map[string
 	}
 	return root
 }
+
+func TestLsofTableParsesSizeOffset(t *testing.T) {
+	table := lsofTable([]lsofRow{
+		{command: "nginx", pid: 10, user: "www", fd: "6u", typ: "IPv4", device: "0x1", sizeOff: "0t0", node: "TCP", name: "*:80 (LISTEN)"},
+		{command: "nginx", pid: 10, user: "www", fd: "txt", typ: "REG", device: "8,1", sizeOff: "1234", node: "42", name: "/usr/sbin/nginx"},
+		{command: "nginx", pid: 10, user: "www", fd: "cwd", typ: "DIR", device: "8,1", sizeOff: "", node: "2", name: "/"},
+	})
+	var got []any
+	for _, row := range table.Rows {
+		got = append(got, row[6])
+	}
+	if len(got) != 3 || got[0] != int64(0) || got[1] != int64(1234) || got[2] != nil {
+		t.Fatalf("unexpected size_off values %#v", got)
+	}
+}
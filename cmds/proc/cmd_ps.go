@@ -1,9 +1,9 @@
 package proc
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -16,7 +16,9 @@ import (
 
 	ps "github.com/mitchellh/go-ps"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
+	"gobox/pkg/procfs"
 )
 
 type procInfo struct {
@@ -48,6 +50,11 @@ type procSnapshot struct {
 	totalJiffies int64
 	cpuTimes     cpuTimes
 	infos        map[int]procInfo
+	taken        time.Time
+	numCPU       int
+	// system is set on snapshots replayed from a --record file; live
+	// snapshots leave it nil and top reads the system state as it renders.
+	system *procSystemState
 }
 
 type cpuTimes struct {
@@ -74,6 +81,10 @@ func (psNoMatchError) ExitCode() int          { return 1 }
 func (psNoMatchError) SuppressCLIError() bool { return true }
 
 func PsCmd(args []string) error {
+	return psCmd(base.Stdio(), args)
+}
+
+func psCmd(inv *base.Invocation, args []string) error {
 	args, bsdMode := normalizePSArgs(args)
 	fsFlags := flag.NewFlagSet("ps", flag.ContinueOnError)
 	all := fsFlags.Bool("e", false, "show all processes (best-effort)")
@@ -95,9 +106,11 @@ func PsCmd(args []string) error {
 	pidFilter := fsFlags.String("p", "", "show only comma-separated process IDs")
 	commandFilter := fsFlags.String("C", "", "show only comma-separated command names")
 	hideIdle := fsFlags.Bool("hide-idle", false, "hide processes with zero sampled CPU")
+	record := fsFlags.String("record", "", "append the samples to FILE for top --replay")
+	color := utils.AddColorFlag(fsFlags)
 
 	fsFlags.Usage = func() {
-		printPSUsage()
+		printPSUsage(inv.Stderr)
 	}
 
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
@@ -144,11 +157,11 @@ func PsCmd(args []string) error {
 	}
 	showFullCommand := *fullFilter != "" || *full || *extendedFull || *longFormat || bsdMode.userFormat
 	ttyWidth := 0
-	if !maxCmdExplicit && !utils.IsTerminal(os.Stdout) {
+	if !maxCmdExplicit && !utils.IsTerminal(inv.Stdout) {
 		*maxCmd = 0
 	}
-	if !maxCmdExplicit && utils.IsTerminal(os.Stdout) {
-		if width, ok := utils.StdoutWidth(); ok {
+	if !maxCmdExplicit && utils.IsTerminal(inv.Stdout) {
+		if width, ok := utils.StdoutWidth(inv.Stdout, inv.Getenv); ok {
 			ttyWidth = width
 			*maxCmd = 0
 		}
@@ -162,15 +175,32 @@ func PsCmd(args []string) error {
 	}
 
 	if runtime.GOOS == "linux" {
-		infos, err := gatherLinuxProcInfos(time.Duration(*sampleMs) * time.Millisecond)
+		var rec *procRecorder
+		if *record != "" {
+			if err := base.CheckWritable(inv, "ps --record"); err != nil {
+				return err
+			}
+			var err error
+			rec, err = openProcRecorder(inv.Path(*record), inv.Roots(), false)
+			base.Audit(inv, "write", inv.Path(*record), err)
+			if err != nil {
+				return err
+			}
+			defer rec.Close()
+		}
+		infos, err := gatherLinuxProcInfos(inv.Roots(), time.Duration(*sampleMs)*time.Millisecond, rec)
 		if err != nil {
-			// fallback to go-ps listing if gathering detailed info fails
-			return psFallback(fsFlags, all, full)
+			// fallback to go-ps listing if gathering detailed info fails;
+			// go-ps always reads /proc, so not under --proc-root
+			if utils.IsStructuredOutput(inv.Output) || inv.Roots().Proc() != utils.DefaultProcRoot || rec != nil {
+				return err
+			}
+			return psFallback(inv.Stdout, fsFlags, all, full)
 		}
 		hasSelection := hasPSSelection(*all, bsdMode, *pidFilter, *userFilter, *commandFilter)
 		var pidOrCommandNoMatch bool
 		if !hasSelection {
-			infos = applyPSDefaultSelection(infos)
+			infos = applyPSDefaultSelection(inv.Roots(), infos)
 		} else {
 			infos, err = applyPSExplicitSelections(infos, *all, bsdMode, *pidFilter, *userFilter, *commandFilter)
 			if err != nil {
@@ -223,7 +253,8 @@ func PsCmd(args []string) error {
 			infos = infos[:*limit]
 		}
 
-		memTotal := readMemTotalBytes()
+		memTotal := readMemTotalBytes(inv.Roots())
+		colorizer := utils.NewColorizer(inv.Stdout, color.Mode, inv.Getenv)
 
 		var exitErr error
 		if pidOrCommandNoMatch {
@@ -231,20 +262,26 @@ func PsCmd(args []string) error {
 		}
 
 		// print
+		if utils.IsStructuredOutput(inv.Output) {
+			if err := psTable(infos, customFields, memTotal).Render(inv.Stdout, inv.Output); err != nil {
+				return err
+			}
+			return exitErr
+		}
 		if len(customFields) > 0 {
-			printCustomPS(infos, customFields, *maxCmd, memTotal, ttyWidth)
+			printCustomPS(inv.Stdout, colorizer, infos, customFields, *maxCmd, memTotal, ttyWidth)
 			return exitErr
 		}
 		if *extendedFull {
-			printPSExtraFullFormat(infos, *maxCmd, ttyWidth)
+			printPSExtraFullFormat(inv.Stdout, infos, *maxCmd, ttyWidth)
 			return exitErr
 		}
 		if *longFormat {
-			printPSLongFormat(infos, *maxCmd, ttyWidth)
+			printPSLongFormat(inv.Stdout, colorizer, infos, *maxCmd, ttyWidth)
 			return exitErr
 		}
 		if *full {
-			printPSFullFormat(infos, *maxCmd, ttyWidth)
+			printPSFullFormat(inv.Stdout, infos, *maxCmd, ttyWidth)
 			return exitErr
 		}
 
@@ -264,43 +301,54 @@ func PsCmd(args []string) error {
 				cmd,
 			})
 		}
-		printPSAlignedTableWithHeaders([]string{"PID", "%CPU", "RSS", "VMS", "CMD"}, rows, ttyWidth)
+		printPSAlignedTableWithHeaders(inv.Stdout, nil, []string{"PID", "%CPU", "RSS", "VMS", "CMD"}, rows, ttyWidth)
 		return exitErr
 	}
 
 	// Non-Linux fallback using go-ps (limited info)
+	if *record != "" {
+		return fmt.Errorf("--record requires /proc")
+	}
+	if utils.IsStructuredOutput(inv.Output) {
+		return fmt.Errorf("--output %s requires /proc", inv.Output)
+	}
 	if len(customFields) > 0 {
-		return psFallbackCustom(customFields, *maxCmd)
-	}
-	return psFallback(fsFlags, all, full)
-}
-
-func printPSUsage() {
-	fmt.Fprintln(os.Stderr, "Usage: gobox ps [OPTION]...")
-	fmt.Fprintln(os.Stderr, "List processes. On Linux this shows CPU% and memory (RSS/VMS) by sampling /proc.")
-	fmt.Fprintln(os.Stderr)
-	fmt.Fprintln(os.Stderr, "Options:")
-	fmt.Fprintln(os.Stderr, "  -e, -A            show all processes")
-	fmt.Fprintln(os.Stderr, "  -f                full format (UID/PPID/STIME/TTY/TIME/CMD)")
-	fmt.Fprintln(os.Stderr, "  -F                extra full format")
-	fmt.Fprintln(os.Stderr, "  -u USERS          show only comma-separated users or UIDs")
-	fmt.Fprintln(os.Stderr, "  -p PIDS           show only comma-separated process IDs")
-	fmt.Fprintln(os.Stderr, "  -C NAMES          show only comma-separated command names")
-	fmt.Fprintln(os.Stderr, "  --comm PATTERN    exact process-name filter (pgrep -x style)")
-	fmt.Fprintln(os.Stderr, "  --full REGEXP     full command-line regexp filter (pgrep -f style)")
-	fmt.Fprintln(os.Stderr, "  -o FIELDS         custom fields: pid,ppid,uid,user,comm,cmd,args,pcpu,pmem,rss,vsz,vms,tty,stat,start,etime,time")
-	fmt.Fprintln(os.Stderr, "  --sort FIELD      sort by: pid|ppid|cpu|pcpu|pmem|rss|vsz|vms|comm|cmd|user|start|etime|time")
-	fmt.Fprintln(os.Stderr, "  -r                reverse sort order")
-	fmt.Fprintln(os.Stderr, "  -n N              show only N entries (0 = all)")
-	fmt.Fprintln(os.Stderr, "  -i MS             CPU sample interval in milliseconds")
-	fmt.Fprintln(os.Stderr, "  -ww               do not truncate command width")
-	fmt.Fprintln(os.Stderr, "  --maxcmd N        max command length (0 = unlimited)")
-	fmt.Fprintln(os.Stderr, "  --hide-idle       hide processes with zero sampled CPU")
-	fmt.Fprintln(os.Stderr, "  --long            long format")
-	fmt.Fprintln(os.Stderr, "  -h, --help        show this help")
-	fmt.Fprintln(os.Stderr)
-	fmt.Fprintln(os.Stderr, "Compatibility:")
-	fmt.Fprintln(os.Stderr, "  ps aux            BSD-style process table with user-oriented columns")
+		return psFallbackCustom(inv.Stdout, customFields, *maxCmd)
+	}
+	return psFallback(inv.Stdout, fsFlags, all, full)
+}
+
+func printPSUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox ps [OPTION]...")
+	fmt.Fprintln(w, "List processes. On Linux this shows CPU% and memory (RSS/VMS) by sampling /proc.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -e, -A            show all processes")
+	fmt.Fprintln(w, "  -f                full format (UID/PPID/STIME/TTY/TIME/CMD)")
+	fmt.Fprintln(w, "  -F                extra full format")
+	fmt.Fprintln(w, "  -u USERS          show only comma-separated users or UIDs")
+	fmt.Fprintln(w, "  -p PIDS           show only comma-separated process IDs")
+	fmt.Fprintln(w, "  -C NAMES          show only comma-separated command names")
+	fmt.Fprintln(w, "  --comm PATTERN    exact process-name filter (pgrep -x style)")
+	fmt.Fprintln(w, "  --full REGEXP     full command-line regexp filter (pgrep -f style)")
+	fmt.Fprintln(w, "  -o FIELDS         custom fields: pid,ppid,uid,user,comm,cmd,args,pcpu,pmem,rss,vsz,vms,tty,stat,start,etime,time")
+	fmt.Fprintln(w, "  --sort FIELD      sort by: pid|ppid|cpu|pcpu|pmem|rss|vsz|vms|comm|cmd|user|start|etime|time")
+	fmt.Fprintln(w, "  -r                reverse sort order")
+	fmt.Fprintln(w, "  -n N              show only N entries (0 = all)")
+	fmt.Fprintln(w, "  -i MS             CPU sample interval in milliseconds")
+	fmt.Fprintln(w, "  -ww               do not truncate command width")
+	fmt.Fprintln(w, "  --maxcmd N        max command length (0 = unlimited)")
+	fmt.Fprintln(w, "  --hide-idle       hide processes with zero sampled CPU")
+	fmt.Fprintln(w, "  --long            long format")
+	fmt.Fprintln(w, "  --record FILE     append the CPU samples to FILE for gobox top --replay")
+	fmt.Fprintln(w, "  --color[=WHEN]    color the state column: auto (default), always or never")
+	fmt.Fprintln(w, "  --output FORMAT   text, json, ndjson, csv or tsv; this is gobox's global")
+	fmt.Fprintln(w, "                    structured output, not a long form of -o, so use -o FIELDS")
+	fmt.Fprintln(w, "                    to pick columns (they also select the structured fields)")
+	fmt.Fprintln(w, "  -h, --help        show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Compatibility:")
+	fmt.Fprintln(w, "  ps aux            BSD-style process table with user-oriented columns")
 }
 
 // psValueFlags lists ps flags that consume the next token as their value.
@@ -319,6 +367,7 @@ var psValueFlags = map[string]bool{
 	"-i": true, "--i": true,
 	"-full": true, "--full": true,
 	"-comm": true, "--comm": true,
+	"-record": true, "--record": true,
 }
 
 func normalizePSArgs(args []string) ([]string, psBSDMode) {
@@ -380,9 +429,9 @@ func hasPSSelection(all bool, mode psBSDMode, pidFilter, userFilter, commandFilt
 	return pidFilter != "" || userFilter != "" || commandFilter != ""
 }
 
-func applyPSDefaultSelection(infos []procInfo) []procInfo {
+func applyPSDefaultSelection(roots utils.Roots, infos []procInfo) []procInfo {
 	currentUID := os.Geteuid()
-	currentTTY := currentProcessTTY()
+	currentTTY := currentProcessTTY(roots)
 	return filterProcInfos(infos, func(pi procInfo) bool {
 		if pi.tgid != 0 && pi.pid != pi.tgid {
 			return false
@@ -442,8 +491,10 @@ func applyPSExplicitSelections(infos []procInfo, all bool, mode psBSDMode, pidFi
 	return filterProcInfos(infos, func(pi procInfo) bool { return selected[pi.pid] }), nil
 }
 
-func currentProcessTTY() string {
-	target, err := os.Readlink("/proc/self/fd/0")
+// currentProcessTTY returns the terminal on the standard input of the
+// process ProcSelf names.
+func currentProcessTTY(roots utils.Roots) string {
+	target, err := os.Readlink(roots.SelfProc().Path("fd", "0"))
 	if err != nil || target == "" {
 		return ""
 	}
@@ -464,7 +515,7 @@ func psFullCommand(pi procInfo) string {
 	return pi.exe
 }
 
-func psFallback(fsFlags *flag.FlagSet, all, full *bool) error {
+func psFallback(w io.Writer, fsFlags *flag.FlagSet, all, full *bool) error {
 	procs, err := ps.Processes()
 	if err != nil {
 		return err
@@ -472,21 +523,21 @@ func psFallback(fsFlags *flag.FlagSet, all, full *bool) error {
 	sort.Slice(procs, func(i, j int) bool { return procs[i].Pid() < procs[j].Pid() })
 
 	if *full {
-		fmt.Printf("UID\tPID\tPPID\tC\tSTIME\tTTY\tTIME\tCMD\n")
+		fmt.Fprintf(w, "UID\tPID\tPPID\tC\tSTIME\tTTY\tTIME\tCMD\n")
 		for _, p := range procs {
-			fmt.Printf("-\t%d\t%d\t0\t-\t?\t0:00\t%s\n", p.Pid(), p.PPid(), p.Executable())
+			fmt.Fprintf(w, "-\t%d\t%d\t0\t-\t?\t0:00\t%s\n", p.Pid(), p.PPid(), p.Executable())
 		}
 		return nil
 	}
 
-	fmt.Printf("PID\tEXE\n")
+	fmt.Fprintf(w, "PID\tEXE\n")
 	for _, p := range procs {
-		fmt.Printf("%d\t%s\n", p.Pid(), p.Executable())
+		fmt.Fprintf(w, "%d\t%s\n", p.Pid(), p.Executable())
 	}
 	return nil
 }
 
-func psFallbackCustom(fields []string, maxCmd int) error {
+func psFallbackCustom(w io.Writer, fields []string, maxCmd int) error {
 	procs, err := ps.Processes()
 	if err != nil {
 		return err
@@ -518,7 +569,7 @@ func psFallbackCustom(fields []string, maxCmd int) error {
 		}
 		rows = append(rows, values)
 	}
-	printPSAlignedTable(fields, rows, 0)
+	printPSAlignedTable(w, nil, fields, rows, 0)
 	return nil
 }
 
@@ -603,22 +654,24 @@ func filterProcInfos(infos []procInfo, keep func(procInfo) bool) []procInfo {
 	return filtered
 }
 
-func captureLinuxProcSnapshot() (procSnapshot, error) {
-	pids, err := listPIDsProc()
+func captureLinuxProcSnapshot(roots utils.Roots) (procSnapshot, error) {
+	pids, err := listPIDsProc(roots)
 	if err != nil {
 		return procSnapshot{}, err
 	}
 	pageSize := int64(os.Getpagesize())
-	total, cpu, bootTime := readProcStatOnce()
+	total, cpu, bootTime := readProcStatOnce(roots)
 	now := time.Now()
 
 	snapshot := procSnapshot{
 		totalJiffies: total,
 		cpuTimes:     cpu,
 		infos:        make(map[int]procInfo, len(pids)),
+		taken:        now,
+		numCPU:       runtime.NumCPU(),
 	}
 	for _, pid := range pids {
-		pi, err := readProcStat(pid, pageSize, bootTime, now)
+		pi, err := readProcStat(roots, pid, pageSize, bootTime, now)
 		if err != nil {
 			continue
 		}
@@ -627,27 +680,29 @@ func captureLinuxProcSnapshot() (procSnapshot, error) {
 	return snapshot, nil
 }
 
-func captureLinuxThreadSnapshot() (procSnapshot, error) {
-	pids, err := listPIDsProc()
+func captureLinuxThreadSnapshot(roots utils.Roots) (procSnapshot, error) {
+	pids, err := listPIDsProc(roots)
 	if err != nil {
 		return procSnapshot{}, err
 	}
 	pageSize := int64(os.Getpagesize())
-	total, cpu, bootTime := readProcStatOnce()
+	total, cpu, bootTime := readProcStatOnce(roots)
 	now := time.Now()
 
 	snapshot := procSnapshot{
 		totalJiffies: total,
 		cpuTimes:     cpu,
 		infos:        make(map[int]procInfo),
+		taken:        now,
+		numCPU:       runtime.NumCPU(),
 	}
 	for _, pid := range pids {
-		tids, err := listTaskIDsProc(pid)
+		tids, err := listTaskIDsProc(roots, pid)
 		if err != nil {
 			continue
 		}
 		for _, tid := range tids {
-			pi, err := readProcTaskStat(pid, tid, pageSize, bootTime, now)
+			pi, err := readProcTaskStat(roots, pid, tid, pageSize, bootTime, now)
 			if err != nil {
 				continue
 			}
@@ -660,7 +715,10 @@ func captureLinuxThreadSnapshot() (procSnapshot, error) {
 func diffProcSnapshots(prev, curr procSnapshot) []procInfo {
 	infos := make([]procInfo, 0, len(curr.infos))
 	deltaTotal := curr.totalJiffies - prev.totalJiffies
-	numCPU := float64(runtime.NumCPU())
+	numCPU := float64(curr.numCPU)
+	if curr.numCPU <= 0 {
+		numCPU = float64(runtime.NumCPU())
+	}
 	for pid, pi := range curr.infos {
 		if prevPi, ok := prev.infos[pid]; ok && deltaTotal > 0 {
 			deltaProc := (pi.utime + pi.stime) - (prevPi.utime + prevPi.stime)
@@ -674,18 +732,26 @@ func diffProcSnapshots(prev, curr procSnapshot) []procInfo {
 
 // gatherLinuxProcInfos samples process and system jiffies to compute CPU% and reads memory info.
 // interval is the sampling duration (e.g. 500ms). CPU% is normalized by CPU count to better match top.
-func gatherLinuxProcInfos(interval time.Duration) ([]procInfo, error) {
-	prev, err := captureLinuxProcSnapshot()
+// A non-nil rec gets both samples appended (ps --record).
+func gatherLinuxProcInfos(roots utils.Roots, interval time.Duration, rec *procRecorder) ([]procInfo, error) {
+	prev, err := captureLinuxProcSnapshot(roots)
 	if err != nil {
 		return nil, err
 	}
 	if interval > 0 {
 		time.Sleep(interval)
 	}
-	curr, err := captureLinuxProcSnapshot()
+	curr, err := captureLinuxProcSnapshot(roots)
 	if err != nil {
 		return nil, err
 	}
+	if rec != nil {
+		for _, s := range []procSnapshot{prev, curr} {
+			if err := rec.record(s); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return diffProcSnapshots(prev, curr), nil
 }
 
@@ -726,8 +792,8 @@ func sortProcInfos(infos []procInfo, sortField string) {
 	}
 }
 
-func listTaskIDsProc(pid int) ([]int, error) {
-	entries, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "task"))
+func listTaskIDsProc(roots utils.Roots, pid int) ([]int, error) {
+	entries, err := os.ReadDir(roots.ProcPath(strconv.Itoa(pid), "task"))
 	if err != nil {
 		return nil, err
 	}
@@ -743,8 +809,8 @@ func listTaskIDsProc(pid int) ([]int, error) {
 	return tids, nil
 }
 
-func listPIDsProc() ([]int, error) {
-	entries, err := os.ReadDir("/proc")
+func listPIDsProc(roots utils.Roots) ([]int, error) {
+	entries, err := os.ReadDir(roots.Proc())
 	if err != nil {
 		return nil, err
 	}
@@ -761,6 +827,19 @@ func listPIDsProc() ([]int, error) {
 	return pids, nil
 }
 
+// pidCompletions lists current PIDs for shell completion of -p.
+func pidCompletions() []string {
+	pids, err := listPIDsProc(utils.Roots{})
+	if err != nil {
+		return nil
+	}
+	out := make([]string, len(pids))
+	for i, pid := range pids {
+		out[i] = strconv.Itoa(pid)
+	}
+	return out
+}
+
 func truncateString(s string, max int) string {
 	if max <= 0 {
 		return s
@@ -827,6 +906,11 @@ func parsePSOutputFields(spec string) ([]string, []string) {
 	return fields, unknown
 }
 
+// psSortKeys are the --sort values offered by shell completion.
+func psSortKeys() []string {
+	return []string{"pid", "ppid", "pcpu", "pmem", "rss", "vsz", "vms", "comm", "args", "user", "uid", "start", "etime", "time"}
+}
+
 func isSupportedPSSortField(field string) bool {
 	switch field {
 	case "pid", "pcpu", "pmem", "rss", "vms", "vsz", "args", "comm", "user", "uid", "ppid", "start", "etime", "time":
@@ -879,7 +963,7 @@ func psFieldHeader(field string) string {
 	}
 }
 
-func printCustomPS(infos []procInfo, fields []string, maxCmd int, memTotal int64, ttyWidth int) {
+func printCustomPS(w io.Writer, c *utils.Colorizer, infos []procInfo, fields []string, maxCmd int, memTotal int64, ttyWidth int) {
 	rows := make([][]string, 0, len(infos))
 	for _, pi := range infos {
 		values := make([]string, 0, len(fields))
@@ -888,38 +972,47 @@ func printCustomPS(infos []procInfo, fields []string, maxCmd int, memTotal int64
 		}
 		rows = append(rows, values)
 	}
-	printPSAlignedTable(fields, rows, ttyWidth)
+	printPSAlignedTable(w, c, fields, rows, ttyWidth)
 }
 
-func printPSAlignedTable(fields []string, rows [][]string, ttyWidth int) {
+func printPSAlignedTable(w io.Writer, c *utils.Colorizer, fields []string, rows [][]string, ttyWidth int) {
 	headers := make([]string, len(fields))
 	for i, field := range fields {
 		headers[i] = psFieldHeader(field)
 	}
-	printPSAlignedTableWithHeaders(headers, rows, ttyWidth)
+	printPSAlignedTableWithHeaders(w, c, headers, rows, ttyWidth)
 }
 
-func printPSAlignedLine(values []string, widths []int) {
-	fmt.Print(renderPSAlignedLine(values, widths))
+func printPSAlignedLine(w io.Writer, values []string, widths []int) {
+	fmt.Fprint(w, renderPSAlignedLine(values, widths, nil, -1))
 }
 
-func renderPSAlignedLine(values []string, widths []int) string {
+// renderPSAlignedLine pads values to widths. The cell in column stateCol,
+// if any, is colored by process state.
+func renderPSAlignedLine(values []string, widths []int, c *utils.Colorizer, stateCol int) string {
 	var b strings.Builder
 	for i, value := range values {
 		if i > 0 {
 			b.WriteByte(' ')
 		}
 		if i == len(values)-1 {
+			if i == stateCol {
+				value = c.Paint(psStateRole(value), value)
+			}
 			b.WriteString(value)
 			continue
 		}
-		fmt.Fprintf(&b, "%-*s", widths[i], value)
+		cell := fmt.Sprintf("%-*s", widths[i], value)
+		if i == stateCol {
+			cell = c.PaintField(psStateRole(value), cell)
+		}
+		b.WriteString(cell)
 	}
 	b.WriteByte('\n')
 	return b.String()
 }
 
-func printPSFullFormat(infos []procInfo, maxCmd int, ttyWidth int) {
+func printPSFullFormat(w io.Writer, infos []procInfo, maxCmd int, ttyWidth int) {
 	rows := make([][]string, 0, len(infos))
 	for _, pi := range infos {
 		cmd := renderPSCommand(pi.cmdline, pi.exe, maxCmd)
@@ -938,12 +1031,12 @@ func printPSFullFormat(infos []procInfo, maxCmd int, ttyWidth int) {
 			cmd,
 		})
 	}
-	printPSAlignedTableWithHeaders([]string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"}, rows, ttyWidth)
+	printPSAlignedTableWithHeaders(w, nil, []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"}, rows, ttyWidth)
 }
 
 // printPSExtraFullFormat implements -F (GNU ps -F, "extra full format"),
 // whose native column set is "UID PID PPID C SZ RSS PSR STIME TTY TIME CMD".
-func printPSExtraFullFormat(infos []procInfo, maxCmd int, ttyWidth int) {
+func printPSExtraFullFormat(w io.Writer, infos []procInfo, maxCmd int, ttyWidth int) {
 	rows := make([][]string, 0, len(infos))
 	for _, pi := range infos {
 		userName := pi.user
@@ -964,7 +1057,7 @@ func printPSExtraFullFormat(infos []procInfo, maxCmd int, ttyWidth int) {
 			renderPSCommand(pi.cmdline, pi.exe, maxCmd),
 		})
 	}
-	printPSAlignedTableWithHeaders([]string{"UID", "PID", "PPID", "C", "SZ", "RSS", "PSR", "STIME", "TTY", "TIME", "CMD"}, rows, ttyWidth)
+	printPSAlignedTableWithHeaders(w, nil, []string{"UID", "PID", "PPID", "C", "SZ", "RSS", "PSR", "STIME", "TTY", "TIME", "CMD"}, rows, ttyWidth)
 }
 
 // printPSLongFormat implements --long (GNU ps -l). Native ps -l's column set
@@ -974,7 +1067,7 @@ func printPSExtraFullFormat(infos []procInfo, maxCmd int, ttyWidth int) {
 // scale that isn't part of any documented, stable formula, so it isn't
 // reproduced here. ADDR is always "-": modern 64-bit kernels no longer
 // expose scheduler addresses, and that's what native ps prints too.
-func printPSLongFormat(infos []procInfo, maxCmd int, ttyWidth int) {
+func printPSLongFormat(w io.Writer, c *utils.Colorizer, infos []procInfo, maxCmd int, ttyWidth int) {
 	rows := make([][]string, 0, len(infos))
 	for _, pi := range infos {
 		userName := pi.user
@@ -998,14 +1091,14 @@ func printPSLongFormat(infos []procInfo, maxCmd int, ttyWidth int) {
 			renderPSCommand(pi.cmdline, pi.exe, maxCmd),
 		})
 	}
-	printPSAlignedTableWithHeaders([]string{"F", "S", "UID", "PID", "PPID", "C", "PRI", "NI", "ADDR", "SZ", "WCHAN", "TTY", "TIME", "CMD"}, rows, ttyWidth)
+	printPSAlignedTableWithHeaders(w, c, []string{"F", "S", "UID", "PID", "PPID", "C", "PRI", "NI", "ADDR", "SZ", "WCHAN", "TTY", "TIME", "CMD"}, rows, ttyWidth)
 }
 
-func printPSAlignedTableWithHeaders(headers []string, rows [][]string, ttyWidth int) {
-	fmt.Print(renderPSAlignedTableWithHeaders(headers, rows, ttyWidth))
+func printPSAlignedTableWithHeaders(w io.Writer, c *utils.Colorizer, headers []string, rows [][]string, ttyWidth int) {
+	fmt.Fprint(w, renderPSAlignedTableWithHeaders(c, headers, rows, ttyWidth))
 }
 
-func renderPSAlignedTableWithHeaders(headers []string, rows [][]string, ttyWidth int) string {
+func renderPSAlignedTableWithHeaders(c *utils.Colorizer, headers []string, rows [][]string, ttyWidth int) string {
 	rows = fitPSRowsToWidth(headers, rows, ttyWidth)
 	widths := make([]int, len(headers))
 	for i, header := range headers {
@@ -1019,13 +1112,40 @@ func renderPSAlignedTableWithHeaders(headers []string, rows [][]string, ttyWidth
 		}
 	}
 	var b strings.Builder
-	b.WriteString(renderPSAlignedLine(headers, widths))
+	stateCol := psStateColumn(headers)
+	b.WriteString(renderPSAlignedLine(headers, widths, nil, -1))
 	for _, row := range rows {
-		b.WriteString(renderPSAlignedLine(row, widths))
+		b.WriteString(renderPSAlignedLine(row, widths, c, stateCol))
 	}
 	return b.String()
 }
 
+// psStateColumn returns the index of the process state column in headers,
+// or -1 when the table has none.
+func psStateColumn(headers []string) int {
+	for i, header := range headers {
+		if header == "S" || header == "STAT" {
+			return i
+		}
+	}
+	return -1
+}
+
+// psStateRole picks the color for a state code such as "R" or BSD-style
+// "Ss+": running is healthy, uninterruptible sleep a warning and zombies
+// critical. Other states stay uncolored.
+func psStateRole(state string) string {
+	switch {
+	case strings.HasPrefix(state, "R"):
+		return utils.ColorOK
+	case strings.HasPrefix(state, "D"):
+		return utils.ColorWarn
+	case strings.HasPrefix(state, "Z"):
+		return utils.ColorCritical
+	}
+	return ""
+}
+
 func fitPSRowsToWidth(headers []string, rows [][]string, ttyWidth int) [][]string {
 	if ttyWidth <= 0 || len(headers) == 0 {
 		return rows
@@ -1147,129 +1267,152 @@ func renderPSField(pi procInfo, field string, maxCmd int, memTotal int64) string
 	}
 }
 
-func readMemTotalBytes() int64 {
-	data, err := os.ReadFile("/proc/meminfo")
-	if err != nil {
-		return 0
+// psDefaultTableFields are the columns of structured output when -o is not
+// given; the text layouts (-f, -F, -l, BSD) all collapse to this one set.
+var psDefaultTableFields = []string{"pid", "ppid", "uid", "user", "pcpu", "pmem", "rss", "vsz", "tty", "stat", "start", "etime", "time", "comm", "args"}
+
+// psTable builds the structured form of the process list. Sizes are bytes,
+// times are seconds and the command line is never truncated.
+func psTable(infos []procInfo, fields []string, memTotal int64) *utils.Table {
+	if len(fields) == 0 {
+		fields = psDefaultTableFields
 	}
-	for _, line := range strings.Split(string(data), "\n") {
-		if !strings.HasPrefix(line, "MemTotal:") {
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 2 {
-			return 0
-		}
-		v, err := strconv.ParseInt(fields[1], 10, 64)
-		if err != nil {
-			return 0
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, psTableColumn(field))
+	}
+	t := utils.NewTable(columns...)
+	for _, pi := range infos {
+		values := make([]any, 0, len(fields))
+		for _, field := range fields {
+			values = append(values, psTableValue(pi, field, memTotal))
 		}
-		return v * 1024
+		t.Append(values...)
 	}
-	return 0
+	return t
 }
 
-// readProcStatOnce reads /proc/stat once and returns total jiffies, per-cpu times,
-// and boot time — avoiding three separate opens of the same file.
-func readProcStatOnce() (total int64, cpu cpuTimes, bootTime time.Time) {
-	f, err := os.Open("/proc/stat")
-	if err != nil {
-		return
+func psTableColumn(field string) string {
+	switch field {
+	case "pcpu":
+		return "cpu_percent"
+	case "pmem":
+		return "mem_percent"
+	case "rss":
+		return "rss_bytes"
+	case "vsz", "vms", "sz":
+		return "vsz_bytes"
+	case "args", "bsdargs", "command", "cmd":
+		return "args"
+	case "start":
+		return "start_time"
+	case "etime":
+		return "elapsed_seconds"
+	case "time":
+		return "cpu_seconds"
+	case "pri":
+		return "priority"
+	case "ni":
+		return "nice"
+	case "f":
+		return "flags"
 	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		switch {
-		case strings.HasPrefix(line, "cpu "):
-			fields := strings.Fields(line)
-			parseU := func(idx int) uint64 {
-				if idx >= len(fields) {
-					return 0
-				}
-				v, _ := strconv.ParseUint(fields[idx], 10, 64)
-				return v
-			}
-			for _, v := range fields[1:] {
-				n, _ := strconv.ParseInt(v, 10, 64)
-				total += n
-			}
-			cpu.user = parseU(1)
-			cpu.nice = parseU(2)
-			cpu.system = parseU(3)
-			cpu.idle = parseU(4)
-			cpu.iowait = parseU(5)
-			cpu.irq = parseU(6)
-			cpu.softirq = parseU(7)
-			cpu.steal = parseU(8)
-		case strings.HasPrefix(line, "btime "):
-			fields := strings.Fields(line)
-			if len(fields) == 2 {
-				sec, _ := strconv.ParseInt(fields[1], 10, 64)
-				bootTime = time.Unix(sec, 0)
-			}
+	return field
+}
+
+func psTableValue(pi procInfo, field string, memTotal int64) any {
+	switch field {
+	case "pid":
+		return pi.pid
+	case "ppid":
+		return pi.ppid
+	case "uid":
+		return pi.uid
+	case "user":
+		if pi.user != "" {
+			return pi.user
+		}
+		return strconv.Itoa(pi.uid)
+	case "pcpu":
+		return pi.cpu
+	case "pmem":
+		if memTotal <= 0 {
+			return 0.0
+		}
+		return float64(pi.rss) / float64(memTotal) * 100.0
+	case "rss":
+		return pi.rss
+	case "vsz", "vms", "sz":
+		return pi.vsize
+	case "tty":
+		if pi.tty == "" {
+			return nil
+		}
+		return pi.tty
+	case "stat":
+		return pi.state
+	case "start":
+		if pi.start.IsZero() {
+			return nil
+		}
+		return pi.start.Format(time.RFC3339)
+	case "etime":
+		return int64(pi.elapsed / time.Second)
+	case "time":
+		return float64(pi.utime+pi.stime) / float64(procClockTicks)
+	case "args", "bsdargs", "command", "cmd":
+		return renderPSCommand(pi.cmdline, pi.exe, 0)
+	case "comm":
+		return renderPSCommand(pi.exe, pi.cmdline, 0)
+	case "psr":
+		return pi.processor
+	case "pri":
+		return pi.priority
+	case "ni":
+		return pi.nice
+	case "wchan":
+		if pi.wchan == "" {
+			return nil
 		}
+		return pi.wchan
+	case "f":
+		return psFlagsColumn(pi.flags)
 	}
-	return
+	return nil
 }
 
-func readTotalJiffies() (int64, error) {
-	f, err := os.Open("/proc/stat")
+func readMemTotalBytes(roots utils.Roots) int64 {
+	mem, err := roots.ProcFS().MemInfo()
 	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	if !scanner.Scan() {
-		return 0, scanner.Err()
-	}
-	line := scanner.Text()
-	fields := strings.Fields(line)
-	var total int64
-	for _, v := range fields[1:] {
-		n, _ := strconv.ParseInt(v, 10, 64)
-		total += n
+		return 0
 	}
-	return total, nil
+	return int64(mem["MemTotal"])
 }
 
-func readCPUTimes() (cpuTimes, error) {
-	var times cpuTimes
-	f, err := os.Open("/proc/stat")
+// readProcStatOnce reads /proc/stat once and returns total jiffies, per-cpu times,
+// and boot time.
+func readProcStatOnce(roots utils.Roots) (total int64, cpu cpuTimes, bootTime time.Time) {
+	st, err := roots.ProcFS().Stat()
 	if err != nil {
-		return times, err
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	if !scanner.Scan() {
-		return times, scanner.Err()
-	}
-	fields := strings.Fields(scanner.Text())
-	if len(fields) < 8 || fields[0] != "cpu" {
-		return times, fmt.Errorf("unexpected /proc/stat cpu line")
-	}
-	parse := func(idx int) uint64 {
-		if idx >= len(fields) {
-			return 0
-		}
-		v, _ := strconv.ParseUint(fields[idx], 10, 64)
-		return v
+		return
 	}
-	times.user = parse(1)
-	times.nice = parse(2)
-	times.system = parse(3)
-	times.idle = parse(4)
-	times.iowait = parse(5)
-	times.irq = parse(6)
-	times.softirq = parse(7)
-	times.steal = parse(8)
-	return times, nil
+	cpu = cpuTimes{
+		user:    st.CPU.User,
+		nice:    st.CPU.Nice,
+		system:  st.CPU.System,
+		idle:    st.CPU.Idle,
+		iowait:  st.CPU.IOWait,
+		irq:     st.CPU.IRQ,
+		softirq: st.CPU.SoftIRQ,
+		steal:   st.CPU.Steal,
+	}
+	return int64(st.CPU.Total()), cpu, st.BootTime
 }
 
 const procClockTicks = int64(100)
 
-func readBootTime() time.Time {
-	data, err := os.ReadFile("/proc/stat")
+func readBootTime(roots utils.Roots) time.Time {
+	data, err := os.ReadFile(roots.ProcPath("stat"))
 	if err != nil {
 		return time.Time{}
 	}
@@ -1290,226 +1433,66 @@ func readBootTime() time.Time {
 	return time.Time{}
 }
 
-func readProcStatFromPaths(pid, tgid int, statPath, statusPath, cmdPath, commPath, exePath string, pageSize int64, bootTime time.Time, now time.Time) (procInfo, error) {
-	var pi procInfo
-	pi.pid = pid
-	pi.tgid = tgid
-	pi.uid = -1
-	pi.tty = "?"
-	data, err := os.ReadFile(statPath)
-	if err != nil {
-		return pi, err
-	}
-	s := string(data)
-	li := strings.Index(s, "(")
-	ri := strings.LastIndex(s, ")")
-	if li < 0 || ri < 0 || ri <= li {
-		return pi, fmt.Errorf("unexpected stat format")
-	}
-	rest := strings.Fields(s[ri+1:])
-	if len(rest) > 21 {
-		pi.state = rest[0]
-		if n, err := strconv.Atoi(rest[1]); err == nil {
-			pi.ppid = n
-		}
-		if tty, err := strconv.ParseInt(rest[4], 10, 64); err == nil {
-			pi.tty = procTTY(tgid, tty)
-		}
-		if flags, err := strconv.ParseUint(rest[6], 10, 64); err == nil {
-			pi.flags = flags
-		}
-		if ut, err := strconv.ParseInt(rest[11], 10, 64); err == nil {
-			pi.utime = ut
-		}
-		if st, err := strconv.ParseInt(rest[12], 10, 64); err == nil {
-			pi.stime = st
-		}
-		if p, err := strconv.ParseInt(rest[15], 10, 64); err == nil {
-			pi.priority = p
-		}
-		if n, err := strconv.ParseInt(rest[16], 10, 64); err == nil {
-			pi.nice = n
-		}
-		if start, err := strconv.ParseInt(rest[19], 10, 64); err == nil && !bootTime.IsZero() {
-			pi.start = bootTime.Add(time.Duration(start/procClockTicks) * time.Second)
-			if now.After(pi.start) {
-				pi.elapsed = now.Sub(pi.start)
-			}
-		}
-		if v, err := strconv.ParseInt(rest[20], 10, 64); err == nil {
-			pi.vsize = v
-		}
-		if r, err := strconv.ParseInt(rest[21], 10, 64); err == nil {
-			pi.rss = r * pageSize
-		}
-		if len(rest) > 36 {
-			if p, err := strconv.ParseInt(rest[36], 10, 64); err == nil {
-				pi.processor = p
-			}
-		}
-	}
-	readProcStatus(&pi, statusPath)
-	pi.wchan = readProcWchan(filepath.Join(filepath.Dir(statPath), "wchan"))
-
-	if data, err := os.ReadFile(cmdPath); err == nil {
-		cmdline := strings.ReplaceAll(string(data), "\x00", " ")
-		cmdline = strings.TrimSpace(cmdline)
-		if cmdline == "" {
-			if p, err := os.Readlink(exePath); err == nil {
-				pi.cmdline = p
-			}
-		} else {
-			pi.cmdline = cmdline
-		}
-	}
-
-	if data, err := os.ReadFile(commPath); err == nil {
-		pi.exe = strings.TrimSpace(string(data))
-	} else if p, err := os.Readlink(exePath); err == nil {
-		pi.exe = filepath.Base(p)
-	}
+func readProcTaskStat(roots utils.Roots, tgid, tid int, pageSize int64, bootTime time.Time, now time.Time) (procInfo, error) {
+	leader := roots.ProcFS().Proc(tgid)
+	return readProcInfo(leader.Task(tid), leader, pageSize, bootTime, now)
+}
 
-	return pi, nil
+// readProcStat reads /proc/<pid> to populate procInfo; only stat is
+// required, the rest is best-effort.
+func readProcStat(roots utils.Roots, pid int, pageSize int64, bootTime time.Time, now time.Time) (procInfo, error) {
+	p := roots.ProcFS().Proc(pid)
+	return readProcInfo(p, p, pageSize, bootTime, now)
 }
 
-func readProcTaskStat(tgid, tid int, pageSize int64, bootTime time.Time, now time.Time) (procInfo, error) {
-	base := filepath.Join("/proc", strconv.Itoa(tgid), "task", strconv.Itoa(tid))
-	return readProcStatFromPaths(tid, tgid,
-		filepath.Join(base, "stat"),
-		filepath.Join(base, "status"),
-		filepath.Join("/proc", strconv.Itoa(tgid), "cmdline"),
-		filepath.Join(base, "comm"),
-		filepath.Join("/proc", strconv.Itoa(tgid), "exe"),
-		pageSize, bootTime, now,
-	)
-}
-
-// readProcStat reads /proc/<pid>/stat and /proc/<pid>/cmdline to populate procInfo (best-effort)
-func readProcStat(pid int, pageSize int64, bootTime time.Time, now time.Time) (procInfo, error) {
-	var pi procInfo
-	pi.pid = pid
-	pi.tgid = pid
-	pi.uid = -1
-	pi.tty = "?"
-	statPath := filepath.Join("/proc", strconv.Itoa(pid), "stat")
-	data, err := os.ReadFile(statPath)
+// readProcInfo reads task's stat, status, comm and wchan. Threads share the
+// command line and executable of their thread group leader.
+func readProcInfo(task, leader procfs.Proc, pageSize int64, bootTime time.Time, now time.Time) (procInfo, error) {
+	pi := procInfo{pid: task.PID, tgid: leader.PID, uid: -1, tty: "?"}
+	st, err := task.Stat()
 	if err != nil {
 		return pi, err
 	}
-	s := string(data)
-	// extract comm which is between first '(' and last ')'
-	li := strings.Index(s, "(")
-	ri := strings.LastIndex(s, ")")
-	if li < 0 || ri < 0 || ri <= li {
-		return pi, fmt.Errorf("unexpected stat format")
-	}
-	// rest fields after )
-	rest := strings.Fields(s[ri+1:])
-	// indexes relative to rest (field 3 onwards)
-	// utime = field 14 -> rest[11], stime = field 15 -> rest[12]
-	if len(rest) > 21 {
-		pi.state = rest[0]
-		// ppid is overall field 4 -> rest[1]
-		if n, err := strconv.Atoi(rest[1]); err == nil {
-			pi.ppid = n
-		}
-		if tty, err := strconv.ParseInt(rest[4], 10, 64); err == nil {
-			pi.tty = procTTY(pid, tty)
-		}
-		if flags, err := strconv.ParseUint(rest[6], 10, 64); err == nil {
-			pi.flags = flags
-		}
-		if ut, err := strconv.ParseInt(rest[11], 10, 64); err == nil {
-			pi.utime = ut
-		}
-		if st, err := strconv.ParseInt(rest[12], 10, 64); err == nil {
-			pi.stime = st
-		}
-		if p, err := strconv.ParseInt(rest[15], 10, 64); err == nil {
-			pi.priority = p
-		}
-		if n, err := strconv.ParseInt(rest[16], 10, 64); err == nil {
-			pi.nice = n
-		}
-		if start, err := strconv.ParseInt(rest[19], 10, 64); err == nil && !bootTime.IsZero() {
-			pi.start = bootTime.Add(time.Duration(start/procClockTicks) * time.Second)
-			if now.After(pi.start) {
-				pi.elapsed = now.Sub(pi.start)
-			}
-		}
-		if v, err := strconv.ParseInt(rest[20], 10, 64); err == nil {
-			pi.vsize = v
-		}
-		if r, err := strconv.ParseInt(rest[21], 10, 64); err == nil {
-			pi.rss = r * pageSize
-		}
-		if len(rest) > 36 {
-			if p, err := strconv.ParseInt(rest[36], 10, 64); err == nil {
-				pi.processor = p
-			}
-		}
-	}
-	readProcStatus(&pi, filepath.Join("/proc", strconv.Itoa(pid), "status"))
-	pi.wchan = readProcWchan(filepath.Join("/proc", strconv.Itoa(pid), "wchan"))
-
-	// cmdline
-	cmdPath := filepath.Join("/proc", strconv.Itoa(pid), "cmdline")
-	if data, err := os.ReadFile(cmdPath); err == nil {
-		cmdline := strings.ReplaceAll(string(data), "\x00", " ")
-		cmdline = strings.TrimSpace(cmdline)
-		if cmdline == "" {
-			// fallback to exe name
-			if p, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "exe")); err == nil {
-				pi.cmdline = p
-			}
-		} else {
+	pi.state = st.State
+	pi.ppid = st.PPID
+	pi.tty = procTTY(leader, st.TTY)
+	pi.flags = st.Flags
+	pi.utime = st.UTime
+	pi.stime = st.STime
+	pi.priority = st.Priority
+	pi.nice = st.Nice
+	if !bootTime.IsZero() {
+		pi.start = bootTime.Add(time.Duration(st.StartTime/procClockTicks) * time.Second)
+		if now.After(pi.start) {
+			pi.elapsed = now.Sub(pi.start)
+		}
+	}
+	pi.vsize = st.VSize
+	pi.rss = st.RSS * pageSize
+	pi.processor = st.Processor
+
+	if status, err := task.Status(); err == nil && len(status.UIDs) > 0 {
+		pi.uid = status.UIDs[0]
+		pi.user = lookupUsername(pi.uid)
+	}
+	// Best-effort: unreadable (permission, already-exited) yields "".
+	pi.wchan, _ = task.Wchan()
+
+	if args, err := leader.Cmdline(); err == nil {
+		if cmdline := strings.TrimSpace(strings.Join(args, " ")); cmdline != "" {
 			pi.cmdline = cmdline
+		} else if p, err := leader.Executable(); err == nil {
+			pi.cmdline = p
 		}
 	}
-
-	// exe field from /proc/<pid>/comm or exe link
-	commPath := filepath.Join("/proc", strconv.Itoa(pid), "comm")
-	if data, err := os.ReadFile(commPath); err == nil {
-		pi.exe = strings.TrimSpace(string(data))
-	} else {
-		// try exe link
-		if p, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "exe")); err == nil {
-			pi.exe = filepath.Base(p)
-		}
+	if comm, err := task.Comm(); err == nil {
+		pi.exe = comm
+	} else if p, err := leader.Executable(); err == nil {
+		pi.exe = filepath.Base(p)
 	}
-
 	return pi, nil
 }
 
-func readProcStatus(pi *procInfo, path string) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return
-	}
-	for _, line := range strings.Split(string(data), "\n") {
-		if strings.HasPrefix(line, "Uid:") {
-			fields := strings.Fields(line)
-			if len(fields) > 1 {
-				if uid, err := strconv.Atoi(fields[1]); err == nil {
-					pi.uid = uid
-					pi.user = lookupUsername(uid)
-				}
-			}
-		}
-	}
-}
-
-// readProcWchan reads /proc/PID/wchan, which holds the name of the kernel
-// function the process is blocked in (empty when running/not blocked).
-// Best-effort: unreadable (permission, already-exited) yields "".
-func readProcWchan(path string) string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return ""
-	}
-	return strings.TrimSpace(string(data))
-}
-
 // psFlagsColumn renders the ps -l "F" column from the raw kernel flags word
 // (/proc/PID/stat field 9), matching procps' bit mapping: PF_FORKNOEXEC
 // (0x40) contributes 1, PF_SUPERPRIV (0x100) contributes 4.
@@ -1538,11 +1521,11 @@ func lookupUsername(uid int) string {
 	return u.Username
 }
 
-func procTTY(pid int, ttyNr int64) string {
+func procTTY(p procfs.Proc, ttyNr int64) string {
 	if ttyNr == 0 {
 		return "?"
 	}
-	target, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "fd", "0"))
+	target, err := os.Readlink(p.Path("fd", "0"))
 	if err == nil && strings.HasPrefix(target, "/dev/") {
 		return strings.TrimPrefix(target, "/dev/")
 	}
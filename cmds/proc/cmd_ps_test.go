@@ -14,6 +14,8 @@ import (
 	"syscall"
 	"testing"
 	"time"
+
+	"gobox/cmds/utils"
 )
 
 // findUnusedPID returns a PID that does not currently correspond to any
@@ -107,7 +109,7 @@ func TestPsCmdHelpPrefersCanonicalFlags(t *testing.T) {
 	if err != nil {
 		t.Fatalf("PsCmd help failed: %v", err)
 	}
-	for _, want := range []string{"Usage: gobox ps [OPTION]...", "--sort FIELD", "--maxcmd N", "--long", "--full REGEXP", "--comm PATTERN", "--hide-idle", "-ww", "Compatibility:", "pid,ppid,uid,user,comm,cmd,args,pcpu,pmem,rss,vsz,vms,tty,stat,start,etime,time", "pid|ppid|cpu|pcpu|pmem|rss|vsz|vms|comm|cmd|user|start|etime|time", "ps aux            BSD-style process table with user-oriented columns"} {
+	for _, want := range []string{"Usage: gobox ps [OPTION]...", "--sort FIELD", "--maxcmd N", "--long", "--full REGEXP", "--comm PATTERN", "--hide-idle", "-ww", "--output FORMAT", "not a long form of -o", "Compatibility:", "pid,ppid,uid,user,comm,cmd,args,pcpu,pmem,rss,vsz,vms,tty,stat,start,etime,time", "pid|ppid|cpu|pcpu|pmem|rss|vsz|vms|comm|cmd|user|start|etime|time", "ps aux            BSD-style process table with user-oriented columns"} {
 		if !strings.Contains(output, want) {
 			t.Fatalf("expected help to contain %q, got %q", want, output)
 		}
@@ -129,6 +131,26 @@ func TestRenderPSCommandSanitizesNewlines(t *testing.T) {
 	}
 }
 
+func TestPSTableKeepsRawValues(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	infos := []procInfo{{
+		pid: 42, ppid: 1, uid: 1000, user: "app", cpu: 12.5,
+		rss: 2048, vsize: 8192, state: "S", start: start, elapsed: 90 * time.Second,
+		utime: 150, stime: 50, exe: "server", cmdline: "server --port 8080",
+	}}
+	var out bytes.Buffer
+	if err := psTable(infos, []string{"pid", "rss", "pmem", "time", "start", "tty", "args"}, 4096).Render(&out, "ndjson"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"pid":42,"rss_bytes":2048,"mem_percent":50,"cpu_seconds":2,"start_time":"2024-05-01T12:00:00Z","tty":null,"args":"server --port 8080"}` + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected ps table %q", out.String())
+	}
+	if got := psTable(infos, nil, 0).Columns; len(got) != len(psDefaultTableFields) || got[0] != "pid" || got[len(got)-1] != "args" {
+		t.Fatalf("unexpected default columns %q", got)
+	}
+}
+
 func TestFitPSRowsToWidthTruncatesLastColumn(t *testing.T) {
 	headers := []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"}
 	rows := [][]string{{"root", "123", "1", "0", "12:00", "pts/0", "00:00", "bash -c this-is-a-very-long-command-line"}}
@@ -631,7 +653,7 @@ func TestPrintCustomPSAlignsColumns(t *testing.T) {
 		{pid: 12345, ppid: 999, user: "verylongusername", cpu: 12.3, rss: 10240, vsize: 20480, cmdline: "a much longer command", start: time.Unix(0, 0)},
 	}
 	out, err := captureProcOutput(t, func() error {
-		printCustomPS(infos, []string{"user", "pid", "ppid", "args"}, 0, 0, 0)
+		printCustomPS(os.Stdout, nil, infos, []string{"user", "pid", "ppid", "args"}, 0, 0, 0)
 		return nil
 	})
 	if err != nil {
@@ -819,3 +841,43 @@ func TestTopCmdBatchFiltersAndSorts(t *testing.T) {
 		t.Fatalf("expected top output to include current pid, got %q", output)
 	}
 }
+
+func TestPSSortKeysAreAccepted(t *testing.T) {
+	for _, key := range psSortKeys() {
+		if field, _ := normalizePSSortField(key); !isSupportedPSSortField(field) {
+			t.Fatalf("completion offers unsupported ps sort key %q", key)
+		}
+	}
+}
+
+func TestPsReadsProcRoot(t *testing.T) {
+	useProcFixture(t, map[string]string{
+		"stat":         "cpu  100 0 100 1000 0 0 0 0 0 0\nbtime 1700000000\n",
+		"meminfo":      "MemTotal:        2048000 kB\n",
+		"4242/stat":    fixtureProcStat("4242", "fixture-sleep", 1),
+		"4242/status":  "Name:\tfixture-sleep\nUid:\t0\t0\t0\t0\n",
+		"4242/comm":    "fixture-sleep\n",
+		"4242/cmdline": "fixture-sleep\x0030\x00",
+	})
+	out, err := captureProcCmd(t, func() error { return PsCmd([]string{"-e", "-i", "0", "-o", "pid,ppid,comm"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || strings.Join(strings.Fields(lines[1]), " ") != "4242 1 fixture-sleep" {
+		t.Fatalf("expected only the fixture process, got %q", out)
+	}
+}
+
+func TestPSStateColumnColorsBSDStates(t *testing.T) {
+	for state, want := range map[string]string{"R+": utils.ColorOK, "D": utils.ColorWarn, "Zs": utils.ColorCritical, "Ss": "", "?": ""} {
+		if got := psStateRole(state); got != want {
+			t.Fatalf("psStateRole(%q) = %q, want %q", state, got, want)
+		}
+	}
+	c := utils.NewColorizer(nil, utils.ColorAlways, func(string) string { return "" })
+	out := renderPSAlignedTableWithHeaders(c, []string{"PID", "STAT", "CMD"}, [][]string{{"7", "D", "dd"}}, 0)
+	if want := "PID STAT CMD\n7   \x1b[33m\x1b[KD\x1b[m\x1b[K    dd\n"; out != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
@@ -3,6 +3,7 @@ package proc
 import (
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"os"
 	"os/exec"
@@ -24,24 +25,28 @@ func (e timeoutExitError) ExitCode() int { return int(e) }
 func (e timeoutExitError) SuppressCLIError() bool { return true }
 
 func TimeoutCmd(args []string) error {
+	return timeoutCmd(base.Stdio(), args)
+}
+
+func timeoutCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("timeout", flag.ContinueOnError)
 	sigName := fsFlags.String("s", "TERM", "signal to send on timeout")
 	fsFlags.StringVar(sigName, "signal", "TERM", "signal to send on timeout")
 	killAfter := fsFlags.String("k", "", "send KILL after duration")
 	preserve := fsFlags.Bool("preserve-status", false, "preserve command exit status")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox timeout [OPTION]... DURATION COMMAND [ARG]...")
-		fmt.Fprintln(os.Stderr, "Run a command with a time limit.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -s, --signal SIGNAL      signal to send on timeout")
-		fmt.Fprintln(os.Stderr, "  -k DURATION              send KILL after additional duration")
-		fmt.Fprintln(os.Stderr, "  --preserve-status        preserve command exit status after timeout")
-		fmt.Fprintln(os.Stderr, "  -h, --help               show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox timeout 5s sleep 30")
-		fmt.Fprintln(os.Stderr, "  gobox timeout -s TERM -k 2s 10s mycmd")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox timeout [OPTION]... DURATION COMMAND [ARG]...")
+		fmt.Fprintln(inv.Stderr, "Run a command with a time limit.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -s, --signal SIGNAL      signal to send on timeout")
+		fmt.Fprintln(inv.Stderr, "  -k DURATION              send KILL after additional duration")
+		fmt.Fprintln(inv.Stderr, "  --preserve-status        preserve command exit status after timeout")
+		fmt.Fprintln(inv.Stderr, "  -h, --help               show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox timeout 5s sleep 30")
+		fmt.Fprintln(inv.Stderr, "  gobox timeout -s TERM -k 2s 10s mycmd")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -68,10 +73,10 @@ func TimeoutCmd(args []string) error {
 			return err
 		}
 	}
-	cmd := exec.Command(rest[1], rest[2:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	if err := base.CheckExternal(inv, rest[1]); err != nil {
+		return err
+	}
+	cmd := inv.Exec(inv.Ctx(), rest[1], rest[2:]...)
 	if err := cmd.Start(); err != nil {
 		return err
 	}
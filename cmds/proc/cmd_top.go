@@ -5,24 +5,27 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/signal"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
-	"unsafe"
 
 	ps "github.com/mitchellh/go-ps"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
 var topSortColumns = []string{"pid", "user", "vms", "rss", "pcpu", "pmem", "time", "cmd"}
 
 func TopCmd(args []string) error {
+	return topCmd(base.Stdio(), args)
+}
+
+func topCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("top", flag.ContinueOnError)
 	interval := fsFlags.String("d", "2", "delay in seconds between updates")
 	count := fsFlags.Int("n", 0, "number of iterations (0 = infinite)")
@@ -35,24 +38,34 @@ func TopCmd(args []string) error {
 	orderBy := fsFlags.String("o", "", "sort by field")
 	sortBy := fsFlags.String("sort", "cpu", "sort by: pid|cpu|rss|vms|cmd")
 	rev := fsFlags.Bool("r", false, "reverse sort order")
+	record := fsFlags.String("record", "", "append every sample to FILE")
+	replay := fsFlags.String("replay", "", "step through samples recorded in FILE")
+	color := utils.AddColorFlag(fsFlags)
 
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox top [OPTION]...")
-		fmt.Fprintln(os.Stderr, "Display dynamic real-time view of running processes (very small subset).")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -d SEC       delay in seconds between updates")
-		fmt.Fprintln(os.Stderr, "  -n COUNT     number of iterations (0 = infinite)")
-		fmt.Fprintln(os.Stderr, "  -b           batch mode")
-		fmt.Fprintln(os.Stderr, "  -p PIDS      show only comma-separated process IDs")
-		fmt.Fprintln(os.Stderr, "  -u USERS     show only processes for comma-separated users or UIDs")
-		fmt.Fprintln(os.Stderr, "  -H           thread display mode (full thread view on Linux)")
-		fmt.Fprintln(os.Stderr, "  -i           hide processes with zero sampled CPU")
-		fmt.Fprintln(os.Stderr, "  -c           show full command lines")
-		fmt.Fprintln(os.Stderr, "  -o FIELD     sort by field")
-		fmt.Fprintln(os.Stderr, "  --sort FIELD sort by: pid|cpu|rss|vms|pmem|cmd|comm|user|ppid|start|etime|time")
-		fmt.Fprintln(os.Stderr, "  -r           reverse sort order")
-		fmt.Fprintln(os.Stderr, "  -h, --help   show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox top [OPTION]...")
+		fmt.Fprintln(inv.Stderr, "Display dynamic real-time view of running processes (very small subset).")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -d SEC       delay in seconds between updates")
+		fmt.Fprintln(inv.Stderr, "  -n COUNT     number of iterations (0 = infinite)")
+		fmt.Fprintln(inv.Stderr, "  -b           batch mode")
+		fmt.Fprintln(inv.Stderr, "  -p PIDS      show only comma-separated process IDs")
+		fmt.Fprintln(inv.Stderr, "  -u USERS     show only processes for comma-separated users or UIDs")
+		fmt.Fprintln(inv.Stderr, "  -H           thread display mode (full thread view on Linux)")
+		fmt.Fprintln(inv.Stderr, "  -i           hide processes with zero sampled CPU")
+		fmt.Fprintln(inv.Stderr, "  -c           show full command lines")
+		fmt.Fprintln(inv.Stderr, "  -o FIELD     sort by field")
+		fmt.Fprintln(inv.Stderr, "  --sort FIELD sort by: pid|cpu|rss|vms|pmem|cmd|comm|user|ppid|start|etime|time")
+		fmt.Fprintln(inv.Stderr, "  -r           reverse sort order")
+		fmt.Fprintln(inv.Stderr, "  --record FILE  append every sample to FILE (gzip-compressed)")
+		fmt.Fprintln(inv.Stderr, "  --replay FILE  step through samples recorded by top or ps --record")
+		fmt.Fprintln(inv.Stderr, "  --color[=WHEN] color the S column: auto (default), always or never")
+		fmt.Fprintln(inv.Stderr, "  -h, --help   show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Interactive keys: left/right change the sort column, up/down reverse it, q quits.")
+		fmt.Fprintln(inv.Stderr, "With --replay: n/p step one sample, f/b step ten, g/G jump to the first/last,")
+		fmt.Fprintln(inv.Stderr, "space plays or pauses at the -d delay.")
 	}
 
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
@@ -88,10 +101,20 @@ func TopCmd(args []string) error {
 	if iterations < 0 {
 		iterations = 0
 	}
-	interactiveTTY := !*batch && utils.IsTerminal(os.Stdout)
+	if *record != "" && *replay != "" {
+		return fmt.Errorf("--record and --replay cannot be used together")
+	}
+	// Structured output is always batch: one table per sample, no screen
+	// control sequences.
+	structured := utils.IsStructuredOutput(inv.Output)
+	interactiveTTY := !*batch && !structured && utils.IsTerminal(inv.Stdout)
+	colorizer := utils.NewColorizer(inv.Stdout, color.Mode, inv.Getenv)
 
-	if runtime.GOOS != "linux" {
-		return runTopViaPS(*batch, *pids, *users, *hideIdle, *fullCmd, sortField, effectiveRev, delay, iterations)
+	if runtime.GOOS != "linux" && *replay == "" {
+		if *record != "" {
+			return fmt.Errorf("--record requires /proc")
+		}
+		return runTopViaPS(inv, *batch, *pids, *users, *hideIdle, *fullCmd, sortField, effectiveRev, delay, iterations)
 	}
 
 	var pidFilter map[int]bool
@@ -110,36 +133,58 @@ func TopCmd(args []string) error {
 		}
 	}
 
+	filter := func(infos []procInfo, threadMode bool) []procInfo {
+		return filterTopInfos(infos, pidFilter, userNames, userIDs, *hideIdle, threadMode)
+	}
+	if *replay != "" {
+		return replayTop(inv, colorizer, *replay, filter, *fullCmd, *batch, sortField, effectiveRev, delay, iterations)
+	}
+
+	roots := inv.Roots()
 	snapshotFn := captureLinuxProcSnapshot
 	if *threads {
 		snapshotFn = captureLinuxThreadSnapshot
 	}
+	var rec *procRecorder
+	if *record != "" {
+		if err := base.CheckWritable(inv, "top --record"); err != nil {
+			return err
+		}
+		rec, err = openProcRecorder(inv.Path(*record), roots, *threads)
+		base.Audit(inv, "write", inv.Path(*record), err)
+		if err != nil {
+			return err
+		}
+		defer rec.Close()
+	}
 
-	prev, err := snapshotFn()
+	prev, err := snapshotFn(roots)
 	if err != nil {
 		return err
 	}
+	if rec != nil {
+		if err := rec.record(prev); err != nil {
+			return err
+		}
+	}
 	if interactiveTTY {
-		hideTopCursor()
-		defer restoreTopScreen()
+		hideTopCursor(inv.Stdout)
+		defer restoreTopScreen(inv.Stdout)
 	}
 
-	memTotal := readMemTotalBytes()
+	memTotal := readMemTotalBytes(roots)
 	var sortInput <-chan topInputEvent
 	var stopInput func()
-	var sigCh chan os.Signal
 	if interactiveTTY {
 		var err error
-		sortInput, stopInput, err = startTopInput()
+		sortInput, stopInput, err = startTopInput(inv.Stdin)
 		if err != nil {
 			return err
 		}
 		defer stopInput()
-		sigCh = make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt)
-		defer signal.Stop(sigCh)
 	}
 
+	tw := utils.NewTableWriter(inv.Stdout, inv.Output)
 	i := 0
 	currentSort := sortField
 	currentReverse := effectiveRev
@@ -151,7 +196,7 @@ func TopCmd(args []string) error {
 			wait = initialTopSampleDelay(delay)
 		}
 		if interactiveTTY {
-			nextSort, nextReverse, redraw, quit, err := waitTopEvent(wait, sortInput, sigCh, currentSort, currentReverse)
+			nextSort, nextReverse, redraw, quit, err := waitTopEvent(wait, sortInput, inv.Ctx().Done(), currentSort, currentReverse)
 			if err != nil {
 				return err
 			}
@@ -165,17 +210,34 @@ func TopCmd(args []string) error {
 				continue
 			}
 		} else if wait > 0 {
-			time.Sleep(wait)
+			select {
+			case <-inv.Ctx().Done():
+				return nil
+			case <-time.After(wait):
+			}
 		}
-		curr, err := snapshotFn()
+		curr, err := snapshotFn(roots)
 		if err != nil {
 			return err
 		}
+		curr.system = readProcSystemState(roots)
+		if rec != nil {
+			if err := rec.record(curr); err != nil {
+				return err
+			}
+		}
 		infos := diffProcSnapshots(prev, curr)
 		prev = curr
-		infos = filterTopInfos(infos, pidFilter, userNames, userIDs, *hideIdle, *threads)
-		renderTopScreen(prev, curr, infos, *fullCmd, *batch, memTotal, currentSort, sortIndex, interactiveTTY, currentReverse)
+		infos = filter(infos, *threads)
 		i++
+		if structured {
+			sortTopInfos(infos, currentSort, currentReverse, memTotal)
+			if err := tw.Write(topTable(infos, *fullCmd, memTotal, i)); err != nil {
+				return err
+			}
+		} else {
+			renderTopScreen(inv.Stdout, inv.Getenv, colorizer, prev, curr, infos, *fullCmd, *batch, memTotal, currentSort, sortIndex, interactiveTTY, currentReverse)
+		}
 		firstDraw = false
 		if iterations != 0 && i >= iterations {
 			break
@@ -196,12 +258,14 @@ func parseTopDelay(value string) (time.Duration, error) {
 	return time.Duration(seconds * float64(time.Second)), nil
 }
 
-func runTopViaPS(batch bool, pids, users string, hideIdle, fullCmd bool, sortField string, rev bool, delay time.Duration, iterations int) error {
-	interactiveTTY := !batch && utils.IsTerminal(os.Stdout)
+func runTopViaPS(inv *base.Invocation, batch bool, pids, users string, hideIdle, fullCmd bool, sortField string, rev bool, delay time.Duration, iterations int) error {
+	structured := utils.IsStructuredOutput(inv.Output)
+	interactiveTTY := !batch && !structured && utils.IsTerminal(inv.Stdout)
 	if interactiveTTY {
-		hideTopCursor()
-		defer restoreTopScreen()
+		hideTopCursor(inv.Stdout)
+		defer restoreTopScreen(inv.Stdout)
 	}
+	tw := utils.NewTableWriter(inv.Stdout, inv.Output)
 	i := 0
 	for {
 		procs, err := ps.Processes()
@@ -232,35 +296,154 @@ func runTopViaPS(batch bool, pids, users string, hideIdle, fullCmd bool, sortFie
 		} else if hideIdle {
 			infos = filterTopInfos(infos, nil, nil, nil, hideIdle, false)
 		}
-		empty := procSnapshot{}
-		renderTopScreen(empty, empty, infos, fullCmd, batch, readMemTotalBytes(), sortField, topSortColumnIndex(sortField), interactiveTTY, rev)
 		i++
+		if structured {
+			memTotal := readMemTotalBytes(inv.Roots())
+			sortTopInfos(infos, sortField, rev, memTotal)
+			if err := tw.Write(topTable(infos, fullCmd, memTotal, i)); err != nil {
+				return err
+			}
+		} else {
+			empty := procSnapshot{system: readProcSystemState(inv.Roots())}
+			renderTopScreen(inv.Stdout, inv.Getenv, nil, empty, empty, infos, fullCmd, batch, readMemTotalBytes(inv.Roots()), sortField, topSortColumnIndex(sortField), interactiveTTY, rev)
+		}
 		if iterations != 0 && i >= iterations {
 			return nil
 		}
 		if delay > 0 {
-			time.Sleep(delay)
+			select {
+			case <-inv.Ctx().Done():
+				return nil
+			case <-time.After(delay):
+			}
 		}
 	}
 }
 
-func hideTopCursor() {
-	fmt.Print("\033[?25l")
+// replayTop shows samples from a --record file through live top's filter,
+// sort and render paths. Each frame diffs a sample against the one recorded
+// before it, as top did when it took them. Batch and structured replays print
+// every frame; interactively the replay starts paused on the first one.
+func replayTop(inv *base.Invocation, c *utils.Colorizer, path string, filter func([]procInfo, bool) []procInfo, fullCmd, batch bool, sortField string, reverse bool, delay time.Duration, iterations int) error {
+	snapshots, threads, err := readProcRecords(inv.Path(path))
+	if err != nil {
+		return err
+	}
+	// A lone sample has nothing to diff against; its CPU summary then
+	// covers the time since boot, like the first line of vmstat.
+	frames := len(snapshots) - 1
+	if frames == 0 {
+		frames = 1
+	}
+	frame := func(i int, note string) (procSnapshot, procSnapshot, []procInfo, int64) {
+		prev, curr := procSnapshot{}, snapshots[0]
+		if len(snapshots) > 1 {
+			prev, curr = snapshots[i], snapshots[i+1]
+		}
+		system := *curr.system
+		system.note = fmt.Sprintf("Replay: sample %d/%d at %s%s", i+1, frames, curr.taken.Format("2006-01-02 15:04:05 MST"), note)
+		curr.system = &system
+		return prev, curr, filter(diffProcSnapshots(prev, curr), threads), int64(system.mem["MemTotal"])
+	}
+
+	structured := utils.IsStructuredOutput(inv.Output)
+	if batch || structured || !utils.IsTerminal(inv.Stdout) {
+		tw := utils.NewTableWriter(inv.Stdout, inv.Output)
+		for i := 0; i < frames; i++ {
+			select {
+			case <-inv.Ctx().Done():
+				return nil
+			default:
+			}
+			prev, curr, infos, memTotal := frame(i, "")
+			if structured {
+				sortTopInfos(infos, sortField, reverse, memTotal)
+				if err := tw.Write(topTable(infos, fullCmd, memTotal, i+1)); err != nil {
+					return err
+				}
+			} else {
+				renderTopScreen(inv.Stdout, inv.Getenv, c, prev, curr, infos, fullCmd, batch, memTotal, sortField, topSortColumnIndex(sortField), false, reverse)
+			}
+			if iterations != 0 && i+1 >= iterations {
+				break
+			}
+		}
+		return nil
+	}
+
+	hideTopCursor(inv.Stdout)
+	defer restoreTopScreen(inv.Stdout)
+	input, stopInput, err := startTopInput(inv.Stdin)
+	if err != nil {
+		return err
+	}
+	defer stopInput()
+	pos, playing := 0, false
+	for {
+		note := "  [paused: n/p step, f/b step 10, g/G first/last, space play, q quit]"
+		if playing {
+			note = "  [playing: space pause, q quit]"
+		}
+		prev, curr, infos, memTotal := frame(pos, note)
+		renderTopScreen(inv.Stdout, inv.Getenv, c, prev, curr, infos, fullCmd, false, memTotal, sortField, topSortColumnIndex(sortField), true, reverse)
+
+		var tick <-chan time.Time
+		if playing {
+			tick = time.After(delay)
+		}
+		select {
+		case <-inv.Ctx().Done():
+			return nil
+		case <-tick:
+			pos++
+		case event, ok := <-input:
+			if !ok || event.quit {
+				return nil
+			}
+			if event.toggleDir {
+				reverse = !reverse
+			}
+			if event.sortDelta != 0 {
+				sortField, reverse = advanceTopSort(sortField, reverse, event.sortDelta)
+			}
+			pos += event.seek
+			switch {
+			case event.seekEdge < 0:
+				pos = 0
+			case event.seekEdge > 0:
+				pos = frames - 1
+			}
+			if event.togglePlay {
+				playing = !playing
+			}
+		}
+		if pos < 0 {
+			pos = 0
+		}
+		if pos >= frames-1 {
+			pos = frames - 1
+			playing = false
+		}
+	}
+}
+
+func hideTopCursor(w io.Writer) {
+	fmt.Fprint(w, "\033[?25l")
 }
 
-func showTopCursor() {
-	fmt.Print("\033[?25h")
+func showTopCursor(w io.Writer) {
+	fmt.Fprint(w, "\033[?25h")
 }
 
-func restoreTopScreen() {
-	showTopCursor()
-	fmt.Print("\n")
+func restoreTopScreen(w io.Writer) {
+	showTopCursor(w)
+	fmt.Fprint(w, "\n")
 }
 
-func clearTopScreen() {
+func clearTopScreen(w io.Writer) {
 	// Clear only from the home position to screen end. This avoids the more
 	// disruptive full-screen reset and reduces visible flicker/scrolling.
-	fmt.Print("\033[H\033[J")
+	fmt.Fprint(w, "\033[H\033[J")
 }
 
 func initialTopSampleDelay(delay time.Duration) time.Duration {
@@ -306,11 +489,11 @@ func filterTopInfos(infos []procInfo, pidFilter map[int]bool, userNames map[stri
 	return infos
 }
 
-func renderTopScreen(prev, curr procSnapshot, infos []procInfo, fullCmd, batch bool, memTotal int64, sortField string, sortIndex int, interactive bool, reverse bool) {
+func renderTopScreen(w io.Writer, getenv func(string) string, c *utils.Colorizer, prev, curr procSnapshot, infos []procInfo, fullCmd, batch bool, memTotal int64, sortField string, sortIndex int, interactive bool, reverse bool) {
 	ttyWidth := 0
 	ttyHeight := 0
-	if utils.IsTerminal(os.Stdout) {
-		if width, height, ok := utils.StdoutSize(); ok {
+	if utils.IsTerminal(w) {
+		if width, height, ok := utils.StdoutSize(w, getenv); ok {
 			ttyWidth = width
 			ttyHeight = height
 		}
@@ -348,12 +531,33 @@ func renderTopScreen(prev, curr procSnapshot, infos []procInfo, fullCmd, batch b
 	// full word "STATE"; pi.state is already a single-char code (R/S/T/Z...).
 	headers := []string{"PID", "USER", "VIRT", "RES", "S", "%CPU", "%MEM", "TIME+", "COMMAND"}
 	headers = highlightTopSortHeader(headers, sortField, sortIndex)
-	out.WriteString(renderTopTable(headers, rows, ttyWidth, interactive))
+	out.WriteString(renderTopTable(c, headers, rows, ttyWidth, interactive))
 	frame := out.String()
 	if interactive {
 		frame = strings.TrimRight(frame, "\n")
 	}
-	fmt.Print(frame)
+	fmt.Fprint(w, frame)
+}
+
+// topTable is the structured form of one top sample. sample numbers the
+// iterations so consumers of a -n stream can tell refreshes apart.
+func topTable(infos []procInfo, fullCmd bool, memTotal int64, sample int) *utils.Table {
+	t := utils.NewTable("sample", "pid", "user", "virt_bytes", "res_bytes", "state", "cpu_percent", "mem_percent", "cpu_seconds", "command")
+	for _, pi := range infos {
+		t.Append(
+			sample,
+			pi.pid,
+			topRenderUser(pi),
+			pi.vsize,
+			pi.rss,
+			topRenderState(pi),
+			pi.cpu,
+			topPMemValue(pi, memTotal),
+			float64(pi.utime+pi.stime)/float64(procClockTicks),
+			renderTopCommand(pi, fullCmd),
+		)
+	}
+	return t
 }
 
 func renderTopCommand(pi procInfo, fullCmd bool) string {
@@ -479,11 +683,12 @@ func topVisibleRowLimit(batch bool, ttyHeight, summaryLines int) int {
 	return available
 }
 
-func renderTopTable(headers []string, rows [][]string, ttyWidth int, interactive bool) string {
+func renderTopTable(c *utils.Colorizer, headers []string, rows [][]string, ttyWidth int, interactive bool) string {
 	widths := []int{7, 12, 8, 8, 5, 6, 6, 8, 0}
 	if len(headers) != len(widths) {
-		return renderPSAlignedTableWithHeaders(headers, rows, ttyWidth)
+		return renderPSAlignedTableWithHeaders(c, headers, rows, ttyWidth)
 	}
+	stateCol := psStateColumn(headers)
 	availableForCommand := 0
 	if ttyWidth > 0 {
 		fixed := 0
@@ -497,15 +702,17 @@ func renderTopTable(headers []string, rows [][]string, ttyWidth int, interactive
 		}
 	}
 	var b strings.Builder
-	b.WriteString(renderTopLine(headers, widths, availableForCommand, false))
+	b.WriteString(renderTopLine(nil, headers, widths, -1, availableForCommand, false))
 	for idx, row := range rows {
 		last := interactive && idx == len(rows)-1
-		b.WriteString(renderTopLine(row, widths, availableForCommand, last))
+		b.WriteString(renderTopLine(c, row, widths, stateCol, availableForCommand, last))
 	}
 	return b.String()
 }
 
-func renderTopLine(values []string, widths []int, commandWidth int, lastLine bool) string {
+// renderTopLine renders one row; the cell in column stateCol, if any, is
+// colored by process state.
+func renderTopLine(c *utils.Colorizer, values []string, widths []int, stateCol, commandWidth int, lastLine bool) string {
 	var b strings.Builder
 	for i, value := range values {
 		if i > 0 {
@@ -520,7 +727,11 @@ func renderTopLine(values []string, widths []int, commandWidth int, lastLine boo
 			continue
 		}
 		value = truncateString(value, width)
-		fmt.Fprintf(&b, "%-*s", width, value)
+		cell := fmt.Sprintf("%-*s", width, value)
+		if i == stateCol {
+			cell = c.PaintField(psStateRole(value), cell)
+		}
+		b.WriteString(cell)
 	}
 	if !lastLine {
 		b.WriteByte('\n')
@@ -557,10 +768,16 @@ func renderTopPMem(pi procInfo, memTotal int64) string {
 }
 
 func buildTopSummary(prev, curr procSnapshot, infos []procInfo) []string {
-	now := time.Now().Format("15:04:05")
-	load1, load5, load15 := readTopLoadAvg()
-	uptime := formatTopUptime(readTopUptime())
-	mem, _ := readMemInfoData()
+	system := curr.system
+	now := time.Now()
+	if system != nil {
+		now = curr.taken
+	} else {
+		system = &procSystemState{mem: map[string]uint64{}}
+	}
+	load1, load5, load15 := system.loadavg[0], system.loadavg[1], system.loadavg[2]
+	uptime := formatTopUptime(system.uptime)
+	mem := system.mem
 	memTotal := mem["MemTotal"]
 	memFree := mem["MemFree"]
 	memAvail := mem["MemAvailable"]
@@ -586,14 +803,18 @@ func buildTopSummary(prev, curr procSnapshot, infos []procInfo) []string {
 		taskWidth = 3
 	}
 
-	return []string{
-		fmt.Sprintf("top - %s up %s, load average: %.2f, %.2f, %.2f", now, uptime, load1, load5, load15),
+	lines := []string{
+		fmt.Sprintf("top - %s up %s, load average: %.2f, %.2f, %.2f", now.Format("15:04:05"), uptime, load1, load5, load15),
 		fmt.Sprintf("Tasks: %*d total, %*d running, %*d sleeping, %*d stopped, %*d zombie",
 			taskWidth, total, taskWidth, running, taskWidth, sleeping, taskWidth, stopped, taskWidth, zombie),
 		fmt.Sprintf("%%Cpu(s): %4.1f us, %4.1f sy, %4.1f ni, %4.1f id, %4.1f wa, %4.1f hi, %4.1f si, %4.1f st", cpu.user, cpu.system, cpu.nice, cpu.idle, cpu.iowait, cpu.irq, cpu.softirq, cpu.steal),
 		fmt.Sprintf("MiB Mem : %8.1f total, %8.1f free, %8.1f used, %8.1f buff/cache", bytesToMiB(memTotal), bytesToMiB(memFree), bytesToMiB(memUsed), bytesToMiB(buffCache)),
 		fmt.Sprintf("MiB Swap: %8.1f total, %8.1f free, %8.1f used. %8.1f avail Mem", bytesToMiB(swapTotal), bytesToMiB(swapFree), bytesToMiB(swapUsed), bytesToMiB(memAvail)),
 	}
+	if system.note != "" {
+		lines = append(lines, system.note)
+	}
+	return lines
 }
 
 type topCPUSummary struct {
@@ -611,6 +832,12 @@ type topInputEvent struct {
 	sortDelta int
 	toggleDir bool
 	quit      bool
+	// Replay navigation: seek moves by that many samples, seekEdge jumps to
+	// the first (-1) or last (+1) one, togglePlay starts or pauses playback.
+	// Live top just redraws on them.
+	seek       int
+	seekEdge   int
+	togglePlay bool
 }
 
 func summarizeTopCPU(prev, curr cpuTimes) topCPUSummary {
@@ -658,8 +885,8 @@ func summarizeTopTasks(infos []procInfo) (total, running, sleeping, stopped, zom
 	return total, running, sleeping, stopped, zombie
 }
 
-func readTopLoadAvg() (float64, float64, float64) {
-	data, err := os.ReadFile("/proc/loadavg")
+func readTopLoadAvg(roots utils.Roots) (float64, float64, float64) {
+	data, err := os.ReadFile(roots.ProcPath("loadavg"))
 	if err != nil {
 		return 0, 0, 0
 	}
@@ -674,8 +901,8 @@ func readTopLoadAvg() (float64, float64, float64) {
 	return parse(fields[0]), parse(fields[1]), parse(fields[2])
 }
 
-func readTopUptime() time.Duration {
-	f, err := os.Open("/proc/uptime")
+func readTopUptime(roots utils.Roots) time.Duration {
+	f, err := os.Open(roots.ProcPath("uptime"))
 	if err != nil {
 		return 0
 	}
@@ -729,6 +956,11 @@ func bytesToMiB(v uint64) float64 {
 // sortTopInfos's default case (silent pid-order fallback, exit 0) instead of
 // erroring like ps's equivalent --sort validation does for the same kind of
 // bad input.
+// topSortKeys are the --sort and -o values offered by shell completion.
+func topSortKeys() []string {
+	return []string{"cpu", "pid", "ppid", "pmem", "rss", "vms", "cmd", "comm", "user", "start", "etime", "time"}
+}
+
 func isSupportedTopSortField(field string) bool {
 	switch field {
 	case "pid", "cpu", "pcpu", "pmem", "rss", "vms", "vsize", "vsz",
@@ -796,9 +1028,13 @@ func normalizeTopSortField(sortField string) string {
 	}
 }
 
-func startTopInput() (<-chan topInputEvent, func(), error) {
-	fd := int(os.Stdin.Fd())
-	oldState, err := makeTopRaw(fd)
+func startTopInput(stdin io.Reader) (<-chan topInputEvent, func(), error) {
+	f, ok := stdin.(*os.File)
+	if !ok {
+		return nil, nil, fmt.Errorf("interactive mode requires a terminal on stdin")
+	}
+	fd := int(f.Fd())
+	oldState, err := utils.MakeRaw(fd)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -807,11 +1043,11 @@ func startTopInput() (<-chan topInputEvent, func(), error) {
 	go readTopInput(fd, events, stop)
 	return events, func() {
 		close(stop)
-		_ = restoreTopTerminal(fd, oldState)
+		_ = utils.RestoreTerminal(fd, oldState)
 	}, nil
 }
 
-func waitTopEvent(delay time.Duration, input <-chan topInputEvent, sigCh <-chan os.Signal, sortField string, reverse bool) (string, bool, bool, bool, error) {
+func waitTopEvent(delay time.Duration, input <-chan topInputEvent, done <-chan struct{}, sortField string, reverse bool) (string, bool, bool, bool, error) {
 	timer := time.NewTimer(delay)
 	if delay <= 0 {
 		timer.Stop()
@@ -837,7 +1073,7 @@ func waitTopEvent(delay time.Duration, input <-chan topInputEvent, sigCh <-chan
 				nextField, nextReverse = advanceTopSort(nextField, nextReverse, event.sortDelta)
 			}
 			return nextField, nextReverse, true, false, nil
-		case <-sigCh:
+		case <-done:
 			return sortField, reverse, false, true, nil
 		case <-timer.C:
 			return sortField, reverse, true, false, nil
@@ -855,100 +1091,35 @@ func advanceTopSort(sortField string, reverse bool, delta int) (string, bool) {
 	return topSortColumns[idx], reverse
 }
 
+// readTopInput turns keypresses into top events until q is pressed.
 func readTopInput(fd int, events chan<- topInputEvent, stop <-chan struct{}) {
 	defer close(events)
-	buf := make([]byte, 1)
-	for {
-		select {
-		case <-stop:
-			return
-		default:
-		}
-		n, err := syscall.Read(fd, buf)
-		if err != nil {
-			if err == syscall.EAGAIN || err == syscall.EWOULDBLOCK {
-				time.Sleep(25 * time.Millisecond)
-				continue
-			}
-			return
-		}
-		if n == 0 {
-			time.Sleep(25 * time.Millisecond)
-			continue
-		}
-		switch buf[0] {
-		case 'q', 'Q':
+	utils.ReadKeys(fd, stop, func(key utils.Key) bool {
+		switch key {
+		case "q", "Q":
 			events <- topInputEvent{quit: true}
-			return
-		case 27:
-			seq := make([]byte, 2)
-			read := 0
-			for read < len(seq) {
-				n, err := syscall.Read(fd, seq[read:])
-				if err != nil {
-					if err == syscall.EAGAIN || err == syscall.EWOULDBLOCK {
-						time.Sleep(10 * time.Millisecond)
-						continue
-					}
-					return
-				}
-				if n == 0 {
-					break
-				}
-				read += n
-			}
-			if read == 2 && seq[0] == '[' {
-				switch seq[1] {
-				case 'C':
-					events <- topInputEvent{sortDelta: 1}
-				case 'D':
-					events <- topInputEvent{sortDelta: -1}
-				case 'A', 'B':
-					events <- topInputEvent{toggleDir: true}
-				}
-			}
+			return false
+		case "n":
+			events <- topInputEvent{seek: 1}
+		case "p":
+			events <- topInputEvent{seek: -1}
+		case "f":
+			events <- topInputEvent{seek: 10}
+		case "b":
+			events <- topInputEvent{seek: -10}
+		case "g":
+			events <- topInputEvent{seekEdge: -1}
+		case "G":
+			events <- topInputEvent{seekEdge: 1}
+		case " ":
+			events <- topInputEvent{togglePlay: true}
+		case utils.KeyRight:
+			events <- topInputEvent{sortDelta: 1}
+		case utils.KeyLeft:
+			events <- topInputEvent{sortDelta: -1}
+		case utils.KeyUp, utils.KeyDown:
+			events <- topInputEvent{toggleDir: true}
 		}
-	}
-}
-
-func makeTopRaw(fd int) (*syscall.Termios, error) {
-	state, err := topTermios(fd)
-	if err != nil {
-		return nil, err
-	}
-	raw := *state
-	raw.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IXON
-	raw.Lflag &^= syscall.ECHO | syscall.ICANON
-	raw.Cc[syscall.VMIN] = 0
-	raw.Cc[syscall.VTIME] = 1
-	if err := setTopTermios(fd, &raw); err != nil {
-		return nil, err
-	}
-	_ = syscall.SetNonblock(fd, true)
-	return state, nil
-}
-
-func restoreTopTerminal(fd int, state *syscall.Termios) error {
-	_ = syscall.SetNonblock(fd, false)
-	if state == nil {
-		return nil
-	}
-	return setTopTermios(fd, state)
-}
-
-func topTermios(fd int) (*syscall.Termios, error) {
-	state := &syscall.Termios{}
-	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(state)), 0, 0, 0)
-	if errno != 0 {
-		return nil, errno
-	}
-	return state, nil
-}
-
-func setTopTermios(fd int, state *syscall.Termios) error {
-	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(state)), 0, 0, 0)
-	if errno != 0 {
-		return errno
-	}
-	return nil
+		return true
+	})
 }
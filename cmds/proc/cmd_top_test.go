@@ -1,10 +1,13 @@
 package proc
 
 import (
+	"os"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"gobox/cmds/utils"
 )
 
 func TestTopCmdHelpPrefersCanonicalSortFlag(t *testing.T) {
@@ -70,6 +73,15 @@ func TestRenderTopCommandRespectsCommandMode(t *testing.T) {
 	}
 }
 
+func TestTopTableUsesRawUnits(t *testing.T) {
+	infos := []procInfo{{pid: 7, user: "root", vsize: 4096, rss: 1024, state: "R", cpu: 3.5, utime: 250, exe: "worker", cmdline: "worker -v"}}
+	table := topTable(infos, true, 2048, 3)
+	want := []any{3, 7, "root", int64(4096), int64(1024), "R", 3.5, 50.0, 2.5, "worker -v"}
+	if len(table.Rows) != 1 || !reflect.DeepEqual(table.Rows[0], want) {
+		t.Fatalf("unexpected top row %#v", table.Rows)
+	}
+}
+
 func TestSortTopInfosKeepsPidTieBreakersStable(t *testing.T) {
 	infos := []procInfo{
 		{pid: 42, cpu: 0, exe: "z"},
@@ -208,7 +220,7 @@ func TestFormatTopCPUTimeUsesMinutesSecondsCentiseconds(t *testing.T) {
 func TestRenderTopScreenUsesSingleLetterStateHeader(t *testing.T) {
 	infos := []procInfo{{pid: 1, state: "S", user: "root"}}
 	out, err := captureProcOutput(t, func() error {
-		renderTopScreen(procSnapshot{}, procSnapshot{}, infos, false, true, 0, "pid", 0, false, false)
+		renderTopScreen(os.Stdout, os.Getenv, nil, procSnapshot{}, procSnapshot{}, infos, false, true, 0, "pid", 0, false, false)
 		return nil
 	})
 	if err != nil {
@@ -232,8 +244,8 @@ func TestRenderTopScreenUsesSingleLetterStateHeader(t *testing.T) {
 
 func TestTopCursorVisibilitySequences(t *testing.T) {
 	out, err := captureProcOutput(t, func() error {
-		hideTopCursor()
-		showTopCursor()
+		hideTopCursor(os.Stdout)
+		showTopCursor(os.Stdout)
 		return nil
 	})
 	if err != nil {
@@ -273,7 +285,7 @@ func TestWaitTopEventToggleDirection(t *testing.T) {
 func TestRenderTopTableTruncatesUserColumn(t *testing.T) {
 	headers := []string{"PID", "USER", "VIRT", "RES", "STATE", "%CPU", "%MEM", "TIME+", "COMMAND"}
 	rows := [][]string{{"123", "verylongusername", "1.0GB", "10MB", "S", "1.0", "0.1", "00:01", "sleep 10"}}
-	out := renderTopTable(headers, rows, 80, false)
+	out := renderTopTable(nil, headers, rows, 80, false)
 	if !strings.Contains(out, "verylongu...") {
 		t.Fatalf("expected truncated username, got %q", out)
 	}
@@ -282,7 +294,7 @@ func TestRenderTopTableTruncatesUserColumn(t *testing.T) {
 func TestRenderTopTableInteractiveHasNoTrailingNewline(t *testing.T) {
 	headers := []string{"PID", "USER", "VIRT", "RES", "STATE", "%CPU", "%MEM", "TIME+", "COMMAND"}
 	rows := [][]string{{"1", "root", "1.0GB", "10MB", "S", "0.0", "0.1", "00:01", "bash"}}
-	out := renderTopTable(headers, rows, 80, true)
+	out := renderTopTable(nil, headers, rows, 80, true)
 	if strings.HasSuffix(out, "\n") {
 		t.Fatalf("interactive top frame should not end with newline, got %q", out)
 	}
@@ -291,8 +303,39 @@ func TestRenderTopTableInteractiveHasNoTrailingNewline(t *testing.T) {
 func TestRenderTopTableKeepsFullPIDVisible(t *testing.T) {
 	headers := []string{"PID", "USER", "VIRT", "RES", "STATE", "%CPU", "%MEM", "TIME+", "COMMAND"}
 	rows := [][]string{{"1234567", "root", "1.0GB", "10MB", "S", "0.0", "0.1", "00:01", "bash"}}
-	out := renderTopTable(headers, rows, 80, false)
+	out := renderTopTable(nil, headers, rows, 80, false)
 	if !strings.Contains(out, "1234567") {
 		t.Fatalf("expected full PID to remain visible, got %q", out)
 	}
 }
+
+func TestRenderTopTableColorsStateColumn(t *testing.T) {
+	headers := []string{"PID", "USER", "VIRT", "RES", "S", "%CPU", "%MEM", "TIME+", "COMMAND"}
+	rows := [][]string{
+		{"1", "root", "1.0GB", "10MB", "R", "0.0", "0.1", "00:01", "bash"},
+		{"2", "root", "1.0GB", "10MB", "Z", "0.0", "0.1", "00:01", "defunct"},
+		{"3", "root", "1.0GB", "10MB", "S", "0.0", "0.1", "00:01", "sleep"},
+	}
+	c := utils.NewColorizer(nil, utils.ColorAlways, func(string) string { return "" })
+	lines := strings.Split(renderTopTable(c, headers, rows, 80, false), "\n")
+	if want := " \x1b[32m\x1b[KR\x1b[m\x1b[K     "; !strings.Contains(lines[1], want) {
+		t.Fatalf("expected running state in green, got %q", lines[1])
+	}
+	if want := "\x1b[31m\x1b[KZ\x1b[m\x1b[K"; !strings.Contains(lines[2], want) {
+		t.Fatalf("expected zombie state in red, got %q", lines[2])
+	}
+	if strings.Contains(lines[0], "\x1b[") || strings.Contains(lines[3], "\x1b[") {
+		t.Fatalf("header and sleeping rows should stay plain: %q", lines)
+	}
+	if got := renderTopTable(nil, headers, rows, 80, false); strings.Contains(got, "\x1b[") {
+		t.Fatalf("nil colorizer emitted escapes: %q", got)
+	}
+}
+
+func TestTopSortKeysAreAccepted(t *testing.T) {
+	for _, key := range topSortKeys() {
+		if field, _ := normalizePSSortField(normalizeTopOrderBy(key)); !isSupportedTopSortField(field) {
+			t.Fatalf("completion offers unsupported top sort key %q", key)
+		}
+	}
+}
@@ -4,39 +4,43 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"os"
-	"os/exec"
 	"time"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
 func WatchCmd(args []string) error {
-	return WatchCmdWithContext(context.Background(), args)
+	return watchCmd(base.Stdio(), args)
 }
 
 func WatchCmdWithContext(ctx context.Context, args []string) error {
+	return watchCmd(base.Stdio().WithContext(ctx), args)
+}
+
+func watchCmd(inv *base.Invocation, args []string) error {
+	ctx := inv.Ctx()
 	fsFlags := flag.NewFlagSet("watch", flag.ContinueOnError)
 	interval := fsFlags.Float64("n", 2, "interval seconds")
 	noTitle := fsFlags.Bool("t", false, "hide title")
 	appendMode := fsFlags.Bool("append", false, "append each refresh instead of clearing the screen")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox watch [-n SEC] [-t] [--append] COMMAND [ARG]...")
-		fmt.Fprintln(os.Stderr, "Run a command periodically.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Behavior:")
-		fmt.Fprintln(os.Stderr, "  default            refresh in-place by clearing the screen each iteration")
-		fmt.Fprintln(os.Stderr, "  --append           keep prior output and append each iteration below it")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -n SEC             interval in seconds between refreshes")
-		fmt.Fprintln(os.Stderr, "  -t                 hide the title line")
-		fmt.Fprintln(os.Stderr, "  --append           append output instead of clearing the screen")
-		fmt.Fprintln(os.Stderr, "  -h, --help         show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox watch -n 1 date")
-		fmt.Fprintln(os.Stderr, "  gobox watch --append -n 1 date")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox watch [-n SEC] [-t] [--append] COMMAND [ARG]...")
+		fmt.Fprintln(inv.Stderr, "Run a command periodically.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Behavior:")
+		fmt.Fprintln(inv.Stderr, "  default            refresh in-place by clearing the screen each iteration")
+		fmt.Fprintln(inv.Stderr, "  --append           keep prior output and append each iteration below it")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -n SEC             interval in seconds between refreshes")
+		fmt.Fprintln(inv.Stderr, "  -t                 hide the title line")
+		fmt.Fprintln(inv.Stderr, "  --append           append output instead of clearing the screen")
+		fmt.Fprintln(inv.Stderr, "  -h, --help         show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox watch -n 1 date")
+		fmt.Fprintln(inv.Stderr, "  gobox watch --append -n 1 date")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -48,24 +52,24 @@ func WatchCmdWithContext(ctx context.Context, args []string) error {
 	if len(cmdArgs) == 0 {
 		return fmt.Errorf("missing command")
 	}
+	if err := base.CheckExternal(inv, cmdArgs[0]); err != nil {
+		return err
+	}
 	delay := time.Duration(*interval * float64(time.Second))
 	if delay <= 0 {
 		delay = time.Second
 	}
-	clearScreen := !*appendMode && utils.IsTerminal(os.Stdout)
+	clearScreen := !*appendMode && utils.IsTerminal(inv.Stdout)
 	for {
 		if clearScreen {
-			fmt.Fprint(os.Stdout, "\033[H\033[J")
+			fmt.Fprint(inv.Stdout, "\033[H\033[J")
 		}
 		if !*noTitle {
-			fmt.Fprintf(os.Stdout, "Every %.1fs: %v\n\n", *interval, cmdArgs)
+			fmt.Fprintf(inv.Stdout, "Every %.1fs: %v\n\n", *interval, cmdArgs)
 		}
-		cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
+		cmd := inv.Exec(ctx, cmdArgs[0], cmdArgs[1:]...)
 		if err := cmd.Run(); err != nil {
-			fmt.Fprintf(os.Stderr, "watch: %v\n", err)
+			fmt.Fprintf(inv.Stderr, "watch: %v\n", err)
 		}
 		select {
 		case <-ctx.Done():
@@ -0,0 +1,571 @@
+package proc
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"gobox/pkg/procfs"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Inode numbers the kernel gives the initial namespaces (PROC_*_INIT_INO in
+// <linux/proc_ns.h>). The network one only exists on recent kernels.
+const (
+	procPidInitIno = 0xEFFFFFFC
+	procNetInitIno = 0xEFFFFFF9
+)
+
+// whereamiNamespaces are the /proc/PID/ns links compared against PID 1.
+var whereamiNamespaces = []string{"cgroup", "ipc", "mnt", "net", "pid", "time", "user", "uts"}
+
+// capabilityNames are the Linux capabilities by bit number.
+var capabilityNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "setgid", "setuid",
+	"setpcap", "linux_immutable", "net_bind_service", "net_broadcast", "net_admin", "net_raw", "ipc_lock", "ipc_owner",
+	"sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct", "sys_admin", "sys_boot", "sys_nice",
+	"sys_resource", "sys_time", "sys_tty_config", "mknod", "lease", "audit_write", "audit_control", "setfcap",
+	"mac_override", "mac_admin", "syslog", "wake_alarm", "block_suspend", "audit_read", "perfmon", "bpf",
+	"checkpoint_restore",
+}
+
+// kubeServiceAccountDir is where Kubernetes mounts the pod's service account.
+const kubeServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var (
+	containerIDPattern       = regexp.MustCompile(`[0-9a-f]{64}`)
+	serviceAccountSubPattern = regexp.MustCompile(`"sub"\s*:\s*"system:serviceaccount:[^:"]*:([^"]*)"`)
+)
+
+type whereamiNamespace struct {
+	name, self, pid1 string
+}
+
+type whereamiReport struct {
+	runtime       string
+	containerID   string
+	cgroupVersion string
+	cgroupPath    string
+	cpuQuota      float64 // CPUs; 0 is unlimited
+	cpuQuotaRaw   string
+	memoryLimit   int64 // bytes; 0 is unlimited
+	namespaces    []whereamiNamespace
+	hostPID       string
+	hostNetwork   string
+	kubernetes    bool
+	kubePod       string
+	kubeNamespace string
+	kubeNode      string
+	kubeAccount   string
+	seccomp       string
+	noNewPrivs    string
+	capEff        string
+	capabilities  []string
+	rootReadOnly  bool
+	readOnly      []string
+}
+
+func WhereamiCmd(args []string) error {
+	return whereamiCmd(base.Stdio(), args)
+}
+
+func whereamiCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("whereami", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	fsFlags.Usage = func() { printWhereamiUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if fsFlags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fsFlags.Arg(0))
+	}
+	roots := inv.Roots()
+	report, err := collectWhereami(roots, whereamiEnv(inv, roots))
+	if err != nil {
+		return err
+	}
+	if utils.IsStructuredOutput(inv.Output) {
+		return whereamiTable(report).Render(inv.Stdout, inv.Output)
+	}
+	printWhereami(inv.Stdout, report)
+	return nil
+}
+
+func printWhereamiUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox whereami")
+	fmt.Fprintln(w, "Report the container runtime, cgroup and resource limits, namespaces,")
+	fmt.Fprintln(w, "Kubernetes pod, security settings and read-only mounts of this process")
+	fmt.Fprintln(w, "(of PID 1 under --proc-root).")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -h          show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox whereami")
+	fmt.Fprintln(w, "  gobox --output json whereami")
+}
+
+// whereamiEnv returns the environment of the inspected process: gobox's own
+// by default, PID 1's under an alternate procfs root.
+func whereamiEnv(inv *base.Invocation, roots utils.Roots) func(string) string {
+	if roots.Proc() == utils.DefaultProcRoot {
+		return inv.Getenv
+	}
+	env := map[string]string{}
+	environ, _ := roots.SelfProc().Environ()
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return func(key string) string { return env[key] }
+}
+
+// collectWhereami gathers the report. Only a missing /proc/PID/cgroup and
+// status are errors; every other source is optional and left blank.
+func collectWhereami(roots utils.Roots, getenv func(string) string) (whereamiReport, error) {
+	var r whereamiReport
+	self := roots.SelfProc()
+	entries, err := self.Cgroups()
+	if err != nil {
+		return r, err
+	}
+	readWhereamiCgroup(&r, roots, entries)
+
+	for _, name := range whereamiNamespaces {
+		ino := readNamespaceInode(self, name)
+		if ino == "" {
+			continue
+		}
+		r.namespaces = append(r.namespaces, whereamiNamespace{name: name, self: ino, pid1: readNamespaceInode(roots.ProcFS().Proc(1), name)})
+	}
+	r.hostPID, r.hostNetwork = whereamiHostNamespaces(r.namespaces)
+
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	r.runtime, r.containerID = detectContainerRuntime(roots, paths, getenv("container"))
+	if r.runtime == "" && r.hostPID == "no" {
+		r.runtime = "unknown"
+	}
+	if r.runtime == "" {
+		r.runtime = "none"
+	}
+
+	readWhereamiKubernetes(&r, roots, getenv)
+
+	status, err := self.Status()
+	if err != nil {
+		return r, err
+	}
+	readWhereamiStatus(&r, status)
+
+	if mounts, err := self.MountInfo(); err == nil {
+		for _, m := range mounts {
+			if !m.ReadOnly() {
+				continue
+			}
+			if m.Target == "/" {
+				r.rootReadOnly = true
+			}
+			r.readOnly = append(r.readOnly, m.Target)
+		}
+	}
+	return r, nil
+}
+
+// readWhereamiCgroup fills in the cgroup version and path and the CPU and
+// memory limits, preferring the unified hierarchy when it carries them. A
+// limit may be set on any ancestor (a systemd or Kubernetes pod slice), so
+// the cgroup and its ancestors are all read and the tightest limit wins.
+func readWhereamiCgroup(r *whereamiReport, roots utils.Roots, entries []procfs.Cgroup) {
+	var unified *procfs.Cgroup
+	v1 := map[string]procfs.Cgroup{}
+	for i, e := range entries {
+		if e.Unified() {
+			unified = &entries[i]
+			continue
+		}
+		for _, c := range e.Controllers {
+			v1[c] = e
+		}
+	}
+	switch {
+	case len(v1) == 0 && unified != nil:
+		r.cgroupVersion = "v2"
+		r.cgroupPath = unified.Path
+		for _, dir := range cgroupDirs(roots, "", unified.Path) {
+			if v, ok := readCgroupValue(dir, "cpu.max"); ok {
+				if fields := strings.Fields(v); len(fields) == 2 && fields[0] != "max" {
+					r.limitCPU(fields[0], fields[1])
+				}
+			}
+			if v, ok := readCgroupValue(dir, "memory.max"); ok && v != "max" {
+				if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+					r.limitMemory(n)
+				}
+			}
+		}
+		return
+	case unified != nil:
+		r.cgroupVersion = "v1 (hybrid)"
+	case len(v1) > 0:
+		r.cgroupVersion = "v1"
+	default:
+		return
+	}
+	for _, c := range []string{"memory", "cpu", "pids"} {
+		if e, ok := v1[c]; ok {
+			r.cgroupPath = e.Path
+			break
+		}
+	}
+	if r.cgroupPath == "" {
+		r.cgroupPath = entries[0].Path
+	}
+	if e, ok := v1["cpu"]; ok {
+		for _, dir := range cgroupDirs(roots, strings.Join(e.Controllers, ","), e.Path) {
+			quota, okQ := readCgroupValue(dir, "cpu.cfs_quota_us")
+			period, okP := readCgroupValue(dir, "cpu.cfs_period_us")
+			if okQ && okP && quota != "-1" {
+				r.limitCPU(quota, period)
+			}
+		}
+	}
+	if e, ok := v1["memory"]; ok {
+		for _, dir := range cgroupDirs(roots, strings.Join(e.Controllers, ","), e.Path) {
+			v, _ := readCgroupValue(dir, "memory.limit_in_bytes")
+			// An unlimited v1 cgroup reports a page-rounded LONG_MAX.
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n < 1<<62 {
+				r.limitMemory(n)
+			}
+		}
+	}
+}
+
+// limitCPU records the quota/period CPU limit unless a tighter one is
+// already known.
+func (r *whereamiReport) limitCPU(quota, period string) {
+	if q := cpuQuota(quota, period); q > 0 && (r.cpuQuota == 0 || q < r.cpuQuota) {
+		r.cpuQuota = q
+		r.cpuQuotaRaw = quota + "/" + period
+	}
+}
+
+// limitMemory records a memory limit of n bytes unless a tighter one is
+// already known.
+func (r *whereamiReport) limitMemory(n int64) {
+	if n > 0 && (r.memoryLimit == 0 || n < r.memoryLimit) {
+		r.memoryLimit = n
+	}
+}
+
+// cgroupDirs lists the directories of the cgroup at path in hierarchy hier
+// ("" for v2) and of its ancestors up to the hierarchy root, leaf first,
+// skipping those that do not exist. A container without a cgroup namespace
+// sees its host-side path in /proc/self/cgroup but has its own cgroup
+// mounted at the hierarchy root, which the walk still reaches.
+func cgroupDirs(roots utils.Roots, hier, path string) []string {
+	var dirs []string
+	for p := path; ; p = filepath.Dir(p) {
+		dir := roots.SysPath("fs", "cgroup", hier, p)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+		if p == "/" || p == "." || p == "" {
+			return dirs
+		}
+	}
+}
+
+// readCgroupValue reads the cgroup file name in dir.
+func readCgroupValue(dir, name string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+func cpuQuota(quota, period string) float64 {
+	q, err1 := strconv.ParseFloat(quota, 64)
+	p, err2 := strconv.ParseFloat(period, 64)
+	if err1 != nil || err2 != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// readNamespaceInode returns the inode of namespace name of p, or "" when
+// the link cannot be read (other users' processes without CAP_SYS_PTRACE).
+func readNamespaceInode(p procfs.Proc, name string) string {
+	ino, err := p.Namespace(name)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatUint(ino, 10)
+}
+
+// whereamiHostNamespaces reports whether the host's PID and network
+// namespaces are in use. The initial PID namespace has a fixed inode; the
+// network namespace is compared with PID 1's once PID 1 is known to be the
+// host's init, since only recent kernels fix its inode too.
+func whereamiHostNamespaces(namespaces []whereamiNamespace) (hostPID, hostNet string) {
+	hostPID, hostNet = "unknown", "unknown"
+	var pid, net whereamiNamespace
+	for _, ns := range namespaces {
+		switch ns.name {
+		case "pid":
+			pid = ns
+		case "net":
+			net = ns
+		}
+	}
+	if pid.self != "" {
+		hostPID = yesNo(pid.self == strconv.Itoa(procPidInitIno))
+	}
+	switch {
+	case net.self == strconv.Itoa(procNetInitIno):
+		hostNet = "yes"
+	case hostPID == "yes" && net.self != "" && net.pid1 != "":
+		hostNet = yesNo(net.self == net.pid1)
+	}
+	return hostPID, hostNet
+}
+
+// detectContainerRuntime recognises the runtime from the scope and
+// directory names each one gives its cgroups, then from the marker files
+// docker and podman leave in the container's root.
+func detectContainerRuntime(roots utils.Roots, cgroupPaths []string, containerEnv string) (runtime, id string) {
+	for _, p := range cgroupPaths {
+		if id == "" {
+			id = containerIDPattern.FindString(p)
+		}
+		switch {
+		case runtime != "":
+		case strings.Contains(p, "/docker/") || strings.Contains(p, "docker-"):
+			runtime = "docker"
+		case strings.Contains(p, "cri-containerd-") || strings.Contains(p, "/containerd/"):
+			runtime = "containerd"
+		case strings.Contains(p, "crio-"):
+			runtime = "cri-o"
+		case strings.Contains(p, "libpod"):
+			runtime = "podman"
+		case strings.Contains(p, "/lxc/") || strings.Contains(p, "lxc.payload"):
+			runtime = "lxc"
+		}
+	}
+	if runtime != "" {
+		return runtime, id
+	}
+	switch {
+	case containerEnv == "podman" || fileExists(roots.HostPath("/run/.containerenv")):
+		runtime = "podman"
+	case fileExists(roots.HostPath("/.dockerenv")):
+		runtime = "docker"
+	case containerEnv != "":
+		runtime = containerEnv
+	}
+	return runtime, id
+}
+
+// readWhereamiKubernetes reads the pod's identity from the service-account
+// mount and the variables Kubernetes and the usual downward-API manifests
+// set.
+func readWhereamiKubernetes(r *whereamiReport, roots utils.Roots, getenv func(string) string) {
+	dir := roots.HostPath(kubeServiceAccountDir)
+	ns, nsErr := os.ReadFile(dir + "/namespace")
+	if nsErr != nil && getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return
+	}
+	r.kubernetes = true
+	r.kubeNamespace = firstNonEmpty(getenv("POD_NAMESPACE"), strings.TrimSpace(string(ns)))
+	r.kubePod = firstNonEmpty(getenv("POD_NAME"), getenv("HOSTNAME"))
+	r.kubeNode = firstNonEmpty(getenv("NODE_NAME"), getenv("KUBE_NODE_NAME"))
+	r.kubeAccount = getenv("SERVICE_ACCOUNT")
+	if r.kubeAccount == "" {
+		r.kubeAccount = serviceAccountFromToken(dir + "/token")
+	}
+}
+
+// serviceAccountFromToken reads the service account name from the
+// "sub" claim of the mounted token (system:serviceaccount:NS:NAME) without
+// verifying it.
+func serviceAccountFromToken(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.TrimSpace(string(data)), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	m := serviceAccountSubPattern.FindSubmatch(payload)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
+
+// readWhereamiStatus picks the security settings out of /proc/PID/status.
+func readWhereamiStatus(r *whereamiReport, st procfs.ProcStatus) {
+	switch st.Seccomp {
+	case 0:
+		r.seccomp = "disabled"
+	case 1:
+		r.seccomp = "strict"
+	case 2:
+		r.seccomp = "filter"
+	default:
+		r.seccomp = strconv.Itoa(st.Seccomp)
+	}
+	r.noNewPrivs = yesNo(st.NoNewPrivs)
+	r.capEff = fmt.Sprintf("%016x", st.CapEff)
+	r.capabilities = decodeCapabilities(st.CapEff)
+}
+
+// decodeCapabilities names the bits of a capability mask, or reports it as
+// "all" when every known capability is set.
+func decodeCapabilities(bits uint64) []string {
+	var names []string
+	for i, name := range capabilityNames {
+		if bits&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == len(capabilityNames) {
+		return []string{"all"}
+	}
+	return names
+}
+
+func printWhereami(w io.Writer, r whereamiReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Runtime:\t%s\n", r.runtime)
+	if r.containerID != "" {
+		fmt.Fprintf(tw, "Container ID:\t%s\n", r.containerID)
+	}
+	fmt.Fprintf(tw, "Cgroup:\t%s %s\n", r.cgroupVersion, r.cgroupPath)
+	if r.cpuQuota > 0 {
+		fmt.Fprintf(tw, "CPU quota:\t%.2f CPUs (%s)\n", r.cpuQuota, r.cpuQuotaRaw)
+	} else {
+		fmt.Fprintf(tw, "CPU quota:\tunlimited\n")
+	}
+	if r.memoryLimit > 0 {
+		fmt.Fprintf(tw, "Memory limit:\t%s\n", utils.HumanSize(r.memoryLimit))
+	} else {
+		fmt.Fprintf(tw, "Memory limit:\tunlimited\n")
+	}
+	fmt.Fprintf(tw, "Host PID namespace:\t%s\n", r.hostPID)
+	fmt.Fprintf(tw, "Host network:\t%s\n", r.hostNetwork)
+	if r.kubernetes {
+		fmt.Fprintf(tw, "Kubernetes:\tpod %s, namespace %s, node %s, service account %s\n",
+			orDash(r.kubePod), orDash(r.kubeNamespace), orDash(r.kubeNode), orDash(r.kubeAccount))
+	} else {
+		fmt.Fprintf(tw, "Kubernetes:\tno\n")
+	}
+	fmt.Fprintf(tw, "Seccomp:\t%s\n", orDash(r.seccomp))
+	fmt.Fprintf(tw, "NoNewPrivs:\t%s\n", orDash(r.noNewPrivs))
+	fmt.Fprintf(tw, "Capabilities:\t%s %s\n", orDash(r.capEff), strings.Join(r.capabilities, ","))
+	fmt.Fprintf(tw, "Root filesystem:\t%s\n", map[bool]string{true: "read-only", false: "read-write"}[r.rootReadOnly])
+	if len(r.readOnly) > 0 {
+		fmt.Fprintf(tw, "Read-only mounts:\t%s\n", strings.Join(r.readOnly, " "))
+	}
+	tw.Flush()
+
+	if len(r.namespaces) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tSELF\tPID 1\tSHARED")
+	for _, ns := range r.namespaces {
+		shared := "-"
+		if ns.pid1 != "" {
+			shared = yesNo(ns.self == ns.pid1)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ns.name, ns.self, orDash(ns.pid1), shared)
+	}
+	tw.Flush()
+}
+
+// whereamiTable reports one field per row; limits are raw numbers with
+// null for unlimited.
+func whereamiTable(r whereamiReport) *utils.Table {
+	t := utils.NewTable("field", "value")
+	t.Append("runtime", r.runtime)
+	t.Append("container_id", r.containerID)
+	t.Append("cgroup_version", r.cgroupVersion)
+	t.Append("cgroup_path", r.cgroupPath)
+	if r.cpuQuota > 0 {
+		t.Append("cpu_quota_cpus", r.cpuQuota)
+	} else {
+		t.Append("cpu_quota_cpus", nil)
+	}
+	if r.memoryLimit > 0 {
+		t.Append("memory_limit_bytes", r.memoryLimit)
+	} else {
+		t.Append("memory_limit_bytes", nil)
+	}
+	t.Append("host_pid_namespace", r.hostPID)
+	t.Append("host_network", r.hostNetwork)
+	for _, ns := range r.namespaces {
+		t.Append("namespace_"+ns.name, ns.self)
+		t.Append("pid1_namespace_"+ns.name, ns.pid1)
+	}
+	t.Append("kubernetes", r.kubernetes)
+	t.Append("kube_pod", r.kubePod)
+	t.Append("kube_namespace", r.kubeNamespace)
+	t.Append("kube_node", r.kubeNode)
+	t.Append("kube_service_account", r.kubeAccount)
+	t.Append("seccomp", r.seccomp)
+	t.Append("no_new_privs", r.noNewPrivs)
+	t.Append("cap_eff", r.capEff)
+	t.Append("capabilities", strings.Join(r.capabilities, ","))
+	t.Append("root_read_only", r.rootReadOnly)
+	t.Append("read_only_mounts", strings.Join(r.readOnly, " "))
+	return t
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
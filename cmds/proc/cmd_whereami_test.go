@@ -0,0 +1,193 @@
+package proc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+	"gobox/internal/testutil"
+	"gobox/pkg/procfs"
+)
+
+const whereamiContainerID = "3f2a9c0d1e2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5"
+
+// useWhereamiFixture lays out PID 1 of a containerd-run Kubernetes pod on
+// cgroup v2 with a read-only root, in its own PID and network namespaces.
+func useWhereamiFixture(t *testing.T) string {
+	t.Helper()
+	scope := "/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + whereamiContainerID + ".scope"
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"system:serviceaccount:shop:web-sa"}`))
+	root := useProcFixture(t, map[string]string{
+		"1/cgroup":  "0::" + scope + "\n",
+		"1/status":  "Name:\tapp\nNoNewPrivs:\t1\nSeccomp:\t2\nCapEff:\t00000000a80425fb\n",
+		"1/environ": "HOSTNAME=web-0\x00KUBERNETES_SERVICE_HOST=10.0.0.1\x00",
+		"1/mountinfo": "1 0 0:1 / / ro,relatime - overlay overlay rw\n" +
+			"2 1 0:2 / /proc rw,nosuid - proc proc rw\n" +
+			"3 1 0:3 / /proc/sys ro,nosuid - proc proc rw\n",
+		"1/root/var/run/secrets/kubernetes.io/serviceaccount/namespace": "shop\n",
+		"1/root/var/run/secrets/kubernetes.io/serviceaccount/token":     "e30." + claims + ".sig\n",
+	})
+	for name, inode := range map[string]string{"pid": "4026532301", "net": "4026532304", "mnt": "4026532300"} {
+		if err := os.MkdirAll(filepath.Join(root, "1", "ns"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(name+":["+inode+"]", filepath.Join(root, "1", "ns", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sys := testutil.Tree(t, map[string]string{
+		"fs/cgroup" + scope + "/cpu.max":    "150000 100000\n",
+		"fs/cgroup" + scope + "/memory.max": "536870912\n",
+	})
+	t.Setenv(base.SysRootEnv, sys)
+	return root
+}
+
+func TestWhereamiReportsContainer(t *testing.T) {
+	useWhereamiFixture(t)
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdout: &out, Stderr: &out}
+	if err := whereamiCmd(inv, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Runtime:             containerd",
+		"Container ID:        " + whereamiContainerID,
+		"Cgroup:              v2 /kubepods.slice/",
+		"CPU quota:           1.50 CPUs (150000/100000)",
+		"Memory limit:        512.0MB",
+		"Host PID namespace:  no",
+		"Kubernetes:          pod web-0, namespace shop, node -, service account web-sa",
+		"Seccomp:             filter",
+		"NoNewPrivs:          yes",
+		"Capabilities:        00000000a80425fb chown,dac_override,fowner,fsetid,kill,setgid,setuid,setpcap,net_bind_service,net_raw,sys_chroot,mknod,audit_write,setfcap",
+		"Root filesystem:     read-only",
+		"Read-only mounts:    / /proc/sys",
+		"pid        4026532301  4026532301  yes",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWhereamiStructuredOutput(t *testing.T) {
+	useWhereamiFixture(t)
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdout: &out, Stderr: &out, Output: "csv"}
+	if err := whereamiCmd(inv, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"field,value\n", "runtime,containerd\n", "cpu_quota_cpus,1.5\n", "memory_limit_bytes,536870912\n", "namespace_net,4026532304\n", "root_read_only,true\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWhereamiCgroupV1Limits(t *testing.T) {
+	// Without a cgroup namespace the container's own cgroup is mounted at
+	// the hierarchy root rather than under its host-side path.
+	sys := testutil.Tree(t, map[string]string{
+		"fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+		"fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+		"fs/cgroup/memory/memory.limit_in_bytes":  "9223372036854771712\n",
+	})
+	var r whereamiReport
+	cgroups, err := procfs.ParseCgroups(strings.NewReader("4:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readWhereamiCgroup(&r, utils.NewRoots("", sys), cgroups)
+	if r.cgroupVersion != "v1" || r.cgroupPath != "/docker/abc" {
+		t.Fatalf("unexpected cgroup %q %q", r.cgroupVersion, r.cgroupPath)
+	}
+	if r.cpuQuota != 0.5 || r.memoryLimit != 0 {
+		t.Fatalf("expected 0.5 CPUs and no memory limit, got %v %d", r.cpuQuota, r.memoryLimit)
+	}
+}
+
+func TestWhereamiCgroupLimitsOnAncestors(t *testing.T) {
+	// Kubernetes sets the limits on the pod slice, not on the container's
+	// own scope; the tightest limit along the path is the effective one.
+	pod := "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1.slice"
+	scope := pod + "/cri-containerd-" + whereamiContainerID + ".scope"
+	sys := testutil.Tree(t, map[string]string{
+		"fs/cgroup/kubepods.slice/cpu.max":    "400000 100000\n",
+		"fs/cgroup/kubepods.slice/memory.max": "8589934592\n",
+		"fs/cgroup" + pod + "/cpu.max":        "50000 100000\n",
+		"fs/cgroup" + pod + "/memory.max":     "268435456\n",
+		"fs/cgroup" + scope + "/cpu.max":      "max 100000\n",
+		"fs/cgroup" + scope + "/memory.max":   "max\n",
+	})
+	var r whereamiReport
+	cgroups, err := procfs.ParseCgroups(strings.NewReader("0::" + scope + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readWhereamiCgroup(&r, utils.NewRoots("", sys), cgroups)
+	if r.cgroupVersion != "v2" || r.cgroupPath != scope {
+		t.Fatalf("unexpected cgroup %q %q", r.cgroupVersion, r.cgroupPath)
+	}
+	if r.cpuQuota != 0.5 || r.cpuQuotaRaw != "50000/100000" || r.memoryLimit != 268435456 {
+		t.Fatalf("expected the pod slice's limits, got %v (%s) %d", r.cpuQuota, r.cpuQuotaRaw, r.memoryLimit)
+	}
+
+	// cgroup v1: the memory limit is only on the parent.
+	sys = testutil.Tree(t, map[string]string{
+		"fs/cgroup/memory/kubepods/pod1/memory.limit_in_bytes":      "134217728\n",
+		"fs/cgroup/memory/kubepods/pod1/ctr/memory.limit_in_bytes":  "9223372036854771712\n",
+		"fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_quota_us":  "-1\n",
+		"fs/cgroup/cpu,cpuacct/kubepods/pod1/ctr/cpu.cfs_period_us": "100000\n",
+		"fs/cgroup/cpu,cpuacct/kubepods/pod1/cpu.cfs_quota_us":      "200000\n",
+		"fs/cgroup/cpu,cpuacct/kubepods/pod1/cpu.cfs_period_us":     "100000\n",
+	})
+	r = whereamiReport{}
+	cgroups, err = procfs.ParseCgroups(strings.NewReader("4:memory:/kubepods/pod1/ctr\n3:cpu,cpuacct:/kubepods/pod1/ctr\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readWhereamiCgroup(&r, utils.NewRoots("", sys), cgroups)
+	if r.cpuQuota != 2 || r.memoryLimit != 134217728 {
+		t.Fatalf("expected the parent's v1 limits, got %v %d", r.cpuQuota, r.memoryLimit)
+	}
+}
+
+func TestDetectContainerRuntime(t *testing.T) {
+	roots := utils.NewRoots(useProcFixture(t, map[string]string{}), "")
+	for _, tc := range []struct {
+		paths      []string
+		env, want  string
+		wantWithID bool
+	}{
+		{paths: []string{"/docker/" + whereamiContainerID}, want: "docker", wantWithID: true},
+		{paths: []string{"/system.slice/docker-" + whereamiContainerID + ".scope"}, want: "docker", wantWithID: true},
+		{paths: []string{"/kubepods/burstable/pod1/crio-" + whereamiContainerID + ".scope"}, want: "cri-o", wantWithID: true},
+		{paths: []string{"/machine.slice/libpod-" + whereamiContainerID + ".scope"}, want: "podman", wantWithID: true},
+		{paths: []string{"/"}, env: "podman", want: "podman"},
+		{paths: []string{"/"}, env: "systemd-nspawn", want: "systemd-nspawn"},
+		{paths: []string{"/user.slice"}, want: ""},
+	} {
+		runtime, id := detectContainerRuntime(roots, tc.paths, tc.env)
+		if runtime != tc.want || (id != "") != tc.wantWithID {
+			t.Errorf("%v %q: got %q %q, want %q", tc.paths, tc.env, runtime, id, tc.want)
+		}
+	}
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	if got := decodeCapabilities(0); got != nil {
+		t.Fatalf("expected no capabilities, got %v", got)
+	}
+	if got := decodeCapabilities(0x1ffffffffff); len(got) != 1 || got[0] != "all" {
+		t.Fatalf("expected all, got %v", got)
+	}
+	if got := strings.Join(decodeCapabilities(0x200400), ","); got != "net_bind_service,sys_admin" {
+		t.Fatalf("unexpected capabilities %q", got)
+	}
+}
@@ -4,19 +4,22 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
-	"os"
-	"os/exec"
 	"strings"
 	"sync"
 )
 
 // xargsCmd implements a basic subset of xargs
 func XargsCmd(args []string) error {
+	return xargsCmd(base.Stdio(), args)
+}
+
+func xargsCmd(inv *base.Invocation, args []string) error {
 	args = normalizeLegacyXargsArgs(args)
 	xargsFlags := flag.NewFlagSet("xargs", flag.ContinueOnError)
-	xargsFlags.SetOutput(os.Stderr)
+	xargsFlags.SetOutput(inv.Stderr)
 	replaceStr := xargsFlags.String("i", "", "replace string (same as -I, use {} as default)")
 	replaceStr2 := xargsFlags.String("I", "", "replace string with custom placeholder")
 	delimiter := xargsFlags.String("d", "\n", "input delimiter (default: newline)")
@@ -27,19 +30,19 @@ func XargsCmd(args []string) error {
 	noRun := xargsFlags.Bool("r", false, "do not run command if no input")
 
 	xargsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox xargs [OPTION]... [COMMAND [ARG]...]")
-		fmt.Fprintln(os.Stderr, "Build and execute command lines from standard input.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -i REPL            replace string (same as -I, use {} as default)")
-		fmt.Fprintln(os.Stderr, "  -I REPL            replace string with custom placeholder")
-		fmt.Fprintln(os.Stderr, "  -d DELIM           input delimiter (default: newline)")
-		fmt.Fprintln(os.Stderr, "  -n N               max number of arguments per command invocation")
-		fmt.Fprintln(os.Stderr, "  -P N               max number of parallel processes")
-		fmt.Fprintln(os.Stderr, "  -t                 print commands before executing")
-		fmt.Fprintln(os.Stderr, "  -v                 legacy alias for -t")
-		fmt.Fprintln(os.Stderr, "  -r                 do not run command if no input")
-		fmt.Fprintln(os.Stderr, "  -h, --help         show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox xargs [OPTION]... [COMMAND [ARG]...]")
+		fmt.Fprintln(inv.Stderr, "Build and execute command lines from standard input.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -i REPL            replace string (same as -I, use {} as default)")
+		fmt.Fprintln(inv.Stderr, "  -I REPL            replace string with custom placeholder")
+		fmt.Fprintln(inv.Stderr, "  -d DELIM           input delimiter (default: newline)")
+		fmt.Fprintln(inv.Stderr, "  -n N               max number of arguments per command invocation")
+		fmt.Fprintln(inv.Stderr, "  -P N               max number of parallel processes")
+		fmt.Fprintln(inv.Stderr, "  -t                 print commands before executing")
+		fmt.Fprintln(inv.Stderr, "  -v                 legacy alias for -t")
+		fmt.Fprintln(inv.Stderr, "  -r                 do not run command if no input")
+		fmt.Fprintln(inv.Stderr, "  -h, --help         show this help")
 	}
 
 	if err := utils.ParseFlagSet(xargsFlags, args); err != nil {
@@ -54,6 +57,9 @@ func XargsCmd(args []string) error {
 	if len(cmdArgs) == 0 {
 		cmdArgs = []string{"echo"}
 	}
+	if err := base.CheckExternal(inv, cmdArgs[0]); err != nil {
+		return err
+	}
 
 	// Determine replace string
 	replaceString := ""
@@ -72,7 +78,7 @@ func XargsCmd(args []string) error {
 	}
 
 	// Read input
-	inputs, err := parseXargsInputs(os.Stdin, *delimiter, hasReplace)
+	inputs, err := parseXargsInputs(inv.Stdin, *delimiter, hasReplace)
 	if err != nil {
 		return err
 	}
@@ -87,13 +93,9 @@ func XargsCmd(args []string) error {
 	// If no input, run command once
 	if len(inputs) == 0 {
 		if verbose {
-			fmt.Fprintf(os.Stderr, "%s\n", strings.Join(cmdArgs, " "))
+			fmt.Fprintf(inv.Stderr, "%s\n", strings.Join(cmdArgs, " "))
 		}
-		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-		return cmd.Run()
+		return inv.Exec(inv.Ctx(), cmdArgs[0], cmdArgs[1:]...).Run()
 	}
 
 	// -P 0 means unlimited parallelism (GNU xargs behaviour)
@@ -107,10 +109,10 @@ func XargsCmd(args []string) error {
 	// Process inputs in batches and execute commands in parallel
 	if replaceString != "" {
 		// Replace mode: replace placeholder with each input
-		return executeReplaceMode(cmdArgs, inputs, replaceString, verbose, maxP)
+		return executeReplaceMode(inv, cmdArgs, inputs, replaceString, verbose, maxP)
 	} else {
 		// Append mode: append inputs to command
-		return executeAppendMode(cmdArgs, inputs, *numArgs, verbose, maxP)
+		return executeAppendMode(inv, cmdArgs, inputs, *numArgs, verbose, maxP)
 	}
 }
 
@@ -182,7 +184,7 @@ func parseXargsInputs(r io.Reader, delimiter string, lineMode bool) ([]string, e
 }
 
 // executeReplaceMode replaces the placeholder with inputs
-func executeReplaceMode(baseCmd []string, inputs []string, replaceString string, verbose bool, maxProcs int) error {
+func executeReplaceMode(inv *base.Invocation, baseCmd []string, inputs []string, replaceString string, verbose bool, maxProcs int) error {
 	semaphore := make(chan struct{}, maxProcs)
 	var wg sync.WaitGroup
 	var lastErr error
@@ -204,12 +206,12 @@ func executeReplaceMode(baseCmd []string, inputs []string, replaceString string,
 			}
 
 			if verbose {
-				fmt.Fprintf(os.Stderr, "%s\n", strings.Join(cmdArgs, " "))
+				fmt.Fprintf(inv.Stderr, "%s\n", strings.Join(cmdArgs, " "))
 			}
 
-			cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
+			// Input was consumed building the arguments; children get none.
+			cmd := inv.Exec(inv.Ctx(), cmdArgs[0], cmdArgs[1:]...)
+			cmd.Stdin = nil
 
 			if err := cmd.Run(); err != nil {
 				mu.Lock()
@@ -224,7 +226,7 @@ func executeReplaceMode(baseCmd []string, inputs []string, replaceString string,
 }
 
 // executeAppendMode appends inputs to the command in batches
-func executeAppendMode(baseCmd []string, inputs []string, batchSize int, verbose bool, maxProcs int) error {
+func executeAppendMode(inv *base.Invocation, baseCmd []string, inputs []string, batchSize int, verbose bool, maxProcs int) error {
 	if batchSize <= 0 {
 		batchSize = len(inputs)
 	}
@@ -254,12 +256,11 @@ func executeAppendMode(baseCmd []string, inputs []string, batchSize int, verbose
 			cmdArgs = append(cmdArgs, batchItems...)
 
 			if verbose {
-				fmt.Fprintf(os.Stderr, "%s\n", strings.Join(cmdArgs, " "))
+				fmt.Fprintf(inv.Stderr, "%s\n", strings.Join(cmdArgs, " "))
 			}
 
-			cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
+			cmd := inv.Exec(inv.Ctx(), cmdArgs[0], cmdArgs[1:]...)
+			cmd.Stdin = nil
 
 			if err := cmd.Run(); err != nil {
 				mu.Lock()
@@ -2,10 +2,14 @@ package proc
 
 import (
 	"bufio"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"gobox/cmds/base"
 )
 
 // runXargsWithStdin feeds stdinInput to XargsCmd and captures combined
@@ -239,3 +243,25 @@ func TestXargsCmdParallelProcessesRunConcurrently(t *testing.T) {
 		t.Fatalf("expected -P 4 to complete near 0.2s, took %s (sequential took %s)", parallel, sequential)
 	}
 }
+
+func TestExternalCommandRunnersRefusedInReadOnlyMode(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	for name, run := range map[string]func(*base.Invocation, []string) error{
+		"xargs":   xargsCmd,
+		"timeout": timeoutCmd,
+		"watch":   watchCmd,
+		"init":    initCmd,
+	} {
+		args := []string{"touch", marker}
+		if name == "timeout" {
+			args = append([]string{"5s"}, args...)
+		}
+		inv := &base.Invocation{Stdin: strings.NewReader(""), Stdout: io.Discard, Stderr: io.Discard, Env: []string{base.ReadOnlyEnv + "=1"}}
+		if err := run(inv, args); base.ExitStatus(err) != base.ReadOnlyExitCode {
+			t.Fatalf("%s: expected a read-only refusal, got %v", name, err)
+		}
+		if _, err := os.Stat(marker); err == nil {
+			t.Fatalf("%s ran the command in read-only mode", name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package proc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// procMetricsSample is how long the first proc scrape samples CPU for; later
+// scrapes measure against the snapshot the previous one took, like top
+// refreshing at the scrape interval.
+const procMetricsSample = 250 * time.Millisecond
+
+var procMetricsState = struct {
+	sync.Mutex
+	prev *procSnapshot
+}{}
+
+// collectMemMetrics exposes /proc/meminfo the way free reads it, one gauge
+// per field.
+func collectMemMetrics(w *utils.MetricsWriter, opts base.MetricsOptions) error {
+	mem, err := readMemInfoData(opts.Roots)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(mem))
+	for key := range mem {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// HugePages_* are page counts, not bytes.
+		if strings.HasPrefix(key, "HugePages_") {
+			name := "gobox_memory_" + utils.MetricName(key)
+			w.Family(name, utils.MetricGauge, "Memory information field "+key+".")
+			w.Sample(name, float64(mem[key]))
+			continue
+		}
+		name := "gobox_memory_" + utils.MetricName(key) + "_bytes"
+		w.Family(name, utils.MetricGauge, "Memory information field "+key+".")
+		w.Sample(name, float64(mem[key]))
+	}
+	return w.Err()
+}
+
+// collectProcMetrics exposes process counts by state and, for the busiest
+// and largest processes, their CPU and resident memory, sampled the way top
+// samples /proc/PID/stat.
+func collectProcMetrics(w *utils.MetricsWriter, opts base.MetricsOptions) error {
+	procMetricsState.Lock()
+	defer procMetricsState.Unlock()
+	prev := procMetricsState.prev
+	if prev == nil {
+		first, err := captureLinuxProcSnapshot(opts.Roots)
+		if err != nil {
+			return err
+		}
+		prev = &first
+		time.Sleep(procMetricsSample)
+	}
+	curr, err := captureLinuxProcSnapshot(opts.Roots)
+	if err != nil {
+		return err
+	}
+	procMetricsState.prev = &curr
+	infos := diffProcSnapshots(*prev, curr)
+
+	states := map[string]int{}
+	for _, pi := range infos {
+		states[topRenderState(pi)]++
+	}
+	names := make([]string, 0, len(states))
+	for state := range states {
+		names = append(names, state)
+	}
+	sort.Strings(names)
+	w.Family("gobox_processes", utils.MetricGauge, "Number of processes by state.")
+	for _, state := range names {
+		w.Sample("gobox_processes", float64(states[state]), "state", state)
+	}
+
+	top := topMetricsProcesses(infos, opts.TopProcesses)
+	w.Family("gobox_process_cpu_percent", utils.MetricGauge, "CPU usage of the busiest and largest processes since the previous scrape, in percent of one CPU.")
+	for _, pi := range top {
+		w.Sample("gobox_process_cpu_percent", pi.cpu, "pid", strconv.Itoa(pi.pid), "comm", pi.exe)
+	}
+	w.Family("gobox_process_cpu_seconds_total", utils.MetricCounter, "User and system CPU time of the busiest and largest processes.")
+	for _, pi := range top {
+		w.Sample("gobox_process_cpu_seconds_total", float64(pi.utime+pi.stime)/float64(procClockTicks), "pid", strconv.Itoa(pi.pid), "comm", pi.exe)
+	}
+	w.Family("gobox_process_resident_memory_bytes", utils.MetricGauge, "Resident memory of the busiest and largest processes.")
+	for _, pi := range top {
+		w.Sample("gobox_process_resident_memory_bytes", float64(pi.rss), "pid", strconv.Itoa(pi.pid), "comm", pi.exe)
+	}
+	return w.Err()
+}
+
+// topMetricsProcesses returns the union of the n processes with the most CPU
+// and the n with the most resident memory, in PID order.
+func topMetricsProcesses(infos []procInfo, n int) []procInfo {
+	if n <= 0 {
+		return nil
+	}
+	picked := map[int]procInfo{}
+	for _, field := range []string{"cpu", "rss"} {
+		sortTopInfos(infos, field, true, 0)
+		for i := 0; i < n && i < len(infos); i++ {
+			picked[infos[i].pid] = infos[i]
+		}
+	}
+	out := make([]procInfo, 0, len(picked))
+	for _, pi := range picked {
+		out = append(out, pi)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].pid < out[j].pid })
+	return out
+}
@@ -0,0 +1,42 @@
+package proc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+func TestCollectMemMetrics(t *testing.T) {
+	setupFreeInjected(t)
+	readMemInfoData = func(utils.Roots) (map[string]uint64, error) {
+		return map[string]uint64{"MemTotal": 2048 * 1024, "Active(anon)": 4096, "HugePages_Total": 8}, nil
+	}
+	var buf bytes.Buffer
+	if err := collectMemMetrics(utils.NewMetricsWriter(&buf), base.MetricsOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"gobox_memory_MemTotal_bytes 2.097152e+06\n", "gobox_memory_Active_anon_bytes 4096\n", "gobox_memory_HugePages_Total 8\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in %q", want, out)
+		}
+	}
+}
+
+func TestTopMetricsProcessesUnionsCPUAndRSS(t *testing.T) {
+	infos := []procInfo{
+		{pid: 1, cpu: 50, rss: 10},
+		{pid: 2, cpu: 1, rss: 900},
+		{pid: 3, cpu: 2, rss: 20},
+	}
+	got := topMetricsProcesses(infos, 1)
+	if len(got) != 2 || got[0].pid != 1 || got[1].pid != 2 {
+		t.Fatalf("unexpected selection: %+v", got)
+	}
+	if topMetricsProcesses(infos, 0) != nil {
+		t.Fatal("expected no processes for n=0")
+	}
+}
@@ -0,0 +1,210 @@
+package proc
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"gobox/cmds/utils"
+)
+
+// procSystemState is the system-wide state top's summary shows. Live top
+// reads it from procfs as it renders; a recorded snapshot carries the state
+// from when it was taken.
+type procSystemState struct {
+	loadavg [3]float64
+	uptime  time.Duration
+	mem     map[string]uint64 // bytes, keyed like /proc/meminfo
+	note    string            // extra summary line, e.g. the replay position
+}
+
+// procRecordMemKeys are the meminfo fields a record keeps: the ones the top
+// summary and %MEM need.
+var procRecordMemKeys = []string{"MemTotal", "MemFree", "MemAvailable", "Buffers", "Cached", "SReclaimable", "SwapTotal", "SwapFree"}
+
+// procRecord is one line of a --record file: a gzip stream of JSON records,
+// one per sample. Each recording session appends its own gzip member, which
+// gzip readers treat as one continuous stream.
+type procRecord struct {
+	Time    time.Time         `json:"time"`
+	Threads bool              `json:"threads,omitempty"`
+	NumCPU  int               `json:"num_cpu"`
+	Jiffies int64             `json:"total_jiffies"`
+	CPU     [8]uint64         `json:"cpu"` // user nice system idle iowait irq softirq steal
+	LoadAvg [3]float64        `json:"loadavg"`
+	Uptime  float64           `json:"uptime_seconds"`
+	Mem     map[string]uint64 `json:"meminfo"`
+	Procs   []procRecordInfo  `json:"procs"`
+}
+
+type procRecordInfo struct {
+	PID       int       `json:"pid"`
+	TGID      int       `json:"tgid,omitempty"`
+	PPID      int       `json:"ppid"`
+	Comm      string    `json:"comm"`
+	Cmdline   string    `json:"cmdline,omitempty"`
+	VSize     int64     `json:"vsize"`
+	RSS       int64     `json:"rss"`
+	UTime     int64     `json:"utime"`
+	STime     int64     `json:"stime"`
+	UID       int       `json:"uid"`
+	User      string    `json:"user,omitempty"`
+	State     string    `json:"state"`
+	TTY       string    `json:"tty,omitempty"`
+	Start     time.Time `json:"start"`
+	Flags     uint64    `json:"flags,omitempty"`
+	Priority  int64     `json:"priority"`
+	Nice      int64     `json:"nice"`
+	Processor int64     `json:"processor"`
+	WChan     string    `json:"wchan,omitempty"`
+}
+
+// procRecorder appends snapshots to a --record file. Every record is
+// flushed, so a recording cut short by a killed pod stays readable up to its
+// last complete sample.
+type procRecorder struct {
+	f       *os.File
+	zw      *gzip.Writer
+	enc     *json.Encoder
+	roots   utils.Roots
+	threads bool
+}
+
+func openProcRecorder(path string, roots utils.Roots, threads bool) (*procRecorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	zw := gzip.NewWriter(f)
+	return &procRecorder{f: f, zw: zw, enc: json.NewEncoder(zw), roots: roots, threads: threads}, nil
+}
+
+// record appends s together with the current system state.
+func (r *procRecorder) record(s procSnapshot) error {
+	if err := r.enc.Encode(newProcRecord(s, readProcSystemState(r.roots), r.threads)); err != nil {
+		return err
+	}
+	return r.zw.Flush()
+}
+
+func (r *procRecorder) Close() error {
+	err := r.zw.Close()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func readProcSystemState(roots utils.Roots) *procSystemState {
+	state := &procSystemState{uptime: readTopUptime(roots), mem: map[string]uint64{}}
+	state.loadavg[0], state.loadavg[1], state.loadavg[2] = readTopLoadAvg(roots)
+	mem, _ := readMemInfoData(roots)
+	for _, key := range procRecordMemKeys {
+		if v, ok := mem[key]; ok {
+			state.mem[key] = v
+		}
+	}
+	return state
+}
+
+func newProcRecord(s procSnapshot, state *procSystemState, threads bool) procRecord {
+	c := s.cpuTimes
+	rec := procRecord{
+		Time:    s.taken,
+		Threads: threads,
+		NumCPU:  s.numCPU,
+		Jiffies: s.totalJiffies,
+		CPU:     [8]uint64{c.user, c.nice, c.system, c.idle, c.iowait, c.irq, c.softirq, c.steal},
+		LoadAvg: state.loadavg,
+		Uptime:  state.uptime.Seconds(),
+		Mem:     state.mem,
+		Procs:   make([]procRecordInfo, 0, len(s.infos)),
+	}
+	for _, pi := range s.infos {
+		rec.Procs = append(rec.Procs, procRecordInfo{
+			PID: pi.pid, TGID: pi.tgid, PPID: pi.ppid,
+			Comm: pi.exe, Cmdline: pi.cmdline,
+			VSize: pi.vsize, RSS: pi.rss,
+			UTime: pi.utime, STime: pi.stime,
+			UID: pi.uid, User: pi.user,
+			State: pi.state, TTY: pi.tty, Start: pi.start,
+			Flags: pi.flags, Priority: pi.priority, Nice: pi.nice,
+			Processor: pi.processor, WChan: pi.wchan,
+		})
+	}
+	sort.Slice(rec.Procs, func(i, j int) bool { return rec.Procs[i].PID < rec.Procs[j].PID })
+	return rec
+}
+
+func (rec procRecord) snapshot() procSnapshot {
+	s := procSnapshot{
+		totalJiffies: rec.Jiffies,
+		cpuTimes: cpuTimes{
+			user: rec.CPU[0], nice: rec.CPU[1], system: rec.CPU[2], idle: rec.CPU[3],
+			iowait: rec.CPU[4], irq: rec.CPU[5], softirq: rec.CPU[6], steal: rec.CPU[7],
+		},
+		infos:  make(map[int]procInfo, len(rec.Procs)),
+		taken:  rec.Time,
+		numCPU: rec.NumCPU,
+		system: &procSystemState{
+			loadavg: rec.LoadAvg,
+			uptime:  time.Duration(rec.Uptime * float64(time.Second)),
+			mem:     rec.Mem,
+		},
+	}
+	for _, p := range rec.Procs {
+		pi := procInfo{
+			pid: p.PID, tgid: p.TGID, ppid: p.PPID,
+			exe: p.Comm, cmdline: p.Cmdline,
+			vsize: p.VSize, rss: p.RSS,
+			utime: p.UTime, stime: p.STime,
+			uid: p.UID, user: p.User,
+			state: p.State, tty: p.TTY, start: p.Start,
+			flags: p.Flags, priority: p.Priority, nice: p.Nice,
+			processor: p.Processor, wchan: p.WChan,
+		}
+		if !pi.start.IsZero() && rec.Time.After(pi.start) {
+			pi.elapsed = rec.Time.Sub(pi.start)
+		}
+		s.infos[pi.pid] = pi
+	}
+	return s
+}
+
+// readProcRecords loads every snapshot in a --record file, oldest first, and
+// reports whether any of them was a thread (top -H) recording. A truncated
+// final record, left by a recorder that was killed, is ignored.
+func readProcRecords(path string) ([]procSnapshot, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: not a gobox record file: %w", path, err)
+	}
+	var snapshots []procSnapshot
+	threads := false
+	dec := json.NewDecoder(zr)
+	for {
+		var rec procRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF || (errors.Is(err, io.ErrUnexpectedEOF) && len(snapshots) > 0) {
+				break
+			}
+			return nil, false, fmt.Errorf("%s: record %d: %w", path, len(snapshots)+1, err)
+		}
+		threads = threads || rec.Threads
+		snapshots = append(snapshots, rec.snapshot())
+	}
+	if len(snapshots) == 0 {
+		return nil, false, fmt.Errorf("%s: no snapshots recorded", path)
+	}
+	return snapshots, threads, nil
+}
@@ -0,0 +1,164 @@
+package proc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+func useRecordFixture(t *testing.T) utils.Roots {
+	t.Helper()
+	root := useProcFixture(t, map[string]string{
+		"stat":         "cpu  100 0 100 1000 0 0 0 0 0 0\nbtime 1700000000\n",
+		"meminfo":      "MemTotal:        2048000 kB\nMemFree:         1024000 kB\n",
+		"loadavg":      "1.50 1.00 0.50 1/100 4242\n",
+		"uptime":       "3700.00 1000.00\n",
+		"4242/stat":    fixtureProcStat("4242", "fixture-sleep", 1),
+		"4242/status":  "Name:\tfixture-sleep\nUid:\t0\t0\t0\t0\n",
+		"4242/comm":    "fixture-sleep\n",
+		"4242/cmdline": "fixture-sleep\x0030\x00",
+	})
+	return utils.NewRoots(root, "")
+}
+
+func recordTestSnapshot(taken time.Time, jiffies int64, procs ...procInfo) procSnapshot {
+	s := procSnapshot{totalJiffies: jiffies, infos: map[int]procInfo{}, taken: taken, numCPU: 2}
+	for _, pi := range procs {
+		s.infos[pi.pid] = pi
+	}
+	return s
+}
+
+func TestProcRecordRoundTrip(t *testing.T) {
+	roots := useRecordFixture(t)
+	path := filepath.Join(t.TempDir(), "top.rec")
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	busy := procInfo{pid: 7, ppid: 1, exe: "busy", cmdline: "busy --spin", user: "app", uid: 1000, state: "R", rss: 4096, start: start.Add(-time.Hour)}
+
+	for session := 0; session < 2; session++ {
+		rec, err := openProcRecorder(path, roots, session == 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		taken := start.Add(time.Duration(session) * time.Minute)
+		busy.utime = int64(session * 100)
+		if err := rec.record(recordTestSnapshot(taken, int64(session*200), busy)); err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, threads, err := readProcRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || !threads {
+		t.Fatalf("expected both appended sessions and the thread flag, got %d (%v)", len(snapshots), threads)
+	}
+	got := snapshots[1].infos[7]
+	if got.exe != "busy" || got.cmdline != "busy --spin" || got.user != "app" || got.utime != 100 || got.elapsed != time.Hour+time.Minute {
+		t.Fatalf("unexpected replayed process %+v", got)
+	}
+	system := snapshots[1].system
+	if system == nil || system.loadavg[0] != 1.5 || system.uptime != 3700*time.Second || system.mem["MemTotal"] != 2048000*1024 {
+		t.Fatalf("expected the recorded system state, got %+v", system)
+	}
+	if infos := diffProcSnapshots(snapshots[0], snapshots[1]); len(infos) != 1 || infos[0].cpu != 100 {
+		t.Fatalf("expected CPU%% from the recorded CPU count, got %+v", infos)
+	}
+}
+
+func TestProcRecordKeepsSamplesBeforeTruncation(t *testing.T) {
+	roots := useRecordFixture(t)
+	path := filepath.Join(t.TempDir(), "top.rec")
+	rec, err := openProcRecorder(path, roots, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := rec.record(recordTestSnapshot(time.Unix(int64(i), 0), int64(i), procInfo{pid: 1, exe: "init"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The recorder was killed: no gzip trailer, and part of a record lost.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-10], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, _, err := readProcRecords(path)
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("expected the two complete samples, got %d (%v)", len(snapshots), err)
+	}
+
+	if err := os.WriteFile(path, []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readProcRecords(path); err == nil || !strings.Contains(err.Error(), "not a gobox record file") {
+		t.Fatalf("expected a format error, got %v", err)
+	}
+}
+
+func TestPsRecordAndTopReplay(t *testing.T) {
+	useRecordFixture(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ps.rec")
+	logPath := filepath.Join(dir, "audit.log")
+	t.Setenv(base.AuditEnv, logPath)
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdout: &out, Stderr: &out, Dir: dir}
+	if err := psCmd(inv, []string{"-e", "-i", "0", "--record", "ps.rec"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := psCmd(inv, []string{"-e", "-i", "0", "--record", "ps.rec"}); err != nil {
+		t.Fatal(err)
+	}
+	if snapshots, _, err := readProcRecords(path); err != nil || len(snapshots) != 4 {
+		t.Fatalf("expected two samples per ps run, got %d (%v)", len(snapshots), err)
+	}
+	audit, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(audit)), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"action":"write","target":"`+path+`"`) {
+		t.Fatalf("expected one write record per ps --record run, got %q", audit)
+	}
+
+	// Replay needs no procfs: point it at an empty one.
+	useProcFixture(t, nil)
+	out.Reset()
+	if err := topCmd(inv, []string{"--replay", path, "-b", "-n", "2", "-p", "4242"}); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, want := range []string{"load average: 1.50, 1.00, 0.50", "Replay: sample 1/3 at ", "Replay: sample 2/3 at ", "fixture-sleep"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("replay output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "sample 3/3") {
+		t.Fatalf("expected -n 2 to stop after two frames:\n%s", text)
+	}
+
+	out.Reset()
+	if err := topCmd(&base.Invocation{Stdout: &out, Stderr: &out, Output: "csv"}, []string{"--replay", path}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[3], "3,4242,") {
+		t.Fatalf("expected one CSV row per frame, got %q", out.String())
+	}
+
+	if err := topCmd(inv, []string{"--replay", path, "--record", "x"}); err == nil {
+		t.Fatal("expected --record with --replay to be rejected")
+	}
+}
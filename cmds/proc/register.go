@@ -3,12 +3,25 @@ package proc
 import "gobox/cmds/base"
 
 func init() {
-	base.Register(base.NewCommand("ps", "List processes", base.Adapt(PsCmd)))
-	base.Register(base.NewCommand("top", "Live process viewer", base.Adapt(TopCmd)))
-	base.Register(base.NewCommand("free", "Show memory usage", base.Adapt(FreeCmd)))
-	base.Register(base.NewCommand("xargs", "Build and execute command lines from stdin", base.Adapt(XargsCmd)))
-	base.Register(base.NewCommand("kill", "Send signals to processes", base.Adapt(KillCmd)))
-	base.Register(base.NewCommand("lsof", "List open files", base.Adapt(LsofCmd)))
-	base.Register(base.NewCommand("watch", "Run a command periodically", base.Adapt(WatchCmd)))
-	base.Register(base.NewCommand("timeout", "Run a command with a time limit", base.Adapt(TimeoutCmd)))
+	base.Register(base.NewCommand("ps", "List processes", psCmd, base.WithTabularOutput(),
+		base.WithFlagValues("-p", pidCompletions), base.WithFlagValues("--sort", psSortKeys)))
+	base.Register(base.NewCommand("top", "Live process viewer", topCmd, base.WithTabularOutput(),
+		base.WithFlagValues("-p", pidCompletions), base.WithFlagValues("--sort", topSortKeys), base.WithFlagValues("-o", topSortKeys)))
+	base.Register(base.NewCommand("free", "Show memory usage", freeCmd, base.WithTabularOutput()))
+	base.Register(base.NewCommand("xargs", "Build and execute command lines from stdin", xargsCmd))
+	base.Register(base.NewCommand("kill", "Send signals to processes", killCmd,
+		base.WithFlagValues("-s", signalNames)))
+	base.Register(base.NewCommand("lsof", "List open files", lsofCmd, base.WithTabularOutput(),
+		base.WithFlagValues("-p", pidCompletions)))
+	base.Register(base.NewCommand("watch", "Run a command periodically", watchCmd))
+	base.Register(base.NewCommand("timeout", "Run a command with a time limit", timeoutCmd,
+		base.WithFlagValues("-s", signalNames), base.WithFlagValues("--signal", signalNames)))
+	base.Register(base.NewCommand("init", "Run a command as a minimal PID-1 init", initCmd, base.WithOwnSignals(),
+		base.WithFlagValues("--stop-signal", signalNames)))
+	base.Register(base.NewCommand("whereami", "Show the container, cgroup and namespaces this process runs in", whereamiCmd, base.WithTabularOutput()))
+
+	base.RegisterMetricsCollector(base.MetricsCollector{Name: "mem", Help: "Memory usage from /proc/meminfo", Collect: collectMemMetrics})
+	base.RegisterMetricsCollector(base.MetricsCollector{Name: "proc", Help: "Process states and the top-N processes by CPU and RSS", Collect: collectProcMetrics})
+	base.RegisterCheckType(base.CheckType{Name: "zombies", Help: "at most max (0) zombie processes", Run: runZombiesCheck})
+	base.RegisterCheckType(base.CheckType{Name: "process", Help: "between min (1) and max processes run command", Run: runProcessCheck})
 }
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"strconv"
 	"testing"
+
+	"gobox/cmds/base"
+	"gobox/internal/testutil"
 )
 
 func captureProcCmd(t *testing.T, fn func() error) (string, error) {
@@ -22,3 +26,18 @@ func captureProcCmd(t *testing.T, fn func() error) (string, error) {
 	_, _ = io.Copy(&buf, r)
 	return buf.String(), runErr
 }
+
+// useProcFixture writes files (relative path -> content) into a temporary
+// procfs tree and points the collectors at it, through GOBOX_PROCFS, for
+// the rest of the test.
+func useProcFixture(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := testutil.Tree(t, files)
+	t.Setenv(base.ProcRootEnv, root)
+	return root
+}
+
+// fixtureProcStat is a /proc/PID/stat line for a sleeping process.
+func fixtureProcStat(pid, comm string, ppid int) string {
+	return pid + " (" + comm + ") S " + strconv.Itoa(ppid) + " 1 1 0 -1 4194304 0 0 0 0 10 5 0 0 20 0 1 0 100 1048576 64 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n"
+}
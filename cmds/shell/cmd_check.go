@@ -0,0 +1,413 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// checkFailedError carries check's exit status once the results table has
+// already reported which checks failed.
+type checkFailedError int
+
+func (e checkFailedError) Error() string          { return fmt.Sprintf("%d checks failed", int(e)) }
+func (e checkFailedError) ExitCode() int          { return 1 }
+func (e checkFailedError) SuppressCLIError() bool { return true }
+
+// checkResult is one check's outcome.
+type checkResult struct {
+	check   base.Check
+	passed  bool
+	detail  string
+	elapsed time.Duration
+}
+
+func CheckCmd(args []string) error {
+	return checkCmd(base.Stdio(), args)
+}
+
+func checkCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("check", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	file := fsFlags.String("f", "", "check file (- for stdin)")
+	junit := fsFlags.String("junit", "", "also write JUnit XML to FILE (- for stdout instead of the table)")
+	quiet := fsFlags.Bool("q", false, "print only failing checks")
+	fsFlags.Usage = func() { printCheckUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if fsFlags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fsFlags.Arg(0))
+	}
+	if *file == "" {
+		return fmt.Errorf("missing check file (-f FILE)")
+	}
+	if *junit != "" && *junit != "-" {
+		if err := base.CheckWritable(inv, "check --junit FILE"); err != nil {
+			return err
+		}
+	}
+	var data []byte
+	var err error
+	if *file == "-" {
+		data, err = io.ReadAll(inv.Stdin)
+	} else {
+		data, err = os.ReadFile(inv.Path(*file))
+	}
+	if err != nil {
+		return err
+	}
+	checks, err := parseCheckFile(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *file, err)
+	}
+
+	started := time.Now()
+	results := runChecks(inv, checks)
+	elapsed := time.Since(started)
+	failed := 0
+	for _, r := range results {
+		if !r.passed {
+			failed++
+		}
+	}
+
+	if *junit != "" {
+		w := inv.Stdout
+		if *junit != "-" {
+			f, err := os.Create(inv.Path(*junit))
+			base.Audit(inv, "write", inv.Path(*junit), err)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := writeCheckJUnit(w, results, elapsed); err != nil {
+			return err
+		}
+	}
+	if *junit != "-" {
+		if utils.IsStructuredOutput(inv.Output) {
+			if err := checkTable(results).Render(inv.Stdout, inv.Output); err != nil {
+				return err
+			}
+		} else {
+			printCheckResults(inv.Stdout, results, *quiet)
+		}
+	}
+	if failed > 0 {
+		return checkFailedError(failed)
+	}
+	return nil
+}
+
+func printCheckUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox check -f FILE [--junit FILE] [-q]")
+	fmt.Fprintln(w, "Run the health checks listed in FILE (JSON or a simple YAML subset) and report")
+	fmt.Fprintln(w, "pass/fail for each. Exits 1 when any check fails, 2 when FILE is invalid.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -f FILE         check file, - for stdin")
+	fmt.Fprintln(w, "  --junit FILE    also write JUnit XML to FILE; - writes it to stdout instead of the table")
+	fmt.Fprintln(w, "  -q              print only failing checks")
+	fmt.Fprintln(w, "  --output FORMAT text, json, ndjson, csv or tsv")
+	fmt.Fprintln(w, "  -h              show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Check types (settings, defaults in parentheses):")
+	for _, t := range base.CheckTypes() {
+		fmt.Fprintf(w, "  %-8s %s\n", t.Name, t.Help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Example file:")
+	fmt.Fprintln(w, "  checks:")
+	fmt.Fprintln(w, "    - name: postgres")
+	fmt.Fprintln(w, "      type: tcp")
+	fmt.Fprintln(w, "      address: db:5432")
+	fmt.Fprintln(w, "    - type: disk")
+	fmt.Fprintln(w, "      path: /data")
+	fmt.Fprintln(w, "      max_use: 90%")
+	fmt.Fprintln(w, "    - type: http")
+	fmt.Fprintln(w, "      url: http://127.0.0.1:8080/healthz")
+	fmt.Fprintln(w, "      timeout: 500ms")
+}
+
+// runChecks runs every check concurrently and returns the results in file
+// order.
+func runChecks(inv *base.Invocation, checks []base.Check) []checkResult {
+	results := make([]checkResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c base.Check) {
+			defer wg.Done()
+			t, _ := base.LookupCheckType(c.Type)
+			started := time.Now()
+			detail, err := t.Run(inv, c)
+			results[i] = checkResult{check: c, passed: err == nil, detail: detail, elapsed: time.Since(started)}
+			if err != nil {
+				results[i].detail = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+func printCheckResults(w io.Writer, results []checkResult, quiet bool) {
+	nameWidth, typeWidth := len("NAME"), len("TYPE")
+	for _, r := range results {
+		if len(r.check.Name) > nameWidth {
+			nameWidth = len(r.check.Name)
+		}
+		if len(r.check.Type) > typeWidth {
+			typeWidth = len(r.check.Type)
+		}
+	}
+	fmt.Fprintf(w, "%-6s %-*s %-*s %9s  %s\n", "STATUS", nameWidth, "NAME", typeWidth, "TYPE", "TIME", "DETAIL")
+	failed := 0
+	for _, r := range results {
+		status := "PASS"
+		if !r.passed {
+			status = "FAIL"
+			failed++
+		} else if quiet {
+			continue
+		}
+		fmt.Fprintf(w, "%-6s %-*s %-*s %9s  %s\n", status, nameWidth, r.check.Name, typeWidth, r.check.Type,
+			r.elapsed.Round(time.Millisecond), r.detail)
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed\n", len(results)-failed, failed)
+}
+
+func checkTable(results []checkResult) *utils.Table {
+	t := utils.NewTable("name", "type", "passed", "duration_seconds", "detail")
+	for _, r := range results {
+		t.Append(r.check.Name, r.check.Type, r.passed, r.elapsed.Seconds(), r.detail)
+	}
+	return t
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeCheckJUnit(w io.Writer, results []checkResult, elapsed time.Duration) error {
+	suite := junitTestSuite{Name: "gobox check", Tests: len(results), Time: junitSeconds(elapsed)}
+	for _, r := range results {
+		tc := junitTestCase{Name: r.check.Name, ClassName: "gobox.check." + r.check.Type, Time: junitSeconds(r.elapsed)}
+		if r.passed {
+			tc.SystemOut = r.detail
+		} else {
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: r.detail, Text: r.detail}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// parseCheckFile reads a check file: JSON when it starts with { or [,
+// otherwise the YAML subset parseCheckYAML accepts. Either form is a list
+// of checks, optionally under a top-level "checks" key.
+func parseCheckFile(data []byte) ([]base.Check, error) {
+	trimmed := bytes.TrimSpace(data)
+	var items []map[string]string
+	var err error
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		items, err = parseCheckJSON(trimmed)
+	} else {
+		items, err = parseCheckYAML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no checks")
+	}
+	checks := make([]base.Check, 0, len(items))
+	for i, item := range items {
+		c := base.Check{Name: item["name"], Type: item["type"], Params: map[string]string{}}
+		if c.Type == "" {
+			return nil, fmt.Errorf("check %d: missing type", i+1)
+		}
+		if _, ok := base.LookupCheckType(c.Type); !ok {
+			var names []string
+			for _, t := range base.CheckTypes() {
+				names = append(names, t.Name)
+			}
+			return nil, fmt.Errorf("check %d: unknown type %q (available: %s)", i+1, c.Type, strings.Join(names, ", "))
+		}
+		if c.Name == "" {
+			c.Name = c.Type + "#" + strconv.Itoa(i+1)
+		}
+		for k, v := range item {
+			if k != "name" && k != "type" {
+				c.Params[k] = v
+			}
+		}
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
+
+func parseCheckJSON(data []byte) ([]map[string]string, error) {
+	var raw []map[string]any
+	if data[0] == '{' {
+		var doc struct {
+			Checks []map[string]any `json:"checks"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		raw = doc.Checks
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+	}
+	items := make([]map[string]string, 0, len(raw))
+	for i, obj := range raw {
+		item := make(map[string]string, len(obj))
+		for k, v := range obj {
+			switch v := v.(type) {
+			case string:
+				item[k] = v
+			case json.Number:
+				item[k] = v.String()
+			case bool:
+				item[k] = strconv.FormatBool(v)
+			case nil:
+			default:
+				return nil, fmt.Errorf("check %d: %s must be a string, number or boolean", i+1, k)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseCheckYAML accepts the block-list subset of YAML check files are
+// written in: an optional "checks:" line, "- key: value" starting each
+// check, further "key: value" lines belonging to it, scalar values
+// (optionally quoted) and # comments. Anything else is an error rather
+// than a guess.
+func parseCheckYAML(data []byte) ([]map[string]string, error) {
+	var items []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(stripYAMLComment(scanner.Text()), " \t")
+		text := strings.TrimSpace(line)
+		if text == "" || text == "---" {
+			continue
+		}
+		if text == "checks:" && line == text {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(text, "-"); ok && (rest == "" || rest[0] == ' ') {
+			items = append(items, map[string]string{})
+			text = strings.TrimSpace(rest)
+			if text == "" {
+				continue
+			}
+		}
+		if len(items) == 0 {
+			return nil, fmt.Errorf("line %d: expected \"- key: value\" to start a check", lineNo)
+		}
+		key, value, ok := strings.Cut(text, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"'") {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", lineNo)
+		}
+		v, err := unquoteYAMLScalar(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		items[len(items)-1][key] = v
+	}
+	return items, scanner.Err()
+}
+
+// stripYAMLComment drops a # comment that starts the line or follows
+// whitespace outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteYAMLScalar(v string) (string, error) {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		return strconv.Unquote(v)
+	}
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'"), nil
+	}
+	if strings.HasPrefix(v, "[") || strings.HasPrefix(v, "{") || strings.HasPrefix(v, "|") || strings.HasPrefix(v, ">") {
+		return "", fmt.Errorf("only scalar values are supported, got %q", v)
+	}
+	return v, nil
+}
@@ -0,0 +1,112 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gobox/cmds/base"
+)
+
+var registerCheckTestTypes sync.Once
+
+func ensureCheckTestTypes() {
+	registerCheckTestTypes.Do(func() {
+		base.RegisterCheckType(base.CheckType{Name: "zz_check_echo", Help: "test", Run: func(_ *base.Invocation, c base.Check) (string, error) {
+			if msg := c.Param("fail", ""); msg != "" {
+				return "", errors.New(msg)
+			}
+			return c.Param("say", "fine"), nil
+		}})
+	})
+}
+
+func TestParseCheckFileYAMLAndJSONAgree(t *testing.T) {
+	ensureCheckTestTypes()
+	yaml := `# runbook
+checks:
+  - name: first   # trailing comment
+    type: zz_check_echo
+    say: "a # b"
+  -
+    type: zz_check_echo
+    port: 5432
+`
+	json := `{"checks":[{"name":"first","type":"zz_check_echo","say":"a # b"},{"type":"zz_check_echo","port":5432}]}`
+	for _, src := range []string{yaml, json} {
+		checks, err := parseCheckFile([]byte(src))
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if len(checks) != 2 || checks[0].Name != "first" || checks[0].Params["say"] != "a # b" {
+			t.Fatalf("unexpected first check from %q: %+v", src, checks)
+		}
+		if checks[1].Name != "zz_check_echo#2" || checks[1].Params["port"] != "5432" {
+			t.Fatalf("unexpected second check from %q: %+v", src, checks[1])
+		}
+	}
+}
+
+func TestParseCheckFileErrors(t *testing.T) {
+	ensureCheckTestTypes()
+	for src, want := range map[string]string{
+		"type: zz_check_echo\n":                    "line 1",
+		"- type: zz_check_echo\n  ports: [1, 2]\n": "only scalar values",
+		"- type: nosuch\n":                         `unknown type "nosuch"`,
+		"- name: x\n":                              "missing type",
+		`[{"type":"zz_check_echo","x":{}}]`:        "must be a string",
+		"# nothing\n":                              "no checks",
+	} {
+		if _, err := parseCheckFile([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: expected error containing %q, got %v", src, want, err)
+		}
+	}
+}
+
+func TestCheckCmdReportsFailuresAndJUnit(t *testing.T) {
+	ensureCheckTestTypes()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "checks.yaml")
+	if err := os.WriteFile(file, []byte("- name: ok\n  type: zz_check_echo\n- name: broken\n  type: zz_check_echo\n  fail: port closed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "audit.log")
+	t.Setenv(base.AuditEnv, logPath)
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdout: &out, Stderr: io.Discard, Dir: dir}
+	err := checkCmd(inv, []string{"-f", "checks.yaml", "--junit", "report.xml"})
+	if base.ExitStatus(err) != 1 || base.ReportError(err) {
+		t.Fatalf("expected silent exit 1, got %v", err)
+	}
+	text := out.String()
+	if !strings.Contains(text, "PASS") || !strings.Contains(text, "port closed") || !strings.Contains(text, "1 passed, 1 failed") {
+		t.Fatalf("unexpected table: %q", text)
+	}
+	xml, err := os.ReadFile(filepath.Join(dir, "report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`tests="2" failures="1"`, `<testcase name="broken" classname="gobox.check.zz_check_echo"`, `<failure message="port closed">`} {
+		if !strings.Contains(string(xml), want) {
+			t.Fatalf("missing %q in %s", want, xml)
+		}
+	}
+	if audit, err := os.ReadFile(logPath); err != nil || !strings.Contains(string(audit), `"action":"write","target":"`+filepath.Join(dir, "report.xml")+`"`) {
+		t.Fatalf("expected a write record for the JUnit report, got %q (%v)", audit, err)
+	}
+
+	out.Reset()
+	inv.Output = "json"
+	inv.Stdin = strings.NewReader(`[{"type":"zz_check_echo","say":"hi"}]`)
+	if err := checkCmd(inv, []string{"-f", "-"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"passed":true`) || !strings.Contains(out.String(), `"detail":"hi"`) {
+		t.Fatalf("unexpected json: %q", out.String())
+	}
+}
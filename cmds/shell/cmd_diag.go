@@ -0,0 +1,440 @@
+package shell
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// Diag entry statuses, as listed in the bundle's manifest.
+const (
+	diagOK         = "ok"
+	diagWarning    = "warning"
+	diagPermission = "permission"
+	diagFailed     = "failed"
+	diagSkipped    = "skipped"
+)
+
+// diagStep is one file of the bundle: the output of a gobox command run
+// in-process, or of collect.
+type diagStep struct {
+	file    string
+	name    string
+	args    []string
+	collect func(utils.Roots) ([]byte, error)
+	sampled bool
+}
+
+// diagEntry is a collected step.
+type diagEntry struct {
+	step    diagStep
+	started time.Time
+	elapsed time.Duration
+	data    []byte
+	stderr  []byte
+	status  string
+	detail  string
+}
+
+func DiagCmd(args []string) error {
+	return diagCmd(base.Stdio(), args)
+}
+
+func diagCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("diag", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	output := fsFlags.String("o", "", "write the bundle to FILE (- for stdout)")
+	durationArg := fsFlags.String("duration", "10s", "sampling window for top, iostat and ifstat")
+	fsFlags.Usage = func() { printDiagUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if fsFlags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fsFlags.Arg(0))
+	}
+	samples, err := diagSampleCount(*durationArg)
+	if err != nil {
+		return err
+	}
+	if *output != "-" {
+		// Without -o - the bundle is written to a file.
+		if err := base.CheckWritable(inv, "diag bundle file (use -o -)"); err != nil {
+			return err
+		}
+	}
+
+	started := time.Now()
+	host, _ := os.Hostname()
+	bundle := "gobox-diag-" + started.Format("20060102-150405")
+	if host != "" {
+		bundle = "gobox-diag-" + host + "-" + started.Format("20060102-150405")
+	}
+	// Open the destination first, so a bad -o fails before the sampling
+	// window rather than after it.
+	path := *output
+	if path == "" {
+		path = bundle + ".tar.gz"
+	}
+	var file *os.File
+	if path != "-" {
+		if file, err = os.Create(inv.Path(path)); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	entries := runDiagSteps(inv, diagSteps(samples))
+	var out io.Writer = inv.Stdout
+	if file != nil {
+		out = file
+	}
+	if err := writeDiagBundle(out, bundle, host, inv.Roots(), started, entries); err != nil {
+		return err
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, e := range entries {
+		if e.status != diagOK && e.status != diagWarning {
+			failed++
+		}
+	}
+	fmt.Fprintf(inv.Stderr, "diag: wrote %s (%d entries, %d failed or skipped; see manifest.txt)\n", path, len(entries), failed)
+	return nil
+}
+
+func printDiagUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox diag [-o FILE] [--duration DUR]")
+	fmt.Fprintln(w, "Collect a one-shot incident bundle (tar.gz) using gobox's own commands.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -o FILE          bundle path, - for stdout (default gobox-diag-HOST-TIME.tar.gz)")
+	fmt.Fprintln(w, "  --duration DUR   sampling window for top, iostat and ifstat (default 10s)")
+	fmt.Fprintln(w, "  -h               show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Contents:")
+	fmt.Fprintln(w, "  ps -ef, top -b, free, df -h, df -i, iostat, ifstat, netstat -tanp,")
+	fmt.Fprintln(w, "  netstat -s, ip addr/route/neigh, lsof summary, resolv.conf, cgroup limits,")
+	fmt.Fprintln(w, "  and manifest.txt with the timing and status of each entry.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox diag")
+	fmt.Fprintln(w, "  gobox diag -o /tmp/incident.tar.gz --duration 30s")
+	fmt.Fprintln(w, "  gobox --proc-root /host/proc diag -o - > host.tar.gz")
+}
+
+// diagSampleCount converts the sampling window (a Go duration or plain
+// seconds) into one-second samples.
+func diagSampleCount(arg string) (int, error) {
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		secs, ferr := strconv.ParseFloat(arg, 64)
+		if ferr != nil {
+			return 0, fmt.Errorf("invalid duration %q", arg)
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	if d < time.Second {
+		return 0, fmt.Errorf("duration must be at least 1s")
+	}
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// diagSteps lists the bundle's contents in manifest order. iostat and ifstat
+// report rates between consecutive reads, so samples intervals take one
+// more read.
+func diagSteps(samples int) []diagStep {
+	n := strconv.Itoa(samples + 1)
+	return []diagStep{
+		{file: "ps-ef.txt", name: "ps", args: []string{"-ef"}},
+		{file: "top.txt", name: "top", args: []string{"-b", "-d", "1", "-n", "1"}, sampled: true},
+		{file: "free.txt", name: "free"},
+		{file: "df-h.txt", name: "df", args: []string{"-h"}},
+		{file: "df-i.txt", name: "df", args: []string{"-i"}},
+		{file: "iostat.txt", name: "iostat", args: []string{"-i", "1", "-n", n}, sampled: true},
+		{file: "ifstat.txt", name: "ifstat", args: []string{"-p", "1", "-n", n}, sampled: true},
+		{file: "netstat-tanp.txt", name: "netstat", args: []string{"-tanp"}},
+		{file: "netstat-s.txt", name: "netstat", args: []string{"-s"}},
+		{file: "ip-addr.txt", name: "ip", args: []string{"addr"}},
+		{file: "ip-route.txt", name: "ip", args: []string{"route"}},
+		{file: "ip-neigh.txt", name: "ip", args: []string{"neigh"}},
+		{file: "lsof-summary.txt", name: "lsof"},
+		{file: "resolv.conf", collect: func(roots utils.Roots) ([]byte, error) { return os.ReadFile(roots.HostPath("/etc/resolv.conf")) }},
+		{file: "cgroup-limits.txt", collect: readDiagCgroupLimits},
+	}
+}
+
+// runDiagSteps runs the sampled steps concurrently over the sampling window
+// and the snapshots one after another meanwhile.
+func runDiagSteps(inv *base.Invocation, steps []diagStep) []diagEntry {
+	entries := make([]diagEntry, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		if step.sampled {
+			wg.Add(1)
+			go func(i int, step diagStep) {
+				defer wg.Done()
+				entries[i] = runDiagStep(inv, step)
+			}(i, step)
+		}
+	}
+	for i, step := range steps {
+		if !step.sampled {
+			entries[i] = runDiagStep(inv, step)
+		}
+	}
+	wg.Wait()
+	return entries
+}
+
+func runDiagStep(inv *base.Invocation, step diagStep) diagEntry {
+	e := diagEntry{step: step, started: time.Now()}
+	if inv.Ctx().Err() != nil {
+		e.status, e.detail = diagSkipped, "interrupted"
+		return e
+	}
+	var err error
+	switch {
+	case step.collect != nil:
+		e.data, err = step.collect(inv.Roots())
+	case step.name == "lsof":
+		e.data, e.stderr, err = runDiagCommand(inv, step.name, step.args, "csv")
+		if err == nil {
+			e.data, err = summarizeLsof(e.data)
+		}
+	default:
+		e.data, e.stderr, err = runDiagCommand(inv, step.name, step.args, "")
+	}
+	e.elapsed = time.Since(e.started)
+	e.status, e.detail = classifyDiagResult(err, e.stderr)
+	return e
+}
+
+// runDiagCommand runs a registered gobox command in-process with its output
+// captured. The config file's defaults are deliberately not applied, so
+// every bundle has the same layout.
+func runDiagCommand(inv *base.Invocation, name string, args []string, output string) ([]byte, []byte, error) {
+	cmd, ok := base.Lookup(name)
+	if !ok || base.IsPlugin(cmd) {
+		return nil, nil, fmt.Errorf("%s: command not available", name)
+	}
+	var stdout, stderr bytes.Buffer
+	err := cmd.Run(&base.Invocation{
+		Context:  inv.Ctx(),
+		Stdin:    strings.NewReader(""),
+		Stdout:   &stdout,
+		Stderr:   &stderr,
+		Env:      inv.Env,
+		Dir:      inv.Dir,
+		Output:   output,
+		ReadOnly: base.ReadOnly(inv),
+	}, args)
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// classifyDiagResult derives an entry's status and the manifest detail from
+// its error and stderr; permission problems are called out separately since
+// they usually mean the pod lacks a capability or hostPID.
+func classifyDiagResult(err error, stderr []byte) (string, string) {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	} else if line, _, _ := strings.Cut(strings.TrimSpace(string(stderr)), "\n"); line != "" {
+		detail = line
+	}
+	lower := strings.ToLower(detail + "\n" + string(stderr))
+	switch {
+	case errors.Is(err, fs.ErrPermission) || strings.Contains(lower, "permission denied") || strings.Contains(lower, "operation not permitted"):
+		return diagPermission, detail
+	case err != nil:
+		return diagFailed, detail
+	case detail != "":
+		return diagWarning, detail
+	}
+	return diagOK, ""
+}
+
+// summarizeLsof condenses lsof's CSV rows into open-file counts per type and
+// for the processes holding the most files.
+func summarizeLsof(data []byte) ([]byte, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("lsof: no output")
+	}
+	col := map[string]int{}
+	for i, name := range records[0] {
+		col[name] = i
+	}
+	byType := map[string]int{}
+	byProc := map[string]int{}
+	for _, rec := range records[1:] {
+		byType[rec[col["type"]]]++
+		byProc[rec[col["pid"]]+" "+rec[col["command"]]]++
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "open files: %d\n\n", len(records)-1)
+	fmt.Fprintf(&buf, "%-10s %8s\n", "TYPE", "COUNT")
+	for _, key := range sortedDiagCounts(byType) {
+		fmt.Fprintf(&buf, "%-10s %8d\n", key, byType[key])
+	}
+	fmt.Fprintf(&buf, "\n%-8s %-20s %8s\n", "PID", "COMMAND", "COUNT")
+	for i, key := range sortedDiagCounts(byProc) {
+		if i == 20 {
+			break
+		}
+		pid, command, _ := strings.Cut(key, " ")
+		fmt.Fprintf(&buf, "%-8s %-20s %8d\n", pid, command, byProc[key])
+	}
+	return buf.Bytes(), nil
+}
+
+// sortedDiagCounts orders keys by descending count, then by name.
+func sortedDiagCounts(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// diagCgroupFiles are the limit and usage files recorded from each cgroup
+// the process belongs to, by hierarchy (v2 is "").
+var diagCgroupFiles = map[string][]string{
+	"":            {"cgroup.controllers", "cpu.max", "cpu.weight", "cpu.stat", "memory.max", "memory.high", "memory.current", "memory.events", "pids.max", "pids.current", "io.max"},
+	"memory":      {"memory.limit_in_bytes", "memory.usage_in_bytes", "memory.max_usage_in_bytes", "memory.failcnt"},
+	"cpu":         {"cpu.cfs_quota_us", "cpu.cfs_period_us", "cpu.shares"},
+	"cpu,cpuacct": {"cpu.cfs_quota_us", "cpu.cfs_period_us", "cpu.shares"},
+	"pids":        {"pids.max", "pids.current"},
+}
+
+// readDiagCgroupLimits records the limit files of the cgroups listed in
+// /proc/self/cgroup (PID 1's under --proc-root).
+func readDiagCgroupLimits(roots utils.Roots) ([]byte, error) {
+	cgroups, err := roots.SelfProc().Cgroups()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	var denied error
+	for _, cg := range cgroups {
+		hierarchy := strings.Join(cg.Controllers, ",")
+		files, ok := diagCgroupFiles[hierarchy]
+		if !ok {
+			continue
+		}
+		dir := roots.SysPath("fs", "cgroup", hierarchy, cg.Path)
+		for _, name := range files {
+			value, err := os.ReadFile(filepath.Join(dir, name))
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				continue
+			case err != nil:
+				if denied == nil && errors.Is(err, fs.ErrPermission) {
+					denied = err
+				}
+				fmt.Fprintf(&buf, "%s: %v\n", filepath.Join(dir, name), err)
+				continue
+			}
+			fmt.Fprintf(&buf, "==> %s <==\n%s\n", filepath.Join(dir, name), strings.TrimRight(string(value), "\n"))
+		}
+	}
+	return buf.Bytes(), denied
+}
+
+// writeDiagBundle writes the entries, their stderr as FILE.stderr, and
+// manifest.txt under a bundle/ directory of a gzipped tar stream.
+func writeDiagBundle(w io.Writer, bundle, host string, roots utils.Roots, started time.Time, entries []diagEntry) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, modTime time.Time, data []byte) error {
+		hdr := &tar.Header{Name: bundle + "/" + name, Mode: 0o644, Size: int64(len(data)), ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	for _, e := range entries {
+		if e.data != nil || e.status == diagOK {
+			if err := add(e.step.file, e.started, e.data); err != nil {
+				return err
+			}
+		}
+		if len(e.stderr) > 0 {
+			if err := add(e.step.file+".stderr", e.started, e.stderr); err != nil {
+				return err
+			}
+		}
+	}
+	finished := time.Now()
+	if err := add("manifest.txt", finished, diagManifest(host, roots, started, finished, entries)); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func diagManifest(host string, roots utils.Roots, started, finished time.Time, entries []diagEntry) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# gobox diag")
+	fmt.Fprintln(&buf, "# host:", host)
+	fmt.Fprintln(&buf, "# started:", started.Format(time.RFC3339))
+	fmt.Fprintln(&buf, "# finished:", finished.Format(time.RFC3339))
+	fmt.Fprintf(&buf, "# proc root: %s, sys root: %s\n", roots.Proc(), roots.Sys())
+	fmt.Fprintf(&buf, "%-18s %-10s %-25s %8s  %-22s %s\n", "FILE", "STATUS", "STARTED", "SECONDS", "SOURCE", "DETAIL")
+	var denied []string
+	for _, e := range entries {
+		source := "gobox " + strings.TrimSpace(e.step.name+" "+strings.Join(e.step.args, " "))
+		if e.step.collect != nil {
+			source = "file"
+		}
+		started := "-"
+		if e.status != diagSkipped {
+			started = e.started.Format(time.RFC3339)
+		}
+		line := fmt.Sprintf("%-18s %-10s %-25s %8.1f  %-22s %s", e.step.file, e.status, started, e.elapsed.Seconds(), source, e.detail)
+		fmt.Fprintln(&buf, strings.TrimRight(line, " "))
+		if e.status == diagPermission {
+			denied = append(denied, e.step.file)
+		}
+	}
+	if len(denied) > 0 {
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, "# permission denied:", strings.Join(denied, " "))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+	"gobox/internal/testutil"
+)
+
+// readDiagBundle returns the bundle's files by name, without the top-level
+// directory.
+func readDiagBundle(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir, name, _ := strings.Cut(hdr.Name, "/")
+		if !strings.HasPrefix(dir, "gobox-diag-") || hdr.ModTime.IsZero() {
+			t.Fatalf("unexpected entry %q (%v)", hdr.Name, hdr.ModTime)
+		}
+		data, _ := io.ReadAll(tr)
+		files[name] = string(data)
+	}
+}
+
+func TestDiagWritesBundleAndManifest(t *testing.T) {
+	procRoot := testutil.Tree(t, map[string]string{
+		"1/cgroup":               "0::/pod\n",
+		"1/root/etc/resolv.conf": "nameserver 10.0.0.10\n",
+	})
+	sysRoot := testutil.Tree(t, map[string]string{
+		"fs/cgroup/pod/memory.max": "536870912\n",
+		"fs/cgroup/pod/cpu.max":    "50000 100000\n",
+	})
+
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	err := diagCmd(&base.Invocation{Stdout: io.Discard, Stderr: &stderr, Dir: dir, Env: []string{base.ProcRootEnv + "=" + procRoot, base.SysRootEnv + "=" + sysRoot}}, []string{"-o", "out.tar.gz", "--duration", "1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "diag: wrote out.tar.gz (15 entries") {
+		t.Fatalf("unexpected summary %q", stderr.String())
+	}
+	files := readDiagBundle(t, filepath.Join(dir, "out.tar.gz"))
+	if files["resolv.conf"] != "nameserver 10.0.0.10\n" {
+		t.Fatalf("expected resolv.conf from the inspected root, got %q", files["resolv.conf"])
+	}
+	limits := files["cgroup-limits.txt"]
+	if !strings.Contains(limits, "memory.max <==\n536870912\n") || !strings.Contains(limits, "cpu.max <==\n50000 100000\n") {
+		t.Fatalf("unexpected cgroup limits:\n%s", limits)
+	}
+
+	// The other command packages are not linked into this test, so every
+	// command step fails and is reported without aborting the bundle.
+	manifest := files["manifest.txt"]
+	for _, want := range []string{
+		"# proc root: " + procRoot,
+		"FILE               STATUS",
+		"ps-ef.txt          failed",
+		"ps: command not available",
+		"resolv.conf        ok",
+		"cgroup-limits.txt  ok",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Fatalf("manifest missing %q:\n%s", want, manifest)
+		}
+	}
+	if _, ok := files["ps-ef.txt"]; ok {
+		t.Fatal("expected no file for a failed step")
+	}
+}
+
+func TestDiagRejectsBadArguments(t *testing.T) {
+	inv := &base.Invocation{Stdout: io.Discard, Stderr: io.Discard, Dir: t.TempDir()}
+	for _, args := range [][]string{{"--duration", "500ms"}, {"--duration", "soon"}, {"extra"}, {"-o", filepath.Join(inv.Dir, "missing", "x.tar.gz")}} {
+		if err := diagCmd(inv, args); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+	if n, err := diagSampleCount("2.5"); err != nil || n != 3 {
+		t.Fatalf("expected plain seconds to round up, got %d (%v)", n, err)
+	}
+}
+
+func TestClassifyDiagResult(t *testing.T) {
+	cases := []struct {
+		err    error
+		stderr string
+		want   string
+	}{
+		{nil, "", diagOK},
+		{nil, "iostat: warning: falling back\n", diagWarning},
+		{os.ErrPermission, "", diagPermission},
+		{nil, "lsof: open /proc/1/fd: permission denied\n", diagPermission},
+		{errors.New("boom"), "", diagFailed},
+	}
+	for _, tc := range cases {
+		if got, _ := classifyDiagResult(tc.err, []byte(tc.stderr)); got != tc.want {
+			t.Errorf("%v / %q: got %s, want %s", tc.err, tc.stderr, got, tc.want)
+		}
+	}
+}
+
+func TestSummarizeLsof(t *testing.T) {
+	csv := "command,pid,user,fd,type,device,size_off,node,name\n" +
+		"nginx,10,root,3u,IPv4,,0,1,*:80\nnginx,10,root,4u,REG,,0,2,/var/log/a\nsh,11,root,0u,CHR,,0,3,/dev/null\n"
+	out, err := summarizeLsof([]byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"open files: 3\n", "CHR               1\n", "10       nginx                       2\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package shell
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// exporterContentType is the Prometheus text exposition format.
+const exporterContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func ExporterCmd(args []string) error {
+	return exporterCmd(base.Stdio(), args)
+}
+
+func exporterCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("exporter", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	listen := fsFlags.String("listen", ":9101", "address to serve /metrics on")
+	names := fsFlags.String("collectors", "", "comma-separated collectors to enable (default all)")
+	top := fsFlags.Int("top", 10, "processes to export by CPU and by RSS")
+	fsFlags.Usage = func() { printExporterUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if fsFlags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fsFlags.Arg(0))
+	}
+	if *top < 0 {
+		return fmt.Errorf("invalid --top %d", *top)
+	}
+	collectors, err := selectMetricsCollectors(*names)
+	if err != nil {
+		return err
+	}
+	handler := newExporterHandler(inv, collectors, base.MetricsOptions{TopProcesses: *top, Roots: inv.Roots()})
+	return runHTTPServer(inv, "exporter", *listen, handler, "", "")
+}
+
+func printExporterUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox exporter [--listen ADDR] [--collectors LIST] [--top N]")
+	fmt.Fprintln(w, "Serve Prometheus metrics at /metrics from the same parsers free, iostat, netstat and top use.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  --listen ADDR       address to listen on (default :9101)")
+	fmt.Fprintln(w, "  --collectors LIST   comma-separated collectors to enable (default all)")
+	fmt.Fprintln(w, "  --top N             export the N busiest and N largest processes (default 10)")
+	fmt.Fprintln(w, "  -h                  show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Collectors:")
+	for _, c := range base.MetricsCollectors() {
+		fmt.Fprintf(w, "  %-8s %s\n", c.Name, c.Help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox exporter")
+	fmt.Fprintln(w, "  gobox exporter --listen 127.0.0.1:9101 --collectors mem,tcp,proc --top 5")
+}
+
+// selectMetricsCollectors resolves a --collectors list; empty means all.
+func selectMetricsCollectors(list string) ([]base.MetricsCollector, error) {
+	all := base.MetricsCollectors()
+	if strings.TrimSpace(list) == "" {
+		return all, nil
+	}
+	byName := make(map[string]base.MetricsCollector, len(all))
+	available := make([]string, 0, len(all))
+	for _, c := range all {
+		byName[c.Name] = c
+		available = append(available, c.Name)
+	}
+	var out []base.MetricsCollector
+	seen := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q (available: %s)", name, strings.Join(available, ", "))
+		}
+		seen[name] = true
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// newExporterHandler serves /metrics. Collectors run in order on every
+// scrape; one that fails is reported through gobox_exporter_collector_success
+// and logged, and its partial output is dropped.
+func newExporterHandler(inv *base.Invocation, collectors []base.MetricsCollector, opts base.MetricsOptions) http.Handler {
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "use GET", http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var out bytes.Buffer
+		success := make([]bool, len(collectors))
+		durations := make([]time.Duration, len(collectors))
+		for i, c := range collectors {
+			var buf bytes.Buffer
+			started := time.Now()
+			err := c.Collect(utils.NewMetricsWriter(&buf), opts)
+			durations[i] = time.Since(started)
+			if err != nil {
+				fmt.Fprintf(inv.Stderr, "exporter: %s: %v\n", c.Name, err)
+				continue
+			}
+			success[i] = true
+			out.Write(buf.Bytes())
+		}
+		mw := utils.NewMetricsWriter(&out)
+		mw.Family("gobox_exporter_collector_success", utils.MetricGauge, "Whether the collector succeeded on this scrape.")
+		for i, c := range collectors {
+			v := 0.0
+			if success[i] {
+				v = 1
+			}
+			mw.Sample("gobox_exporter_collector_success", v, "collector", c.Name)
+		}
+		mw.Family("gobox_exporter_collector_duration_seconds", utils.MetricGauge, "How long the collector took on this scrape.")
+		for i, c := range collectors {
+			mw.Sample("gobox_exporter_collector_duration_seconds", durations[i].Seconds(), "collector", c.Name)
+		}
+		w.Header().Set("Content-Type", exporterContentType)
+		_, _ = w.Write(out.Bytes())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "gobox exporter: metrics are at /metrics")
+	})
+	return mux
+}
@@ -0,0 +1,74 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+func TestExporterHandlerDropsFailedCollector(t *testing.T) {
+	collectors := []base.MetricsCollector{
+		{Name: "good", Collect: func(w *utils.MetricsWriter, opts base.MetricsOptions) error {
+			w.Family("gobox_good", utils.MetricGauge, "Good.")
+			w.Sample("gobox_good", float64(opts.TopProcesses))
+			return w.Err()
+		}},
+		{Name: "bad", Collect: func(w *utils.MetricsWriter, _ base.MetricsOptions) error {
+			w.Family("gobox_bad", utils.MetricGauge, "Bad.")
+			w.Sample("gobox_bad", 1)
+			return errors.New("boom")
+		}},
+	}
+	var stderr bytes.Buffer
+	inv := &base.Invocation{Stdout: io.Discard, Stderr: &stderr}
+	srv := httptest.NewServer(newExporterHandler(inv, collectors, base.MetricsOptions{TopProcesses: 3}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	out := string(body)
+	if ct := resp.Header.Get("Content-Type"); ct != exporterContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	for _, want := range []string{
+		"gobox_good 3\n",
+		`gobox_exporter_collector_success{collector="good"} 1` + "\n",
+		`gobox_exporter_collector_success{collector="bad"} 0` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, "gobox_bad") {
+		t.Fatalf("failed collector output leaked: %q", out)
+	}
+	if !strings.Contains(stderr.String(), "exporter: bad: boom") {
+		t.Fatalf("expected collector error on stderr, got %q", stderr.String())
+	}
+
+	resp, err = http.Post(srv.URL+"/metrics", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /metrics: got %d", resp.StatusCode)
+	}
+}
+
+func TestExporterRejectsUnknownCollector(t *testing.T) {
+	if _, err := selectMetricsCollectors("nosuch"); err == nil || !strings.Contains(err.Error(), "unknown collector") {
+		t.Fatalf("expected unknown collector error, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// remoteExitError carries the exit status of a command run by gobox serve,
+// with its error message when the remote command reported one.
+type remoteExitError struct {
+	code int
+	msg  string
+}
+
+func (e remoteExitError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e remoteExitError) ExitCode() int { return e.code }
+
+// SuppressCLIError keeps main quiet when the remote command already wrote
+// its own diagnostics to the streamed stderr.
+func (e remoteExitError) SuppressCLIError() bool { return e.msg == "" }
+
+func RemoteCmd(args []string) error {
+	return remoteCmd(base.Stdio(), args)
+}
+
+func remoteCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("remote", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	tokenFile := fsFlags.String("token-file", "", "file holding the server's bearer token (default $GOBOX_REMOTE_TOKEN)")
+	fsFlags.Usage = func() { printRemoteUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if fsFlags.NArg() < 2 {
+		printRemoteUsage(inv.Stderr)
+		return fmt.Errorf("missing host or command")
+	}
+	token := strings.TrimSpace(inv.Getenv("GOBOX_REMOTE_TOKEN"))
+	if *tokenFile != "" {
+		var err error
+		if token, err = readServeToken(inv.Path(*tokenFile)); err != nil {
+			return err
+		}
+	}
+	if token == "" {
+		return fmt.Errorf("no token: use --token-file or set GOBOX_REMOTE_TOKEN")
+	}
+
+	host := fsFlags.Arg(0)
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	body, err := json.Marshal(serveRequest{Command: fsFlags.Arg(1), Args: append([]string{}, fsFlags.Args()[2:]...)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(inv.Ctx(), http.MethodPost, strings.TrimSuffix(host, "/")+serveRunPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return copyServeFrames(inv, resp.Body)
+}
+
+// copyServeFrames writes streamed output to inv's stdout and stderr and
+// returns the remote exit status as an error.
+func copyServeFrames(inv *base.Invocation, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var frame serveFrame
+		if err := dec.Decode(&frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("reading response: %w", err)
+		}
+		switch {
+		case frame.Exit != nil:
+			if *frame.Exit == 0 && frame.Error == "" {
+				return nil
+			}
+			return remoteExitError{code: *frame.Exit, msg: frame.Error}
+		case frame.Stream == "stderr":
+			if _, err := inv.Stderr.Write(frame.Data); err != nil {
+				return err
+			}
+		default:
+			if _, err := inv.Stdout.Write(frame.Data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func printRemoteUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox remote [--token-file FILE] HOST[:PORT] COMMAND [ARG]...")
+	fmt.Fprintln(w, "Run a gobox command on a gobox serve server and stream its output.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  --token-file FILE   bearer token for the server (default $GOBOX_REMOTE_TOKEN)")
+	fmt.Fprintln(w, "  -h                  show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "HOST without a scheme uses http://; give https://HOST for a TLS server.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox remote --token-file token node1:7070 ps aux")
+	fmt.Fprintln(w, "  GOBOX_REMOTE_TOKEN=secret gobox remote https://node1:7070 tail -f /var/log/syslog")
+}
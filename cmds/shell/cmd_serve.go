@@ -0,0 +1,404 @@
+package shell
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+// serveRunPath is the endpoint gobox remote posts a serveRequest to.
+const serveRunPath = "/v1/run"
+
+// serveMaxRequest bounds a serveRequest body.
+const serveMaxRequest = 1 << 20
+
+// serveRequest names a registered command and its argument vector; the
+// arguments are never passed through a shell.
+type serveRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// serveFrame is one NDJSON line of a /v1/run response: a chunk of stdout or
+// stderr as it is written, then a final frame with the exit status.
+type serveFrame struct {
+	Stream string `json:"stream,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+	Exit   *int   `json:"exit,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// serveRestricted lists the commands serve refuses unless --allow names
+// them: they signal processes, modify or upload files, serve a directory, or
+// run arbitrary programs. The value reports whether a given argument vector is
+// restricted; sed is only restricted when it edits in place, find when it runs
+// commands or writes, and the others when they name an output or upload file.
+var serveRestricted = map[string]func(args []string) bool{
+	"kill":     always,
+	"truncate": always,
+	"sed":      withOption("i", "efl", "in-place"),
+	"find":     findRunsOrWrites,
+	"curl":     withOption("oOTF", "wmXHdcnt", "output", "remote-name", "upload-file", "form"),
+	"sort":     withOption("o", "kt", "output"),
+	"hex":      withOption("o", "", "o"),
+	"base64":   withOption("o", "", "o"),
+	"rand":     withOption("", "", "out"),
+	"ps":       withOption("", "", "record"),
+	"top":      withOption("", "", "record"),
+	"check":    junitToFile,
+	"tw":       always,
+	"sh":       always,
+	"xargs":    always,
+	"timeout":  always,
+	"init":     always,
+	"watch":    always,
+	"install":  always,
+	"diag":     always,
+	"exporter": always,
+	"ioperf":   always,
+	"serve":    always,
+	"remote":   always,
+}
+
+func always([]string) bool { return true }
+
+// withOption returns a restrictor reporting whether args give one of the
+// short options, alone or in a group such as -ni or -i.bak, or one of the long
+// names as --name, --name=VALUE, -name or -name=VALUE. A group stops at the
+// first of valued, whose value follows attached; nothing after -- counts.
+func withOption(short, valued string, long ...string) func(args []string) bool {
+	return func(args []string) bool {
+		for _, arg := range args {
+			if arg == "--" {
+				return false
+			}
+			if !strings.HasPrefix(arg, "-") || arg == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			for _, l := range long {
+				if name == l {
+					return true
+				}
+			}
+			if strings.HasPrefix(arg, "--") {
+				continue
+			}
+			for _, c := range arg[1:] {
+				if strings.ContainsRune(short, c) {
+					return true
+				}
+				if strings.ContainsRune(valued, c) {
+					break
+				}
+			}
+		}
+		return false
+	}
+}
+
+// junitToFile reports whether check args write the JUnit report to a file;
+// --junit - only sends it to stdout.
+func junitToFile(args []string) bool {
+	for i, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "junit" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		if value != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// findRunsOrWrites reports whether find args contain an action that runs a
+// program, deletes files or writes to a file. Any argument is checked, so a
+// pattern spelled like an action is refused too.
+func findRunsOrWrites(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-exec", "-execdir", "-ok", "-okdir", "-delete":
+			return true
+		}
+		if strings.HasPrefix(arg, "-fprint") {
+			return true
+		}
+	}
+	return false
+}
+
+// servePolicy decides which commands a server runs.
+type servePolicy struct {
+	allow map[string]bool // nil: every command that is not restricted
+	deny  map[string]bool
+}
+
+func parseServeList(list string) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// check returns why cmd may not run with args, or nil.
+func (p servePolicy) check(cmd base.Command, args []string) error {
+	name := cmd.Name()
+	switch {
+	case p.deny[name]:
+		return fmt.Errorf("%s is denied", name)
+	case base.IsConfigAlias(cmd):
+		return fmt.Errorf("%s is a config alias; request the command itself", name)
+	case p.allow != nil && !p.allow[name]:
+		return fmt.Errorf("%s is not in the allow list", name)
+	case p.allow == nil && base.IsPlugin(cmd):
+		return fmt.Errorf("%s is a plugin; allow it with --allow", name)
+	}
+	if restricted, ok := serveRestricted[name]; ok && p.allow == nil && restricted(args) {
+		return fmt.Errorf("%s %s is restricted; allow it with --allow", name, strings.Join(args, " "))
+	}
+	return nil
+}
+
+func ServeCmd(args []string) error {
+	return serveCmd(base.Stdio(), args)
+}
+
+func serveCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	listen := fsFlags.String("listen", ":7070", "address to listen on")
+	tokenFile := fsFlags.String("token-file", "", "file holding the bearer token clients must send")
+	allow := fsFlags.String("allow", "", "comma-separated commands to allow (default all but restricted ones)")
+	deny := fsFlags.String("deny", "", "comma-separated commands to refuse")
+	tlsCert := fsFlags.String("tls-cert", "", "serve HTTPS with this certificate file")
+	tlsKey := fsFlags.String("tls-key", "", "private key file for --tls-cert")
+	fsFlags.Usage = func() { printServeUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if fsFlags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fsFlags.Arg(0))
+	}
+	if *tokenFile == "" {
+		return fmt.Errorf("--token-file is required")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	token, err := readServeToken(inv.Path(*tokenFile))
+	if err != nil {
+		return err
+	}
+	policy := servePolicy{deny: parseServeList(*deny)}
+	if *allow != "" {
+		policy.allow = parseServeList(*allow)
+	}
+
+	certFile, keyFile := *tlsCert, *tlsKey
+	if certFile != "" {
+		certFile, keyFile = inv.Path(certFile), inv.Path(keyFile)
+	}
+	return runHTTPServer(inv, "serve", *listen, newServeHandler(inv, token, policy), certFile, keyFile)
+}
+
+// runHTTPServer serves handler on addr, over TLS when certFile is set, until
+// inv's context is cancelled. name prefixes the startup message.
+func runHTTPServer(inv *base.Invocation, name, addr string, handler http.Handler, certFile, keyFile string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	scheme := "http"
+	if certFile != "" {
+		scheme = "https"
+	}
+	fmt.Fprintf(inv.Stderr, "%s: listening on %s://%s\n", name, scheme, ln.Addr())
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-inv.Ctx().Done():
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+		case <-done:
+		}
+	}()
+	if certFile != "" {
+		err = srv.ServeTLS(ln, certFile, keyFile)
+	} else {
+		err = srv.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func printServeUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox serve --token-file FILE [--listen ADDR] [--allow CMDS] [--deny CMDS]")
+	fmt.Fprintln(w, "Run gobox commands for authenticated gobox remote clients over HTTP.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  --listen ADDR       address to listen on (default :7070)")
+	fmt.Fprintln(w, "  --token-file FILE   bearer token clients must send (required)")
+	fmt.Fprintln(w, "  --allow CMDS        only run these commands, restricted ones included")
+	fmt.Fprintln(w, "  --deny CMDS         never run these commands")
+	fmt.Fprintln(w, "  --tls-cert FILE     serve HTTPS with this certificate")
+	fmt.Fprintln(w, "  --tls-key FILE      private key for --tls-cert")
+	fmt.Fprintln(w, "  -h                  show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Restricted unless allowed: kill, truncate, sed -i, find -exec/-execdir/-ok/")
+	fmt.Fprintln(w, "-okdir/-delete/-fprint, curl -o/-O/-T/-F, sort -o, hex -o, base64 -o,")
+	fmt.Fprintln(w, "rand -out, ps --record, top --record, check --junit FILE (--junit - is")
+	fmt.Fprintln(w, "allowed), tw, sh, xargs, timeout, watch, install, diag, ioperf, exporter,")
+	fmt.Fprintln(w, "serve, remote and plugins.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox serve --token-file /etc/gobox/token")
+	fmt.Fprintln(w, "  gobox serve --listen 127.0.0.1:7070 --token-file token --allow ps,netstat,df")
+}
+
+// readServeToken reads a token file; surrounding whitespace is ignored.
+func readServeToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s: empty token", path)
+	}
+	return token, nil
+}
+
+// newServeHandler serves POST /v1/run. Each request runs in-process with the
+// request's context, so a client that disconnects stops the command.
+func newServeHandler(inv *base.Invocation, token string, policy servePolicy) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(serveRunPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		var req serveRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxRequest)).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		cmd, ok := base.Lookup(req.Command)
+		if !ok {
+			http.Error(w, "unknown command: "+req.Command, http.StatusNotFound)
+			return
+		}
+		if err := policy.check(cmd, req.Args); err != nil {
+			fmt.Fprintf(inv.Stderr, "serve: %s: refused %s: %v\n", r.RemoteAddr, req.Command, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		out := &serveStream{w: w, enc: json.NewEncoder(w)}
+		out.flush()
+		started := time.Now()
+		err := cmd.Run(&base.Invocation{
+			Context:  r.Context(),
+			Stdin:    strings.NewReader(""),
+			Stdout:   out.writer("stdout"),
+			Stderr:   out.writer("stderr"),
+			Env:      inv.Env,
+			Dir:      inv.Dir,
+			ReadOnly: base.ReadOnly(inv),
+		}, req.Args)
+		code := base.ExitStatus(err)
+		final := serveFrame{Exit: &code}
+		if base.ReportError(err) {
+			final.Error = err.Error()
+		}
+		out.frame(final)
+		fmt.Fprintf(inv.Stderr, "serve: %s: %s -> %d (%s)\n", r.RemoteAddr,
+			strings.TrimSpace(req.Command+" "+strings.Join(req.Args, " ")), code, time.Since(started).Round(time.Millisecond))
+	})
+	return mux
+}
+
+// serveStream encodes writes to a command's stdout and stderr as frames,
+// flushing each so long-running commands stream.
+type serveStream struct {
+	mu  sync.Mutex
+	w   http.ResponseWriter
+	enc *json.Encoder
+}
+
+func (s *serveStream) frame(f serveFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(f); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+func (s *serveStream) flush() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *serveStream) writer(stream string) io.Writer {
+	return serveStreamWriter{s: s, stream: stream}
+}
+
+type serveStreamWriter struct {
+	s      *serveStream
+	stream string
+}
+
+func (w serveStreamWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.s.frame(serveFrame{Stream: w.stream, Data: append([]byte(nil), p...)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,234 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gobox/cmds/base"
+	_ "gobox/cmds/fs"
+)
+
+const serveTestToken = "s3cret"
+
+func startServeTest(t *testing.T, policy servePolicy) *httptest.Server {
+	t.Helper()
+	ensureShTestCommands()
+	srv := httptest.NewServer(newServeHandler(&base.Invocation{Stderr: io.Discard}, serveTestToken, policy))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func runRemoteTest(t *testing.T, ctx context.Context, url string, args ...string) (string, string, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	inv := &base.Invocation{
+		Context: ctx,
+		Stdout:  &out,
+		Stderr:  &errOut,
+		Env:     []string{"GOBOX_REMOTE_TOKEN=" + serveTestToken},
+	}
+	err := remoteCmd(inv, append([]string{url}, args...))
+	return out.String(), errOut.String(), err
+}
+
+func TestServeRemoteRoundTrip(t *testing.T) {
+	srv := startServeTest(t, servePolicy{})
+
+	out, _, err := runRemoteTest(t, nil, srv.URL, "zz_sh_args", "a b", "$(rm -rf /)", "*")
+	if err != nil {
+		t.Fatalf("remote: %v", err)
+	}
+	if out != "a b $(rm -rf /) *\n" {
+		t.Fatalf("expected args to arrive verbatim, got %q", out)
+	}
+
+	_, _, err = runRemoteTest(t, nil, srv.URL, "zz_sh_fail")
+	if base.ExitStatus(err) != 2 || !base.ReportError(err) || err.Error() != "boom" {
+		t.Fatalf("expected the remote error with exit status 2, got %v", err)
+	}
+
+	_, _, err = runRemoteTest(t, nil, strings.TrimPrefix(srv.URL, "http://"), "zz_no_such_command")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected an unknown command to fail with 404, got %v", err)
+	}
+}
+
+func TestServeRejectsBadToken(t *testing.T) {
+	srv := startServeTest(t, servePolicy{})
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdout: &out, Stderr: io.Discard, Env: []string{"GOBOX_REMOTE_TOKEN=wrong"}}
+	err := remoteCmd(inv, []string{srv.URL, "zz_sh_args", "x"})
+	if err == nil || !strings.Contains(err.Error(), "401") || out.Len() != 0 {
+		t.Fatalf("expected 401 without running the command, got %v / %q", err, out.String())
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+serveRunPath, strings.NewReader(`{"command":"zz_sh_args"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", serveTestToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a token without the Bearer scheme to be refused, got %s", resp.Status)
+	}
+
+	resp, err = http.Get(srv.URL + serveRunPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be refused, got %s", resp.Status)
+	}
+}
+
+func TestServePolicy(t *testing.T) {
+	noop := func(*base.Invocation, []string) error { return nil }
+	for _, args := range [][]string{
+		{"kill", "-9", "1"},
+		{"truncate", "-s", "0", "/etc/passwd"},
+		{"sed", "-i", "s/a/b/", "f"},
+		{"sed", "-ni.bak", "p", "f"},
+		{"sed", "--in-place", "s/a/b/", "f"},
+		{"sh", "-c", "kill 1"},
+		{"find", ".", "-exec", "rm", "{}", ";"},
+		{"find", ".", "-name", "*.log", "-execdir", "rm", "{}", "+"},
+		{"find", ".", "-ok", "rm", "{}", ";"},
+		{"find", ".", "-delete"},
+		{"find", ".", "-fprint", "/etc/passwd"},
+		{"curl", "-o", "/etc/cron.d/x", "http://example.com"},
+		{"curl", "-sSo/tmp/x", "http://example.com"},
+		{"curl", "-sO", "http://example.com/x"},
+		{"curl", "--output=/tmp/x", "http://example.com"},
+		{"curl", "-T", "/etc/shadow", "http://example.com"},
+		{"curl", "-F", "f=@/etc/shadow", "http://example.com"},
+		{"sort", "-o", "/etc/passwd", "f"},
+		{"sort", "--output=/etc/passwd", "f"},
+		{"hex", "-o", "/tmp/x", "f"},
+		{"base64", "--o=/tmp/x", "f"},
+		{"rand", "-out", "/tmp/x"},
+		{"tw", "-d", "/"},
+		{"ps", "--record", "/etc/passwd"},
+		{"ps", "-e", "--record=/tmp/x"},
+		{"top", "-record", "/tmp/x", "-n", "1"},
+		{"check", "-f", "checks.yaml", "--junit", "/etc/passwd"},
+		{"check", "-f", "checks.yaml", "--junit=/tmp/x"},
+	} {
+		if err := (servePolicy{}).check(base.NewCommand(args[0], "test", noop), args[1:]); err == nil {
+			t.Fatalf("%q: expected to be restricted", args)
+		}
+	}
+	for _, args := range [][]string{
+		{"sed", "-n", "s/i/j/p", "f"},
+		{"sed", "-e", "s/i/j/", "f"},
+		{"sed", "-n", "--", "-i"},
+		{"ps", "-ef"},
+		{"find", ".", "-name", "*.log", "-print"},
+		{"curl", "-sS", "-H", "X-Out: -o", "http://example.com"},
+		{"curl", "-w%{http_code}", "http://example.com"},
+		{"sort", "-t", "o", "-k2", "f"},
+		{"hex", "-C", "f"},
+		{"rand", "-hex", "16"},
+		{"top", "-n", "1"},
+		{"check", "-f", "checks.yaml", "--junit", "-"},
+		{"check", "-f", "checks.yaml", "--junit=-"},
+	} {
+		if err := (servePolicy{}).check(base.NewCommand(args[0], "test", noop), args[1:]); err != nil {
+			t.Fatalf("%q: expected to be allowed, got %v", args, err)
+		}
+	}
+	allowKill := servePolicy{allow: parseServeList("kill, ps")}
+	if err := allowKill.check(base.NewCommand("kill", "test", noop), []string{"-9", "1"}); err != nil {
+		t.Fatalf("expected --allow kill to lift the restriction, got %v", err)
+	}
+	if err := allowKill.check(base.NewCommand("df", "test", noop), nil); err == nil {
+		t.Fatal("expected commands outside --allow to be refused")
+	}
+
+	srv := startServeTest(t, servePolicy{deny: parseServeList("zz_sh_fail")})
+	if _, _, err := runRemoteTest(t, nil, srv.URL, "zz_sh_fail"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected a denied command to fail with 403, got %v", err)
+	}
+	allowed := startServeTest(t, servePolicy{allow: parseServeList("zz_sh_args")})
+	if out, _, err := runRemoteTest(t, nil, allowed.URL, "zz_sh_args", "ok"); err != nil || out != "ok\n" {
+		t.Fatalf("expected an allowed command to run, got %q (%v)", out, err)
+	}
+	if _, _, err := runRemoteTest(t, nil, allowed.URL, "zz_sh_upper"); err == nil || !strings.Contains(err.Error(), "allow list") {
+		t.Fatalf("expected commands outside --allow to be refused, got %v", err)
+	}
+}
+
+func TestServeRefusesFindExec(t *testing.T) {
+	dir := t.TempDir()
+	victim := filepath.Join(dir, "victim")
+	if err := os.WriteFile(victim, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := startServeTest(t, servePolicy{})
+	_, _, err := runRemoteTest(t, nil, srv.URL, "find", dir, "-type", "f", "-exec", "rm", "{}", ";")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "restricted") {
+		t.Fatalf("expected find -exec to be refused with 403, got %v", err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Fatalf("expected the refused command not to run: %v", err)
+	}
+	out, _, err := runRemoteTest(t, nil, srv.URL, "find", dir, "-type", "f")
+	if err != nil || out != victim+"\n" {
+		t.Fatalf("expected plain find to run, got %q (%v)", out, err)
+	}
+}
+
+func TestServeStreamsAndStopsOnDisconnect(t *testing.T) {
+	srv := startServeTest(t, servePolicy{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	inv := &base.Invocation{Context: ctx, Stdout: pw, Stderr: io.Discard, Env: []string{"GOBOX_REMOTE_TOKEN=" + serveTestToken}}
+	done := make(chan error, 1)
+	go func() {
+		err := remoteCmd(inv, []string{srv.URL, "zz_sh_yes"})
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	line := make([]byte, 2)
+	if _, err := io.ReadFull(pr, line); err != nil || string(line) != "y\n" {
+		t.Fatalf("expected streamed output before the command ends, got %q (%v)", line, err)
+	}
+	cancel()
+	go io.Copy(io.Discard, pr)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote did not return after cancellation")
+	}
+	// httptest's Close waits for the handler, so a command that kept running
+	// after the client went away would hang the test here.
+	srv.Close()
+}
+
+func TestServeCmdRequiresToken(t *testing.T) {
+	var errOut bytes.Buffer
+	if err := serveCmd(&base.Invocation{Stderr: &errOut}, []string{"--listen", "127.0.0.1:0"}); err == nil || !strings.Contains(err.Error(), "--token-file") {
+		t.Fatalf("expected --token-file to be required, got %v", err)
+	}
+	empty := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := serveCmd(&base.Invocation{Stderr: &errOut}, []string{"--listen", "127.0.0.1:0", "--token-file", empty}); err == nil || !strings.Contains(err.Error(), "empty token") {
+		t.Fatalf("expected an empty token to be rejected, got %v", err)
+	}
+}
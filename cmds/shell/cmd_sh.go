@@ -0,0 +1,706 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gobox/cmds/base"
+	"gobox/cmds/utils"
+)
+
+type shExitError int
+
+func (e shExitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e shExitError) ExitCode() int { return int(e) }
+
+// SuppressCLIError keeps main quiet: the failing stage already reported its
+// own error, sh only propagates the status like a real shell.
+func (e shExitError) SuppressCLIError() bool { return true }
+
+// shExitRequest unwinds the script when the exit builtin runs.
+type shExitRequest int
+
+func (e shExitRequest) Error() string { return fmt.Sprintf("exit %d", int(e)) }
+
+func ShCmd(args []string) error {
+	return shCmd(base.Stdio(), args)
+}
+
+func shCmd(inv *base.Invocation, args []string) error {
+	fsFlags := flag.NewFlagSet("sh", flag.ContinueOnError)
+	fsFlags.SetOutput(inv.Stderr)
+	command := fsFlags.Bool("c", false, "read commands from the first operand")
+	fsFlags.Usage = func() { printShUsage(inv.Stderr) }
+	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	rest := fsFlags.Args()
+
+	var src string
+	name := "sh"
+	switch {
+	case *command:
+		if len(rest) == 0 {
+			return fmt.Errorf("-c requires an argument")
+		}
+		src = rest[0]
+		rest = rest[1:]
+		if len(rest) > 0 {
+			name, rest = rest[0], rest[1:]
+		}
+	case len(rest) > 0:
+		data, err := os.ReadFile(inv.Path(rest[0]))
+		if err != nil {
+			return err
+		}
+		src = string(data)
+		name, rest = rest[0], rest[1:]
+	default:
+		data, err := io.ReadAll(inv.Stdin)
+		if err != nil {
+			return err
+		}
+		src = string(data)
+	}
+
+	script, err := parseShell(src)
+	if err != nil {
+		return err
+	}
+	r := newShRunner(inv, name, rest)
+	status := r.runScript(script)
+	if status != 0 {
+		return shExitError(status)
+	}
+	return nil
+}
+
+func printShUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gobox sh [-c SCRIPT [NAME [ARG]...]] [FILE [ARG]...]")
+	fmt.Fprintln(w, "Run a small shell-like command language without /bin/sh.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Supports: cmd | cmd, &&, ||, ;, newlines, < > >> 2> 2>&1,")
+	fmt.Fprintln(w, "'single' and \"double\" quotes, \\ escapes, $VAR ${VAR} $? $# $@ $1..$9,")
+	fmt.Fprintln(w, "NAME=value assignments and builtins: cd echo exit export pwd true false :.")
+	fmt.Fprintln(w, "Registered gobox commands run in-process; anything else runs from PATH.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -c        read commands from SCRIPT instead of FILE or stdin")
+	fmt.Fprintln(w, "  -h        show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  gobox sh -c 'ps aux | grep java | sort -k3 -n'")
+	fmt.Fprintln(w, "  gobox sh -c 'netstat -tn > /tmp/conns && wc -l < /tmp/conns'")
+}
+
+type shRunner struct {
+	inv      *base.Invocation
+	stdout   io.Writer
+	stderr   io.Writer
+	dir      string
+	vars     map[string]string
+	exported map[string]bool
+	name     string
+	args     []string
+	status   int
+}
+
+func newShRunner(inv *base.Invocation, name string, args []string) *shRunner {
+	r := &shRunner{
+		inv:      inv,
+		stdout:   inv.Stdout,
+		stderr:   inv.Stderr,
+		dir:      inv.Dir,
+		vars:     make(map[string]string),
+		exported: make(map[string]bool),
+		name:     name,
+		args:     args,
+	}
+	// Pipeline stages write concurrently; serialize writers that are not real
+	// files (buffers in tests, remote streams) so output never interleaves
+	// mid-write. Files stay unwrapped so children inherit them directly.
+	var mu sync.Mutex
+	if _, ok := r.stdout.(*os.File); !ok {
+		r.stdout = &shSyncWriter{mu: &mu, w: r.stdout}
+	}
+	if _, ok := r.stderr.(*os.File); !ok {
+		r.stderr = &shSyncWriter{mu: &mu, w: r.stderr}
+	}
+	for _, kv := range inv.Environ() {
+		if eq := strings.IndexByte(kv, '='); eq > 0 {
+			r.vars[kv[:eq]] = kv[eq+1:]
+			r.exported[kv[:eq]] = true
+		}
+	}
+	return r
+}
+
+type shSyncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (s *shSyncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (r *shRunner) runScript(script shScript) int {
+	for _, chain := range script {
+		if r.inv.Ctx().Err() != nil {
+			return 130
+		}
+		if err := r.runAndOr(chain); err != nil {
+			var exit shExitRequest
+			if errors.As(err, &exit) {
+				return int(exit)
+			}
+			fmt.Fprintf(r.stderr, "%s: %v\n", r.name, err)
+			r.status = 2
+		}
+	}
+	return r.status
+}
+
+func (r *shRunner) runAndOr(chain shAndOr) error {
+	for i, pipeline := range chain.pipelines {
+		if i > 0 {
+			op := chain.ops[i-1]
+			if (op == "&&" && r.status != 0) || (op == "||" && r.status == 0) {
+				continue
+			}
+		}
+		status, err := r.runPipeline(pipeline)
+		if err != nil {
+			return err
+		}
+		r.status = status
+	}
+	return nil
+}
+
+// shStage is one resolved pipeline element with its streams wired up.
+type shStage struct {
+	argv   []string
+	env    []string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// closers release pipe ends and redirect files once the stage is done.
+	closers []io.Closer
+	cancel  context.CancelFunc
+	ctx     context.Context
+}
+
+func (r *shRunner) runPipeline(pipeline shPipeline) (int, error) {
+	// A lone assignment or builtin must affect the shell itself, so it runs
+	// synchronously against r; inside a pipeline it gets a scratch copy, the
+	// way a subshell would.
+	if len(pipeline) == 1 {
+		return r.runStages(pipeline, r)
+	}
+	return r.runStages(pipeline, nil)
+}
+
+func (r *shRunner) runStages(pipeline shPipeline, self *shRunner) (int, error) {
+	stages := make([]*shStage, len(pipeline))
+	var prevReader *io.PipeReader
+	cleanup := func() {
+		for _, st := range stages {
+			if st != nil {
+				st.finish()
+			}
+		}
+	}
+	for i, cmd := range pipeline {
+		ctx, cancel := context.WithCancel(r.inv.Ctx())
+		st := &shStage{stdin: r.inv.Stdin, stdout: r.stdout, stderr: r.stderr, ctx: ctx, cancel: cancel}
+		stages[i] = st
+		if prevReader != nil {
+			st.stdin = prevReader
+			st.closers = append(st.closers, shPipeReadCloser{prevReader})
+			prevReader = nil
+		}
+		if i < len(pipeline)-1 {
+			pr, pw := io.Pipe()
+			st.stdout = &shPipeWriter{pw: pw, cancel: cancel}
+			st.closers = append(st.closers, pw)
+			prevReader = pr
+		}
+		st.argv = r.expandWords(cmd.words)
+		if err := r.applyRedirects(st, cmd.redirects); err != nil {
+			cleanup()
+			fmt.Fprintf(r.stderr, "%s: %v\n", r.name, err)
+			return 1, nil
+		}
+		st.env = r.stageEnv(cmd.assigns, self != nil && len(st.argv) == 0)
+	}
+
+	statuses := make([]int, len(stages))
+	var wg sync.WaitGroup
+	var exitReq error
+	for i, st := range stages {
+		if self != nil {
+			statuses[i], exitReq = r.runStage(st, self)
+			st.finish()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, st *shStage) {
+			defer wg.Done()
+			scratch := r.clone()
+			statuses[i], _ = r.runStage(st, scratch)
+			st.finish()
+		}(i, st)
+	}
+	wg.Wait()
+	if exitReq != nil {
+		return 0, exitReq
+	}
+	return statuses[len(statuses)-1], nil
+}
+
+func (st *shStage) finish() {
+	for _, c := range st.closers {
+		c.Close()
+	}
+	st.cancel()
+}
+
+// shPipeReadCloser closes the read side with EPIPE so an upstream stage that
+// keeps writing after its reader has gone sees a broken pipe, like SIGPIPE.
+type shPipeReadCloser struct {
+	pr *io.PipeReader
+}
+
+func (c shPipeReadCloser) Close() error {
+	return c.pr.CloseWithError(syscall.EPIPE)
+}
+
+// shPipeWriter cancels the producing stage once its reader is gone, so
+// commands that loop on ctx stop instead of writing into the void.
+type shPipeWriter struct {
+	pw     *io.PipeWriter
+	cancel context.CancelFunc
+}
+
+func (w *shPipeWriter) Write(p []byte) (int, error) {
+	n, err := w.pw.Write(p)
+	if err != nil {
+		w.cancel()
+	}
+	return n, err
+}
+
+func (r *shRunner) clone() *shRunner {
+	c := *r
+	c.vars = make(map[string]string, len(r.vars))
+	for k, v := range r.vars {
+		c.vars[k] = v
+	}
+	c.exported = make(map[string]bool, len(r.exported))
+	for k, v := range r.exported {
+		c.exported[k] = v
+	}
+	return &c
+}
+
+// runStage executes one stage and returns its exit status. self is the
+// runner whose state builtins and bare assignments modify.
+func (r *shRunner) runStage(st *shStage, self *shRunner) (int, error) {
+	if len(st.argv) == 0 {
+		return 0, nil
+	}
+	name, args := st.argv[0], st.argv[1:]
+	if builtin, ok := shBuiltins[name]; ok {
+		return builtin(self, st, args)
+	}
+	if name == "gobox" && len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	inv := &base.Invocation{
+		Context:  st.ctx,
+		Stdin:    st.stdin,
+		Stdout:   st.stdout,
+		Stderr:   st.stderr,
+		Env:      st.env,
+		Dir:      r.dir,
+		Config:   r.inv.Config,
+		ReadOnly: base.ReadOnly(r.inv),
+	}
+	if cmd, ok := base.Lookup(name); ok {
+		err := cmd.Run(inv, args)
+		if base.ReportError(err) && !errors.Is(err, syscall.EPIPE) {
+			fmt.Fprintf(st.stderr, "%s: %v\n", name, err)
+		}
+		return base.ExitStatus(err), nil
+	}
+	return runShExternal(inv, name, args), nil
+}
+
+func runShExternal(inv *base.Invocation, name string, args []string) int {
+	if err := base.CheckExternal(inv, name); err != nil {
+		fmt.Fprintf(inv.Stderr, "%s: %v\n", name, err)
+		return base.ExitStatus(err)
+	}
+	path := name
+	if !strings.Contains(name, "/") {
+		found, err := lookPathIn(name, inv.Getenv("PATH"))
+		if err != nil {
+			fmt.Fprintf(inv.Stderr, "%s: command not found\n", name)
+			return 127
+		}
+		path = found
+	} else {
+		path = inv.Path(name)
+	}
+	cmd := inv.Exec(inv.Ctx(), path, args...)
+	cmd.Args[0] = name
+	err := cmd.Run()
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	fmt.Fprintf(inv.Stderr, "%s: %v\n", name, err)
+	return 126
+}
+
+// lookPathIn searches the invocation's PATH rather than the process one, so
+// PATH=... assignments in the script take effect.
+func lookPathIn(name, pathEnv string) (string, error) {
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			dir = "."
+		}
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", exec.ErrNotFound
+}
+
+func (r *shRunner) applyRedirects(st *shStage, redirects []shRedirect) error {
+	for _, rd := range redirects {
+		target, err := r.expandSingle(rd.target)
+		if err != nil {
+			return err
+		}
+		if rd.op == ">&" {
+			var dup io.Writer
+			switch target {
+			case "1":
+				dup = st.stdout
+			case "2":
+				dup = st.stderr
+			default:
+				return fmt.Errorf("%s: bad file descriptor", target)
+			}
+			if rd.fd == 1 {
+				st.stdout = dup
+			} else if rd.fd == 2 {
+				st.stderr = dup
+			}
+			continue
+		}
+		path := target
+		if r.dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(r.dir, path)
+		}
+		if rd.op != "<" && path != os.DevNull {
+			if err := base.CheckWritable(r.inv, rd.op+" "+target); err != nil {
+				return err
+			}
+		}
+		var f *os.File
+		switch rd.op {
+		case "<":
+			f, err = os.Open(path)
+		case ">":
+			f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+		case ">>":
+			f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+		}
+		if err != nil {
+			return err
+		}
+		st.closers = append(st.closers, f)
+		switch rd.fd {
+		case 0:
+			st.stdin = f
+		case 1:
+			st.stdout = f
+		case 2:
+			st.stderr = f
+		}
+	}
+	return nil
+}
+
+// stageEnv builds the environment for a stage from exported variables and
+// the command's NAME=value prefixes. With persist set (a bare assignment
+// such as `FOO=bar`), the values are stored as shell variables instead.
+func (r *shRunner) stageEnv(assigns []shAssign, persist bool) []string {
+	overrides := make(map[string]string, len(assigns))
+	for _, a := range assigns {
+		value := r.expandJoined(a.value)
+		if persist {
+			r.vars[a.name] = value
+			continue
+		}
+		overrides[a.name] = value
+	}
+	env := make([]string, 0, len(r.exported)+len(overrides))
+	for name := range r.exported {
+		if _, ok := overrides[name]; ok {
+			continue
+		}
+		if v, ok := r.vars[name]; ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	for name, v := range overrides {
+		env = append(env, name+"="+v)
+	}
+	return env
+}
+
+func (r *shRunner) lookupParam(name string) string {
+	switch name {
+	case "?":
+		return strconv.Itoa(r.status)
+	case "#":
+		return strconv.Itoa(len(r.args))
+	case "@", "*":
+		return strings.Join(r.args, " ")
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	case "0":
+		return r.name
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if n >= 1 && n <= len(r.args) {
+			return r.args[n-1]
+		}
+		return ""
+	}
+	return r.vars[name]
+}
+
+// expandWords expands each word into zero or more fields: unquoted
+// expansions are split on whitespace, quoted text never is, and "$@" gives
+// one field per positional parameter. There is no pathname globbing.
+func (r *shRunner) expandWords(words []shWord) []string {
+	var fields []string
+	for _, w := range words {
+		fields = append(fields, r.expandFields(w)...)
+	}
+	return fields
+}
+
+func (r *shRunner) expandFields(w shWord) []string {
+	var fields []string
+	var cur strings.Builder
+	have := false
+	flush := func() {
+		if have {
+			fields = append(fields, cur.String())
+		}
+		cur.Reset()
+		have = false
+	}
+	for i, part := range w {
+		if part.param == "" {
+			cur.WriteString(part.text)
+			// The empty part opening "$@" must not keep a field alive when
+			// there are no positional parameters.
+			openQuotedAt := part.text == "" && i+1 < len(w) && w[i+1].quoted && w[i+1].param == "@"
+			have = have || part.text != "" || (part.quoted && !openQuotedAt)
+			continue
+		}
+		if part.quoted && part.param == "@" {
+			for j, arg := range r.args {
+				if j > 0 {
+					flush()
+				}
+				cur.WriteString(arg)
+				have = true
+			}
+			continue
+		}
+		value := r.lookupParam(part.param)
+		if part.quoted {
+			cur.WriteString(value)
+			have = true
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		if strings.TrimLeft(value, " \t\n") != value {
+			flush()
+		}
+		for i, piece := range strings.Fields(value) {
+			if i > 0 {
+				flush()
+			}
+			cur.WriteString(piece)
+			have = true
+		}
+		if strings.TrimRight(value, " \t\n") != value {
+			flush()
+		}
+	}
+	flush()
+	return fields
+}
+
+func (r *shRunner) expandJoined(w shWord) string {
+	var b strings.Builder
+	for _, part := range w {
+		if part.param != "" {
+			b.WriteString(r.lookupParam(part.param))
+			continue
+		}
+		b.WriteString(part.text)
+	}
+	return b.String()
+}
+
+func (r *shRunner) expandSingle(w shWord) (string, error) {
+	fields := r.expandFields(w)
+	if len(fields) != 1 {
+		return "", fmt.Errorf("ambiguous redirect")
+	}
+	return fields[0], nil
+}
+
+type shBuiltin func(r *shRunner, st *shStage, args []string) (int, error)
+
+var shBuiltins map[string]shBuiltin
+
+func init() {
+	shBuiltins = map[string]shBuiltin{
+		":":      func(*shRunner, *shStage, []string) (int, error) { return 0, nil },
+		"true":   func(*shRunner, *shStage, []string) (int, error) { return 0, nil },
+		"false":  func(*shRunner, *shStage, []string) (int, error) { return 1, nil },
+		"cd":     shCd,
+		"echo":   shEcho,
+		"exit":   shExit,
+		"export": shExport,
+		"pwd":    shPwd,
+	}
+}
+
+func shCd(r *shRunner, st *shStage, args []string) (int, error) {
+	target := r.vars["HOME"]
+	if len(args) > 0 {
+		target = args[0]
+	}
+	if target == "" {
+		fmt.Fprintln(st.stderr, "cd: HOME not set")
+		return 1, nil
+	}
+	if !filepath.IsAbs(target) {
+		base := r.dir
+		if base == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				fmt.Fprintf(st.stderr, "cd: %v\n", err)
+				return 1, nil
+			}
+			base = wd
+		}
+		target = filepath.Join(base, target)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		fmt.Fprintf(st.stderr, "cd: %s: no such file or directory\n", args[0])
+		return 1, nil
+	}
+	if !info.IsDir() {
+		fmt.Fprintf(st.stderr, "cd: %s: not a directory\n", args[0])
+		return 1, nil
+	}
+	r.dir = filepath.Clean(target)
+	r.vars["PWD"] = r.dir
+	return 0, nil
+}
+
+func shEcho(r *shRunner, st *shStage, args []string) (int, error) {
+	newline := true
+	if len(args) > 0 && args[0] == "-n" {
+		newline = false
+		args = args[1:]
+	}
+	out := strings.Join(args, " ")
+	if newline {
+		out += "\n"
+	}
+	if _, err := io.WriteString(st.stdout, out); err != nil {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func shExit(r *shRunner, st *shStage, args []string) (int, error) {
+	code := r.status
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(st.stderr, "exit: %s: numeric argument required\n", args[0])
+			n = 2
+		}
+		code = n & 0xff
+	}
+	return code, shExitRequest(code)
+}
+
+func shExport(r *shRunner, st *shStage, args []string) (int, error) {
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isShellName(name) {
+			fmt.Fprintf(st.stderr, "export: %s: not a valid identifier\n", arg)
+			return 1, nil
+		}
+		if hasValue {
+			r.vars[name] = value
+		}
+		r.exported[name] = true
+	}
+	return 0, nil
+}
+
+func shPwd(r *shRunner, st *shStage, args []string) (int, error) {
+	dir := r.dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(st.stderr, "pwd: %v\n", err)
+			return 1, nil
+		}
+		dir = wd
+	}
+	fmt.Fprintln(st.stdout, dir)
+	return 0, nil
+}
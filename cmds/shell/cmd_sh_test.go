@@ -0,0 +1,249 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gobox/cmds/base"
+)
+
+var registerShTestCommands sync.Once
+
+// ensureShTestCommands registers small in-process commands so pipelines can
+// be exercised without depending on the other command packages.
+func ensureShTestCommands() {
+	registerShTestCommands.Do(func() {
+		base.Register(base.NewCommand("zz_sh_upper", "test", func(inv *base.Invocation, args []string) error {
+			data, err := io.ReadAll(inv.Stdin)
+			if err != nil {
+				return err
+			}
+			_, err = inv.Stdout.Write(bytes.ToUpper(data))
+			return err
+		}))
+		base.Register(base.NewCommand("zz_sh_yes", "test", func(inv *base.Invocation, args []string) error {
+			for {
+				if _, err := fmt.Fprintln(inv.Stdout, "y"); err != nil {
+					return err
+				}
+			}
+		}))
+		base.Register(base.NewCommand("zz_sh_first", "test", func(inv *base.Invocation, args []string) error {
+			line, err := bufio.NewReader(inv.Stdin).ReadString('\n')
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(inv.Stdout, line)
+			return err
+		}))
+		base.Register(base.NewCommand("zz_sh_fail", "test", func(inv *base.Invocation, args []string) error {
+			return fmt.Errorf("boom")
+		}))
+		base.Register(base.NewCommand("zz_sh_args", "test", func(inv *base.Invocation, args []string) error {
+			_, err := fmt.Fprintln(inv.Stdout, strings.Join(args, " "))
+			return err
+		}))
+		base.Register(base.NewCommand("zz_sh_mutating", "test", func(inv *base.Invocation, args []string) error {
+			return nil
+		}, base.WithMutating()))
+	})
+}
+
+func runShTest(t *testing.T, inv *base.Invocation, args ...string) (string, string, error) {
+	t.Helper()
+	ensureShTestCommands()
+	var out, errOut bytes.Buffer
+	if inv == nil {
+		inv = &base.Invocation{}
+	}
+	inv.Stdout = &out
+	inv.Stderr = &errOut
+	err := base.NewCommand("sh", "", shCmd).Run(inv, args)
+	return out.String(), errOut.String(), err
+}
+
+func TestShCmdPipesInProcessCommands(t *testing.T) {
+	out, stderr, err := runShTest(t, nil, "-c", "echo hello world | zz_sh_upper")
+	if err != nil {
+		t.Fatalf("expected no error, got %v (%s)", err, stderr)
+	}
+	if out != "HELLO WORLD\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestShCmdStopsProducerWhenReaderExits(t *testing.T) {
+	done := make(chan struct{})
+	var out string
+	var err error
+	go func() {
+		out, _, err = runShTest(t, nil, "-c", "zz_sh_yes | zz_sh_first")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not finish after its reader exited")
+	}
+	if err != nil || out != "y\n" {
+		t.Fatalf("expected single line, got %q err=%v", out, err)
+	}
+}
+
+func TestShCmdAndOrAndStatus(t *testing.T) {
+	out, stderr, err := runShTest(t, nil, "-c", "false && echo no; false || echo yes $?; zz_sh_fail; echo $?")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out != "yes 1\n2\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if !strings.Contains(stderr, "zz_sh_fail: boom") {
+		t.Fatalf("expected stage error on stderr, got %q", stderr)
+	}
+}
+
+func TestShCmdExitStatusPropagates(t *testing.T) {
+	_, _, err := runShTest(t, nil, "-c", "echo before; exit 3; echo after")
+	if got := base.ExitStatus(err); got != 3 {
+		t.Fatalf("expected exit status 3, got %d (%v)", got, err)
+	}
+	if base.ReportError(err) {
+		t.Fatalf("expected sh exit status not to be reported again")
+	}
+}
+
+func TestShCmdRedirectsResolveAgainstDir(t *testing.T) {
+	dir := t.TempDir()
+	inv := &base.Invocation{Dir: dir}
+	out, stderr, err := runShTest(t, inv, "-c", "echo one > f; echo two >> f; zz_sh_upper < f; zz_sh_fail 2> err.log")
+	if err == nil {
+		t.Fatalf("expected last command's failure to propagate")
+	}
+	if out != "ONE\nTWO\n" {
+		t.Fatalf("unexpected output %q (stderr %q)", out, stderr)
+	}
+	data, readErr := os.ReadFile(filepath.Join(dir, "err.log"))
+	if readErr != nil || !strings.Contains(string(data), "boom") {
+		t.Fatalf("expected stderr redirected to err.log, got %q %v", data, readErr)
+	}
+}
+
+func TestShCmdStderrToStdout(t *testing.T) {
+	out, stderr, _ := runShTest(t, nil, "-c", "zz_sh_fail 2>&1 | zz_sh_upper")
+	if !strings.Contains(out, "ZZ_SH_FAIL: BOOM") || stderr != "" {
+		t.Fatalf("expected stderr to flow through the pipe, got out=%q err=%q", out, stderr)
+	}
+}
+
+func TestShCmdVariablesAndArgs(t *testing.T) {
+	inv := &base.Invocation{Env: []string{"GREETING=hi there"}}
+	out, _, err := runShTest(t, inv, "-c", `X=1; echo $GREETING "$X" $1 $#; X=2 env_check=1; echo $X`, "name", "first")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out != "hi there 1 first 1\n2\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestShCmdBuiltinInPipelineDoesNotChangeShell(t *testing.T) {
+	dir := t.TempDir()
+	out, _, err := runShTest(t, &base.Invocation{Dir: dir}, "-c", "cd / | zz_sh_upper; pwd")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.TrimSpace(out) != dir {
+		t.Fatalf("expected cd inside a pipeline to be scoped, got %q", out)
+	}
+}
+
+func TestShCmdRunsExternalCommands(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	inv := &base.Invocation{Env: []string{"PATH=" + os.Getenv("PATH")}}
+	out, stderr, err := runShTest(t, inv, "-c", "echo external | cat | zz_sh_upper; definitely-not-a-command-xyz; echo $?")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out != "EXTERNAL\n127\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if !strings.Contains(stderr, "definitely-not-a-command-xyz: command not found") {
+		t.Fatalf("expected command not found message, got %q", stderr)
+	}
+}
+
+func TestShCmdReadsScriptFromStdin(t *testing.T) {
+	inv := &base.Invocation{Stdin: strings.NewReader("echo a\necho b | zz_sh_upper\n")}
+	out, _, err := runShTest(t, inv)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out != "a\nB\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestShCmdStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := runShTest(t, &base.Invocation{Context: ctx}, "-c", "echo unreachable")
+	if got := base.ExitStatus(err); got != 130 {
+		t.Fatalf("expected status 130 for a cancelled script, got %d", got)
+	}
+}
+
+func TestShCmdSyntaxError(t *testing.T) {
+	_, _, err := runShTest(t, nil, "-c", "echo 'open")
+	if err == nil || !strings.Contains(err.Error(), "unterminated") {
+		t.Fatalf("expected syntax error, got %v", err)
+	}
+}
+
+func TestShCmdAppliesConfigDefaultsToStages(t *testing.T) {
+	cfg := &base.Config{Defaults: map[string]base.ConfigEntry{"zz_sh_args": {Args: []string{"-x"}}}}
+	out, stderr, err := runShTest(t, &base.Invocation{Config: cfg}, "-c", "zz_sh_args a | zz_sh_upper")
+	if err != nil {
+		t.Fatalf("expected no error, got %v (%s)", err, stderr)
+	}
+	if out != "-X A\n" {
+		t.Fatalf("expected the configured defaults before the arguments, got %q", out)
+	}
+}
+
+func TestShCmdRefusesExternalCommandsInReadOnly(t *testing.T) {
+	inv := &base.Invocation{Env: []string{base.ReadOnlyEnv + "=1", "PATH=" + os.Getenv("PATH")}}
+	out, stderr, err := runShTest(t, inv, "-c", "echo in-process | zz_sh_upper; touch x; echo $?")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out != "IN-PROCESS\n77\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if !strings.Contains(stderr, "touch: external command touch is disabled in read-only mode") {
+		t.Fatalf("expected the external command to be refused, got %q", stderr)
+	}
+}
+
+func TestShCmdStagesCannotClearReadOnly(t *testing.T) {
+	inv := &base.Invocation{Env: []string{base.ReadOnlyEnv + "=1"}}
+	out, _, err := runShTest(t, inv, "-c", base.ReadOnlyEnv+"=0 zz_sh_mutating; echo $?; export "+base.ReadOnlyEnv+"=0; zz_sh_mutating; echo $?")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out != "77\n77\n" {
+		t.Fatalf("expected both stages to stay read-only, got %q", out)
+	}
+}
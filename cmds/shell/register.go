@@ -0,0 +1,12 @@
+package shell
+
+import "gobox/cmds/base"
+
+func init() {
+	base.Register(base.NewCommand("sh", "Run pipelines of gobox and external commands", shCmd))
+	base.Register(base.NewCommand("diag", "Collect a one-shot incident bundle", diagCmd))
+	base.Register(base.NewCommand("serve", "Run gobox commands for remote clients over HTTP", serveCmd))
+	base.Register(base.NewCommand("remote", "Run a gobox command on a gobox serve server", remoteCmd))
+	base.Register(base.NewCommand("exporter", "Serve Prometheus metrics from the gobox collectors", exporterCmd))
+	base.Register(base.NewCommand("check", "Run declarative health checks from a file", checkCmd, base.WithTabularOutput()))
+}
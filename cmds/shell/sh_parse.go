@@ -0,0 +1,483 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shPart is one piece of a word: literal text, or a parameter to expand at
+// run time. quoted records whether it came from inside quotes, which decides
+// whether an expansion is subject to field splitting.
+type shPart struct {
+	text   string
+	param  string
+	quoted bool
+}
+
+type shWord []shPart
+
+type shRedirect struct {
+	fd     int    // descriptor being redirected: 0, 1 or 2
+	op     string // "<", ">", ">>" or ">&"
+	target shWord // file name; for ">&" the descriptor to duplicate
+}
+
+type shAssign struct {
+	name  string
+	value shWord
+}
+
+type shCommand struct {
+	assigns   []shAssign
+	words     []shWord
+	redirects []shRedirect
+}
+
+type shPipeline []shCommand
+
+// shAndOr is a chain of pipelines joined by && / ||; ops[i] sits between
+// pipelines[i] and pipelines[i+1].
+type shAndOr struct {
+	pipelines []shPipeline
+	ops       []string
+}
+
+type shScript []shAndOr
+
+const (
+	shTokWord = iota
+	shTokOp
+	shTokRedirect
+	shTokNewline
+	shTokEOF
+)
+
+type shToken struct {
+	kind  int
+	op    string
+	fd    int
+	word  shWord
+	atPos int
+}
+
+type shLexer struct {
+	src  string
+	pos  int
+	toks []shToken
+}
+
+func lexShell(src string) ([]shToken, error) {
+	lx := &shLexer{src: src}
+	for {
+		tok, err := lx.next()
+		if err != nil {
+			return nil, err
+		}
+		lx.toks = append(lx.toks, tok)
+		if tok.kind == shTokEOF {
+			return lx.toks, nil
+		}
+	}
+}
+
+func (lx *shLexer) next() (shToken, error) {
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		if c == ' ' || c == '\t' || c == '\r' {
+			lx.pos++
+			continue
+		}
+		if c == '\\' && lx.pos+1 < len(lx.src) && lx.src[lx.pos+1] == '\n' {
+			lx.pos += 2
+			continue
+		}
+		if c == '#' {
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' {
+				lx.pos++
+			}
+			continue
+		}
+		break
+	}
+	start := lx.pos
+	if lx.pos >= len(lx.src) {
+		return shToken{kind: shTokEOF, atPos: start}, nil
+	}
+	rest := lx.src[lx.pos:]
+	switch {
+	case rest[0] == '\n':
+		lx.pos++
+		return shToken{kind: shTokNewline, atPos: start}, nil
+	case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"):
+		lx.pos += 2
+		return shToken{kind: shTokOp, op: rest[:2], atPos: start}, nil
+	case rest[0] == '|' || rest[0] == ';':
+		lx.pos++
+		return shToken{kind: shTokOp, op: rest[:1], atPos: start}, nil
+	case rest[0] == '&':
+		return shToken{}, fmt.Errorf("syntax error: background jobs (&) are not supported")
+	case rest[0] == '(' || rest[0] == ')':
+		return shToken{}, fmt.Errorf("syntax error: subshells are not supported")
+	}
+	if tok, ok := lx.redirect(); ok {
+		return tok, nil
+	}
+	word, err := lx.word()
+	if err != nil {
+		return shToken{}, err
+	}
+	return shToken{kind: shTokWord, word: word, atPos: start}, nil
+}
+
+// redirect recognizes [n]< [n]> [n]>> and [n]>&m, where n defaults to 0 for
+// input and 1 for output.
+func (lx *shLexer) redirect() (shToken, bool) {
+	rest := lx.src[lx.pos:]
+	fd := -1
+	i := 0
+	if len(rest) > 1 && rest[0] >= '0' && rest[0] <= '2' && (rest[1] == '<' || rest[1] == '>') {
+		fd = int(rest[0] - '0')
+		i = 1
+	}
+	if i >= len(rest) || (rest[i] != '<' && rest[i] != '>') {
+		return shToken{}, false
+	}
+	var op string
+	switch {
+	case rest[i] == '<':
+		op = "<"
+		if fd < 0 {
+			fd = 0
+		}
+	case strings.HasPrefix(rest[i:], ">>"):
+		op = ">>"
+	case strings.HasPrefix(rest[i:], ">&"):
+		op = ">&"
+	default:
+		op = ">"
+	}
+	if fd < 0 {
+		fd = 1
+	}
+	lx.pos += i + len(op)
+	return shToken{kind: shTokRedirect, op: op, fd: fd, atPos: lx.pos}, true
+}
+
+func (lx *shLexer) word() (shWord, error) {
+	var parts shWord
+	var lit strings.Builder
+	flush := func(quoted bool) {
+		if lit.Len() > 0 || quoted {
+			parts = append(parts, shPart{text: lit.String(), quoted: quoted})
+			lit.Reset()
+		}
+	}
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		if strings.IndexByte(" \t\r\n|&;<>()", c) >= 0 {
+			break
+		}
+		switch c {
+		case '\\':
+			// An escaped character is quoted: it never splits and never
+			// starts an assignment.
+			lx.pos++
+			if lx.pos < len(lx.src) {
+				flush(false)
+				lit.WriteByte(lx.src[lx.pos])
+				lx.pos++
+				flush(true)
+			}
+		case '\'':
+			flush(false)
+			end := strings.IndexByte(lx.src[lx.pos+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("syntax error: unterminated single quote")
+			}
+			lit.WriteString(lx.src[lx.pos+1 : lx.pos+1+end])
+			lx.pos += end + 2
+			flush(true)
+		case '"':
+			flush(false)
+			lx.pos++
+			quotedParts, err := lx.doubleQuoted()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, quotedParts...)
+		case '$':
+			param, err := lx.param()
+			if err != nil {
+				return nil, err
+			}
+			if param == "" {
+				lit.WriteByte('$')
+				continue
+			}
+			flush(false)
+			parts = append(parts, shPart{param: param})
+		case '`':
+			return nil, fmt.Errorf("syntax error: command substitution is not supported")
+		default:
+			lit.WriteByte(c)
+			lx.pos++
+		}
+	}
+	flush(false)
+	return parts, nil
+}
+
+// doubleQuoted scans up to the closing quote. Inside double quotes only $ and
+// the backslash escapes \$ \" \\ \` keep their special meaning.
+func (lx *shLexer) doubleQuoted() (shWord, error) {
+	parts := shWord{{quoted: true}}
+	var lit strings.Builder
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		switch c {
+		case '"':
+			lx.pos++
+			if lit.Len() > 0 {
+				parts = append(parts, shPart{text: lit.String(), quoted: true})
+			}
+			return parts, nil
+		case '\\':
+			if lx.pos+1 < len(lx.src) && strings.IndexByte("$\"\\`", lx.src[lx.pos+1]) >= 0 {
+				lit.WriteByte(lx.src[lx.pos+1])
+				lx.pos += 2
+				continue
+			}
+			lit.WriteByte(c)
+			lx.pos++
+		case '$':
+			param, err := lx.param()
+			if err != nil {
+				return nil, err
+			}
+			if param == "" {
+				lit.WriteByte('$')
+				continue
+			}
+			if lit.Len() > 0 {
+				parts = append(parts, shPart{text: lit.String(), quoted: true})
+				lit.Reset()
+			}
+			parts = append(parts, shPart{param: param, quoted: true})
+		case '`':
+			return nil, fmt.Errorf("syntax error: command substitution is not supported")
+		default:
+			lit.WriteByte(c)
+			lx.pos++
+		}
+	}
+	return nil, fmt.Errorf("syntax error: unterminated double quote")
+}
+
+// param consumes a $NAME, ${NAME} or special parameter ($?, $#, $@, $*, $$,
+// $0-$9) and returns its name. A lone $ yields "" and is kept literally.
+func (lx *shLexer) param() (string, error) {
+	lx.pos++ // '$'
+	if lx.pos >= len(lx.src) {
+		return "", nil
+	}
+	c := lx.src[lx.pos]
+	switch {
+	case c == '{':
+		end := strings.IndexByte(lx.src[lx.pos:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("syntax error: missing '}'")
+		}
+		name := lx.src[lx.pos+1 : lx.pos+end]
+		if !isShellName(name) && !isSpecialParam(name) {
+			return "", fmt.Errorf("bad substitution: ${%s}", name)
+		}
+		lx.pos += end + 1
+		return name, nil
+	case c == '(':
+		return "", fmt.Errorf("syntax error: command substitution is not supported")
+	case strings.IndexByte("?#@*$", c) >= 0 || (c >= '0' && c <= '9'):
+		lx.pos++
+		return string(c), nil
+	case isShellNameStart(c):
+		start := lx.pos
+		for lx.pos < len(lx.src) && isShellNameChar(lx.src[lx.pos]) {
+			lx.pos++
+		}
+		return lx.src[start:lx.pos], nil
+	}
+	return "", nil
+}
+
+func isShellNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isShellNameChar(c byte) bool {
+	return isShellNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func isShellName(s string) bool {
+	if s == "" || !isShellNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isShellNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSpecialParam(s string) bool {
+	return len(s) == 1 && strings.IndexByte("?#@*$0123456789", s[0]) >= 0
+}
+
+type shParser struct {
+	toks []shToken
+	pos  int
+}
+
+// parseShell parses src into a list of and-or chains separated by ; or
+// newlines.
+func parseShell(src string) (shScript, error) {
+	toks, err := lexShell(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &shParser{toks: toks}
+	var script shScript
+	for {
+		p.skipSeparators()
+		if p.peek().kind == shTokEOF {
+			return script, nil
+		}
+		andOr, err := p.andOr()
+		if err != nil {
+			return nil, err
+		}
+		script = append(script, andOr)
+		tok := p.peek()
+		switch {
+		case tok.kind == shTokEOF, tok.kind == shTokNewline, tok.kind == shTokOp && tok.op == ";":
+		default:
+			return nil, p.unexpected(tok)
+		}
+	}
+}
+
+func (p *shParser) peek() shToken {
+	return p.toks[p.pos]
+}
+
+func (p *shParser) skipSeparators() {
+	for {
+		tok := p.peek()
+		if tok.kind == shTokNewline || (tok.kind == shTokOp && tok.op == ";") {
+			p.pos++
+			continue
+		}
+		return
+	}
+}
+
+func (p *shParser) skipNewlines() {
+	for p.peek().kind == shTokNewline {
+		p.pos++
+	}
+}
+
+func (p *shParser) andOr() (shAndOr, error) {
+	var chain shAndOr
+	for {
+		pipeline, err := p.pipeline()
+		if err != nil {
+			return chain, err
+		}
+		chain.pipelines = append(chain.pipelines, pipeline)
+		tok := p.peek()
+		if tok.kind != shTokOp || (tok.op != "&&" && tok.op != "||") {
+			return chain, nil
+		}
+		chain.ops = append(chain.ops, tok.op)
+		p.pos++
+		p.skipNewlines()
+	}
+}
+
+func (p *shParser) pipeline() (shPipeline, error) {
+	var pipeline shPipeline
+	for {
+		cmd, err := p.command()
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, cmd)
+		tok := p.peek()
+		if tok.kind != shTokOp || tok.op != "|" {
+			return pipeline, nil
+		}
+		p.pos++
+		p.skipNewlines()
+	}
+}
+
+func (p *shParser) command() (shCommand, error) {
+	var cmd shCommand
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case shTokWord:
+			p.pos++
+			if len(cmd.words) == 0 {
+				if assign, ok := splitAssignment(tok.word); ok {
+					cmd.assigns = append(cmd.assigns, assign)
+					continue
+				}
+			}
+			cmd.words = append(cmd.words, tok.word)
+		case shTokRedirect:
+			p.pos++
+			target := p.peek()
+			if target.kind != shTokWord {
+				return cmd, p.unexpected(target)
+			}
+			p.pos++
+			cmd.redirects = append(cmd.redirects, shRedirect{fd: tok.fd, op: tok.op, target: target.word})
+		default:
+			if len(cmd.words) == 0 && len(cmd.assigns) == 0 && len(cmd.redirects) == 0 {
+				return cmd, p.unexpected(tok)
+			}
+			return cmd, nil
+		}
+	}
+}
+
+func (p *shParser) unexpected(tok shToken) error {
+	switch tok.kind {
+	case shTokEOF:
+		return fmt.Errorf("syntax error: unexpected end of input")
+	case shTokNewline:
+		return fmt.Errorf("syntax error: unexpected newline")
+	case shTokOp, shTokRedirect:
+		return fmt.Errorf("syntax error near unexpected token %q", tok.op)
+	}
+	return fmt.Errorf("syntax error at offset %d", tok.atPos)
+}
+
+// splitAssignment recognizes NAME=value, where NAME and the = must be
+// unquoted, unescaped literal text at the start of the word.
+func splitAssignment(word shWord) (shAssign, bool) {
+	if len(word) == 0 || word[0].quoted || word[0].param != "" {
+		return shAssign{}, false
+	}
+	eq := strings.IndexByte(word[0].text, '=')
+	if eq <= 0 || !isShellName(word[0].text[:eq]) {
+		return shAssign{}, false
+	}
+	value := shWord{}
+	if rest := word[0].text[eq+1:]; rest != "" {
+		value = append(value, shPart{text: rest})
+	}
+	value = append(value, word[1:]...)
+	return shAssign{name: word[0].text[:eq], value: value}, true
+}
@@ -0,0 +1,168 @@
+package shell
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseShellSplitsListsAndPipelines(t *testing.T) {
+	script, err := parseShell("ps aux | grep java | sort -k3 -n; a && b || c\nd")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(script) != 3 {
+		t.Fatalf("expected 3 and-or lists, got %d", len(script))
+	}
+	if got := len(script[0].pipelines[0]); got != 3 {
+		t.Fatalf("expected 3 pipeline stages, got %d", got)
+	}
+	if !reflect.DeepEqual(script[1].ops, []string{"&&", "||"}) {
+		t.Fatalf("unexpected operators %v", script[1].ops)
+	}
+}
+
+func TestParseShellRedirects(t *testing.T) {
+	script, err := parseShell("cmd <in >out 2>>log 2>&1")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	cmd := script[0].pipelines[0][0]
+	if len(cmd.words) != 1 {
+		t.Fatalf("expected redirect targets not to be arguments, got %d words", len(cmd.words))
+	}
+	want := []struct {
+		fd     int
+		op     string
+		target string
+	}{{0, "<", "in"}, {1, ">", "out"}, {2, ">>", "log"}, {2, ">&", "1"}}
+	if len(cmd.redirects) != len(want) {
+		t.Fatalf("expected %d redirects, got %d", len(want), len(cmd.redirects))
+	}
+	for i, w := range want {
+		rd := cmd.redirects[i]
+		if rd.fd != w.fd || rd.op != w.op || rd.target[0].text != w.target {
+			t.Fatalf("redirect %d: got fd=%d op=%q target=%q", i, rd.fd, rd.op, rd.target[0].text)
+		}
+	}
+}
+
+func TestParseShellAssignmentsOnlyBeforeCommand(t *testing.T) {
+	script, err := parseShell("A=1 B='x y' env C=2")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	cmd := script[0].pipelines[0][0]
+	if len(cmd.assigns) != 2 || cmd.assigns[0].name != "A" || cmd.assigns[1].name != "B" {
+		t.Fatalf("unexpected assignments %+v", cmd.assigns)
+	}
+	if len(cmd.words) != 2 {
+		t.Fatalf("expected C=2 to stay an argument, got %d words", len(cmd.words))
+	}
+}
+
+func TestParseShellEscapedEqualsIsNotAssignment(t *testing.T) {
+	r := &shRunner{vars: map[string]string{}}
+	for _, src := range []string{`a\=b`, `a'='b`, `a"="b`, `\a=b`} {
+		script, err := parseShell(src + " x")
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", src, err)
+		}
+		cmd := script[0].pipelines[0][0]
+		if len(cmd.assigns) != 0 || len(cmd.words) != 2 {
+			t.Fatalf("%s: expected a command word, got assigns %+v", src, cmd.assigns)
+		}
+		if got := r.expandJoined(cmd.words[0]); got != "a=b" {
+			t.Fatalf("%s: expected the word a=b, got %q", src, got)
+		}
+	}
+	script, err := parseShell(`A=x\ y`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	cmd := script[0].pipelines[0][0]
+	if len(cmd.assigns) != 1 || cmd.assigns[0].name != "A" || r.expandJoined(cmd.assigns[0].value) != "x y" {
+		t.Fatalf("unexpected assignments %+v", cmd.assigns)
+	}
+}
+
+func TestParseShellErrors(t *testing.T) {
+	cases := map[string]string{
+		"echo 'open":  "unterminated single quote",
+		"echo \"open": "unterminated double quote",
+		"a |":         "unexpected end of input",
+		"| a":         "unexpected token",
+		"sleep 1 &":   "background jobs",
+		"echo $(id)":  "command substitution",
+		"echo `id`":   "command substitution",
+		"(cd /tmp)":   "subshells",
+		"cat <":       "unexpected end of input",
+	}
+	for src, want := range cases {
+		_, err := parseShell(src)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: expected error containing %q, got %v", src, want, err)
+		}
+	}
+}
+
+func TestExpandFieldsQuotingAndSplitting(t *testing.T) {
+	r := &shRunner{vars: map[string]string{"X": "a  b", "E": ""}, name: "sh", args: []string{"one", "two"}}
+	cases := []struct {
+		src  string
+		want []string
+	}{
+		{`$X`, []string{"a", "b"}},
+		{`"$X"`, []string{"a  b"}},
+		{`pre$X`, []string{"pre" + "a", "b"}},
+		{`$E`, nil},
+		{`"$E"`, []string{""}},
+		{`''`, []string{""}},
+		{`'$X'`, []string{"$X"}},
+		{`\$X`, []string{"$X"}},
+		{`${X}z`, []string{"a", "bz"}},
+		{`$1:$#:$0`, []string{"one:2:sh"}},
+		{`"a\"b"`, []string{`a"b`}},
+		{`$`, []string{"$"}},
+		{`\ `, []string{" "}},
+		{`"$@"`, []string{"one", "two"}},
+		{`"<$@>"`, []string{"<one", "two>"}},
+		{`"$*"`, []string{"one two"}},
+		{`$@`, []string{"one", "two"}},
+	}
+	for _, tc := range cases {
+		script, err := parseShell("cmd " + tc.src)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", tc.src, err)
+		}
+		got := r.expandFields(script[0].pipelines[0][0].words[1])
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: expected %q, got %q", tc.src, tc.want, got)
+		}
+	}
+}
+
+func TestExpandFieldsQuotedAtKeepsParameters(t *testing.T) {
+	cases := []struct {
+		args []string
+		src  string
+		want []string
+	}{
+		{[]string{"a b", "", "c"}, `"$@"`, []string{"a b", "", "c"}},
+		{nil, `"$@"`, nil},
+		{nil, `x"$@"`, []string{"x"}},
+		{nil, `"$*"`, []string{""}},
+		{[]string{"a", "b"}, `"$@$@"`, []string{"a", "ba", "b"}},
+	}
+	for _, tc := range cases {
+		r := &shRunner{vars: map[string]string{}, args: tc.args}
+		script, err := parseShell("cmd " + tc.src)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", tc.src, err)
+		}
+		got := r.expandWords(script[0].pipelines[0][0].words[1:])
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s with %q: expected %q, got %q", tc.src, tc.args, tc.want, got)
+		}
+	}
+}
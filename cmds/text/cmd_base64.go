@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -11,6 +12,10 @@ import (
 )
 
 func Base64Cmd(args []string) error {
+	return base64Cmd(base.Stdio(), args)
+}
+
+func base64Cmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("base64", flag.ContinueOnError)
 	decode := fsFlags.Bool("d", false, "decode data")
 	fsFlags.BoolVar(decode, "decode", false, "decode data")
@@ -20,15 +25,15 @@ func Base64Cmd(args []string) error {
 	fsFlags.BoolVar(ignoreGarbage, "ignore-garbage", false, "ignore non-alphabet characters while decoding")
 	output := fsFlags.String("o", "", "write output to file")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox base64 [OPTION]... [FILE]...")
-		fmt.Fprintln(os.Stderr, "Encode or decode base64 data.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -d, --decode              decode data")
-		fmt.Fprintln(os.Stderr, "  -w, --wrap COLS           wrap encoded lines after COLS characters (0 disables wrapping)")
-		fmt.Fprintln(os.Stderr, "  -i, --ignore-garbage      ignore non-alphabet characters while decoding")
-		fmt.Fprintln(os.Stderr, "  -o FILE                   write output to file")
-		fmt.Fprintln(os.Stderr, "  -h, --help                show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox base64 [OPTION]... [FILE]...")
+		fmt.Fprintln(inv.Stderr, "Encode or decode base64 data.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -d, --decode              decode data")
+		fmt.Fprintln(inv.Stderr, "  -w, --wrap COLS           wrap encoded lines after COLS characters (0 disables wrapping)")
+		fmt.Fprintln(inv.Stderr, "  -i, --ignore-garbage      ignore non-alphabet characters while decoding")
+		fmt.Fprintln(inv.Stderr, "  -o FILE                   write output to file")
+		fmt.Fprintln(inv.Stderr, "  -h, --help                show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -36,22 +41,29 @@ func Base64Cmd(args []string) error {
 		}
 		return err
 	}
-	var out io.Writer = os.Stdout
+	var out io.Writer = inv.Stdout
 	var outFile *os.File
 	if *output != "" {
-		f, err := os.Create(*output)
+		if err := base.CheckWritable(inv, "base64 -o"); err != nil {
+			return err
+		}
+		f, err := os.Create(inv.Path(*output))
 		if err != nil {
+			base.Audit(inv, "write", inv.Path(*output), err)
 			return err
 		}
 		defer f.Close()
 		outFile = f
 		out = outFile
 	}
-	data, err := readAllInputs(fsFlags.Args())
-	if err != nil {
-		return err
+	data, err := readAllInputs(inv, fsFlags.Args())
+	if err == nil {
+		err = base64Bytes(data, out, *decode, *ignoreGarbage, *wrap)
+	}
+	if outFile != nil {
+		base.Audit(inv, "write", outFile.Name(), err)
 	}
-	return base64Bytes(data, out, *decode, *ignoreGarbage, *wrap)
+	return err
 }
 
 func base64Stream(r io.Reader, w io.Writer, decode, ignoreGarbage bool, wrap int) error {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -24,6 +25,8 @@ type diffOptions struct {
 	recursive       bool
 	newFile         bool
 	stripTrailingCR bool
+	colorMode       string
+	color           *utils.Colorizer
 }
 
 type diffFile struct {
@@ -43,14 +46,19 @@ type diffOp struct {
 }
 
 func DiffCmd(args []string) error {
-	opts, files, err := parseDiffArgs(args)
+	return diffCmd(base.Stdio(), args)
+}
+
+func diffCmd(inv *base.Invocation, args []string) error {
+	opts, files, err := parseDiffArgs(inv.Stderr, args)
 	if err != nil {
 		return err
 	}
 	if files == nil {
 		return nil
 	}
-	different, err := diffPaths(files[0], files[1], opts)
+	opts.color = utils.NewColorizer(inv.Stdout, opts.colorMode, inv.Getenv)
+	different, err := diffPaths(inv, files[0], files[1], opts)
 	if err != nil {
 		return err
 	}
@@ -60,7 +68,7 @@ func DiffCmd(args []string) error {
 	return nil
 }
 
-func parseDiffArgs(args []string) (diffOptions, []string, error) {
+func parseDiffArgs(stderr io.Writer, args []string) (diffOptions, []string, error) {
 	var opts diffOptions
 	fsFlags := flag.NewFlagSet("diff", flag.ContinueOnError)
 	fsFlags.BoolVar(&opts.unified, "u", false, "output unified diff")
@@ -72,16 +80,19 @@ func parseDiffArgs(args []string) (diffOptions, []string, error) {
 	fsFlags.BoolVar(&opts.newFile, "N", false, "treat missing files as empty")
 	fsFlags.BoolVar(&opts.newFile, "new-file", false, "treat missing files as empty")
 	fsFlags.BoolVar(&opts.stripTrailingCR, "strip-trailing-cr", false, "strip trailing carriage returns")
+	color := utils.AddColorFlag(fsFlags)
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox diff [OPTION]... FILE1 FILE2")
-		fmt.Fprintln(os.Stderr, "Compare files line by line.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -u, --unified            output unified diff")
-		fmt.Fprintln(os.Stderr, "  -q, --brief              report only whether files differ")
-		fmt.Fprintln(os.Stderr, "  -r, --recursive          recursively compare directories")
-		fmt.Fprintln(os.Stderr, "  -N, --new-file           treat missing files as empty")
-		fmt.Fprintln(os.Stderr, "  --strip-trailing-cr      strip trailing carriage returns")
+		fmt.Fprintln(stderr, "Usage: gobox diff [OPTION]... FILE1 FILE2")
+		fmt.Fprintln(stderr, "Compare files line by line.")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Options:")
+		fmt.Fprintln(stderr, "  -u, --unified            output unified diff")
+		fmt.Fprintln(stderr, "  -q, --brief              report only whether files differ")
+		fmt.Fprintln(stderr, "  -r, --recursive          recursively compare directories")
+		fmt.Fprintln(stderr, "  -N, --new-file           treat missing files as empty")
+		fmt.Fprintln(stderr, "  --strip-trailing-cr      strip trailing carriage returns")
+		fmt.Fprintln(stderr, "  --color[=WHEN]           color added and deleted lines: auto (default),")
+		fmt.Fprintln(stderr, "                           always or never")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -89,6 +100,7 @@ func parseDiffArgs(args []string) (diffOptions, []string, error) {
 		}
 		return opts, nil, err
 	}
+	opts.colorMode = color.Mode
 	files := fsFlags.Args()
 	if len(files) != 2 {
 		return opts, nil, fmt.Errorf("diff requires two operands")
@@ -99,21 +111,21 @@ func parseDiffArgs(args []string) (diffOptions, []string, error) {
 	return opts, files, nil
 }
 
-func diffPaths(a, b string, opts diffOptions) (bool, error) {
+func diffPaths(inv *base.Invocation, a, b string, opts diffOptions) (bool, error) {
 	if a == "-" || b == "-" {
-		left, err := readDiffFile(a, opts.newFile)
+		left, err := readDiffFile(inv, a, opts.newFile)
 		if err != nil {
 			return false, err
 		}
-		right, err := readDiffFile(b, opts.newFile)
+		right, err := readDiffFile(inv, b, opts.newFile)
 		if err != nil {
 			return false, err
 		}
-		return diffFilePair(left, right, opts)
+		return diffFilePair(inv.Stdout, left, right, opts)
 	}
 
-	infoA, errA := os.Lstat(a)
-	infoB, errB := os.Lstat(b)
+	infoA, errA := os.Lstat(inv.Path(a))
+	infoB, errB := os.Lstat(inv.Path(b))
 	if err := missingDiffPathError(a, errA, opts.newFile); err != nil {
 		return false, err
 	}
@@ -121,15 +133,15 @@ func diffPaths(a, b string, opts diffOptions) (bool, error) {
 		return false, err
 	}
 	if errA != nil || errB != nil {
-		left, err := readDiffFile(a, opts.newFile)
+		left, err := readDiffFile(inv, a, opts.newFile)
 		if err != nil {
 			return false, err
 		}
-		right, err := readDiffFile(b, opts.newFile)
+		right, err := readDiffFile(inv, b, opts.newFile)
 		if err != nil {
 			return false, err
 		}
-		return diffFilePair(left, right, opts)
+		return diffFilePair(inv.Stdout, left, right, opts)
 	}
 	if infoA.IsDir() || infoB.IsDir() {
 		if !infoA.IsDir() || !infoB.IsDir() {
@@ -138,17 +150,17 @@ func diffPaths(a, b string, opts diffOptions) (bool, error) {
 		if !opts.recursive {
 			return false, fmt.Errorf("%s and %s are directories; use -r", a, b)
 		}
-		return diffDirectories(a, b, opts)
+		return diffDirectories(inv, a, b, opts)
 	}
-	left, err := readDiffFile(a, false)
+	left, err := readDiffFile(inv, a, false)
 	if err != nil {
 		return false, err
 	}
-	right, err := readDiffFile(b, false)
+	right, err := readDiffFile(inv, b, false)
 	if err != nil {
 		return false, err
 	}
-	return diffFilePair(left, right, opts)
+	return diffFilePair(inv.Stdout, left, right, opts)
 }
 
 func missingDiffPathError(path string, err error, newFile bool) error {
@@ -158,15 +170,15 @@ func missingDiffPathError(path string, err error, newFile bool) error {
 	return err
 }
 
-func readDiffFile(path string, newFile bool) (diffFile, error) {
+func readDiffFile(inv *base.Invocation, path string, newFile bool) (diffFile, error) {
 	if path == "-" {
-		data, err := io.ReadAll(os.Stdin)
+		data, err := io.ReadAll(inv.Stdin)
 		if err != nil {
 			return diffFile{}, err
 		}
 		return diffFile{name: path, data: data, exists: true}, nil
 	}
-	data, err := os.ReadFile(path)
+	data, err := os.ReadFile(inv.Path(path))
 	if err != nil {
 		if newFile && os.IsNotExist(err) {
 			return diffFile{name: path, exists: false}, nil
@@ -176,12 +188,12 @@ func readDiffFile(path string, newFile bool) (diffFile, error) {
 	return diffFile{name: path, data: data, exists: true}, nil
 }
 
-func diffDirectories(a, b string, opts diffOptions) (bool, error) {
-	entriesA, err := collectDiffFiles(a)
+func diffDirectories(inv *base.Invocation, a, b string, opts diffOptions) (bool, error) {
+	entriesA, err := collectDiffFiles(inv.Path(a))
 	if err != nil {
 		return false, err
 	}
-	entriesB, err := collectDiffFiles(b)
+	entriesB, err := collectDiffFiles(inv.Path(b))
 	if err != nil {
 		return false, err
 	}
@@ -211,7 +223,7 @@ func diffDirectories(a, b string, opts diffOptions) (bool, error) {
 				if !leftOK {
 					base = b
 				}
-				fmt.Printf("Only in %s: %s\n", filepath.Join(base, filepath.Dir(rel)), filepath.Base(rel))
+				fmt.Fprintf(inv.Stdout, "Only in %s: %s\n", filepath.Join(base, filepath.Dir(rel)), filepath.Base(rel))
 				continue
 			}
 		}
@@ -227,23 +239,23 @@ func diffDirectories(a, b string, opts diffOptions) (bool, error) {
 		dfA := diffFile{name: leftName, exists: leftOK}
 		dfB := diffFile{name: rightName, exists: rightOK}
 		if leftOK {
-			data, err := os.ReadFile(leftName)
+			data, err := os.ReadFile(inv.Path(leftName))
 			if err != nil {
 				return false, err
 			}
 			dfA.data = data
 		}
 		if rightOK {
-			data, err := os.ReadFile(rightName)
+			data, err := os.ReadFile(inv.Path(rightName))
 			if err != nil {
 				return false, err
 			}
 			dfB.data = data
 		}
 		if !opts.brief && !diffFilesEqual(dfA.data, dfB.data, opts) {
-			fmt.Printf("diff -r %s %s\n", leftName, rightName)
+			fmt.Fprintf(inv.Stdout, "diff -r %s %s\n", leftName, rightName)
 		}
-		fileDifferent, err := diffFilePair(dfA, dfB, opts)
+		fileDifferent, err := diffFilePair(inv.Stdout, dfA, dfB, opts)
 		if err != nil {
 			return false, err
 		}
@@ -276,25 +288,25 @@ func collectDiffFiles(root string) (map[string]os.FileInfo, error) {
 	return files, err
 }
 
-func diffFilePair(a, b diffFile, opts diffOptions) (bool, error) {
+func diffFilePair(w io.Writer, a, b diffFile, opts diffOptions) (bool, error) {
 	if diffFilesEqual(a.data, b.data, opts) {
 		return false, nil
 	}
 	if opts.brief {
-		fmt.Printf("Files %s and %s differ\n", a.name, b.name)
+		fmt.Fprintf(w, "Files %s and %s differ\n", a.name, b.name)
 		return true, nil
 	}
 	if isBinaryDiffData(a.data) || isBinaryDiffData(b.data) {
-		fmt.Printf("Binary files %s and %s differ\n", a.name, b.name)
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", a.name, b.name)
 		return true, nil
 	}
 	oldLines := splitDiffLines(a.data, opts.stripTrailingCR)
 	newLines := splitDiffLines(b.data, opts.stripTrailingCR)
 	ops := buildDiffOps(oldLines, newLines)
 	if opts.unified {
-		printUnifiedDiff(a.name, b.name, ops, len(oldLines), len(newLines))
+		printUnifiedDiff(w, opts.color, a.name, b.name, ops, len(oldLines), len(newLines))
 	} else {
-		printNormalDiff(ops)
+		printNormalDiff(w, opts.color, ops)
 	}
 	return true, nil
 }
@@ -378,7 +390,7 @@ func buildDiffOps(oldLines, newLines []diffLine) []diffOp {
 	return ops
 }
 
-func printNormalDiff(ops []diffOp) {
+func printNormalDiff(w io.Writer, c *utils.Colorizer, ops []diffOp) {
 	oldLine, newLine := 1, 1
 	for i := 0; i < len(ops); {
 		if ops[i].kind == ' ' {
@@ -402,23 +414,23 @@ func printNormalDiff(ops []diffOp) {
 		}
 		switch {
 		case len(dels) > 0 && len(adds) > 0:
-			fmt.Printf("%sc%s\n", normalRange(oldStart, len(dels)), normalRange(newStart, len(adds)))
+			fmt.Fprintln(w, c.Paint(utils.ColorHunk, normalRange(oldStart, len(dels))+"c"+normalRange(newStart, len(adds))))
 			for _, line := range dels {
-				fmt.Printf("< %s\n", line)
+				fmt.Fprintln(w, c.Paint(utils.ColorDeleted, "< "+line))
 			}
-			fmt.Println("---")
+			fmt.Fprintln(w, "---")
 			for _, line := range adds {
-				fmt.Printf("> %s\n", line)
+				fmt.Fprintln(w, c.Paint(utils.ColorAdded, "> "+line))
 			}
 		case len(dels) > 0:
-			fmt.Printf("%sd%s\n", normalRange(oldStart, len(dels)), normalRange(newStart-1, 1))
+			fmt.Fprintln(w, c.Paint(utils.ColorHunk, normalRange(oldStart, len(dels))+"d"+normalRange(newStart-1, 1)))
 			for _, line := range dels {
-				fmt.Printf("< %s\n", line)
+				fmt.Fprintln(w, c.Paint(utils.ColorDeleted, "< "+line))
 			}
 		case len(adds) > 0:
-			fmt.Printf("%sa%s\n", normalRange(oldStart-1, 1), normalRange(newStart, len(adds)))
+			fmt.Fprintln(w, c.Paint(utils.ColorHunk, normalRange(oldStart-1, 1)+"a"+normalRange(newStart, len(adds))))
 			for _, line := range adds {
-				fmt.Printf("> %s\n", line)
+				fmt.Fprintln(w, c.Paint(utils.ColorAdded, "> "+line))
 			}
 		}
 	}
@@ -529,19 +541,19 @@ func buildUnifiedHunks(ops []diffOp) []diffHunk {
 	return hunks
 }
 
-func printUnifiedDiff(oldName, newName string, ops []diffOp, oldCount, newCount int) {
-	fmt.Printf("--- %s\n", oldName)
-	fmt.Printf("+++ %s\n", newName)
+func printUnifiedDiff(w io.Writer, c *utils.Colorizer, oldName, newName string, ops []diffOp, oldCount, newCount int) {
+	fmt.Fprintln(w, c.Paint(utils.ColorHeader, "--- "+oldName))
+	fmt.Fprintln(w, c.Paint(utils.ColorHeader, "+++ "+newName))
 	for _, h := range buildUnifiedHunks(ops) {
-		fmt.Printf("@@ -%s +%s @@\n", unifiedRange(h.oldStart, h.oldCount), unifiedRange(h.newStart, h.newCount))
+		fmt.Fprintln(w, c.Paint(utils.ColorHunk, "@@ -"+unifiedRange(h.oldStart, h.oldCount)+" +"+unifiedRange(h.newStart, h.newCount)+" @@"))
 		for _, op := range h.ops {
 			switch op.kind {
 			case ' ':
-				fmt.Printf(" %s\n", op.old.text)
+				fmt.Fprintf(w, " %s\n", op.old.text)
 			case '-':
-				fmt.Printf("-%s\n", op.old.text)
+				fmt.Fprintln(w, c.Paint(utils.ColorDeleted, "-"+op.old.text))
 			case '+':
-				fmt.Printf("+%s\n", op.new.text)
+				fmt.Fprintln(w, c.Paint(utils.ColorAdded, "+"+op.new.text))
 			}
 		}
 	}
@@ -235,6 +235,34 @@ func TestDiffBinaryDifferenceReportsWithoutDumping(t *testing.T) {
 	}
 }
 
+func TestDiffColorAlwaysPaintsHunks(t *testing.T) {
+	dir := t.TempDir()
+	a := writeDiffTestFile(t, dir, "a", "one\ntwo\n")
+	b := writeDiffTestFile(t, dir, "b", "one\nTWO\n")
+
+	out, err := captureTextCmd(t, "", func() error {
+		return DiffCmd([]string{"--color=always", "-u", a, b})
+	})
+	assertDiffExit(t, err)
+	for _, want := range []string{
+		"\x1b[1m\x1b[K--- " + a + "\x1b[m\x1b[K\n",
+		"\x1b[36m\x1b[K@@ -1,2 +1,2 @@\x1b[m\x1b[K\n one\n",
+		"\x1b[31m\x1b[K-two\x1b[m\x1b[K\n\x1b[32m\x1b[K+TWO\x1b[m\x1b[K\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in colored diff, got %q", want, out)
+		}
+	}
+
+	out, err = captureTextCmd(t, "", func() error {
+		return DiffCmd([]string{"--color=always", a, b})
+	})
+	assertDiffExit(t, err)
+	if want := "\x1b[36m\x1b[K2c2\x1b[m\x1b[K\n\x1b[31m\x1b[K< two\x1b[m\x1b[K\n---\n\x1b[32m\x1b[K> TWO\x1b[m\x1b[K\n"; out != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
 func writeDiffTestFile(t *testing.T, dir, name, content string) string {
 	t.Helper()
 	path := filepath.Join(dir, name)
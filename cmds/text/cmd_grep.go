@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -42,6 +43,10 @@ type grepOptions struct {
 	afterContext      int
 	includePattern    string
 	excludeDir        string
+	// color paints matches and the filename and line-number prefixes;
+	// highlight marks the matches in a selected line when color is on.
+	color     *utils.Colorizer
+	highlight func(line string) string
 }
 
 type grepResult struct {
@@ -51,6 +56,10 @@ type grepResult struct {
 
 // GrepCmd implements a basic subset of grep functionality.
 func GrepCmd(args []string) error {
+	return grepCmd(base.Stdio(), args)
+}
+
+func grepCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("grep", flag.ContinueOnError)
 	ignoreCase := fsFlags.Bool("i", false, "ignore case")
 	invert := fsFlags.Bool("v", false, "invert match (show non-matching lines)")
@@ -74,45 +83,47 @@ func GrepCmd(args []string) error {
 	filesWithoutMatch := fsFlags.Bool("L", false, "print only names of files without selected lines")
 	filesWithMatchesLong := fsFlags.Bool("files-with-matches", false, "print only names of files with selected lines")
 	filesWithoutMatchLong := fsFlags.Bool("files-without-match", false, "print only names of files without selected lines")
+	color := utils.AddColorFlag(fsFlags)
 	help := fsFlags.Bool("help", false, "show help")
 
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox grep [OPTION]... PATTERN [FILE...]")
-		fmt.Fprintln(os.Stderr, "Search for PATTERN in each FILE or standard input.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Matching:")
-		fmt.Fprintln(os.Stderr, "  -i                      ignore case")
-		fmt.Fprintln(os.Stderr, "  -v                      invert match")
-		fmt.Fprintln(os.Stderr, "  -F                      interpret pattern as fixed string")
-		fmt.Fprintln(os.Stderr, "  -E                      use extended regular expressions (default in Go)")
-		fmt.Fprintln(os.Stderr, "  -o                      print only matching text")
-		fmt.Fprintln(os.Stderr, "  -q                      suppress normal output and return status only")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Output:")
-		fmt.Fprintln(os.Stderr, "  -c                      show count of matching lines only")
-		fmt.Fprintln(os.Stderr, "  -n                      show line numbers")
-		fmt.Fprintln(os.Stderr, "  --line-buffered         flush output after each line")
-		fmt.Fprintln(os.Stderr, "  -l, --files-with-matches")
-		fmt.Fprintln(os.Stderr, "                          print only names of files with selected lines")
-		fmt.Fprintln(os.Stderr, "  -L, --files-without-match")
-		fmt.Fprintln(os.Stderr, "                          print only names of files without selected lines")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Context:")
-		fmt.Fprintln(os.Stderr, "  -r                      recursive search in directories")
-		fmt.Fprintln(os.Stderr, "  -A, --after-context N   print N trailing context lines")
-		fmt.Fprintln(os.Stderr, "  -B, --before-context N  print N leading context lines")
-		fmt.Fprintln(os.Stderr, "  -C, --context N         print N lines of surrounding context")
-		fmt.Fprintln(os.Stderr, "  --include PATTERN       search only files matching PATTERN")
-		fmt.Fprintln(os.Stderr, "  --exclude-dir DIR       skip directories named DIR")
-		fmt.Fprintln(os.Stderr, "  -h, --help              show this help")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Examples:")
-		fmt.Fprintln(os.Stderr, "  gobox grep \"error\" /var/log/syslog")
-		fmt.Fprintln(os.Stderr, "  gobox grep -i -r \"TODO\" /path/to/code")
-		fmt.Fprintln(os.Stderr, "  gobox grep -v \"^#\" config.txt")
-		fmt.Fprintln(os.Stderr, "  gobox grep -o \"[0-9]+\" file.txt  # only matching parts")
-		fmt.Fprintln(os.Stderr, "  gobox grep -q \"pattern\" file && echo \"found\"")
-		fmt.Fprintln(os.Stderr, "  cat file.txt | gobox grep \"pattern\"")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox grep [OPTION]... PATTERN [FILE...]")
+		fmt.Fprintln(inv.Stderr, "Search for PATTERN in each FILE or standard input.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Matching:")
+		fmt.Fprintln(inv.Stderr, "  -i                      ignore case")
+		fmt.Fprintln(inv.Stderr, "  -v                      invert match")
+		fmt.Fprintln(inv.Stderr, "  -F                      interpret pattern as fixed string")
+		fmt.Fprintln(inv.Stderr, "  -E                      use extended regular expressions (default in Go)")
+		fmt.Fprintln(inv.Stderr, "  -o                      print only matching text")
+		fmt.Fprintln(inv.Stderr, "  -q                      suppress normal output and return status only")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Output:")
+		fmt.Fprintln(inv.Stderr, "  -c                      show count of matching lines only")
+		fmt.Fprintln(inv.Stderr, "  -n                      show line numbers")
+		fmt.Fprintln(inv.Stderr, "  --line-buffered         flush output after each line")
+		fmt.Fprintln(inv.Stderr, "  --color[=WHEN]          highlight matches: auto (default), always or never")
+		fmt.Fprintln(inv.Stderr, "  -l, --files-with-matches")
+		fmt.Fprintln(inv.Stderr, "                          print only names of files with selected lines")
+		fmt.Fprintln(inv.Stderr, "  -L, --files-without-match")
+		fmt.Fprintln(inv.Stderr, "                          print only names of files without selected lines")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Context:")
+		fmt.Fprintln(inv.Stderr, "  -r                      recursive search in directories")
+		fmt.Fprintln(inv.Stderr, "  -A, --after-context N   print N trailing context lines")
+		fmt.Fprintln(inv.Stderr, "  -B, --before-context N  print N leading context lines")
+		fmt.Fprintln(inv.Stderr, "  -C, --context N         print N lines of surrounding context")
+		fmt.Fprintln(inv.Stderr, "  --include PATTERN       search only files matching PATTERN")
+		fmt.Fprintln(inv.Stderr, "  --exclude-dir DIR       skip directories named DIR")
+		fmt.Fprintln(inv.Stderr, "  -h, --help              show this help")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Examples:")
+		fmt.Fprintln(inv.Stderr, "  gobox grep \"error\" /var/log/syslog")
+		fmt.Fprintln(inv.Stderr, "  gobox grep -i -r \"TODO\" /path/to/code")
+		fmt.Fprintln(inv.Stderr, "  gobox grep -v \"^#\" config.txt")
+		fmt.Fprintln(inv.Stderr, "  gobox grep -o \"[0-9]+\" file.txt  # only matching parts")
+		fmt.Fprintln(inv.Stderr, "  gobox grep -q \"pattern\" file && echo \"found\"")
+		fmt.Fprintln(inv.Stderr, "  cat file.txt | gobox grep \"pattern\"")
 	}
 
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
@@ -127,7 +138,7 @@ func GrepCmd(args []string) error {
 		return nil
 	}
 	if fsFlags.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "grep: PATTERN is required")
+		fmt.Fprintln(inv.Stderr, "grep: PATTERN is required")
 		fsFlags.Usage()
 		return fmt.Errorf("pattern required")
 	}
@@ -170,6 +181,7 @@ func GrepCmd(args []string) error {
 		afterContext:      *afterContext,
 		includePattern:    *includePattern,
 		excludeDir:        *excludeDir,
+		color:             utils.NewColorizer(inv.Stdout, color.Mode, inv.Getenv, "GREP_COLORS"),
 	}
 	if *context > 0 {
 		if opts.beforeContext == 0 {
@@ -199,9 +211,15 @@ func GrepCmd(args []string) error {
 		}
 	}
 
+	if opts.color.Enabled() && !opts.invert {
+		opts.highlight = func(line string) string {
+			return grepHighlight(line, pattern, regex, opts)
+		}
+	}
+
 	matchedAny := false
 	if len(files) == 0 {
-		result, err := grepReader(os.Stdin, pattern, regex, opts, "")
+		result, err := grepReader(inv.Stdout, inv.Stdin, pattern, regex, opts, "")
 		if err != nil {
 			return err
 		}
@@ -210,7 +228,7 @@ func GrepCmd(args []string) error {
 		for _, file := range files {
 			var err error
 			if opts.recursive {
-				err = filepath.WalkDir(file, func(path string, d os.DirEntry, walkErr error) error {
+				err = filepath.WalkDir(inv.Path(file), func(path string, d os.DirEntry, walkErr error) error {
 					if walkErr != nil {
 						return nil
 					}
@@ -226,7 +244,7 @@ func GrepCmd(args []string) error {
 							return nil
 						}
 					}
-					result, err := grepFile(path, pattern, regex, opts)
+					result, err := grepFile(inv, inv.DisplayPath(file, path), pattern, regex, opts)
 					if err != nil {
 						return err
 					}
@@ -240,7 +258,7 @@ func GrepCmd(args []string) error {
 					err = nil
 				}
 			} else {
-				result, grepErr := grepFile(file, pattern, regex, opts)
+				result, grepErr := grepFile(inv, file, pattern, regex, opts)
 				if grepErr != nil {
 					return grepErr
 				}
@@ -264,19 +282,19 @@ func GrepCmd(args []string) error {
 	return nil
 }
 
-func grepFile(path, pattern string, regex *regexp.Regexp, opts grepOptions) (grepResult, error) {
+func grepFile(inv *base.Invocation, path, pattern string, regex *regexp.Regexp, opts grepOptions) (grepResult, error) {
 	if path == "-" {
-		return grepReader(os.Stdin, pattern, regex, opts, path)
+		return grepReader(inv.Stdout, inv.Stdin, pattern, regex, opts, path)
 	}
-	file, err := os.Open(path)
+	file, err := os.Open(inv.Path(path))
 	if err != nil {
 		return grepResult{}, fmt.Errorf("cannot open %s: %w", path, err)
 	}
 	defer file.Close()
-	return grepReader(file, pattern, regex, opts, path)
+	return grepReader(inv.Stdout, file, pattern, regex, opts, path)
 }
 
-func grepReader(r io.Reader, pattern string, regex *regexp.Regexp, opts grepOptions, filename string) (grepResult, error) {
+func grepReader(w io.Writer, r io.Reader, pattern string, regex *regexp.Regexp, opts grepOptions, filename string) (grepResult, error) {
 	// When context or filesWithoutMatch requires seeing all lines, buffer first.
 	if opts.beforeContext > 0 || opts.filesWithoutMatch {
 		scanner := bufio.NewScanner(r)
@@ -291,13 +309,13 @@ func grepReader(r io.Reader, pattern string, regex *regexp.Regexp, opts grepOpti
 			}
 			return grepResult{}, fmt.Errorf("error reading %s: %w", name, err)
 		}
-		return grepLines(lines, pattern, regex, opts, filename)
+		return grepLines(w, lines, pattern, regex, opts, filename)
 	}
-	return grepReaderStream(r, pattern, regex, opts, filename)
+	return grepReaderStream(w, r, pattern, regex, opts, filename)
 }
 
 // grepReaderStream processes lines one at a time when no before-context buffering is needed.
-func grepReaderStream(r io.Reader, pattern string, regex *regexp.Regexp, opts grepOptions, filename string) (grepResult, error) {
+func grepReaderStream(w io.Writer, r io.Reader, pattern string, regex *regexp.Regexp, opts grepOptions, filename string) (grepResult, error) {
 	scanner := bufio.NewScanner(r)
 	matches := 0
 	lineNum := 0
@@ -313,7 +331,7 @@ func grepReaderStream(r io.Reader, pattern string, regex *regexp.Regexp, opts gr
 				return grepResult{matches: matches, matched: true}, nil
 			}
 			if opts.filesWithMatches {
-				fmt.Fprintln(os.Stdout, filename)
+				fmt.Fprintln(w, filename)
 				return grepResult{matches: matches, matched: true}, nil
 			}
 			afterRemain = opts.afterContext
@@ -322,10 +340,10 @@ func grepReaderStream(r io.Reader, pattern string, regex *regexp.Regexp, opts gr
 			if !opts.count && !opts.quiet && !opts.filesWithMatches {
 				if opts.onlyMatching && matched {
 					for _, part := range grepFindMatches(line, pattern, regex, opts) {
-						printGrepLineWithOptions(part, filename, lineNum, opts)
+						printGrepLineWithOptions(w, part, filename, lineNum, opts)
 					}
 				} else if !opts.onlyMatching {
-					printGrepLineWithOptions(line, filename, lineNum, opts)
+					printGrepLineWithOptions(w, line, filename, lineNum, opts)
 				}
 			}
 			if !matched && afterRemain > 0 {
@@ -343,15 +361,15 @@ func grepReaderStream(r io.Reader, pattern string, regex *regexp.Regexp, opts gr
 	matched := matches > 0
 	if opts.count && !opts.quiet {
 		if opts.showFilename && filename != "" {
-			fmt.Printf("%s:%d\n", filename, matches)
+			fmt.Fprintf(w, "%s:%d\n", filename, matches)
 		} else {
-			fmt.Println(matches)
+			fmt.Fprintln(w, matches)
 		}
 	}
 	return grepResult{matches: matches, matched: matched}, nil
 }
 
-func grepLines(lines []string, pattern string, regex *regexp.Regexp, opts grepOptions, filename string) (grepResult, error) {
+func grepLines(w io.Writer, lines []string, pattern string, regex *regexp.Regexp, opts grepOptions, filename string) (grepResult, error) {
 	matchedLines := make([]bool, len(lines))
 	matches := 0
 	for i, line := range lines {
@@ -368,21 +386,21 @@ func grepLines(lines []string, pattern string, regex *regexp.Regexp, opts grepOp
 
 	if opts.filesWithMatches {
 		if matched {
-			fmt.Fprintln(os.Stdout, filename)
+			fmt.Fprintln(w, filename)
 		}
 		return grepResult{matches: matches, matched: matched}, nil
 	}
 	if opts.filesWithoutMatch {
 		if !matched {
-			fmt.Fprintln(os.Stdout, filename)
+			fmt.Fprintln(w, filename)
 		}
 		return grepResult{matches: matches, matched: matched}, nil
 	}
 	if opts.count && !opts.quiet {
 		if opts.showFilename && filename != "" {
-			fmt.Printf("%s:%d\n", filename, matches)
+			fmt.Fprintf(w, "%s:%d\n", filename, matches)
 		} else {
-			fmt.Println(matches)
+			fmt.Fprintln(w, matches)
 		}
 		return grepResult{matches: matches, matched: matched}, nil
 	}
@@ -396,7 +414,7 @@ func grepLines(lines []string, pattern string, regex *regexp.Regexp, opts grepOp
 				continue
 			}
 			for _, part := range grepFindMatches(line, pattern, regex, opts) {
-				printGrepLineWithOptions(part, filename, i+1, opts)
+				printGrepLineWithOptions(w, part, filename, i+1, opts)
 			}
 		}
 		return grepResult{matches: matches, matched: matched}, nil
@@ -421,7 +439,7 @@ func grepLines(lines []string, pattern string, regex *regexp.Regexp, opts grepOp
 	}
 	for i, line := range lines {
 		if toPrint[i] {
-			printGrepLineWithOptions(line, filename, i+1, opts)
+			printGrepLineWithOptions(w, line, filename, i+1, opts)
 		}
 	}
 	return grepResult{matches: matches, matched: matched}, nil
@@ -450,6 +468,22 @@ func grepFindMatches(line, pattern string, regex *regexp.Regexp, opts grepOption
 	}
 	if opts.fixedString && !opts.ignoreCase {
 		parts := make([]string, 0)
+		for _, loc := range grepMatchIndexes(line, pattern, regex, opts) {
+			parts = append(parts, line[loc[0]:loc[1]])
+		}
+		return parts
+	}
+	return regex.FindAllString(line, -1)
+}
+
+// grepMatchIndexes returns the [start, end) byte offsets of each match in
+// line, in the order grepFindMatches reports them.
+func grepMatchIndexes(line, pattern string, regex *regexp.Regexp, opts grepOptions) [][]int {
+	if opts.fixedString && !opts.ignoreCase {
+		var locs [][]int
+		if pattern == "" {
+			return locs
+		}
 		start := 0
 		for {
 			idx := strings.Index(line[start:], pattern)
@@ -457,20 +491,48 @@ func grepFindMatches(line, pattern string, regex *regexp.Regexp, opts grepOption
 				break
 			}
 			actualIdx := start + idx
-			parts = append(parts, line[actualIdx:actualIdx+len(pattern)])
+			locs = append(locs, []int{actualIdx, actualIdx + len(pattern)})
 			start = actualIdx + len(pattern)
 		}
-		return parts
+		return locs
 	}
-	return regex.FindAllString(line, -1)
+	return regex.FindAllStringIndex(line, -1)
+}
+
+// grepHighlight paints every non-empty match in line with the match color.
+func grepHighlight(line, pattern string, regex *regexp.Regexp, opts grepOptions) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range grepMatchIndexes(line, pattern, regex, opts) {
+		if loc[1] == loc[0] {
+			continue
+		}
+		b.WriteString(line[last:loc[0]])
+		b.WriteString(opts.color.Paint(utils.ColorMatch, line[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	if last == 0 {
+		return line
+	}
+	b.WriteString(line[last:])
+	return b.String()
 }
 
-func printGrepLineWithOptions(line, filename string, lineNum int, opts grepOptions) {
+func printGrepLineWithOptions(w io.Writer, line, filename string, lineNum int, opts grepOptions) {
+	c := opts.color
 	if opts.showFilename && filename != "" {
-		fmt.Fprintf(os.Stdout, "%s:", filename)
+		fmt.Fprintf(w, "%s%s", c.Paint(utils.ColorFilename, filename), c.Paint(utils.ColorSeparator, ":"))
 	}
 	if opts.lineNumber {
-		fmt.Fprintf(os.Stdout, "%d:", lineNum)
+		fmt.Fprintf(w, "%s%s", c.Paint(utils.ColorLineNumber, fmt.Sprint(lineNum)), c.Paint(utils.ColorSeparator, ":"))
+	}
+	if opts.highlight != nil {
+		if opts.onlyMatching {
+			// With -o the line is the match itself.
+			line = c.Paint(utils.ColorMatch, line)
+		} else {
+			line = opts.highlight(line)
+		}
 	}
-	fmt.Fprintln(os.Stdout, line)
+	fmt.Fprintln(w, line)
 }
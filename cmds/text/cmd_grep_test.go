@@ -1,6 +1,9 @@
 package text
 
 import (
+	"bytes"
+	"gobox/cmds/base"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -600,6 +603,39 @@ func TestGrepCmdExplicitDashReadsStdin(t *testing.T) {
 	}
 }
 
+func TestGrepColorAlwaysMatchesGNUSequences(t *testing.T) {
+	output, err := runGrepCmdWithStdin([]string{"--color=always", "-n", "o"}, "foo\nbar\n")
+	if err != nil {
+		t.Fatalf("grep --color=always failed: %v", err)
+	}
+	want := "\x1b[32m\x1b[K1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K" +
+		"f\x1b[01;31m\x1b[Ko\x1b[m\x1b[K\x1b[01;31m\x1b[Ko\x1b[m\x1b[K\n"
+	if output != want {
+		t.Fatalf("got %q, want %q", output, want)
+	}
+}
+
+func TestGrepColorAutoIsPlainOnPipe(t *testing.T) {
+	output, err := runGrepCmdWithStdin([]string{"--color", "o"}, "foo\n")
+	if err != nil {
+		t.Fatalf("grep --color failed: %v", err)
+	}
+	if output != "foo\n" {
+		t.Fatalf("expected no escapes when stdout is a pipe, got %q", output)
+	}
+}
+
+func TestGrepColorHonorsGrepColors(t *testing.T) {
+	var out bytes.Buffer
+	inv := &base.Invocation{Stdin: strings.NewReader("foo\n"), Stdout: &out, Stderr: io.Discard, Env: []string{"GREP_COLORS=mt=01;32"}}
+	if err := grepCmd(inv, []string{"--color=always", "-o", "o+"}); err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if want := "\x1b[01;32m\x1b[Koo\x1b[m\x1b[K\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
+
 // writeTestFile helper kept for compatibility with other test files in this package
 func writeTestFile(t *testing.T, filename, content string) {
 	err := os.WriteFile(filename, []byte(content), 0644)
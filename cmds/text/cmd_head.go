@@ -3,6 +3,7 @@ package text
 import (
 	"bufio"
 	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"os"
 	"strconv"
@@ -11,6 +12,10 @@ import (
 
 // headCmd implements the head command
 func HeadCmd(args []string) error {
+	return headCmd(base.Stdio(), args)
+}
+
+func headCmd(inv *base.Invocation, args []string) error {
 	var (
 		lines        = 10    // default number of lines
 		linesFromEnd = false // -n -N: print all but the last N lines
@@ -109,7 +114,7 @@ func HeadCmd(args []string) error {
 
 doneFlags:
 	if showHelp {
-		printHeadUsage(os.Stdout)
+		printHeadUsage(inv.Stdout)
 		return nil
 	}
 
@@ -118,7 +123,7 @@ doneFlags:
 
 	// If no files, read from stdin
 	if len(files) == 0 {
-		if err := headReader(os.Stdin, os.Stdout, lines, linesFromEnd, bytes, bytesFromEnd); err != nil {
+		if err := headReader(inv.Stdin, inv.Stdout, lines, linesFromEnd, bytes, bytesFromEnd); err != nil {
 			return err
 		}
 		return nil
@@ -127,19 +132,27 @@ doneFlags:
 	// Process files
 	for _, file := range files {
 		if multipleFiles && !quiet {
-			fmt.Printf("==> %s <==\n", file)
+			fmt.Fprintf(inv.Stdout, "==> %s <==\n", file)
 		}
-		if err := headFile(file, os.Stdout, lines, linesFromEnd, bytes, bytesFromEnd); err != nil {
+		if err := headFile(inv, file, lines, linesFromEnd, bytes, bytesFromEnd); err != nil {
 			return err
 		}
 		if multipleFiles && !quiet && file != files[len(files)-1] {
-			fmt.Println()
+			fmt.Fprintln(inv.Stdout)
 		}
 	}
 
 	return nil
 }
 
+// headFlags declares head's options for shell completion.
+var headFlags = []base.Flag{
+	{Name: "-n", TakesValue: true, Usage: "print the first NUM lines"}, {Name: "--lines", TakesValue: true, Usage: "print the first NUM lines"},
+	{Name: "-c", TakesValue: true, Usage: "print the first NUM bytes"}, {Name: "--bytes", TakesValue: true, Usage: "print the first NUM bytes"},
+	{Name: "-q", Usage: "never print file name headers"}, {Name: "--quiet", Usage: "never print file name headers"}, {Name: "--silent", Usage: "never print file name headers"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
 func printHeadUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox head [OPTION]... [FILE...]")
 	fmt.Fprintln(w, "Print the first lines of a file.")
@@ -220,15 +233,15 @@ func headBytes(r io.Reader, w io.Writer, n int) error {
 	return err
 }
 
-func headFile(filename string, w io.Writer, lines int, linesFromEnd bool, bytes int, bytesFromEnd bool) error {
+func headFile(inv *base.Invocation, filename string, lines int, linesFromEnd bool, bytes int, bytesFromEnd bool) error {
 	if filename == "-" {
-		return headReader(os.Stdin, w, lines, linesFromEnd, bytes, bytesFromEnd)
+		return headReader(inv.Stdin, inv.Stdout, lines, linesFromEnd, bytes, bytesFromEnd)
 	}
-	file, err := os.Open(filename)
+	file, err := os.Open(inv.Path(filename))
 	if err != nil {
 		return fmt.Errorf("cannot open %s: %w", filename, err)
 	}
 	defer file.Close()
 
-	return headReader(file, w, lines, linesFromEnd, bytes, bytesFromEnd)
+	return headReader(file, inv.Stdout, lines, linesFromEnd, bytes, bytesFromEnd)
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -13,6 +14,10 @@ import (
 )
 
 func HexCmd(args []string) error {
+	return hexCmd(base.Stdio(), args)
+}
+
+func hexCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("hex", flag.ContinueOnError)
 	dump := fsFlags.Bool("dump", false, "dump bytes like hexdump")
 	encode := fsFlags.Bool("encode", false, "encode input as hex")
@@ -27,22 +32,22 @@ func HexCmd(args []string) error {
 	format := fsFlags.String("e", "", "format string subset")
 	output := fsFlags.String("o", "", "write output to file")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox hex --dump|--encode|--decode [OPTION]... [FILE]...")
-		fmt.Fprintln(os.Stderr, "Encode, decode, or dump hexadecimal data.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Modes:")
-		fmt.Fprintln(os.Stderr, "  --dump              dump bytes like hexdump")
-		fmt.Fprintln(os.Stderr, "  --encode            encode input as hex")
-		fmt.Fprintln(os.Stderr, "  --decode            decode hex input")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -C                  canonical dump (default; no effect)")
-		fmt.Fprintln(os.Stderr, "  -n LEN              read at most LEN bytes")
-		fmt.Fprintln(os.Stderr, "  -s OFFSET           skip OFFSET bytes")
-		fmt.Fprintln(os.Stderr, "  -v                  do not fold repeated lines")
-		fmt.Fprintf(os.Stderr, "%s\n", "  -e FORMAT           dump byte format, one of: %02x %02X %u %d")
-		fmt.Fprintln(os.Stderr, "  -o FILE             write output to file")
-		fmt.Fprintln(os.Stderr, "  -h, --help          show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox hex --dump|--encode|--decode [OPTION]... [FILE]...")
+		fmt.Fprintln(inv.Stderr, "Encode, decode, or dump hexadecimal data.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Modes:")
+		fmt.Fprintln(inv.Stderr, "  --dump              dump bytes like hexdump")
+		fmt.Fprintln(inv.Stderr, "  --encode            encode input as hex")
+		fmt.Fprintln(inv.Stderr, "  --decode            decode hex input")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -C                  canonical dump (default; no effect)")
+		fmt.Fprintln(inv.Stderr, "  -n LEN              read at most LEN bytes")
+		fmt.Fprintln(inv.Stderr, "  -s OFFSET           skip OFFSET bytes")
+		fmt.Fprintln(inv.Stderr, "  -v                  do not fold repeated lines")
+		fmt.Fprintf(inv.Stderr, "%s\n", "  -e FORMAT           dump byte format, one of: %02x %02X %u %d")
+		fmt.Fprintln(inv.Stderr, "  -o FILE             write output to file")
+		fmt.Fprintln(inv.Stderr, "  -h, --help          show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -59,8 +64,13 @@ func HexCmd(args []string) error {
 	if modes != 1 {
 		return fmt.Errorf("exactly one of --dump, --encode, --decode is required")
 	}
+	if *output != "" {
+		if err := base.CheckWritable(inv, "hex -o"); err != nil {
+			return err
+		}
+	}
 	files := fsFlags.Args()
-	data, err := readAllInputs(files)
+	data, err := readAllInputs(inv, files)
 	if err != nil {
 		return err
 	}
@@ -74,11 +84,12 @@ func HexCmd(args []string) error {
 	if *limit >= 0 && *limit < int64(len(data)) {
 		data = data[:*limit]
 	}
-	var out io.Writer = os.Stdout
+	var out io.Writer = inv.Stdout
 	var f *os.File
 	if *output != "" {
-		f, err = os.Create(*output)
+		f, err = os.Create(inv.Path(*output))
 		if err != nil {
+			base.Audit(inv, "write", inv.Path(*output), err)
 			return err
 		}
 		defer f.Close()
@@ -106,24 +117,27 @@ func HexCmd(args []string) error {
 			err = dumpCanonicalHex(out, data, *offset, !*verbose)
 		}
 	}
+	if f != nil {
+		base.Audit(inv, "write", f.Name(), err)
+	}
 	return err
 }
 
-func readAllInputs(files []string) ([]byte, error) {
+func readAllInputs(inv *base.Invocation, files []string) ([]byte, error) {
 	if len(files) == 0 {
-		return io.ReadAll(os.Stdin)
+		return io.ReadAll(inv.Stdin)
 	}
 	var out []byte
 	for _, file := range files {
 		if file == "-" {
-			b, err := io.ReadAll(os.Stdin)
+			b, err := io.ReadAll(inv.Stdin)
 			if err != nil {
 				return nil, err
 			}
 			out = append(out, b...)
 			continue
 		}
-		b, err := os.ReadFile(file)
+		b, err := os.ReadFile(inv.Path(file))
 		if err != nil {
 			return nil, err
 		}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"os"
 	"strconv"
@@ -21,6 +22,10 @@ type randConfig struct {
 }
 
 func RandCmd(args []string) error {
+	return randCmd(base.Stdio(), args)
+}
+
+func randCmd(inv *base.Invocation, args []string) error {
 	cfg := randConfig{
 		numBytes: 32,
 		hex:      true,
@@ -66,7 +71,7 @@ func RandCmd(args []string) error {
 			i++
 			cfg.output = args[i]
 		case arg == "-h", arg == "--help":
-			printRandUsage(os.Stdout)
+			printRandUsage(inv.Stdout)
 			return nil
 		case strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--"):
 			// Handle combined short flags
@@ -115,6 +120,11 @@ func RandCmd(args []string) error {
 	}
 
 doneFlags:
+	if cfg.output != "" {
+		if err := base.CheckWritable(inv, "rand -out"); err != nil {
+			return err
+		}
+	}
 
 	// Generate random bytes
 	data := make([]byte, cfg.numBytes)
@@ -132,17 +142,21 @@ doneFlags:
 	}
 
 	// Write output
-	var out io.Writer = os.Stdout
+	var out io.Writer = inv.Stdout
 	if cfg.output != "" {
-		f, err := os.Create(cfg.output)
+		f, err := os.Create(inv.Path(cfg.output))
 		if err != nil {
+			base.Audit(inv, "write", inv.Path(cfg.output), err, "bytes", strconv.Itoa(cfg.numBytes))
 			return fmt.Errorf("cannot create output file: %w", err)
 		}
 		out = f
 		defer f.Close()
 	}
 
-	fmt.Fprintln(out, outData)
+	_, err = fmt.Fprintln(out, outData)
+	if cfg.output != "" {
+		base.Audit(inv, "write", inv.Path(cfg.output), err, "bytes", strconv.Itoa(cfg.numBytes))
+	}
 	return nil
 }
 
@@ -170,6 +184,15 @@ func isDigits(s string) bool {
 	return true
 }
 
+// randFlags declares rand's options for shell completion.
+var randFlags = []base.Flag{
+	{Name: "-n", TakesValue: true, Usage: "number of bytes to generate"},
+	{Name: "-hex", Usage: "hex output"},
+	{Name: "-base64", Usage: "base64 output"},
+	{Name: "-out", TakesValue: true, Usage: "write the encoded output to FILE"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
 func printRandUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox rand [OPTION]... [NUM]")
 	fmt.Fprintln(w, "Generate random bytes.")
@@ -9,11 +9,16 @@ import (
 	"strconv"
 	"strings"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
 // sedCmd implements a subset of sed functionality
 func SedCmd(args []string) error {
+	return sedCmd(base.Stdio(), args)
+}
+
+func sedCmd(inv *base.Invocation, args []string) error {
 	var (
 		quiet       bool
 		inPlace     string
@@ -64,16 +69,21 @@ func SedCmd(args []string) error {
 doneFlags:
 
 	if showHelp {
-		printUsage(os.Stdout)
+		printUsage(inv.Stdout)
 		return nil
 	}
+	if inPlaceSeen {
+		if err := base.CheckWritable(inv, "sed -i"); err != nil {
+			return err
+		}
+	}
 
 	// Collect scripts from -e
 	scripts := append([]string{}, expressions...)
 
 	// Collect scripts from -f
 	if scriptFile != "" {
-		content, err := os.ReadFile(scriptFile)
+		content, err := os.ReadFile(inv.Path(scriptFile))
 		if err != nil {
 			return fmt.Errorf("cannot read script file %s: %w", scriptFile, err)
 		}
@@ -94,8 +104,8 @@ doneFlags:
 	}
 
 	if len(scripts) == 0 {
-		fmt.Fprintln(os.Stderr, "sed: no script provided")
-		printUsage(os.Stderr)
+		fmt.Fprintln(inv.Stderr, "sed: no script provided")
+		printUsage(inv.Stderr)
 		return fmt.Errorf("script required")
 	}
 
@@ -109,7 +119,7 @@ doneFlags:
 
 	// If no files, read from stdin
 	if len(files) == 0 {
-		if err := sedReader(os.Stdin, os.Stdout, commands, quiet); err != nil {
+		if err := sedReader(inv.Stdin, inv.Stdout, commands, quiet); err != nil {
 			return err
 		}
 		return nil
@@ -118,11 +128,18 @@ doneFlags:
 	// Process files
 	for _, file := range files {
 		if inPlaceSeen {
-			if err := sedFileInPlace(file, commands, quiet, inPlace); err != nil {
+			path := inv.Path(file)
+			err := sedFileInPlace(path, commands, quiet, inPlace)
+			var detail []string
+			if inPlace != "" {
+				detail = []string{"backup", path + inPlace}
+			}
+			base.Audit(inv, "edit", path, err, detail...)
+			if err != nil {
 				return err
 			}
 		} else {
-			if err := sedFile(file, os.Stdout, commands, quiet); err != nil {
+			if err := sedFile(inv, file, commands, quiet); err != nil {
 				return err
 			}
 		}
@@ -131,6 +148,15 @@ doneFlags:
 	return nil
 }
 
+// sedFlags declares sed's options for shell completion.
+var sedFlags = []base.Flag{
+	{Name: "-n", Usage: "suppress automatic printing"},
+	{Name: "-i", Usage: "edit files in place (-iSUFFIX keeps a backup)"},
+	{Name: "-e", TakesValue: true, Usage: "add SCRIPT to the commands"},
+	{Name: "-f", TakesValue: true, Usage: "add the commands in FILE"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
 func printUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox sed [OPTION]... [SCRIPT] [FILE...]")
 	fmt.Fprintln(w, "Stream editor for filtering and transforming text.")
@@ -501,17 +527,17 @@ func processReplacement(replacement string) string {
 	return result
 }
 
-func sedFile(filename string, out io.Writer, commands []sedCommand, quiet bool) error {
+func sedFile(inv *base.Invocation, filename string, commands []sedCommand, quiet bool) error {
 	if filename == "-" {
-		return sedReader(os.Stdin, out, commands, quiet)
+		return sedReader(inv.Stdin, inv.Stdout, commands, quiet)
 	}
-	file, err := os.Open(filename)
+	file, err := os.Open(inv.Path(filename))
 	if err != nil {
 		return fmt.Errorf("cannot open %s: %w", filename, err)
 	}
 	defer file.Close()
 
-	return sedReader(file, out, commands, quiet)
+	return sedReader(file, inv.Stdout, commands, quiet)
 }
 
 func sedFileInPlace(filename string, commands []sedCommand, quiet bool, backup string) error {
@@ -2,14 +2,19 @@ package text
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"strconv"
 	"strings"
 
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 )
 
 func SeqCmd(args []string) error {
+	return seqCmd(base.Stdio(), args)
+}
+
+func seqCmd(inv *base.Invocation, args []string) error {
 	var first, inc, last float64
 	var format string
 	var separator string
@@ -49,7 +54,7 @@ func SeqCmd(args []string) error {
 		case arg == "-w" || arg == "--equal-width":
 			equalWidth = true
 		case arg == "-h" || arg == "--help":
-			printSeqUsage(os.Stdout)
+			printSeqUsage(inv.Stdout)
 			return nil
 		case arg == "--":
 			// GNU-style end-of-options marker: everything after it is a
@@ -168,18 +173,22 @@ doneFlags:
 			break
 		}
 		if n > 0 {
-			fmt.Print(separator)
+			// Stop once the reader is gone (e.g. `seq 1 1e9 | head` under
+			// gobox sh) instead of generating the rest into a closed pipe.
+			if _, err := fmt.Fprint(inv.Stdout, separator); err != nil {
+				return err
+			}
 		}
 		switch {
 		case width > 0:
-			fmt.Print(formatSeqEqualWidth(cur, width, decimals))
+			fmt.Fprint(inv.Stdout, formatSeqEqualWidth(cur, width, decimals))
 		case formatExplicit:
-			fmt.Printf(format, cur)
+			fmt.Fprintf(inv.Stdout, format, cur)
 		default:
-			fmt.Print(strconv.FormatFloat(cur, 'f', decimals, 64))
+			fmt.Fprint(inv.Stdout, strconv.FormatFloat(cur, 'f', decimals, 64))
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(inv.Stdout)
 	return nil
 }
 
@@ -220,7 +229,15 @@ func formatSeqEqualWidth(v float64, width, decimals int) string {
 	return intPart + fracPart
 }
 
-func printSeqUsage(w *os.File) {
+// seqFlags declares seq's options for shell completion.
+var seqFlags = []base.Flag{
+	{Name: "-f", TakesValue: true, Usage: "printf-style FORMAT"}, {Name: "--format", TakesValue: true, Usage: "printf-style FORMAT"},
+	{Name: "-s", TakesValue: true, Usage: "separator between numbers"}, {Name: "--separator", TakesValue: true, Usage: "separator between numbers"},
+	{Name: "-w", Usage: "equalize width with leading zeros"}, {Name: "--equal-width", Usage: "equalize width with leading zeros"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
+func printSeqUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox seq [OPTIONS] [FIRST [INC]] LAST")
 	fmt.Fprintln(w, "Print sequences of numbers.")
 	fmt.Fprintln(w)
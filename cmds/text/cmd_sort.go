@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"math/rand"
 	"os"
@@ -59,6 +60,10 @@ var monthNames = map[string]time.Month{
 }
 
 func SortCmd(args []string) error {
+	return sortCmd(base.Stdio(), args)
+}
+
+func sortCmd(inv *base.Invocation, args []string) error {
 	cfg := sortConfig{key: 0}
 
 	i := 0
@@ -130,7 +135,7 @@ func SortCmd(args []string) error {
 		case strings.HasPrefix(arg, "--output="):
 			cfg.output = arg[len("--output="):]
 		case arg == "--help":
-			printSortUsage(os.Stdout)
+			printSortUsage(inv.Stdout)
 			return nil
 		case strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--"):
 			// Handle combined flags like -ru
@@ -167,14 +172,19 @@ func SortCmd(args []string) error {
 
 doneFlags:
 	files := args[i:]
+	if cfg.output != "" {
+		if err := base.CheckWritable(inv, "sort -o"); err != nil {
+			return err
+		}
+	}
 
 	// Read input
 	var lines []string
 	if len(files) == 0 {
-		lines = readLines(os.Stdin, cfg.zeroTerminated)
+		lines = readLines(inv.Stdin, cfg.zeroTerminated)
 	} else {
 		for _, file := range files {
-			f, err := os.Open(file)
+			f, err := os.Open(inv.Path(file))
 			if err != nil {
 				return fmt.Errorf("cannot open %s: %w", file, err)
 			}
@@ -189,7 +199,7 @@ doneFlags:
 		if len(files) > 0 {
 			sourceName = files[0]
 		}
-		return checkSorted(lines, cfg, sourceName)
+		return checkSorted(inv.Stderr, lines, cfg, sourceName)
 	}
 
 	// Sort
@@ -199,18 +209,19 @@ doneFlags:
 	}
 
 	// Output
-	var out io.Writer = os.Stdout
-	if cfg.output != "" {
-		f, err := os.Create(cfg.output)
-		if err != nil {
-			return fmt.Errorf("cannot create output file: %w", err)
-		}
-		out = f
-		defer f.Close()
+	if cfg.output == "" {
+		writeLines(inv.Stdout, sorted, cfg.zeroTerminated)
+		return nil
 	}
-
-	writeLines(out, sorted, cfg.zeroTerminated)
-	return nil
+	f, err := os.Create(inv.Path(cfg.output))
+	if err != nil {
+		base.Audit(inv, "write", inv.Path(cfg.output), err)
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+	writeLines(f, sorted, cfg.zeroTerminated)
+	err = f.Close()
+	base.Audit(inv, "write", f.Name(), err, "lines", strconv.Itoa(len(sorted)))
+	return err
 }
 
 func readLines(r io.Reader, zeroTerminated bool) []string {
@@ -419,7 +430,7 @@ func compareSortValues(vi, vj interface{}) int {
 	}
 }
 
-func checkSorted(lines []string, cfg sortConfig, sourceName string) error {
+func checkSorted(stderr io.Writer, lines []string, cfg sortConfig, sourceName string) error {
 	if len(lines) <= 1 {
 		return nil
 	}
@@ -443,13 +454,30 @@ func checkSorted(lines []string, cfg sortConfig, sourceName string) error {
 			disorder = cmp < 0
 		}
 		if disorder {
-			fmt.Fprintf(os.Stderr, "sort: %s: disorder: line %d\n", sourceName, i+1)
+			fmt.Fprintf(stderr, "sort: %s: disorder: line %d\n", sourceName, i+1)
 			return sortExitError{code: 1, err: errors.New("check failed")}
 		}
 	}
 	return nil
 }
 
+// sortFlags declares sort's options for shell completion. Its long options
+// only take an attached value (--key=NUM).
+var sortFlags = []base.Flag{
+	{Name: "-n", Usage: "sort by numeric value"}, {Name: "--numeric-sort", Usage: "sort by numeric value"},
+	{Name: "-r", Usage: "reverse order"}, {Name: "--reverse", Usage: "reverse order"},
+	{Name: "-k", TakesValue: true, Usage: "sort by column NUM"}, {Name: "--key", Usage: "sort by column NUM (--key=NUM)"},
+	{Name: "-t", TakesValue: true, Usage: "field separator"}, {Name: "--field-separator", Usage: "field separator (--field-separator=CHAR)"},
+	{Name: "-u", Usage: "remove duplicate lines"}, {Name: "--unique", Usage: "remove duplicate lines"},
+	{Name: "-M", Usage: "sort by month"}, {Name: "--month-sort", Usage: "sort by month"},
+	{Name: "-h", Usage: "sort human readable numbers"}, {Name: "--human-numeric-sort", Usage: "sort human readable numbers"},
+	{Name: "-R", Usage: "random sort"}, {Name: "--random-sort", Usage: "random sort"},
+	{Name: "-c", Usage: "check if sorted"}, {Name: "--check", Usage: "check if sorted"},
+	{Name: "-o", TakesValue: true, Usage: "write to FILE"}, {Name: "--output", Usage: "write to FILE (--output=FILE)"},
+	{Name: "-z", Usage: "lines end with a 0 byte"}, {Name: "--zero-terminated", Usage: "lines end with a 0 byte"},
+	{Name: "--help", Usage: "show help"},
+}
+
 func printSortUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox sort [OPTION]... [FILE]")
 	fmt.Fprintln(w, "Sort lines of text files.")
@@ -1,10 +1,13 @@
 package text
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"gobox/cmds/base"
 )
 
 // ============== NORMAL CASES TESTS ==============
@@ -909,6 +912,27 @@ func TestSortOutputFileOverwrite(t *testing.T) {
 	}
 }
 
+func TestSortOutputFileWritesAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "sorted.txt")
+	logPath := filepath.Join(dir, "audit.log")
+	inv := &base.Invocation{Stdin: strings.NewReader("b\na\n"), Env: []string{base.AuditEnv + "=" + logPath}}
+	if err := sortCmd(inv, []string{"-o", outputFile}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec base.AuditRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("audit line %q: %v", data, err)
+	}
+	if rec.Action != "write" || rec.Target != outputFile || rec.Detail["lines"] != "2" || rec.Outcome != "ok" {
+		t.Fatalf("unexpected audit record: %+v", rec)
+	}
+}
+
 func TestSortOutputRequiresArgument(t *testing.T) {
 	_, err := runSortCmd([]string{"-o"})
 	if err == nil {
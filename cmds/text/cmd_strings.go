@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"gobox/cmds/base"
 	"gobox/cmds/utils"
 	"io"
 	"os"
@@ -12,21 +13,25 @@ import (
 )
 
 func StringsCmd(args []string) error {
+	return stringsCmd(base.Stdio(), args)
+}
+
+func stringsCmd(inv *base.Invocation, args []string) error {
 	fsFlags := flag.NewFlagSet("strings", flag.ContinueOnError)
 	minLen := fsFlags.Int("n", 4, "minimum string length")
 	withFile := fsFlags.Bool("f", false, "print filename before each string")
 	offsetBase := fsFlags.String("t", "", "print offset in o, d, or x")
 	_ = fsFlags.Bool("a", false, "scan entire file")
 	fsFlags.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: gobox strings [OPTION]... [FILE]...")
-		fmt.Fprintln(os.Stderr, "Print printable character sequences from files.")
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -n N       minimum string length")
-		fmt.Fprintln(os.Stderr, "  -f         print filename before each string")
-		fmt.Fprintln(os.Stderr, "  -t BASE    print offset in o, d, or x")
-		fmt.Fprintln(os.Stderr, "  -a         scan entire file")
-		fmt.Fprintln(os.Stderr, "  -h, --help show this help")
+		fmt.Fprintln(inv.Stderr, "Usage: gobox strings [OPTION]... [FILE]...")
+		fmt.Fprintln(inv.Stderr, "Print printable character sequences from files.")
+		fmt.Fprintln(inv.Stderr)
+		fmt.Fprintln(inv.Stderr, "Options:")
+		fmt.Fprintln(inv.Stderr, "  -n N       minimum string length")
+		fmt.Fprintln(inv.Stderr, "  -f         print filename before each string")
+		fmt.Fprintln(inv.Stderr, "  -t BASE    print offset in o, d, or x")
+		fmt.Fprintln(inv.Stderr, "  -a         scan entire file")
+		fmt.Fprintln(inv.Stderr, "  -h, --help show this help")
 	}
 	if err := utils.ParseFlagSet(fsFlags, args); err != nil {
 		if err == flag.ErrHelp {
@@ -39,20 +44,20 @@ func StringsCmd(args []string) error {
 	}
 	files := fsFlags.Args()
 	if len(files) == 0 {
-		return stringsFromReader("-", os.Stdin, *minLen, *withFile, *offsetBase)
+		return stringsFromReader(inv.Stdout, "-", inv.Stdin, *minLen, *withFile, *offsetBase)
 	}
 	for _, file := range files {
 		if file == "-" {
-			if err := stringsFromReader("-", os.Stdin, *minLen, *withFile, *offsetBase); err != nil {
+			if err := stringsFromReader(inv.Stdout, "-", inv.Stdin, *minLen, *withFile, *offsetBase); err != nil {
 				return err
 			}
 			continue
 		}
-		f, err := os.Open(file)
+		f, err := os.Open(inv.Path(file))
 		if err != nil {
 			return err
 		}
-		err = stringsFromReader(file, f, *minLen, *withFile, *offsetBase)
+		err = stringsFromReader(inv.Stdout, file, f, *minLen, *withFile, *offsetBase)
 		_ = f.Close()
 		if err != nil {
 			return err
@@ -61,7 +66,7 @@ func StringsCmd(args []string) error {
 	return nil
 }
 
-func stringsFromReader(name string, r io.Reader, minLen int, withFile bool, offsetBase string) error {
+func stringsFromReader(w io.Writer, name string, r io.Reader, minLen int, withFile bool, offsetBase string) error {
 	br := bufio.NewReader(r)
 	var buf []rune
 	var start, offset int64
@@ -71,19 +76,19 @@ func stringsFromReader(name string, r io.Reader, minLen int, withFile bool, offs
 			return
 		}
 		if withFile {
-			fmt.Printf("%s: ", name)
+			fmt.Fprintf(w, "%s: ", name)
 		}
 		if offsetBase != "" {
 			switch offsetBase {
 			case "o":
-				fmt.Printf("%7s ", strconv.FormatInt(start, 8))
+				fmt.Fprintf(w, "%7s ", strconv.FormatInt(start, 8))
 			case "d":
-				fmt.Printf("%7d ", start)
+				fmt.Fprintf(w, "%7d ", start)
 			case "x":
-				fmt.Printf("%7s ", strconv.FormatInt(start, 16))
+				fmt.Fprintf(w, "%7s ", strconv.FormatInt(start, 16))
 			}
 		}
-		fmt.Println(string(buf))
+		fmt.Fprintln(w, string(buf))
 	}
 
 	for {
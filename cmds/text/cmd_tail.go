@@ -4,9 +4,9 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"os"
-	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
@@ -15,6 +15,10 @@ import (
 
 // TailCmdWithContext implements the tail command with context support
 func TailCmdWithContext(ctx context.Context, args []string) error {
+	return tailCmd(base.Stdio().WithContext(ctx), args)
+}
+
+func tailCmd(inv *base.Invocation, args []string) error {
 	var (
 		lines         = 10    // default number of lines
 		fromStart     = false // -n +N: output starting at line N (from the beginning)
@@ -111,7 +115,7 @@ func TailCmdWithContext(ctx context.Context, args []string) error {
 
 doneFlags:
 	if showHelp {
-		printTailUsage(os.Stdout)
+		printTailUsage(inv.Stdout)
 		return nil
 	}
 
@@ -122,7 +126,7 @@ doneFlags:
 	// ignores it otherwise. Without this, tailFileWithRetry would loop
 	// forever waiting for a file that will never be watched into existence.
 	if retry && !follow {
-		fmt.Fprintln(os.Stderr, "tail: warning: --retry ignored; --retry is useful only when following")
+		fmt.Fprintln(inv.Stderr, "tail: warning: --retry ignored; --retry is useful only when following")
 		retry = false
 	}
 
@@ -131,7 +135,7 @@ doneFlags:
 		if follow {
 			return fmt.Errorf("cannot follow stdin in follow mode")
 		}
-		if err := tailReader(os.Stdin, os.Stdout, lines, fromStart); err != nil {
+		if err := tailReader(inv.Stdin, inv.Stdout, lines, fromStart); err != nil {
 			return err
 		}
 		return nil
@@ -139,28 +143,39 @@ doneFlags:
 
 	// Follow mode
 	if follow {
-		return tailFollow(ctx, files, lines, fromStart, followByName, retry, quiet, multipleFiles, sleepInterval, pid)
+		return tailFollow(inv, files, lines, fromStart, followByName, retry, quiet, multipleFiles, sleepInterval, pid)
 	}
 
 	// Normal mode - process files
 	for _, file := range files {
 		if multipleFiles && !quiet {
-			fmt.Printf("==> %s <==\n", file)
+			fmt.Fprintf(inv.Stdout, "==> %s <==\n", file)
 		}
-		if err := tailFileWithRetry(file, os.Stdout, lines, fromStart, retry); err != nil {
+		if err := tailFileWithRetry(inv, file, lines, fromStart, retry); err != nil {
 			return err
 		}
 		if multipleFiles && !quiet && file != files[len(files)-1] {
-			fmt.Println()
+			fmt.Fprintln(inv.Stdout)
 		}
 	}
 
 	return nil
 }
 
-// tailCmd implements the tail command
+// TailCmd implements the tail command
 func TailCmd(args []string) error {
-	return TailCmdWithContext(context.Background(), args)
+	return tailCmd(base.Stdio(), args)
+}
+
+// tailFlags declares tail's options for shell completion.
+var tailFlags = []base.Flag{
+	{Name: "-n", TakesValue: true, Usage: "print the last NUM lines"}, {Name: "--lines", TakesValue: true, Usage: "print the last NUM lines"},
+	{Name: "-f", Usage: "output appended data as the file grows"}, {Name: "--follow", Usage: "output appended data (--follow=name follows by name)"},
+	{Name: "--retry", Usage: "keep trying to open a missing file"},
+	{Name: "-q", Usage: "never print file name headers"}, {Name: "--quiet", Usage: "never print file name headers"}, {Name: "--silent", Usage: "never print file name headers"},
+	{Name: "-s", TakesValue: true, Usage: "seconds between iterations"}, {Name: "--sleep-interval", TakesValue: true, Usage: "seconds between iterations"},
+	{Name: "--pid", Usage: "stop when process PID exits (--pid=PID)"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
 }
 
 func printTailUsage(w io.Writer) {
@@ -240,12 +255,13 @@ func tailReader(r io.Reader, w io.Writer, n int, fromStart bool) error {
 	return nil
 }
 
-func tailFileWithRetry(filename string, w io.Writer, n int, fromStart, retry bool) error {
+func tailFileWithRetry(inv *base.Invocation, filename string, n int, fromStart, retry bool) error {
+	w := inv.Stdout
 	if filename == "-" {
-		return tailReader(os.Stdin, w, n, fromStart)
+		return tailReader(inv.Stdin, w, n, fromStart)
 	}
 	for {
-		file, err := os.Open(filename)
+		file, err := os.Open(inv.Path(filename))
 		if err != nil {
 			if retry && os.IsNotExist(err) {
 				time.Sleep(100 * time.Millisecond)
@@ -282,11 +298,11 @@ func getFileInode(f *os.File) (uint64, uint64, error) {
 }
 
 // tailFollow implements the -f (follow) functionality
-func tailFollow(ctx context.Context, files []string, lines int, fromStart bool, followByName, retry, quiet, multipleFiles bool, sleepInterval float64, pid int) error {
-	// Set up signal handling for SIGINT
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
+// It runs until inv's context is cancelled (main cancels it on SIGINT/SIGTERM)
+// or the --pid process exits.
+func tailFollow(inv *base.Invocation, files []string, lines int, fromStart bool, followByName, retry, quiet, multipleFiles bool, sleepInterval float64, pid int) error {
+	ctx := inv.Ctx()
+	w := inv.Stdout
 	trackedFiles := make(map[string]*tailFileInfo)
 
 	finishChan := make(chan struct{})
@@ -315,7 +331,7 @@ func tailFollow(ctx context.Context, files []string, lines int, fromStart bool,
 
 	// Open initial files
 	for _, filename := range files {
-		f, err := os.Open(filename)
+		f, err := os.Open(inv.Path(filename))
 		if err != nil {
 			if retry {
 				// Will retry later
@@ -336,16 +352,16 @@ func tailFollow(ctx context.Context, files []string, lines int, fromStart bool,
 		}
 		// Print initial content: from line n with +N, else the last n lines.
 		if !quiet && multipleFiles {
-			fmt.Printf("==> %s <==\n", filename)
+			fmt.Fprintf(w, "==> %s <==\n", filename)
 		}
 		if fromStart {
 			if _, err := f.Seek(0, io.SeekStart); err == nil {
-				if err := tailReader(f, os.Stdout, lines, true); err != nil {
+				if err := tailReader(f, w, lines, true); err != nil {
 					f.Close()
 					return err
 				}
 			}
-		} else if err := tailFileReader(f, os.Stdout, lines); err != nil {
+		} else if err := tailFileReader(f, w, lines); err != nil {
 			f.Close()
 			return err
 		}
@@ -365,14 +381,6 @@ func tailFollow(ctx context.Context, files []string, lines int, fromStart bool,
 				}
 			}
 			return nil
-		case <-sigChan:
-			// Clean up and exit
-			for _, fi := range trackedFiles {
-				if fi.reader != nil {
-					fi.reader.Close()
-				}
-			}
-			return nil
 		case <-finishChan:
 			procFinished = true
 		case <-ticker.C:
@@ -393,7 +401,7 @@ func tailFollow(ctx context.Context, files []string, lines int, fromStart bool,
 				}
 				if fi.reader == nil && retry {
 					// Try to open the file
-					f, err := os.Open(fi.name)
+					f, err := os.Open(inv.Path(fi.name))
 					if err != nil {
 						continue
 					}
@@ -403,20 +411,20 @@ func tailFollow(ctx context.Context, files []string, lines int, fromStart bool,
 					fi.ino = ino
 					fi.dev = dev
 					if !quiet && multipleFiles {
-						fmt.Printf("==> %s <==\n", fi.name)
+						fmt.Fprintf(w, "==> %s <==\n", fi.name)
 					}
-					if err := tailFileReader(f, os.Stdout, lines); err != nil {
+					if err := tailFileReader(f, w, lines); err != nil {
 						return err
 					}
 					fi.offset = stat.Size()
 				}
 
 				if followByName {
-					if err := tailFollowByName(fi, os.Stdout, lines, quiet, multipleFiles); err != nil {
+					if err := tailFollowByName(inv, fi, lines, quiet, multipleFiles); err != nil {
 						return err
 					}
 				} else {
-					if err := tailFollowByFd(fi, os.Stdout, lines); err != nil {
+					if err := tailFollowByFd(fi, w, lines); err != nil {
 						return err
 					}
 				}
@@ -516,9 +524,10 @@ func tailFollowByFd(fi *tailFileInfo, w io.Writer, lines int) error {
 	return nil
 }
 
-func tailFollowByName(fi *tailFileInfo, w io.Writer, lines int, quiet, multipleFiles bool) error {
+func tailFollowByName(inv *base.Invocation, fi *tailFileInfo, lines int, quiet, multipleFiles bool) error {
+	w := inv.Stdout
 	// Reopen file to check for rotation
-	f, err := os.Open(fi.name)
+	f, err := os.Open(inv.Path(fi.name))
 	if err != nil {
 		return nil // File might not exist yet
 	}
@@ -539,7 +548,7 @@ func tailFollowByName(fi *tailFileInfo, w io.Writer, lines int, quiet, multipleF
 	if ino != fi.ino || dev != fi.dev {
 		// File was rotated, print separator and read last n lines from new file
 		if !quiet && multipleFiles {
-			fmt.Printf("==> %s (rotation) <==\n", fi.name)
+			fmt.Fprintf(w, "==> %s (rotation) <==\n", fi.name)
 		}
 		tailFileReader(f, w, lines)
 		fi.ino = ino
@@ -568,7 +577,7 @@ func tailFollowByName(fi *tailFileInfo, w io.Writer, lines int, quiet, multipleF
 	} else if currentSize < fi.offset {
 		// File was truncated
 		if !quiet && multipleFiles {
-			fmt.Printf("==> %s (truncated) <==\n", fi.name)
+			fmt.Fprintf(w, "==> %s (truncated) <==\n", fi.name)
 		}
 		tailFileReader(f, w, lines)
 		fi.offset = stat.Size()
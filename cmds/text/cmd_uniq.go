@@ -3,6 +3,7 @@ package text
 import (
 	"bufio"
 	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"os"
 	"strconv"
@@ -11,6 +12,10 @@ import (
 
 // uniqCmd implements the uniq command for filtering adjacent duplicate lines
 func UniqCmd(args []string) error {
+	return uniqCmd(base.Stdio(), args)
+}
+
+func uniqCmd(inv *base.Invocation, args []string) error {
 	var (
 		showCount    bool
 		showRepeated bool
@@ -103,18 +108,18 @@ doneFlags:
 	remaining := args[i:]
 
 	if showHelp {
-		printUniqUsage(os.Stdout)
+		printUniqUsage(inv.Stdout)
 		return nil
 	}
 
 	// If no files specified, read from stdin
 	if len(remaining) == 0 {
-		return uniqReader(os.Stdin, os.Stdout, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields)
+		return uniqReader(inv.Stdin, inv.Stdout, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields)
 	}
 
 	// Process files
 	for _, file := range remaining {
-		if err := uniqFile(file, os.Stdout, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields); err != nil {
+		if err := uniqFile(inv, file, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields); err != nil {
 			return err
 		}
 	}
@@ -122,6 +127,17 @@ doneFlags:
 	return nil
 }
 
+// uniqFlags declares uniq's options for shell completion.
+var uniqFlags = []base.Flag{
+	{Name: "-c", Usage: "prefix lines by the number of occurrences"}, {Name: "--count", Usage: "prefix lines by the number of occurrences"},
+	{Name: "-d", Usage: "only print duplicate lines"}, {Name: "--repeated", Usage: "only print duplicate lines"},
+	{Name: "-u", Usage: "only print unique lines"}, {Name: "--unique", Usage: "only print unique lines"},
+	{Name: "-i", Usage: "ignore case differences"}, {Name: "--ignore-case", Usage: "ignore case differences"},
+	{Name: "-w", TakesValue: true, Usage: "compare at most N characters"}, {Name: "--check-chars", TakesValue: true, Usage: "compare at most N characters"},
+	{Name: "-f", TakesValue: true, Usage: "skip the first N fields"}, {Name: "--skip-fields", TakesValue: true, Usage: "skip the first N fields"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
 func printUniqUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox uniq [OPTION]... [FILE]")
 	fmt.Fprintln(w, "Filter adjacent matching lines from FILE (or stdin)")
@@ -147,17 +163,17 @@ func printUniqUsage(w io.Writer) {
 	fmt.Fprintln(w, "  gobox uniq -f 2 file.txt")
 }
 
-func uniqFile(filename string, out io.Writer, showCount, showRepeated, showUnique, ignoreCase bool, checkChars, skipFields int) error {
+func uniqFile(inv *base.Invocation, filename string, showCount, showRepeated, showUnique, ignoreCase bool, checkChars, skipFields int) error {
 	if filename == "-" {
-		return uniqReader(os.Stdin, out, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields)
+		return uniqReader(inv.Stdin, inv.Stdout, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields)
 	}
-	file, err := os.Open(filename)
+	file, err := os.Open(inv.Path(filename))
 	if err != nil {
 		return fmt.Errorf("cannot open %s: %w", filename, err)
 	}
 	defer file.Close()
 
-	return uniqReader(file, out, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields)
+	return uniqReader(file, inv.Stdout, showCount, showRepeated, showUnique, ignoreCase, checkChars, skipFields)
 }
 
 func uniqReader(r io.Reader, out io.Writer, showCount, showRepeated, showUnique, ignoreCase bool, checkChars, skipFields int) error {
@@ -3,6 +3,7 @@ package text
 import (
 	"bufio"
 	"fmt"
+	"gobox/cmds/base"
 	"io"
 	"os"
 	"strings"
@@ -30,6 +31,10 @@ type wcResult struct {
 }
 
 func WcCmd(args []string) error {
+	return wcCmd(base.Stdio(), args)
+}
+
+func wcCmd(inv *base.Invocation, args []string) error {
 	flags := wcFlags{}
 	showHelp := false
 
@@ -89,7 +94,7 @@ doneFlags:
 	files := args[argIndex:]
 
 	if showHelp {
-		printWcUsage(os.Stdout)
+		printWcUsage(inv.Stdout)
 		return nil
 	}
 
@@ -101,14 +106,14 @@ doneFlags:
 
 	// If no files, read from stdin
 	if len(files) == 0 {
-		result, err := wcReader(os.Stdin, "")
+		result, err := wcReader(inv.Stdin, "")
 		if err != nil {
 			return err
 		}
 		results = append(results, result)
 	} else {
 		for _, filename := range files {
-			result, err := wcFile(filename)
+			result, err := wcFile(inv, filename)
 			if err != nil {
 				return err
 			}
@@ -125,23 +130,23 @@ doneFlags:
 
 	// Output results
 	for _, result := range results {
-		printWcResult(os.Stdout, result, flags, showAll)
+		printWcResult(inv.Stdout, result, flags, showAll)
 	}
 
 	// Print total line if multiple files
 	if len(results) > 1 {
 		totalResult.filename = "total"
-		printWcResult(os.Stdout, totalResult, flags, showAll)
+		printWcResult(inv.Stdout, totalResult, flags, showAll)
 	}
 
 	return nil
 }
 
-func wcFile(filename string) (wcResult, error) {
+func wcFile(inv *base.Invocation, filename string) (wcResult, error) {
 	if filename == "-" {
-		return wcReader(os.Stdin, "")
+		return wcReader(inv.Stdin, "")
 	}
-	file, err := os.Open(filename)
+	file, err := os.Open(inv.Path(filename))
 	if err != nil {
 		return wcResult{}, fmt.Errorf("cannot open %s: %w", filename, err)
 	}
@@ -265,6 +270,16 @@ func printWcResult(w io.Writer, result wcResult, flags wcFlags, showAll bool) {
 	fmt.Fprintf(w, " %s\n", filename)
 }
 
+// wcOptionFlags declares wc's options for shell completion.
+var wcOptionFlags = []base.Flag{
+	{Name: "-l", Usage: "print the line counts"}, {Name: "--lines", Usage: "print the line counts"},
+	{Name: "-w", Usage: "print the word counts"}, {Name: "--words", Usage: "print the word counts"},
+	{Name: "-c", Usage: "print the byte counts"}, {Name: "--bytes", Usage: "print the byte counts"},
+	{Name: "-m", Usage: "print the character counts"}, {Name: "--chars", Usage: "print the character counts"},
+	{Name: "-L", Usage: "print the maximum display width"}, {Name: "--max-line-length", Usage: "print the maximum display width"},
+	{Name: "-h", Usage: "show help"}, {Name: "--help", Usage: "show help"},
+}
+
 func printWcUsage(w io.Writer) {
 	fmt.Fprintln(w, "Usage: gobox wc [OPTION]... [FILE]...")
 	fmt.Fprintln(w, "Print line, word, and byte counts for each FILE.")
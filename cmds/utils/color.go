@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Modes accepted by --color.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Color roles. Each names one kind of highlighted text and is also its key
+// in GOBOX_COLORS and GREP_COLORS-style theme strings.
+const (
+	ColorMatch      = "ms" // grep: matched text
+	ColorFilename   = "fn" // grep: file name prefix
+	ColorLineNumber = "ln" // grep: line number prefix
+	ColorSeparator  = "se" // grep: ':' after file name and line number
+	ColorAdded      = "ad" // diff: added lines
+	ColorDeleted    = "de" // diff: deleted lines
+	ColorHeader     = "hd" // diff: file header lines
+	ColorHunk       = "hk" // diff: hunk and change-command lines
+	ColorOK         = "ok" // healthy state: running, established
+	ColorWarn       = "wa" // transitional or high: D state, TIME_WAIT, >=80%
+	ColorCritical   = "cr" // bad state: zombie, CLOSE_WAIT, >=90%
+	ColorListen     = "li" // listening sockets
+)
+
+// ColorThemeEnv names the environment variable holding gobox's own theme, in
+// the GREP_COLORS syntax: colon-separated ROLE=SGR entries such as
+// "ms=01;31:fn=35". An empty SGR disables that role.
+const ColorThemeEnv = "GOBOX_COLORS"
+
+// defaultColorTheme follows GNU grep's defaults for the roles it shares.
+var defaultColorTheme = map[string]string{
+	ColorMatch:      "01;31",
+	ColorFilename:   "35",
+	ColorLineNumber: "32",
+	ColorSeparator:  "36",
+	ColorAdded:      "32",
+	ColorDeleted:    "31",
+	ColorHeader:     "1",
+	ColorHunk:       "36",
+	ColorOK:         "32",
+	ColorWarn:       "33",
+	ColorCritical:   "31",
+	ColorListen:     "36",
+}
+
+// ParseColorMode validates a --color value. A bare --color arrives as "true"
+// and means auto, as do the GNU synonyms tty and if-tty.
+func ParseColorMode(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "true", ColorAuto, "tty", "if-tty":
+		return ColorAuto, nil
+	case ColorAlways, "yes", "force":
+		return ColorAlways, nil
+	case ColorNever, "no", "none", "false":
+		return ColorNever, nil
+	}
+	return "", fmt.Errorf("invalid color mode %q (want auto, always or never)", s)
+}
+
+// ColorFlag is the value of a --color[=WHEN] flag. It reports itself as a
+// boolean flag so a bare --color is accepted; WHEN must then be attached
+// with '='.
+type ColorFlag struct {
+	Mode string
+}
+
+func (c *ColorFlag) String() string {
+	if c == nil || c.Mode == "" {
+		return ColorAuto
+	}
+	return c.Mode
+}
+
+func (c *ColorFlag) Set(s string) error {
+	mode, err := ParseColorMode(s)
+	if err != nil {
+		return err
+	}
+	c.Mode = mode
+	return nil
+}
+
+func (c *ColorFlag) IsBoolFlag() bool { return true }
+
+// AddColorFlag registers --color on fs with the default mode auto.
+func AddColorFlag(fs *flag.FlagSet) *ColorFlag {
+	c := &ColorFlag{Mode: ColorAuto}
+	fs.Var(c, "color", "colorize output: auto, always or never")
+	return c
+}
+
+// Colorizer wraps text in SGR escape sequences for one output stream. A nil
+// or disabled Colorizer returns text unchanged, so renderers can call it
+// unconditionally.
+type Colorizer struct {
+	theme map[string]string
+}
+
+// NewColorizer decides whether output to w is colored under mode and loads
+// the theme. In auto mode color needs w to be a terminal, NO_COLOR to be
+// unset or empty and TERM not to be "dumb". The theme starts from the
+// defaults, then applies GOBOX_COLORS and each of themeVars (for example
+// GREP_COLORS) in order. getenv is usually inv.Getenv.
+func NewColorizer(w io.Writer, mode string, getenv func(string) string, themeVars ...string) *Colorizer {
+	switch mode {
+	case ColorNever:
+		return &Colorizer{}
+	case ColorAlways:
+	default:
+		if !IsTerminal(w) || getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" {
+			return &Colorizer{}
+		}
+	}
+	theme := make(map[string]string, len(defaultColorTheme))
+	for role, sgr := range defaultColorTheme {
+		theme[role] = sgr
+	}
+	for _, name := range append([]string{ColorThemeEnv}, themeVars...) {
+		applyColorTheme(theme, getenv(name))
+	}
+	return &Colorizer{theme: theme}
+}
+
+// applyColorTheme merges a GREP_COLORS-style spec into theme. Entries that
+// are not ROLE=SGR with a numeric SGR, such as grep's boolean "ne" and "rv"
+// capabilities, are ignored. grep's "mt" sets the match color.
+func applyColorTheme(theme map[string]string, spec string) {
+	for _, entry := range strings.Split(spec, ":") {
+		role, sgr, ok := strings.Cut(entry, "=")
+		if !ok || !validSGR(sgr) {
+			continue
+		}
+		if role == "mt" {
+			role = ColorMatch
+		}
+		theme[role] = sgr
+	}
+}
+
+func validSGR(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && s[i] != ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// Enabled reports whether c emits any escape sequences.
+func (c *Colorizer) Enabled() bool {
+	return c != nil && c.theme != nil
+}
+
+// Paint wraps s in the color for role. It returns s unchanged when c is
+// disabled, the role has no color or s is empty. The sequences match GNU
+// grep's, erasing to end of line so background colors do not bleed.
+func (c *Colorizer) Paint(role, s string) string {
+	if !c.Enabled() || s == "" {
+		return s
+	}
+	sgr := c.theme[role]
+	if sgr == "" {
+		return s
+	}
+	return "\x1b[" + sgr + "m\x1b[K" + s + "\x1b[m\x1b[K"
+}
+
+// PaintField paints the text of an already padded table cell, leaving the
+// surrounding spaces outside the escapes so column alignment is kept.
+func (c *Colorizer) PaintField(role, field string) string {
+	if !c.Enabled() {
+		return field
+	}
+	core := strings.TrimSpace(field)
+	if core == "" {
+		return field
+	}
+	start := strings.Index(field, core)
+	return field[:start] + c.Paint(role, core) + field[start+len(core):]
+}
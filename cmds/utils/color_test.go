@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+)
+
+func colorEnv(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestParseColorMode(t *testing.T) {
+	for in, want := range map[string]string{"": ColorAuto, "true": ColorAuto, "tty": ColorAuto, "ALWAYS": ColorAlways, "force": ColorAlways, "never": ColorNever, "none": ColorNever} {
+		got, err := ParseColorMode(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseColorMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
+
+func TestAddColorFlagAcceptsBareAndAttachedValues(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{nil, ColorAuto},
+		{[]string{"--color"}, ColorAuto},
+		{[]string{"--color=always"}, ColorAlways},
+		{[]string{"--color=never", "x"}, ColorNever},
+	} {
+		fs := flag.NewFlagSet("t", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		c := AddColorFlag(fs)
+		if err := ParseFlagSet(fs, tc.args); err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if c.Mode != tc.want {
+			t.Fatalf("%v: mode %q, want %q", tc.args, c.Mode, tc.want)
+		}
+	}
+}
+
+func TestNewColorizerAutoNeedsTerminal(t *testing.T) {
+	c := NewColorizer(&bytes.Buffer{}, ColorAuto, colorEnv(nil))
+	if c.Enabled() {
+		t.Fatal("auto mode colored a buffer")
+	}
+	if got := c.Paint(ColorMatch, "x"); got != "x" {
+		t.Fatalf("disabled Paint = %q", got)
+	}
+	var nilColorizer *Colorizer
+	if got := nilColorizer.PaintField(ColorOK, " x "); got != " x " {
+		t.Fatalf("nil PaintField = %q", got)
+	}
+	if NewColorizer(&bytes.Buffer{}, ColorNever, colorEnv(nil)).Enabled() {
+		t.Fatal("never mode enabled color")
+	}
+}
+
+func TestColorizerAlwaysUsesGrepSequences(t *testing.T) {
+	c := NewColorizer(&bytes.Buffer{}, ColorAlways, colorEnv(nil))
+	if got, want := c.Paint(ColorMatch, "hit"), "\x1b[01;31m\x1b[Khit\x1b[m\x1b[K"; got != want {
+		t.Fatalf("Paint = %q, want %q", got, want)
+	}
+	if got, want := c.PaintField(ColorWarn, "  D  "), "  \x1b[33m\x1b[KD\x1b[m\x1b[K  "; got != want {
+		t.Fatalf("PaintField = %q, want %q", got, want)
+	}
+	if got := c.Paint("", "plain"); got != "plain" {
+		t.Fatalf("role without a color painted: %q", got)
+	}
+}
+
+func TestColorizerThemeOverrides(t *testing.T) {
+	env := colorEnv(map[string]string{
+		ColorThemeEnv: "fn=34:ok=:ad=bogus",
+		"GREP_COLORS": "mt=01;32:ne:fn=33",
+	})
+	c := NewColorizer(&bytes.Buffer{}, ColorAlways, env, "GREP_COLORS")
+	if got, want := c.Paint(ColorMatch, "m"), "\x1b[01;32m\x1b[Km\x1b[m\x1b[K"; got != want {
+		t.Fatalf("mt override: %q, want %q", got, want)
+	}
+	if got, want := c.Paint(ColorFilename, "f"), "\x1b[33m\x1b[Kf\x1b[m\x1b[K"; got != want {
+		t.Fatalf("later theme variable should win: %q, want %q", got, want)
+	}
+	if got := c.Paint(ColorOK, "R"); got != "R" {
+		t.Fatalf("empty SGR should disable the role: %q", got)
+	}
+	if got, want := c.Paint(ColorAdded, "+"), "\x1b[32m\x1b[K+\x1b[m\x1b[K"; got != want {
+		t.Fatalf("invalid SGR should keep the default: %q, want %q", got, want)
+	}
+}
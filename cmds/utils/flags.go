@@ -20,6 +20,9 @@ type LongFlagClassifier func(name string) (defined, takesValue bool)
 // own, namely bundled boolean flags (-zv == -z -v) and attached values
 // (-n1 == -n 1). Commands should call this instead of fs.Parse(args).
 func ParseFlagSet(fs *flag.FlagSet, args []string) error {
+	if isDescribeRequest(args) {
+		return &FlagSetDescription{FlagSet: fs}
+	}
 	short, long := classifiersFor(fs)
 	expanded, err := ExpandShortClusters(args, short, long)
 	if err != nil {
@@ -33,6 +36,9 @@ func ParseFlagSet(fs *flag.FlagSet, args []string) error {
 // GNU getopt's default argument permutation. Use it for commands whose options
 // may legitimately follow a positional (e.g. `np --scan PORTS -W 1 HOST`).
 func ParseFlagSetPermute(fs *flag.FlagSet, args []string) error {
+	if isDescribeRequest(args) {
+		return &FlagSetDescription{FlagSet: fs}
+	}
 	short, long := classifiersFor(fs)
 	permuted := PermuteArgs(args, short, long)
 	expanded, err := ExpandShortClusters(permuted, short, long)
@@ -42,20 +48,39 @@ func ParseFlagSetPermute(fs *flag.FlagSet, args []string) error {
 	return fs.Parse(expanded)
 }
 
+// DescribeFlagsArg is the sole argument base passes to a command handler to
+// discover its options for shell completion. ParseFlagSet and
+// ParseFlagSetPermute answer it with a *FlagSetDescription error instead of
+// parsing, so the handler returns before doing any work.
+const DescribeFlagsArg = "--gobox-describe-flags"
+
+// FlagSetDescription carries the FlagSet a command would have parsed.
+type FlagSetDescription struct {
+	FlagSet *flag.FlagSet
+}
+
+func (d *FlagSetDescription) Error() string {
+	return "flag description requested"
+}
+
+func isDescribeRequest(args []string) bool {
+	return len(args) == 1 && args[0] == DescribeFlagsArg
+}
+
 func classifiersFor(fs *flag.FlagSet) (ShortFlagClassifier, LongFlagClassifier) {
 	short := func(c byte) (bool, bool) {
 		f := fs.Lookup(string(c))
 		if f == nil {
 			return false, false
 		}
-		return true, !isBoolFlag(f)
+		return true, !IsBoolFlag(f)
 	}
 	long := func(name string) (bool, bool) {
 		f := fs.Lookup(name)
 		if f == nil {
 			return false, false
 		}
-		return true, !isBoolFlag(f)
+		return true, !IsBoolFlag(f)
 	}
 	return short, long
 }
@@ -322,7 +347,8 @@ func LooksLikeFlag(tok string) bool {
 	return true
 }
 
-func isBoolFlag(f *flag.Flag) bool {
+// IsBoolFlag reports whether f is a boolean flag, i.e. takes no value.
+func IsBoolFlag(f *flag.Flag) bool {
 	if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
 		return bf.IsBoolFlag()
 	}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"flag"
 	"reflect"
 	"testing"
@@ -180,6 +181,20 @@ func TestParseFlagSetBundledAndAttached(t *testing.T) {
 	}
 }
 
+func TestParseFlagSetAnswersDescribeRequest(t *testing.T) {
+	for _, parse := range []func(*flag.FlagSet, []string) error{ParseFlagSet, ParseFlagSetPermute} {
+		fs := newTestFlagSet()
+		err := parse(fs, []string{DescribeFlagsArg})
+		var desc *FlagSetDescription
+		if !errors.As(err, &desc) || desc.FlagSet != fs {
+			t.Fatalf("expected a FlagSetDescription, got %v", err)
+		}
+		if fs.Parsed() {
+			t.Fatal("expected the FlagSet to stay unparsed")
+		}
+	}
+}
+
 func TestParseFlagSetLongNamesStillWork(t *testing.T) {
 	fs := newTestFlagSet()
 	if err := ParseFlagSet(fs, []string{"-name", "*.go", "-maxdepth", "2"}); err != nil {
@@ -257,14 +272,14 @@ func TestPermuteArgs(t *testing.T) {
 		if f == nil {
 			return false, false
 		}
-		return true, !isBoolFlag(f)
+		return true, !IsBoolFlag(f)
 	}
 	long := func(name string) (bool, bool) {
 		f := fs.Lookup(name)
 		if f == nil {
 			return false, false
 		}
-		return true, !isBoolFlag(f)
+		return true, !IsBoolFlag(f)
 	}
 	cases := []struct {
 		name string
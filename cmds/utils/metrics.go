@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Prometheus metric types.
+const (
+	MetricGauge   = "gauge"
+	MetricCounter = "counter"
+	MetricUntyped = "untyped"
+)
+
+// MetricsWriter renders metrics in the Prometheus text exposition format.
+// Each family is introduced once with Family, and its samples follow before
+// the next family starts, as the format requires.
+type MetricsWriter struct {
+	w    io.Writer
+	seen map[string]bool
+	err  error
+}
+
+// NewMetricsWriter returns a MetricsWriter writing to w.
+func NewMetricsWriter(w io.Writer) *MetricsWriter {
+	return &MetricsWriter{w: w, seen: map[string]bool{}}
+}
+
+// Family writes the HELP and TYPE lines of name; a repeated name is ignored,
+// so collectors may call it unconditionally before each group of samples.
+func (m *MetricsWriter) Family(name, typ, help string) {
+	if m.seen[name] {
+		return
+	}
+	m.seen[name] = true
+	m.printf("# HELP %s %s\n# TYPE %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help), name, typ)
+}
+
+// Sample writes one sample of name. labels are name/value pairs.
+func (m *MetricsWriter) Sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatMetricValue(value))
+	b.WriteByte('\n')
+	m.printf("%s", b.String())
+}
+
+// Err returns the first write error.
+func (m *MetricsWriter) Err() error {
+	return m.err
+}
+
+func (m *MetricsWriter) printf(format string, args ...any) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, format, args...)
+}
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// MetricName makes s a valid metric name component: characters outside
+// [a-zA-Z0-9_] become underscores and runs of them collapse, so a kernel
+// field such as "Active(anon)" becomes "Active_anon".
+func MetricName(s string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range s {
+		ok := r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		if !ok {
+			if !underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			underscore = true
+			continue
+		}
+		b.WriteRune(r)
+		underscore = r == '_'
+	}
+	return strings.TrimRight(b.String(), "_")
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMetricsWriterFormat(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMetricsWriter(&buf)
+	m.Family("gobox_x", MetricGauge, "Line one\nline two.")
+	m.Sample("gobox_x", 1.5, "dev", `a"b\c`)
+	m.Family("gobox_x", MetricGauge, "ignored")
+	m.Sample("gobox_x", math.Inf(1))
+	if err := m.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP gobox_x Line one\\nline two.\n# TYPE gobox_x gauge\n" +
+		"gobox_x{dev=\"a\\\"b\\\\c\"} 1.5\ngobox_x +Inf\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+	if strings.Count(buf.String(), "# TYPE") != 1 {
+		t.Fatalf("family repeated: %q", buf.String())
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	for in, want := range map[string]string{"Active(anon)": "Active_anon", "Ip6InOctets": "Ip6InOctets", "a--b__c": "a_b__c", "(x)": "x"} {
+		if got := MetricName(in); got != want {
+			t.Fatalf("MetricName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
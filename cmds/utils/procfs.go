@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"path/filepath"
+
+	"gobox/pkg/netfs"
+	"gobox/pkg/procfs"
+)
+
+// Default mount points of procfs and sysfs.
+const (
+	DefaultProcRoot = "/proc"
+	DefaultSysRoot  = "/sys"
+)
+
+// Roots are the procfs and sysfs trees the Linux collectors read. gobox
+// --proc-root/--sys-root (GOBOX_PROCFS/GOBOX_SYSFS) point them at a host's
+// /proc mounted elsewhere, another container's, or a captured fixture tree.
+// The zero value reads /proc and /sys.
+type Roots struct {
+	proc, sys string
+}
+
+// NewRoots returns roots reading procfs under proc and sysfs under sys; ""
+// keeps /proc or /sys.
+func NewRoots(proc, sys string) Roots {
+	var r Roots
+	if proc != "" {
+		r.proc = filepath.Clean(proc)
+	}
+	if sys != "" {
+		r.sys = filepath.Clean(sys)
+	}
+	return r
+}
+
+// Proc returns the procfs root.
+func (r Roots) Proc() string {
+	if r.proc == "" {
+		return DefaultProcRoot
+	}
+	return r.proc
+}
+
+// Sys returns the sysfs root.
+func (r Roots) Sys() string {
+	if r.sys == "" {
+		return DefaultSysRoot
+	}
+	return r.sys
+}
+
+// ProcPath joins elem onto the procfs root: ProcPath("meminfo") is
+// /proc/meminfo by default.
+func (r Roots) ProcPath(elem ...string) string {
+	return filepath.Join(append([]string{r.Proc()}, elem...)...)
+}
+
+// SysPath joins elem onto the sysfs root: SysPath("class", "net") is
+// /sys/class/net by default.
+func (r Roots) SysPath(elem ...string) string {
+	return filepath.Join(append([]string{r.Sys()}, elem...)...)
+}
+
+// ProcSelf names the process whose view the collectors take of per-process
+// state (mount table, cgroup, network tables). That is gobox itself under
+// /proc, but under an alternate root the subject is the inspected host or
+// container, so it is that procfs's PID 1: ROOT/self would still resolve to
+// gobox.
+func (r Roots) ProcSelf() string {
+	if r.Proc() == DefaultProcRoot {
+		return "self"
+	}
+	return "1"
+}
+
+// ProcNetPath returns the path of the network table name (tcp, route, dev,
+// ...): /proc/net/NAME by default, ROOT/1/net/NAME under an alternate root.
+func (r Roots) ProcNetPath(name string) string {
+	if r.Proc() == DefaultProcRoot {
+		return filepath.Join(DefaultProcRoot, "net", name)
+	}
+	return r.ProcPath(r.ProcSelf(), "net", name)
+}
+
+// HostPath maps an absolute path as seen by the inspected system onto the
+// local filesystem: unchanged by default, ROOT/1/root/PATH under an
+// alternate procfs root, so statfs reaches the mounts listed in its
+// mountinfo.
+func (r Roots) HostPath(path string) string {
+	if r.Proc() == DefaultProcRoot {
+		return path
+	}
+	return r.ProcPath(r.ProcSelf(), "root", path)
+}
+
+// ProcFS returns the procfs tree collectors read.
+func (r Roots) ProcFS() procfs.FS {
+	return procfs.NewFS(r.Proc())
+}
+
+// SelfProc returns the process named by ProcSelf.
+func (r Roots) SelfProc() procfs.Proc {
+	if r.Proc() == DefaultProcRoot {
+		return r.ProcFS().Self()
+	}
+	return r.ProcFS().Proc(1)
+}
+
+// NetFS returns the network tables ProcNetPath names.
+func (r Roots) NetFS() netfs.FS {
+	return netfs.NewFS(filepath.Dir(r.ProcNetPath("tcp")))
+}
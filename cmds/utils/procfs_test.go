@@ -0,0 +1,29 @@
+package utils
+
+import "testing"
+
+func TestProcAndSysRoots(t *testing.T) {
+	var def Roots
+	if def.ProcPath("meminfo") != "/proc/meminfo" || def.ProcNetPath("tcp") != "/proc/net/tcp" || def.HostPath("/var") != "/var" {
+		t.Fatalf("unexpected default paths %q %q %q", def.ProcPath("meminfo"), def.ProcNetPath("tcp"), def.HostPath("/var"))
+	}
+	if def.ProcPath(def.ProcSelf(), "mountinfo") != "/proc/self/mountinfo" || def.SysPath("class", "net") != "/sys/class/net" {
+		t.Fatalf("unexpected default paths %q %q", def.ProcPath(def.ProcSelf(), "mountinfo"), def.SysPath("class", "net"))
+	}
+	if NewRoots("", "") != def {
+		t.Fatal("expected empty roots to keep /proc and /sys")
+	}
+
+	host := NewRoots("/host/proc/", "/host/sys")
+	for got, want := range map[string]string{
+		host.ProcPath("meminfo"):                    "/host/proc/meminfo",
+		host.ProcPath(host.ProcSelf(), "mountinfo"): "/host/proc/1/mountinfo",
+		host.ProcNetPath("tcp"):                     "/host/proc/1/net/tcp",
+		host.HostPath("/var"):                       "/host/proc/1/root/var",
+		host.SysPath("class", "net"):                "/host/sys/class/net",
+	} {
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Output formats accepted by --output. OutputText keeps each command's own
+// aligned table; the others render a Table.
+const (
+	OutputText   = "text"
+	OutputJSON   = "json"
+	OutputNDJSON = "ndjson"
+	OutputCSV    = "csv"
+	OutputTSV    = "tsv"
+)
+
+// ParseOutputFormat validates a --output value. An empty value means text.
+func ParseOutputFormat(s string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(s)); f {
+	case "", OutputText:
+		return OutputText, nil
+	case OutputJSON, OutputNDJSON, OutputCSV, OutputTSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid output format %q (want text, json, ndjson, csv or tsv)", s)
+}
+
+// IsStructuredOutput reports whether format asks for a machine-readable
+// rendering rather than the command's text table.
+func IsStructuredOutput(format string) bool {
+	return format != "" && format != OutputText
+}
+
+// Table is a typed tabular result for structured --output formats. It does
+// not render aligned text: the text tables reproduce each native tool's
+// layout (units, headers, truncation, colour), which raw values and
+// snake_case fields cannot express. Commands fill it from the same rows their
+// text renderer prints, keeping raw values (bytes, counts, percentages as
+// numbers) instead of formatted text. Values must be strings, bools, integer
+// or float types, or nil for a missing field.
+type Table struct {
+	// Columns are the stable snake_case field names.
+	Columns []string
+	Rows    [][]any
+}
+
+// NewTable returns an empty table with the given field names.
+func NewTable(columns ...string) *Table {
+	return &Table{Columns: columns}
+}
+
+// Append adds a row; it must have one value per column.
+func (t *Table) Append(values ...any) {
+	if len(values) != len(t.Columns) {
+		panic(fmt.Sprintf("table row has %d values, want %d", len(values), len(t.Columns)))
+	}
+	t.Rows = append(t.Rows, values)
+}
+
+// Render writes the table in a structured format. Text output stays with each
+// command's own renderer, so OutputText is rejected here.
+func (t *Table) Render(w io.Writer, format string) error {
+	return NewTableWriter(w, format).Write(t)
+}
+
+// TableWriter renders successive tables with the same columns, as produced
+// by sampling commands (iostat, ifstat, top -b, netstat -c). CSV and TSV
+// print the header once; JSON emits one array per table and NDJSON simply
+// continues the stream.
+type TableWriter struct {
+	w           io.Writer
+	format      string
+	wroteHeader bool
+}
+
+func NewTableWriter(w io.Writer, format string) *TableWriter {
+	return &TableWriter{w: w, format: format}
+}
+
+func (tw *TableWriter) Write(t *Table) error {
+	header := !tw.wroteHeader
+	tw.wroteHeader = true
+	switch tw.format {
+	case OutputJSON:
+		return t.renderJSON(tw.w, false)
+	case OutputNDJSON:
+		return t.renderJSON(tw.w, true)
+	case OutputCSV:
+		return t.renderCSV(tw.w, header)
+	case OutputTSV:
+		return t.renderTSV(tw.w, header)
+	}
+	return fmt.Errorf("invalid structured output format %q", tw.format)
+}
+
+// renderJSON writes an array of objects, or one object per line for NDJSON.
+// Keys follow column order so output is stable and diffable.
+func (t *Table) renderJSON(w io.Writer, lines bool) error {
+	var buf bytes.Buffer
+	if !lines {
+		if len(t.Rows) == 0 {
+			buf.WriteString("[]\n")
+			_, err := w.Write(buf.Bytes())
+			return err
+		}
+		buf.WriteString("[\n")
+	}
+	for r, row := range t.Rows {
+		if !lines {
+			buf.WriteString("  ")
+		}
+		buf.WriteByte('{')
+		for i, value := range row {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(t.Columns[i])
+			buf.Write(key)
+			buf.WriteByte(':')
+			if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				value = nil
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buf.Write(encoded)
+		}
+		buf.WriteByte('}')
+		if !lines && r < len(t.Rows)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	if !lines {
+		buf.WriteString("]\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (t *Table) renderCSV(w io.Writer, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(t.Columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = scalarString(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tsvEscaper keeps every record on one line with exactly len(Columns) fields.
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func (t *Table) renderTSV(w io.Writer, header bool) error {
+	var buf bytes.Buffer
+	if header {
+		buf.WriteString(strings.Join(t.Columns, "\t"))
+		buf.WriteByte('\n')
+	}
+	for _, row := range t.Rows {
+		for i, value := range row {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			buf.WriteString(tsvEscaper.Replace(scalarString(value)))
+		}
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func scalarString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func sampleTable() *Table {
+	t := NewTable("name", "size_bytes", "use_percent", "note")
+	t.Append("root", int64(1024), 12.5, nil)
+	t.Append("a,b", uint64(0), math.NaN(), "tab\there")
+	return t
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for in, want := range map[string]string{"": OutputText, "TEXT": OutputText, "json": OutputJSON, " csv ": OutputCSV, "ndjson": OutputNDJSON, "tsv": OutputTSV} {
+		got, err := ParseOutputFormat(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseOutputFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseOutputFormat("xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestTableRenderJSONKeepsColumnOrderAndRawNumbers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleTable().Render(&buf, OutputJSON); err != nil {
+		t.Fatal(err)
+	}
+	want := "[\n" +
+		`  {"name":"root","size_bytes":1024,"use_percent":12.5,"note":null},` + "\n" +
+		`  {"name":"a,b","size_bytes":0,"use_percent":null,"note":"tab\there"}` + "\n" +
+		"]\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected json:\n%s", buf.String())
+	}
+}
+
+func TestTableRenderEmptyJSONIsArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewTable("a").Render(&buf, OutputJSON); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Fatalf("expected empty array, got %q", buf.String())
+	}
+}
+
+func TestTableRenderNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleTable().Render(&buf, OutputNDJSON); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != `{"name":"root","size_bytes":1024,"use_percent":12.5,"note":null}` {
+		t.Fatalf("unexpected ndjson %q", buf.String())
+	}
+}
+
+func TestTableRenderCSVAndTSV(t *testing.T) {
+	var csvBuf, tsvBuf bytes.Buffer
+	if err := sampleTable().Render(&csvBuf, OutputCSV); err != nil {
+		t.Fatal(err)
+	}
+	if want := "name,size_bytes,use_percent,note\nroot,1024,12.5,\n\"a,b\",0,NaN,tab\there\n"; csvBuf.String() != want {
+		t.Fatalf("unexpected csv %q", csvBuf.String())
+	}
+	if err := sampleTable().Render(&tsvBuf, OutputTSV); err != nil {
+		t.Fatal(err)
+	}
+	if want := "name\tsize_bytes\tuse_percent\tnote\nroot\t1024\t12.5\t\na,b\t0\tNaN\ttab\\there\n"; tsvBuf.String() != want {
+		t.Fatalf("unexpected tsv %q", tsvBuf.String())
+	}
+}
+
+func TestTableWriterPrintsHeaderOnce(t *testing.T) {
+	var buf bytes.Buffer
+	tw := NewTableWriter(&buf, OutputCSV)
+	for i := 0; i < 2; i++ {
+		table := NewTable("sample", "value")
+		table.Append(i+1, 0.5)
+		if err := tw.Write(table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "sample,value\n1,0.5\n2,0.5\n"; buf.String() != want {
+		t.Fatalf("unexpected stream %q", buf.String())
+	}
+}
+
+func TestTableWriterRejectsText(t *testing.T) {
+	if err := NewTableWriter(&bytes.Buffer{}, OutputText).Write(NewTable("a")); err == nil {
+		t.Fatal("expected text format to be rejected")
+	}
+}
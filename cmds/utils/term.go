@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// MakeRaw switches the terminal on fd to non-canonical, no-echo input and
+// makes reads non-blocking, returning the previous state for
+// RestoreTerminal. Signals such as Ctrl-C still work.
+func MakeRaw(fd int) (*syscall.Termios, error) {
+	state, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	raw := *state
+	raw.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	_ = syscall.SetNonblock(fd, true)
+	return state, nil
+}
+
+// RestoreTerminal undoes MakeRaw.
+func RestoreTerminal(fd int, state *syscall.Termios) error {
+	_ = syscall.SetNonblock(fd, false)
+	if state == nil {
+		return nil
+	}
+	return setTermios(fd, state)
+}
+
+func getTermios(fd int) (*syscall.Termios, error) {
+	state := &syscall.Termios{}
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(state)), 0, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return state, nil
+}
+
+func setTermios(fd int, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(state)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Key is one keypress: a character typed as itself ("q", " ") or one of
+// the named keys below.
+type Key string
+
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyEnter     Key = "enter"
+	KeyBackspace Key = "backspace"
+	KeyEscape    Key = "esc"
+)
+
+// escapeKeys maps the VT100/xterm sequences following ESC to named keys.
+var escapeKeys = map[string]Key{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[4~": KeyEnd, "[7~": KeyHome, "[8~": KeyEnd,
+	"[5~": KeyPageUp, "[6~": KeyPageDown,
+}
+
+// ReadKeys reads keypresses from fd, as set up by MakeRaw, and passes each
+// to handle until handle returns false, stop is closed or the read fails.
+// Unknown escape sequences are dropped; an ESC with nothing after it is
+// KeyEscape.
+func ReadKeys(fd int, stop <-chan struct{}, handle func(Key) bool) {
+	buf := make([]byte, 1)
+	// read returns the next byte; ok is false when none arrived within
+	// tries polls, or when reading failed (failed is then set).
+	failed := false
+	read := func(tries int) (byte, bool) {
+		for {
+			select {
+			case <-stop:
+				failed = true
+				return 0, false
+			default:
+			}
+			n, err := syscall.Read(fd, buf)
+			if err != nil && err != syscall.EAGAIN && err != syscall.EWOULDBLOCK {
+				failed = true
+				return 0, false
+			}
+			if err == nil && n == 1 {
+				return buf[0], true
+			}
+			if tries--; tries == 0 {
+				return 0, false
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	for {
+		ch, ok := read(-1)
+		if !ok {
+			return
+		}
+		key := Key(string(rune(ch)))
+		switch ch {
+		case '\r', '\n':
+			key = KeyEnter
+		case 127, '\b':
+			key = KeyBackspace
+		case 27:
+			seq := []byte{}
+			for len(seq) < 4 {
+				next, ok := read(5)
+				if !ok {
+					break
+				}
+				seq = append(seq, next)
+				if len(seq) > 1 && (next == '~' || (next >= 'A' && next <= 'Z')) {
+					break
+				}
+			}
+			if failed {
+				return
+			}
+			if len(seq) == 0 {
+				key = KeyEscape
+			} else if named, ok := escapeKeys[string(seq)]; ok {
+				key = named
+			} else {
+				continue
+			}
+		}
+		if !handle(key) {
+			return
+		}
+	}
+}
+
+// OnResize calls redraw, from its own goroutine, each time the terminal is
+// resized (SIGWINCH). The returned stop unregisters it; once stop returns,
+// redraw is no longer called.
+func OnResize(redraw func()) (stop func()) {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-winch:
+				redraw()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(winch)
+		close(done)
+		<-exited
+	}
+}
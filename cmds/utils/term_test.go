@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReadKeysDecodesSequences(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, err := w.Write([]byte("j\x1b[A\r\x7f\x1b[6~\x1bOH\x1b[9~x\x1b")); err != nil {
+		t.Fatal(err)
+	}
+
+	// MakeRaw leaves the terminal non-blocking; a lone ESC relies on it.
+	fd := int(r.Fd())
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		t.Fatal(err)
+	}
+	var got []Key
+	ReadKeys(fd, make(chan struct{}), func(k Key) bool {
+		got = append(got, k)
+		return k != KeyEscape
+	})
+	want := []Key{"j", KeyUp, KeyEnter, KeyBackspace, KeyPageDown, KeyHome, "x", KeyEscape}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestReadKeysStops(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	stop := make(chan struct{})
+	close(stop)
+	ReadKeys(int(r.Fd()), stop, func(Key) bool {
+		t.Fatal("no key was typed")
+		return false
+	})
+}
+
+func TestOnResizeRedrawsUntilStopped(t *testing.T) {
+	calls := make(chan struct{}, 1)
+	stop := OnResize(func() { calls <- struct{}{} })
+	if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-calls:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGWINCH did not trigger a redraw")
+	}
+	stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-calls:
+		t.Fatal("redraw called after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
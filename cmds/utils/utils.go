@@ -3,43 +3,48 @@ package utils
 import (
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"syscall"
 	"unsafe"
 )
 
-// IsTerminal returns true if the given writer is a terminal.
+// IsTerminal returns true if the given writer is a terminal. Only writers
+// backed by a file descriptor can be; character devices such as /dev/null
+// are not terminals.
 func IsTerminal(w io.Writer) bool {
-	fi, err := os.Stdout.Stat()
-	if err != nil {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
 		return false
 	}
-	return (fi.Mode() & os.ModeCharDevice) != 0
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(&termios)), 0, 0, 0)
+	return errno == 0
 }
 
-// StdoutWidth returns the current stdout terminal width when available.
-func StdoutWidth() (int, bool) {
-	width, _, ok := StdoutSize()
+// StdoutWidth returns the terminal width of w when available.
+func StdoutWidth(w io.Writer, getenv func(string) string) (int, bool) {
+	width, _, ok := StdoutSize(w, getenv)
 	return width, ok
 }
 
-// StdoutHeight returns the current stdout terminal height when available.
-func StdoutHeight() (int, bool) {
-	_, height, ok := StdoutSize()
+// StdoutHeight returns the terminal height of w when available.
+func StdoutHeight(w io.Writer, getenv func(string) string) (int, bool) {
+	_, height, ok := StdoutSize(w, getenv)
 	return height, ok
 }
 
-// StdoutSize returns the current stdout terminal width and height when available.
-func StdoutSize() (int, int, bool) {
+// StdoutSize returns the terminal width and height of w when available.
+// COLUMNS and LINES, read through getenv (usually inv.Getenv), take
+// precedence over the size the terminal reports; w is usually inv.Stdout.
+func StdoutSize(w io.Writer, getenv func(string) string) (int, int, bool) {
 	width := 0
 	height := 0
-	if cols := os.Getenv("COLUMNS"); cols != "" {
+	if cols := getenv("COLUMNS"); cols != "" {
 		if n, err := strconv.Atoi(cols); err == nil && n > 0 {
 			width = n
 		}
 	}
-	if lines := os.Getenv("LINES"); lines != "" {
+	if lines := getenv("LINES"); lines != "" {
 		if n, err := strconv.Atoi(lines); err == nil && n > 0 {
 			height = n
 		}
@@ -54,7 +59,10 @@ func StdoutSize() (int, int, bool) {
 		Ypixel uint16
 	}
 	ws := &winsize{}
-	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(ws)))
+	errno := syscall.ENOTTY
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(ws)))
+	}
 	if errno != 0 {
 		if width > 0 || height > 0 {
 			return width, height, true
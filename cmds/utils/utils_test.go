@@ -1,7 +1,40 @@
 package utils
 
-import "testing"
+import (
+	"bytes"
+	"os"
+	"testing"
+)
 
 func TestIsTerminal(t *testing.T) {
 	_ = IsTerminal(nil)
+	if IsTerminal(&bytes.Buffer{}) {
+		t.Fatal("a buffer is not a terminal")
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer devNull.Close()
+	if IsTerminal(devNull) {
+		t.Fatal("/dev/null is a character device but not a terminal")
+	}
+}
+
+func TestStdoutSizeUsesGivenWriterAndEnv(t *testing.T) {
+	t.Setenv("COLUMNS", "7")
+	t.Setenv("LINES", "3")
+	env := map[string]string{}
+	getenv := func(name string) string { return env[name] }
+	if _, _, ok := StdoutSize(&bytes.Buffer{}, getenv); ok {
+		t.Fatal("expected no size for a buffer without COLUMNS/LINES in the given env")
+	}
+	env["COLUMNS"] = "100"
+	if width, height, ok := StdoutSize(&bytes.Buffer{}, getenv); !ok || width != 100 || height != 0 {
+		t.Fatalf("expected 100x0, got %dx%d ok=%v", width, height, ok)
+	}
+	env["LINES"] = "30"
+	if width, height, ok := StdoutSize(&bytes.Buffer{}, getenv); !ok || width != 100 || height != 30 {
+		t.Fatalf("expected 100x30, got %dx%d ok=%v", width, height, ok)
+	}
 }
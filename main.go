@@ -1,33 +1,123 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"gobox/cmds/base"
 	_ "gobox/cmds/disk"
 	_ "gobox/cmds/fs"
 	_ "gobox/cmds/net"
 	_ "gobox/cmds/proc"
+	_ "gobox/cmds/shell"
 	_ "gobox/cmds/text"
+	"gobox/cmds/utils"
 )
 
-type cliErrorSilencer interface {
-	SuppressCLIError() bool
+// interruptGrace is how long a command gets to unwind after SIGINT/SIGTERM
+// cancels its context before gobox exits anyway.
+const interruptGrace = 500 * time.Millisecond
+
+// releaseSignals hands SIGINT and SIGTERM back to a command that handles
+// them itself (base.WithOwnSignals); main sets it once it catches them.
+var releaseSignals = func() {}
+
+func main() {
+	ctx, interrupted, release := signalContext()
+	releaseSignals = release
+	inv := &base.Invocation{Context: ctx, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	code := runInvocation(inv, commandArgs(os.Args))
+	if sig := interrupted(); sig != nil {
+		code = signalExitCode(sig)
+	}
+	os.Exit(code)
 }
 
-type cliExitCoder interface {
-	ExitCode() int
+// signalContext returns a context cancelled on the first SIGINT or SIGTERM.
+// After that the default disposition is restored, so a second signal kills
+// the process outright, and commands that ignore cancellation are cut off
+// after interruptGrace with the conventional 128+signo exit status. The
+// returned release stops watching for the signals.
+func signalContext() (context.Context, func() os.Signal, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	caught := make(chan os.Signal, 1)
+	go func() {
+		sig := <-sigCh
+		signal.Stop(sigCh)
+		caught <- sig
+		cancel()
+		time.Sleep(interruptGrace)
+		os.Exit(signalExitCode(sig))
+	}()
+	interrupted := func() os.Signal {
+		select {
+		case sig := <-caught:
+			return sig
+		default:
+			return nil
+		}
+	}
+	return ctx, interrupted, func() { signal.Stop(sigCh) }
 }
 
-func main() {
-	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 130
+}
+
+// commandArgs maps the process argv onto run's argument list. When gobox is
+// invoked through a symlink or hardlink named after a registered command
+// (ps, grep, curl, ...), the link name becomes the subcommand, BusyBox style.
+func commandArgs(argv []string) []string {
+	if len(argv) == 0 {
+		return nil
+	}
+	name := strings.TrimSuffix(filepath.Base(argv[0]), ".exe")
+	if name != "gobox" && !strings.HasPrefix(name, "gobox-") {
+		if _, ok := base.Lookup(name); ok {
+			return append([]string{name}, argv[1:]...)
+		}
+	}
+	return argv[1:]
 }
 
 func run(args []string, stdout, stderr io.Writer) int {
+	return runInvocation(&base.Invocation{Stdin: os.Stdin, Stdout: stdout, Stderr: stderr}, args)
+}
+
+func runInvocation(inv *base.Invocation, args []string) int {
+	stdout, stderr := inv.Stdout, inv.Stderr
+	args, opts, ok := parseGlobalFlags(inv, args)
+	if !ok {
+		return 2
+	}
+	if !setFSRoots(inv, opts) {
+		return 2
+	}
+	base.SetPluginPath(inv)
+	inv.Config = nil
+	if !opts.noConfig {
+		cfg, err := base.LoadConfig(inv)
+		if err != nil {
+			fmt.Fprintln(stderr, "gobox: config:", err)
+			return 2
+		}
+		inv.Config = cfg
+	}
+	base.RegisterConfigAliases(inv.Config)
 	if len(args) < 1 {
-		usage(stdout)
+		usage(stdout, inv)
 		return 1
 	}
 
@@ -36,46 +126,163 @@ func run(args []string, stdout, stderr io.Writer) int {
 
 	switch cmd {
 	case "--help", "-h", "help":
-		usage(stdout)
+		usage(stdout, inv)
 		return 0
 	case "--version", "version", "-v":
 		fmt.Fprintln(stdout, "gobox 0.1 - container troubleshooting toolset")
+		if note := readOnlyNote(inv); note != "" {
+			fmt.Fprintln(stdout, note)
+		}
 		return 0
 	}
 
 	command, ok := base.Lookup(cmd)
 	if !ok {
 		fmt.Fprintln(stderr, "unknown command:", cmd)
-		usage(stdout)
+		usage(stdout, inv)
 		return 127
 	}
 
-	err := command.Run(args, stdout)
-	if err != nil {
-		if exitErr, ok := err.(cliExitCoder); ok {
-			if silencer, ok := err.(cliErrorSilencer); !ok || !silencer.SuppressCLIError() {
-				fmt.Fprintln(stderr, cmd+":", err)
+	if inv.Output != "" && !base.SupportsOutput(command) {
+		fmt.Fprintf(stderr, "gobox: --output is not supported by %s\n", cmd)
+		return 2
+	}
+
+	if base.OwnsSignals(command) {
+		releaseSignals()
+	}
+	err := command.Run(inv, args)
+	if base.ReportError(err) {
+		fmt.Fprintln(stderr, cmd+":", err)
+	}
+	return base.ExitStatus(err)
+}
+
+// globalOptions are the options parseGlobalFlags consumed besides --output.
+type globalOptions struct {
+	noConfig bool
+	procRoot string
+	sysRoot  string
+}
+
+// parseGlobalFlags consumes the options accepted before the command name:
+// --output FORMAT / --output=FORMAT, which tabular commands also take after
+// it, --no-config, and --proc-root/--sys-root DIR.
+func parseGlobalFlags(inv *base.Invocation, args []string) ([]string, globalOptions, bool) {
+	var opts globalOptions
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		switch name {
+		case "--no-config":
+			if hasValue {
+				fmt.Fprintln(inv.Stderr, "gobox: --no-config takes no argument")
+				return nil, opts, false
 			}
-			return exitErr.ExitCode()
+			opts.noConfig = true
+			args = args[1:]
+			continue
+		case "--output", "--proc-root", "--sys-root":
+		default:
+			return args, opts, true
 		}
-		if silencer, ok := err.(cliErrorSilencer); !ok || !silencer.SuppressCLIError() {
-			fmt.Fprintln(stderr, cmd+":", err)
+		if hasValue {
+			args = args[1:]
+		} else {
+			if len(args) < 2 {
+				fmt.Fprintf(inv.Stderr, "gobox: %s requires an argument\n", name)
+				return nil, opts, false
+			}
+			value, args = args[1], args[2:]
 		}
-		return 2
+		switch name {
+		case "--proc-root":
+			opts.procRoot = value
+		case "--sys-root":
+			opts.sysRoot = value
+		default:
+			format, err := utils.ParseOutputFormat(value)
+			if err != nil {
+				fmt.Fprintln(inv.Stderr, "gobox:", err)
+				return nil, opts, false
+			}
+			inv.Output = format
+		}
+	}
+	return args, opts, true
+}
+
+// setFSRoots points the procfs and sysfs collectors at --proc-root and
+// --sys-root, else $GOBOX_PROCFS and $GOBOX_SYSFS, else /proc and /sys. The
+// flags are exported into the invocation's environment, which is where
+// commands, sh stages and serve requests resolve the roots from.
+func setFSRoots(inv *base.Invocation, opts globalOptions) bool {
+	for _, root := range []struct {
+		flag, env, value string
+	}{
+		{"--proc-root", base.ProcRootEnv, opts.procRoot},
+		{"--sys-root", base.SysRootEnv, opts.sysRoot},
+	} {
+		name, dir := root.flag, root.value
+		if dir == "" {
+			name, dir = root.env, inv.Getenv(root.env)
+		}
+		if dir == "" {
+			continue
+		}
+		dir = inv.Path(dir)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Fprintf(inv.Stderr, "gobox: %s: %s is not a directory\n", name, dir)
+			return false
+		}
+		if root.value != "" {
+			inv.Env = append(inv.Environ(), root.env+"="+dir)
+		}
+	}
+	return true
+}
+
+// readOnlyNote says why read-only mode is on, or is empty when it is off.
+func readOnlyNote(inv *base.Invocation) string {
+	switch {
+	case base.ReadOnlyBuild:
+		return "read-only build: commands and options that change system state are disabled"
+	case base.ReadOnly(inv):
+		return "read-only mode (" + base.ReadOnlyEnv + "): commands and options that change system state are disabled"
 	}
-	return 0
+	return ""
 }
 
-func usage(w io.Writer) {
+func usage(w io.Writer, inv *base.Invocation) {
 	fmt.Fprintln(w, "gobox - minimal container troubleshooting utility set")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Usage: gobox <command> [options]")
+	if note := readOnlyNote(inv); note != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Note: "+note+".")
+	}
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Commands:")
+	readOnly := base.ReadOnly(inv)
 	for _, cmd := range base.Commands() {
+		if readOnly && base.IsMutating(cmd) {
+			continue
+		}
 		fmt.Fprintf(w, "  %-12s %s\n", cmd.Name(), cmd.Help())
 	}
 	fmt.Fprintf(w, "  %-12s %s\n", "version", "Print program version (-v, --version)")
 	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global options:")
+	var tabular []string
+	for _, cmd := range base.Commands() {
+		if base.SupportsOutput(cmd) {
+			tabular = append(tabular, cmd.Name())
+		}
+	}
+	fmt.Fprintf(w, "  %-16s %s\n", "--output FORMAT", "text, json, ndjson, csv or tsv for tabular commands")
+	fmt.Fprintf(w, "  %-16s (%s)\n", "", strings.Join(tabular, " "))
+	fmt.Fprintf(w, "  %-16s %s\n", "--no-config", "ignore the config file and GOBOX_<CMD>_OPTS defaults")
+	fmt.Fprintf(w, "  %-16s %s\n", "--proc-root DIR", "read procfs from DIR instead of /proc ($GOBOX_PROCFS)")
+	fmt.Fprintf(w, "  %-16s %s\n", "--sys-root DIR", "read sysfs from DIR instead of /sys ($GOBOX_SYSFS)")
+	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Common flags are implemented as a focused troubleshooting subset.")
 }
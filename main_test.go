@@ -1,9 +1,15 @@
 package main
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"gobox/cmds/base"
 )
 
 func TestRunNoArgsShowsUsage(t *testing.T) {
@@ -74,6 +80,38 @@ func TestRunUnknownCommand(t *testing.T) {
 	}
 }
 
+func TestRunGlobalOutputFlag(t *testing.T) {
+	var out bytes.Buffer
+	var err bytes.Buffer
+
+	code := run([]string{"--output", "json", "free"}, &out, &err)
+
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d (%s)", code, err.String())
+	}
+	if !strings.HasPrefix(out.String(), "[\n  {\"type\":\"mem\",\"total_bytes\":") {
+		t.Fatalf("expected json rows, got %q", out.String())
+	}
+}
+
+func TestRunGlobalOutputFlagErrors(t *testing.T) {
+	cases := map[string][]string{
+		"not supported by wc":   {"--output=csv", "wc"},
+		"invalid output format": {"--output", "xml", "free"},
+		"requires an argument":  {"--output"},
+	}
+	for want, args := range cases {
+		var out bytes.Buffer
+		var err bytes.Buffer
+		if code := run(args, &out, &err); code != 2 {
+			t.Fatalf("%q: expected exit code 2, got %d", args, code)
+		}
+		if !strings.Contains(err.String(), want) {
+			t.Fatalf("%q: expected %q on stderr, got %q", args, want, err.String())
+		}
+	}
+}
+
 func TestRunCurlSilentSuppressesTopLevelErrorOutput(t *testing.T) {
 	var out bytes.Buffer
 	var err bytes.Buffer
@@ -101,3 +139,252 @@ func TestRunCurlSilentShowErrorKeepsTopLevelErrorOutput(t *testing.T) {
 		t.Fatalf("expected stderr for silent+show-error failure, got %q", err.String())
 	}
 }
+
+func TestCommandArgsDispatchesOnLinkName(t *testing.T) {
+	got := commandArgs([]string{"/usr/local/bin/ps", "-ef"})
+	if strings.Join(got, " ") != "ps -ef" {
+		t.Fatalf("expected argv[0] dispatch to ps, got %q", got)
+	}
+}
+
+func TestCommandArgsKeepsSubcommandForGoboxBinary(t *testing.T) {
+	for _, argv0 := range []string{"/bin/gobox", "./gobox-linux-arm64", "gobox.exe"} {
+		got := commandArgs([]string{argv0, "grep", "x"})
+		if strings.Join(got, " ") != "grep x" {
+			t.Fatalf("%s: expected subcommand dispatch, got %q", argv0, got)
+		}
+	}
+}
+
+func TestCommandArgsUnknownLinkNameFallsBackToSubcommand(t *testing.T) {
+	got := commandArgs([]string{"/tmp/not-a-gobox-command", "ps"})
+	if strings.Join(got, " ") != "ps" {
+		t.Fatalf("expected fallback to subcommand dispatch, got %q", got)
+	}
+}
+
+// TestEveryCommandDescribesItsFlags guards shell completion: each command
+// either declares its options or parses with utils.ParseFlagSet so they can
+// be discovered, and value completers land on real flags.
+func TestEveryCommandDescribesItsFlags(t *testing.T) {
+	for _, cmd := range base.Commands() {
+		if base.IsPlugin(cmd) {
+			continue
+		}
+		if len(base.CommandFlags(cmd)) == 0 {
+			t.Errorf("%s: no flags discovered or declared", cmd.Name())
+		}
+	}
+	dynamic := map[string][]string{
+		"ps":      {"-p", "--sort"},
+		"top":     {"-p", "--sort", "-o"},
+		"lsof":    {"-p"},
+		"kill":    {"-s"},
+		"timeout": {"-s", "--signal"},
+		"ifstat":  {"-i"},
+		"np":      {"-I"},
+		"netstat": {"--sort"},
+		"curl":    {"-X", "--request"},
+		"dig":     {"-t", "--type"},
+	}
+	for name, flags := range dynamic {
+		cmd, ok := base.Lookup(name)
+		if !ok {
+			t.Fatalf("%s not registered", name)
+		}
+		values := map[string]bool{}
+		for _, f := range base.CommandFlags(cmd) {
+			values[f.Name] = f.TakesValue && f.Values != nil
+		}
+		for _, flag := range flags {
+			if !values[flag] {
+				t.Errorf("%s %s: expected a value-taking flag with a completer", name, flag)
+			}
+		}
+	}
+}
+
+func TestRunCompletionValuesListsSignals(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := run([]string{"completion", "values", "kill", "-s"}, &out, &errOut); code != 0 {
+		t.Fatalf("expected exit code 0, got %d (%s)", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "\nTERM\n") {
+		t.Fatalf("expected TERM among signal names, got %q", out.String())
+	}
+}
+
+func TestRunAppliesConfigDefaultsAndAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gobox.conf")
+	if err := os.WriteFile(path, []byte("seq = -s ,\n[alias]\ncount3 = seq 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOBOX_CONFIG", path)
+	t.Cleanup(func() { base.RegisterConfigAliases(nil) })
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"seq", "2"}, "1,2\n"},
+		{[]string{"seq", "-s", ":", "2"}, "1:2\n"},
+		{[]string{"count3"}, "1,2,3\n"},
+		{[]string{"--no-config", "seq", "2"}, "1\n2\n"},
+	}
+	for _, tc := range cases {
+		var out, errOut bytes.Buffer
+		if code := run(tc.args, &out, &errOut); code != 0 || out.String() != tc.want {
+			t.Fatalf("%q: expected %q, got %q (exit %d, %s)", tc.args, tc.want, out.String(), code, errOut.String())
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"--no-config", "count3"}, &out, &errOut); code != 127 {
+		t.Fatalf("expected the alias to disappear with --no-config, got exit %d", code)
+	}
+
+	t.Setenv("GOBOX_SEQ_OPTS", "-w")
+	out.Reset()
+	if code := run([]string{"config", "show"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "seq     defaults  -w     GOBOX_SEQ_OPTS") {
+		t.Fatalf("unexpected config show output %q", out.String())
+	}
+}
+
+func TestRunReportsConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gobox.conf")
+	if err := os.WriteFile(path, []byte("nosuchcmd = -x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOBOX_CONFIG", path)
+	var out, errOut bytes.Buffer
+	if code := run([]string{"seq", "1"}, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "gobox: config: "+path+":1: unknown command") {
+		t.Fatalf("expected a config error, got exit %d: %q", code, errOut.String())
+	}
+	if code := run([]string{"--no-config", "seq", "1"}, &out, &errOut); code != 0 {
+		t.Fatalf("expected --no-config to bypass the broken file, got exit %d", code)
+	}
+}
+
+func TestRunExecsPluginsOnLookupMiss(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\n[ \"$1\" = --gobox-describe ] && { echo 'Team tool'; exit 0; }\necho \"tool $*\"\nexit 4\n"
+	if err := os.WriteFile(filepath.Join(dir, "gobox-zz-team"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOBOX_PLUGIN_DIR", dir)
+	t.Cleanup(func() { base.SetPluginPath(&base.Invocation{Env: []string{}}) })
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"zz-team", "-x", "y"}, &out, &errOut); code != 4 || out.String() != "tool -x y\n" || errOut.Len() != 0 {
+		t.Fatalf("expected the plugin's output and exit status, got %d %q %q", code, out.String(), errOut.String())
+	}
+	out.Reset()
+	if code := run([]string{"--help"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "  zz-team      Team tool\n") {
+		t.Fatalf("expected the plugin in usage, got %q", out.String())
+	}
+}
+
+func TestRunProcAndSysRoots(t *testing.T) {
+	root := t.TempDir()
+	meminfo := "MemTotal:        4096000 kB\nMemFree:         1024000 kB\nMemAvailable:    2048000 kB\n"
+	if err := os.WriteFile(filepath.Join(root, "meminfo"), []byte(meminfo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"--proc-root", root, "free"},
+		{"--proc-root=" + root, "free"},
+	} {
+		var out, errOut bytes.Buffer
+		if code := run(args, &out, &errOut); code != 0 || !strings.Contains(out.String(), "4096000") {
+			t.Fatalf("%q: expected the fixture meminfo, got exit %d %q %q", args, code, out.String(), errOut.String())
+		}
+	}
+
+	t.Setenv("GOBOX_PROCFS", root)
+	var out, errOut bytes.Buffer
+	if code := run([]string{"free"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "4096000") {
+		t.Fatalf("expected GOBOX_PROCFS to apply, got exit %d %q", code, out.String())
+	}
+	if code := run([]string{"--proc-root", filepath.Join(root, "missing"), "free"}, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "--proc-root: ") {
+		t.Fatalf("expected a missing --proc-root to be rejected, got exit %d %q", code, errOut.String())
+	}
+	t.Setenv("GOBOX_PROCFS", "")
+	out.Reset()
+	if code := run([]string{"free"}, &out, &errOut); code != 0 || strings.Contains(out.String(), "4096000") {
+		t.Fatalf("expected each run to reset the root, got %q", out.String())
+	}
+	if code := run([]string{"--sys-root"}, &out, &errOut); code != 2 {
+		t.Fatalf("expected --sys-root without a directory to fail, got exit %d", code)
+	}
+}
+
+func TestRunDiagUsesBuiltinCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	var out, errOut bytes.Buffer
+	if code := run([]string{"diag", "-o", path, "--duration", "1s"}, &out, &errOut); code != 0 {
+		t.Fatalf("diag failed with exit %d: %s", code, errOut.String())
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+		names = append(names, filepath.Base(hdr.Name))
+	}
+	listing := strings.Join(names, " ")
+	for _, want := range []string{"ps-ef.txt", "free.txt", "ip-addr.txt", "manifest.txt"} {
+		if !strings.Contains(listing, want) {
+			t.Fatalf("bundle missing %q: %s", want, listing)
+		}
+	}
+}
+
+func TestRunReadOnlyMode(t *testing.T) {
+	t.Setenv(base.ReadOnlyEnv, "1")
+	file := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(file, []byte("12345"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"version"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "read-only mode (GOBOX_READONLY)") {
+		t.Fatalf("expected version to report read-only mode, got exit %d %q", code, out.String())
+	}
+	out.Reset()
+	if code := run([]string{"--help"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "Note: read-only mode") {
+		t.Fatalf("expected usage to report read-only mode, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "  truncate ") {
+		t.Fatalf("expected usage to leave out blocked commands, got %q", out.String())
+	}
+
+	errOut.Reset()
+	code := run([]string{"truncate", "-s", "0", file}, &out, &errOut)
+	if code != base.ReadOnlyExitCode || !strings.Contains(errOut.String(), "truncate: truncate is disabled in read-only mode") {
+		t.Fatalf("expected truncate to be refused, got exit %d %q", code, errOut.String())
+	}
+	if info, err := os.Stat(file); err != nil || info.Size() != 5 {
+		t.Fatalf("expected the file to stay untouched, got %v %v", info, err)
+	}
+	errOut.Reset()
+	if code := run([]string{"sed", "-i", "s/1/x/", file}, &out, &errOut); code != base.ReadOnlyExitCode {
+		t.Fatalf("expected sed -i to be refused, got exit %d %q", code, errOut.String())
+	}
+	if data, _ := os.ReadFile(file); string(data) != "12345" {
+		t.Fatalf("expected sed -i to leave the file alone, got %q", data)
+	}
+
+	t.Setenv(base.ReadOnlyEnv, "0")
+	out.Reset()
+	if code := run([]string{"version"}, &out, &errOut); code != 0 || strings.Contains(out.String(), "read-only") {
+		t.Fatalf("expected GOBOX_READONLY=0 to leave the mode off, got %q", out.String())
+	}
+}
@@ -0,0 +1,60 @@
+package netfs
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ARP entry flags (ATF_* in include/uapi/linux/if_arp.h).
+const (
+	ARPComplete  uint32 = 0x2
+	ARPPermanent uint32 = 0x4
+)
+
+// ARPEntry is one line of /proc/net/arp.
+type ARPEntry struct {
+	IP     net.IP
+	HWType uint16
+	Flags  uint32
+	// HWAddr is all zeros while the entry is incomplete.
+	HWAddr net.HardwareAddr
+	Device string
+}
+
+// ParseARP parses /proc/net/arp, skipping the header and malformed lines.
+func ParseARP(r io.Reader) ([]ARPEntry, error) {
+	var res []ARPEntry
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		if first {
+			first = false
+			continue
+		}
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		e := ARPEntry{IP: net.ParseIP(fields[0]), Device: fields[5]}
+		if e.IP == nil {
+			continue
+		}
+		hw, err := net.ParseMAC(fields[3])
+		if err != nil {
+			continue
+		}
+		e.HWAddr = hw
+		if v, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "0x"), 16, 16); err == nil {
+			e.HWType = uint16(v)
+		}
+		if v, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32); err == nil {
+			e.Flags = uint32(v)
+		}
+		res = append(res, e)
+	}
+	return res, scanner.Err()
+}
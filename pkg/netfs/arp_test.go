@@ -0,0 +1,26 @@
+package netfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseARP(t *testing.T) {
+	data := "IP address       HW type     Flags       HW address            Mask     Device\n" +
+		"10.0.0.1         0x1         0x2         aa:bb:cc:dd:ee:01     *        eth0\n" +
+		"10.0.0.9         0x1         0x0         00:00:00:00:00:00     *        eth0\n" +
+		"bogus            0x1         0x2         aa:bb:cc:dd:ee:02     *        eth0\n"
+	entries, err := ParseARP(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %+v", entries)
+	}
+	if e := entries[0]; e.IP.String() != "10.0.0.1" || e.HWType != 1 || e.Flags != ARPComplete || e.HWAddr.String() != "aa:bb:cc:dd:ee:01" || e.Device != "eth0" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if e := entries[1]; e.Flags&ARPComplete != 0 || e.HWAddr.String() != "00:00:00:00:00:00" {
+		t.Fatalf("unexpected incomplete entry %+v", e)
+	}
+}
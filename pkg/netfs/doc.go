@@ -0,0 +1,10 @@
+// Package netfs parses the Linux network tables under /proc/net (or
+// /proc/PID/net, the same tables as another network namespace sees them).
+//
+// Parsers take an io.Reader; FS opens the tables in a directory:
+//
+//	fs := netfs.NewFS("/proc/net")
+//	conns, err := fs.TCP()
+//	routes, err := fs.IPv4Routes()
+//	neigh, err := fs.ARP()
+package netfs
@@ -0,0 +1,61 @@
+package netfs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDir is the network table directory of the reading process.
+const DefaultDir = "/proc/net"
+
+// FS is a directory of network tables.
+type FS struct {
+	dir string
+}
+
+// NewFS returns the tables in dir; "" means /proc/net.
+func NewFS(dir string) FS {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return FS{dir: filepath.Clean(dir)}
+}
+
+// Path returns the path of table name.
+func (fs FS) Path(name string) string { return filepath.Join(fs.dir, name) }
+
+// TCP reads /proc/net/tcp.
+func (fs FS) TCP() ([]Socket, error) { return readTable(fs.Path("tcp"), ParseSockets) }
+
+// TCP6 reads /proc/net/tcp6.
+func (fs FS) TCP6() ([]Socket, error) { return readTable(fs.Path("tcp6"), ParseSockets) }
+
+// UDP reads /proc/net/udp.
+func (fs FS) UDP() ([]Socket, error) { return readTable(fs.Path("udp"), ParseSockets) }
+
+// UDP6 reads /proc/net/udp6.
+func (fs FS) UDP6() ([]Socket, error) { return readTable(fs.Path("udp6"), ParseSockets) }
+
+// Unix reads /proc/net/unix.
+func (fs FS) Unix() ([]UnixSocket, error) { return readTable(fs.Path("unix"), ParseUnixSockets) }
+
+// ARP reads /proc/net/arp.
+func (fs FS) ARP() ([]ARPEntry, error) { return readTable(fs.Path("arp"), ParseARP) }
+
+// IPv4Routes reads /proc/net/route.
+func (fs FS) IPv4Routes() ([]IPv4Route, error) { return readTable(fs.Path("route"), ParseIPv4Routes) }
+
+// IPv6Routes reads /proc/net/ipv6_route.
+func (fs FS) IPv6Routes() ([]IPv6Route, error) {
+	return readTable(fs.Path("ipv6_route"), ParseIPv6Routes)
+}
+
+func readTable[T any](path string, parse func(io.Reader) ([]T, error)) ([]T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
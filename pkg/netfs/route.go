@@ -0,0 +1,144 @@
+package netfs
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Route flags (include/uapi/linux/route.h).
+const (
+	RouteUp       = 0x1
+	RouteGateway  = 0x2
+	RouteHost     = 0x4
+	RouteDynamic  = 0x10
+	RouteModified = 0x20
+)
+
+// IPv4Route is one line of /proc/net/route.
+type IPv4Route struct {
+	Iface       string
+	Destination net.IP
+	Gateway     net.IP
+	Mask        net.IPMask
+	Flags       uint64
+	RefCnt      int
+	Use         int
+	Metric      int
+	MTU         int
+	Window      int
+	IRTT        int
+}
+
+// Default reports whether r is a default route.
+func (r IPv4Route) Default() bool {
+	ones, _ := r.Mask.Size()
+	return r.Destination.Equal(net.IPv4zero) && ones == 0
+}
+
+// ParseIPv4Routes parses /proc/net/route, skipping the header and
+// malformed lines.
+func ParseIPv4Routes(r io.Reader) ([]IPv4Route, error) {
+	var routes []IPv4Route
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		if first {
+			first = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		dst, err1 := parseHexIP(fields[1])
+		gw, err2 := parseHexIP(fields[2])
+		mask, err3 := parseHexIP(fields[7])
+		flags, err4 := strconv.ParseUint(fields[3], 16, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		route := IPv4Route{
+			Iface:       fields[0],
+			Destination: dst,
+			Gateway:     gw,
+			Mask:        net.IPMask(mask),
+			Flags:       flags,
+		}
+		route.RefCnt, _ = strconv.Atoi(fields[4])
+		route.Use, _ = strconv.Atoi(fields[5])
+		route.Metric, _ = strconv.Atoi(fields[6])
+		if len(fields) >= 11 {
+			route.MTU, _ = strconv.Atoi(fields[8])
+			route.Window, _ = strconv.Atoi(fields[9])
+			route.IRTT, _ = strconv.Atoi(fields[10])
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// IPv6Route is one line of /proc/net/ipv6_route.
+type IPv6Route struct {
+	Destination  net.IP
+	PrefixLen    int
+	Source       net.IP
+	SourcePrefix int
+	NextHop      net.IP
+	Metric       uint32
+	RefCnt       uint32
+	Use          uint32
+	Flags        uint64
+	Iface        string
+}
+
+// ParseIPv6Routes parses /proc/net/ipv6_route, which has no header.
+func ParseIPv6Routes(r io.Reader) ([]IPv6Route, error) {
+	var routes []IPv6Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		var route IPv6Route
+		var err error
+		if route.Destination, err = parseIPv6(fields[0]); err != nil {
+			continue
+		}
+		if route.Source, err = parseIPv6(fields[2]); err != nil {
+			continue
+		}
+		if route.NextHop, err = parseIPv6(fields[4]); err != nil {
+			continue
+		}
+		route.PrefixLen = int(parseHex32(fields[1]))
+		route.SourcePrefix = int(parseHex32(fields[3]))
+		route.Metric = parseHex32(fields[5])
+		route.RefCnt = parseHex32(fields[6])
+		route.Use = parseHex32(fields[7])
+		route.Flags = uint64(parseHex32(fields[8]))
+		route.Iface = fields[9]
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseIPv6 decodes an address of ipv6_route, which, unlike the socket
+// tables, is printed in network byte order.
+func parseIPv6(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != net.IPv6len {
+		return nil, fmt.Errorf("invalid IPv6 address %q", s)
+	}
+	return net.IP(b), nil
+}
+
+func parseHex32(s string) uint32 {
+	v, _ := strconv.ParseUint(s, 16, 32)
+	return uint32(v)
+}
@@ -0,0 +1,44 @@
+package netfs
+
+import (
+	"strings"
+	"testing"
+
+	"gobox/internal/testutil"
+)
+
+func TestParseIPv4Routes(t *testing.T) {
+	data := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t0100A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t1500\t0\t0\n"
+	routes, err := ParseIPv4Routes(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", routes)
+	}
+	if r := routes[0]; !r.Default() || r.Gateway.String() != "192.168.0.1" || r.Flags != RouteUp|RouteGateway || r.Metric != 100 {
+		t.Fatalf("unexpected default route %+v", r)
+	}
+	r := routes[1]
+	ones, _ := r.Mask.Size()
+	if r.Default() || r.Destination.String() != "192.168.0.0" || ones != 24 || r.MTU != 1500 {
+		t.Fatalf("unexpected subnet route %+v", r)
+	}
+}
+
+func TestFSIPv6Routes(t *testing.T) {
+	line := "fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001 eth0\n"
+	dir := testutil.Tree(t, map[string]string{"ipv6_route": line})
+	routes, err := NewFS(dir).IPv6Routes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Destination.String() != "fe80::" || routes[0].PrefixLen != 64 || routes[0].Metric != 256 || routes[0].Iface != "eth0" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	if _, err := NewFS(dir).TCP(); err == nil {
+		t.Fatal("expected an error for a missing table")
+	}
+}
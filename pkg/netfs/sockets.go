@@ -0,0 +1,136 @@
+package netfs
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TCP socket states as /proc/net/tcp numbers them (include/net/tcp_states.h).
+const (
+	TCPEstablished uint8 = iota + 1
+	TCPSynSent
+	TCPSynRecv
+	TCPFinWait1
+	TCPFinWait2
+	TCPTimeWait
+	TCPClose
+	TCPCloseWait
+	TCPLastAck
+	TCPListen
+	TCPClosing
+)
+
+var tcpStateNames = map[uint8]string{
+	TCPEstablished: "ESTABLISHED",
+	TCPSynSent:     "SYN_SENT",
+	TCPSynRecv:     "SYN_RECV",
+	TCPFinWait1:    "FIN_WAIT1",
+	TCPFinWait2:    "FIN_WAIT2",
+	TCPTimeWait:    "TIME_WAIT",
+	TCPClose:       "CLOSE",
+	TCPCloseWait:   "CLOSE_WAIT",
+	TCPLastAck:     "LAST_ACK",
+	TCPListen:      "LISTEN",
+	TCPClosing:     "CLOSING",
+}
+
+// TCPStateName returns the netstat name of a TCP state, or its two-digit
+// hex number when the state is unknown.
+func TCPStateName(state uint8) string {
+	if name, ok := tcpStateNames[state]; ok {
+		return name
+	}
+	return fmt.Sprintf("%02X", state)
+}
+
+// Socket is one line of /proc/net/tcp, tcp6, udp or udp6.
+type Socket struct {
+	LocalIP    net.IP
+	LocalPort  int
+	RemoteIP   net.IP
+	RemotePort int
+	// State is the kernel's state number; UDP sockets use the TCP numbering
+	// (TCPEstablished when connected, TCPClose otherwise).
+	State   uint8
+	TxQueue uint64
+	RxQueue uint64
+	// Timer is the raw "tr:tm->when" column.
+	Timer string
+	UID   int
+	Inode uint64
+}
+
+// ParseSockets parses a tcp/udp table, skipping the header and malformed
+// lines.
+func ParseSockets(r io.Reader) ([]Socket, error) {
+	var res []Socket
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		if first {
+			first = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		var s Socket
+		var err error
+		if s.LocalIP, s.LocalPort, err = ParseAddr(fields[1]); err != nil {
+			continue
+		}
+		if s.RemoteIP, s.RemotePort, err = ParseAddr(fields[2]); err != nil {
+			continue
+		}
+		if st, err := strconv.ParseUint(fields[3], 16, 8); err == nil {
+			s.State = uint8(st)
+		}
+		if tx, rx, ok := strings.Cut(fields[4], ":"); ok {
+			s.TxQueue, _ = strconv.ParseUint(tx, 16, 64)
+			s.RxQueue, _ = strconv.ParseUint(rx, 16, 64)
+		}
+		s.Timer = fields[5]
+		s.UID, _ = strconv.Atoi(fields[7])
+		s.Inode, _ = strconv.ParseUint(fields[9], 10, 64)
+		res = append(res, s)
+	}
+	return res, scanner.Err()
+}
+
+// ParseAddr decodes an "ADDR:PORT" column of the socket tables: 8 hex
+// digits of little-endian IPv4 or 32 of IPv6 stored as four little-endian
+// words, then the port in hex.
+func ParseAddr(s string) (net.IP, int, error) {
+	addr, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid socket address %q", s)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid socket port %q", s)
+	}
+	ip, err := parseHexIP(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ip, int(port), nil
+}
+
+// parseHexIP decodes an address as the kernel prints it in host (little
+// endian) byte order, one 32-bit word at a time.
+func parseHexIP(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, fmt.Errorf("invalid hex address %q", s)
+	}
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return net.IP(b), nil
+}
@@ -0,0 +1,52 @@
+package netfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAddr(t *testing.T) {
+	ip, port, err := ParseAddr("0100007F:0035")
+	if err != nil || ip.String() != "127.0.0.1" || port != 53 {
+		t.Fatalf("expected 127.0.0.1:53, got %v:%d %v", ip, port, err)
+	}
+	// ::1 in /proc/net/tcp6 is stored as 4 little-endian uint32 words: last word 0x01000000
+	ip, _, err = ParseAddr("00000000000000000000000001000000:0035")
+	if err != nil || ip.String() != "::1" {
+		t.Fatalf("expected IPv6 ::1, got %v %v", ip, err)
+	}
+	for _, bad := range []string{"bad", "0100007F:XYZ", "01007F:0035"} {
+		if _, _, err := ParseAddr(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestParseSockets(t *testing.T) {
+	data := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n" +
+		"   0: 0100007F:0035 00000000:0000 0A 00000000:00000001 00:00000000 00000000   101        0 12345 1 0000000000000000 100 0 0 10 0\n" +
+		"   1: broken\n"
+	socks, err := ParseSockets(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(socks) != 1 {
+		t.Fatalf("expected one socket, got %+v", socks)
+	}
+	s := socks[0]
+	if s.LocalIP.String() != "127.0.0.1" || s.LocalPort != 53 || s.RemoteIP.String() != "0.0.0.0" || s.State != TCPListen {
+		t.Fatalf("unexpected addresses/state %+v", s)
+	}
+	if s.RxQueue != 1 || s.UID != 101 || s.Inode != 12345 || s.Timer != "00:00000000" {
+		t.Fatalf("unexpected counters %+v", s)
+	}
+}
+
+func TestTCPStateName(t *testing.T) {
+	if got := TCPStateName(TCPEstablished); got != "ESTABLISHED" {
+		t.Fatalf("got %q", got)
+	}
+	if got := TCPStateName(0x2a); got != "2A" {
+		t.Fatalf("expected unknown states in hex, got %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package netfs
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Unix socket states as /proc/net/unix numbers them (SS_* in
+// include/uapi/linux/net.h).
+const (
+	UnixUnconnected uint8 = iota + 1
+	UnixConnected
+	UnixConnecting
+	UnixDisconnecting
+)
+
+// UnixSocket is one line of /proc/net/unix.
+type UnixSocket struct {
+	Flags uint32
+	Type  uint16
+	State uint8
+	Inode uint64
+	// Path is the bound name, "" for unbound sockets; abstract names
+	// start with "@".
+	Path string
+}
+
+// ParseUnixSockets parses /proc/net/unix, skipping the header and
+// malformed lines.
+func ParseUnixSockets(r io.Reader) ([]UnixSocket, error) {
+	var res []UnixSocket
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		if first {
+			first = false
+			continue
+		}
+		// Num RefCount Protocol Flags Type St Inode [Path]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		var s UnixSocket
+		if v, err := strconv.ParseUint(fields[3], 16, 32); err == nil {
+			s.Flags = uint32(v)
+		}
+		if v, err := strconv.ParseUint(fields[4], 16, 16); err == nil {
+			s.Type = uint16(v)
+		}
+		if v, err := strconv.ParseUint(fields[5], 16, 8); err == nil {
+			s.State = uint8(v)
+		}
+		s.Inode, _ = strconv.ParseUint(fields[6], 10, 64)
+		if len(fields) > 7 {
+			s.Path = strings.Join(fields[7:], " ")
+		}
+		res = append(res, s)
+	}
+	return res, scanner.Err()
+}
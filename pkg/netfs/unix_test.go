@@ -0,0 +1,26 @@
+package netfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnixSockets(t *testing.T) {
+	data := "Num       RefCount Protocol Flags    Type St Inode Path\n" +
+		"0000000000000000: 00000002 00000000 00010000 0001 01 14173 /run/user 1/bus\n" +
+		"0000000000000000: 00000003 00000000 00000000 0001 03 22663\n" +
+		"broken\n"
+	socks, err := ParseUnixSockets(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(socks) != 2 {
+		t.Fatalf("expected two sockets, got %+v", socks)
+	}
+	if s := socks[0]; s.Flags != 0x10000 || s.Type != 1 || s.State != UnixUnconnected || s.Inode != 14173 || s.Path != "/run/user 1/bus" {
+		t.Fatalf("unexpected bound socket %+v", s)
+	}
+	if s := socks[1]; s.State != UnixConnecting || s.Inode != 22663 || s.Path != "" {
+		t.Fatalf("unexpected unbound socket %+v", s)
+	}
+}
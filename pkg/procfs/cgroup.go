@@ -0,0 +1,38 @@
+package procfs
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Cgroup is one line of /proc/PID/cgroup: hierarchy ID, controllers and the
+// path within that hierarchy.
+type Cgroup struct {
+	ID          string
+	Controllers []string
+	Path        string
+}
+
+// Unified reports whether c is the cgroup v2 entry ("0::PATH").
+func (c Cgroup) Unified() bool {
+	return c.ID == "0" && len(c.Controllers) == 0
+}
+
+// ParseCgroups parses /proc/PID/cgroup.
+func ParseCgroups(r io.Reader) ([]Cgroup, error) {
+	var out []Cgroup
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		c := Cgroup{ID: parts[0], Path: parts[2]}
+		if parts[1] != "" {
+			c.Controllers = strings.Split(parts[1], ",")
+		}
+		out = append(out, c)
+	}
+	return out, scanner.Err()
+}
@@ -0,0 +1,22 @@
+package procfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCgroups(t *testing.T) {
+	cgroups, err := ParseCgroups(strings.NewReader("12:cpu,cpuacct:/docker/abc\n1:name=systemd:/x\n0::/system.slice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cgroups) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", cgroups)
+	}
+	if c := cgroups[0]; c.Unified() || c.Path != "/docker/abc" || len(c.Controllers) != 2 || c.Controllers[1] != "cpuacct" {
+		t.Fatalf("unexpected v1 entry %+v", c)
+	}
+	if c := cgroups[2]; !c.Unified() || c.Path != "/system.slice" {
+		t.Fatalf("unexpected v2 entry %+v", c)
+	}
+}
@@ -0,0 +1,72 @@
+package procfs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SectorSize is the unit of the sector counts in /proc/diskstats, whatever
+// the device's own sector size.
+const SectorSize = 512
+
+// DiskStats is one device line of /proc/diskstats. Times are milliseconds.
+type DiskStats struct {
+	Major, Minor int
+	Name         string
+
+	ReadIOs, ReadMerges, ReadSectors, ReadTicks     uint64
+	WriteIOs, WriteMerges, WriteSectors, WriteTicks uint64
+	InFlight                                        uint64
+	// IOTicks is the time the device had I/O in flight; WeightedIOTicks
+	// weighs it by the queue length.
+	IOTicks, WeightedIOTicks uint64
+}
+
+// ParseDiskstats parses /proc/diskstats in file order, skipping lines with
+// fewer than the 14 classic fields.
+func ParseDiskstats(r io.Reader) ([]DiskStats, error) {
+	var out []DiskStats
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 {
+			continue
+		}
+		var d DiskStats
+		var err error
+		if d.Major, err = strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+		if d.Minor, err = strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		d.Name = fields[2]
+		counters := []*uint64{&d.ReadIOs, &d.ReadMerges, &d.ReadSectors, &d.ReadTicks,
+			&d.WriteIOs, &d.WriteMerges, &d.WriteSectors, &d.WriteTicks,
+			&d.InFlight, &d.IOTicks, &d.WeightedIOTicks}
+		ok := true
+		for i, c := range counters {
+			if *c, err = strconv.ParseUint(fields[3+i], 10, 64); err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, d)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// Diskstats reads /proc/diskstats.
+func (fs FS) Diskstats() ([]DiskStats, error) {
+	f, err := os.Open(fs.Path("diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseDiskstats(f)
+}
@@ -0,0 +1,24 @@
+package procfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiskstats(t *testing.T) {
+	data := "   8       0 sda 100 5 2048 30 200 10 4096 60 1 70 90 0 0 0 0\n" +
+		"   8       1 sda1 bad 0 0 0 0 0 0 0 0 0 0\n" +
+		"   7       0 loop0 1 2 3\n"
+	disks, err := ParseDiskstats(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disks) != 1 {
+		t.Fatalf("expected only the well-formed line, got %+v", disks)
+	}
+	want := DiskStats{Major: 8, Name: "sda", ReadIOs: 100, ReadMerges: 5, ReadSectors: 2048, ReadTicks: 30,
+		WriteIOs: 200, WriteMerges: 10, WriteSectors: 4096, WriteTicks: 60, InFlight: 1, IOTicks: 70, WeightedIOTicks: 90}
+	if disks[0] != want {
+		t.Fatalf("got %+v\nwant %+v", disks[0], want)
+	}
+}
@@ -0,0 +1,14 @@
+// Package procfs parses the Linux /proc files gobox's process, memory,
+// disk and container commands read.
+//
+// Parsers take an io.Reader so they work on captured files and fixtures as
+// well as on a live system; FS and Proc open the files under a procfs root,
+// which need not be /proc:
+//
+//	fs := procfs.NewFS("/host/proc")
+//	mem, err := fs.MemInfo()
+//	stat, err := fs.Proc(1).Stat()
+//
+// Values are reported in the units the kernel uses unless a field's comment
+// says otherwise: clock ticks for CPU times, pages for RSS, and so on.
+package procfs
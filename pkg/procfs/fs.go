@@ -0,0 +1,57 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultRoot is where procfs is normally mounted.
+const DefaultRoot = "/proc"
+
+// FS is a procfs tree rooted at a directory.
+type FS struct {
+	root string
+}
+
+// NewFS returns the procfs tree at root; "" means /proc.
+func NewFS(root string) FS {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return FS{root: filepath.Clean(root)}
+}
+
+// Root returns the directory fs reads.
+func (fs FS) Root() string { return fs.root }
+
+// Path joins elem onto the root.
+func (fs FS) Path(elem ...string) string {
+	return filepath.Join(append([]string{fs.root}, elem...)...)
+}
+
+// Proc returns the process pid.
+func (fs FS) Proc(pid int) Proc {
+	return Proc{PID: pid, dir: fs.Path(strconv.Itoa(pid))}
+}
+
+// Self returns the process reading fs, through /proc/self. Its PID is 0.
+func (fs FS) Self() Proc {
+	return Proc{dir: fs.Path("self")}
+}
+
+// AllPIDs returns the PIDs of the processes listed under the root, in
+// directory order.
+func (fs FS) AllPIDs() ([]int, error) {
+	entries, err := os.ReadDir(fs.root)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil && pid > 0 {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
@@ -0,0 +1,45 @@
+package procfs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MemInfo maps each /proc/meminfo field to its value in bytes. Fields the
+// kernel reports without a unit (HugePages_Total, ...) are counts and are
+// kept as-is.
+type MemInfo map[string]uint64
+
+// ParseMemInfo parses /proc/meminfo, skipping malformed lines.
+func ParseMemInfo(r io.Reader) (MemInfo, error) {
+	out := MemInfo{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			v *= 1024
+		}
+		out[strings.TrimSuffix(fields[0], ":")] = v
+	}
+	return out, scanner.Err()
+}
+
+// MemInfo reads /proc/meminfo.
+func (fs FS) MemInfo() (MemInfo, error) {
+	f, err := os.Open(fs.Path("meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseMemInfo(f)
+}
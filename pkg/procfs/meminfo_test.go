@@ -0,0 +1,32 @@
+package procfs
+
+import (
+	"strings"
+	"testing"
+
+	"gobox/internal/testutil"
+)
+
+func TestParseMemInfo(t *testing.T) {
+	mem, err := ParseMemInfo(strings.NewReader("MemTotal: 2 kB\nBad:\nCached: nope kB\nSwapFree: 1 kB\nHugePages_Total: 8\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem["MemTotal"] != 2048 || mem["SwapFree"] != 1024 {
+		t.Fatalf("unexpected parsed meminfo %#v", mem)
+	}
+	if _, ok := mem["Cached"]; ok {
+		t.Fatalf("invalid numeric field should be ignored: %#v", mem)
+	}
+	if mem["HugePages_Total"] != 8 {
+		t.Fatalf("fields without a unit should be kept as counts: %#v", mem)
+	}
+}
+
+func TestFSMemInfo(t *testing.T) {
+	fs := NewFS(testutil.Tree(t, map[string]string{"meminfo": "MemTotal: 4 kB\n"}))
+	mem, err := fs.MemInfo()
+	if err != nil || mem["MemTotal"] != 4096 {
+		t.Fatalf("unexpected meminfo %#v %v", mem, err)
+	}
+}
@@ -0,0 +1,61 @@
+package procfs
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// MountInfo is one mount from /proc/PID/mountinfo.
+type MountInfo struct {
+	Source string
+	Target string
+	FSType string
+	// Options are the per-mount options ("rw", "nosuid", ...).
+	Options []string
+}
+
+// ReadOnly reports whether the mount is mounted ro.
+func (m MountInfo) ReadOnly() bool {
+	for _, o := range m.Options {
+		if o == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMountInfo reads a mountinfo table in file order, skipping lines it
+// cannot parse.
+func ParseMountInfo(r io.Reader) ([]MountInfo, error) {
+	var mounts []MountInfo
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), " - ")
+		if len(parts) != 2 {
+			continue
+		}
+		left := strings.Fields(parts[0])
+		right := strings.Fields(parts[1])
+		if len(left) < 6 || len(right) < 3 {
+			continue
+		}
+		mounts = append(mounts, MountInfo{
+			Source:  right[1],
+			Target:  decodeMountField(left[4]),
+			FSType:  right[0],
+			Options: strings.Split(left[5], ","),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// decodeMountField undoes the octal escapes the kernel applies to spaces,
+// tabs, newlines and backslashes in mount paths.
+func decodeMountField(s string) string {
+	s = strings.ReplaceAll(s, `\040`, " ")
+	s = strings.ReplaceAll(s, `\011`, "\t")
+	s = strings.ReplaceAll(s, `\012`, "\n")
+	s = strings.ReplaceAll(s, `\134`, `\`)
+	return s
+}
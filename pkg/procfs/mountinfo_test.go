@@ -0,0 +1,25 @@
+package procfs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	data := "22 1 0:21 / / ro,relatime shared:1 - overlay overlay rw,lowerdir=/l\n" +
+		"23 22 0:22 / /mnt/my\\040disk rw,nosuid - ext4 /dev/sdb1 rw\n" +
+		"garbage\n"
+	mounts, err := ParseMountInfo(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 2 {
+		t.Fatalf("expected 2 mounts, got %+v", mounts)
+	}
+	if m := mounts[0]; m.Target != "/" || m.FSType != "overlay" || m.Source != "overlay" || !m.ReadOnly() {
+		t.Fatalf("unexpected root mount %+v", m)
+	}
+	if m := mounts[1]; m.Target != "/mnt/my disk" || m.Source != "/dev/sdb1" || m.ReadOnly() {
+		t.Fatalf("unexpected data mount %+v", m)
+	}
+}
@@ -0,0 +1,137 @@
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Proc is one process (or thread, see Task) under a procfs root.
+type Proc struct {
+	PID int
+	dir string
+}
+
+// Dir returns the process's /proc/PID directory.
+func (p Proc) Dir() string { return p.dir }
+
+// Path joins elem onto the process directory.
+func (p Proc) Path(elem ...string) string {
+	return filepath.Join(append([]string{p.dir}, elem...)...)
+}
+
+// Task returns thread tid of the process, from /proc/PID/task/TID.
+func (p Proc) Task(tid int) Proc {
+	return Proc{PID: tid, dir: p.Path("task", strconv.Itoa(tid))}
+}
+
+// Stat reads /proc/PID/stat.
+func (p Proc) Stat() (ProcStat, error) {
+	f, err := os.Open(p.Path("stat"))
+	if err != nil {
+		return ProcStat{}, err
+	}
+	defer f.Close()
+	return ParseProcStat(f)
+}
+
+// Status reads /proc/PID/status.
+func (p Proc) Status() (ProcStatus, error) {
+	f, err := os.Open(p.Path("status"))
+	if err != nil {
+		return ProcStatus{}, err
+	}
+	defer f.Close()
+	return ParseProcStatus(f)
+}
+
+// Cmdline returns the arguments from /proc/PID/cmdline; kernel threads and
+// zombies have none.
+func (p Proc) Cmdline() ([]string, error) {
+	data, err := os.ReadFile(p.Path("cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(data), "\x00"), nil
+}
+
+// Comm returns the command name from /proc/PID/comm.
+func (p Proc) Comm() (string, error) {
+	data, err := os.ReadFile(p.Path("comm"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Executable returns the target of /proc/PID/exe.
+func (p Proc) Executable() (string, error) {
+	return os.Readlink(p.Path("exe"))
+}
+
+// Wchan returns the kernel function the process is blocked in, from
+// /proc/PID/wchan; it is empty or "0" while the process runs.
+func (p Proc) Wchan() (string, error) {
+	data, err := os.ReadFile(p.Path("wchan"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Cgroups reads /proc/PID/cgroup.
+func (p Proc) Cgroups() ([]Cgroup, error) {
+	f, err := os.Open(p.Path("cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCgroups(f)
+}
+
+// MountInfo reads /proc/PID/mountinfo, the mount table as the process sees
+// it.
+func (p Proc) MountInfo() ([]MountInfo, error) {
+	f, err := os.Open(p.Path("mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseMountInfo(f)
+}
+
+// Namespace returns the inode of the process's namespace name ("net",
+// "pid", "mnt", ...), which is equal for processes sharing it.
+func (p Proc) Namespace(name string) (uint64, error) {
+	link, err := os.Readlink(p.Path("ns", name))
+	if err != nil {
+		return 0, err
+	}
+	// The link reads "net:[4026531840]".
+	i := strings.IndexByte(link, '[')
+	if i < 0 || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("unexpected namespace link %q", link)
+	}
+	return strconv.ParseUint(link[i+1:len(link)-1], 10, 64)
+}
+
+// Environ returns the environment the process started with, from
+// /proc/PID/environ.
+func (p Proc) Environ() ([]string, error) {
+	data, err := os.ReadFile(p.Path("environ"))
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(data), "\x00"), nil
+}
@@ -0,0 +1,68 @@
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gobox/internal/testutil"
+)
+
+func TestFSPaths(t *testing.T) {
+	if got := NewFS("").Proc(42).Path("stat"); got != "/proc/42/stat" {
+		t.Fatalf("unexpected default path %q", got)
+	}
+	fs := NewFS("/host/proc/")
+	if got := fs.Self().Path("mountinfo"); got != "/host/proc/self/mountinfo" {
+		t.Fatalf("unexpected self path %q", got)
+	}
+	if task := fs.Proc(7).Task(9); task.PID != 9 || task.Path("stat") != "/host/proc/7/task/9/stat" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+}
+
+func TestProcFiles(t *testing.T) {
+	root := testutil.Tree(t, map[string]string{
+		"12/stat":    "12 (app) S 1 12 12 0 -1 4194304 0 0 0 0 3 4 0 0 20 0 2 0 500 1048576 16\n",
+		"12/cmdline": "/usr/bin/app\x00--flag\x00\x00",
+		"12/comm":    "app\n",
+		"12/environ": "A=1\x00B=2\x00",
+		"12/wchan":   "do_select\n",
+		"uptime":     "1.0 1.0\n",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "12", "ns"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("net:[4026531840]", filepath.Join(root, "12", "ns", "net")); err != nil {
+		t.Fatal(err)
+	}
+	fs := NewFS(root)
+	p := fs.Proc(12)
+
+	st, err := p.Stat()
+	if err != nil || st.PID != 12 || st.Comm != "app" || st.StartTime != 500 {
+		t.Fatalf("unexpected stat %+v %v", st, err)
+	}
+	if args, err := p.Cmdline(); err != nil || !reflect.DeepEqual(args, []string{"/usr/bin/app", "--flag"}) {
+		t.Fatalf("unexpected cmdline %q %v", args, err)
+	}
+	if comm, err := p.Comm(); err != nil || comm != "app" {
+		t.Fatalf("unexpected comm %q %v", comm, err)
+	}
+	if env, err := p.Environ(); err != nil || !reflect.DeepEqual(env, []string{"A=1", "B=2"}) {
+		t.Fatalf("unexpected environ %q %v", env, err)
+	}
+	if wchan, err := p.Wchan(); err != nil || wchan != "do_select" {
+		t.Fatalf("unexpected wchan %q %v", wchan, err)
+	}
+	if ino, err := p.Namespace("net"); err != nil || ino != 4026531840 {
+		t.Fatalf("unexpected namespace %d %v", ino, err)
+	}
+	if _, err := p.Namespace("pid"); err == nil {
+		t.Fatal("expected an error for a missing namespace link")
+	}
+	if pids, err := fs.AllPIDs(); err != nil || !reflect.DeepEqual(pids, []int{12}) {
+		t.Fatalf("unexpected pids %v %v", pids, err)
+	}
+}
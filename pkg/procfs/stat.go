@@ -0,0 +1,144 @@
+package procfs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcStat is /proc/PID/stat. Field comments give the field number in
+// proc(5).
+type ProcStat struct {
+	PID   int    // (1)
+	Comm  string // (2), without the parentheses
+	State string // (3)
+	PPID  int    // (4)
+	// TTY is the controlling terminal's device number (7), 0 for none.
+	TTY   int64
+	Flags uint64 // (9)
+	// UTime and STime are CPU time in user and kernel mode, in clock ticks
+	// (14, 15).
+	UTime    int64
+	STime    int64
+	Priority int64 // (18)
+	Nice     int64 // (19)
+	Threads  int64 // (20)
+	// StartTime is when the process started, in clock ticks after boot
+	// (22).
+	StartTime int64
+	VSize     int64 // (23) bytes
+	RSS       int64 // (24) pages
+	Processor int64 // (39) CPU last run on
+}
+
+// ParseProcStat parses /proc/PID/stat. Fields the kernel does not provide
+// are left zero.
+func ParseProcStat(r io.Reader) (ProcStat, error) {
+	var st ProcStat
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return st, err
+	}
+	s := string(data)
+	// comm may itself contain spaces and parentheses.
+	li := strings.Index(s, "(")
+	ri := strings.LastIndex(s, ")")
+	if li < 0 || ri < 0 || ri <= li {
+		return st, fmt.Errorf("unexpected stat format")
+	}
+	st.PID, _ = strconv.Atoi(strings.TrimSpace(s[:li]))
+	st.Comm = s[li+1 : ri]
+	// rest[0] is field 3.
+	rest := strings.Fields(s[ri+1:])
+	field := func(n int) int64 {
+		if n-3 >= len(rest) {
+			return 0
+		}
+		v, _ := strconv.ParseInt(rest[n-3], 10, 64)
+		return v
+	}
+	if len(rest) > 0 {
+		st.State = rest[0]
+	}
+	st.PPID = int(field(4))
+	st.TTY = field(7)
+	if len(rest) > 6 {
+		st.Flags, _ = strconv.ParseUint(rest[6], 10, 64)
+	}
+	st.UTime = field(14)
+	st.STime = field(15)
+	st.Priority = field(18)
+	st.Nice = field(19)
+	st.Threads = field(20)
+	st.StartTime = field(22)
+	st.VSize = field(23)
+	st.RSS = field(24)
+	st.Processor = field(39)
+	return st, nil
+}
+
+// CPUTimes are the cumulative times of the "cpu" line of /proc/stat, in
+// clock ticks.
+type CPUTimes struct {
+	User, Nice, System, Idle, IOWait, IRQ, SoftIRQ, Steal, Guest, GuestNice uint64
+}
+
+// Total sums every column of the line, as the kernel reports them.
+func (c CPUTimes) Total() uint64 {
+	return c.User + c.Nice + c.System + c.Idle + c.IOWait + c.IRQ + c.SoftIRQ + c.Steal + c.Guest + c.GuestNice
+}
+
+// Stat is the system-wide /proc/stat.
+type Stat struct {
+	CPU      CPUTimes
+	BootTime time.Time
+}
+
+// ParseStat parses /proc/stat.
+func ParseStat(r io.Reader) (Stat, error) {
+	var st Stat
+	sawCPU := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "cpu":
+			sawCPU = true
+			cols := []*uint64{&st.CPU.User, &st.CPU.Nice, &st.CPU.System, &st.CPU.Idle, &st.CPU.IOWait,
+				&st.CPU.IRQ, &st.CPU.SoftIRQ, &st.CPU.Steal, &st.CPU.Guest, &st.CPU.GuestNice}
+			for i, v := range fields[1:] {
+				if i < len(cols) {
+					*cols[i], _ = strconv.ParseUint(v, 10, 64)
+				}
+			}
+		case "btime":
+			if sec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				st.BootTime = time.Unix(sec, 0)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return st, err
+	}
+	if !sawCPU {
+		return st, fmt.Errorf("no cpu line in stat")
+	}
+	return st, nil
+}
+
+// Stat reads /proc/stat.
+func (fs FS) Stat() (Stat, error) {
+	f, err := os.Open(fs.Path("stat"))
+	if err != nil {
+		return Stat{}, err
+	}
+	defer f.Close()
+	return ParseStat(f)
+}
@@ -0,0 +1,41 @@
+package procfs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// comm may contain spaces and parentheses; only the last ")" ends it.
+	line := "4321 (my (odd) proc) R 1 4321 4321 34816 4321 4194560 0 0 0 0 150 25 0 0 20 -5 3 0 7200 104857600 2560 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n"
+	st, err := ParseProcStat(strings.NewReader(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ProcStat{PID: 4321, Comm: "my (odd) proc", State: "R", PPID: 1, TTY: 34816, Flags: 4194560,
+		UTime: 150, STime: 25, Priority: 20, Nice: -5, Threads: 3, StartTime: 7200, VSize: 104857600, RSS: 2560, Processor: 3}
+	if st != want {
+		t.Fatalf("got %+v\nwant %+v", st, want)
+	}
+	if _, err := ParseProcStat(strings.NewReader("garbage")); err == nil {
+		t.Fatal("expected an error without a comm field")
+	}
+}
+
+func TestParseStat(t *testing.T) {
+	data := "cpu  10 1 5 100 2 0 1 0 0 0\ncpu0 10 1 5 100 2 0 1 0 0 0\nbtime 1700000000\n"
+	st, err := ParseStat(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.CPU.User != 10 || st.CPU.Idle != 100 || st.CPU.SoftIRQ != 1 || st.CPU.Total() != 119 {
+		t.Fatalf("unexpected cpu times %+v", st.CPU)
+	}
+	if !st.BootTime.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("unexpected boot time %v", st.BootTime)
+	}
+	if _, err := ParseStat(strings.NewReader("btime 1\n")); err == nil {
+		t.Fatal("expected an error without a cpu line")
+	}
+}
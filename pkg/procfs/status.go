@@ -0,0 +1,80 @@
+package procfs
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ProcStatus holds the fields of /proc/PID/status gobox reads.
+type ProcStatus struct {
+	Name  string
+	State string
+	PPID  int
+	// UIDs and GIDs are the real, effective, saved and filesystem IDs; nil
+	// when the file has no Uid/Gid line.
+	UIDs    []int
+	GIDs    []int
+	Threads int
+	// NoNewPrivs is the no_new_privs bit; Seccomp is 0 (disabled), 1
+	// (strict) or 2 (filter).
+	NoNewPrivs bool
+	Seccomp    int
+	// Capability sets, one bit per capability number.
+	CapInh, CapPrm, CapEff, CapBnd, CapAmb uint64
+}
+
+// ParseProcStatus parses /proc/PID/status, ignoring lines it does not know.
+func ParseProcStatus(r io.Reader) (ProcStatus, error) {
+	var st ProcStatus
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			st.Name = value
+		case "State":
+			st.State, _, _ = strings.Cut(value, " ")
+		case "PPid":
+			st.PPID, _ = strconv.Atoi(value)
+		case "Uid":
+			st.UIDs = parseIDs(value)
+		case "Gid":
+			st.GIDs = parseIDs(value)
+		case "Threads":
+			st.Threads, _ = strconv.Atoi(value)
+		case "NoNewPrivs":
+			st.NoNewPrivs = value == "1"
+		case "Seccomp":
+			st.Seccomp, _ = strconv.Atoi(value)
+		case "CapInh":
+			st.CapInh, _ = strconv.ParseUint(value, 16, 64)
+		case "CapPrm":
+			st.CapPrm, _ = strconv.ParseUint(value, 16, 64)
+		case "CapEff":
+			st.CapEff, _ = strconv.ParseUint(value, 16, 64)
+		case "CapBnd":
+			st.CapBnd, _ = strconv.ParseUint(value, 16, 64)
+		case "CapAmb":
+			st.CapAmb, _ = strconv.ParseUint(value, 16, 64)
+		}
+	}
+	return st, scanner.Err()
+}
+
+func parseIDs(value string) []int {
+	var ids []int
+	for _, f := range strings.Fields(value) {
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,30 @@
+package procfs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProcStatus(t *testing.T) {
+	data := "Name:\tnginx\nState:\tS (sleeping)\nPPid:\t1\nUid:\t101\t101\t101\t101\nGid:\t0\t0\t0\t0\n" +
+		"Threads:\t4\nCapEff:\t00000000a80425fb\nNoNewPrivs:\t1\nSeccomp:\t2\n"
+	st, err := ParseProcStatus(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Name != "nginx" || st.State != "S" || st.PPID != 1 || st.Threads != 4 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	if !reflect.DeepEqual(st.UIDs, []int{101, 101, 101, 101}) || st.GIDs[0] != 0 {
+		t.Fatalf("unexpected ids %v %v", st.UIDs, st.GIDs)
+	}
+	if st.CapEff != 0xa80425fb || !st.NoNewPrivs || st.Seccomp != 2 {
+		t.Fatalf("unexpected security fields %+v", st)
+	}
+
+	st, err = ParseProcStatus(strings.NewReader("Name:\tkthreadd\n"))
+	if err != nil || st.UIDs != nil {
+		t.Fatalf("expected no uids, got %v %v", st.UIDs, err)
+	}
+}
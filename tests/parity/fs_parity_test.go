@@ -479,8 +479,8 @@ func TestParity_FindCases(t *testing.T) {
 			NormalizeFactory: normFactory,
 		},
 		{
-			// FIND-008b: smaller-than size filter; use -type f so both gobox and
-			// native exclude directories (gobox explicitly skips dirs for -size).
+			// FIND-008b: smaller-than size filter; -type f keeps the directory,
+			// whose size depends on the filesystem, out of the comparison.
 			ID:            "FIND-008b",
 			Name:          "find -size -2K (smaller than)",
 			GoboxArgs:     []string{"find", "-type", "f", "-size", "-2K", "tree"},
@@ -2470,10 +2470,14 @@ func statFieldValue(lineOrOut, label string) string {
 // script-generation behavior (docs/TEST-CASES.md "alias" table), driven off
 // the live command registry (cmds/base.Commands()) rather than a hardcoded
 // command list, so the case stays correct as commands are added/removed.
+// The helpers the script leaves out (alias, completion, install, sh, ...)
+// come from base.IsHelper for the same reason.
 func TestParity_AliasCases(t *testing.T) {
-	// ALIAS-001: default script exports gobox_alias_type=bash, aliases every
-	// registered subcommand except "alias" itself (no recursive alias).
+	// ALIAS-001: default script (bash, via $SHELL) exports
+	// gobox_alias_type=bash, aliases every registered subcommand except the
+	// helpers (no recursive alias).
 	t.Run("ALIAS-001", func(t *testing.T) {
+		t.Setenv("SHELL", "/bin/bash")
 		env := t.TempDir()
 		res := runGoboxCLI(t, env, "", "alias")
 		if res.ExitCode != 0 {
@@ -2490,7 +2494,7 @@ func TestParity_AliasCases(t *testing.T) {
 			t.Fatal("base.Commands() returned no registered commands; cannot verify alias coverage")
 		}
 		for _, cmd := range cmds {
-			if cmd.Name() == "alias" {
+			if base.IsHelper(cmd) {
 				continue
 			}
 			want := fmt.Sprintf("alias %s='gobox %s'", cmd.Name(), cmd.Name())
@@ -2503,6 +2507,7 @@ func TestParity_AliasCases(t *testing.T) {
 	// ALIAS-002: -u prints the mirrored unalias script for the same command
 	// set, and cleans up gobox_alias_type at the end.
 	t.Run("ALIAS-002", func(t *testing.T) {
+		t.Setenv("SHELL", "/bin/bash")
 		env := t.TempDir()
 		res := runGoboxCLI(t, env, "", "alias", "-u")
 		if res.ExitCode != 0 {
@@ -2512,9 +2517,9 @@ func TestParity_AliasCases(t *testing.T) {
 			t.Fatalf("alias -u script should unset gobox_alias_type\n%s", res.Stdout)
 		}
 		for _, cmd := range base.Commands() {
-			if cmd.Name() == "alias" {
-				if strings.Contains(res.Stdout, "unalias alias ") {
-					t.Fatalf("alias -u script must not unalias 'alias' itself\n%s", res.Stdout)
+			if base.IsHelper(cmd) {
+				if strings.Contains(res.Stdout, "unalias "+cmd.Name()+" ") {
+					t.Fatalf("alias -u script must not unalias helper command %q\n%s", cmd.Name(), res.Stdout)
 				}
 				continue
 			}
@@ -338,7 +338,7 @@ func invokeGobox(args []string) error {
 		if !ok {
 			return fmt.Errorf("alias command not registered")
 		}
-		return aliasCmd.Run(argv, os.Stdout)
+		return aliasCmd.Run(base.Stdio(), argv)
 	default:
 		return fmt.Errorf("unknown command %s", cmd)
 	}